@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -44,6 +45,7 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/scope"
+	testutil "istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -304,6 +306,19 @@ var testGrid = []testCase{
 			{msg.InvalidRegexp, "VirtualService lots-of-regexes"},
 		},
 	},
+	{
+		name:       "virtualServiceShadowedRoutes",
+		inputFiles: []string{"testdata/virtualservice_shadowedroutes.yaml"},
+		analyzer:   &virtualservice.ShadowedRouteAnalyzer{},
+		expected: []message{
+			{msg.VirtualServiceShadowedRoute, "VirtualService catch-all-first.default"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService prefix-shadowing.default"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService prefix-shadowing.default"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService details.default"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService details-delegate.default"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService bookinfo-b.default"},
+		},
+	},
 	{
 		name: "unknown service registry in mesh networks",
 		inputFiles: []string{
@@ -332,6 +347,23 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "AuthorizationPolicy httpbin-bogus-not-ns.httpbin"},
 		},
 	},
+	{
+		name: "authorizationpolicies effective policies",
+		inputFiles: []string{
+			"testdata/authorizationpolicies-effective.yaml",
+		},
+		meshConfigFile: "testdata/mesh-with-extension-providers.yaml",
+		analyzer:       &authz.EffectivePoliciesAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyIneffectiveRule, "AuthorizationPolicy deny-admin-v1.authz"},
+			{msg.AuthorizationPolicyIneffectiveRule, "AuthorizationPolicy allow-admin.authz"},
+			{msg.AuthorizationPolicyAllowsAll, "AuthorizationPolicy allow-all.authz"},
+			{msg.AuthorizationPolicyIneffectiveRule, "AuthorizationPolicy allow-reviews-get.authz"},
+			{msg.AuthorizationPolicyIneffectiveRule, "AuthorizationPolicy allow-api-ns.authz"},
+			{msg.AuthorizationPolicyIneffectiveRule, "AuthorizationPolicy allow-api-ns.authz"},
+			{msg.ReferencedResourceNotFound, "AuthorizationPolicy ext-authz-missing.authz"},
+		},
+	},
 	{
 		name: "destinationrule with no cacert, simple at destinationlevel",
 		inputFiles: []string{
@@ -560,6 +592,29 @@ func TestAnalyzers(t *testing.T) {
 	})
 }
 
+// TestShadowedRoutesGolden checks the routes reported by the ShadowedRouteAnalyzer, and the reasons why they
+// are shadowed. Run with REFRESH_GOLDEN=true to update the golden file.
+func TestShadowedRoutesGolden(t *testing.T) {
+	sa, err := setupAnalyzerForCase(testCase{
+		inputFiles: []string{"testdata/virtualservice_shadowedroutes.yaml"},
+		analyzer:   &virtualservice.ShadowedRouteAnalyzer{},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Error setting up analysis: %v", err)
+	}
+	result, err := runAnalyzer(sa)
+	if err != nil {
+		t.Fatalf("Error running analysis: %v", err)
+	}
+
+	lines := make([]string, 0, len(result.Messages))
+	for _, m := range result.Messages {
+		lines = append(lines, m.String())
+	}
+	sort.Strings(lines)
+	testutil.CompareContent([]byte(strings.Join(lines, "\n")+"\n"), "testdata/virtualservice_shadowedroutes.golden", t)
+}
+
 // Verify that all of the analyzers tested here are also registered in All()
 func TestAnalyzersInAll(t *testing.T) {
 	g := NewWithT(t)
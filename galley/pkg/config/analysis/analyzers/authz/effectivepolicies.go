@@ -0,0 +1,262 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// maxListedWorkloads is the number of workloads named in a message, the others are only counted.
+const maxListedWorkloads = 3
+
+// EffectivePoliciesAnalyzer evaluates the authorization policies applying to each workload, the way they are
+// enforced by the proxies: CUSTOM policies first, then DENY policies, then ALLOW policies. It reports the rules
+// that have no effect on any of the workloads they apply to, the ALLOW rules matching every request and the
+// CUSTOM policies using an extension provider that is not defined in the mesh config.
+type EffectivePoliciesAnalyzer struct{}
+
+var _ analysis.Analyzer = &EffectivePoliciesAnalyzer{}
+
+// policyRule is a rule of an ALLOW or DENY authorization policy.
+type policyRule struct {
+	policy *resource.Instance
+	action v1beta1.AuthorizationPolicy_Action
+	index  int
+	model  *authzmodel.Model
+	// order of the rule among all the rules, by policy name and rule index.
+	order int
+}
+
+// workload is an in-mesh pod authorization policies apply to.
+type workload struct {
+	name      string
+	namespace string
+	labels    k8s_labels.Set
+}
+
+func (a *EffectivePoliciesAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.EffectivePoliciesAnalyzer",
+		Description: "Checks the authorization policies applying to each workload for rules without effect and missing extension providers",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			collections.K8SCoreV1Namespaces.Name(),
+			collections.K8SCoreV1Pods.Name(),
+		},
+	}
+}
+
+func (a *EffectivePoliciesAnalyzer) Analyze(c analysis.Context) {
+	mc := currentMeshConfig(c)
+	providers := map[string]bool{}
+	for _, p := range mc.GetExtensionProviders() {
+		providers[p.GetName()] = true
+	}
+
+	var policies []*resource.Instance
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		policies = append(policies, r)
+		return true
+	})
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Metadata.FullName.String() < policies[j].Metadata.FullName.String()
+	})
+
+	var rules []*policyRule
+	for _, r := range policies {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		switch ap.Action {
+		case v1beta1.AuthorizationPolicy_CUSTOM:
+			// The decision of a CUSTOM policy is made by its extension provider, it can't be evaluated here.
+			a.analyzeProvider(r, c, providers)
+		case v1beta1.AuthorizationPolicy_ALLOW, v1beta1.AuthorizationPolicy_DENY:
+			for i, rule := range ap.Rules {
+				m, err := authzmodel.New(rule)
+				if err != nil {
+					// Invalid rules are reported by the validation of the policy.
+					continue
+				}
+				pr := &policyRule{policy: r, action: ap.Action, index: i, model: m, order: len(rules)}
+				rules = append(rules, pr)
+				if ap.Action == v1beta1.AuthorizationPolicy_ALLOW && m.MatchesAll() {
+					report(c, pr, msg.NewAuthorizationPolicyAllowsAll(r, i))
+				}
+			}
+		}
+	}
+
+	a.analyzeIneffectiveRules(c, rules, initWorkloads(c), mc.GetRootNamespace())
+}
+
+// analyzeProvider reports CUSTOM policies whose extension provider is not defined in the mesh config.
+func (a *EffectivePoliciesAnalyzer) analyzeProvider(r *resource.Instance, c analysis.Context, providers map[string]bool) {
+	name := r.Message.(*v1beta1.AuthorizationPolicy).GetProvider().GetName()
+	if name == "" || providers[name] {
+		return
+	}
+	m := msg.NewReferencedResourceNotFound(r, "extension provider", name)
+	if line, ok := util.ErrorLine(r, util.AuthorizationPolicyProviderName); ok {
+		m.Line = line
+	}
+	c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+}
+
+// analyzeIneffectiveRules reports the rules that have no effect on any of the workloads they apply to, because
+// every request they match is already decided by another rule applying to the workload: a DENY rule covering an
+// ALLOW rule, or a rule covering another rule with the same action.
+func (a *EffectivePoliciesAnalyzer) analyzeIneffectiveRules(c analysis.Context, rules []*policyRule, workloads []workload, rootNs string) {
+	// For each rule, the workloads it has no effect on, by the rule making it ineffective.
+	covered := map[*policyRule]map[*policyRule][]string{}
+	effective := map[*policyRule]bool{}
+	for _, w := range workloads {
+		var applied []*policyRule
+		for _, r := range rules {
+			if appliesTo(r.policy, w, rootNs) {
+				applied = append(applied, r)
+			}
+		}
+		for _, r := range applied {
+			if effective[r] {
+				continue
+			}
+			by := coveringRule(r, applied)
+			if by == nil {
+				effective[r] = true
+				continue
+			}
+			if covered[r] == nil {
+				covered[r] = map[*policyRule][]string{}
+			}
+			covered[r][by] = append(covered[r][by], w.name)
+		}
+	}
+
+	for _, r := range rules {
+		if effective[r] || len(covered[r]) == 0 {
+			continue
+		}
+		by := make([]*policyRule, 0, len(covered[r]))
+		for b := range covered[r] {
+			by = append(by, b)
+		}
+		sort.Slice(by, func(i, j int) bool { return by[i].order < by[j].order })
+		for _, b := range by {
+			outcome := "allowed"
+			if b.action == v1beta1.AuthorizationPolicy_DENY {
+				outcome = "denied"
+			}
+			report(c, r, msg.NewAuthorizationPolicyIneffectiveRule(r.policy, r.action.String(), r.index,
+				describeWorkloads(covered[r][b]), outcome, b.index, b.policy.Metadata.FullName.String()))
+		}
+	}
+}
+
+// coveringRule returns a rule matching every request r matches, making r ineffective, or nil. DENY rules take
+// precedence over ALLOW rules. Of two rules matching the same requests, the first one makes the other ineffective.
+func coveringRule(r *policyRule, rules []*policyRule) *policyRule {
+	var allow *policyRule
+	for _, o := range rules {
+		if o == r || !o.model.Covers(r.model) {
+			continue
+		}
+		if o.action == r.action && r.model.Covers(o.model) && r.order < o.order {
+			continue
+		}
+		if o.action == v1beta1.AuthorizationPolicy_DENY {
+			return o
+		}
+		if r.action == v1beta1.AuthorizationPolicy_ALLOW && allow == nil {
+			allow = o
+		}
+	}
+	return allow
+}
+
+// appliesTo returns true if the policy applies to the workload: the policy is in the root namespace or in the
+// namespace of the workload, and its selector, if any, matches the labels of the workload.
+func appliesTo(r *resource.Instance, w workload, rootNs string) bool {
+	ns := r.Metadata.FullName.Namespace.String()
+	if ns != w.namespace && ns != rootNs {
+		return false
+	}
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+	if ap.Selector == nil {
+		return true
+	}
+	return k8s_labels.SelectorFromSet(ap.Selector.MatchLabels).Matches(w.labels)
+}
+
+func report(c analysis.Context, r *policyRule, m diag.Message) {
+	if line, ok := util.ElementLine(r.policy, fmt.Sprintf(util.AuthorizationPolicyRule, r.index)); ok {
+		m.Line = line
+	} else if line, ok := util.ErrorLine(r.policy, util.MetadataName); ok {
+		m.Line = line
+	}
+	c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+}
+
+func describeWorkloads(names []string) string {
+	sort.Strings(names)
+	if len(names) <= maxListedWorkloads {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedWorkloads], ", "), len(names)-maxListedWorkloads)
+}
+
+// initWorkloads returns the in-mesh pods.
+func initWorkloads(c analysis.Context) []workload {
+	var workloads []workload
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if !util.PodInMesh(r, c) {
+			return true
+		}
+		p := r.Message.(*v1.Pod)
+		workloads = append(workloads, workload{
+			name:      r.Metadata.FullName.String(),
+			namespace: r.Metadata.FullName.Namespace.String(),
+			labels:    k8s_labels.Set(p.Labels),
+		})
+		return true
+	})
+	return workloads
+}
+
+// currentMeshConfig returns the mesh config of the analysis. Unlike fetchMeshConfig, it is not cached across
+// analyses, so that extension providers are looked up in the current mesh config.
+func currentMeshConfig(c analysis.Context) *v1alpha1.MeshConfig {
+	var mc *v1alpha1.MeshConfig
+	c.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		mc = r.Message.(*v1alpha1.MeshConfig)
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+	return mc
+}
@@ -0,0 +1,335 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package custom contains analyzers that are defined in YAML files rather than in code. Each analyzer
+// evaluates a CEL predicate against the resources of its input collections, and reports a message
+// for every resource the predicate holds for.
+package custom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/hashicorp/go-multierror"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// builtinCodePrefix is the prefix of the codes of the messages of the built-in analyzers.
+const builtinCodePrefix = "IST"
+
+// Config is the content of a file of custom analyzers.
+type Config struct {
+	Analyzers []Spec `json:"analyzers"`
+}
+
+// Spec defines a custom analyzer.
+type Spec struct {
+	// Name of the analyzer. It is prefixed with "custom." when listed along with the built-in analyzers.
+	Name string `json:"name"`
+
+	// Description is a short explanation of what the analyzer checks.
+	Description string `json:"description,omitempty"`
+
+	// Inputs are the names of the collections the analyzer runs on, e.g. "istio/networking/v1alpha3/virtualservices".
+	Inputs []string `json:"inputs"`
+
+	// Expression is a CEL predicate evaluated against every resource of the inputs. A message is reported
+	// for each resource the predicate evaluates to true for. The resource is available through the
+	// "metadata" (name, namespace, labels and annotations), "spec" and "collection" variables.
+	Expression string `json:"expression"`
+
+	// Path is the field the message refers to, e.g. "{.spec.http[0].timeout}", used to find the line number
+	// of the message. The line of the resource name is used if the field is not found.
+	Path string `json:"path,omitempty"`
+
+	Message MessageSpec `json:"message"`
+}
+
+// MessageSpec defines the message reported by a custom analyzer.
+type MessageSpec struct {
+	// Code of the message. Codes starting with "IST" are reserved for the built-in analyzers.
+	Code string `json:"code"`
+
+	// Level of the message, one of Info, Warning or Error.
+	Level string `json:"level"`
+
+	// Template is the format of the message, with one verb for each of the parameters.
+	Template string `json:"template"`
+
+	// Parameters are CEL expressions, evaluated in the same environment as the predicate, whose values
+	// are used to format the template.
+	Parameters []string `json:"parameters,omitempty"`
+}
+
+// Analyzer is an analyzer defined by a Spec.
+type Analyzer struct {
+	spec       Spec
+	inputs     collection.Names
+	predicate  cel.Program
+	parameters []cel.Program
+	msgType    *diag.MessageType
+}
+
+var _ analysis.Analyzer = &Analyzer{}
+
+// LoadFiles reads the custom analyzers defined in the given files.
+func LoadFiles(paths ...string) ([]analysis.Analyzer, error) {
+	var result []analysis.Analyzer
+	var errs error
+	for _, p := range paths {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		analyzers, err := Parse(b)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", p, err))
+			continue
+		}
+		result = append(result, analyzers...)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// Parse parses the custom analyzers defined in a YAML document.
+func Parse(b []byte) ([]analysis.Analyzer, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	result := make([]analysis.Analyzer, 0, len(c.Analyzers))
+	var errs error
+	seen := map[string]struct{}{}
+	for _, s := range c.Analyzers {
+		if _, f := seen[s.Name]; f {
+			errs = multierror.Append(errs, fmt.Errorf("analyzer %q: duplicate name", s.Name))
+			continue
+		}
+		seen[s.Name] = struct{}{}
+		a, err := New(s)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		result = append(result, a)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// New validates a Spec and compiles its expressions.
+func New(s Spec) (*Analyzer, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("analyzer name is required")
+	}
+	wrap := func(err error) error {
+		return fmt.Errorf("analyzer %q: %v", s.Name, err)
+	}
+	if len(s.Inputs) == 0 {
+		return nil, wrap(fmt.Errorf("at least one input collection is required"))
+	}
+	inputs := make(collection.Names, 0, len(s.Inputs))
+	for _, in := range s.Inputs {
+		col, f := collections.All.Find(in)
+		if !f {
+			return nil, wrap(fmt.Errorf("unknown input collection %q", in))
+		}
+		inputs = append(inputs, col.Name())
+	}
+
+	level, f := diag.GetUppercaseStringToLevelMap()[strings.ToUpper(s.Message.Level)]
+	if !f {
+		return nil, wrap(fmt.Errorf("invalid message level %q, valid values: %v", s.Message.Level, diag.GetAllLevelStrings()))
+	}
+	if s.Message.Code == "" {
+		return nil, wrap(fmt.Errorf("message code is required"))
+	}
+	if strings.HasPrefix(s.Message.Code, builtinCodePrefix) {
+		return nil, wrap(fmt.Errorf("message code %q uses the %q prefix reserved for built-in analyzers", s.Message.Code, builtinCodePrefix))
+	}
+	if s.Message.Template == "" {
+		return nil, wrap(fmt.Errorf("message template is required"))
+	}
+
+	env, err := newEnv()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	predicate, err := compile(env, s.Expression, decls.Bool)
+	if err != nil {
+		return nil, wrap(fmt.Errorf("expression: %v", err))
+	}
+	parameters := make([]cel.Program, 0, len(s.Message.Parameters))
+	for i, p := range s.Message.Parameters {
+		prg, err := compile(env, p, nil)
+		if err != nil {
+			return nil, wrap(fmt.Errorf("parameter %d: %v", i, err))
+		}
+		parameters = append(parameters, prg)
+	}
+
+	return &Analyzer{
+		spec:       s,
+		inputs:     inputs,
+		predicate:  predicate,
+		parameters: parameters,
+		msgType:    diag.NewMessageType(level, s.Message.Code, s.Message.Template),
+	}, nil
+}
+
+// MessageType returns the type of the messages reported by the analyzer.
+func (a *Analyzer) MessageType() *diag.MessageType {
+	return a.msgType
+}
+
+// Metadata implements Analyzer
+func (a *Analyzer) Metadata() analysis.Metadata {
+	desc := a.spec.Description
+	if desc == "" {
+		desc = fmt.Sprintf("Reports %s when %s", a.spec.Message.Code, a.spec.Expression)
+	}
+	return analysis.Metadata{
+		Name:        "custom." + a.spec.Name,
+		Description: desc,
+		Inputs:      a.inputs,
+	}
+}
+
+// Analyze implements Analyzer
+func (a *Analyzer) Analyze(ctx analysis.Context) {
+	for _, c := range a.inputs {
+		ctx.ForEach(c, func(r *resource.Instance) bool {
+			a.analyzeResource(ctx, c, r)
+			return true
+		})
+	}
+}
+
+func (a *Analyzer) analyzeResource(ctx analysis.Context, c collection.Name, r *resource.Instance) {
+	vars, err := activation(c, r)
+	if err != nil {
+		scope.Analysis.Warnf("Custom analyzer %q skipped %s: %v", a.spec.Name, r.Metadata.FullName, err)
+		return
+	}
+	out, _, err := a.predicate.Eval(vars)
+	if err != nil {
+		// Accessing a field that is not set is an error in CEL, expressions are expected to guard such
+		// accesses with has(). Failing to do so is treated as the predicate not holding.
+		scope.Analysis.Debugf("Custom analyzer %q failed to evaluate %s: %v", a.spec.Name, r.Metadata.FullName, err)
+		return
+	}
+	if out != types.True {
+		return
+	}
+
+	params := make([]interface{}, 0, len(a.parameters))
+	for _, p := range a.parameters {
+		v, _, err := p.Eval(vars)
+		if err != nil {
+			params = append(params, fmt.Sprintf("<%v>", err))
+			continue
+		}
+		params = append(params, v.Value())
+	}
+
+	m := diag.NewMessage(a.msgType, r, params...)
+	if a.spec.Path != "" {
+		if line, ok := util.ErrorLine(r, a.spec.Path); ok {
+			m.Line = line
+		}
+	}
+	if m.Line == 0 {
+		if line, ok := util.ErrorLine(r, util.MetadataName); ok {
+			m.Line = line
+		}
+	}
+	ctx.Report(c, m)
+}
+
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(cel.Declarations(
+		decls.NewVar("collection", decls.String),
+		decls.NewVar("metadata", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("spec", decls.NewMapType(decls.String, decls.Dyn)),
+	))
+}
+
+// compile compiles an expression, checking its result type if resultType is set.
+func compile(env *cel.Env, expr string, resultType *exprpb.Type) (cel.Program, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("expression is required")
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if resultType != nil {
+		if t := ast.ResultType(); t.GetDyn() == nil && cel.FormatType(t) != cel.FormatType(resultType) {
+			return nil, fmt.Errorf("expression must evaluate to %s, got %s", cel.FormatType(resultType), cel.FormatType(t))
+		}
+	}
+	return env.Program(ast)
+}
+
+// activation returns the variables a resource is evaluated with.
+func activation(c collection.Name, r *resource.Instance) (map[string]interface{}, error) {
+	spec := map[string]interface{}{}
+	if r.Message != nil {
+		m, err := config.ToMap(r.Message)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			spec = m
+		}
+	}
+	return map[string]interface{}{
+		"collection": c.String(),
+		"metadata": map[string]interface{}{
+			"name":        r.Metadata.FullName.Name.String(),
+			"namespace":   r.Metadata.FullName.Namespace.String(),
+			"labels":      stringMap(r.Metadata.Labels),
+			"annotations": stringMap(r.Metadata.Annotations),
+		},
+		"spec": spec,
+	}, nil
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
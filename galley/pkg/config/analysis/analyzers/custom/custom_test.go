@@ -0,0 +1,252 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/testing/fixtures"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const timeoutAnalyzer = `
+analyzers:
+- name: vs-timeout
+  description: Every VirtualService must set timeouts
+  inputs:
+  - istio/networking/v1alpha3/virtualservices
+  expression: has(spec.http) && spec.http.exists(r, !has(r.timeout))
+  path: "{.spec.http[0]}"
+  message:
+    code: HOUSE0001
+    level: Warning
+    template: "VirtualService %s has %d HTTP routes without timeout"
+    parameters:
+    - metadata.name
+    - spec.http.filter(r, !has(r.timeout)).size()
+`
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{
+			name: "valid",
+			in:   timeoutAnalyzer,
+		},
+		{
+			name: "unknown field",
+			in: `
+analyzers:
+- name: a
+  predicate: "true"
+`,
+			err: "unknown field",
+		},
+		{
+			name: "duplicate name",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "true"
+  message: {code: C1, level: Info, template: t}
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "true"
+  message: {code: C1, level: Info, template: t}
+`,
+			err: "duplicate name",
+		},
+		{
+			name: "unknown collection",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservicez]
+  expression: "true"
+  message: {code: C1, level: Info, template: t}
+`,
+			err: "unknown input collection",
+		},
+		{
+			name: "invalid level",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "true"
+  message: {code: C1, level: Fatal, template: t}
+`,
+			err: "invalid message level",
+		},
+		{
+			name: "reserved code",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "true"
+  message: {code: IST0101, level: Info, template: t}
+`,
+			err: "reserved for built-in analyzers",
+		},
+		{
+			name: "syntax error",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "spec.hosts.size() >"
+  message: {code: C1, level: Info, template: t}
+`,
+			err: "Syntax error",
+		},
+		{
+			name: "not a predicate",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "1 + 2"
+  message: {code: C1, level: Info, template: t}
+`,
+			err: "must evaluate to bool",
+		},
+		{
+			name: "invalid parameter",
+			in: `
+analyzers:
+- name: a
+  inputs: [istio/networking/v1alpha3/virtualservices]
+  expression: "true"
+  message: {code: C1, level: Info, template: "%s", parameters: [unknown.name]}
+`,
+			err: "parameter 0",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := Parse([]byte(tt.in))
+			if tt.err == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.err)))
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	g := NewWithT(t)
+
+	analyzers, err := Parse([]byte(timeoutAnalyzer))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(analyzers).To(HaveLen(1))
+	a := analyzers[0].(*Analyzer)
+
+	g.Expect(a.Metadata().Name).To(Equal("custom.vs-timeout"))
+	g.Expect(a.Metadata().Inputs).To(ConsistOf(collections.IstioNetworkingV1Alpha3Virtualservices.Name()))
+
+	missing := &resource.Instance{
+		Metadata: resource.Metadata{FullName: resource.NewFullName("default", "missing")},
+		Message: &v1alpha3.VirtualService{
+			Http: []*v1alpha3.HTTPRoute{{}, {}},
+		},
+		Origin: fakeOrigin{fields: map[string]int{"{.metadata.name}": 4, "{.spec.http[0]}": 9}},
+	}
+	nameOnly := &resource.Instance{
+		Metadata: resource.Metadata{FullName: resource.NewFullName("default", "name-only")},
+		Message: &v1alpha3.VirtualService{
+			Http: []*v1alpha3.HTTPRoute{{}},
+		},
+		Origin: fakeOrigin{fields: map[string]int{"{.metadata.name}": 4}},
+	}
+	ok := &resource.Instance{
+		Metadata: resource.Metadata{FullName: resource.NewFullName("default", "ok")},
+		Message:  &v1alpha3.VirtualService{},
+		Origin:   fakeOrigin{},
+	}
+	ctx := &fixtures.Context{Resources: []*resource.Instance{missing, nameOnly, ok}}
+	a.Analyze(ctx)
+
+	g.Expect(ctx.Reports).To(HaveLen(2))
+	g.Expect(ctx.Reports[0].Type.Code()).To(Equal("HOUSE0001"))
+	g.Expect(ctx.Reports[0].Type.Level()).To(Equal(diag.Warning))
+	g.Expect(ctx.Reports[0].Resource).To(Equal(missing))
+	g.Expect(ctx.Reports[0].Line).To(Equal(9))
+	g.Expect(ctx.Reports[0].String()).To(ContainSubstring("VirtualService missing has 2 HTTP routes without timeout"))
+	g.Expect(ctx.Reports[1].Resource).To(Equal(nameOnly))
+	g.Expect(ctx.Reports[1].Line).To(Equal(4))
+}
+
+func TestAnalyzeEvaluationError(t *testing.T) {
+	g := NewWithT(t)
+
+	a, err := New(Spec{
+		Name:       "tcp",
+		Inputs:     []string{collections.IstioNetworkingV1Alpha3Virtualservices.Name().String()},
+		Expression: "spec.tcp.size() > 0",
+		Message:    MessageSpec{Code: "HOUSE0002", Level: "Error", Template: "tcp routes"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ctx := &fixtures.Context{Resources: []*resource.Instance{{
+		Metadata: resource.Metadata{FullName: resource.NewFullName("default", "vs")},
+		Message:  &v1alpha3.VirtualService{},
+		Origin:   fakeOrigin{},
+	}}}
+	a.Analyze(ctx)
+	g.Expect(ctx.Reports).To(BeEmpty())
+}
+
+func TestLoadFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "analyzers.yaml")
+	g.Expect(ioutil.WriteFile(p, []byte(timeoutAnalyzer), 0o644)).To(Succeed())
+
+	analyzers, err := LoadFiles(p)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(analyzers).To(HaveLen(1))
+
+	_, err = LoadFiles(p, filepath.Join(dir, "missing.yaml"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+type fakeOrigin struct {
+	fields map[string]int
+}
+
+func (fakeOrigin) FriendlyName() string          { return "myFriendlyName" }
+func (fakeOrigin) Comparator() string            { return "myFriendlyName" }
+func (fakeOrigin) Namespace() resource.Namespace { return "myNamespace" }
+func (fakeOrigin) Reference() resource.Reference { return fakeReference{} }
+func (o fakeOrigin) FieldMap() map[string]int    { return o.fields }
+
+type fakeReference struct{}
+
+func (fakeReference) String() string { return "" }
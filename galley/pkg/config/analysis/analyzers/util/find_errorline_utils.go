@@ -78,6 +78,18 @@ const (
 	// Required parameters: rule index, from index, namespace index.
 	AuthorizationPolicyNameSpace = "{.spec.rules[%d].from[%d].source.namespaces[%d]}"
 
+	// Path for rule in authorizationPolicy.
+	// Required parameters: rule index.
+	AuthorizationPolicyRule = "{.spec.rules[%d]}"
+
+	// Path for provider name in authorizationPolicy.
+	// Required parameters: none.
+	AuthorizationPolicyProviderName = "{.spec.provider.name}"
+
+	// Path for HTTP route in VirtualService.
+	// Required parameters: http index.
+	HTTPRoute = "{.spec.http[%d]}"
+
 	// Path for annotation.
 	// Required parameters: annotation name.
 	Annotation = "{.metadata.annotations.%s}"
@@ -105,6 +117,18 @@ func ErrorLine(r *resource.Instance, path string) (line int, found bool) {
 	return line, true
 }
 
+// ElementLine returns the line number of the first field of the object at the input path key in the resource, e.g.
+// the first line of an HTTP route for "{.spec.http[0]}". Only the paths of scalar fields are in the field map.
+func ElementLine(r *resource.Instance, path string) (line int, found bool) {
+	prefix := strings.TrimSuffix(path, "}") + "."
+	for p, l := range r.Origin.FieldMap() {
+		if strings.HasPrefix(p, prefix) && (!found || l < line) {
+			line, found = l, true
+		}
+	}
+	return line, found
+}
+
 // ExtractLabelFromSelectorString returns the label of the match in the k8s labels.Selector
 func ExtractLabelFromSelectorString(s string) string {
 	equalIndex := strings.Index(s, "=")
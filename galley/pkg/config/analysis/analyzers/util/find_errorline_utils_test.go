@@ -38,6 +38,7 @@ var fieldMap = map[string]int{
 	"{.spec.ports[0].port}":                            1,
 	"{.spec.containers[0].image}":                      1,
 	"{.spec.rules[0].from[0].source.namespaces[0]}":    1,
+	"{.spec.provider.name}":                            1,
 	"{.spec.selector.test}":                            1,
 	"{.spec.servers[0].tls.credentialName}":            1,
 	"{.networks.test.endpoints[0]}":                    1,
@@ -60,6 +61,24 @@ func TestErrorLine(t *testing.T) {
 	g.Expect(err2).To(Equal(false))
 }
 
+func TestElementLine(t *testing.T) {
+	g := NewWithT(t)
+	r := &resource.Instance{Origin: &rt.Origin{FieldsMap: map[string]int{
+		"{.spec.http[1].name}":                        5,
+		"{.spec.http[1].match[0].uri.prefix}":         7,
+		"{.spec.http[10].name}":                       3,
+		"{.spec.rules[0].from[0].source.ipBlocks[0]}": 9,
+	}}}
+	line, found := ElementLine(r, fmt.Sprintf(HTTPRoute, 1))
+	g.Expect(found).To(BeTrue())
+	g.Expect(line).To(Equal(5))
+	line, found = ElementLine(r, fmt.Sprintf(AuthorizationPolicyRule, 0))
+	g.Expect(found).To(BeTrue())
+	g.Expect(line).To(Equal(9))
+	_, found = ElementLine(r, fmt.Sprintf(HTTPRoute, 0))
+	g.Expect(found).To(BeFalse())
+}
+
 func TestConstants(t *testing.T) {
 	g := NewWithT(t)
 
@@ -75,6 +94,7 @@ func TestConstants(t *testing.T) {
 		fmt.Sprintf(FromRegistry, "test", 0),
 		fmt.Sprintf(ImageInContainer, 0),
 		fmt.Sprintf(AuthorizationPolicyNameSpace, 0, 0, 0),
+		AuthorizationPolicyProviderName,
 		fmt.Sprintf(Annotation, "test"),
 		fmt.Sprintf(GatewaySelector, "test"),
 		fmt.Sprintf(CredentialName, 0),
@@ -0,0 +1,599 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ShadowedRouteAnalyzer checks for HTTP routes that are never used, because every request they match is
+// matched by an earlier route. Earlier routes are the routes of the same VirtualService, with the routes of
+// the VirtualServices it delegates to merged in, and the routes of the VirtualServices merged before it for
+// the same host on a gateway.
+type ShadowedRouteAnalyzer struct{}
+
+var _ analysis.Analyzer = &ShadowedRouteAnalyzer{}
+
+// httpRoute is an HTTP route as evaluated by the proxies, after the routes of delegate VirtualServices are
+// merged into their root VirtualService.
+type httpRoute struct {
+	// root is the VirtualService the route is part of.
+	root *resource.Instance
+	// owner is the VirtualService defining the route. It is a delegate of root for delegated routes.
+	owner *resource.Instance
+	// index of the route in the HTTP routes of owner.
+	index int
+	name  string
+	// matches of the route, merged with those of the delegating route. A route without matches matches
+	// every request.
+	matches []*routeMatch
+}
+
+type routeMatch struct {
+	*v1alpha3.HTTPMatchRequest
+	// gateways the match applies to, as namespace/name or "mesh".
+	gateways map[string]bool
+}
+
+// gatewayHost identifies the VirtualServices whose routes are merged by a gateway.
+type gatewayHost struct {
+	gateway string
+	host    string
+}
+
+// Metadata implements Analyzer
+func (s *ShadowedRouteAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "virtualservice.ShadowedRouteAnalyzer",
+		Description: "Checks for VirtualService HTTP routes that are never used because an earlier route matches all their requests",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (s *ShadowedRouteAnalyzer) Analyze(ctx analysis.Context) {
+	reported := map[*resource.Instance]map[int]bool{}
+	byGatewayHost := map[gatewayHost][]*resource.Instance{}
+	routesByRoot := map[*resource.Instance][]*httpRoute{}
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		// VirtualServices without hosts are delegates, analyzed as part of their root VirtualServices.
+		if len(vs.Hosts) == 0 {
+			return true
+		}
+		routes := expandRoutes(ctx, r)
+		routesByRoot[r] = routes
+		s.analyzeRoutes(ctx, routes, "", reported)
+
+		// Gateways merge the routes of all the VirtualServices of a host, sidecars only use one of them.
+		for _, gw := range vsGateways(r) {
+			if gw == util.MeshGateway {
+				continue
+			}
+			for _, h := range vs.Hosts {
+				key := gatewayHost{gateway: gw, host: util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h)}
+				byGatewayHost[key] = append(byGatewayHost[key], r)
+			}
+		}
+		return true
+	})
+
+	keys := make([]gatewayHost, 0, len(byGatewayHost))
+	for k, vses := range byGatewayHost {
+		if len(vses) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].gateway != keys[j].gateway {
+			return keys[i].gateway < keys[j].gateway
+		}
+		return keys[i].host < keys[j].host
+	})
+	for _, k := range keys {
+		vses := byGatewayHost[k]
+		// The routes of the oldest VirtualService come first.
+		sort.SliceStable(vses, func(i, j int) bool {
+			ti, tj := vses[i].Metadata.CreateTime, vses[j].Metadata.CreateTime
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return vses[i].Metadata.FullName.String() < vses[j].Metadata.FullName.String()
+		})
+		var merged []*httpRoute
+		for _, r := range vses {
+			merged = append(merged, routesForGateway(routesByRoot[r], k.gateway)...)
+		}
+		s.analyzeRoutes(ctx, merged, k.gateway, reported)
+	}
+}
+
+// analyzeRoutes reports the routes shadowed by an earlier route. Routes of the same root VirtualService are
+// only compared when gateway is empty, otherwise the routes are those merged for a host on that gateway.
+func (s *ShadowedRouteAnalyzer) analyzeRoutes(ctx analysis.Context, routes []*httpRoute, gateway string,
+	reported map[*resource.Instance]map[int]bool) {
+	unreachable := map[*resource.Instance]map[int]bool{}
+	for i, b := range routes {
+		if reported[b.owner][b.index] {
+			continue
+		}
+		if _, f := unreachable[b.owner]; !f {
+			unreachable[b.owner] = reportedByValidation(b.owner)
+		}
+		// Exact duplicates within a VirtualService are already reported by its validation.
+		if unreachable[b.owner][b.index] {
+			continue
+		}
+		for _, a := range routes[:i] {
+			if (gateway == "") != (a.root == b.root) {
+				continue
+			}
+			reason, ok := shadows(a, b, gateway == "")
+			if !ok {
+				continue
+			}
+			m := msg.NewVirtualServiceShadowedRoute(b.owner, routeLabel(b.name, b.index), routeLabel(a.name, a.index),
+				a.owner.Metadata.FullName.String(), reason)
+			if line, ok := util.ElementLine(b.owner, fmt.Sprintf(util.HTTPRoute, b.index)); ok {
+				m.Line = line
+			} else if line, ok := util.ErrorLine(b.owner, util.MetadataName); ok {
+				m.Line = line
+			}
+			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+
+			if reported[b.owner] == nil {
+				reported[b.owner] = map[int]bool{}
+			}
+			reported[b.owner][b.index] = true
+			break
+		}
+	}
+}
+
+// expandRoutes returns the HTTP routes of a root VirtualService, with delegating routes replaced by the routes
+// of their delegate, as done by istiod.
+func expandRoutes(ctx analysis.Context, r *resource.Instance) []*httpRoute {
+	vs := r.Message.(*v1alpha3.VirtualService)
+	gateways := vsGateways(r)
+	var out []*httpRoute
+	for i, route := range vs.Http {
+		if route == nil {
+			continue
+		}
+		if route.Delegate == nil {
+			out = append(out, &httpRoute{
+				root:    r,
+				owner:   r,
+				index:   i,
+				name:    route.Name,
+				matches: routeMatches(route.Match, r.Metadata.FullName.Namespace, gateways),
+			})
+			continue
+		}
+
+		ns := resource.Namespace(route.Delegate.Namespace)
+		if ns == "" {
+			ns = r.Metadata.FullName.Namespace
+		}
+		d := ctx.Find(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), resource.NewFullName(ns, resource.LocalName(route.Delegate.Name)))
+		if d == nil {
+			continue
+		}
+		for j, dr := range d.Message.(*v1alpha3.VirtualService).Http {
+			if dr == nil || dr.Delegate != nil {
+				continue
+			}
+			out = append(out, &httpRoute{
+				root:    r,
+				owner:   d,
+				index:   j,
+				name:    dr.Name,
+				matches: routeMatches(mergeMatches(route.Match, dr.Match), r.Metadata.FullName.Namespace, gateways),
+			})
+		}
+	}
+	return out
+}
+
+// routesForGateway returns the routes, restricted to the matches that apply to a gateway.
+func routesForGateway(routes []*httpRoute, gateway string) []*httpRoute {
+	var out []*httpRoute
+	for _, r := range routes {
+		var matches []*routeMatch
+		for _, m := range r.matches {
+			if m.gateways[gateway] {
+				matches = append(matches, m)
+			}
+		}
+		if len(matches) > 0 {
+			restricted := *r
+			restricted.matches = matches
+			out = append(out, &restricted)
+		}
+	}
+	return out
+}
+
+// routeMatches returns the matches of a route. A route without matches has a single match without
+// conditions, applying to the gateways of its VirtualService.
+func routeMatches(matches []*v1alpha3.HTTPMatchRequest, ns resource.Namespace, vsGateways []string) []*routeMatch {
+	if len(matches) == 0 {
+		matches = []*v1alpha3.HTTPMatchRequest{{}}
+	}
+	out := make([]*routeMatch, 0, len(matches))
+	for _, m := range matches {
+		gateways := vsGateways
+		if len(m.Gateways) > 0 {
+			gateways = m.Gateways
+		}
+		set := make(map[string]bool, len(gateways))
+		for _, gw := range gateways {
+			set[normalizeGateway(ns, gw)] = true
+		}
+		out = append(out, &routeMatch{HTTPMatchRequest: m, gateways: set})
+	}
+	return out
+}
+
+// mergeMatches merges the matches of a delegating route with those of a delegate route. The conditions of the
+// delegate match take precedence over those of the root match.
+func mergeMatches(root, delegate []*v1alpha3.HTTPMatchRequest) []*v1alpha3.HTTPMatchRequest {
+	if len(root) == 0 {
+		return delegate
+	}
+	if len(delegate) == 0 {
+		return root
+	}
+	out := make([]*v1alpha3.HTTPMatchRequest, 0, len(root)*len(delegate))
+	for _, d := range delegate {
+		for _, r := range root {
+			m := *d
+			if m.Uri == nil {
+				m.Uri = r.Uri
+			}
+			if m.Scheme == nil {
+				m.Scheme = r.Scheme
+			}
+			if m.Method == nil {
+				m.Method = r.Method
+			}
+			if m.Authority == nil {
+				m.Authority = r.Authority
+			}
+			m.Headers = mergeStringMatches(r.Headers, d.Headers)
+			m.WithoutHeaders = mergeStringMatches(r.WithoutHeaders, d.WithoutHeaders)
+			m.QueryParams = mergeStringMatches(r.QueryParams, d.QueryParams)
+			if m.Port == 0 {
+				m.Port = r.Port
+			}
+			if len(r.SourceLabels) > 0 || len(d.SourceLabels) > 0 {
+				m.SourceLabels = map[string]string{}
+				for k, v := range r.SourceLabels {
+					m.SourceLabels[k] = v
+				}
+				for k, v := range d.SourceLabels {
+					m.SourceLabels[k] = v
+				}
+			}
+			if m.SourceNamespace == "" {
+				m.SourceNamespace = r.SourceNamespace
+			}
+			if len(m.Gateways) == 0 {
+				m.Gateways = r.Gateways
+			}
+			out = append(out, &m)
+		}
+	}
+	return out
+}
+
+func mergeStringMatches(root, delegate map[string]*v1alpha3.StringMatch) map[string]*v1alpha3.StringMatch {
+	if len(root) == 0 && len(delegate) == 0 {
+		return nil
+	}
+	out := make(map[string]*v1alpha3.StringMatch, len(root)+len(delegate))
+	for k, v := range root {
+		out[k] = v
+	}
+	for k, v := range delegate {
+		out[k] = v
+	}
+	return out
+}
+
+// shadows returns whether every request matched by route b is matched by route a, and why.
+func shadows(a, b *httpRoute, checkGateways bool) (string, bool) {
+	reasons := make([]string, 0, len(b.matches))
+	for _, bm := range b.matches {
+		var covering *routeMatch
+		for _, am := range a.matches {
+			if covers(am, bm, checkGateways) {
+				covering = am
+				break
+			}
+		}
+		if covering == nil {
+			return "", false
+		}
+		if isEmptyMatch(covering.HTTPMatchRequest) {
+			return "it has no match conditions", true
+		}
+		ad, bd := describeMatch(covering.HTTPMatchRequest), describeMatch(bm.HTTPMatchRequest)
+		if ad == bd {
+			reasons = append(reasons, "both match "+ad)
+		} else {
+			reasons = append(reasons, fmt.Sprintf("%s covers %s", ad, bd))
+		}
+	}
+	return strings.Join(reasons, "; "), true
+}
+
+// covers returns whether every request matched by b is matched by a.
+func covers(a, b *routeMatch, checkGateways bool) bool {
+	if checkGateways {
+		for gw := range b.gateways {
+			if !a.gateways[gw] {
+				return false
+			}
+		}
+	}
+	if a.Uri != nil && a.Uri.MatchType != nil {
+		// A case sensitive match does not cover a case insensitive one.
+		if b.IgnoreUriCase && !a.IgnoreUriCase {
+			return false
+		}
+		if !stringMatchCovers(a.Uri, b.Uri, a.IgnoreUriCase) {
+			return false
+		}
+	}
+	if !stringMatchCovers(a.Scheme, b.Scheme, false) ||
+		!stringMatchCovers(a.Method, b.Method, false) ||
+		!stringMatchCovers(a.Authority, b.Authority, false) {
+		return false
+	}
+	for k, av := range a.Headers {
+		bv, f := b.Headers[k]
+		if !f || !stringMatchCovers(av, bv, false) {
+			return false
+		}
+	}
+	for k, av := range a.QueryParams {
+		bv, f := b.QueryParams[k]
+		if !f || !stringMatchCovers(av, bv, false) {
+			return false
+		}
+	}
+	for k, av := range a.WithoutHeaders {
+		bv, f := b.WithoutHeaders[k]
+		if !f || !sameStringMatch(av, bv) {
+			return false
+		}
+	}
+	if a.Port != 0 && a.Port != b.Port {
+		return false
+	}
+	for k, av := range a.SourceLabels {
+		if bv, f := b.SourceLabels[k]; !f || av != bv {
+			return false
+		}
+	}
+	if a.SourceNamespace != "" && a.SourceNamespace != b.SourceNamespace {
+		return false
+	}
+	return true
+}
+
+// stringMatchCovers returns whether every value matched by b is matched by a. A match without a type matches
+// every value.
+func stringMatchCovers(a, b *v1alpha3.StringMatch, ignoreCase bool) bool {
+	if a == nil || a.MatchType == nil {
+		return true
+	}
+	if b == nil || b.MatchType == nil {
+		return false
+	}
+	fold := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch am := a.MatchType.(type) {
+	case *v1alpha3.StringMatch_Exact:
+		if bm, ok := b.MatchType.(*v1alpha3.StringMatch_Exact); ok {
+			return fold(am.Exact) == fold(bm.Exact)
+		}
+	case *v1alpha3.StringMatch_Prefix:
+		switch bm := b.MatchType.(type) {
+		case *v1alpha3.StringMatch_Exact:
+			return strings.HasPrefix(fold(bm.Exact), fold(am.Prefix))
+		case *v1alpha3.StringMatch_Prefix:
+			return strings.HasPrefix(fold(bm.Prefix), fold(am.Prefix))
+		}
+	case *v1alpha3.StringMatch_Regex:
+		switch bm := b.MatchType.(type) {
+		case *v1alpha3.StringMatch_Exact:
+			if ignoreCase {
+				return false
+			}
+			// Envoy requires regular expressions to match the whole value.
+			re, err := regexp.Compile("^(?:" + am.Regex + ")$")
+			return err == nil && re.MatchString(bm.Exact)
+		case *v1alpha3.StringMatch_Regex:
+			return am.Regex == bm.Regex
+		}
+	}
+	return false
+}
+
+func sameStringMatch(a, b *v1alpha3.StringMatch) bool {
+	return stringMatchCovers(a, b, false) && stringMatchCovers(b, a, false)
+}
+
+func isEmptyMatch(m *v1alpha3.HTTPMatchRequest) bool {
+	return describeMatch(m) == "any request"
+}
+
+// describeMatch returns a short description of the conditions of a match.
+func describeMatch(m *v1alpha3.HTTPMatchRequest) string {
+	var conds []string
+	if s := describeStringMatch(m.Uri); s != "" {
+		if m.IgnoreUriCase {
+			s += " ignoring case"
+		}
+		conds = append(conds, "uri "+s)
+	}
+	if s := describeStringMatch(m.Scheme); s != "" {
+		conds = append(conds, "scheme "+s)
+	}
+	if s := describeStringMatch(m.Method); s != "" {
+		conds = append(conds, "method "+s)
+	}
+	if s := describeStringMatch(m.Authority); s != "" {
+		conds = append(conds, "authority "+s)
+	}
+	for _, k := range sortedKeys(m.Headers) {
+		conds = append(conds, describeKeyedMatch("header", k, m.Headers[k]))
+	}
+	for _, k := range sortedKeys(m.WithoutHeaders) {
+		conds = append(conds, describeKeyedMatch("without header", k, m.WithoutHeaders[k]))
+	}
+	for _, k := range sortedKeys(m.QueryParams) {
+		conds = append(conds, describeKeyedMatch("query parameter", k, m.QueryParams[k]))
+	}
+	if m.Port != 0 {
+		conds = append(conds, fmt.Sprintf("port %d", m.Port))
+	}
+	labels := make([]string, 0, len(m.SourceLabels))
+	for k, v := range m.SourceLabels {
+		labels = append(labels, fmt.Sprintf("source label %s=%s", k, v))
+	}
+	sort.Strings(labels)
+	conds = append(conds, labels...)
+	if m.SourceNamespace != "" {
+		conds = append(conds, "source namespace "+m.SourceNamespace)
+	}
+	if len(conds) == 0 {
+		return "any request"
+	}
+	return strings.Join(conds, " and ")
+}
+
+func describeStringMatch(s *v1alpha3.StringMatch) string {
+	if s == nil {
+		return ""
+	}
+	switch m := s.MatchType.(type) {
+	case *v1alpha3.StringMatch_Exact:
+		return fmt.Sprintf("exact %q", m.Exact)
+	case *v1alpha3.StringMatch_Prefix:
+		return fmt.Sprintf("prefix %q", m.Prefix)
+	case *v1alpha3.StringMatch_Regex:
+		return fmt.Sprintf("regex %q", m.Regex)
+	}
+	return ""
+}
+
+func describeKeyedMatch(kind, key string, s *v1alpha3.StringMatch) string {
+	if d := describeStringMatch(s); d != "" {
+		return fmt.Sprintf("%s %s %s", kind, key, d)
+	}
+	return fmt.Sprintf("%s %s present", kind, key)
+}
+
+func sortedKeys(m map[string]*v1alpha3.StringMatch) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportedByValidation returns the indexes of the HTTP routes of a VirtualService that its validation reports
+// as unreachable: routes after a route without matches, and routes whose matches all duplicate earlier ones.
+func reportedByValidation(r *resource.Instance) map[int]bool {
+	out := map[int]bool{}
+	seen := map[string]bool{}
+	emptyMatch := false
+	for i, route := range r.Message.(*v1alpha3.VirtualService).Http {
+		if route == nil {
+			continue
+		}
+		if len(route.Match) == 0 {
+			if emptyMatch {
+				out[i] = true
+			}
+			emptyMatch = true
+			continue
+		}
+		duplicates := 0
+		for _, m := range route.Match {
+			unnamed := *m
+			unnamed.Name = ""
+			b, _ := json.Marshal(&unnamed)
+			if seen[string(b)] {
+				duplicates++
+			}
+			seen[string(b)] = true
+		}
+		if duplicates == len(route.Match) {
+			out[i] = true
+		}
+	}
+	return out
+}
+
+// vsGateways returns the gateways of a VirtualService, as namespace/name or "mesh".
+func vsGateways(r *resource.Instance) []string {
+	vs := r.Message.(*v1alpha3.VirtualService)
+	if len(vs.Gateways) == 0 {
+		return []string{util.MeshGateway}
+	}
+	out := make([]string, 0, len(vs.Gateways))
+	for _, gw := range vs.Gateways {
+		out = append(out, normalizeGateway(r.Metadata.FullName.Namespace, gw))
+	}
+	return out
+}
+
+func normalizeGateway(ns resource.Namespace, gw string) string {
+	if gw == util.MeshGateway {
+		return gw
+	}
+	return resource.NewShortOrFullName(ns, gw).String()
+}
+
+func routeLabel(name string, index int) string {
+	if name != "" {
+		return fmt.Sprintf("#%d (%q)", index, name)
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -156,6 +156,18 @@ var (
 	// IngressRouteRulesNotAffected defines a diag.MessageType for message "IngressRouteRulesNotAffected".
 	// Description: Route rules have no effect on ingress gateway requests
 	IngressRouteRulesNotAffected = diag.NewMessageType(diag.Warning, "IST0140", "Subset in virtual service %s has no effect on ingress gateway %s requests")
+
+	// VirtualServiceShadowedRoute defines a diag.MessageType for message "VirtualServiceShadowedRoute".
+	// Description: A VirtualService HTTP route will never be used because an earlier route matches every request it matches
+	VirtualServiceShadowedRoute = diag.NewMessageType(diag.Warning, "IST0141", "HTTP route %s is never used: every request it matches is matched first by route %s of VirtualService %s (%s)")
+
+	// AuthorizationPolicyIneffectiveRule defines a diag.MessageType for message "AuthorizationPolicyIneffectiveRule".
+	// Description: An authorization policy rule has no effect because every request it matches is already allowed or denied by another rule
+	AuthorizationPolicyIneffectiveRule = diag.NewMessageType(diag.Warning, "IST0142", "%s rule %d has no effect on workloads %s: every request it matches is already %s by rule %d of AuthorizationPolicy %s")
+
+	// AuthorizationPolicyAllowsAll defines a diag.MessageType for message "AuthorizationPolicyAllowsAll".
+	// Description: An ALLOW authorization policy rule matches every request
+	AuthorizationPolicyAllowsAll = diag.NewMessageType(diag.Warning, "IST0143", "ALLOW rule %d matches every request, so the policy allows all traffic to the workloads it applies to")
 )
 
 // All returns a list of all known message types.
@@ -198,9 +210,56 @@ func All() []*diag.MessageType {
 		GatewayDuplicateCertificate,
 		InvalidWebhook,
 		IngressRouteRulesNotAffected,
+		VirtualServiceShadowedRoute,
+		AuthorizationPolicyIneffectiveRule,
+		AuthorizationPolicyAllowsAll,
 	}
 }
 
+// details contains the name and description of all known message types, keyed by code.
+var details = map[string]Detail{
+	"IST0001": {Name: "InternalError", Description: "There was an internal error in the toolchain. This is almost always a bug in the implementation."},
+	"IST0002": {Name: "Deprecated", Description: "A feature that the configuration is depending on is now deprecated."},
+	"IST0101": {Name: "ReferencedResourceNotFound", Description: "A resource being referenced does not exist."},
+	"IST0102": {Name: "NamespaceNotInjected", Description: "A namespace is not enabled for Istio injection."},
+	"IST0103": {Name: "PodMissingProxy", Description: "A pod is missing the Istio proxy."},
+	"IST0104": {Name: "GatewayPortNotOnWorkload", Description: "Unhandled gateway port"},
+	"IST0105": {Name: "IstioProxyImageMismatch", Description: "The image of the Istio proxy running on the pod does not match the image defined in the injection configuration."},
+	"IST0106": {Name: "SchemaValidationError", Description: "The resource has a schema validation error."},
+	"IST0107": {Name: "MisplacedAnnotation", Description: "An Istio annotation is applied to the wrong kind of resource."},
+	"IST0108": {Name: "UnknownAnnotation", Description: "An Istio annotation is not recognized for any kind of resource"},
+	"IST0109": {Name: "ConflictingMeshGatewayVirtualServiceHosts", Description: "Conflicting hosts on VirtualServices associated with mesh gateway"},
+	"IST0110": {Name: "ConflictingSidecarWorkloadSelectors", Description: "A Sidecar resource selects the same workloads as another Sidecar resource"},
+	"IST0111": {Name: "MultipleSidecarsWithoutWorkloadSelectors", Description: "More than one sidecar resource in a namespace has no workload selector"},
+	"IST0112": {Name: "VirtualServiceDestinationPortSelectorRequired", Description: "A VirtualService routes to a service with more than one port exposed, but does not specify which to use."},
+	"IST0113": {Name: "MTLSPolicyConflict", Description: "A DestinationRule and Policy are in conflict with regards to mTLS."},
+	"IST0116": {Name: "DeploymentAssociatedToMultipleServices", Description: "The resulting pods of a service mesh deployment can't be associated with multiple services using the same port but different protocols."},
+	"IST0117": {Name: "DeploymentRequiresServiceAssociated", Description: "The resulting pods of a service mesh deployment must be associated with at least one service."},
+	"IST0118": {Name: "PortNameIsNotUnderNamingConvention", Description: "Port name is not under naming convention. Protocol detection is applied to the port."},
+	"IST0119": {Name: "JwtFailureDueToInvalidServicePortPrefix", Description: "Authentication policy with JWT targets Service with invalid port specification."},
+	"IST0122": {Name: "InvalidRegexp", Description: "Invalid Regex"},
+	"IST0123": {Name: "NamespaceMultipleInjectionLabels", Description: "A namespace has both new and legacy injection labels"},
+	"IST0125": {Name: "InvalidAnnotation", Description: "An Istio annotation that is not valid"},
+	"IST0126": {Name: "UnknownMeshNetworksServiceRegistry", Description: "A service registry in Mesh Networks is unknown"},
+	"IST0127": {Name: "NoMatchingWorkloadsFound", Description: "There aren't workloads matching the resource labels"},
+	"IST0128": {Name: "NoServerCertificateVerificationDestinationLevel", Description: "No caCertificates are set in DestinationRule, this results in no verification of presented server certificate."},
+	"IST0129": {Name: "NoServerCertificateVerificationPortLevel", Description: "No caCertificates are set in DestinationRule, this results in no verification of presented server certificate for traffic to a given port."},
+	"IST0130": {Name: "VirtualServiceUnreachableRule", Description: "A VirtualService rule will never be used because a previous rule uses the same match."},
+	"IST0131": {Name: "VirtualServiceIneffectiveMatch", Description: "A VirtualService rule match duplicates a match in a previous rule."},
+	"IST0132": {Name: "VirtualServiceHostNotFoundInGateway", Description: "Host defined in VirtualService not found in Gateway."},
+	"IST0133": {Name: "SchemaWarning", Description: "The resource has a schema validation warning."},
+	"IST0134": {Name: "ServiceEntryAddressesRequired", Description: "Virtual IP addresses are required for ports serving TCP (or unset) protocol"},
+	"IST0135": {Name: "DeprecatedAnnotation", Description: "A resource is using a deprecated Istio annotation."},
+	"IST0136": {Name: "AlphaAnnotation", Description: "An Istio annotation may not be suitable for production."},
+	"IST0137": {Name: "DeploymentConflictingPorts", Description: "Two services selecting the same workload with the same targetPort MUST refer to the same port."},
+	"IST0138": {Name: "GatewayDuplicateCertificate", Description: "Duplicate certificate in multiple gateways may cause 404s if clients re-use HTTP2 connections."},
+	"IST0139": {Name: "InvalidWebhook", Description: "Webhook is invalid or references a control plane service that does not exist."},
+	"IST0140": {Name: "IngressRouteRulesNotAffected", Description: "Route rules have no effect on ingress gateway requests"},
+	"IST0141": {Name: "VirtualServiceShadowedRoute", Description: "A VirtualService HTTP route will never be used because an earlier route matches every request it matches"},
+	"IST0142": {Name: "AuthorizationPolicyIneffectiveRule", Description: "An authorization policy rule has no effect because every request it matches is already allowed or denied by another rule"},
+	"IST0143": {Name: "AuthorizationPolicyAllowsAll", Description: "An ALLOW authorization policy rule matches every request"},
+}
+
 // NewInternalError returns a new diag.Message based on InternalError.
 func NewInternalError(r *resource.Instance, detail string) diag.Message {
 	return diag.NewMessage(
@@ -572,3 +631,38 @@ func NewIngressRouteRulesNotAffected(r *resource.Instance, virtualservicesubset
 		virtualservice,
 	)
 }
+
+// NewVirtualServiceShadowedRoute returns a new diag.Message based on VirtualServiceShadowedRoute.
+func NewVirtualServiceShadowedRoute(r *resource.Instance, route string, shadowingRoute string, virtualService string, reason string) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceShadowedRoute,
+		r,
+		route,
+		shadowingRoute,
+		virtualService,
+		reason,
+	)
+}
+
+// NewAuthorizationPolicyIneffectiveRule returns a new diag.Message based on AuthorizationPolicyIneffectiveRule.
+func NewAuthorizationPolicyIneffectiveRule(r *resource.Instance, action string, rule int, workloads string, outcome string, coveringRule int, coveringPolicy string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyIneffectiveRule,
+		r,
+		action,
+		rule,
+		workloads,
+		outcome,
+		coveringRule,
+		coveringPolicy,
+	)
+}
+
+// NewAuthorizationPolicyAllowsAll returns a new diag.Message based on AuthorizationPolicyAllowsAll.
+func NewAuthorizationPolicyAllowsAll(r *resource.Instance, rule int) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyAllowsAll,
+		r,
+		rule,
+	)
+}
@@ -18,3 +18,16 @@ package msg
 //go:generate go run "$REPO_ROOT/galley/pkg/config/analysis/msg/generate.main.go" messages.yaml messages.gen.go
 
 //go:generate goimports -w -local istio.io "$REPO_ROOT/galley/pkg/config/analysis/msg/messages.gen.go"
+
+// Detail is the name and description of a message type, as defined in messages.yaml.
+type Detail struct {
+	Name        string
+	Description string
+}
+
+// Describe returns the details of the message type with the given code. It returns false for the codes of
+// messages not defined in messages.yaml, such as the messages of custom analyzers.
+func Describe(code string) (Detail, bool) {
+	d, ok := details[code]
+	return d, ok
+}
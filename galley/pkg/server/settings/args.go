@@ -60,6 +60,9 @@ type Args struct { // nolint:maligned
 	// Enable Config Analysis service, that will analyze and update CRD status. UseOldProcessor must be set to false.
 	EnableConfigAnalysis bool
 
+	// CustomAnalyzerFiles are files defining analyzers that run along with the built-in analyzers.
+	CustomAnalyzerFiles []string
+
 	Snapshots       []string
 	TriggerSnapshot string
 }
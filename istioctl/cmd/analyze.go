@@ -31,6 +31,7 @@ import (
 
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/custom"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
@@ -76,6 +77,7 @@ var (
 	suppress          []string
 	analysisTimeout   time.Duration
 	recursive         bool
+	customAnalyzers   []string
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -104,6 +106,9 @@ func Analyze() *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze the current live cluster, also running the analyzers defined in house-rules.yaml
+  istioctl analyze --custom-analyzers house-rules.yaml
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -115,8 +120,13 @@ func Analyze() *cobra.Command {
 				}
 			}
 
+			all, err := analyzersToRun()
+			if err != nil {
+				return err
+			}
+
 			if listAnalyzers {
-				fmt.Print(AnalyzersAsString(analyzers.All()))
+				fmt.Print(AnalyzersAsString(all))
 				return nil
 			}
 
@@ -148,7 +158,7 @@ func Analyze() *cobra.Command {
 				selectedNamespace = ""
 			}
 
-			sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", all...),
 				resource.Namespace(selectedNamespace), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
 
 			// Check for suppressions and add them to our SourceAnalyzer
@@ -161,7 +171,7 @@ func Analyze() *cobra.Command {
 				// Check to see if the supplied code is valid. If not, emit a
 				// warning but continue.
 				codeIsValid := false
-				for _, at := range msg.All() {
+				for _, at := range messageTypes(all) {
 					if at.Code() == parts[0] {
 						codeIsValid = true
 						break
@@ -277,7 +287,9 @@ func Analyze() *cobra.Command {
 			// Return code is based on the unfiltered validation message list/parse errors
 			// We're intentionally keeping failure threshold and output threshold decoupled for now
 			var returnError error
-			if msgOutputFormat == formatting.LogFormat {
+			// The SARIF and JUnit formats are meant for CI gates, so they also fail above the threshold.
+			if msgOutputFormat == formatting.LogFormat || msgOutputFormat == formatting.SARIFFormat ||
+				msgOutputFormat == formatting.JUnitFormat {
 				returnError = errorIfMessagesExceedThreshold(result.Messages)
 				if returnError == nil && parseErrors > 0 {
 					returnError = FileParseError{}
@@ -313,9 +325,35 @@ func Analyze() *cobra.Command {
 		"The duration to wait before failing")
 	analysisCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false,
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
+	analysisCmd.PersistentFlags().StringArrayVar(&customAnalyzers, "custom-analyzers", []string{},
+		"Files defining additional analyzers, each with input collections, a CEL expression and the message to report. Can be repeated.")
 	return analysisCmd
 }
 
+// analyzersToRun returns the built-in analyzers, along with the analyzers defined in the --custom-analyzers files.
+func analyzersToRun() ([]analysis.Analyzer, error) {
+	all := analyzers.All()
+	if len(customAnalyzers) == 0 {
+		return all, nil
+	}
+	extra, err := custom.LoadFiles(customAnalyzers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom analyzers: %v", err)
+	}
+	return append(all, extra...), nil
+}
+
+// messageTypes returns the types of the messages of the built-in analyzers and of the given custom analyzers.
+func messageTypes(all []analysis.Analyzer) []*diag.MessageType {
+	types := msg.All()
+	for _, a := range all {
+		if ca, ok := a.(*custom.Analyzer); ok {
+			types = append(types, ca.MessageType())
+		}
+	}
+	return types
+}
+
 func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error) {
 	var readers []local.ReaderSource
 	for _, f := range args {
@@ -323,7 +361,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 
 		// Handle "-" as stdin as a special case.
 		if f == "-" {
-			if isatty.IsTerminal(os.Stdin.Fd()) && !isJSONorYAMLOutputFormat() {
+			if isatty.IsTerminal(os.Stdin.Fd()) && !isMachineReadableOutputFormat() {
 				fmt.Fprint(cmd.OutOrStdout(), "Reading from stdin:\n")
 			}
 			r = os.Stdin
@@ -451,6 +489,6 @@ func analyzeTargetAsString() string {
 }
 
 // TODO: Refactor output writer so that it is smart enough to know when to output what.
-func isJSONorYAMLOutputFormat() bool {
-	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat
+func isMachineReadableOutputFormat() bool {
+	return msgOutputFormat != formatting.LogFormat
 }
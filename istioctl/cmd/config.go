@@ -43,12 +43,17 @@ var settableFlags = map[string]interface{}{
 func configCmd() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "config SUBCOMMAND",
-		Short: "Configure istioctl defaults",
+		Short: "Configure istioctl defaults, and export and import the mesh configuration",
 		Args:  cobra.NoArgs,
 		Example: `  # list configuration parameters
-  istioctl config list`,
+  istioctl config list
+
+  # export the mesh configuration of the cluster
+  istioctl x config export -o backup.tar.gz`,
 	}
 	configCmd.AddCommand(listCommand())
+	configCmd.AddCommand(configExportCommand())
+	configCmd.AddCommand(configImportCommand())
 	return configCmd
 }
 
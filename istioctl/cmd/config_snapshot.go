@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/snapshot"
+	"istio.io/pkg/version"
+)
+
+const defaultSnapshotFile = "istio-config-snapshot.tar.gz"
+
+func configExportCommand() *cobra.Command {
+	var (
+		output   string
+		revision string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the mesh-wide Istio configuration of the cluster to an archive",
+		Long: `Export writes the Istio resources, the mesh config, the revision tags and the metadata of the
+plugged-in CA certificates of the cluster to an archive, which can be imported into a cluster with
+'istioctl x config import'. The status and server set metadata of the resources are not exported,
+nor is the private key of the CA.`,
+		Example: `  # Export the configuration of the default revision
+  istioctl x config export -o backup.tar.gz
+
+  # Export the configuration of the canary revision
+  istioctl x config export --revision canary -o canary.tar.gz`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			client, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			s, err := snapshot.Export(context.Background(), client.Kube(), client.Dynamic(), snapshot.ExportOptions{
+				IstioNamespace:  istioNamespace,
+				Revision:        revision,
+				IstioctlVersion: version.Info.Version,
+			})
+			if err != nil {
+				return err
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := s.Write(f); err != nil {
+				_ = f.Close()
+				return fmt.Errorf("failed to write %s: %v", output, err)
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			c.Printf("Exported %d resources to %s\n", len(s.Resources), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", defaultSnapshotFile, "The archive to write")
+	cmd.Flags().StringVarP(&revision, "revision", "r", "", "Control plane revision whose configuration is exported")
+	return cmd
+}
+
+func configImportCommand() *cobra.Command {
+	var (
+		apply     bool
+		overwrite bool
+		revision  string
+	)
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Validate an exported configuration archive and import it into the cluster",
+		Long: `Import validates an archive written by 'istioctl x config export' against the resource versions
+supported by this istioctl, and compares it to the cluster. Without --apply, it only reports what
+importing the archive would change. With --apply, the mesh config and resources are applied, security
+policies first and routing configuration after the destinations it refers to. Resources differing
+from the cluster are only replaced with --overwrite.
+
+Revision tags and CA certificates are not imported: differences are reported so they can be
+recreated by other means.`,
+		Example: `  # Show what importing the archive would change
+  istioctl x config import backup.tar.gz
+
+  # Import the archive, replacing conflicting resources
+  istioctl x config import backup.tar.gz --apply --overwrite`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			s, err := snapshot.Read(f)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", args[0], err)
+			}
+
+			opts := snapshot.ImportOptions{IstioNamespace: istioNamespace, Revision: revision}
+			warnings, err := snapshot.Validate(s, opts)
+			for _, w := range warnings {
+				c.PrintErrf("Warning: %s\n", w)
+			}
+			if err != nil {
+				return fmt.Errorf("the archive is invalid: %v", err)
+			}
+
+			client, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			p, err := snapshot.NewPlan(context.Background(), client.Kube(), client.Dynamic(), s, opts)
+			if err != nil {
+				return err
+			}
+			printPlan(c.OutOrStdout(), p)
+			if !apply {
+				return nil
+			}
+			return snapshot.Apply(context.Background(), client.Kube(), client.Dynamic(), s, p, opts, overwrite, c.OutOrStdout())
+		},
+	}
+	cmd.Flags().BoolVar(&apply, "apply", false, "Apply the archive to the cluster, instead of only reporting the changes")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace the resources of the cluster differing from the archive")
+	cmd.Flags().StringVarP(&revision, "revision", "r", "", "Control plane revision whose mesh config is imported")
+	return cmd
+}
+
+func printPlan(writer io.Writer, p *snapshot.Plan) {
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tACTION")
+	if p.MeshConfig != "" {
+		fmt.Fprintf(w, "mesh config\t%s\n", p.MeshConfig)
+	}
+	if p.MeshNetworks != "" {
+		fmt.Fprintf(w, "meshNetworks\t%s\n", p.MeshNetworks)
+	}
+	for _, c := range p.Changes {
+		ns := c.Resource.GetNamespace()
+		if ns != "" {
+			ns += "/"
+		}
+		fmt.Fprintf(w, "%s %s%s\t%s\n", c.Resource.GetKind(), ns, c.Resource.GetName(), c.Action)
+	}
+	_ = w.Flush()
+	for _, ns := range p.MissingNamespaces {
+		fmt.Fprintf(writer, "Namespace %s does not exist\n", ns)
+	}
+	for _, n := range p.Notes {
+		fmt.Fprintf(writer, "Note: %s\n", n)
+	}
+}
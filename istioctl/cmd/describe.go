@@ -16,11 +16,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	envoy_api_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -36,6 +38,7 @@ import (
 	k8s_labels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 
+	"istio.io/api/annotation"
 	"istio.io/api/networking/v1alpha3"
 	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
@@ -43,6 +46,7 @@ import (
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	istio_envoy_configdump "istio.io/istio/istioctl/pkg/writer/envoy/configdump"
+	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/model"
 	pilot_v1alpha3 "istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -64,6 +68,9 @@ type myProtoValue struct {
 
 const (
 	k8sSuffix = ".svc." + constants.DefaultKubernetesDomain
+
+	// defaultStatusPort is the port of the sidecar status server, unless overridden by annotation.
+	defaultStatusPort = 15020
 )
 
 var (
@@ -103,6 +110,7 @@ the configuration objects that affect that pod.`,
 			podLabels := k8s_labels.Set(pod.ObjectMeta.Labels)
 
 			printPod(writer, pod)
+			printProxyReadiness(writer, client, pod)
 
 			svcs, err := client.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{})
 			if err != nil {
@@ -468,6 +476,43 @@ func printPod(writer io.Writer, pod *v1.Pod) {
 	}
 }
 
+// printProxyReadiness prints the subsystems of the sidecar that are not ready, as reported by the
+// readiness detail of its status server.
+func printProxyReadiness(writer io.Writer, client kubernetes.Interface, pod *v1.Pod) {
+	if pod.Status.Phase != v1.PodRunning || !isMeshed(pod) {
+		return
+	}
+	statusPort := defaultStatusPort
+	if v, f := pod.Annotations[annotation.SidecarStatusPort.Name]; f {
+		if p, err := strconv.Atoi(v); err == nil {
+			statusPort = p
+		}
+	}
+	b, err := client.CoreV1().Pods(pod.Namespace).
+		ProxyGet("", pod.Name, strconv.Itoa(statusPort), "healthz/ready/detail", nil).
+		DoRaw(context.TODO())
+	// The status server returns the readiness detail with a 503 if the proxy is not ready.
+	status := ready.Status{}
+	if len(b) == 0 || json.Unmarshal(b, &status) != nil {
+		if err == nil {
+			err = fmt.Errorf("unexpected response %q", string(b))
+		}
+		fmt.Fprintf(writer, "WARNING: could not get the readiness of the proxy of %s: %v\n", kname(pod.ObjectMeta), err)
+		return
+	}
+	for _, ss := range status.Subsystems {
+		if ss.Ready {
+			continue
+		}
+		severity := "WARNING"
+		if !ss.Required {
+			severity = "Note"
+		}
+		fmt.Fprintf(writer, "%s: Proxy subsystem %s NOT READY since %s: %s\n",
+			severity, ss.Name, ss.LastTransitionTime.Format(time.RFC3339), ss.Reason)
+	}
+}
+
 func kname(meta metav1.ObjectMeta) string {
 	ns := handlers.HandleNamespace(namespace, defaultNamespace)
 	if meta.Namespace == ns {
@@ -54,6 +54,9 @@ By default it will use the default serviceAccount from (istio-system) namespace
   # Retrieve sync diff for a single Envoy and Istiod
   istioctl x internal-debug syncz istio-egressgateway-59585c5b9c-ndc59.istio-system
 
+  # Explain the order of the HTTP filters of each listener of a pod, with their phase and origin
+  istioctl x internal-debug filterchainz productpage-v1-7d9cc8c8b4-x2xw4.default
+
   # SECURITY OPTIONS
 
   # Retrieve syncz debug information directly from the control plane, using token security
@@ -155,6 +158,10 @@ By default it will use the default serviceAccount from (istio-system) namespace
 			if err != nil {
 				return err
 			}
+			if args[0] == "filterchainz" {
+				fw := pilot.FilterChainWriter{Writer: c.OutOrStdout()}
+				return fw.PrintAll(xdsResponses)
+			}
 			sw := pilot.XdsStatusWriter{Writer: c.OutOrStdout()}
 			return sw.PrintAll(xdsResponses)
 		},
@@ -0,0 +1,61 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+)
+
+func remoteClustersCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+
+	remoteClustersCmd := &cobra.Command{
+		Use:   "remote-clusters",
+		Short: "Lists the remote clusters each Istiod instance is connected to [kube only]",
+		Long: `
+Lists the remote clusters configured through remote secrets, as seen by each Istiod instance.
+For each cluster, shows whether its informers synced, the result of the last health check of its
+API server, the expiry of its credentials and the last error encountered.
+`,
+		Example: `  # List the remote clusters of all Istiod instances
+  istioctl x remote-clusters
+
+  # List the remote clusters of the Istiod instances of a revision
+  istioctl x remote-clusters --revision canary
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			statuses, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/clusterz")
+			if err != nil {
+				return err
+			}
+			sw := pilot.ClusterStatusWriter{Writer: c.OutOrStdout()}
+			return sw.PrintAll(statuses)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(remoteClustersCmd)
+
+	return remoteClustersCmd
+}
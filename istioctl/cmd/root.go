@@ -227,6 +227,8 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(workloadCommands())
 	experimentalCmd.AddCommand(revisionCommand())
 	experimentalCmd.AddCommand(debugCommand())
+	experimentalCmd.AddCommand(remoteClustersCommand())
+	experimentalCmd.AddCommand(sidecarRecommendCmd())
 
 	analyzeCmd := Analyze()
 	hideInheritedFlags(analyzeCmd, "istioNamespace")
@@ -0,0 +1,302 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/recommend"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/envoy"
+	"istio.io/istio/pkg/kube"
+)
+
+func sidecarRecommendCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var domainSuffix string
+
+	recommendCmd := &cobra.Command{
+		Use:   "sidecar-recommend [<type>/]<name>[.<namespace>]",
+		Short: "Recommends a Sidecar restricting the egress of workloads to the services they call [kube only]",
+		Long: `
+Recommends a Sidecar resource restricting the egress of workloads to the services they were observed calling,
+reducing the configuration pushed to their proxies.
+
+The outbound traffic is read from the upstream request and connection counters of the Envoy clusters, which are
+reset when the proxies restart: run it once the workloads have served representative traffic.
+With a pod or workload argument, the Sidecar selects all the pods sharing its "app" label. Otherwise, a
+namespace-wide Sidecar is recommended from all the pods of the namespace.
+
+The output is serialized YAML, preceded by the estimated reduction of outbound clusters and services and the
+diff against the Sidecar currently applying to the workloads, if any. The reduction is estimated by computing
+the Sidecar scope of the workloads from the services and Istio configuration of the cluster, with the current
+and the recommended Sidecar.
+`,
+		Example: `  # Recommend a Sidecar for the pods of the productpage deployment
+  istioctl x sidecar-recommend deployment/productpage-v1 -n default
+
+  # Recommend a namespace-wide Sidecar, and apply it
+  istioctl x sidecar-recommend -n default | kubectl apply -f -
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			ns := handlers.HandleNamespace(namespace, defaultNamespace)
+			name := "default"
+			var selector map[string]string
+			if len(args) == 1 {
+				podName, podNs, err := handlers.InferPodInfoFromTypedResource(args[0], ns, kubeClient.UtilFactory())
+				if err != nil {
+					return err
+				}
+				pod, err := kubeClient.CoreV1().Pods(podNs).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				app := pod.Labels["app"]
+				if app == "" {
+					return fmt.Errorf("pod %s.%s has no app label to select its workload, "+
+						"omit it to recommend a namespace-wide Sidecar", podName, podNs)
+				}
+				ns, name, selector = podNs, app, map[string]string{"app": app}
+			}
+
+			pods, err := kubeClient.PodsForSelector(context.TODO(), ns, labels.SelectorFromSet(selector).String())
+			if err != nil {
+				return err
+			}
+			stats, err := sidecarClusterStats(kubeClient, pods.Items)
+			if err != nil {
+				return err
+			}
+			if len(stats) == 0 {
+				return fmt.Errorf("no running pod with a sidecar found in namespace %s", ns)
+			}
+			egress := recommend.RecommendSidecarEgress(stats, domainSuffix)
+			if len(egress.Hosts) == 0 {
+				return fmt.Errorf("no outbound traffic observed from %d pods, not recommending a Sidecar", len(stats))
+			}
+
+			spec := &networking.Sidecar{
+				Egress: []*networking.IstioEgressListener{{Hosts: egress.Hosts}},
+			}
+			if selector != nil {
+				spec.WorkloadSelector = &networking.WorkloadSelector{Labels: selector}
+			}
+			meshConfig, err := getMeshConfigFromConfigMap(kubeconfig, "x sidecar-recommend", opts.Revision)
+			if err != nil {
+				return err
+			}
+			push, err := meshPushContext(kubeClient, meshConfig, domainSuffix)
+			if err != nil {
+				return err
+			}
+			currentSize, recommendedSize := recommend.SidecarConfigSize(push, ns, selector, spec)
+
+			existingName, existingHosts, err := existingSidecarEgress(kubeClient, ns, selector)
+			if err != nil {
+				return err
+			}
+			return writeSidecarRecommendation(c.OutOrStdout(), name, ns, spec, len(stats), egress.ObservedClusters,
+				recommend.Reduction(currentSize, recommendedSize), existingName, existingHosts)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(recommendCmd)
+	recommendCmd.PersistentFlags().StringVar(&domainSuffix, "domain-suffix", constants.DefaultKubernetesDomain,
+		"The domain suffix of the Kubernetes services of the mesh")
+
+	return recommendCmd
+}
+
+// sidecarClusterStats returns the cluster stats of the proxies of the running pods.
+func sidecarClusterStats(kubeClient kube.ExtendedClient, pods []corev1.Pod) ([]map[string]envoy.ClusterTraffic, error) {
+	var out []map[string]envoy.ClusterTraffic
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || !hasProxyContainer(pod) {
+			continue
+		}
+		resp, err := kubeClient.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", envoy.ClusterStatsPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the cluster stats of %s.%s: %v", pod.Name, pod.Namespace, err)
+		}
+		stats, err := envoy.ParseClusterStats(string(resp))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the cluster stats of %s.%s: %v", pod.Name, pod.Namespace, err)
+		}
+		out = append(out, stats)
+	}
+	return out, nil
+}
+
+func hasProxyContainer(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == proxyContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+// existingSidecarEgress returns the name and egress hosts of the Sidecar currently applying to the workloads:
+// the Sidecar selecting them, or the namespace-wide Sidecar, or the mesh-wide Sidecar of the root namespace.
+func existingSidecarEgress(kubeClient kube.ExtendedClient, ns string, selector map[string]string) (string, []string, error) {
+	for _, candidateNs := range []string{ns, istioNamespace} {
+		sidecars, err := kubeClient.Istio().NetworkingV1alpha3().Sidecars(candidateNs).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		namespaceWide := -1
+		for i := range sidecars.Items {
+			sc := &sidecars.Items[i]
+			if sc.Spec.WorkloadSelector == nil || len(sc.Spec.WorkloadSelector.Labels) == 0 {
+				namespaceWide = i
+				continue
+			}
+			if candidateNs == ns && selector != nil &&
+				labels.SelectorFromSet(sc.Spec.WorkloadSelector.Labels).Matches(labels.Set(selector)) {
+				return fmt.Sprintf("%s.%s", sc.Name, sc.Namespace), sidecarEgressHosts(&sc.Spec), nil
+			}
+		}
+		if namespaceWide >= 0 {
+			sc := &sidecars.Items[namespaceWide]
+			return fmt.Sprintf("%s.%s", sc.Name, sc.Namespace), sidecarEgressHosts(&sc.Spec), nil
+		}
+	}
+	return "", nil, nil
+}
+
+// meshPushContext computes the push context of the mesh from the Kubernetes services and the Istio networking
+// configuration of the cluster.
+func meshPushContext(kubeClient kube.ExtendedClient, meshConfig *meshconfig.MeshConfig, domainSuffix string) (*model.PushContext, error) {
+	kubeServices, err := kubeClient.CoreV1().Services(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*model.Service, 0, len(kubeServices.Items))
+	for _, svc := range kubeServices.Items {
+		services = append(services, kubecontroller.ConvertService(svc, domainSuffix, string(serviceregistry.Kubernetes)))
+	}
+
+	var configs []config.Config
+	add := func(obj runtime.Object, kind config.GroupVersionKind) {
+		configs = append(configs, *crdclient.TranslateObject(obj, kind, domainSuffix))
+	}
+	client := kubeClient.Istio().NetworkingV1alpha3()
+	serviceEntries, err := client.ServiceEntries(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range serviceEntries.Items {
+		add(&serviceEntries.Items[i], gvk.ServiceEntry)
+	}
+	virtualServices, err := client.VirtualServices(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range virtualServices.Items {
+		add(&virtualServices.Items[i], gvk.VirtualService)
+	}
+	destinationRules, err := client.DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range destinationRules.Items {
+		add(&destinationRules.Items[i], gvk.DestinationRule)
+	}
+	sidecars, err := client.Sidecars(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range sidecars.Items {
+		add(&sidecars.Items[i], gvk.Sidecar)
+	}
+
+	return recommend.NewPushContext(meshConfig, services, configs)
+}
+
+func sidecarEgressHosts(sc *networking.Sidecar) []string {
+	var hosts []string
+	for _, e := range sc.Egress {
+		hosts = append(hosts, e.Hosts...)
+	}
+	return hosts
+}
+
+func writeSidecarRecommendation(w io.Writer, name, ns string, spec *networking.Sidecar, pods, observedClusters int,
+	reduction string, existingName string, existingHosts []string) error {
+	_, _ = fmt.Fprintf(w, "# Observed outbound traffic from %d pods to %d clusters.\n", pods, observedClusters)
+	_, _ = fmt.Fprintf(w, "# Estimated reduction: %s.\n", reduction)
+	if existingName == "" {
+		_, _ = fmt.Fprintln(w, "# No Sidecar currently applies to the workloads.")
+	} else {
+		diff, err := recommend.DiffHosts("Sidecar "+existingName+" egress hosts", existingHosts, sidecarEgressHosts(spec))
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, "#")
+		for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+			_, _ = fmt.Fprintf(w, "# %s\n", line)
+		}
+	}
+
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": collections.IstioNetworkingV1Alpha3Sidecars.Resource().APIVersion(),
+			"kind":       collections.IstioNetworkingV1Alpha3Sidecars.Resource().Kind(),
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": ns,
+			},
+		},
+	}
+	iSpec, err := unstructureIstioType(spec)
+	if err != nil {
+		return err
+	}
+	u.Object["spec"] = iSpec
+	out, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func TestWriteSidecarRecommendation(t *testing.T) {
+	hosts := []string{"*/httpbin.org", "default/reviews.default.svc.cluster.local"}
+	reduction := "40 -> 4 outbound clusters (-90%), 20 -> 2 services"
+	cases := []struct {
+		name          string
+		selector      map[string]string
+		existingName  string
+		existingHosts []string
+		want          string
+	}{
+		{
+			name:     "workload without existing sidecar",
+			selector: map[string]string{"app": "productpage"},
+			want: `# Observed outbound traffic from 2 pods to 2 clusters.
+# Estimated reduction: 40 -> 4 outbound clusters (-90%), 20 -> 2 services.
+# No Sidecar currently applies to the workloads.
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: productpage
+  namespace: default
+spec:
+  egress:
+  - hosts:
+    - '*/httpbin.org'
+    - default/reviews.default.svc.cluster.local
+  workloadSelector:
+    labels:
+      app: productpage
+`,
+		},
+		{
+			name:          "namespace with existing sidecar",
+			existingName:  "default.istio-system",
+			existingHosts: []string{"./*", "istio-system/*"},
+			want: `# Observed outbound traffic from 2 pods to 2 clusters.
+# Estimated reduction: 40 -> 4 outbound clusters (-90%), 20 -> 2 services.
+#
+# --- Sidecar default.istio-system egress hosts
+# +++ Recommended
+# @@ -1,2 +1,2 @@
+# -./*
+# -istio-system/*
+# +*/httpbin.org
+# +default/reviews.default.svc.cluster.local
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: productpage
+  namespace: default
+spec:
+  egress:
+  - hosts:
+    - '*/httpbin.org'
+    - default/reviews.default.svc.cluster.local
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			spec := &networking.Sidecar{
+				Egress: []*networking.IstioEgressListener{{Hosts: hosts}},
+			}
+			if tt.selector != nil {
+				spec.WorkloadSelector = &networking.WorkloadSelector{Labels: tt.selector}
+			}
+			err := writeSidecarRecommendation(got, "productpage", "default", spec, 2, 2, reduction, tt.existingName, tt.existingHosts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommend
+
+import (
+	"fmt"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ConfigSize is the size of the outbound configuration pushed to the proxies of a SidecarScope.
+type ConfigSize struct {
+	// Services is the number of services visible to the proxies.
+	Services int
+	// Clusters is the number of outbound clusters: one per service port, plus one per port and subset of the
+	// DestinationRule of the service.
+	Clusters int
+}
+
+// Reduction returns the reduction from the current to the recommended configuration size as a human readable
+// summary.
+func Reduction(current, recommended ConfigSize) string {
+	if current.Clusters == 0 {
+		return "no outbound clusters"
+	}
+	removed := current.Clusters - recommended.Clusters
+	return fmt.Sprintf("%d -> %d outbound clusters (-%d%%), %d -> %d services", current.Clusters,
+		recommended.Clusters, removed*100/current.Clusters, current.Services, recommended.Services)
+}
+
+// NewPushContext computes the push context of a mesh from its Kubernetes services and Istio configuration.
+// ServiceEntries are read from the configuration.
+func NewPushContext(m *meshconfig.MeshConfig, services []*model.Service, configs []config.Config) (*model.PushContext, error) {
+	store := memory.MakeSkipValidation(collections.Pilot)
+	for _, cfg := range configs {
+		if _, err := store.Create(cfg); err != nil {
+			return nil, fmt.Errorf("failed to load %s %s/%s: %v", cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, err)
+		}
+	}
+	configController := memory.NewController(store)
+
+	registries := aggregate.NewController(aggregate.Options{})
+	registries.AddRegistry(serviceentry.NewServiceDiscovery(configController, model.MakeIstioStore(store), noopXDSUpdater{}))
+	kubeServices := memregistry.NewServiceDiscovery(services)
+	registries.AddRegistry(serviceregistry.Simple{
+		ProviderID:       serviceregistry.Kubernetes,
+		ServiceDiscovery: kubeServices,
+		Controller:       kubeServices.Controller,
+	})
+
+	env := &model.Environment{
+		ServiceDiscovery: registries,
+		IstioConfigStore: model.MakeIstioStore(configController),
+		Watcher:          mesh.NewFixedWatcher(m),
+		NetworksWatcher:  mesh.NewFixedNetworksWatcher(nil),
+	}
+	env.Init()
+	push := model.NewPushContext()
+	if err := push.InitContext(env, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to compute the push context: %v", err)
+	}
+	return push, nil
+}
+
+// SidecarConfigSize returns the size of the outbound configuration of the workloads of a namespace matching
+// the labels, with the Sidecar currently applying to them and with the recommended Sidecar spec.
+func SidecarConfigSize(push *model.PushContext, namespace string, workloadLabels map[string]string,
+	recommended *networking.Sidecar) (current, recommendedSize ConfigSize) {
+	proxy := &model.Proxy{
+		Type:            model.SidecarProxy,
+		ConfigNamespace: namespace,
+		Metadata:        &model.NodeMetadata{Labels: workloadLabels},
+	}
+	proxy.SetSidecarScope(push)
+	scope := model.ConvertToSidecarScope(push, &config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.IstioNetworkingV1Alpha3Sidecars.Resource().GroupVersionKind(),
+			Name:             "recommended",
+			Namespace:        namespace,
+		},
+		Spec: recommended,
+	}, namespace)
+	return sidecarScopeSize(proxy.SidecarScope), sidecarScopeSize(scope)
+}
+
+func sidecarScopeSize(scope *model.SidecarScope) ConfigSize {
+	out := ConfigSize{}
+	for _, svc := range scope.Services() {
+		out.Services++
+		clusters := 1
+		if dr := scope.DestinationRule(svc.Hostname); dr != nil {
+			clusters += len(dr.Spec.(*networking.DestinationRule).Subsets)
+		}
+		out.Clusters += clusters * len(svc.Ports)
+	}
+	return out
+}
+
+// noopXDSUpdater ignores the updates of the ServiceEntry registry: the push context is computed once.
+type noopXDSUpdater struct{}
+
+var _ model.XDSUpdater = noopXDSUpdater{}
+
+func (noopXDSUpdater) EDSUpdate(_, _, _ string, _ []*model.IstioEndpoint) {}
+
+func (noopXDSUpdater) EDSCacheUpdate(_, _, _ string, _ []*model.IstioEndpoint) {}
+
+func (noopXDSUpdater) SvcUpdate(_, _, _ string, _ model.Event) {}
+
+func (noopXDSUpdater) ConfigUpdate(*model.PushRequest) {}
+
+func (noopXDSUpdater) ProxyUpdate(_, _ string) {}
@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommend
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestSidecarConfigSize(t *testing.T) {
+	service := func(name, namespace string, ports ...int) *model.Service {
+		svc := &model.Service{
+			Hostname:   host.Name(name + "." + namespace + ".svc.cluster.local"),
+			Attributes: model.ServiceAttributes{Name: name, Namespace: namespace},
+		}
+		for _, p := range ports {
+			svc.Ports = append(svc.Ports, &model.Port{Name: "http", Port: p, Protocol: protocol.HTTP})
+		}
+		return svc
+	}
+	services := []*model.Service{
+		service("reviews", "default", 9080),
+		service("ratings", "default", 9080),
+		service("details", "other", 9080, 9443),
+	}
+	destinationRule := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.DestinationRule, Name: "reviews", Namespace: "default"},
+		Spec: &networking.DestinationRule{
+			Host:    "reviews.default.svc.cluster.local",
+			Subsets: []*networking.Subset{{Name: "v1"}, {Name: "v2"}},
+		},
+	}
+	namespaceSidecar := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.Sidecar, Name: "default", Namespace: "default"},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{{Hosts: []string{"./*"}}},
+		},
+	}
+	recommended := &networking.Sidecar{
+		Egress: []*networking.IstioEgressListener{{Hosts: []string{"default/reviews.default.svc.cluster.local"}}},
+	}
+
+	cases := []struct {
+		name        string
+		configs     []config.Config
+		current     ConfigSize
+		recommended ConfigSize
+		reduction   string
+	}{
+		{
+			name:        "no sidecar",
+			configs:     []config.Config{destinationRule},
+			current:     ConfigSize{Services: 3, Clusters: 6},
+			recommended: ConfigSize{Services: 1, Clusters: 3},
+			reduction:   "6 -> 3 outbound clusters (-50%), 3 -> 1 services",
+		},
+		{
+			name:        "namespace sidecar",
+			configs:     []config.Config{destinationRule, namespaceSidecar},
+			current:     ConfigSize{Services: 2, Clusters: 4},
+			recommended: ConfigSize{Services: 1, Clusters: 3},
+			reduction:   "4 -> 3 outbound clusters (-25%), 2 -> 1 services",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mesh.DefaultMeshConfig()
+			push, err := NewPushContext(&m, services, tt.configs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current, recommendedSize := SidecarConfigSize(push, "default", map[string]string{"app": "productpage"}, recommended)
+			if current != tt.current {
+				t.Errorf("got current size %+v, want %+v", current, tt.current)
+			}
+			if recommendedSize != tt.recommended {
+				t.Errorf("got recommended size %+v, want %+v", recommendedSize, tt.recommended)
+			}
+			if got := Reduction(current, recommendedSize); got != tt.reduction {
+				t.Errorf("Reduction() => %q, want %q", got, tt.reduction)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recommend proposes configuration from the traffic observed by proxies.
+package recommend
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/envoy"
+)
+
+// SidecarEgress is the Sidecar egress recommended for a set of workloads, restricted to the hosts
+// they were observed sending traffic to.
+type SidecarEgress struct {
+	// Hosts are the recommended egress hosts, in <namespace>/<dnsName> form.
+	Hosts []string
+	// ObservedClusters is the number of outbound clusters which received traffic.
+	ObservedClusters int
+}
+
+// RecommendSidecarEgress recommends the Sidecar egress of workloads from the cluster stats of their
+// proxies. Clusters are considered used if any request or connection was sent to them.
+func RecommendSidecarEgress(stats []map[string]envoy.ClusterTraffic, domainSuffix string) SidecarEgress {
+	used := map[host.Name]struct{}{}
+	observed := map[string]struct{}{}
+	for _, proxyStats := range stats {
+		for cluster, traffic := range proxyStats {
+			direction, _, hostname, _ := model.ParseSubsetKey(cluster)
+			if direction != model.TrafficDirectionOutbound || hostname == "" {
+				continue
+			}
+			if traffic.RequestsTotal > 0 || traffic.ConnectionsTotal > 0 {
+				used[hostname] = struct{}{}
+				observed[cluster] = struct{}{}
+			}
+		}
+	}
+
+	out := SidecarEgress{
+		Hosts:            make([]string, 0, len(used)),
+		ObservedClusters: len(observed),
+	}
+	for hostname := range used {
+		out.Hosts = append(out.Hosts, EgressHost(hostname, domainSuffix))
+	}
+	sort.Strings(out.Hosts)
+	return out
+}
+
+// EgressHost returns the Sidecar egress host selecting the given service. Kubernetes services are
+// selected in their namespace; other hosts, such as ServiceEntry hosts, in any namespace.
+func EgressHost(hostname host.Name, domainSuffix string) string {
+	svcSuffix := ".svc." + domainSuffix
+	if name := string(hostname); strings.HasSuffix(name, svcSuffix) {
+		if parts := strings.Split(strings.TrimSuffix(name, svcSuffix), "."); len(parts) == 2 {
+			return parts[1] + "/" + name
+		}
+	}
+	return "*/" + string(hostname)
+}
+
+// DiffHosts returns the unified diff from the existing egress hosts to the recommended ones.
+func DiffHosts(existingName string, existing []string, recommended []string) (string, error) {
+	existing = append([]string{}, existing...)
+	sort.Strings(existing)
+	diff := difflib.UnifiedDiff{
+		A:        lines(existing),
+		B:        lines(recommended),
+		FromFile: existingName,
+		ToFile:   "Recommended",
+		Context:  len(existing) + len(recommended),
+	}
+	return difflib.GetUnifiedDiffString(diff)
+}
+
+func lines(hosts []string) []string {
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, h+"\n")
+	}
+	return out
+}
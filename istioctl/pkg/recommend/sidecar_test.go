@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommend
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/envoy"
+)
+
+func TestRecommendSidecarEgress(t *testing.T) {
+	stats := []map[string]envoy.ClusterTraffic{
+		{
+			"outbound|9080||reviews.default.svc.cluster.local":      {RequestsTotal: 10, ConnectionsTotal: 1},
+			"outbound|9080|v1|reviews.default.svc.cluster.local":    {},
+			"outbound|9080||ratings.default.svc.cluster.local":      {},
+			"outbound|443||httpbin.org":                             {ConnectionsTotal: 1},
+			"outbound|15012||istiod.istio-system.svc.cluster.local": {},
+			"inbound|9080||":     {RequestsTotal: 100},
+			"BlackHoleCluster":   {},
+			"PassthroughCluster": {ConnectionsTotal: 3},
+			"prometheus_stats":   {RequestsTotal: 20},
+		},
+		{
+			"outbound|9080||reviews.default.svc.cluster.local":      {},
+			"outbound|9080||ratings.default.svc.cluster.local":      {},
+			"outbound|8080||details.other.svc.cluster.local":        {RequestsTotal: 1},
+			"outbound|15012||istiod.istio-system.svc.cluster.local": {},
+		},
+	}
+	got := RecommendSidecarEgress(stats, "cluster.local")
+	want := SidecarEgress{
+		Hosts: []string{
+			"*/httpbin.org",
+			"default/reviews.default.svc.cluster.local",
+			"other/details.other.svc.cluster.local",
+		},
+		ObservedClusters: 3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("RecommendSidecarEgress() => %+v, want %+v", got, want)
+	}
+}
+
+func TestEgressHost(t *testing.T) {
+	cases := []struct {
+		host host.Name
+		want string
+	}{
+		{"reviews.default.svc.cluster.local", "default/reviews.default.svc.cluster.local"},
+		{"reviews.default.svc.example.com", "*/reviews.default.svc.example.com"},
+		{"a.b.c.svc.cluster.local", "*/a.b.c.svc.cluster.local"},
+		{"httpbin.org", "*/httpbin.org"},
+	}
+	for _, tt := range cases {
+		if got := EgressHost(tt.host, "cluster.local"); got != tt.want {
+			t.Errorf("EgressHost(%v) => %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestDiffHosts(t *testing.T) {
+	got, err := DiffHosts("Sidecar default.default", []string{"./*", "istio-system/*"}, []string{"default/a.default.svc.cluster.local", "istio-system/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `--- Sidecar default.default
++++ Recommended
+@@ -1,2 +1,2 @@
+-./*
++default/a.default.svc.cluster.local
+ istio-system/*
+`
+	if got != want {
+		t.Errorf("DiffHosts() =>\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const (
+	// maxExportAttempts bounds how many times the resources are listed again when they change during
+	// the export.
+	maxExportAttempts = 3
+
+	istioTagLabel            = "istio.io/tag"
+	defaultConfigMapName     = "istio"
+	meshConfigMapKey         = "mesh"
+	meshNetworksConfigMapKey = "meshNetworks"
+	caCertsSecretName        = "cacerts"
+	// caKeyEntry is the CA private key in the cacerts Secret, which is never read.
+	caKeyEntry = "ca-key.pem"
+	// lastAppliedAnnotation is set by kubectl apply, and is not part of the resource configuration.
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// ExportOptions configure Export.
+type ExportOptions struct {
+	IstioNamespace string
+	// Revision is the control plane revision whose mesh config and resources are exported. Resources
+	// without an istio.io/rev label are exported for all revisions.
+	Revision string
+	// Schemas are the resource kinds exported. Defaults to the Istio resources read by Pilot.
+	Schemas collection.Schemas
+	// IstioctlVersion is recorded in the manifest.
+	IstioctlVersion string
+}
+
+func (o ExportOptions) schemas() collection.Schemas {
+	if len(o.Schemas.All()) == 0 {
+		return collections.Pilot
+	}
+	return o.Schemas
+}
+
+// ConfigMapName returns the name of the Istio ConfigMap of revision.
+func ConfigMapName(revision string) string {
+	if revision == "" || revision == "default" {
+		return defaultConfigMapName
+	}
+	return defaultConfigMapName + "-" + revision
+}
+
+// Export returns a snapshot of the Istio configuration of a cluster. The resources are listed until
+// two consecutive lists match, so that the snapshot does not mix configuration from before and after
+// a concurrent change.
+func Export(ctx context.Context, kube kubernetes.Interface, dyn dynamic.Interface, opts ExportOptions) (*Snapshot, error) {
+	schemas := opts.schemas()
+	var resources []*unstructured.Unstructured
+	for attempt := 1; ; attempt++ {
+		first, err := listResources(ctx, dyn, schemas, opts.Revision)
+		if err != nil {
+			return nil, err
+		}
+		second, err := listResources(ctx, dyn, schemas, opts.Revision)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(resourceVersions(first), resourceVersions(second)) {
+			resources = second
+			break
+		}
+		if attempt == maxExportAttempts {
+			return nil, fmt.Errorf("the Istio configuration kept changing during %d attempts to export it, "+
+				"retry once it is stable", maxExportAttempts)
+		}
+	}
+
+	s := &Snapshot{
+		Manifest: Manifest{
+			FormatVersion:   FormatVersion,
+			IstioctlVersion: opts.IstioctlVersion,
+			CreationTime:    time.Now().UTC().Truncate(time.Second),
+			IstioNamespace:  opts.IstioNamespace,
+			Revision:        opts.Revision,
+		},
+	}
+	counts := map[string]int{}
+	for _, r := range resources {
+		counts[gvkOf(r).String()]++
+		s.Resources = append(s.Resources, cleanResource(r))
+	}
+	for _, sc := range applyOrder(schemas) {
+		gvk := sc.Resource().GroupVersionKind()
+		s.Manifest.Schemas = append(s.Manifest.Schemas, SchemaVersion{GroupVersionKind: gvk, Resources: counts[gvk.String()]})
+	}
+	sortResources(s.Resources, kindOrder(schemas))
+
+	cm, err := kube.CoreV1().ConfigMaps(opts.IstioNamespace).Get(ctx, ConfigMapName(opts.Revision), metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		s.MeshConfig = cm.Data[meshConfigMapKey]
+		s.MeshNetworks = cm.Data[meshNetworksConfigMapKey]
+	}
+
+	if s.RevisionTags, err = listRevisionTags(ctx, kube); err != nil {
+		return nil, err
+	}
+	if s.CACerts, err = getCACerts(ctx, kube, opts.IstioNamespace); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// listResources lists the resources of schemas in all namespaces. Kinds whose CRD is not installed
+// are skipped.
+func listResources(ctx context.Context, dyn dynamic.Interface, schemas collection.Schemas,
+	revision string) ([]*unstructured.Unstructured, error) {
+	var out []*unstructured.Unstructured
+	for _, sc := range schemas.All() {
+		list, err := dyn.Resource(sc.Resource().GroupVersionResource()).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", sc.Resource().Plural(), err)
+		}
+		for i := range list.Items {
+			r := &list.Items[i]
+			// Mirror the revision selection of istiod.
+			if rev, f := r.GetLabels()[label.IoIstioRev.Name]; f && rev != revision {
+				continue
+			}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func resourceVersions(resources []*unstructured.Unstructured) map[string]string {
+	out := make(map[string]string, len(resources))
+	for _, r := range resources {
+		out[resourceKey(r)] = r.GetResourceVersion()
+	}
+	return out
+}
+
+// cleanResource returns a copy of r without its status and the metadata set by the API server. Owner
+// references are removed as well, as the owners would not exist in the cluster the snapshot is
+// imported into, and the resources would be garbage collected.
+func cleanResource(r *unstructured.Unstructured) *unstructured.Unstructured {
+	out := r.DeepCopy()
+	unstructured.RemoveNestedField(out.Object, "status")
+	for _, f := range []string{"resourceVersion", "uid", "selfLink", "creationTimestamp", "generation",
+		"managedFields", "ownerReferences"} {
+		unstructured.RemoveNestedField(out.Object, "metadata", f)
+	}
+	if annotations := out.GetAnnotations(); annotations != nil {
+		delete(annotations, lastAppliedAnnotation)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		out.SetAnnotations(annotations)
+	}
+	return out
+}
+
+// listRevisionTags returns the revision tags, from the injection webhooks labeled istio.io/tag.
+func listRevisionTags(ctx context.Context, kube kubernetes.Interface) ([]RevisionTag, error) {
+	webhooks, err := kube.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+		LabelSelector: istioTagLabel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revision tags: %v", err)
+	}
+	tags := []RevisionTag{}
+	for _, wh := range webhooks.Items {
+		tags = append(tags, RevisionTag{Tag: wh.Labels[istioTagLabel], Revision: wh.Labels[label.IoIstioRev.Name]})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags, nil
+}
+
+// getCACerts returns the metadata of the certificates of the cacerts Secret, or nil if there is none.
+func getCACerts(ctx context.Context, kube kubernetes.Interface, namespace string) (*CACerts, error) {
+	secret, err := kube.CoreV1().Secrets(namespace).Get(ctx, caCertsSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s Secret: %v", caCertsSecretName, err)
+	}
+	out := &CACerts{Keys: []string{}}
+	for key := range secret.Data {
+		out.Keys = append(out.Keys, key)
+	}
+	sort.Strings(out.Keys)
+	for _, key := range out.Keys {
+		if key == caKeyEntry || !strings.HasSuffix(key, ".pem") {
+			continue
+		}
+		out.Certificates = append(out.Certificates, certificatesMetadata(key, secret.Data[key])...)
+	}
+	return out, nil
+}
+
+// certificatesMetadata returns the metadata of the PEM encoded certificates of the cacerts entry key.
+func certificatesMetadata(key string, data []byte) []CertificateMetadata {
+	var out []CertificateMetadata
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return out
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		fp := sha256.Sum256(cert.Raw)
+		out = append(out, CertificateMetadata{
+			Key:               key,
+			Subject:           cert.Subject.String(),
+			Issuer:            cert.Issuer.String(),
+			NotBefore:         cert.NotBefore.UTC(),
+			NotAfter:          cert.NotAfter.UTC(),
+			SHA256Fingerprint: hex.EncodeToString(fp[:]),
+		})
+	}
+}
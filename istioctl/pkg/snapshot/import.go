@@ -0,0 +1,466 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// kindApplyOrder is the order resources are applied in. Security policies come first so that no
+// workload is reachable without the policies protecting it. Services are defined before the
+// DestinationRules whose subsets routes refer to, and Gateways before the VirtualServices bound to
+// them. Kinds not listed are applied last.
+var kindApplyOrder = []string{
+	"PeerAuthentication",
+	"RequestAuthentication",
+	"AuthorizationPolicy",
+	"WorkloadGroup",
+	"WorkloadEntry",
+	"ServiceEntry",
+	"DestinationRule",
+	"Gateway",
+	"VirtualService",
+	"Sidecar",
+	"EnvoyFilter",
+	"Telemetry",
+}
+
+// kindOrder returns the rank of a kind in the apply order.
+func kindOrder(schemas collection.Schemas) func(config.GroupVersionKind) int {
+	rank := map[config.GroupVersionKind]int{}
+	for i, sc := range applyOrder(schemas) {
+		rank[sc.Resource().GroupVersionKind()] = i
+	}
+	return func(gvk config.GroupVersionKind) int {
+		if r, f := rank[gvk]; f {
+			return r
+		}
+		return len(rank)
+	}
+}
+
+// applyOrder returns schemas in the order their resources are applied.
+func applyOrder(schemas collection.Schemas) []collection.Schema {
+	rank := func(sc collection.Schema) int {
+		for i, k := range kindApplyOrder {
+			if sc.Resource().Kind() == k {
+				return i
+			}
+		}
+		return len(kindApplyOrder)
+	}
+	out := append([]collection.Schema{}, schemas.All()...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if ri, rj := rank(out[i]), rank(out[j]); ri != rj {
+			return ri < rj
+		}
+		return out[i].Resource().GroupVersionKind().String() < out[j].Resource().GroupVersionKind().String()
+	})
+	return out
+}
+
+// ImportOptions configure Validate, NewPlan and Apply.
+type ImportOptions struct {
+	IstioNamespace string
+	// Revision is the control plane revision whose mesh config is imported.
+	Revision string
+	// Schemas are the resource kinds supported. Defaults to the Istio resources read by Pilot.
+	Schemas collection.Schemas
+}
+
+func (o ImportOptions) schemas() collection.Schemas {
+	if len(o.Schemas.All()) == 0 {
+		return collections.Pilot
+	}
+	return o.Schemas
+}
+
+// Validate checks that the resource kinds of s are supported, and validates its resources, mesh
+// config and meshNetworks. It returns the validation warnings, and an error listing every problem.
+func Validate(s *Snapshot, opts ImportOptions) ([]string, error) {
+	schemas := opts.schemas()
+	var warnings []string
+	var errs error
+	for _, sv := range s.Manifest.Schemas {
+		if _, f := schemas.FindByGroupVersionKind(sv.GroupVersionKind); !f && sv.Resources > 0 {
+			errs = multierror.Append(errs, fmt.Errorf("%s is not supported by this version of istioctl%s",
+				sv.GroupVersionKind, supportedVersions(schemas, sv.GroupVersionKind)))
+		}
+	}
+	for _, r := range s.Resources {
+		sc, f := schemas.FindByGroupVersionKind(gvkOf(r))
+		if !f {
+			// Reported above.
+			continue
+		}
+		cfg, err := toConfig(sc, r)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", resourceKey(r), err))
+			continue
+		}
+		warn, err := sc.Resource().ValidateConfig(*cfg)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", resourceKey(r), err))
+		}
+		if warn != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", resourceKey(r), warn))
+		}
+	}
+	if s.MeshConfig != "" {
+		if _, err := mesh.ApplyMeshConfigDefaults(s.MeshConfig); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("mesh config: %v", err))
+		}
+	}
+	if s.MeshNetworks != "" {
+		if _, err := mesh.ParseMeshNetworks(s.MeshNetworks); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("meshNetworks: %v", err))
+		}
+	}
+	return warnings, errs
+}
+
+// supportedVersions returns the versions of the kind of gvk supported by schemas, for error messages.
+func supportedVersions(schemas collection.Schemas, gvk config.GroupVersionKind) string {
+	var versions []string
+	for _, sc := range schemas.All() {
+		if sgvk := sc.Resource().GroupVersionKind(); sgvk.Group == gvk.Group && sgvk.Kind == gvk.Kind {
+			versions = append(versions, sgvk.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", supported versions: %s", strings.Join(versions, ", "))
+}
+
+// toConfig converts a resource of the snapshot to the config model.
+func toConfig(sc collection.Schema, r *unstructured.Unstructured) (*config.Config, error) {
+	obj := &crd.IstioKind{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(r.Object, obj); err != nil {
+		return nil, err
+	}
+	return crd.ConvertObject(sc, obj, "")
+}
+
+// Action is what importing an object does to the cluster.
+type Action string
+
+const (
+	// Create is an object missing from the cluster.
+	Create Action = "create"
+	// Unchanged is an object of the cluster with the same configuration.
+	Unchanged Action = "unchanged"
+	// Conflict is an object of the cluster with a different configuration, which is only
+	// overwritten on request.
+	Conflict Action = "conflict"
+)
+
+// Change is the action importing a resource takes.
+type Change struct {
+	Resource *unstructured.Unstructured
+	Action   Action
+}
+
+// Plan is what importing a snapshot changes in a cluster, and the differences it does not change.
+type Plan struct {
+	// MeshConfig and MeshNetworks are empty if the snapshot has none.
+	MeshConfig   Action
+	MeshNetworks Action
+	// Changes are in the order they are applied.
+	Changes []Change
+	// MissingNamespaces are the namespaces of the snapshot resources that do not exist in the cluster.
+	MissingNamespaces []string
+	// Notes are differences import does not reconcile, like revision tags and CA certificates.
+	Notes []string
+}
+
+// Conflicts returns the number of objects of the cluster the plan overwrites.
+func (p *Plan) Conflicts() int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == Conflict {
+			n++
+		}
+	}
+	if p.MeshConfig == Conflict {
+		n++
+	}
+	if p.MeshNetworks == Conflict {
+		n++
+	}
+	return n
+}
+
+// NewPlan compares s to the cluster and returns what importing it changes.
+func NewPlan(ctx context.Context, kube kubernetes.Interface, dyn dynamic.Interface, s *Snapshot, opts ImportOptions) (*Plan, error) {
+	schemas := opts.schemas()
+	p := &Plan{}
+
+	cm, err := getConfigMap(ctx, kube, opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.MeshConfig != "" {
+		p.MeshConfig = compareConfigMapEntry(cm, meshConfigMapKey, s.MeshConfig)
+	}
+	if s.MeshNetworks != "" {
+		p.MeshNetworks = compareConfigMapEntry(cm, meshNetworksConfigMapKey, s.MeshNetworks)
+	}
+
+	resources := append([]*unstructured.Unstructured{}, s.Resources...)
+	sortResources(resources, kindOrder(schemas))
+	namespaces := map[string]bool{}
+	for _, r := range resources {
+		sc, f := schemas.FindByGroupVersionKind(gvkOf(r))
+		if !f {
+			return nil, fmt.Errorf("%s: unsupported kind %s", resourceKey(r), gvkOf(r))
+		}
+		if ns := r.GetNamespace(); ns != "" {
+			if _, checked := namespaces[ns]; !checked {
+				_, err := kube.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+				if err != nil && !errors.IsNotFound(err) {
+					return nil, err
+				}
+				namespaces[ns] = err == nil
+				if err != nil {
+					p.MissingNamespaces = append(p.MissingNamespaces, ns)
+				}
+			}
+			if !namespaces[ns] {
+				p.Changes = append(p.Changes, Change{Resource: r, Action: Create})
+				continue
+			}
+		}
+		current, err := dyn.Resource(sc.Resource().GroupVersionResource()).Namespace(r.GetNamespace()).
+			Get(ctx, r.GetName(), metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			p.Changes = append(p.Changes, Change{Resource: r, Action: Create})
+		case err != nil:
+			return nil, fmt.Errorf("failed to get %s: %v", resourceKey(r), err)
+		case sameConfiguration(r, cleanResource(current)):
+			p.Changes = append(p.Changes, Change{Resource: r, Action: Unchanged})
+		default:
+			p.Changes = append(p.Changes, Change{Resource: r, Action: Conflict})
+		}
+	}
+	sort.Strings(p.MissingNamespaces)
+
+	tagNotes, err := compareRevisionTags(ctx, kube, s.RevisionTags)
+	if err != nil {
+		return nil, err
+	}
+	p.Notes = append(p.Notes, tagNotes...)
+	caNotes, err := compareCACerts(ctx, kube, opts.IstioNamespace, s.CACerts)
+	if err != nil {
+		return nil, err
+	}
+	p.Notes = append(p.Notes, caNotes...)
+	return p, nil
+}
+
+func getConfigMap(ctx context.Context, kube kubernetes.Interface, opts ImportOptions) (*corev1.ConfigMap, error) {
+	cm, err := kube.CoreV1().ConfigMaps(opts.IstioNamespace).Get(ctx, ConfigMapName(opts.Revision), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return cm, err
+}
+
+func compareConfigMapEntry(cm *corev1.ConfigMap, key, value string) Action {
+	if cm == nil {
+		return Create
+	}
+	current, f := cm.Data[key]
+	switch {
+	case !f:
+		return Create
+	case current == value:
+		return Unchanged
+	default:
+		return Conflict
+	}
+}
+
+// sameConfiguration reports whether a and b have the same spec, labels and annotations.
+func sameConfiguration(a, b *unstructured.Unstructured) bool {
+	return reflect.DeepEqual(a.Object["spec"], b.Object["spec"]) &&
+		reflect.DeepEqual(emptyIfNil(a.GetLabels()), emptyIfNil(b.GetLabels())) &&
+		reflect.DeepEqual(emptyIfNil(a.GetAnnotations()), emptyIfNil(b.GetAnnotations()))
+}
+
+func emptyIfNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// compareRevisionTags returns the revision tags of the snapshot that differ in the cluster. Tags are
+// not imported, as their webhooks are generated for the control plane installed in the cluster.
+func compareRevisionTags(ctx context.Context, kube kubernetes.Interface, tags []RevisionTag) ([]string, error) {
+	current, err := listRevisionTags(ctx, kube)
+	if err != nil {
+		return nil, err
+	}
+	revisions := map[string]string{}
+	for _, t := range current {
+		revisions[t.Tag] = t.Revision
+	}
+	var notes []string
+	for _, t := range tags {
+		rev, f := revisions[t.Tag]
+		switch {
+		case !f:
+			notes = append(notes, fmt.Sprintf("revision tag %q pointing to revision %q does not exist, create it with "+
+				"'istioctl x tag set %s --revision <revision>'", t.Tag, t.Revision, t.Tag))
+		case rev != t.Revision:
+			notes = append(notes, fmt.Sprintf("revision tag %q points to revision %q, instead of %q in the snapshot",
+				t.Tag, rev, t.Revision))
+		}
+	}
+	return notes, nil
+}
+
+// compareCACerts returns the differences between the CA certificates of the snapshot and the cluster.
+// The cacerts Secret is not imported, as the snapshot does not hold the CA private key.
+func compareCACerts(ctx context.Context, kube kubernetes.Interface, namespace string, snapshot *CACerts) ([]string, error) {
+	current, err := getCACerts(ctx, kube, namespace)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case snapshot == nil && current == nil:
+		return nil, nil
+	case snapshot == nil:
+		return []string{fmt.Sprintf("the cluster has a %s Secret, the snapshot was taken from a cluster without one", caCertsSecretName)}, nil
+	case current == nil:
+		return []string{fmt.Sprintf("the cluster has no %s Secret: restore the plugged-in CA certificates and key "+
+			"from their backup before istiod issues workload certificates, or workloads will not trust the other clusters of the mesh",
+			caCertsSecretName)}, nil
+	}
+	fingerprints := func(c *CACerts) []string {
+		var out []string
+		for _, cert := range c.Certificates {
+			out = append(out, cert.Key+"="+cert.SHA256Fingerprint)
+		}
+		sort.Strings(out)
+		return out
+	}
+	if !reflect.DeepEqual(fingerprints(snapshot), fingerprints(current)) {
+		return []string{fmt.Sprintf("the certificates of the %s Secret differ from the snapshot", caCertsSecretName)}, nil
+	}
+	return nil, nil
+}
+
+// Apply imports the changes of p into the cluster: the mesh config first, then the resources in
+// dependency order. It fails without changing anything if namespaces are missing, or if p has
+// conflicts and overwrite is not set.
+func Apply(ctx context.Context, kube kubernetes.Interface, dyn dynamic.Interface, s *Snapshot, p *Plan,
+	opts ImportOptions, overwrite bool, w io.Writer) error {
+	if len(p.MissingNamespaces) > 0 {
+		return fmt.Errorf("namespaces %s do not exist, create them before importing", strings.Join(p.MissingNamespaces, ", "))
+	}
+	if n := p.Conflicts(); n > 0 && !overwrite {
+		return fmt.Errorf("%d objects of the cluster differ from the snapshot, set --overwrite to replace them", n)
+	}
+
+	if changed, err := applyMeshConfig(ctx, kube, s, p, opts); err != nil {
+		return err
+	} else if changed {
+		fmt.Fprintf(w, "ConfigMap %s/%s updated\n", opts.IstioNamespace, ConfigMapName(opts.Revision))
+	}
+
+	schemas := opts.schemas()
+	for _, c := range p.Changes {
+		if c.Action == Unchanged {
+			continue
+		}
+		sc, _ := schemas.FindByGroupVersionKind(gvkOf(c.Resource))
+		client := dyn.Resource(sc.Resource().GroupVersionResource()).Namespace(c.Resource.GetNamespace())
+		if c.Action == Create {
+			if _, err := client.Create(ctx, c.Resource, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to create %s: %v", resourceKey(c.Resource), err)
+			}
+			fmt.Fprintf(w, "%s created\n", resourceKey(c.Resource))
+			continue
+		}
+		current, err := client.Get(ctx, c.Resource.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %v", resourceKey(c.Resource), err)
+		}
+		updated := c.Resource.DeepCopy()
+		updated.SetResourceVersion(current.GetResourceVersion())
+		if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update %s: %v", resourceKey(c.Resource), err)
+		}
+		fmt.Fprintf(w, "%s updated\n", resourceKey(c.Resource))
+	}
+	return nil
+}
+
+// applyMeshConfig writes the mesh config and meshNetworks of s to the Istio ConfigMap, creating it if
+// needed. The other entries of the ConfigMap are kept. It reports whether the ConfigMap was changed.
+func applyMeshConfig(ctx context.Context, kube kubernetes.Interface, s *Snapshot, p *Plan, opts ImportOptions) (bool, error) {
+	if (p.MeshConfig == "" || p.MeshConfig == Unchanged) && (p.MeshNetworks == "" || p.MeshNetworks == Unchanged) {
+		return false, nil
+	}
+	cm, err := getConfigMap(ctx, kube, opts)
+	if err != nil {
+		return false, err
+	}
+	create := cm == nil
+	if create {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName(opts.Revision), Namespace: opts.IstioNamespace}}
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if s.MeshConfig != "" {
+		cm.Data[meshConfigMapKey] = s.MeshConfig
+	}
+	if s.MeshNetworks != "" {
+		cm.Data[meshNetworksConfigMapKey] = s.MeshNetworks
+	}
+	if create {
+		_, err = kube.CoreV1().ConfigMaps(opts.IstioNamespace).Create(ctx, cm, metav1.CreateOptions{})
+	} else {
+		_, err = kube.CoreV1().ConfigMaps(opts.IstioNamespace).Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write the mesh config: %v", err)
+	}
+	return true, nil
+}
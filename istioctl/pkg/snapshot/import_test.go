@@ -0,0 +1,222 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	admit_v1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func newDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, sc := range collections.Pilot.All() {
+		listKinds[sc.Resource().GroupVersionResource()] = sc.Resource().Kind() + "List"
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestExport(t *testing.T) {
+	vs := newResource("VirtualService", "default", "reviews", map[string]interface{}{
+		"hosts": []interface{}{"reviews"},
+	})
+	vs.SetResourceVersion("12")
+	vs.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Deployment", Name: "reviews"}})
+	vs.SetAnnotations(map[string]string{lastAppliedAnnotation: "{}"})
+	vs.Object["status"] = map[string]interface{}{"observedGeneration": int64(1)}
+	dr := newResource("DestinationRule", "default", "reviews", map[string]interface{}{"host": "reviews"})
+	canary := newResource("DestinationRule", "default", "canary", map[string]interface{}{"host": "reviews"})
+	canary.SetLabels(map[string]string{"istio.io/rev": "canary"})
+
+	kube := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data:       map[string]string{"mesh": "trustDomain: cluster.local\n", "meshNetworks": "networks: {}\n"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cacerts", Namespace: "istio-system"},
+			Data:       map[string][]byte{"ca-key.pem": []byte("secret"), "root-cert.pem": []byte("not a certificate")},
+		},
+		&admit_v1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+			Name:   "istio-revision-tag-prod",
+			Labels: map[string]string{"istio.io/tag": "prod", "istio.io/rev": "1-9-0"},
+		}},
+	)
+	s, err := Export(context.Background(), kube, newDynamicClient(vs, dr, canary), ExportOptions{IstioNamespace: "istio-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range s.Resources {
+		got = append(got, resourceKey(r))
+	}
+	// DestinationRules are applied before the VirtualServices referring to their subsets.
+	if want := []string{"DestinationRule default/reviews", "VirtualService default/reviews"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("exported %v, want %v", got, want)
+	}
+	exported := s.Resources[1]
+	if exported.GetResourceVersion() != "" || exported.GetOwnerReferences() != nil || exported.GetAnnotations() != nil ||
+		exported.Object["status"] != nil {
+		t.Errorf("server set fields were exported: %v", exported.Object)
+	}
+	if vs.GetResourceVersion() != "12" {
+		t.Errorf("the listed resource was modified")
+	}
+	for _, sv := range s.Manifest.Schemas {
+		want := 0
+		if sv.Kind == "VirtualService" || sv.Kind == "DestinationRule" {
+			want = 1
+		}
+		if sv.Resources != want {
+			t.Errorf("manifest lists %d %s, want %d", sv.Resources, sv.Kind, want)
+		}
+	}
+	if s.MeshConfig != "trustDomain: cluster.local\n" || s.MeshNetworks != "networks: {}\n" {
+		t.Errorf("unexpected mesh config %q and meshNetworks %q", s.MeshConfig, s.MeshNetworks)
+	}
+	if want := []RevisionTag{{Tag: "prod", Revision: "1-9-0"}}; !reflect.DeepEqual(s.RevisionTags, want) {
+		t.Errorf("revision tags = %v, want %v", s.RevisionTags, want)
+	}
+	if want := (&CACerts{Keys: []string{"ca-key.pem", "root-cert.pem"}}); !reflect.DeepEqual(s.CACerts, want) {
+		t.Errorf("cacerts = %+v, want %+v", s.CACerts, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := &Snapshot{
+		Manifest: Manifest{Schemas: []SchemaVersion{
+			{GroupVersionKind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(), Resources: 2},
+			{GroupVersionKind: config.GroupVersionKind{Group: "networking.istio.io", Version: "v2", Kind: "Gateway"}, Resources: 1},
+		}},
+		Resources: []*unstructured.Unstructured{
+			newResource("VirtualService", "default", "valid", map[string]interface{}{
+				"hosts": []interface{}{"reviews"},
+				"http":  []interface{}{map[string]interface{}{"route": []interface{}{map[string]interface{}{"destination": map[string]interface{}{"host": "reviews"}}}}},
+			}),
+			newResource("VirtualService", "default", "invalid", map[string]interface{}{}),
+		},
+		MeshConfig: "accessLogFile: [",
+	}
+	_, err := Validate(s, ImportOptions{})
+	if err == nil {
+		t.Fatal("Validate() succeeded")
+	}
+	for _, want := range []string{
+		"networking.istio.io/v2/Gateway is not supported by this version of istioctl, supported versions: v1alpha3",
+		"VirtualService default/invalid:",
+		"mesh config:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "default/valid") {
+		t.Errorf("Validate() reported the valid VirtualService: %v", err)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	dr := newResource("DestinationRule", "default", "reviews", map[string]interface{}{"host": "reviews"})
+	vs := newResource("VirtualService", "default", "reviews", map[string]interface{}{"hosts": []interface{}{"reviews"}})
+	authz := newResource("AuthorizationPolicy", "default", "deny-all", map[string]interface{}{})
+	other := newResource("VirtualService", "other", "ratings", map[string]interface{}{"hosts": []interface{}{"ratings"}})
+	s := &Snapshot{
+		Resources:    []*unstructured.Unstructured{dr, vs, authz, other},
+		MeshConfig:   "trustDomain: example.com\n",
+		RevisionTags: []RevisionTag{{Tag: "prod", Revision: "1-9-0"}},
+		CACerts:      &CACerts{Keys: []string{"ca-cert.pem"}},
+	}
+
+	changed := vs.DeepCopy()
+	changed.Object["spec"] = map[string]interface{}{"hosts": []interface{}{"details"}}
+	changed.SetResourceVersion("3")
+	kube := fake.NewSimpleClientset(namespace("default"), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+		Data:       map[string]string{"mesh": "trustDomain: example.com\n", "values": "{}"},
+	})
+	dyn := newDynamicClient(dr.DeepCopy(), changed)
+	opts := ImportOptions{IstioNamespace: "istio-system"}
+
+	p, err := NewPlan(context.Background(), kube, dyn, s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range p.Changes {
+		got = append(got, resourceKey(c.Resource)+" "+string(c.Action))
+	}
+	want := []string{
+		"AuthorizationPolicy default/deny-all create",
+		"DestinationRule default/reviews unchanged",
+		"VirtualService default/reviews conflict",
+		"VirtualService other/ratings create",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+	if p.MeshConfig != Unchanged || p.MeshNetworks != "" || p.Conflicts() != 1 {
+		t.Errorf("unexpected plan %+v", p)
+	}
+	if !reflect.DeepEqual(p.MissingNamespaces, []string{"other"}) {
+		t.Errorf("missing namespaces = %v, want [other]", p.MissingNamespaces)
+	}
+	if len(p.Notes) != 2 || !strings.Contains(p.Notes[0], `revision tag "prod"`) || !strings.Contains(p.Notes[1], "no cacerts Secret") {
+		t.Errorf("notes = %v", p.Notes)
+	}
+
+	out := &bytes.Buffer{}
+	if err := Apply(context.Background(), kube, dyn, s, p, opts, true, out); err == nil || !strings.Contains(err.Error(), "other") {
+		t.Fatalf("Apply() error = %v, want missing namespace", err)
+	}
+	if _, err := kube.CoreV1().Namespaces().Create(context.Background(), namespace("other"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = NewPlan(context.Background(), kube, dyn, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(context.Background(), kube, dyn, s, p, opts, false, out); err == nil || !strings.Contains(err.Error(), "--overwrite") {
+		t.Fatalf("Apply() error = %v, want a conflict", err)
+	}
+	if err := Apply(context.Background(), kube, dyn, s, p, opts, true, out); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = NewPlan(context.Background(), kube, dyn, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range p.Changes {
+		if c.Action != Unchanged {
+			t.Errorf("%s not imported: %s", resourceKey(c.Resource), c.Action)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot exports the mesh-wide Istio configuration of a cluster to an archive, and
+// validates and imports it into a cluster, e.g. to recover from the loss of a cluster.
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config"
+)
+
+// FormatVersion is the version of the archive layout. Archives of a newer version are rejected.
+const FormatVersion = 1
+
+// Files of the archive.
+const (
+	manifestFile     = "manifest.json"
+	meshConfigFile   = "mesh/mesh.yaml"
+	meshNetworksFile = "mesh/meshNetworks.yaml"
+	revisionTagsFile = "revision-tags.json"
+	caCertsFile      = "cacerts.json"
+	resourcesDir     = "resources"
+)
+
+// Manifest describes the contents of a snapshot.
+type Manifest struct {
+	FormatVersion   int       `json:"formatVersion"`
+	IstioctlVersion string    `json:"istioctlVersion,omitempty"`
+	CreationTime    time.Time `json:"creationTime"`
+	IstioNamespace  string    `json:"istioNamespace"`
+	Revision        string    `json:"revision,omitempty"`
+	// Schemas are the versions of the resource kinds in the snapshot, as known to pkg/config/schema
+	// when it was exported.
+	Schemas []SchemaVersion `json:"schemas"`
+}
+
+// SchemaVersion is the version of a resource kind in the snapshot, and how many resources of it
+// were exported.
+type SchemaVersion struct {
+	config.GroupVersionKind `json:",inline"`
+	Resources               int `json:"resources"`
+}
+
+// RevisionTag is a revision tag and the control plane revision it points to.
+type RevisionTag struct {
+	Tag      string `json:"tag"`
+	Revision string `json:"revision"`
+}
+
+// CACerts is the metadata of the plugged-in CA certificates of the cacerts Secret. The contents of
+// the Secret, in particular the CA private key, are never exported.
+type CACerts struct {
+	// Keys are the entries of the Secret.
+	Keys         []string              `json:"keys"`
+	Certificates []CertificateMetadata `json:"certificates,omitempty"`
+}
+
+// CertificateMetadata identifies a certificate of the cacerts Secret.
+type CertificateMetadata struct {
+	// Key is the entry of the Secret holding the certificate, e.g. ca-cert.pem.
+	Key               string    `json:"key"`
+	Subject           string    `json:"subject"`
+	Issuer            string    `json:"issuer"`
+	NotBefore         time.Time `json:"notBefore"`
+	NotAfter          time.Time `json:"notAfter"`
+	SHA256Fingerprint string    `json:"sha256Fingerprint"`
+}
+
+// Snapshot is the mesh-wide Istio configuration of a cluster.
+type Snapshot struct {
+	Manifest Manifest
+	// Resources are the Istio resources, without their status and server set metadata.
+	Resources []*unstructured.Unstructured
+	// MeshConfig and MeshNetworks are the mesh and meshNetworks entries of the Istio ConfigMap.
+	MeshConfig   string
+	MeshNetworks string
+	RevisionTags []RevisionTag
+	// CACerts is nil if the cluster has no cacerts Secret.
+	CACerts *CACerts
+}
+
+// Write writes s as a gzipped tar archive to w.
+func (s *Snapshot) Write(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	add := func(name string, b []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(b)),
+			ModTime: s.Manifest.CreationTime,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(b)
+		return err
+	}
+	addJSON := func(name string, v interface{}) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, b)
+	}
+
+	if err := addJSON(manifestFile, s.Manifest); err != nil {
+		return err
+	}
+	if s.MeshConfig != "" {
+		if err := add(meshConfigFile, []byte(s.MeshConfig)); err != nil {
+			return err
+		}
+	}
+	if s.MeshNetworks != "" {
+		if err := add(meshNetworksFile, []byte(s.MeshNetworks)); err != nil {
+			return err
+		}
+	}
+	if err := addJSON(revisionTagsFile, s.RevisionTags); err != nil {
+		return err
+	}
+	if s.CACerts != nil {
+		if err := addJSON(caCertsFile, s.CACerts); err != nil {
+			return err
+		}
+	}
+
+	// One multi-document file per kind, in the order of the manifest.
+	byKind := map[config.GroupVersionKind][]string{}
+	for _, r := range s.Resources {
+		b, err := yaml.Marshal(r.Object)
+		if err != nil {
+			return err
+		}
+		gvk := gvkOf(r)
+		byKind[gvk] = append(byKind[gvk], string(b))
+	}
+	for _, sv := range s.Manifest.Schemas {
+		docs := byKind[sv.GroupVersionKind]
+		if len(docs) == 0 {
+			continue
+		}
+		if err := add(resourceFile(sv.GroupVersionKind), []byte(strings.Join(docs, "---\n"))); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Read reads a snapshot written by Write from r.
+func Read(r io.Reader) (*Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %v", err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(h.Name)] = b
+	}
+
+	s := &Snapshot{}
+	b, f := files[manifestFile]
+	if !f {
+		return nil, fmt.Errorf("not a snapshot archive: %s is missing", manifestFile)
+	}
+	if err := json.Unmarshal(b, &s.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", manifestFile, err)
+	}
+	if s.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("snapshot format version %d is not supported, upgrade istioctl to a version supporting it",
+			s.Manifest.FormatVersion)
+	}
+	s.MeshConfig = string(files[meshConfigFile])
+	s.MeshNetworks = string(files[meshNetworksFile])
+	if b, f := files[revisionTagsFile]; f {
+		if err := json.Unmarshal(b, &s.RevisionTags); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", revisionTagsFile, err)
+		}
+	}
+	if b, f := files[caCertsFile]; f {
+		s.CACerts = &CACerts{}
+		if err := json.Unmarshal(b, s.CACerts); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", caCertsFile, err)
+		}
+	}
+
+	for _, sv := range s.Manifest.Schemas {
+		name := resourceFile(sv.GroupVersionKind)
+		b, f := files[name]
+		if !f {
+			if sv.Resources > 0 {
+				return nil, fmt.Errorf("%s is missing", name)
+			}
+			continue
+		}
+		resources, err := decodeResources(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		if len(resources) != sv.Resources {
+			return nil, fmt.Errorf("%s holds %d resources, the manifest lists %d", name, len(resources), sv.Resources)
+		}
+		s.Resources = append(s.Resources, resources...)
+	}
+	return s, nil
+}
+
+// decodeResources decodes the resources of a multi-document YAML file. They are decoded as the
+// dynamic client does, with integers as int64, so that they compare equal to the cluster resources.
+func decodeResources(b []byte) ([]*unstructured.Unstructured, error) {
+	var out []*unstructured.Unstructured
+	d := kubeyaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 512*1024)
+	for {
+		raw := json.RawMessage{}
+		if err := d.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw)) == 0 || string(raw) == "null" {
+			continue
+		}
+		r := &unstructured.Unstructured{}
+		if err := r.UnmarshalJSON(raw); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// resourceFile returns the archive file holding the resources of gvk.
+func resourceFile(gvk config.GroupVersionKind) string {
+	return path.Join(resourcesDir, gvk.CanonicalGroup(), gvk.Version, gvk.Kind+".yaml")
+}
+
+func gvkOf(r *unstructured.Unstructured) config.GroupVersionKind {
+	gvk := r.GroupVersionKind()
+	return config.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+}
+
+// resourceKey identifies a resource in messages, e.g. VirtualService default/reviews.
+func resourceKey(r *unstructured.Unstructured) string {
+	if r.GetNamespace() == "" {
+		return r.GetKind() + " " + r.GetName()
+	}
+	return r.GetKind() + " " + r.GetNamespace() + "/" + r.GetName()
+}
+
+// sortResources sorts resources by namespace and name, keeping kinds grouped in their order.
+func sortResources(resources []*unstructured.Unstructured, kindOrder func(config.GroupVersionKind) int) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if ka, kb := kindOrder(gvkOf(a)), kindOrder(gvkOf(b)); ka != kb {
+			return ka < kb
+		}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func newResource(kind, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	apiVersion := "networking.istio.io/v1alpha3"
+	if kind == "AuthorizationPolicy" {
+		apiVersion = "security.istio.io/v1beta1"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}}
+}
+
+func TestWriteRead(t *testing.T) {
+	vs := newResource("VirtualService", "default", "reviews", map[string]interface{}{
+		"hosts": []interface{}{"reviews"},
+	})
+	dr := newResource("DestinationRule", "default", "reviews", map[string]interface{}{
+		"host": "reviews",
+	})
+	want := &Snapshot{
+		Manifest: Manifest{
+			FormatVersion:  FormatVersion,
+			CreationTime:   time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			IstioNamespace: "istio-system",
+			Schemas: []SchemaVersion{
+				{GroupVersionKind: collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind(), Resources: 1},
+				{GroupVersionKind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(), Resources: 1},
+				{GroupVersionKind: collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind()},
+			},
+		},
+		Resources:    []*unstructured.Unstructured{dr, vs},
+		MeshConfig:   "trustDomain: cluster.local\n",
+		RevisionTags: []RevisionTag{{Tag: "prod", Revision: "1-9-0"}},
+		CACerts:      &CACerts{Keys: []string{"ca-cert.pem", "ca-key.pem"}},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := want.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		snapshot *Snapshot
+		want     string
+	}{
+		{
+			name:     "newer format",
+			snapshot: &Snapshot{Manifest: Manifest{FormatVersion: FormatVersion + 1}},
+			want:     "is not supported",
+		},
+		{
+			name: "missing resources",
+			snapshot: &Snapshot{Manifest: Manifest{
+				FormatVersion: FormatVersion,
+				Schemas: []SchemaVersion{
+					{GroupVersionKind: collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind(), Resources: 2},
+				},
+			}},
+			want: "Gateway.yaml is missing",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := tt.snapshot.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Read(buf); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Read(strings.NewReader("not an archive")); err == nil {
+		t.Errorf("Read() of invalid archive succeeded")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -30,13 +31,15 @@ import (
 
 // Formatting options for Messages
 const (
-	LogFormat  = "log"
-	JSONFormat = "json"
-	YAMLFormat = "yaml"
+	LogFormat   = "log"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+	SARIFFormat = "sarif"
+	JUnitFormat = "junit"
 )
 
 var (
-	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat}
+	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat, SARIFFormat, JUnitFormat}
 	MsgOutputFormats    = make(map[string]bool)
 	termEnvVar          = env.RegisterStringVar("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 )
@@ -56,6 +59,10 @@ func Print(ms diag.Messages, format string, colorize bool) (string, error) {
 		return printJSON(ms)
 	case YAMLFormat:
 		return printYAML(ms)
+	case SARIFFormat:
+		return printSARIF(ms)
+	case JUnitFormat:
+		return printJUnit(ms)
 	default:
 		return "", fmt.Errorf("invalid format, expected one of %v but got %q", MsgOutputFormatKeys, format)
 	}
@@ -79,6 +86,23 @@ func printYAML(ms diag.Messages) (string, error) {
 	return string(yamlOutput), err
 }
 
+// location returns the file and line a message refers to, for resources read from files. The line is 0 if unknown.
+func location(m diag.Message) (string, int) {
+	if m.Resource == nil || m.Resource.Origin == nil || m.Resource.Origin.Reference() == nil {
+		return "", 0
+	}
+	ref := m.Resource.Origin.Reference().String()
+	if m.Line != 0 {
+		ref = m.ReplaceLine(ref)
+	}
+	if i := strings.LastIndex(ref, ":"); i > 0 {
+		if line, err := strconv.Atoi(ref[i+1:]); err == nil {
+			return ref[:i], line
+		}
+	}
+	return ref, 0
+}
+
 // Formatting options for Message
 var (
 	colorPrefixes = map[diag.Level]string{
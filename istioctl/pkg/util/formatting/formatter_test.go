@@ -20,6 +20,8 @@ import (
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/url"
 )
 
@@ -152,3 +154,171 @@ func TestFormatter_PrintEmpty(t *testing.T) {
 	yamlOutput, _ := Print(msgs, YAMLFormat, false)
 	g.Expect(yamlOutput).To(Equal("[]\n"))
 }
+
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	msgs := diag.Messages{
+		withLine(msg.NewReferencedResourceNotFound(fileResource("VirtualService reviews.default", "vs.yaml:3"),
+			"host", "reviews"), 9),
+		diag.NewMessage(
+			diag.NewMessageType(diag.Warning, "C1", "Collapse danger: %v"),
+			diag.MockResource("GrandCastle"),
+			"the castle is too old",
+		),
+	}
+	output, err := Print(msgs, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	expectedOutput := `{
+  "$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "istioctl",
+          "informationUri": "` + url.ConfigAnalysis + `",
+          "rules": [
+            {
+              "id": "IST0101",
+              "name": "ReferencedResourceNotFound",
+              "shortDescription": {
+                "text": "A resource being referenced does not exist."
+              },
+              "helpUri": "` + url.ConfigAnalysis + `/ist0101/",
+              "defaultConfiguration": {
+                "level": "error"
+              }
+            },
+            {
+              "id": "C1",
+              "shortDescription": {
+                "text": "Collapse danger: %v"
+              },
+              "helpUri": "` + url.ConfigAnalysis + `/c1/",
+              "defaultConfiguration": {
+                "level": "warning"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "IST0101",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "Referenced host not found: \"reviews\""
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "vs.yaml"
+                },
+                "region": {
+                  "startLine": 9
+                }
+              },
+              "logicalLocations": [
+                {
+                  "fullyQualifiedName": "VirtualService reviews.default"
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "ruleId": "C1",
+          "ruleIndex": 1,
+          "level": "warning",
+          "message": {
+            "text": "Collapse danger: the castle is too old"
+          },
+          "locations": [
+            {
+              "logicalLocations": [
+                {
+                  "fullyQualifiedName": "GrandCastle"
+                }
+              ]
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+	g.Expect(output).To(Equal(expectedOutput))
+}
+
+func TestFormatter_PrintJUnit(t *testing.T) {
+	g := NewWithT(t)
+
+	msgs := diag.Messages{
+		withLine(msg.NewReferencedResourceNotFound(fileResource("VirtualService reviews.default", "vs.yaml:3"),
+			"host", "reviews"), 9),
+		diag.NewMessage(
+			diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+			diag.MockResource("GrandCastle"),
+			"the castle is too old",
+		),
+	}
+	output, err := Print(msgs, JUnitFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	expectedOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="istioctl" tests="2" failures="1">
+  <testsuite name="IST0101 ReferencedResourceNotFound" tests="1" failures="1">
+    <testcase name="VirtualService reviews.default" classname="vs.yaml:9">
+      <failure message="Referenced host not found: &#34;reviews&#34;" type="Error">Error [IST0101] (VirtualService reviews.default vs.yaml:9) Referenced host not found: &#34;reviews&#34;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="C1" tests="1" failures="0">
+    <testcase name="GrandCastle" classname="C1">
+      <system-out>Info [C1] (GrandCastle) Collapse danger: the castle is too old</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+	g.Expect(output).To(Equal(expectedOutput))
+}
+
+func TestFormatter_PrintEmptyMachineReadable(t *testing.T) {
+	g := NewWithT(t)
+
+	sarifOutput, err := Print(diag.Messages{}, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sarifOutput).To(ContainSubstring(`"results": []`))
+
+	junitOutput, err := Print(diag.Messages{}, JUnitFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(junitOutput).To(ContainSubstring(`<testsuites name="istioctl" tests="0" failures="0"></testsuites>`))
+}
+
+func withLine(m diag.Message, line int) diag.Message {
+	m.Line = line
+	return m
+}
+
+func fileResource(name, ref string) *resource.Instance {
+	return &resource.Instance{Origin: fileOrigin{name: name, ref: ref}}
+}
+
+type fileOrigin struct {
+	name string
+	ref  string
+}
+
+func (o fileOrigin) FriendlyName() string          { return o.name }
+func (o fileOrigin) Comparator() string            { return o.name }
+func (o fileOrigin) Namespace() resource.Namespace { return "" }
+func (o fileOrigin) Reference() resource.Reference { return fileReference(o.ref) }
+func (o fileOrigin) FieldMap() map[string]int      { return nil }
+
+type fileReference string
+
+func (r fileReference) String() string { return string(r) }
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// printJUnit reports messages as JUnit XML, with a test suite for each message code and a test case for each
+// message. Error and Warning messages are reported as failures, Info messages as passing test cases.
+func printJUnit(ms diag.Messages) (string, error) {
+	result := junitTestSuites{Name: "istioctl", Suites: []junitTestSuite{}}
+	suiteIndex := map[string]int{}
+	for _, m := range ms {
+		code := m.Type.Code()
+		idx, ok := suiteIndex[code]
+		if !ok {
+			name := code
+			if d, ok := msg.Describe(code); ok {
+				name = fmt.Sprintf("%s %s", code, d.Name)
+			}
+			idx = len(result.Suites)
+			suiteIndex[code] = idx
+			result.Suites = append(result.Suites, junitTestSuite{Name: name})
+		}
+		suite := &result.Suites[idx]
+
+		text := fmt.Sprintf(m.Type.Template(), m.Parameters...)
+		tc := junitTestCase{Name: code, ClassName: code}
+		if m.Resource != nil {
+			tc.Name = m.Resource.Origin.FriendlyName()
+			if file, line := location(m); file != "" {
+				tc.ClassName = file
+				if line > 0 {
+					tc.ClassName = fmt.Sprintf("%s:%d", file, line)
+				}
+			}
+		}
+		if m.Type.Level().IsWorseThanOrEqualTo(diag.Warning) {
+			tc.Failure = &junitFailure{
+				Message: text,
+				Type:    m.Type.Level().String(),
+				Body:    render(m, false),
+			}
+			suite.Failures++
+			result.Failures++
+		} else {
+			tc.SystemOut = render(m, false)
+		}
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		result.Tests++
+	}
+
+	out, err := xml.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out), nil
+}
@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/url"
+)
+
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+	sarifToolName = "istioctl"
+)
+
+// The subset of the SARIF 2.1.0 object model used to report messages.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name,omitempty"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	HelpURI              string             `json:"helpUri"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// sarifLevels maps message levels to SARIF result levels.
+var sarifLevels = map[diag.Level]string{
+	diag.Info:    "note",
+	diag.Warning: "warning",
+	diag.Error:   "error",
+}
+
+func printSARIF(ms diag.Messages) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           sarifToolName,
+			InformationURI: url.ConfigAnalysis,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := map[string]int{}
+	for _, m := range ms {
+		code := m.Type.Code()
+		idx, ok := ruleIndex[code]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[code] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(m.Type))
+		}
+
+		result := sarifResult{
+			RuleID:    code,
+			RuleIndex: idx,
+			Level:     sarifLevels[m.Type.Level()],
+			Message:   sarifText{Text: fmt.Sprintf(m.Type.Template(), m.Parameters...)},
+		}
+		if m.Resource != nil {
+			loc := sarifLocation{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: m.Resource.Origin.FriendlyName()}},
+			}
+			if file, line := location(m); file != "" {
+				loc.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: file}}
+				if line > 0 {
+					loc.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+				}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	out, err := json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	return string(out), err
+}
+
+func sarifRuleFor(t *diag.MessageType) sarifRule {
+	r := sarifRule{
+		ID:                   t.Code(),
+		ShortDescription:     sarifText{Text: t.Template()},
+		HelpURI:              fmt.Sprintf("%s/%s/", url.ConfigAnalysis, strings.ToLower(t.Code())),
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevels[t.Level()]},
+	}
+	if d, ok := msg.Describe(t.Code()); ok {
+		r.Name = d.Name
+		r.ShortDescription.Text = d.Description
+	}
+	return r
+}
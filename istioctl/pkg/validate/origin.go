@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/galley/pkg/config/source/kube/inmemory"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+// fileOrigin is the origin of a document of a validated file.
+type fileOrigin struct {
+	kind      string
+	name      string
+	namespace string
+	pos       *rt.Position
+	fieldMap  map[string]int
+}
+
+var _ resource.Origin = &fileOrigin{}
+
+// FriendlyName implements resource.Origin
+func (o *fileOrigin) FriendlyName() string {
+	switch {
+	case o.kind == "":
+		return o.pos.Filename
+	case o.namespace == "":
+		return fmt.Sprintf("%s %s", o.kind, o.name)
+	default:
+		// The istioctl convention is <type> <name>[.<namespace>].
+		return fmt.Sprintf("%s %s.%s", o.kind, o.name, o.namespace)
+	}
+}
+
+// Comparator implements resource.Origin
+func (o *fileOrigin) Comparator() string {
+	return o.pos.String() + "/" + o.kind + "/" + o.name + "/" + o.namespace
+}
+
+// Namespace implements resource.Origin
+func (o *fileOrigin) Namespace() resource.Namespace {
+	return resource.Namespace(o.namespace)
+}
+
+// Reference implements resource.Origin
+func (o *fileOrigin) Reference() resource.Reference {
+	return o.pos
+}
+
+// FieldMap implements resource.Origin
+func (o *fileOrigin) FieldMap() map[string]int {
+	return o.fieldMap
+}
+
+// newFileResource returns the resource the errors and warnings of a document starting at the given line of a file are
+// reported for. The document is used to locate its fields, and may be nil along with the object if it can't be parsed.
+func newFileResource(filename string, line int, doc []byte, un *unstructured.Unstructured) *resource.Instance {
+	o := &fileOrigin{
+		pos:      &rt.Position{Filename: filename, Line: line},
+		fieldMap: map[string]int{},
+	}
+	if un != nil {
+		o.kind, o.name, o.namespace = un.GetKind(), un.GetName(), un.GetNamespace()
+	}
+	if doc != nil {
+		node := yamlv3.Node{}
+		if err := yamlv3.Unmarshal(doc, &node); err == nil && len(node.Content) == 1 {
+			inmemory.BuildFieldPathMap(node.Content[0], line, "", o.fieldMap)
+		}
+	}
+	return &resource.Instance{
+		Metadata: resource.Metadata{
+			FullName: resource.FullName{Namespace: resource.Namespace(o.namespace), Name: resource.LocalName(o.name)},
+		},
+		Origin: o,
+	}
+}
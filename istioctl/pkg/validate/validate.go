@@ -15,6 +15,7 @@
 package validate
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,6 +30,11 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	analyzerutil "istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/util/kubeyaml"
+	"istio.io/istio/istioctl/pkg/util/formatting"
 	operator_istio "istio.io/istio/operator/pkg/apis/istio"
 	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/util"
@@ -63,7 +69,10 @@ Example resource specifications include:
 	serviceProtocolUDP = "UDP"
 )
 
-type validator struct{}
+type validator struct {
+	// messages are the errors and warnings found, reported when an output format other than log is requested.
+	messages diag.Messages
+}
 
 func checkFields(un *unstructured.Unstructured) error {
 	var errs error
@@ -231,20 +240,24 @@ func GetTemplateLabels(u *unstructured.Unstructured) (map[string]string, error)
 	return nil, nil
 }
 
-func (v *validator) validateFile(istioNamespace *string, defaultNamespace string, reader io.Reader, writer io.Writer) (validation.Warning, error) {
-	decoder := yaml.NewDecoder(reader)
-	decoder.SetStrict(true)
+func (v *validator) validateFile(filename string, istioNamespace *string, defaultNamespace string, reader io.Reader,
+	writer io.Writer) (validation.Warning, error) {
+	yamlReader := kubeyaml.NewYAMLReader(bufio.NewReader(reader))
 	var errs error
 	var warnings validation.Warning
 	for {
-		// YAML allows non-string keys and the produces generic keys for nested fields
-		raw := make(map[interface{}]interface{})
-		err := decoder.Decode(&raw)
+		doc, lineNum, err := yamlReader.Read()
 		if err == io.EOF {
 			return warnings, errs
 		}
+		// YAML allows non-string keys and the produces generic keys for nested fields
+		raw := make(map[interface{}]interface{})
+		if err == nil {
+			err = yaml.UnmarshalStrict(doc, &raw)
+		}
 		if err != nil {
 			errs = multierror.Append(errs, err)
+			v.report(msg.NewSchemaValidationError(newFileResource(filename, lineNum, nil, nil), err))
 			return warnings, errs
 		}
 		if len(raw) == 0 {
@@ -253,18 +266,46 @@ func (v *validator) validateFile(istioNamespace *string, defaultNamespace string
 		out := transformInterfaceMap(raw)
 		un := unstructured.Unstructured{Object: out}
 		warning, err := v.validateResource(*istioNamespace, defaultNamespace, &un, writer)
+		r := newFileResource(filename, lineNum, doc, &un)
 		if err != nil {
 			errs = multierror.Append(errs, multierror.Prefix(err, fmt.Sprintf("%s/%s/%s:",
 				un.GetKind(), un.GetNamespace(), un.GetName())))
+			for _, e := range flatten(err) {
+				v.report(msg.NewSchemaValidationError(r, e))
+			}
 		}
 		if warning != nil {
 			warnings = multierror.Append(warnings, multierror.Prefix(warning, fmt.Sprintf("%s/%s/%s:",
 				un.GetKind(), un.GetNamespace(), un.GetName())))
+			for _, w := range flatten(warning) {
+				v.report(msg.NewSchemaWarning(r, w))
+			}
+		}
+	}
+}
+
+// report records a message, positioned at the name of its resource when it is known.
+func (v *validator) report(m diag.Message) {
+	if m.Resource != nil {
+		if line, ok := analyzerutil.ErrorLine(m.Resource, analyzerutil.MetadataName); ok {
+			m.Line = line
 		}
 	}
+	v.messages.Add(m)
 }
 
-func validateFiles(istioNamespace *string, defaultNamespace string, filenames []string, writer io.Writer) error {
+// flatten returns the errors wrapped by a multierror, or the error itself.
+func flatten(err error) []error {
+	if me, ok := err.(*multierror.Error); ok {
+		return me.WrappedErrors()
+	}
+	return []error{err}
+}
+
+// validateFiles validates the given files, writing the results in the given format. Results in the log format are written
+// to writer, along with the notices of the validation, while results in the other formats are written to out.
+func validateFiles(istioNamespace *string, defaultNamespace string, filenames []string, outputFormat string,
+	writer, out io.Writer) error {
 	if len(filenames) == 0 {
 		return errMissingFilename
 	}
@@ -282,15 +323,28 @@ func validateFiles(istioNamespace *string, defaultNamespace string, filenames []
 		}
 		if err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("cannot read file %q: %v", filename, err))
+			v.report(msg.NewSchemaValidationError(newFileResource(filename, 0, nil, nil), err))
 			continue
 		}
-		warning, err := v.validateFile(istioNamespace, defaultNamespace, reader, writer)
+		warning, err := v.validateFile(filename, istioNamespace, defaultNamespace, reader, writer)
 		if err != nil {
 			errs = multierror.Append(errs, err)
 		}
 		warningsByFilename[filename] = warning
 	}
 
+	if outputFormat != formatting.LogFormat {
+		output, err := formatting.Print(v.messages, outputFormat, false)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out, output)
+		if errs != nil {
+			return errors.New("validation failed")
+		}
+		return nil
+	}
+
 	if errs != nil {
 		// Display warnings we encountered as well
 		for _, fname := range filenames {
@@ -329,6 +383,7 @@ func validateFiles(istioNamespace *string, defaultNamespace string, filenames []
 func NewValidateCommand(istioNamespace *string, defaultNamespace *string) *cobra.Command {
 	var filenames []string
 	var referential bool
+	var outputFormat string
 
 	c := &cobra.Command{
 		Use:     "validate -f FILENAME [options]",
@@ -346,18 +401,27 @@ func NewValidateCommand(istioNamespace *string, defaultNamespace *string) *cobra
   # Validate current services under 'default' namespace within the cluster
   kubectl get services -o yaml | istioctl validate -f -
 
+  # Validate bookinfo-gateway.yaml and report the results in SARIF
+  istioctl validate -f samples/bookinfo/networking/bookinfo-gateway.yaml -o sarif
+
   # Also see the related command 'istioctl analyze'
   istioctl analyze samples/bookinfo/networking/bookinfo-gateway.yaml
 `,
 		Args: cobra.NoArgs,
 		RunE: func(c *cobra.Command, _ []string) error {
-			return validateFiles(istioNamespace, *defaultNamespace, filenames, c.OutOrStderr())
+			outputFormat = strings.ToLower(outputFormat)
+			if !formatting.MsgOutputFormats[outputFormat] {
+				return fmt.Errorf("%s not a valid option for format, expected one of %v", outputFormat, formatting.MsgOutputFormatKeys)
+			}
+			return validateFiles(istioNamespace, *defaultNamespace, filenames, outputFormat, c.OutOrStderr(), c.OutOrStdout())
 		},
 	}
 
 	flags := c.PersistentFlags()
 	flags.StringSliceVarP(&filenames, "filename", "f", nil, "Names of files to validate")
 	flags.BoolVarP(&referential, "referential", "x", true, "Enable structural validation for policy and telemetry")
+	flags.StringVarP(&outputFormat, "output", "o", formatting.LogFormat,
+		fmt.Sprintf("Output format: one of %v", formatting.MsgOutputFormatKeys))
 
 	return c
 }
@@ -610,6 +610,23 @@ Error: 1 error occurred:
 	\* VirtualService//invalid-virtual-service: total destination weight 90 != 100`),
 			wantError: true,
 		},
+		{
+			name:           "sarif output",
+			args:           []string{"--filename", warningFilename, "--output", "sarif"},
+			expectedRegexp: regexp.MustCompile(`(?s)"ruleId": "IST0106".*"uri": ".*TestValidateCommand.*"startLine": 4.*"ruleId": "IST0133"`),
+			wantError:      true,
+		},
+		{
+			name:           "junit output",
+			args:           []string{"--filename", validFilename, "-o", "junit"},
+			expectedRegexp: regexp.MustCompile(`<testsuites name="istioctl" tests="0" failures="0"></testsuites>`),
+			wantError:      false,
+		},
+		{
+			name:      "invalid output format",
+			args:      []string{"--filename", validFilename, "-o", "html"},
+			wantError: true,
+		},
 	}
 	istioNamespace := "istio-system"
 	defaultNamespace := ""
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"istio.io/istio/pkg/kube/secretcontroller"
+)
+
+// ClusterStatusWriter enables printing of remote cluster health using multiple []byte Istiod clusterz responses
+type ClusterStatusWriter struct {
+	Writer io.Writer
+	// Now is used to tell expired credentials. Defaults to time.Now.
+	Now func() time.Time
+}
+
+type clusterWriterStatus struct {
+	pilot string
+	secretcontroller.ClusterStatus
+}
+
+// PrintAll takes a slice of Pilot clusterz responses and outputs them using a tabwriter
+func (s *ClusterStatusWriter) PrintAll(statuses map[string][]byte) error {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CLUSTER\tSECRET\tSYNCED\tHEALTHY\tCREDENTIALS EXPIRY\tLAST ERROR\tISTIOD")
+	fullStatus := make([]*clusterWriterStatus, 0, len(statuses))
+	for pilot, status := range statuses {
+		var ss []*clusterWriterStatus
+		if err := json.Unmarshal(status, &ss); err != nil {
+			return fmt.Errorf("failed to parse clusterz response of %s: %v", pilot, err)
+		}
+		for _, s := range ss {
+			s.pilot = pilot
+		}
+		fullStatus = append(fullStatus, ss...)
+	}
+	sort.Slice(fullStatus, func(i, j int) bool {
+		if fullStatus[i].ID != fullStatus[j].ID {
+			return fullStatus[i].ID < fullStatus[j].ID
+		}
+		return fullStatus[i].pilot < fullStatus[j].pilot
+	})
+	for _, status := range fullStatus {
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			status.ID, status.SecretName, status.Synced, healthStatus(status.ClusterStatus),
+			credentialsExpiry(status.CredentialsExpiry, now), lastError(status.ClusterStatus), status.pilot)
+	}
+	return w.Flush()
+}
+
+func healthStatus(status secretcontroller.ClusterStatus) string {
+	if status.LastHealthCheckTime.IsZero() {
+		return "UNKNOWN"
+	}
+	if status.Healthy {
+		return "HEALTHY"
+	}
+	return "UNHEALTHY"
+}
+
+func credentialsExpiry(expiry, now time.Time) string {
+	if expiry.IsZero() {
+		return "-"
+	}
+	if expiry.Before(now) {
+		return "EXPIRED"
+	}
+	return expiry.UTC().Format(time.RFC3339)
+}
+
+func lastError(status secretcontroller.ClusterStatus) string {
+	if status.LastError == "" {
+		return "-"
+	}
+	return status.LastError
+}
@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/kube/secretcontroller"
+)
+
+func TestClusterStatusWriter_PrintAll(t *testing.T) {
+	now := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	istiod1 := []secretcontroller.ClusterStatus{
+		{
+			ID:                  "remote-b",
+			SecretName:          "istio-remote-secret-b",
+			Synced:              true,
+			Healthy:             false,
+			LastHealthCheckTime: now,
+			CredentialsExpiry:   now.Add(-time.Hour),
+			LastError:           "Unauthorized",
+			LastErrorTime:       now,
+		},
+		{
+			ID:                  "remote-a",
+			SecretName:          "istio-remote-secret-a",
+			Synced:              true,
+			Healthy:             true,
+			LastHealthCheckTime: now,
+			CredentialsExpiry:   now.Add(24 * time.Hour),
+		},
+	}
+	istiod2 := []secretcontroller.ClusterStatus{
+		{
+			ID:         "remote-a",
+			SecretName: "istio-remote-secret-a",
+		},
+	}
+	marshal := func(s []secretcontroller.ClusterStatus) []byte {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	got := &bytes.Buffer{}
+	sw := ClusterStatusWriter{Writer: got, Now: func() time.Time { return now }}
+	if err := sw.PrintAll(map[string][]byte{"istiod-1": marshal(istiod1), "istiod-2": marshal(istiod2)}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(got.String()), "\n")
+	want := [][]string{
+		{"CLUSTER", "SECRET", "SYNCED", "HEALTHY", "CREDENTIALS", "EXPIRY", "LAST", "ERROR", "ISTIOD"},
+		{"remote-a", "istio-remote-secret-a", "true", "HEALTHY", "2021-04-02T00:00:00Z", "-", "istiod-1"},
+		{"remote-a", "istio-remote-secret-a", "false", "UNKNOWN", "-", "-", "istiod-2"},
+		{"remote-b", "istio-remote-secret-b", "true", "UNHEALTHY", "EXPIRED", "Unauthorized", "istiod-1"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), got.String())
+	}
+	for i, line := range lines {
+		if fields := strings.Fields(line); strings.Join(fields, " ") != strings.Join(want[i], " ") {
+			t.Errorf("line %d: got %q, want %q", i, fields, want[i])
+		}
+	}
+
+	if err := sw.PrintAll(map[string][]byte{"istiod-1": []byte("not json")}); err == nil {
+		t.Errorf("expected error for invalid response")
+	}
+}
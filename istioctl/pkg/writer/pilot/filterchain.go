@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// FilterChainWriter enables printing of the HTTP filter chains of a proxy using Istiod filterchainz responses
+type FilterChainWriter struct {
+	Writer io.Writer
+}
+
+// PrintAll prints the filter chains from the response of the Istiod the proxy is connected to. The other
+// Istiods respond with an error, which is only reported if no Istiod knows the proxy.
+func (s *FilterChainWriter) PrintAll(responses map[string]*xdsapi.DiscoveryResponse) error {
+	var chains []xds.FilterChainDebug
+	var errs []string
+	istiods := make([]string, 0, len(responses))
+	for istiod := range responses {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	for _, istiod := range istiods {
+		for _, resource := range responses[istiod].Resources {
+			if err := json.Unmarshal(resource.Value, &chains); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", istiod, resource.Value))
+				continue
+			}
+			return s.print(chains)
+		}
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no filter chains found (checked %d istiods)", len(responses))
+	}
+	return fmt.Errorf("no filter chains found:\n%s", strings.Join(errs, "\n"))
+}
+
+func (s *FilterChainWriter) print(chains []xds.FilterChainDebug) error {
+	w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "LISTENER\tFILTER CHAIN\tHTTP FILTER\tPHASE\tPRIORITY\tSOURCE")
+	for _, fc := range chains {
+		for _, f := range fc.HTTPFilters {
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				fc.Listener, orDash(fc.FilterChain), f.Name, orDash(f.Phase), f.Priority, f.Source)
+		}
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+func TestFilterChainWriter_PrintAll(t *testing.T) {
+	chains := []xds.FilterChainDebug{
+		{
+			Listener:    "0.0.0.0_80",
+			FilterChain: "#0",
+			HTTPFilters: []xds.HTTPFilterDebug{
+				{Name: "envoy.filters.http.rbac", Phase: "AUTHZ", Source: "istio"},
+				{Name: "custom.stats", Phase: "STATS", Priority: 5, Source: "EnvoyFilter default/stats"},
+				{Name: "envoy.filters.http.router", Phase: "ROUTER", Source: "istio"},
+			},
+		},
+		{
+			Listener:    "virtualInbound",
+			HTTPFilters: []xds.HTTPFilterDebug{{Name: "envoy.filters.http.cors", Source: "istio"}},
+		},
+	}
+	b, err := json.Marshal(chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := map[string]*xdsapi.DiscoveryResponse{
+		"istiod-1": {Resources: []*anypb.Any{{Value: []byte(`{"statusCode":"404"}Proxy not connected to this Pilot instance`)}}},
+		"istiod-2": {Resources: []*anypb.Any{{Value: b}}},
+	}
+
+	got := &bytes.Buffer{}
+	fw := FilterChainWriter{Writer: got}
+	if err := fw.PrintAll(responses); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(got.String()), "\n")
+	want := [][]string{
+		{"LISTENER", "FILTER", "CHAIN", "HTTP", "FILTER", "PHASE", "PRIORITY", "SOURCE"},
+		{"0.0.0.0_80", "#0", "envoy.filters.http.rbac", "AUTHZ", "0", "istio"},
+		{"0.0.0.0_80", "#0", "custom.stats", "STATS", "5", "EnvoyFilter", "default/stats"},
+		{"0.0.0.0_80", "#0", "envoy.filters.http.router", "ROUTER", "0", "istio"},
+		{"virtualInbound", "-", "envoy.filters.http.cors", "-", "0", "istio"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), got.String())
+	}
+	for i, line := range lines {
+		if fields := strings.Fields(line); strings.Join(fields, " ") != strings.Join(want[i], " ") {
+			t.Errorf("line %d: got %q, want %q", i, fields, want[i])
+		}
+	}
+
+	delete(responses, "istiod-2")
+	if err := fw.PrintAll(responses); err == nil || !strings.Contains(err.Error(), "Proxy not connected") {
+		t.Errorf("got error %v, want the response of istiod-1", err)
+	}
+}
@@ -148,8 +148,12 @@ var (
 			defer cancel()
 
 			// If a status port was provided, start handling status probes.
+			// The status server outlives the signal context, so that the drain can be followed.
+			var statusServer *status.Server
 			if proxyConfig.StatusPort > 0 {
-				if err := initStatusServer(ctx, proxy, proxyConfig, agent); err != nil {
+				statusCtx, statusCancel := context.WithCancel(context.Background())
+				defer statusCancel()
+				if statusServer, err = initStatusServer(statusCtx, proxy, proxyConfig, agent); err != nil {
 					return err
 				}
 			}
@@ -188,7 +192,19 @@ var (
 			})
 
 			drainDuration, _ := types.DurationFromProto(proxyConfig.TerminationDrainDuration)
-			envoyAgent := envoy.NewAgent(envoyProxy, drainDuration)
+			envoyAgent := envoy.NewAgent(envoyProxy, drainDuration, options.MinimumDrainDuration)
+			envoyAgent.SetRestartPolicy(envoy.RestartPolicy{
+				MaxRestarts:    options.ProxyMaxRestarts,
+				InitialBackoff: options.ProxyRestartInitialBackoff,
+				MaxBackoff:     options.ProxyRestartMaxBackoff,
+				ResetAfter:     options.ProxyRestartResetAfter,
+			})
+
+			if statusServer != nil {
+				statusServer.SetDrainStatus(envoyAgent.DrainStatus)
+				statusServer.SetSupervisorStatus(envoyAgent.SupervisorStatus)
+			}
+
 			// On SIGINT or SIGTERM, cancel the context, triggering a graceful shutdown
 			go cmd.WaitSignalFunc(cancel)
 
@@ -240,14 +256,17 @@ func init() {
 }
 
 func initStatusServer(ctx context.Context, proxy *model.Proxy, proxyConfig *meshconfig.ProxyConfig,
-	probes ...ready.Prober) error {
-	o := options.NewStatusServerOptions(proxy, proxyConfig, probes...)
+	agent *istio_agent.Agent) (*status.Server, error) {
+	o := options.NewStatusServerOptions(proxy, proxyConfig, ready.Named(ready.DNSProxySubsystem, agent))
+	o.DetailProbes = []ready.NamedProber{
+		ready.Named(ready.SDSSubsystem, ready.ProberFunc(agent.CheckWorkloadCertificate)),
+	}
 	statusServer, err := status.NewServer(*o)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	go statusServer.Run(ctx)
-	return nil
+	return statusServer, nil
 }
 
 func initStsServer(proxy *model.Proxy, tokenManager security.TokenManager) (*stsserver.Server, error) {
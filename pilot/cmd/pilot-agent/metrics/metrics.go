@@ -22,7 +22,8 @@ import (
 )
 
 var (
-	typeTag = monitoring.MustCreateLabel("type")
+	typeTag      = monitoring.MustCreateLabel("type")
+	subsystemTag = monitoring.MustCreateLabel("subsystem")
 
 	// StartupTime measures the time it takes for the agent to get ready Note: This
 	// is dependant on readiness probes. This means our granularity is correlated to
@@ -47,6 +48,20 @@ var (
 		"scrapes_total",
 		"The total number of scrapes.",
 	)
+
+	// subsystemReady records the readiness of each subsystem of the proxy.
+	subsystemReady = monitoring.NewGauge(
+		"subsystem_ready",
+		"Whether a subsystem of the proxy is ready (1) or not (0), as reported on /healthz/ready/detail.",
+		monitoring.WithLabels(subsystemTag),
+	)
+
+	// subsystemTransitionTime records when the readiness of each subsystem last changed.
+	subsystemTransitionTime = monitoring.NewGauge(
+		"subsystem_last_transition_timestamp_seconds",
+		"The unix time at which the readiness of a subsystem of the proxy last changed.",
+		monitoring.WithLabels(subsystemTag),
+	)
 )
 
 var (
@@ -63,10 +78,22 @@ func RecordStartupTime() {
 	log.Infof("Initialization took %v", delta)
 }
 
+// RecordSubsystemReadiness records the readiness of a subsystem and when it last changed.
+func RecordSubsystemReadiness(subsystem string, ready bool, lastTransition time.Time) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	subsystemReady.With(subsystemTag.Value(subsystem)).Record(value)
+	subsystemTransitionTime.With(subsystemTag.Value(subsystem)).Record(float64(lastTransition.Unix()))
+}
+
 func init() {
 	monitoring.MustRegister(
 		ScrapeTotals,
 		scrapeErrors,
 		startupTime,
+		subsystemReady,
+		subsystemTransitionTime,
 	)
 }
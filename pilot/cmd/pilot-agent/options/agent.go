@@ -28,13 +28,14 @@ const xdsHeaderPrefix = "XDS_HEADER_"
 
 func NewAgentOptions(proxy *model.Proxy) *istioagent.AgentOptions {
 	o := &istioagent.AgentOptions{
-		XDSRootCerts:             xdsRootCA,
-		CARootCerts:              caRootCA,
-		XDSHeaders:               map[string]string{},
-		XdsUdsPath:               constants.DefaultXdsUdsPath,
-		IsIPv6:                   proxy.SupportsIPv6(),
-		ProxyType:                proxy.Type,
-		EnableDynamicProxyConfig: enableProxyConfigXdsEnv,
+		XDSRootCerts:                      xdsRootCA,
+		CARootCerts:                       caRootCA,
+		XDSHeaders:                        map[string]string{},
+		XdsUdsPath:                        constants.DefaultXdsUdsPath,
+		IsIPv6:                            proxy.SupportsIPv6(),
+		ProxyType:                         proxy.Type,
+		EnableDynamicProxyConfig:          enableProxyConfigXdsEnv,
+		ConnectionPoolUsageReportInterval: connectionPoolUsageReportInterval,
 	}
 	extractXDSHeadersFromEnv(o)
 	if proxyXDSViaAgent {
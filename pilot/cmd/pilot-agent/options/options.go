@@ -75,7 +75,8 @@ var (
 	eccSigAlgEnv        = env.RegisterStringVar("ECC_SIGNATURE_ALGORITHM", "", "The type of ECC signature algorithm to use when generating private keys").Get()
 	fileMountedCertsEnv = env.RegisterBoolVar("FILE_MOUNTED_CERTS", false, "").Get()
 	credFetcherTypeEnv  = env.RegisterStringVar("CREDENTIAL_FETCHER_TYPE", "",
-		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
+		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine, "+
+			"AmazonWebServices and MicrosoftAzure").Get()
 	credIdentityProvider = env.RegisterStringVar("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
 	proxyXDSViaAgent = env.RegisterBoolVar("PROXY_XDS_VIA_AGENT", true,
@@ -88,4 +89,25 @@ var (
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.RegisterBoolVar("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
+
+	connectionPoolUsageReportInterval = env.RegisterDurationVar("CONNECTION_POOL_USAGE_REPORT_INTERVAL", 0,
+		"The interval at which the agent reports the connection pool usage of the outbound clusters to istiod, "+
+			"used by the adaptive connection pool. If unset, the usage is not reported.").Get()
+
+	MinimumDrainDuration = env.RegisterDurationVar("MINIMUM_DRAIN_DURATION", 5*time.Second,
+		"The minimum duration the proxy drains for on termination. After it, the agent terminates the proxy as soon as "+
+			"it has no active downstream connections or requests, or at the latest after terminationDrainDuration.").Get()
+
+	ProxyMaxRestarts = env.RegisterIntVar("PROXY_MAX_RESTARTS", 0,
+		"The number of consecutive times the agent restarts the proxy when it exits with an error, before giving up "+
+			"and exiting. Zero disables restarts, the agent exits as soon as the proxy does.").Get()
+
+	ProxyRestartInitialBackoff = env.RegisterDurationVar("PROXY_RESTART_INITIAL_BACKOFF", time.Second,
+		"The delay before the agent restarts the proxy, doubled on each consecutive restart.").Get()
+
+	ProxyRestartMaxBackoff = env.RegisterDurationVar("PROXY_RESTART_MAX_BACKOFF", time.Minute,
+		"The maximum delay before the agent restarts the proxy.").Get()
+
+	ProxyRestartResetAfter = env.RegisterDurationVar("PROXY_RESTART_RESET_AFTER", 5*time.Minute,
+		"How long the proxy must run for its restart backoff and budget to be reset.").Get()
 )
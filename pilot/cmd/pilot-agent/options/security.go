@@ -87,9 +87,9 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 		o.CAEndpoint = proxyConfig.DiscoveryAddress
 	}
 
-	// TODO (liminw): CredFetcher is a general interface. In 1.7, we limit the use on GCE only because
-	// GCE is the only supported plugin at the moment.
-	if credFetcherTypeEnv == security.GCE {
+	// CredFetcher is a general interface, limited to the platforms with a plugin.
+	switch credFetcherTypeEnv {
+	case security.GCE, security.AWS, security.Azure:
 		o.CredIdentityProvider = credIdentityProvider
 		credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, o.TrustDomain, jwtPath, o.CredIdentityProvider)
 		if err != nil {
@@ -37,15 +37,63 @@ type Prober interface {
 	Check() error
 }
 
+// NamedProber is a Prober for a subsystem of the proxy, reported by name in the readiness detail.
+type NamedProber interface {
+	Prober
+	// Name returns the name of the subsystem.
+	Name() string
+}
+
+// ProberFunc adapts a function to a Prober.
+type ProberFunc func() error
+
+// Check calls f.
+func (f ProberFunc) Check() error {
+	return f()
+}
+
+type namedProber struct {
+	Prober
+	name string
+}
+
+func (n namedProber) Name() string {
+	return n.name
+}
+
+// Named returns a NamedProber checking p for the subsystem name.
+func Named(name string, p Prober) NamedProber {
+	return namedProber{Prober: p, name: name}
+}
+
 var _ Prober = &Probe{}
 
 // Check executes the probe and returns an error if the probe fails.
 func (p *Probe) Check() error {
 	// First, check that Envoy has received a configuration update from Pilot.
-	if err := p.checkConfigStatus(); err != nil {
+	if err := p.CheckConfig(); err != nil {
+		return err
+	}
+	return p.CheckServer()
+}
+
+// CheckConfig checks that Envoy has received its initial configuration from Pilot.
+func (p *Probe) CheckConfig() error {
+	return p.checkConfigStatus()
+}
+
+// CheckServer checks that Envoy is live and its workers have started. While it is not, the error
+// includes the listeners and clusters still warming, which are waiting on SDS secrets, RDS or EDS.
+func (p *Probe) CheckServer() error {
+	err := p.isEnvoyReady()
+	if err == nil {
+		return nil
+	}
+	listeners, clusters, werr := util.GetWarmingStats(p.LocalHostAddr, p.AdminPort)
+	if werr != nil || listeners+clusters == 0 {
 		return err
 	}
-	return p.isEnvoyReady()
+	return fmt.Errorf("%v; %d listeners and %d clusters warming", err, listeners, clusters)
 }
 
 // checkConfigStatus checks to make sure initial configs have been received from Pilot.
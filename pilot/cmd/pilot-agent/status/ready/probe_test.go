@@ -120,6 +120,21 @@ func TestEnvoyInitializing(t *testing.T) {
 	g.Expect(err).To(HaveOccurred())
 }
 
+func TestEnvoyWarming(t *testing.T) {
+	g := NewWithT(t)
+
+	server := testserver.CreateAndStartServer(initServerStats +
+		"\nlistener_manager.total_listeners_warming: 2\ncluster_manager.warming_clusters: 1")
+	defer server.Close()
+	probe := Probe{AdminPort: uint16(server.Listener.Addr().(*net.TCPAddr).Port)}
+
+	g.Expect(probe.CheckConfig()).NotTo(HaveOccurred())
+	err := probe.CheckServer()
+
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(HaveSuffix("; 2 listeners and 1 clusters warming"))
+}
+
 func TestEnvoyNoClusterManagerStats(t *testing.T) {
 	g := NewWithT(t)
 
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ready
+
+import (
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/metrics"
+)
+
+// Names of the subsystems reported in the readiness detail.
+const (
+	// EnvoyConfigSubsystem is Envoy having received its initial CDS and LDS configuration.
+	EnvoyConfigSubsystem = "envoy-config"
+	// EnvoyServerSubsystem is Envoy being live with its workers started.
+	EnvoyServerSubsystem = "envoy-server"
+	// DNSProxySubsystem is the agent DNS proxy having received its lookup table.
+	DNSProxySubsystem = "dns-proxy"
+	// SDSSubsystem is the agent having a valid workload certificate to serve over SDS.
+	SDSSubsystem = "sds"
+	// DrainSubsystem is the proxy not draining for termination.
+	DrainSubsystem = "drain"
+	// AppSubsystemPrefix prefixes the name of the container of each application readiness probe.
+	AppSubsystemPrefix = "app/"
+)
+
+// SubsystemStatus is the readiness of a subsystem of the proxy.
+type SubsystemStatus struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// Required is set for the subsystems the proxy readiness depends on. The others are reported to
+	// help troubleshooting, e.g. the application readiness probes.
+	Required bool `json:"required"`
+	// Reason is why the subsystem is not ready.
+	Reason string `json:"reason,omitempty"`
+	// LastTransitionTime is when the subsystem was first checked or last changed readiness.
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Status is the readiness of the proxy and of each of its subsystems.
+type Status struct {
+	// Ready is set if all the required subsystems are ready.
+	Ready      bool              `json:"ready"`
+	Subsystems []SubsystemStatus `json:"subsystems"`
+}
+
+// Subsystems tracks the readiness of the subsystems of the proxy and when it last changed.
+type Subsystems struct {
+	mu       sync.RWMutex
+	order    []string
+	statuses map[string]*SubsystemStatus
+	now      func() time.Time
+}
+
+// NewSubsystems returns an empty Subsystems.
+func NewSubsystems() *Subsystems {
+	return &Subsystems{
+		statuses: make(map[string]*SubsystemStatus),
+		now:      time.Now,
+	}
+}
+
+// Update records the result err of checking subsystem name.
+func (s *Subsystems) Update(name string, required bool, err error) {
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, f := s.statuses[name]
+	if !f {
+		st = &SubsystemStatus{Name: name, LastTransitionTime: s.now()}
+		s.statuses[name] = st
+		s.order = append(s.order, name)
+	} else if st.Ready != (err == nil) {
+		st.LastTransitionTime = s.now()
+	}
+	st.Ready = err == nil
+	st.Required = required
+	st.Reason = reason
+	metrics.RecordSubsystemReadiness(name, st.Ready, st.LastTransitionTime)
+}
+
+// Status returns the readiness of the subsystems, in the order they were first checked.
+func (s *Subsystems) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := Status{Ready: true, Subsystems: make([]SubsystemStatus, 0, len(s.order))}
+	for _, name := range s.order {
+		st := *s.statuses[name]
+		if st.Required && !st.Ready {
+			out.Ready = false
+		}
+		out.Subsystems = append(out.Subsystems, st)
+	}
+	return out
+}
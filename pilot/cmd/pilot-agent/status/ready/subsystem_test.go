@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ready
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSubsystems(t *testing.T) {
+	g := NewWithT(t)
+
+	start := time.Unix(1600000000, 0)
+	now := start
+	s := NewSubsystems()
+	s.now = func() time.Time { return now }
+
+	s.Update(EnvoyConfigSubsystem, true, errors.New("cds not received"))
+	s.Update(AppSubsystemPrefix+"app", false, errors.New("connection refused"))
+	status := s.Status()
+	g.Expect(status.Ready).To(BeFalse())
+	g.Expect(status.Subsystems).To(Equal([]SubsystemStatus{
+		{Name: EnvoyConfigSubsystem, Required: true, Reason: "cds not received", LastTransitionTime: start},
+		{Name: AppSubsystemPrefix + "app", Reason: "connection refused", LastTransitionTime: start},
+	}))
+
+	// Failing again keeps the transition time, while becoming ready updates it.
+	now = start.Add(time.Minute)
+	s.Update(EnvoyConfigSubsystem, true, nil)
+	s.Update(AppSubsystemPrefix+"app", false, errors.New("connection refused"))
+	status = s.Status()
+	// Informational subsystems do not gate readiness.
+	g.Expect(status.Ready).To(BeTrue())
+	g.Expect(status.Subsystems[0]).To(Equal(SubsystemStatus{
+		Name: EnvoyConfigSubsystem, Ready: true, Required: true, LastTransitionTime: now,
+	}))
+	g.Expect(status.Subsystems[1].LastTransitionTime).To(Equal(start))
+}
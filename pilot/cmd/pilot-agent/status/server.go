@@ -43,6 +43,7 @@ import (
 	"istio.io/istio/pilot/cmd/pilot-agent/metrics"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/envoy"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/pkg/env"
 	"istio.io/pkg/log"
@@ -51,8 +52,14 @@ import (
 const (
 	// readyPath is for the pilot agent readiness itself.
 	readyPath = "/healthz/ready"
+	// readyDetailPath reports the readiness of each subsystem of the proxy.
+	readyDetailPath = "/healthz/ready/detail"
 	// quitPath is to notify the pilot agent to quit.
 	quitPath = "/quitquitquit"
+	// drainPath reports the progress of the proxy drain on termination.
+	drainPath = "/drain"
+	// crashPath reports the proxy restarts and crash reports.
+	crashPath = "/crashes"
 	// KubeAppProberEnvName is the name of the command line flag for pilot agent to pass app prober config.
 	// The json encoded string to pass app HTTP probe information from injector(istioctl or webhook).
 	// For example, ISTIO_KUBE_APP_PROBERS='{"/app-health/httpbin/livez":{"httpGet":{"path": "/hello", "port": 8080}}.
@@ -103,12 +110,24 @@ type Options struct {
 	StatusPort     uint16
 	AdminPort      uint16
 	IPv6           bool
-	Probes         []ready.Prober
+	// Probes gate the proxy readiness. Probes implementing ready.NamedProber are reported by name
+	// in the readiness detail.
+	Probes []ready.Prober
+	// DetailProbes are only reported in the readiness detail, they do not gate the proxy readiness.
+	DetailProbes []ready.NamedProber
+	// DrainStatus returns the progress of the proxy drain, served on /drain. It may also be set
+	// once the proxy is created with SetDrainStatus.
+	DrainStatus func() envoy.DrainStatus
+	// SupervisorStatus returns the proxy restarts and crash reports, served on /crashes. It may
+	// also be set once the proxy is created with SetSupervisorStatus.
+	SupervisorStatus func() envoy.SupervisorStatus
 }
 
 // Server provides an endpoint for handling status probes.
 type Server struct {
 	ready                 []ready.Prober
+	detail                []ready.NamedProber
+	subsystems            *ready.Subsystems
 	prometheus            *PrometheusScrapeConfiguration
 	mutex                 sync.RWMutex
 	appProbersDestination string
@@ -117,6 +136,8 @@ type Server struct {
 	statusPort            uint16
 	lastProbeSuccessful   bool
 	envoyStatsPort        int
+	drainStatus           func() envoy.DrainStatus
+	supervisorStatus      func() envoy.SupervisorStatus
 }
 
 func init() {
@@ -140,17 +161,24 @@ func NewServer(config Options) (*Server, error) {
 	if config.IPv6 {
 		localhost = localHostIPv6
 	}
-	probes := make([]ready.Prober, 0)
-	probes = append(probes, &ready.Probe{
+	envoyProbe := &ready.Probe{
 		LocalHostAddr: localhost,
 		AdminPort:     config.AdminPort,
-	})
+	}
+	probes := make([]ready.Prober, 0)
+	probes = append(probes,
+		ready.Named(ready.EnvoyConfigSubsystem, ready.ProberFunc(envoyProbe.CheckConfig)),
+		ready.Named(ready.EnvoyServerSubsystem, ready.ProberFunc(envoyProbe.CheckServer)))
 	probes = append(probes, config.Probes...)
 	s := &Server{
 		statusPort:            config.StatusPort,
 		ready:                 probes,
+		detail:                config.DetailProbes,
+		subsystems:            ready.NewSubsystems(),
 		appProbersDestination: config.PodIP,
 		envoyStatsPort:        15090,
+		drainStatus:           config.DrainStatus,
+		supervisorStatus:      config.SupervisorStatus,
 	}
 	if legacyLocalhostProbeDestination.Get() {
 		s.appProbersDestination = "localhost"
@@ -238,8 +266,11 @@ func (s *Server) Run(ctx context.Context) {
 
 	// Add the handler for ready probes.
 	mux.HandleFunc(readyPath, s.handleReadyProbe)
+	mux.HandleFunc(readyDetailPath, s.handleReadyDetail)
 	mux.HandleFunc(`/stats/prometheus`, s.handleStats)
 	mux.HandleFunc(quitPath, s.handleQuit)
+	mux.HandleFunc(drainPath, s.handleDrain)
+	mux.HandleFunc(crashPath, s.handleCrashes)
 	mux.HandleFunc("/app-health/", s.handleAppProbe)
 
 	// Add the handler for pprof.
@@ -348,13 +379,54 @@ func (s *Server) handleReadyProbe(w http.ResponseWriter, _ *http.Request) {
 	s.mutex.Unlock()
 }
 
+// isReady checks every subsystem and records its readiness. It returns the error of the first
+// required subsystem that is not ready.
 func (s *Server) isReady() error {
-	for _, p := range s.ready {
-		if err := p.Check(); err != nil {
-			return err
+	var firstErr error
+	if drainStatus := s.getDrainStatus(); drainStatus != nil {
+		var err error
+		if drainStatus().Draining {
+			err = fmt.Errorf("proxy is draining")
+			firstErr = err
 		}
+		s.subsystems.Update(ready.DrainSubsystem, true, err)
+	}
+	for i, p := range s.ready {
+		err := p.Check()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.subsystems.Update(subsystemName(p, i), true, err)
+	}
+	for _, p := range s.detail {
+		s.subsystems.Update(p.Name(), false, p.Check())
+	}
+	return firstErr
+}
+
+// subsystemName returns the name of the subsystem checked by the i-th readiness probe p.
+func subsystemName(p ready.Prober, i int) string {
+	if n, ok := p.(ready.NamedProber); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("probe-%d", i)
+}
+
+// handleReadyDetail reports the readiness of each subsystem of the proxy, with why and since when it
+// is not ready. Like the readiness probe, it returns 503 if the proxy is not ready.
+func (s *Server) handleReadyDetail(w http.ResponseWriter, _ *http.Request) {
+	_ = s.isReady()
+	status := s.subsystems.Status()
+	b, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
-	return nil
+	_, _ = w.Write(b)
 }
 
 func isRequestFromLocalhost(r *http.Request) bool {
@@ -506,6 +578,71 @@ func (s *Server) handleQuit(w http.ResponseWriter, r *http.Request) {
 	notifyExit()
 }
 
+// SetDrainStatus sets the progress of the proxy drain served on /drain, once the proxy is created.
+func (s *Server) SetDrainStatus(drainStatus func() envoy.DrainStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.drainStatus = drainStatus
+}
+
+// SetSupervisorStatus sets the proxy restarts and crash reports served on /crashes, once the proxy
+// is created.
+func (s *Server) SetSupervisorStatus(supervisorStatus func() envoy.SupervisorStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.supervisorStatus = supervisorStatus
+}
+
+func (s *Server) getDrainStatus() func() envoy.DrainStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.drainStatus
+}
+
+func (s *Server) getSupervisorStatus() func() envoy.SupervisorStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.supervisorStatus
+}
+
+// handleDrain reports the progress of the proxy drain: whether it started or completed, and
+// the downstream traffic still active.
+func (s *Server) handleDrain(w http.ResponseWriter, _ *http.Request) {
+	drainStatus := s.getDrainStatus()
+	if drainStatus == nil {
+		http.Error(w, "drain status is not available", http.StatusNotFound)
+		return
+	}
+	b, err := json.MarshalIndent(drainStatus(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// handleCrashes reports the proxy restarts and why the proxy exited, including the end of its
+// stderr. It is only served to localhost, as the proxy logs may contain sensitive data.
+func (s *Server) handleCrashes(w http.ResponseWriter, r *http.Request) {
+	if !isRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	supervisorStatus := s.getSupervisorStatus()
+	if supervisorStatus == nil {
+		http.Error(w, "crash reports are not available", http.StatusNotFound)
+		return
+	}
+	b, err := json.MarshalIndent(supervisorStatus(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
 func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	// Validate the request first.
 	path := req.URL.Path
@@ -559,6 +696,7 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	response, err := httpClient.Do(appReq)
 	if err != nil {
 		log.Errorf("Request to probe app failed: %v, original URL path = %v\napp URL path = %v", err, path, proberPath)
+		s.recordAppReadiness(path, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -568,10 +706,27 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 		_ = response.Body.Close()
 	}()
 
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusBadRequest {
+		s.recordAppReadiness(path, fmt.Errorf("readiness probe returned status code %d", response.StatusCode))
+	} else {
+		s.recordAppReadiness(path, nil)
+	}
+
 	// We only write the status code to the response.
 	w.WriteHeader(response.StatusCode)
 }
 
+// recordAppReadiness records the result of the application readiness probe of path in the readiness
+// detail. Liveness and startup probes are ignored.
+func (s *Server) recordAppReadiness(path string, err error) {
+	m := appProberPattern.FindStringSubmatch(path)
+	if m == nil || m[1] != "readyz" {
+		return
+	}
+	container := strings.TrimSuffix(strings.TrimPrefix(path, "/app-health/"), "/readyz")
+	s.subsystems.Update(ready.AppSubsystemPrefix+container, false, err)
+}
+
 // notifyExit sends SIGTERM to itself
 func notifyExit() {
 	p, err := os.FindProcess(os.Getpid())
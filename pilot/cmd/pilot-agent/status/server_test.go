@@ -35,6 +35,7 @@ import (
 
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/testserver"
+	"istio.io/istio/pkg/envoy"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/istio/pkg/test/env"
 	"istio.io/istio/pkg/test/util/retry"
@@ -674,6 +675,60 @@ func TestAdditionalProbes(t *testing.T) {
 	}
 }
 
+func TestHandleDrain(t *testing.T) {
+	draining := false
+	s, err := NewServer(Options{
+		StatusPort: 15020,
+		Probes:     []ready.Prober{readyProbe{}},
+		DrainStatus: func() envoy.DrainStatus {
+			return envoy.DrainStatus{Draining: draining, ActiveConnections: 3, ActiveRequests: 1}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the additional probe is checked, not Envoy itself.
+	s.ready = s.ready[2:]
+	if err := s.isReady(); err != nil {
+		t.Fatalf("isReady() = %v before drain", err)
+	}
+
+	draining = true
+	if err := s.isReady(); err == nil || err.Error() != "proxy is draining" {
+		t.Fatalf("isReady() = %v while draining, want proxy is draining", err)
+	}
+	resp := httptest.NewRecorder()
+	s.handleDrain(resp, httptest.NewRequest("GET", drainPath, nil))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected response code 200 got %v", resp.Code)
+	}
+	got := envoy.DrainStatus{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Draining || got.ActiveConnections != 3 || got.ActiveRequests != 1 {
+		t.Errorf("unexpected drain status %+v", got)
+	}
+
+	noDrain, err := NewServer(Options{StatusPort: 15020})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = httptest.NewRecorder()
+	noDrain.handleDrain(resp, httptest.NewRequest("GET", drainPath, nil))
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("Expected response code 404 got %v", resp.Code)
+	}
+
+	// The drain status is set once the proxy is created, after the server started.
+	noDrain.SetDrainStatus(func() envoy.DrainStatus { return envoy.DrainStatus{Draining: true} })
+	resp = httptest.NewRecorder()
+	noDrain.handleDrain(resp, httptest.NewRequest("GET", drainPath, nil))
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected response code 200 got %v", resp.Code)
+	}
+}
+
 type readyProbe struct{}
 
 func (s readyProbe) Check() error {
@@ -685,3 +740,95 @@ type unreadyProbe struct{}
 func (u unreadyProbe) Check() error {
 	return errors.New("not ready")
 }
+
+func TestHandleCrashes(t *testing.T) {
+	s, err := NewServer(Options{
+		StatusPort: 15020,
+		SupervisorStatus: func() envoy.SupervisorStatus {
+			return envoy.SupervisorStatus{
+				Enabled:     true,
+				Restarts:    1,
+				MaxRestarts: 3,
+				Crashes:     []envoy.CrashReport{{Epoch: 0, ExitCode: -1, Signal: "killed", OOMSuspected: true}},
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", crashPath, nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	resp := httptest.NewRecorder()
+	s.handleCrashes(resp, req)
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("Expected response code 403 for remote request got %v", resp.Code)
+	}
+
+	req.RemoteAddr = "127.0.0.1:1234"
+	resp = httptest.NewRecorder()
+	s.handleCrashes(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected response code 200 got %v", resp.Code)
+	}
+	got := envoy.SupervisorStatus{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Restarts != 1 || len(got.Crashes) != 1 || !got.Crashes[0].OOMSuspected {
+		t.Errorf("unexpected supervisor status %+v", got)
+	}
+}
+
+func TestHandleReadyDetail(t *testing.T) {
+	testServer := testserver.CreateAndStartServer(liveServerStats)
+	defer testServer.Close()
+	s, err := NewServer(Options{
+		AdminPort:    uint16(testServer.Listener.Addr().(*net.TCPAddr).Port),
+		Probes:       []ready.Prober{ready.Named(ready.DNSProxySubsystem, unreadyProbe{}), readyProbe{}},
+		DetailProbes: []ready.NamedProber{ready.Named(ready.SDSSubsystem, unreadyProbe{})},
+		DrainStatus:  func() envoy.DrainStatus { return envoy.DrainStatus{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := httptest.NewRecorder()
+	s.handleReadyDetail(resp, httptest.NewRequest("GET", readyDetailPath, nil))
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected response code 503 got %v", resp.Code)
+	}
+	got := ready.Status{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		ready.DrainSubsystem:       "",
+		ready.EnvoyConfigSubsystem: "",
+		ready.EnvoyServerSubsystem: "",
+		ready.DNSProxySubsystem:    "not ready",
+		"probe-3":                  "",
+		ready.SDSSubsystem:         "not ready",
+	}
+	if got.Ready || len(got.Subsystems) != len(want) {
+		t.Fatalf("unexpected readiness detail %+v", got)
+	}
+	for _, ss := range got.Subsystems {
+		reason, f := want[ss.Name]
+		if !f || ss.Reason != reason || ss.Ready != (reason == "") || ss.Required == (ss.Name == ready.SDSSubsystem) {
+			t.Errorf("unexpected subsystem status %+v", ss)
+		}
+	}
+
+	// The SDS subsystem is informational, it does not make the proxy unready.
+	s, err = NewServer(Options{
+		AdminPort:    uint16(testServer.Listener.Addr().(*net.TCPAddr).Port),
+		DetailProbes: []ready.NamedProber{ready.Named(ready.SDSSubsystem, unreadyProbe{})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = httptest.NewRecorder()
+	s.handleReadyDetail(resp, httptest.NewRequest("GET", readyDetailPath, nil))
+	if resp.Code != http.StatusOK {
+		t.Errorf("Expected response code 200 got %v: %s", resp.Code, resp.Body.String())
+	}
+}
@@ -31,7 +31,10 @@ const (
 	statLdsSuccess     = "listener_manager.lds.update_success"
 	statServerState    = "server.state"
 	statWorkersStarted = "listener_manager.workers_started"
+	statListenersWarm  = "listener_manager.total_listeners_warming"
+	statClustersWarm   = "cluster_manager.warming_clusters"
 	readyStatsRegex    = "^(server\\.state|listener_manager\\.workers_started)"
+	warmingStatsRegex  = "^(listener_manager\\.total_listeners_warming|cluster_manager\\.warming_clusters)$"
 	updateStatsRegex   = "^(cluster_manager\\.cds|listener_manager\\.lds)\\.(update_success|update_rejected)$"
 )
 
@@ -96,6 +99,28 @@ func GetReadinessStats(localHostAddr string, adminPort uint16) (*uint64, bool, e
 	return &s.ServerState, s.WorkersStarted == 1, nil
 }
 
+// GetWarmingStats returns the number of listeners and clusters Envoy is warming.
+func GetWarmingStats(localHostAddr string, adminPort uint16) (listeners, clusters uint64, err error) {
+	// If the localHostAddr was not set, we use 'localhost' to void empty host in URL.
+	if localHostAddr == "" {
+		localHostAddr = "localhost"
+	}
+
+	stats, err := doHTTPGetWithTimeout(fmt.Sprintf("http://%s:%d/stats?filter=%s", localHostAddr, adminPort, warmingStatsRegex),
+		readinessTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	allStats := []*stat{
+		{name: statListenersWarm, value: &listeners},
+		{name: statClustersWarm, value: &clusters},
+	}
+	if err := parseStats(stats, allStats); err != nil {
+		return 0, 0, err
+	}
+	return listeners, clusters, nil
+}
+
 // GetUpdateStatusStats returns the version stats for CDS and LDS.
 func GetUpdateStatusStats(localHostAddr string, adminPort uint16) (*Stats, error) {
 	// If the localHostAddr was not set, we use 'localhost' to void empty host in URL.
@@ -17,6 +17,7 @@ package bootstrap
 import (
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
@@ -28,6 +29,8 @@ import (
 	"istio.io/istio/pilot/pkg/config/kube/gateway"
 	"istio.io/istio/pilot/pkg/config/kube/ingress"
 	ingressv1 "istio.io/istio/pilot/pkg/config/kube/ingressv1"
+	"istio.io/istio/pilot/pkg/config/kube/ratelimit"
+	remoteconfig "istio.io/istio/pilot/pkg/config/kube/remote"
 	"istio.io/istio/pilot/pkg/config/memory"
 	configmonitor "istio.io/istio/pilot/pkg/config/monitor"
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
@@ -51,8 +54,9 @@ const (
 	// xds://ADDRESS - load XDS-over-MCP sources
 	// example xds://127.0.0.1:49133
 	XDS ConfigSourceAddressScheme = "xds"
-	// k8s:// - load in-cluster k8s controller
-	// example k8s://
+	// k8s://[CLUSTER] - load in-cluster k8s controller, or the Istio resources of a remote cluster
+	// read with the credentials of its remote secret
+	// example k8s:// or k8s://config-cluster
 	Kubernetes ConfigSourceAddressScheme = "k8s"
 )
 
@@ -161,6 +165,12 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 	if features.EnableServiceApis {
 		s.ConfigStores = append(s.ConfigStores, gateway.NewController(s.kubeClient, configController, args.RegistryOptions.KubeOptions))
 	}
+	if ratelimit.Available(s.kubeClient) {
+		s.ConfigStores = append(s.ConfigStores,
+			ratelimit.NewController(s.kubeClient, args.Revision, args.RegistryOptions.KubeOptions.DomainSuffix))
+	} else {
+		log.Infof("LocalRateLimit CRD not found, local rate limiting is disabled")
+	}
 	if features.EnableAnalysis {
 		if err := s.initInprocessAnalysisController(args); err != nil {
 			return err
@@ -175,7 +185,8 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 }
 
 // initConfigSources will process mesh config 'configSources' and initialize
-// associated configs.
+// associated configs. When several sources have a config with the same name, the
+// first source in the mesh config takes precedence.
 func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 	for _, configSource := range s.environment.Mesh().ConfigSources {
 		srcAddress, err := url.Parse(configSource.Address)
@@ -216,7 +227,11 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 			s.ConfigStores = append(s.ConfigStores, configController)
 			log.Warn("Started XDS config ", s.ConfigStores)
 		case Kubernetes:
-			if srcAddress.Path == "" || srcAddress.Path == "/" {
+			clusterID := srcAddress.Host
+			if clusterID == "" {
+				clusterID = strings.Trim(srcAddress.Path, "/")
+			}
+			if clusterID == "" || clusterID == s.clusterID {
 				err2 := s.initK8SConfigStore(args)
 				if err2 != nil {
 					log.Warn("Error loading k8s ", err2)
@@ -224,14 +239,37 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 				}
 				log.Warn("Started K8S config")
 			} else {
-				log.Warnf("Not implemented, ignore: %v", configSource.Address)
-				// TODO: handle k8s:// scheme for remote cluster. Use same mechanism as service registry,
-				// using the cluster name as key to match a secret.
+				// The remote cluster is accessed with its remote secret, which the Kubernetes registry watches.
+				if !hasKubeRegistry(args.RegistryOptions.Registries) {
+					return fmt.Errorf("config source %s requires the Kubernetes registry", configSource.Address)
+				}
+				configController := remoteconfig.NewController(clusterID, args.Revision, args.RegistryOptions.KubeOptions.DomainSuffix)
+				s.ConfigStores = append(s.ConfigStores, configController)
+				s.remoteConfigStores = append(s.remoteConfigStores, configController)
+				log.Infof("Reading config of cluster %s from its remote secret", clusterID)
 			}
 		default:
 			log.Warnf("Ignoring unsupported config source: %v", configSource.Address)
 		}
 	}
+	return s.initRemoteConfigStatus()
+}
+
+// initRemoteConfigStatus writes the status of the configs of remote clusters back to their cluster.
+func (s *Server) initRemoteConfigStatus() error {
+	if len(s.remoteConfigStores) == 0 {
+		return nil
+	}
+	owners := make([]model.ConfigStoreCache, 0, len(s.remoteConfigStores))
+	for _, c := range s.remoteConfigStores {
+		owners = append(owners, c)
+	}
+	// Configs of other sources keep being written through the store of the local cluster, if any.
+	store, err := configaggregate.MakeWriteableCacheWithStatusOwners(s.ConfigStores, s.RWConfigStore, owners)
+	if err != nil {
+		return err
+	}
+	s.RWConfigStore = store
 	return nil
 }
 
@@ -243,6 +281,9 @@ func (s *Server) initInprocessAnalysisController(args *PilotArgs) error {
 	processingArgs.KubeConfig = args.RegistryOptions.KubeConfig
 	processingArgs.WatchedNamespaces = args.RegistryOptions.KubeOptions.WatchedNamespaces
 	processingArgs.EnableConfigAnalysis = true
+	if features.AnalysisCustomAnalyzers != "" {
+		processingArgs.CustomAnalyzerFiles = strings.Split(features.AnalysisCustomAnalyzers, ",")
+	}
 	meshSource := mesh.NewInmemoryMeshCfg()
 	meshSource.Set(s.environment.Mesh())
 	s.environment.Watcher.AddMeshHandler(func() {
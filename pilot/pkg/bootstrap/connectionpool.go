@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"istio.io/istio/pilot/pkg/leaderelection"
+	"istio.io/istio/pkg/kube/configmapwatcher"
+	"istio.io/pkg/log"
+)
+
+const (
+	// connectionPoolLimitsConfigMap holds the connection pool limits tuned by the leader istiod, followed by
+	// all the replicas.
+	connectionPoolLimitsConfigMap = "istio-connection-pool-limits"
+	connectionPoolLimitsKey       = "limits"
+	// connectionPoolUsageConfigMap holds the usage reported to each istiod replica, keyed by pod name, from
+	// which the leader tunes the limits.
+	connectionPoolUsageConfigMap = "istio-connection-pool-usage"
+)
+
+// initConnectionPoolTuner shares the limits of the adaptive connection pool between the istiod replicas: the
+// leader tunes them and publishes them in a ConfigMap, from which all the replicas, and the leader after a
+// restart, load them. The other replicas share the usage reported by their proxies in another ConfigMap, so
+// that the leader tunes the limits from the usage of all the proxies.
+func (s *Server) initConnectionPoolTuner(args *PilotArgs) {
+	tuner := s.XDSServer.ConnectionPoolTuner
+	if tuner == nil || s.kubeClient == nil {
+		return
+	}
+	tuner.Share(func(limits []byte) error {
+		return s.publishConnectionPoolLimits(args.Namespace, limits)
+	}, func(usage []byte) error {
+		return s.publishConnectionPoolUsage(args.Namespace, args.PodName, usage)
+	})
+	c := configmapwatcher.NewController(s.kubeClient, args.Namespace, connectionPoolLimitsConfigMap, func(cm *v1.ConfigMap) {
+		var limits []byte
+		if cm != nil {
+			limits = []byte(cm.Data[connectionPoolLimitsKey])
+		}
+		if err := tuner.SetLimits(limits); err != nil {
+			log.Errorf("failed to load the connection pool limits of ConfigMap %s: %v", connectionPoolLimitsConfigMap, err)
+		}
+	})
+	usage := configmapwatcher.NewController(s.kubeClient, args.Namespace, connectionPoolUsageConfigMap, func(cm *v1.ConfigMap) {
+		if cm != nil {
+			tuner.RecordUsage(cm.Data)
+		}
+	})
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go c.Run(stop)
+		go usage.Run(stop)
+		go tuner.Run(stop)
+		return nil
+	})
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.ConnectionPoolTuningController, s.kubeClient).
+			AddRunFunction(tuner.Lead).
+			Run(stop)
+		return nil
+	})
+}
+
+// publishConnectionPoolLimits writes the limits tuned by the leader to the connection pool limits ConfigMap.
+func (s *Server) publishConnectionPoolLimits(namespace string, limits []byte) error {
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(context.TODO(), connectionPoolLimitsConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(context.TODO(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: connectionPoolLimitsConfigMap, Namespace: namespace},
+			Data:       map[string]string{connectionPoolLimitsKey: string(limits)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[connectionPoolLimitsKey] == string(limits) {
+		return nil
+	}
+	cm.Data = map[string]string{connectionPoolLimitsKey: string(limits)}
+	_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
+
+// publishConnectionPoolUsage writes the usage reported to the replica pod to the connection pool usage ConfigMap,
+// and removes the expired usage of other replicas.
+func (s *Server) publishConnectionPoolUsage(namespace, pod string, usage []byte) error {
+	tuner := s.XDSServer.ConnectionPoolTuner
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	// The replicas update the ConfigMap concurrently, and retry on conflicts.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(context.TODO(), connectionPoolUsageConfigMap, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = configMaps.Create(context.TODO(), &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: connectionPoolUsageConfigMap, Namespace: namespace},
+				Data:       map[string]string{pod: string(usage)},
+			}, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				return errors.NewConflict(v1.Resource("configmaps"), connectionPoolUsageConfigMap, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		for replica, u := range cm.Data {
+			if replica != pod && tuner.UsageExpired([]byte(u)) {
+				delete(cm.Data, replica)
+			}
+		}
+		cm.Data[pod] = string(usage)
+		_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -27,20 +27,25 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
+	"istio.io/istio/pilot/pkg/model"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
 	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/configmapwatcher"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/security/pkg/cmd"
 	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/ledger"
 	"istio.io/istio/security/pkg/pki/ra"
 	caserver "istio.io/istio/security/pkg/server/ca"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
@@ -56,6 +61,9 @@ type caOptions struct {
 	TrustDomain    string
 	Namespace      string
 	Authenticators []security.Authenticator
+	// Ledger and Revoker are only set for the Istio CA, they are not supported with an external CA.
+	Ledger  ledger.Store
+	Revoker *ledger.Revoker
 }
 
 // Based on istio_ca main - removing creation of Secrets with private keys in all namespaces and install complexity.
@@ -118,6 +126,44 @@ var (
 			"Jitter selects a backoff time in seconds to start root cert rotator, "+
 			"and the back off time is below root cert check interval.")
 
+	pluggedCertCheckInterval = env.RegisterDurationVar("CITADEL_PLUGGED_CERT_CHECK_INTERVAL", 0,
+		"The interval that a CA using plugged-in certificates checks them for changes, and rotates to new "+
+			"certificates in stages. The rotation is disabled by default, when this interval is zero or negative. "+
+			"When enabled, the rotation state, including the CA private keys, is persisted in the "+
+			"istio-ca-rotation-state Secret.")
+
+	pluggedCertTrustDistributionPeriod = env.RegisterDurationVar("CITADEL_PLUGGED_CERT_TRUST_DISTRIBUTION_PERIOD", 0,
+		"How long the old and new roots of rotated plugged-in certificates are distributed before signing "+
+			"with the new key. Defaults to DEFAULT_WORKLOAD_CERT_TTL, by when all workloads renewed their "+
+			"certificate and got the new roots.")
+
+	pluggedCertOldRootRetentionPeriod = env.RegisterDurationVar("CITADEL_PLUGGED_CERT_OLD_ROOT_RETENTION_PERIOD", 0,
+		"How long the old roots of rotated plugged-in certificates are distributed after signing with the "+
+			"new key. Defaults to DEFAULT_WORKLOAD_CERT_TTL, by when the certificates signed with the old key "+
+			"were renewed.")
+
+	certLedgerPath = env.RegisterStringVar("CITADEL_CERT_LEDGER_PATH", "",
+		"Path of the file recording the certificates issued by the CA. If empty, they are recorded in "+
+			"memory and lost when istiod restarts.")
+
+	revocationsConfigMap = env.RegisterStringVar("CITADEL_REVOCATIONS_CONFIGMAP", "istio-ca-revocations",
+		"Name of the ConfigMap in the istiod namespace listing the revoked certificates. Its \"serials\" key "+
+			"lists hex serial numbers, and its \"identities\" key lists SPIFFE IDs, one per line.")
+
+	crlValidity = env.RegisterDurationVar("CITADEL_CRL_VALIDITY", ledger.DefaultCRLValidity,
+		"How long the CRL of the revoked certificates published to proxies is valid. It is signed again "+
+			"halfway through by the leader istiod. If no istiod signs it again before it expires, all the "+
+			"proxies reject all the peer certificates.")
+
+	enableCertRevocation = env.RegisterBoolVar("CITADEL_ENABLE_CERT_REVOCATION", false,
+		"If enabled, the certificates listed in CITADEL_REVOCATIONS_CONFIGMAP are revoked. The CRL is only "+
+			"delivered to proxies whose agent runs with PROXY_CONFIG_XDS_AGENT=true, other proxies keep "+
+			"accepting the revoked certificates: only enable it once all the proxies run with it.")
+
+	crlConfigMap = env.RegisterStringVar("CITADEL_CRL_CONFIGMAP", "istio-ca-crl",
+		"Name of the ConfigMap in the istiod namespace holding the CRL signed by the leader istiod, "+
+			"served by all the istiod replicas.")
+
 	k8sInCluster = env.RegisterStringVar("KUBERNETES_SERVICE_HOST", "",
 		"Kuberenetes service host, set automatically when running in-cluster")
 
@@ -201,6 +247,8 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 	if startErr != nil {
 		log.Fatalf("failed to create istio ca server: %v", startErr)
 	}
+	caServer.Ledger = opts.Ledger
+	caServer.Revoker = opts.Revoker
 
 	// TODO: if not set, parse Istiod's own token (if present) and get the issuer. The same issuer is used
 	// for all tokens - no need to configure twice. The token may also include cluster info to auto-configure
@@ -330,10 +378,25 @@ func (s *Server) loadRemoteCACerts(caOpts *caOptions, dir string) error {
 	}
 
 	log.Infof("cacerts Secret found in remote cluster, saving contents to %s", dir)
+	return writeCACerts(dir, secret.Data)
+}
+
+// syncRemoteCACerts refreshes the files loaded by loadRemoteCACerts, so that changes of the remote
+// cacerts Secret are rotated to.
+func (s *Server) syncRemoteCACerts(namespace, dir string) error {
+	secret, err := s.kubeClient.Kube().CoreV1().Secrets(namespace).Get(
+		context.TODO(), "cacerts", metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return writeCACerts(dir, secret.Data)
+}
+
+func writeCACerts(dir string, data map[string][]byte) error {
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return err
 	}
-	for key, data := range secret.Data {
+	for key, data := range data {
 		filename := path.Join(dir, key)
 		if err := ioutil.WriteFile(filename, data, 0600); err != nil {
 			return err
@@ -342,6 +405,188 @@ func (s *Server) loadRemoteCACerts(caOpts *caOptions, dir string) error {
 	return nil
 }
 
+// pluggedCertRotatorConfig returns the config of the rotation of the plugged-in certificates, or nil
+// if it is disabled.
+func (s *Server) pluggedCertRotatorConfig(opts *caOptions) *ca.PluggedCertRotatorConfig {
+	if pluggedCertCheckInterval.Get() <= 0 {
+		return nil
+	}
+	config := &ca.PluggedCertRotatorConfig{
+		CertDir:                 LocalCertDir.Get(),
+		CheckInterval:           pluggedCertCheckInterval.Get(),
+		TrustDistributionPeriod: pluggedCertTrustDistributionPeriod.Get(),
+		OldRootRetentionPeriod:  pluggedCertOldRootRetentionPeriod.Get(),
+		// The root cert ConfigMaps pick up the new roots on their next resync, and workloads with their
+		// next certificate, which holds the roots.
+		OnRootCertsChanged: func() {
+			if features.MultiRootMesh.Get() {
+				if err := s.addIstioCAToTrustBundle(opts.Namespace); err != nil {
+					log.Errorf("failed to update the trust bundle with the rotated CA roots: %v", err)
+				}
+			}
+		},
+	}
+	if config.TrustDistributionPeriod <= 0 {
+		config.TrustDistributionPeriod = workloadCertTTL.Get()
+	}
+	if config.OldRootRetentionPeriod <= 0 {
+		config.OldRootRetentionPeriod = workloadCertTTL.Get()
+	}
+	if useRemoteCerts.Get() && s.kubeClient != nil {
+		config.Sync = func() error {
+			return s.syncRemoteCACerts(opts.Namespace, LocalCertDir.Get())
+		}
+	}
+	if s.kubeClient != nil {
+		// All the replicas resume a rotation from the persisted state, only the leader writes it. The state
+		// holds the CA keys, it is only persisted when the rotation is enabled.
+		state := ca.NewSecretRotationStateStore(s.kubeClient.CoreV1(), opts.Namespace)
+		config.State = state
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			leaderelection.
+				NewLeaderElection(opts.Namespace, podNameVar.Get(), leaderelection.PluggedCARotationController, s.kubeClient).
+				AddRunFunction(state.Persist).
+				Run(stop)
+			return nil
+		})
+	}
+	return config
+}
+
+const (
+	revokedSerialsKey    = "serials"
+	revokedIdentitiesKey = "identities"
+	crlKey               = "crl.pem"
+)
+
+// initCARevocation records the certificates issued by the Istio CA, and publishes the ones revoked
+// in the revocations ConfigMap to proxies, with the trust bundle. The CRL is signed by the leader
+// istiod and published in the CRL ConfigMap, so that all the replicas serve the same CRL.
+func (s *Server) initCARevocation(opts *caOptions) error {
+	var store ledger.Store = ledger.NewMemoryStore()
+	if p := certLedgerPath.Get(); p != "" {
+		fs, err := ledger.NewFileStore(p)
+		if err != nil {
+			return err
+		}
+		store = fs
+	}
+	opts.Ledger = store
+	s.XDSServer.CALedger = store
+	if !enableCertRevocation.Get() {
+		log.Infof("certificate revocation is disabled, set CITADEL_ENABLE_CERT_REVOCATION to enable it")
+		return nil
+	}
+	if s.kubeClient != nil {
+		log.Warnf("certificate revocation is enabled: proxies only reject the revoked certificates if their " +
+			"agent runs with PROXY_CONFIG_XDS_AGENT=true. The certificate ledger is not shared between the istiod " +
+			"replicas, identities are only revoked in the certificates issued by the leader istiod: revoke the " +
+			"serials of the certificates issued by the other replicas, listed by their /debug/issuedcertz endpoint")
+	}
+	chainCRLFile := path.Join(LocalCertDir.Get(), ca.CRLChainID)
+	chainCRLs, err := ioutil.ReadFile(chainCRLFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// The chain CRLs are read again, so that the CRLs renewed by the CAs above are served without a restart.
+	config := ledger.RevokerConfig{
+		CRLValidity:              crlValidity.Get(),
+		ChainRevocationLists:     chainCRLs,
+		ChainRevocationListsFile: chainCRLFile,
+		OnChange: func() {
+			s.XDSServer.ConfigUpdate(&model.PushRequest{
+				Full:   true,
+				Reason: []model.TriggerReason{model.GlobalUpdate},
+			})
+		},
+	}
+	if s.kubeClient != nil {
+		config.Publish = func(crl []byte) error {
+			return s.publishCRL(opts.Namespace, crl)
+		}
+	}
+	revoker, err := ledger.NewRevoker(store, s.CA.GetCAKeyCertBundle(), config)
+	if err != nil {
+		return err
+	}
+	opts.Revoker = revoker
+	s.XDSServer.CARevoker = revoker
+
+	if s.kubeClient != nil {
+		c := configmapwatcher.NewController(s.kubeClient, opts.Namespace, revocationsConfigMap.Get(), func(cm *v1.ConfigMap) {
+			revocations := revocationsFromConfigMap(cm)
+			if len(revocations.Identities) > 0 {
+				log.Warnf("revoking identities %v: only the certificates issued by the leader istiod are revoked, "+
+					"the certificate ledger is not shared between the istiod replicas", revocations.Identities)
+			}
+			if err := revoker.Update(revocations); err != nil {
+				log.Errorf("failed to revoke the certificates listed in ConfigMap %s: %v", revocationsConfigMap.Get(), err)
+			}
+		})
+		crls := configmapwatcher.NewController(s.kubeClient, opts.Namespace, crlConfigMap.Get(), func(cm *v1.ConfigMap) {
+			var crl []byte
+			if cm != nil {
+				crl = []byte(cm.Data[crlKey])
+			}
+			if err := revoker.SetRevocationList(crl); err != nil {
+				log.Errorf("failed to serve the CRL of ConfigMap %s: %v", crlConfigMap.Get(), err)
+			}
+		})
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go c.Run(stop)
+			go crls.Run(stop)
+			return nil
+		})
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			leaderelection.
+				NewLeaderElection(opts.Namespace, podNameVar.Get(), leaderelection.CARevocationController, s.kubeClient).
+				AddRunFunction(revoker.Lead).
+				Run(stop)
+			return nil
+		})
+	}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go revoker.Run(stop)
+		return nil
+	})
+	return nil
+}
+
+// publishCRL writes the CRL signed by the leader to the CRL ConfigMap, from which all the istiod
+// replicas serve it.
+func (s *Server) publishCRL(namespace string, crl []byte) error {
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(context.TODO(), crlConfigMap.Get(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(context.TODO(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: crlConfigMap.Get(), Namespace: namespace},
+			Data:       map[string]string{crlKey: string(crl)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[crlKey] == string(crl) {
+		return nil
+	}
+	cm.Data = map[string]string{crlKey: string(crl)}
+	_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
+
+// revocationsFromConfigMap returns the revoked serials and identities listed in the ConfigMap, or
+// nothing if it doesn't exist.
+func revocationsFromConfigMap(cm *v1.ConfigMap) ledger.Revocations {
+	if cm == nil {
+		return ledger.Revocations{}
+	}
+	return ledger.Revocations{
+		Serials:    strings.Fields(cm.Data[revokedSerialsKey]),
+		Identities: strings.Fields(cm.Data[revokedIdentitiesKey]),
+	}
+}
+
 // createIstioCA initializes the Istio CA signing functionality.
 // - for 'plugged in', uses ./etc/cacert directory, mounted from 'cacerts' secret in k8s.
 //   Inside, the key/cert are 'ca-key.pem' and 'ca-cert.pem'. The root cert signing the intermeidate is root-cert.pem,
@@ -391,16 +636,21 @@ func (s *Server) createIstioCA(client corev1.CoreV1Interface, opts *caOptions) (
 		//
 		certChainFile := path.Join(LocalCertDir.Get(), "cert-chain.pem")
 		s.caBundlePath = certChainFile
+		keyFileExists := err == nil
 		caOpts, err = ca.NewPluggedCertIstioCAOptions(certChainFile, signingCertFile, signingKeyFile,
 			rootCertFile, workloadCertTTL.Get(), maxWorkloadCertTTL.Get(), caRSAKeySize.Get())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
 		}
+		if keyFileExists {
+			caOpts.PluggedCertRotatorConfig = s.pluggedCertRotatorConfig(opts)
+		}
 	}
 	istioCA, err := ca.NewIstioCA(caOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
 	}
+	s.XDSServer.CARotationStatus = istioCA.PluggedCertRotationStatus
 	// TODO: provide an endpoint returning all the roots. SDS can only pull a single root in current impl.
 	// ca.go saves or uses the secret, but also writes to the configmap "istio-security", under caTLSRootCert
 	// rootCertRotatorChan channel accepts signals to stop root cert rotator for
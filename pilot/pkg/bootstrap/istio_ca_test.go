@@ -111,3 +111,18 @@ func createCASecret(client kube.Client) error {
 func readSampleCertFromFile(f string) ([]byte, error) {
 	return ioutil.ReadFile(path.Join(env.IstioSrc, "samples/certs", f))
 }
+
+func TestRevocationsFromConfigMap(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(revocationsFromConfigMap(nil).IsEmpty()).To(BeTrue())
+
+	revs := revocationsFromConfigMap(&v1.ConfigMap{
+		Data: map[string]string{
+			revokedSerialsKey:    "1a2b\n  3c:4d\n\n",
+			revokedIdentitiesKey: "spiffe://cluster.local/ns/a/sa/a\nspiffe://cluster.local/ns/b/sa/b\n",
+		},
+	})
+	g.Expect(revs.Serials).To(Equal([]string{"1a2b", "3c:4d"}))
+	g.Expect(revs.Identities).To(Equal([]string{"spiffe://cluster.local/ns/a/sa/a", "spiffe://cluster.local/ns/b/sa/b"}))
+}
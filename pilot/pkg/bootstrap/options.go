@@ -64,6 +64,9 @@ type PilotArgs struct {
 	KeepaliveOptions   *keepalive.Options
 	ShutdownDuration   time.Duration
 	JwtRule            string
+	// PlatformAttestationConfig is the path of the platformauth.Config authenticating VMs by
+	// their cloud platform instance identity.
+	PlatformAttestationConfig string
 }
 
 // DiscoveryServerOptions contains options for create a new discovery server instance.
@@ -116,6 +119,9 @@ var (
 	podNameVar      = env.RegisterStringVar("POD_NAME", "", "")
 	jwtRuleVar      = env.RegisterStringVar("JWT_RULE", "",
 		"The JWT rule used by istiod authentication")
+	platformAttestationConfigVar = env.RegisterStringVar("PLATFORM_ATTESTATION_CONFIG", "",
+		"Path of the configuration authenticating VMs by their AWS or Azure instance identity document, "+
+			"and mapping them to WorkloadGroups")
 )
 
 // RevisionVar is the value of the Istio control plane revision, e.g. "canary",
@@ -150,6 +156,7 @@ func (p *PilotArgs) applyDefaults() {
 	p.PodName = podNameVar.Get()
 	p.Revision = RevisionVar.Get()
 	p.JwtRule = jwtRuleVar.Get()
+	p.PlatformAttestationConfig = platformAttestationConfigVar.Get()
 	p.KeepaliveOptions = keepalive.DefaultOption()
 	p.RegistryOptions.DistributionTrackingEnabled = features.EnableDistributionTracking
 	p.RegistryOptions.DistributionCacheRetention = features.DistributionHistoryRetention
@@ -42,7 +42,9 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
+	"istio.io/api/networking/v1alpha3"
 	"istio.io/api/security/v1beta1"
+	remoteconfig "istio.io/istio/pilot/pkg/config/kube/remote"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
@@ -59,6 +61,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
 	istiokeepalive "istio.io/istio/pkg/keepalive"
@@ -71,6 +74,7 @@ import (
 	"istio.io/istio/security/pkg/pki/ra"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 	"istio.io/istio/security/pkg/server/ca/authenticate/kubeauth"
+	"istio.io/istio/security/pkg/server/ca/authenticate/platformauth"
 	"istio.io/pkg/ctrlz"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
@@ -122,6 +126,10 @@ type Server struct {
 	ConfigStores      []model.ConfigStoreCache
 	serviceEntryStore *serviceentry.ServiceEntryStore
 
+	// remoteConfigStores are the config stores of the k8s://<cluster> config sources, fed by the remote
+	// secrets of the clusters.
+	remoteConfigStores []*remoteconfig.Controller
+
 	httpServer       *http.Server // debug, monitoring and readiness Server.
 	httpsServer      *http.Server // webhooks HTTPS Server.
 	httpsReadyClient *http.Client
@@ -176,6 +184,14 @@ type Server struct {
 	RWConfigStore model.ConfigStoreCache
 }
 
+// WithKubeClient returns an init function for NewServer, setting the kube client used by the server instead of
+// creating one from the kubeconfig. This allows running istiod in process against a fake client.
+func WithKubeClient(client kubelib.Client) func(*Server) {
+	return func(s *Server) {
+		s.kubeClient = client
+	}
+}
+
 // NewServer creates a new Server instance based on the provided arguments.
 func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	e := &model.Environment{
@@ -238,6 +254,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 
 	s.initMeshNetworks(args, s.fileWatcher)
 	s.initMeshHandlers()
+	if err := s.initJwksFetchPolicyWatcher(); err != nil {
+		return nil, err
+	}
 	s.environment.Init()
 
 	// Options based on the current 'defaults' in istio.
@@ -311,6 +330,8 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 
 	s.initDiscoveryService(args)
 
+	s.initConnectionPoolTuner(args)
+
 	s.initSDSServer(args)
 
 	// Notice that the order of authenticators matters, since at runtime
@@ -331,6 +352,13 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		}
 		authenticators = append(authenticators, jwtAuthn)
 	}
+	if args.PlatformAttestationConfig != "" {
+		platformAuthn, err := s.initPlatformAuthenticators(args)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing platform authenticators: %v", err)
+		}
+		authenticators = append(authenticators, platformAuthn...)
+	}
 	if features.XDSAuth {
 		s.XDSServer.Authenticators = authenticators
 	}
@@ -376,6 +404,33 @@ func initOIDC(args *PilotArgs, trustDomain string) (security.Authenticator, erro
 	return jwtAuthn, nil
 }
 
+// initPlatformAuthenticators creates the authenticators of VMs presenting an instance identity
+// document signed by their cloud platform. Instances are granted the service account of the
+// WorkloadGroup they map to.
+func (s *Server) initPlatformAuthenticators(args *PilotArgs) ([]security.Authenticator, error) {
+	cfg, err := platformauth.LoadConfig(args.PlatformAttestationConfig)
+	if err != nil {
+		return nil, err
+	}
+	resolver := func(namespace, name string) (string, error) {
+		groupCfg := s.environment.IstioConfigStore.Get(gvk.WorkloadGroup, name, namespace)
+		if groupCfg == nil {
+			return "", fmt.Errorf("cannot find WorkloadGroup %s/%s", namespace, name)
+		}
+		return groupCfg.Spec.(*v1alpha3.WorkloadGroup).GetTemplate().GetServiceAccount(), nil
+	}
+	// Bindings of AWS instances to their nonce must be shared by the replicas and survive
+	// restarts, or a replayed document would be accepted by another replica.
+	var ledger platformauth.InstanceLedger
+	if s.kubeClient != nil {
+		ledger = platformauth.NewSecretLedger(s.kubeClient, args.Namespace)
+	} else if cfg.AWS != nil {
+		log.Warnf("No Kubernetes client, AWS instance nonces are only kept in memory")
+	}
+	log.Infof("Istiod authenticating VMs using platform attestation config %s", args.PlatformAttestationConfig)
+	return platformauth.NewAuthenticators(cfg, s.environment.Mesh().TrustDomain, resolver, ledger)
+}
+
 func getClusterID(args *PilotArgs) string {
 	clusterID := args.RegistryOptions.KubeOptions.ClusterID
 	if clusterID == "" {
@@ -515,6 +570,10 @@ func (s *Server) initSDSServer(args *PilotArgs) {
 // This is determined by the presence of a kube registry, which
 // uses in-context k8s, or a config source of type k8s.
 func (s *Server) initKubeClient(args *PilotArgs) error {
+	if s.kubeClient != nil {
+		// Client was already set by an init function, see WithKubeClient.
+		return nil
+	}
 	hasK8SConfigStore := false
 	if args.RegistryOptions.FileDir == "" {
 		// If file dir is set - config controller will just use file.
@@ -903,6 +962,7 @@ func (s *Server) initRegistryEventHandlers() {
 
 			s.configController.RegisterEventHandler(schema.Resource().GroupVersionKind(), configHandler)
 		}
+		s.configController.RegisterEventHandler(ratelimit.GroupVersionKind, configHandler)
 	}
 }
 
@@ -1011,6 +1071,40 @@ func (s *Server) initCertificateWatches(tlsOptions TLSOptions) error {
 	return nil
 }
 
+// initJwksFetchPolicyWatcher reloads the per-issuer JWKS fetch policies when their file, typically mounted from a
+// ConfigMap, changes.
+func (s *Server) initJwksFetchPolicyWatcher() error {
+	file := features.JwksFetchPolicyFile
+	if file == "" {
+		return nil
+	}
+	if err := s.fileWatcher.Add(file); err != nil {
+		return fmt.Errorf("could not watch %v: %v", file, err)
+	}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go func() {
+			var timerC <-chan time.Time
+			for {
+				select {
+				case <-timerC:
+					timerC = nil
+					s.XDSServer.ReloadJwksFetchPolicies()
+				case <-s.fileWatcher.Events(file):
+					if timerC == nil {
+						timerC = time.After(watchDebounceDelay)
+					}
+				case err := <-s.fileWatcher.Errors(file):
+					log.Errorf("error watching %v: %v", file, err)
+				case <-stop:
+					return
+				}
+			}
+		}()
+		return nil
+	})
+	return nil
+}
+
 // getCertKeyPair returns cert and key loaded in tls.Certificate.
 func (s *Server) getCertKeyPair(tlsOptions TLSOptions) (tls.Certificate, error) {
 	key, cert := s.getCertKeyPaths(tlsOptions)
@@ -1124,6 +1218,11 @@ func (s *Server) maybeCreateCA(caOpts *caOptions) error {
 				return fmt.Errorf("failed to create RA: %v", err)
 			}
 		}
+		if s.RA == nil {
+			if err = s.initCARevocation(caOpts); err != nil {
+				return fmt.Errorf("failed to initialize certificate revocation: %v", err)
+			}
+		}
 		if err = s.initPublicKey(); err != nil {
 			return fmt.Errorf("error initializing public key: %v", err)
 		}
@@ -1182,7 +1281,7 @@ func (s *Server) initMeshHandlers() {
 	})
 }
 
-func (s *Server) addIstioCAToTrustBundle(args *PilotArgs) error {
+func (s *Server) addIstioCAToTrustBundle(namespace string) error {
 	var err error
 	if s.CA != nil {
 		// If IstioCA is setup, derive trustAnchor directly from CA
@@ -1192,7 +1291,7 @@ func (s *Server) addIstioCAToTrustBundle(args *PilotArgs) error {
 			Source:            tb.SourceIstioCA,
 		})
 		if err != nil {
-			log.Errorf("unable to add CA root from namespace %s as trustAnchor", args.Namespace)
+			log.Errorf("unable to add CA root from namespace %s as trustAnchor", namespace)
 			return err
 		}
 		return nil
@@ -1231,7 +1330,7 @@ func (s *Server) initWorkloadTrustBundle(args *PilotArgs) error {
 		_ = s.workloadTrustBundle.AddMeshConfigUpdate(s.environment.Mesh())
 	})
 
-	err = s.addIstioCAToTrustBundle(args)
+	err = s.addIstioCAToTrustBundle(args.Namespace)
 	if err != nil {
 		return err
 	}
@@ -17,6 +17,8 @@ package bootstrap
 import (
 	"fmt"
 
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
@@ -37,6 +39,16 @@ func (s *Server) initServiceControllers(args *PilotArgs) error {
 
 	s.serviceEntryStore = serviceentry.NewServiceDiscovery(s.configController, s.environment.IstioConfigStore, s.XDSServer)
 	serviceControllers.AddRegistry(s.serviceEntryStore)
+	if features.PersistAutoAllocatedIPs && s.kubeClient != nil {
+		// Only the leader records the auto allocated addresses, the other replicas read them from the status.
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			leaderelection.
+				NewLeaderElection(args.Namespace, args.PodName, leaderelection.ServiceEntryController, s.kubeClient).
+				AddRunFunction(s.serviceEntryStore.PersistAutoAllocatedAddresses).
+				Run(stop)
+			return nil
+		})
+	}
 
 	registered := make(map[serviceregistry.ProviderID]bool)
 	for _, r := range args.RegistryOptions.Registries {
@@ -95,6 +107,11 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 		s.environment.ClusterLocal(),
 		s.server)
 
+	// The config stores of remote config sources follow the remote secrets of their cluster.
+	for _, c := range s.remoteConfigStores {
+		mc.AddClusterHandler(c)
+	}
+
 	// initialize the "main" cluster registry before starting controllers for remote clusters
 	if err := mc.AddMemberCluster(s.kubeClient, args.RegistryOptions.KubeOptions.ClusterID); err != nil {
 		log.Errorf("failed initializing registry for %s: %v", args.RegistryOptions.KubeOptions.ClusterID, err)
@@ -111,6 +128,7 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 	})
 
 	s.multicluster = mc
+	s.XDSServer.ClusterStatuses = mc.ClusterStatuses
 	return
 }
 
@@ -29,7 +29,7 @@ var errorUnsupported = errors.New("unsupported operation: the config aggregator
 
 // makeStore creates an aggregate config store from several config stores and
 // unifies their descriptors
-func makeStore(stores []model.ConfigStore, writer model.ConfigStore) (model.ConfigStore, error) {
+func makeStore(stores []model.ConfigStore, writer model.ConfigStore, statusOwners ...model.ConfigStore) (model.ConfigStore, error) {
 	union := collection.NewSchemasBuilder()
 	storeTypes := make(map[config.GroupVersionKind][]model.ConfigStore)
 	for _, store := range stores {
@@ -48,9 +48,10 @@ func makeStore(stores []model.ConfigStore, writer model.ConfigStore) (model.Conf
 		return nil, err
 	}
 	result := &store{
-		schemas: schemas,
-		stores:  storeTypes,
-		writer:  writer,
+		schemas:      schemas,
+		stores:       storeTypes,
+		writer:       writer,
+		statusOwners: statusOwners,
 	}
 
 	return result, nil
@@ -59,11 +60,29 @@ func makeStore(stores []model.ConfigStore, writer model.ConfigStore) (model.Conf
 // MakeWriteableCache creates an aggregate config store cache from several config store caches. An additional
 // `writer` config store is passed, which may or may not be part of `caches`.
 func MakeWriteableCache(caches []model.ConfigStoreCache, writer model.ConfigStore) (model.ConfigStoreCache, error) {
+	return MakeWriteableCacheWithStatusOwners(caches, writer, nil)
+}
+
+// MakeCache creates an aggregate config store cache from several config store
+// caches.
+func MakeCache(caches []model.ConfigStoreCache) (model.ConfigStoreCache, error) {
+	return MakeWriteableCache(caches, nil)
+}
+
+// MakeWriteableCacheWithStatusOwners creates an aggregate config store cache like MakeWriteableCache, except
+// that the status of a config read from one of the `statusOwners` is written back to that store rather than to
+// the writer. This is used to write the status of the configs of remote clusters to the cluster they come from.
+func MakeWriteableCacheWithStatusOwners(caches []model.ConfigStoreCache, writer model.ConfigStore,
+	statusOwners []model.ConfigStoreCache) (model.ConfigStoreCache, error) {
 	stores := make([]model.ConfigStore, 0, len(caches))
 	for _, cache := range caches {
 		stores = append(stores, cache)
 	}
-	store, err := makeStore(stores, writer)
+	owners := make([]model.ConfigStore, 0, len(statusOwners))
+	for _, owner := range statusOwners {
+		owners = append(owners, owner)
+	}
+	store, err := makeStore(stores, writer, owners...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,12 +92,6 @@ func MakeWriteableCache(caches []model.ConfigStoreCache, writer model.ConfigStor
 	}, nil
 }
 
-// MakeCache creates an aggregate config store cache from several config store
-// caches.
-func MakeCache(caches []model.ConfigStoreCache) (model.ConfigStoreCache, error) {
-	return MakeWriteableCache(caches, nil)
-}
-
 type store struct {
 	// schemas is the unified
 	schemas collection.Schemas
@@ -87,6 +100,9 @@ type store struct {
 	stores map[config.GroupVersionKind][]model.ConfigStore
 
 	writer model.ConfigStore
+
+	// statusOwners are the stores the status of their own configs is written to.
+	statusOwners []model.ConfigStore
 }
 
 func (cr *store) Schemas() collection.Schemas {
@@ -153,12 +169,35 @@ func (cr *store) Update(c config.Config) (string, error) {
 }
 
 func (cr *store) UpdateStatus(c config.Config) (string, error) {
+	if owner := cr.statusOwner(c); owner != nil {
+		return owner.UpdateStatus(c)
+	}
 	if cr.writer == nil {
 		return "", errorUnsupported
 	}
 	return cr.writer.UpdateStatus(c)
 }
 
+// statusOwner returns the store the config is read from if it is one of the status owners, or nil.
+func (cr *store) statusOwner(c config.Config) model.ConfigStore {
+	if len(cr.statusOwners) == 0 {
+		return nil
+	}
+	for _, store := range cr.stores[c.GroupVersionKind] {
+		if store.Get(c.GroupVersionKind, c.Name, c.Namespace) == nil {
+			continue
+		}
+		for _, owner := range cr.statusOwners {
+			if store == owner {
+				return store
+			}
+		}
+		// The config is read from a store with a higher precedence than the owners.
+		return nil
+	}
+	return nil
+}
+
 func (cr *store) Patch(orig config.Config, patchFn config.PatchFunc) (string, error) {
 	if cr.writer == nil {
 		return "", errorUnsupported
@@ -179,6 +179,40 @@ func TestAggregateStoreWriteWithoutWriter(t *testing.T) {
 	}
 }
 
+func TestAggregateStoreUpdateStatusOwners(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	kind := collections.K8SServiceApisV1Alpha1Httproutes.Resource().GroupVersionKind()
+	local := memory.NewController(memory.Make(collection.SchemasFor(collections.K8SServiceApisV1Alpha1Httproutes)))
+	remote := memory.NewController(memory.Make(collection.SchemasFor(collections.K8SServiceApisV1Alpha1Httproutes)))
+	for _, c := range []struct {
+		store model.ConfigStore
+		name  string
+	}{{local, "shadowed"}, {remote, "shadowed"}, {remote, "remote"}} {
+		if _, err := c.store.Create(config.Config{Meta: config.Meta{GroupVersionKind: kind, Name: c.name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := MakeWriteableCacheWithStatusOwners([]model.ConfigStoreCache{local, remote}, local, []model.ConfigStoreCache{remote})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// The status of configs read from the owner is written back to it.
+	remoteConfig := store.Get(kind, "remote", "")
+	remoteConfig.Status = "written to remote"
+	_, err = store.UpdateStatus(*remoteConfig)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(remote.Get(kind, "remote", "").Status).To(gomega.Equal("written to remote"))
+
+	// Configs read from a store with a higher precedence are written to the writer.
+	shadowed := store.Get(kind, "shadowed", "")
+	shadowed.Status = "written to local"
+	_, err = store.UpdateStatus(*shadowed)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(local.Get(kind, "shadowed", "").Status).To(gomega.Equal("written to local"))
+	g.Expect(remote.Get(kind, "shadowed", "").Status).To(gomega.BeNil())
+}
+
 func TestAggregateStoreFails(t *testing.T) {
 	g := gomega.NewWithT(t)
 
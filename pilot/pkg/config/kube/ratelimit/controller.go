@@ -0,0 +1,302 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit provides a read-only view of the Kubernetes LocalRateLimit resources
+// as a config store.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/ratelimit"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/queue"
+	"istio.io/pkg/log"
+)
+
+// Schema describes the LocalRateLimit collection.
+var Schema = collection.Builder{
+	Name:         "k8s/extensions.istio.io/v1alpha1/localratelimits",
+	VariableName: "K8SExtensionsIstioIoV1Alpha1Localratelimits",
+	Resource: resource.Builder{
+		Group:         ratelimit.GroupVersionKind.Group,
+		Kind:          ratelimit.GroupVersionKind.Kind,
+		Plural:        ratelimit.Plural,
+		Version:       ratelimit.GroupVersionKind.Version,
+		ReflectType:   reflect.TypeOf(&ratelimit.LocalRateLimit{}).Elem(),
+		ValidateProto: validation.ValidateLocalRateLimitConfig,
+	}.MustBuild(),
+}.MustBuild()
+
+var gvr = schema.GroupVersionResource{
+	Group:    ratelimit.GroupVersionKind.Group,
+	Version:  ratelimit.GroupVersionKind.Version,
+	Resource: ratelimit.Plural,
+}
+
+var errUnsupportedOp = errors.New("unsupported operation: the LocalRateLimit config store is a read-only view")
+
+type controller struct {
+	revision     string
+	domainSuffix string
+
+	informer cache.SharedIndexInformer
+	lister   cache.GenericLister
+	queue    queue.Instance
+	handlers []func(config.Config, config.Config, model.Event)
+}
+
+// Available returns true if the LocalRateLimit CRD is installed in the cluster.
+func Available(client kube.Client) bool {
+	name := ratelimit.Plural + "." + ratelimit.GroupVersionKind.Group
+	_, err := client.Ext().ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		log.Errorf("failed to get CRD %s: %v", name, err)
+	}
+	return err == nil
+}
+
+// NewController creates a config store serving the LocalRateLimit resources of the cluster.
+func NewController(client kube.Client, revision, domainSuffix string) model.ConfigStoreCache {
+	// queue requires a time duration for a retry delay after a handler error
+	q := queue.NewQueue(1 * time.Second)
+
+	informer := client.DynamicInformer().ForResource(gvr)
+	c := &controller{
+		revision:     revision,
+		domainSuffix: domainSuffix,
+		informer:     informer.Informer(),
+		lister:       informer.Lister(),
+		queue:        q,
+	}
+
+	c.informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				q.Push(func() error {
+					return c.onEvent(nil, obj, model.EventAdd)
+				})
+			},
+			UpdateFunc: func(old, cur interface{}) {
+				if !reflect.DeepEqual(old, cur) {
+					q.Push(func() error {
+						return c.onEvent(old, cur, model.EventUpdate)
+					})
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				q.Push(func() error {
+					return c.onEvent(nil, obj, model.EventDelete)
+				})
+			},
+		})
+
+	return c
+}
+
+func (c *controller) onEvent(oldObj, curObj interface{}, event model.Event) error {
+	if !c.HasSynced() {
+		return errors.New("waiting till full synchronization")
+	}
+
+	cur, err := toUnstructured(curObj)
+	if err != nil {
+		return err
+	}
+	var old config.Config
+	if oldObj != nil {
+		o, err := toUnstructured(oldObj)
+		if err != nil {
+			return err
+		}
+		if !c.inRevision(o) && !c.inRevision(cur) {
+			return nil
+		}
+		old = convertMeta(o, c.domainSuffix)
+	} else if !c.inRevision(cur) {
+		return nil
+	}
+
+	// The spec is not converted: handlers only need the metadata, and invalid resources are skipped on List.
+	curr := convertMeta(cur, c.domainSuffix)
+	for _, f := range c.handlers {
+		f(old, curr, event)
+	}
+	return nil
+}
+
+func (c *controller) inRevision(u *unstructured.Unstructured) bool {
+	rev, f := u.GetLabels()[label.IoIstioRev.Name]
+	// Resources without revision label are global, and always included.
+	return !f || rev == c.revision
+}
+
+func (c *controller) RegisterEventHandler(kind config.GroupVersionKind, f func(config.Config, config.Config, model.Event)) {
+	if kind == ratelimit.GroupVersionKind {
+		c.handlers = append(c.handlers, f)
+	}
+}
+
+func (c *controller) HasSynced() bool {
+	return c.informer.HasSynced()
+}
+
+func (c *controller) Run(stop <-chan struct{}) {
+	go func() {
+		cache.WaitForCacheSync(stop, c.HasSynced)
+		c.queue.Run(stop)
+	}()
+	<-stop
+}
+
+func (c *controller) Schemas() collection.Schemas {
+	return collection.SchemasFor(Schema)
+}
+
+func (c *controller) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	if typ != ratelimit.GroupVersionKind {
+		return nil
+	}
+	obj, err := c.lister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		return nil
+	}
+	u, err := toUnstructured(obj)
+	if err != nil || !c.inRevision(u) {
+		return nil
+	}
+	cfg, err := convert(u, c.domainSuffix)
+	if err != nil {
+		log.Warnf("ignoring LocalRateLimit: %v", err)
+		return nil
+	}
+	return cfg
+}
+
+func (c *controller) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	if typ != ratelimit.GroupVersionKind {
+		return nil, errUnsupportedOp
+	}
+
+	var objs []runtime.Object
+	var err error
+	if namespace == metav1.NamespaceAll {
+		objs, err = c.lister.List(klabels.Everything())
+	} else {
+		objs, err = c.lister.ByNamespace(namespace).List(klabels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]config.Config, 0, len(objs))
+	for _, obj := range objs {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		if !c.inRevision(u) {
+			continue
+		}
+		cfg, err := convert(u, c.domainSuffix)
+		if err != nil {
+			log.Warnf("ignoring LocalRateLimit: %v", err)
+			continue
+		}
+		out = append(out, *cfg)
+	}
+	return out, nil
+}
+
+func (c *controller) Create(_ config.Config) (string, error) {
+	return "", errUnsupportedOp
+}
+
+func (c *controller) Update(_ config.Config) (string, error) {
+	return "", errUnsupportedOp
+}
+
+func (c *controller) UpdateStatus(config.Config) (string, error) {
+	return "", errUnsupportedOp
+}
+
+func (c *controller) Patch(_ config.Config, _ config.PatchFunc) (string, error) {
+	return "", errUnsupportedOp
+}
+
+func (c *controller) Delete(_ config.GroupVersionKind, _, _ string, _ *string) error {
+	return errUnsupportedOp
+}
+
+func toUnstructured(obj interface{}) (*unstructured.Unstructured, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	return u, nil
+}
+
+func convertMeta(u *unstructured.Unstructured, domainSuffix string) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind:  ratelimit.GroupVersionKind,
+			Name:              u.GetName(),
+			Namespace:         u.GetNamespace(),
+			Domain:            domainSuffix,
+			Labels:            u.GetLabels(),
+			Annotations:       u.GetAnnotations(),
+			ResourceVersion:   u.GetResourceVersion(),
+			CreationTimestamp: u.GetCreationTimestamp().Time,
+			Generation:        u.GetGeneration(),
+		},
+	}
+}
+
+// convert converts a LocalRateLimit resource to a validated config.
+func convert(u *unstructured.Unstructured, domainSuffix string) (*config.Config, error) {
+	cfg := convertMeta(u, domainSuffix)
+	spec, err := json.Marshal(u.Object["spec"])
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", cfg.Namespace, cfg.Name, err)
+	}
+	if cfg.Spec, err = ratelimit.Parse(string(spec)); err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", cfg.Namespace, cfg.Name, err)
+	}
+	if _, err := Schema.Resource().ValidateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", cfg.Namespace, cfg.Name, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+func TestConvert(t *testing.T) {
+	object := func(spec map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "extensions.istio.io/v1alpha1",
+			"kind":       "LocalRateLimit",
+			"metadata": map[string]interface{}{
+				"name":      "reviews",
+				"namespace": "default",
+			},
+		}}
+		if spec != nil {
+			u.Object["spec"] = spec
+		}
+		return u
+	}
+	cases := []struct {
+		name    string
+		in      *unstructured.Unstructured
+		want    *ratelimit.LocalRateLimit
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			in: object(map[string]interface{}{
+				"workloadSelector": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "reviews"},
+				},
+				"listeners": []interface{}{
+					map[string]interface{}{
+						"port": int64(9080),
+						"tokenBucket": map[string]interface{}{
+							"maxTokens":    int64(100),
+							"fillInterval": "1s",
+						},
+					},
+				},
+			}),
+			want: &ratelimit.LocalRateLimit{
+				WorkloadSelector: &ratelimit.WorkloadSelector{Labels: map[string]string{"app": "reviews"}},
+				Listeners: []ratelimit.ListenerRateLimit{{
+					Port:        9080,
+					TokenBucket: ratelimit.TokenBucket{MaxTokens: 100, FillInterval: "1s"},
+				}},
+			},
+		},
+		{
+			name: "no spec",
+			in:   object(nil),
+			want: &ratelimit.LocalRateLimit{},
+		},
+		{
+			name:    "unknown field",
+			in:      object(map[string]interface{}{"listener": []interface{}{}}),
+			wantErr: true,
+		},
+		{
+			name: "invalid",
+			in: object(map[string]interface{}{
+				"routes": []interface{}{
+					map[string]interface{}{
+						"name":        "limited",
+						"tokenBucket": map[string]interface{}{"maxTokens": int64(0), "fillInterval": "1s"},
+					},
+				},
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convert(tt.in, "cluster.local")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.GroupVersionKind != ratelimit.GroupVersionKind || got.Name != "reviews" || got.Namespace != "default" {
+				t.Errorf("got metadata %+v", got.Meta)
+			}
+			if !reflect.DeepEqual(got.Spec, tt.want) {
+				t.Errorf("got spec %+v, want %+v", got.Spec, tt.want)
+			}
+		})
+	}
+}
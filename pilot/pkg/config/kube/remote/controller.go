@@ -0,0 +1,354 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote implements a config store reading the Istio resources of a remote cluster, configured with a
+// `k8s://<cluster>` config source.
+package remote
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+var scope = log.RegisterScope("remoteconfig", "Remote cluster config sources", 0)
+
+var (
+	clusterTag = monitoring.MustCreateLabel("cluster")
+
+	remoteSecretPresent = monitoring.NewGauge(
+		"pilot_remote_config_source_connected",
+		"Whether the remote secret of the cluster of a k8s://<cluster> config source is present.",
+		monitoring.WithLabels(clusterTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(remoteSecretPresent)
+}
+
+// missingSecretReportInterval is how often a config source without remote secret is reported.
+const missingSecretReportInterval = time.Minute
+
+type handler = func(config.Config, config.Config, model.Event)
+
+// Controller is a config store for the Istio resources of a remote cluster. The cluster is accessed with the
+// credentials of its remote secret: the store is not synced until the secret is added and follows its updates.
+// When the secret is removed, the last known configs are kept, so that policies of the cluster keep applying, and
+// the missing secret is reported until it is added again.
+type Controller struct {
+	clusterID string
+	schemas   collection.Schemas
+	// newStore creates the store of the cluster once its client is known.
+	newStore func(client kube.Client) (model.ConfigStoreCache, error)
+
+	mu sync.RWMutex
+	// store is nil until the first remote secret of the cluster is added. It is kept once the secret is removed.
+	store model.ConfigStoreCache
+	// connected is true while the cluster has a remote secret.
+	connected bool
+	// pending is the store created for an updated remote secret. It replaces store once synced.
+	pending  model.ConfigStoreCache
+	handlers map[config.GroupVersionKind][]handler
+}
+
+var _ model.ConfigStoreCache = &Controller{}
+
+// NewController returns the config store of the Istio resources of cluster clusterID of the control plane
+// revision.
+func NewController(clusterID, revision, domainSuffix string) *Controller {
+	return newController(clusterID, collections.Pilot, func(client kube.Client) (model.ConfigStoreCache, error) {
+		return crdclient.NewForSchemas(client, revision, domainSuffix, collections.Pilot)
+	})
+}
+
+func newController(clusterID string, schemas collection.Schemas,
+	newStore func(client kube.Client) (model.ConfigStoreCache, error)) *Controller {
+	remoteSecretPresent.With(clusterTag.Value(clusterID)).Record(0)
+	return &Controller{
+		clusterID: clusterID,
+		schemas:   schemas,
+		newStore:  newStore,
+		handlers:  map[config.GroupVersionKind][]handler{},
+	}
+}
+
+// ClusterID returns the cluster the resources are read from.
+func (c *Controller) ClusterID() string {
+	return c.clusterID
+}
+
+// ClusterAdded starts reading the resources of the cluster, if it is the cluster of the controller, until stop
+// is closed. It is called when a remote secret is added or updated, before the informers of the client are
+// started. On update, or when a removed secret is added again, the resources read with the previous secret are
+// served until the new store is synced.
+func (c *Controller) ClusterAdded(clusterID string, client kube.Client, stop <-chan struct{}) {
+	if clusterID != c.clusterID {
+		return
+	}
+	store, err := c.newStore(client)
+	if err != nil {
+		scope.Errorf("failed to create the config store of cluster %s: %v", clusterID, err)
+		return
+	}
+	c.mu.Lock()
+	c.connected = true
+	remoteSecretPresent.With(clusterTag.Value(clusterID)).Record(1)
+	if c.store == nil {
+		c.registerHandlers(store)
+		c.store = store
+		c.pending = nil
+		c.mu.Unlock()
+		scope.Infof("reading Istio config from cluster %s", clusterID)
+		go store.Run(stop)
+		return
+	}
+	c.pending = store
+	c.mu.Unlock()
+	scope.Infof("reading Istio config from cluster %s with its updated remote secret", clusterID)
+	go store.Run(stop)
+	go c.replaceWhenSynced(store, stop)
+}
+
+// replaceWhenSynced replaces the current store with store once it is synced, and notifies the handlers of the
+// differences between the two.
+func (c *Controller) replaceWhenSynced(store model.ConfigStoreCache, stop <-chan struct{}) {
+	if !cache.WaitForCacheSync(stop, store.HasSynced) {
+		return
+	}
+	c.mu.Lock()
+	if c.pending != store {
+		// The cluster was removed or updated again in the meantime.
+		c.mu.Unlock()
+		return
+	}
+	old := c.store
+	c.registerHandlers(store)
+	c.store = store
+	c.pending = nil
+	handlers := c.copyHandlers()
+	c.mu.Unlock()
+	scope.Infof("switched to the updated remote secret of cluster %s", c.clusterID)
+	for kind, hs := range handlers {
+		c.notifyChanges(kind, old, store, hs)
+	}
+}
+
+// notifyChanges notifies the handlers of the configs of kind added, updated or deleted between the old and the
+// new store.
+func (c *Controller) notifyChanges(kind config.GroupVersionKind, old, store model.ConfigStoreCache, hs []handler) {
+	oldConfigs, err := old.List(kind, "")
+	if err != nil {
+		scope.Warnf("failed to list %v of cluster %s: %v", kind, c.clusterID, err)
+		return
+	}
+	configs, err := store.List(kind, "")
+	if err != nil {
+		scope.Warnf("failed to list %v of cluster %s: %v", kind, c.clusterID, err)
+		return
+	}
+	previous := make(map[string]config.Config, len(oldConfigs))
+	for _, cfg := range oldConfigs {
+		previous[cfg.Namespace+"/"+cfg.Name] = cfg
+	}
+	for _, cfg := range configs {
+		key := cfg.Namespace + "/" + cfg.Name
+		prev, ok := previous[key]
+		delete(previous, key)
+		switch {
+		case !ok:
+			for _, h := range hs {
+				h(config.Config{}, cfg, model.EventAdd)
+			}
+		case prev.ResourceVersion != cfg.ResourceVersion:
+			for _, h := range hs {
+				h(prev, cfg, model.EventUpdate)
+			}
+		}
+	}
+	for _, cfg := range previous {
+		for _, h := range hs {
+			h(config.Config{}, cfg, model.EventDelete)
+		}
+	}
+}
+
+// ClusterDeleted stops reading the resources of the cluster, if it is the cluster of the controller. The last
+// known configs of the cluster keep being served: removing them would drop the policies they define. The missing
+// remote secret is reported until it is added again.
+func (c *Controller) ClusterDeleted(clusterID string) {
+	if clusterID != c.clusterID {
+		return
+	}
+	c.mu.Lock()
+	c.connected = false
+	c.pending = nil
+	c.mu.Unlock()
+	remoteSecretPresent.With(clusterTag.Value(clusterID)).Record(0)
+	scope.Errorf("the remote secret of cluster %s was removed, serving its last known Istio config", clusterID)
+}
+
+// registerHandlers registers the handlers on store. c.mu must be held.
+func (c *Controller) registerHandlers(store model.ConfigStoreCache) {
+	for kind, handlers := range c.handlers {
+		for _, h := range handlers {
+			store.RegisterEventHandler(kind, h)
+		}
+	}
+}
+
+// copyHandlers returns a copy of the handlers. c.mu must be held.
+func (c *Controller) copyHandlers() map[config.GroupVersionKind][]handler {
+	handlers := make(map[config.GroupVersionKind][]handler, len(c.handlers))
+	for kind, h := range c.handlers {
+		handlers[kind] = h
+	}
+	return handlers
+}
+
+func (c *Controller) current() model.ConfigStoreCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.store
+}
+
+// writable returns the store of the cluster if it has a remote secret, nil otherwise.
+func (c *Controller) writable() model.ConfigStoreCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.connected {
+		return nil
+	}
+	return c.store
+}
+
+func (c *Controller) errNotConnected() error {
+	return fmt.Errorf("cluster %s has no remote secret", c.clusterID)
+}
+
+func (c *Controller) Schemas() collection.Schemas {
+	return c.schemas
+}
+
+func (c *Controller) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	store := c.current()
+	if store == nil {
+		return nil
+	}
+	return store.Get(typ, name, namespace)
+}
+
+func (c *Controller) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	store := c.current()
+	if store == nil {
+		return nil, nil
+	}
+	return store.List(typ, namespace)
+}
+
+func (c *Controller) Create(cfg config.Config) (string, error) {
+	store := c.writable()
+	if store == nil {
+		return "", c.errNotConnected()
+	}
+	return store.Create(cfg)
+}
+
+func (c *Controller) Update(cfg config.Config) (string, error) {
+	store := c.writable()
+	if store == nil {
+		return "", c.errNotConnected()
+	}
+	return store.Update(cfg)
+}
+
+func (c *Controller) UpdateStatus(cfg config.Config) (string, error) {
+	store := c.writable()
+	if store == nil {
+		return "", c.errNotConnected()
+	}
+	return store.UpdateStatus(cfg)
+}
+
+func (c *Controller) Patch(orig config.Config, patchFn config.PatchFunc) (string, error) {
+	store := c.writable()
+	if store == nil {
+		return "", c.errNotConnected()
+	}
+	return store.Patch(orig, patchFn)
+}
+
+func (c *Controller) Delete(typ config.GroupVersionKind, name, namespace string, resourceVersion *string) error {
+	store := c.writable()
+	if store == nil {
+		return c.errNotConnected()
+	}
+	return store.Delete(typ, name, namespace, resourceVersion)
+}
+
+func (c *Controller) RegisterEventHandler(kind config.GroupVersionKind, h func(config.Config, config.Config, model.Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[kind] = append(c.handlers[kind], h)
+	if c.store != nil {
+		c.store.RegisterEventHandler(kind, h)
+	}
+}
+
+// Run reports the cluster while it has no remote secret, until stop is closed. The store of the cluster runs
+// until the cluster is removed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(missingSecretReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.reportMissingSecret()
+		}
+	}
+}
+
+func (c *Controller) reportMissingSecret() {
+	c.mu.RLock()
+	connected, known := c.connected, c.store != nil
+	c.mu.RUnlock()
+	switch {
+	case connected:
+	case known:
+		scope.Errorf("config source k8s://%s has no remote secret, serving the last known Istio config of the cluster",
+			c.clusterID)
+	default:
+		scope.Errorf("config source k8s://%s has no remote secret, istiod is not ready until it is added", c.clusterID)
+	}
+}
+
+// HasSynced returns true once the resources of the cluster are synced. A cluster whose remote secret was never
+// added is not synced, so that istiod does not serve proxies without the policies of the cluster.
+func (c *Controller) HasSynced() bool {
+	store := c.current()
+	return store != nil && store.HasSynced()
+}
@@ -0,0 +1,220 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) handle(_ config.Config, cfg config.Config, event model.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event.String()+" "+cfg.Name)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.events...)
+}
+
+// syncingStore is a store synced once synced is set.
+type syncingStore struct {
+	model.ConfigStoreCache
+	synced *atomic.Bool
+}
+
+func (s syncingStore) HasSynced() bool {
+	return s.synced.Load()
+}
+
+func TestController(t *testing.T) {
+	stores := 0
+	c := newController("remote", collections.Pilot, func(kube.Client) (model.ConfigStoreCache, error) {
+		stores++
+		return memory.NewSyncController(memory.MakeSkipValidation(collections.Pilot)), nil
+	})
+	events := &recorder{}
+	c.RegisterEventHandler(gvk.VirtualService, events.handle)
+	vs := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "reviews", Namespace: "default"},
+		Spec: &networking.VirtualService{Hosts: []string{"reviews"}},
+	}
+
+	// Without remote secret, the store is empty, read-only and not synced.
+	if c.HasSynced() {
+		t.Errorf("a cluster without remote secret should not be synced")
+	}
+	if _, err := c.Create(vs); err == nil {
+		t.Errorf("Create() succeeded without remote secret")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c.ClusterAdded("other", nil, stop)
+	if stores != 0 {
+		t.Fatalf("created a store for another cluster")
+	}
+	c.ClusterAdded("remote", nil, stop)
+	if !c.HasSynced() {
+		t.Errorf("the cluster should be synced once its remote secret is added")
+	}
+	if _, err := c.Create(vs); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get(gvk.VirtualService, "reviews", "default"); got == nil {
+		t.Fatalf("Get() did not return the created config")
+	}
+
+	c.ClusterDeleted("other")
+	if l, _ := c.List(gvk.VirtualService, ""); len(l) != 1 {
+		t.Fatalf("removing another cluster changed the configs: %v", l)
+	}
+	// The last known configs are kept once the remote secret is removed, but cannot be written.
+	c.ClusterDeleted("remote")
+	if l, _ := c.List(gvk.VirtualService, ""); len(l) != 1 {
+		t.Errorf("List() = %v after the cluster was removed, want the last known configs", l)
+	}
+	if !c.HasSynced() {
+		t.Errorf("the cluster should stay synced once its remote secret is removed")
+	}
+	if _, err := c.Create(vs); err == nil {
+		t.Errorf("Create() succeeded after the remote secret was removed")
+	}
+	if got := events.get(); len(got) != 1 || got[0] != "add reviews" {
+		t.Errorf("events = %v, want no delete when the cluster is removed", got)
+	}
+
+	// The store of a cluster added again replaces the last known configs once synced, and gets the handlers.
+	c.ClusterAdded("remote", nil, stop)
+	if stores != 2 {
+		t.Fatalf("created %d stores, want 2", stores)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if l, _ := c.List(gvk.VirtualService, ""); len(l) != 0 {
+			return fmt.Errorf("List() = %v, want the configs of the new store", l)
+		}
+		if got := events.get(); len(got) != 2 {
+			return fmt.Errorf("events = %v, want the delete of the config missing from the new store", got)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+	if _, err := c.Create(vs); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"add reviews", "delete reviews", "add reviews"}
+	if got := events.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestControllerSecretUpdated(t *testing.T) {
+	synced := atomic.NewBool(true)
+	var stores []model.ConfigStoreCache
+	c := newController("remote", collections.Pilot, func(kube.Client) (model.ConfigStoreCache, error) {
+		store := syncingStore{memory.NewSyncController(memory.MakeSkipValidation(collections.Pilot)), synced}
+		stores = append(stores, store)
+		return store, nil
+	})
+	events := &recorder{}
+	c.RegisterEventHandler(gvk.VirtualService, events.handle)
+	vs := func(name string) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: name, Namespace: "default"},
+			Spec: &networking.VirtualService{Hosts: []string{name}},
+		}
+	}
+
+	stop := make(chan struct{})
+	c.ClusterAdded("remote", nil, stop)
+	if _, err := c.Create(vs("reviews")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote secret is updated: the store of the previous secret is stopped and a new one is created.
+	close(stop)
+	synced.Store(false)
+	stop = make(chan struct{})
+	defer close(stop)
+	c.ClusterAdded("remote", nil, stop)
+	if len(stores) != 2 {
+		t.Fatalf("created %d stores, want 2", len(stores))
+	}
+	for _, name := range []string{"reviews", "ratings"} {
+		if _, err := stores[1].Create(vs(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The configs read with the previous secret are served until the new store is synced.
+	time.Sleep(200 * time.Millisecond)
+	if l, _ := c.List(gvk.VirtualService, ""); len(l) != 1 || l[0].Name != "reviews" {
+		t.Fatalf("List() = %v before the new store is synced, want the configs of the previous store", l)
+	}
+	if !c.HasSynced() {
+		t.Errorf("the controller should stay synced while the new store syncs")
+	}
+
+	synced.Store(true)
+	retry.UntilSuccessOrFail(t, func() error {
+		if l, _ := c.List(gvk.VirtualService, ""); len(l) != 2 {
+			return fmt.Errorf("List() = %v, want the configs of the new store", l)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+	retry.UntilSuccessOrFail(t, func() error {
+		got := events.get()
+		added := false
+		for _, e := range got {
+			if strings.HasPrefix(e, "delete") {
+				t.Fatalf("events = %v, the update of the remote secret should not delete configs", got)
+			}
+			added = added || e == "add ratings"
+		}
+		if !added {
+			return fmt.Errorf("events = %v, want an add of the config of the new store", got)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+
+	// The handlers are registered on the new store.
+	if err := c.Delete(gvk.VirtualService, "ratings", "default", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := events.get(); got[len(got)-1] != "delete ratings" {
+		t.Errorf("events = %v, want a delete of the config deleted from the new store", got)
+	}
+}
@@ -175,6 +175,14 @@ var (
 			"and configures Remote Jwks to let Envoy fetch the Jwks instead of Istiod.",
 	).Get()
 
+	JwksFetchPolicyFile = env.RegisterStringVar(
+		"PILOT_JWKS_FETCH_POLICY_FILE",
+		"",
+		"Path to a YAML file, typically mounted from a ConfigMap, holding per-issuer JWKS fetch policies: "+
+			"custom CA certificates, client certificates, an HTTP proxy, or letting Envoy fetch the JWKS itself. "+
+			"The file is reloaded when it changes.",
+	).Get()
+
 	EnableEDSForHeadless = env.RegisterBoolVar(
 		"PILOT_ENABLE_EDS_FOR_HEADLESS_SERVICES",
 		false,
@@ -210,6 +218,14 @@ var (
 		"If enabled, Pilot will generate MCS ServiceExport objects for every non cluster-local service in the cluster",
 	).Get()
 
+	EnableMCSClusterSetLocal = env.RegisterBoolVar(
+		"PILOT_ENABLE_MCS_CLUSTERSET_LOCAL",
+		false,
+		"If enabled, Pilot will watch MCS ServiceExport and ServiceImport objects and generate <svc>.<ns>.svc.clusterset.local "+
+			"services, load balancing only to the endpoints of the clusters exporting the service. This requires the MCS CRDs "+
+			"to be installed in every cluster",
+	).Get()
+
 	EnableSDSServer = env.RegisterBoolVar(
 		"ISTIOD_ENABLE_SDS_SERVER",
 		true,
@@ -224,6 +240,13 @@ var (
 			"Istio Resources",
 	).Get()
 
+	AnalysisCustomAnalyzers = env.RegisterStringVar(
+		"PILOT_ANALYSIS_CUSTOM_ANALYZERS",
+		"",
+		"Comma separated list of files defining analyzers to run, along with the built-in analyzers, "+
+			"when PILOT_ENABLE_ANALYSIS is enabled.",
+	).Get()
+
 	EnableStatus = env.RegisterBoolVar(
 		"PILOT_ENABLE_STATUS",
 		false,
@@ -304,6 +327,11 @@ var (
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
+	PersistAutoAllocatedIPs = env.RegisterBoolVar("PILOT_PERSIST_AUTO_ALLOCATED_IPS", false,
+		"If enabled, addresses automatically allocated to ServiceEntries without addresses are recorded in the "+
+			"AutoAllocatedAddresses status condition of the ServiceEntries by the leader istiod, and kept as long as the "+
+			"ServiceEntries exist.").Get()
+
 	EnableK8SServiceSelectWorkloadEntries = env.RegisterBoolVar("PILOT_ENABLE_K8S_SELECT_WORKLOAD_ENTRIES", true,
 		"If enabled, Kubernetes services with selectors will select workload entries with matching labels. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
@@ -429,6 +457,35 @@ var (
 		"Additional config map to load for shared MeshConfig settings. The standard mesh config will take precedence.").Get()
 	MultiRootMesh = env.RegisterBoolVar("ISTIO_MULTIROOT_MESH", false,
 		"If enabled, mesh will support certificates signed by more than one trustAnchor for ISTIO_MUTUAL mTLS")
+
+	AdaptiveConnectionPool = env.RegisterStringVar("PILOT_ADAPTIVE_CONNECTION_POOL", "off",
+		"Tunes the max requests and max connections of the outbound clusters from the connection pool usage "+
+			"reported by the proxies. If set to recommend, the tuned limits are only reported on "+
+			"/debug/connection_pool_tuning. If set to apply, they raise the limits set by the DestinationRules. "+
+			"The leader istiod tunes the limits and shares them with the other replicas in the "+
+			"istio-connection-pool-limits ConfigMap. Proxies report their usage when "+
+			"CONNECTION_POOL_USAGE_REPORT_INTERVAL is set.").Get()
+
+	AdaptiveConnectionPoolMin = env.RegisterIntVar("PILOT_ADAPTIVE_CONNECTION_POOL_MIN", 64,
+		"The lowest max requests and max connections set by the adaptive connection pool.").Get()
+
+	AdaptiveConnectionPoolMax = env.RegisterIntVar("PILOT_ADAPTIVE_CONNECTION_POOL_MAX", 4096,
+		"The highest max requests and max connections set by the adaptive connection pool.").Get()
+
+	AdaptiveConnectionPoolHeadroom = env.RegisterFloatVar("PILOT_ADAPTIVE_CONNECTION_POOL_HEADROOM", 2,
+		"The ratio between the limits set by the adaptive connection pool and the peak usage reported by the proxies.").Get()
+
+	AdaptiveConnectionPoolHysteresis = env.RegisterFloatVar("PILOT_ADAPTIVE_CONNECTION_POOL_HYSTERESIS", 0.2,
+		"The relative change of a limit below which the adaptive connection pool keeps the current limit, "+
+			"unless requests or connections overflowed.").Get()
+
+	AdaptiveConnectionPoolCooldown = env.RegisterDurationVar("PILOT_ADAPTIVE_CONNECTION_POOL_COOLDOWN", 5*time.Minute,
+		"The minimum time between a change of the limits of a cluster and their decrease by the adaptive connection pool, "+
+			"and between the first usage reported for a cluster and its first limits, unless requests or connections overflowed.").Get()
+
+	AdaptiveConnectionPoolWindow = env.RegisterDurationVar("PILOT_ADAPTIVE_CONNECTION_POOL_WINDOW", 10*time.Minute,
+		"The duration for which the usage reported by a proxy is taken into account by the adaptive connection pool. "+
+			"The limits of a cluster without usage reported within it are removed.").Get()
 )
 
 // UnsafeFeaturesEnabled returns true if any unsafe features are enabled.
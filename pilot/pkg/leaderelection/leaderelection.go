@@ -29,9 +29,13 @@ import (
 
 // Various locks used throughout the code
 const (
-	NamespaceController     = "istio-namespace-controller-election"
-	ValidationController    = "istio-validation-controller-election"
-	ServiceExportController = "istio-serviceexport-controller-election"
+	NamespaceController            = "istio-namespace-controller-election"
+	ValidationController           = "istio-validation-controller-election"
+	ServiceExportController        = "istio-serviceexport-controller-election"
+	ServiceEntryController         = "istio-serviceentry-controller-election"
+	PluggedCARotationController    = "istio-plugged-ca-rotation-election"
+	CARevocationController         = "istio-ca-revocation-election"
+	ConnectionPoolTuningController = "istio-connection-pool-tuning-election"
 	// This holds the legacy name to not conflict with older control plane deployments which are just
 	// doing the ingress syncing.
 	IngressController = "istio-leader"
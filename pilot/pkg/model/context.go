@@ -39,6 +39,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/ledger"
 	"istio.io/pkg/monitoring"
@@ -228,6 +229,9 @@ type Proxy struct {
 	// The merged gateways associated with the proxy if this is a Router
 	MergedGateway *MergedGateway
 
+	// The merged LocalRateLimit resources selecting the proxy
+	LocalRateLimit *ratelimit.LocalRateLimit
+
 	// service instances associated with the proxy
 	ServiceInstances []*ServiceInstance
 
@@ -745,6 +749,11 @@ func (node *Proxy) SetGatewaysForProxy(ps *PushContext) {
 	node.MergedGateway = ps.mergeGateways(node)
 }
 
+// SetLocalRateLimit caches the merged LocalRateLimit resources selecting this proxy in the proxy Node.
+func (node *Proxy) SetLocalRateLimit(ps *PushContext) {
+	node.LocalRateLimit = ps.LocalRateLimit(node)
+}
+
 func (node *Proxy) SetServiceInstances(serviceDiscovery ServiceDiscovery) {
 	instances := serviceDiscovery.GetProxyServiceInstances(node)
 
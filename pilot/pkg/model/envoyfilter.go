@@ -44,6 +44,14 @@ type EnvoyFilterConfigPatchWrapper struct {
 	// regex match, but as an optimization we can reduce this to a prefix match for common cases.
 	// If this is set, ProxyVersionRegex is ignored.
 	ProxyPrefixMatch string
+	// Name and Namespace of the EnvoyFilter the patch comes from.
+	Name      string
+	Namespace string
+	// FilterClass places an HTTP filter inserted by the patch in the phase of the class, rather than
+	// relative to other filters.
+	FilterClass networking.EnvoyFilter_Patch_FilterClass
+	// Priority orders the filters inserted in the same phase, lowest first.
+	Priority int32
 }
 
 // wellKnownVersions defines a mapping of well known regex matches to prefix matches
@@ -71,6 +79,8 @@ func convertToEnvoyFilterWrapper(local *config.Config) *EnvoyFilterWrapper {
 		out.workloadSelector = localEnvoyFilter.WorkloadSelector.Labels
 	}
 	out.Patches = make(map[networking.EnvoyFilter_ApplyTo][]*EnvoyFilterConfigPatchWrapper)
+	// An invalid priority is caught by validation, use the default.
+	priority, _ := xds.EnvoyFilterPriority(local.Annotations)
 	for _, cp := range localEnvoyFilter.ConfigPatches {
 		if cp.Patch == nil {
 			// Should be caught by validation, but sometimes its disabled and we don't want to crash
@@ -81,9 +91,13 @@ func convertToEnvoyFilterWrapper(local *config.Config) *EnvoyFilterWrapper {
 			continue
 		}
 		cpw := &EnvoyFilterConfigPatchWrapper{
-			ApplyTo:   cp.ApplyTo,
-			Match:     cp.Match,
-			Operation: cp.Patch.Operation,
+			ApplyTo:     cp.ApplyTo,
+			Match:       cp.Match,
+			Operation:   cp.Patch.Operation,
+			Name:        local.Name,
+			Namespace:   local.Namespace,
+			FilterClass: cp.Patch.FilterClass,
+			Priority:    priority,
 		}
 		var err error
 		// Use non-strict building to avoid issues where EnvoyFilter is valid but meant
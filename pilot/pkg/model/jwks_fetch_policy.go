@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// JwksFetchPolicy controls how the JWKS of a single issuer is fetched. Issuers without a policy
+// are fetched by istiod directly, trusting the system roots and the extra CA bundle.
+type JwksFetchPolicy struct {
+	// Issuer the policy applies to. Must match the issuer of the RequestAuthentication jwtRules exactly.
+	Issuer string `json:"issuer"`
+
+	// CACertificates is the path of a PEM bundle, typically mounted from a ConfigMap, trusted in
+	// addition to the system roots when verifying the JWKS server.
+	CACertificates string `json:"caCertificates,omitempty"`
+
+	// ClientCertificate and PrivateKey are the paths of the PEM encoded client certificate and key
+	// presented to JWKS servers requiring mutual TLS. Both must be set, or neither.
+	ClientCertificate string `json:"clientCertificate,omitempty"`
+	PrivateKey        string `json:"privateKey,omitempty"`
+
+	// Proxy is the URL of an HTTP proxy to fetch the JWKS through, for example the istiod sidecar
+	// or an egress proxy. If unset, the proxy environment variables are honored.
+	Proxy string `json:"proxy,omitempty"`
+
+	// FetchByEnvoy configures Envoy to fetch the JWKS itself using remote_jwks instead of inlining
+	// the keys fetched by istiod. This requires the JWKS host to be a service known to the mesh;
+	// TLS origination towards it is configured through a DestinationRule as for any other service.
+	FetchByEnvoy bool `json:"fetchByEnvoy,omitempty"`
+}
+
+// JwksFetchPolicies is the format of the file referenced by PILOT_JWKS_FETCH_POLICY_FILE.
+type JwksFetchPolicies struct {
+	Issuers []JwksFetchPolicy `json:"issuers"`
+}
+
+// LoadJwksFetchPolicies reads and validates the JWKS fetch policies at the given path.
+func LoadJwksFetchPolicies(path string) ([]JwksFetchPolicy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS fetch policies %s: %v", path, err)
+	}
+	policies := JwksFetchPolicies{}
+	if err := yaml.UnmarshalStrict(b, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS fetch policies %s: %v", path, err)
+	}
+	seen := map[string]struct{}{}
+	for _, p := range policies.Issuers {
+		if p.Issuer == "" {
+			return nil, fmt.Errorf("JWKS fetch policy in %s is missing the issuer", path)
+		}
+		if _, f := seen[p.Issuer]; f {
+			return nil, fmt.Errorf("duplicate JWKS fetch policy for issuer %q in %s", p.Issuer, path)
+		}
+		seen[p.Issuer] = struct{}{}
+	}
+	return policies.Issuers, nil
+}
+
+// newHTTPClient builds the client used to fetch the JWKS of the policy issuer. The extra CA bundles
+// trusted by the default client are trusted by the policy client as well.
+func (p JwksFetchPolicy) newHTTPClient(caBundlePaths []string) (*http.Client, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		log.Warnf("Failed to fetch Cert from SystemCertPool: %v", err)
+		roots = x509.NewCertPool()
+	}
+	for _, pemFile := range caBundlePaths {
+		if caCert, err := ioutil.ReadFile(pemFile); err == nil {
+			roots.AppendCertsFromPEM(caCert)
+		}
+	}
+	if p.CACertificates != "" {
+		caCert, err := ioutil.ReadFile(p.CACertificates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates for issuer %q: %v", p.Issuer, err)
+		}
+		if !roots.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid CA certificates found in %s for issuer %q", p.CACertificates, p.Issuer)
+		}
+	}
+	tlsConfig := &tls.Config{RootCAs: roots}
+
+	if (p.ClientCertificate == "") != (p.PrivateKey == "") {
+		return nil, fmt.Errorf("clientCertificate and privateKey must be set together for issuer %q", p.Issuer)
+	}
+	if p.ClientCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(p.ClientCertificate, p.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate for issuer %q: %v", p.Issuer, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if p.Proxy != "" {
+		proxyURL, err := url.Parse(p.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q for issuer %q: %v", p.Proxy, p.Issuer, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{
+		Timeout: jwksHTTPTimeOutInSec * time.Second,
+		Transport: &http.Transport{
+			Proxy:             proxy,
+			DisableKeepAlives: true,
+			TLSClientConfig:   tlsConfig,
+		},
+	}, nil
+}
@@ -110,6 +110,19 @@ type jwtKey struct {
 	issuer  string
 }
 
+// jwksFetchStatus records the outcome of the last network fetches for a jwtKey, for debugging.
+type jwksFetchStatus struct {
+	lastFetchTime time.Time
+	lastError     string
+	lastErrorTime time.Time
+}
+
+// jwksIssuerFetcher holds the fetch policy of an issuer along with the client built from it.
+type jwksIssuerFetcher struct {
+	policy JwksFetchPolicy
+	client *http.Client
+}
+
 // JwksResolver is resolver for jwksURI and jwt public key.
 type JwksResolver struct {
 	// Callback function to invoke when detecting jwt public key change.
@@ -123,6 +136,16 @@ type JwksResolver struct {
 	httpClient       *http.Client
 	refreshTicker    *time.Ticker
 
+	// Extra CA bundles trusted when fetching over https, also trusted by per-issuer clients.
+	caBundlePaths []string
+
+	// Per-issuer fetch policies, keyed by issuer.
+	fetchersMutex sync.RWMutex
+	fetchers      map[string]jwksIssuerFetcher
+
+	// Outcome of the last fetches, map key is jwtKey, map value is jwksFetchStatus.
+	fetchStatus sync.Map
+
 	// Cached key will be removed from cache if (time.now - cachedItem.lastUsedTime >= evictionDuration), this prevents key cache growing indefinitely.
 	evictionDuration time.Duration
 
@@ -172,6 +195,7 @@ func newJwksResolverWithCABundlePaths(
 		refreshDefaultInterval:   refreshDefaultInterval,
 		refreshIntervalOnFailure: refreshIntervalOnFailure,
 		retryInterval:            retryInterval,
+		caBundlePaths:            caBundlePaths,
 		httpClient: &http.Client{
 			Timeout: jwksHTTPTimeOutInSec * time.Second,
 			Transport: &http.Transport{
@@ -216,6 +240,58 @@ func newJwksResolverWithCABundlePaths(
 
 var errEmptyPubKeyFoundInCache = errors.New("empty public key found in cache")
 
+// SetFetchPolicies replaces the per-issuer fetch policies. Policies are validated and their clients
+// built before any of them is applied, so an invalid policy leaves the current ones in place.
+// Cached keys are refreshed with the new policies on the next refresh.
+func (r *JwksResolver) SetFetchPolicies(policies []JwksFetchPolicy) error {
+	fetchers := make(map[string]jwksIssuerFetcher, len(policies))
+	for _, p := range policies {
+		client, err := p.newHTTPClient(r.caBundlePaths)
+		if err != nil {
+			return err
+		}
+		fetchers[p.Issuer] = jwksIssuerFetcher{policy: p, client: client}
+	}
+	r.fetchersMutex.Lock()
+	r.fetchers = fetchers
+	r.fetchersMutex.Unlock()
+	return nil
+}
+
+// FetchByEnvoy returns true if the JWKS of the issuer should be fetched by Envoy using remote_jwks.
+func (r *JwksResolver) FetchByEnvoy(issuer string) bool {
+	if r == nil {
+		return false
+	}
+	r.fetchersMutex.RLock()
+	defer r.fetchersMutex.RUnlock()
+	return r.fetchers[issuer].policy.FetchByEnvoy
+}
+
+func (r *JwksResolver) fetcherFor(issuer string) (jwksIssuerFetcher, bool) {
+	r.fetchersMutex.RLock()
+	defer r.fetchersMutex.RUnlock()
+	f, ok := r.fetchers[issuer]
+	return f, ok
+}
+
+// recordFetch records the outcome of a network fetch for the given key.
+func (r *JwksResolver) recordFetch(key jwtKey, err error) {
+	now := time.Now()
+	status := jwksFetchStatus{}
+	if val, found := r.fetchStatus.Load(key); found {
+		status = val.(jwksFetchStatus)
+	}
+	if err != nil {
+		status.lastError = err.Error()
+		status.lastErrorTime = now
+	} else {
+		status.lastFetchTime = now
+		status.lastError = ""
+	}
+	r.fetchStatus.Store(key, status)
+}
+
 // GetPublicKey gets JWT public key and cache the key for future use.
 func (r *JwksResolver) GetPublicKey(issuer string, jwksURI string) (string, error) {
 	now := time.Now()
@@ -241,12 +317,13 @@ func (r *JwksResolver) GetPublicKey(issuer string, jwksURI string) (string, erro
 		log.Errorf("Failed to jwks URI from %q: %v", issuer, err)
 	} else {
 		var resp []byte
-		resp, err = r.getRemoteContentWithRetry(jwksURI, networkFetchRetryCountOnMainFlow)
+		resp, err = r.getRemoteContentWithRetry(issuer, jwksURI, networkFetchRetryCountOnMainFlow)
 		if err != nil {
 			log.Errorf("Failed to fetch public key from %q: %v", jwksURI, err)
 		}
 		pubKey = string(resp)
 	}
+	r.recordFetch(key, err)
 
 	r.keyEntries.Store(key, jwtPubKeyEntry{
 		pubKey:            pubKey,
@@ -291,7 +368,7 @@ func CreateFakeJwks(jwksURI string) string {
 // Resolve jwks_uri through openID discovery.
 func (r *JwksResolver) resolveJwksURIUsingOpenID(issuer string) (string, error) {
 	// Try to get jwks_uri through OpenID Discovery.
-	body, err := r.getRemoteContentWithRetry(issuer+openIDDiscoveryCfgURLSuffix, networkFetchRetryCountOnMainFlow)
+	body, err := r.getRemoteContentWithRetry(issuer, issuer+openIDDiscoveryCfgURLSuffix, networkFetchRetryCountOnMainFlow)
 	if err != nil {
 		log.Errorf("Failed to fetch jwks_uri from %q: %v", issuer+openIDDiscoveryCfgURLSuffix, err)
 		return "", err
@@ -309,7 +386,7 @@ func (r *JwksResolver) resolveJwksURIUsingOpenID(issuer string) (string, error)
 	return jwksURI, nil
 }
 
-func (r *JwksResolver) getRemoteContentWithRetry(uri string, retry int) ([]byte, error) {
+func (r *JwksResolver) getRemoteContentWithRetry(issuer, uri string, retry int) ([]byte, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
 		log.Errorf("Failed to parse %q", uri)
@@ -317,7 +394,9 @@ func (r *JwksResolver) getRemoteContentWithRetry(uri string, retry int) ([]byte,
 	}
 
 	client := r.httpClient
-	if strings.EqualFold(u.Scheme, "https") {
+	if f, ok := r.fetcherFor(issuer); ok {
+		client = f.client
+	} else if strings.EqualFold(u.Scheme, "https") {
 		// https client may be uninitialized because of root CA bundle missing.
 		if r.secureHTTPClient == nil {
 			return nil, fmt.Errorf("pilot does not support fetch public key through https endpoint %q", uri)
@@ -418,6 +497,7 @@ func (r *JwksResolver) refresh() bool {
 			log.Infof("Removed cached JWT public key (lastRefreshed: %s, lastUsed: %s) from %q",
 				e.lastRefreshedTime, e.lastUsedTime, k.issuer)
 			r.keyEntries.Delete(k)
+			r.fetchStatus.Delete(k)
 			return true
 		}
 
@@ -433,6 +513,7 @@ func (r *JwksResolver) refresh() bool {
 			if jwksURI == "" {
 				var err error
 				jwksURI, err = r.resolveJwksURIUsingOpenID(k.issuer)
+				r.recordFetch(k, err)
 				if err != nil {
 					hasErrors = true
 					log.Errorf("Failed to resolve Jwks from issuer %q: %v", k.issuer, err)
@@ -441,7 +522,8 @@ func (r *JwksResolver) refresh() bool {
 				}
 			}
 
-			resp, err := r.getRemoteContentWithRetry(jwksURI, networkFetchRetryCountOnRefreshFlow)
+			resp, err := r.getRemoteContentWithRetry(k.issuer, jwksURI, networkFetchRetryCountOnRefreshFlow)
+			r.recordFetch(k, err)
 			if err != nil {
 				hasErrors = true
 				log.Errorf("Failed to refresh JWT public key from %q: %v", jwksURI, err)
@@ -489,6 +571,86 @@ func (r *JwksResolver) Close() {
 	closeChan <- true
 }
 
+// JwksDebugEntry describes a cached JWKS and the outcome of its last fetches.
+type JwksDebugEntry struct {
+	Issuer  string `json:"issuer,omitempty"`
+	JwksURI string `json:"jwks_uri,omitempty"`
+	// Key IDs of the cached keys, and the raw cached JWKS.
+	KeyIDs []string `json:"key_ids,omitempty"`
+	Jwks   string   `json:"jwks,omitempty"`
+
+	LastRefreshedTime time.Time `json:"last_refreshed_time"`
+	LastUsedTime      time.Time `json:"last_used_time"`
+	// ExpiryTime is when the entry is evicted from the cache unless it is used and refreshed again.
+	ExpiryTime time.Time `json:"expiry_time"`
+
+	LastFetchTime time.Time `json:"last_fetch_time"`
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time"`
+
+	FetchPolicy *JwksFetchPolicy `json:"fetch_policy,omitempty"`
+}
+
+// Debug returns the cached JWKS entries, sorted by issuer and jwks URI.
+func (r *JwksResolver) Debug() []JwksDebugEntry {
+	out := []JwksDebugEntry{}
+	r.keyEntries.Range(func(key interface{}, value interface{}) bool {
+		k := key.(jwtKey)
+		e := value.(jwtPubKeyEntry)
+		entry := JwksDebugEntry{
+			Issuer:            k.issuer,
+			JwksURI:           k.jwksURI,
+			KeyIDs:            jwksKeyIDs(e.pubKey),
+			Jwks:              e.pubKey,
+			LastRefreshedTime: e.lastRefreshedTime,
+			LastUsedTime:      e.lastUsedTime,
+		}
+		oldest := e.lastUsedTime
+		if e.lastRefreshedTime.Before(oldest) {
+			oldest = e.lastRefreshedTime
+		}
+		entry.ExpiryTime = oldest.Add(r.evictionDuration)
+		if val, found := r.fetchStatus.Load(k); found {
+			status := val.(jwksFetchStatus)
+			entry.LastFetchTime = status.lastFetchTime
+			entry.LastError = status.lastError
+			entry.LastErrorTime = status.lastErrorTime
+		}
+		if f, ok := r.fetcherFor(k.issuer); ok {
+			policy := f.policy
+			entry.FetchPolicy = &policy
+		}
+		out = append(out, entry)
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Issuer != out[j].Issuer {
+			return out[i].Issuer < out[j].Issuer
+		}
+		return out[i].JwksURI < out[j].JwksURI
+	})
+	return out
+}
+
+// jwksKeyIDs returns the "kid" of each key in the JWKS, in order. Keys without a kid are skipped.
+func jwksKeyIDs(jwks string) []string {
+	var parsed struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(jwks), &parsed); err != nil {
+		return nil
+	}
+	var kids []string
+	for _, k := range parsed.Keys {
+		if k.Kid != "" {
+			kids = append(kids, k.Kid)
+		}
+	}
+	return kids
+}
+
 // Compare two JWKS responses, returning true if there is a difference and false otherwise
 func compareJWKSResponse(oldKeyString string, newKeyString string) (bool, error) {
 	if oldKeyString == newKeyString {
@@ -16,6 +16,10 @@ package model
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -231,6 +235,149 @@ func TestGetPublicKeyUsingTLSWithoutCABundles(t *testing.T) {
 	}
 }
 
+func TestGetPublicKeyUsingIssuerFetchPolicy(t *testing.T) {
+	r := newJwksResolverWithCABundlePaths(
+		JwtPubKeyEvictionDuration,
+		JwtPubKeyRefreshInterval,
+		JwtPubKeyRefreshIntervalOnFailure,
+		testRetryInterval,
+		[]string{},
+	)
+	defer r.Close()
+
+	ms, err := test.StartNewTLSServer("./test/testcert/cert.pem", "./test/testcert/key.pem")
+	defer ms.Stop()
+	if err != nil {
+		t.Fatal("failed to start a mock server")
+	}
+
+	issuer := "https://private.example.com"
+	if err := r.SetFetchPolicies([]JwksFetchPolicy{{
+		Issuer:         issuer,
+		CACertificates: "./test/testcert/cert.pem",
+	}}); err != nil {
+		t.Fatalf("SetFetchPolicies() failed: %v", err)
+	}
+
+	mockCertURL := ms.URL + "/oauth2/v3/certs"
+	pk, err := r.GetPublicKey(issuer, mockCertURL)
+	if err != nil {
+		t.Errorf("GetPublicKey(%q, %+v) fails: expected no error, got (%v)", issuer, mockCertURL, err)
+	}
+	if test.JwtPubKey1 != pk {
+		t.Errorf("GetPublicKey(%q, %+v): expected (%s), got (%s)", issuer, mockCertURL, test.JwtPubKey1, pk)
+	}
+
+	// Issuers without a policy keep using the default client, which does not trust the mock server.
+	if _, err := r.GetPublicKey("https://other.example.com", mockCertURL); err == nil {
+		t.Errorf("GetPublicKey() for issuer without policy did not fail: expected bad certificate error, got no error")
+	}
+
+	debug := r.Debug()
+	if len(debug) != 2 {
+		t.Fatalf("Debug() returned %d entries, expected 2", len(debug))
+	}
+	if got := debug[0]; got.Issuer != "https://other.example.com" || got.LastError == "" || got.FetchPolicy != nil {
+		t.Errorf("Debug() entry for issuer without policy: expected last error and no policy, got %+v", got)
+	}
+	if got, want := debug[1].KeyIDs, []string{"fakeKey1_1", "fakeKey1_2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Debug() key ids: expected %v, got %v", want, got)
+	}
+	if got := debug[1]; got.LastError != "" || got.LastFetchTime.IsZero() || got.FetchPolicy == nil {
+		t.Errorf("Debug() entry for issuer with policy: expected successful fetch and policy, got %+v", got)
+	}
+	if got, want := debug[1].ExpiryTime, debug[1].LastRefreshedTime.Add(JwtPubKeyEvictionDuration); !got.Equal(want) {
+		t.Errorf("Debug() expiry: expected %v, got %v", want, got)
+	}
+}
+
+func TestSetFetchPolicies(t *testing.T) {
+	r := NewJwksResolver(JwtPubKeyEvictionDuration, JwtPubKeyRefreshInterval, JwtPubKeyRefreshIntervalOnFailure, testRetryInterval)
+	defer r.Close()
+
+	cases := []struct {
+		name    string
+		policy  JwksFetchPolicy
+		wantErr string
+	}{
+		{"default", JwksFetchPolicy{Issuer: "a"}, ""},
+		{"ca", JwksFetchPolicy{Issuer: "a", CACertificates: "./test/testcert/cert.pem"}, ""},
+		{"missing ca", JwksFetchPolicy{Issuer: "a", CACertificates: "./test/testcert/missing.pem"}, "failed to read CA certificates"},
+		{"invalid ca", JwksFetchPolicy{Issuer: "a", CACertificates: "./test/testcert/generate.sh"}, "no valid CA certificates"},
+		{"client cert", JwksFetchPolicy{Issuer: "a", ClientCertificate: "./test/testcert/cert.pem", PrivateKey: "./test/testcert/key.pem"}, ""},
+		{"client cert without key", JwksFetchPolicy{Issuer: "a", ClientCertificate: "./test/testcert/cert.pem"}, "must be set together"},
+		{"mismatched key", JwksFetchPolicy{Issuer: "a", ClientCertificate: "./test/testcert/cert.pem", PrivateKey: "./test/testcert/key2.pem"}, "failed to load client certificate"},
+		{"proxy", JwksFetchPolicy{Issuer: "a", Proxy: "http://localhost:15001"}, ""},
+		{"invalid proxy", JwksFetchPolicy{Issuer: "a", Proxy: "http://[::1"}, "invalid proxy"},
+		{"envoy", JwksFetchPolicy{Issuer: "a", FetchByEnvoy: true}, ""},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.SetFetchPolicies([]JwksFetchPolicy{tt.policy})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("SetFetchPolicies() failed: %v", err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SetFetchPolicies() expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if got := r.FetchByEnvoy("a"); got != tt.policy.FetchByEnvoy {
+				t.Errorf("FetchByEnvoy() => %v, expected %v", got, tt.policy.FetchByEnvoy)
+			}
+		})
+	}
+
+	var nilResolver *JwksResolver
+	if nilResolver.FetchByEnvoy("a") {
+		t.Errorf("FetchByEnvoy() on nil resolver should be false")
+	}
+}
+
+func TestLoadJwksFetchPolicies(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    []JwksFetchPolicy
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			content: `
+issuers:
+- issuer: https://private.example.com
+  caCertificates: /etc/istio/jwks/ca.pem
+  proxy: http://localhost:15001
+- issuer: https://envoy.example.com
+  fetchByEnvoy: true
+`,
+			want: []JwksFetchPolicy{
+				{Issuer: "https://private.example.com", CACertificates: "/etc/istio/jwks/ca.pem", Proxy: "http://localhost:15001"},
+				{Issuer: "https://envoy.example.com", FetchByEnvoy: true},
+			},
+		},
+		{name: "missing issuer", content: "issuers:\n- proxy: http://localhost:15001\n", wantErr: true},
+		{name: "duplicate issuer", content: "issuers:\n- issuer: a\n- issuer: a\n", wantErr: true},
+		{name: "unknown field", content: "issuers:\n- issuer: a\n  unknown: b\n", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadJwksFetchPolicies(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadJwksFetchPolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadJwksFetchPolicies() => %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestJwtPubKeyEvictionForNotUsed(t *testing.T) {
 	r := NewJwksResolver(
 		100*time.Millisecond, /*EvictionDuration*/
@@ -32,6 +32,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
 	"istio.io/pkg/monitoring"
@@ -173,6 +174,9 @@ type PushContext struct {
 	// envoy filters for each namespace including global config namespace
 	envoyFiltersByNamespace map[string][]*EnvoyFilterWrapper
 
+	// localRateLimitsByNamespace contains the LocalRateLimit resources by namespace, sorted by creation time.
+	localRateLimitsByNamespace map[string][]config.Config
+
 	// AuthnPolicies contains Authn policies by namespace.
 	AuthnPolicies *AuthenticationPolicies `json:"-"`
 
@@ -204,6 +208,10 @@ type PushContext struct {
 	// JwtKeyResolver holds a reference to the JWT key resolver instance.
 	JwtKeyResolver *JwksResolver
 
+	// ConnectionPoolLimits holds the connection pool limits tuned from the traffic reported by the proxies,
+	// keyed by cluster name. They raise the limits set by the DestinationRules.
+	ConnectionPoolLimits map[string]ConnectionPoolLimits `json:"-"`
+
 	// cache gateways addresses for each network
 	// this is mainly used for kubernetes multi-cluster scenario
 	networksMu      sync.RWMutex
@@ -307,6 +315,12 @@ type PushRequest struct {
 	Reason []TriggerReason
 }
 
+// ConnectionPoolLimits are the limits of the connection pool of a cluster. Zero values are not set.
+type ConnectionPoolLimits struct {
+	MaxRequests    uint32 `json:"maxRequests,omitempty"`
+	MaxConnections uint32 `json:"maxConnections,omitempty"`
+}
+
 type TriggerReason string
 
 const (
@@ -330,6 +344,8 @@ const (
 	NetworksTrigger TriggerReason = "networks"
 	// Desribes a push triggered based on proxy request
 	ProxyRequest TriggerReason = "proxyrequest"
+	// Describes a push triggered by a change of the tuned connection pool limits
+	ConnectionPoolUpdate TriggerReason = "connectionpool"
 )
 
 // Merge two update requests together
@@ -531,14 +547,15 @@ func init() {
 func NewPushContext() *PushContext {
 	// TODO: detect push in progress, don't update status if set
 	return &PushContext{
-		ServiceIndex:            newServiceIndex(),
-		virtualServiceIndex:     newVirtualServiceIndex(),
-		destinationRuleIndex:    newDestinationRuleIndex(),
-		sidecarsByNamespace:     map[string][]*SidecarScope{},
-		envoyFiltersByNamespace: map[string][]*EnvoyFilterWrapper{},
-		gatewayIndex:            newGatewayIndex(),
-		ProxyStatus:             map[string]map[string]ProxyPushStatus{},
-		ServiceAccounts:         map[host.Name]map[int][]string{},
+		ServiceIndex:               newServiceIndex(),
+		virtualServiceIndex:        newVirtualServiceIndex(),
+		destinationRuleIndex:       newDestinationRuleIndex(),
+		sidecarsByNamespace:        map[string][]*SidecarScope{},
+		envoyFiltersByNamespace:    map[string][]*EnvoyFilterWrapper{},
+		localRateLimitsByNamespace: map[string][]config.Config{},
+		gatewayIndex:               newGatewayIndex(),
+		ProxyStatus:                map[string]map[string]ProxyPushStatus{},
+		ServiceAccounts:            map[host.Name]map[int][]string{},
 	}
 }
 
@@ -1014,6 +1031,10 @@ func (ps *PushContext) createNewContext(env *Environment) error {
 		return err
 	}
 
+	if err := ps.initLocalRateLimits(env); err != nil {
+		return err
+	}
+
 	if err := ps.initGateways(env); err != nil {
 		return err
 	}
@@ -1030,7 +1051,7 @@ func (ps *PushContext) updateContext(
 	oldPushContext *PushContext,
 	pushReq *PushRequest) error {
 	var servicesChanged, virtualServicesChanged, destinationRulesChanged, gatewayChanged,
-		authnChanged, authzChanged, envoyFiltersChanged, sidecarsChanged, telemetryChanged bool
+		authnChanged, authzChanged, envoyFiltersChanged, sidecarsChanged, telemetryChanged, localRateLimitsChanged bool
 
 	for conf := range pushReq.ConfigsUpdated {
 		switch conf.Kind {
@@ -1056,6 +1077,8 @@ func (ps *PushContext) updateContext(
 			gatewayChanged = true
 		case gvk.Telemetry:
 			telemetryChanged = true
+		case ratelimit.GroupVersionKind:
+			localRateLimitsChanged = true
 		}
 	}
 
@@ -1119,6 +1142,14 @@ func (ps *PushContext) updateContext(
 		ps.envoyFiltersByNamespace = oldPushContext.envoyFiltersByNamespace
 	}
 
+	if localRateLimitsChanged {
+		if err := ps.initLocalRateLimits(env); err != nil {
+			return err
+		}
+	} else {
+		ps.localRateLimitsByNamespace = oldPushContext.localRateLimitsByNamespace
+	}
+
 	if gatewayChanged {
 		if err := ps.initGateways(env); err != nil {
 			return err
@@ -1682,6 +1713,56 @@ func (ps *PushContext) EnvoyFilters(proxy *Proxy) *EnvoyFilterWrapper {
 	return out
 }
 
+// pre computes LocalRateLimit resources per namespace
+func (ps *PushContext) initLocalRateLimits(env *Environment) error {
+	configs, err := env.List(ratelimit.GroupVersionKind, NamespaceAll)
+	if err != nil {
+		return err
+	}
+
+	sortConfigByCreationTime(configs)
+
+	ps.localRateLimitsByNamespace = make(map[string][]config.Config)
+	for _, cfg := range configs {
+		ps.localRateLimitsByNamespace[cfg.Namespace] = append(ps.localRateLimitsByNamespace[cfg.Namespace], cfg)
+	}
+	return nil
+}
+
+// LocalRateLimit returns the merged LocalRateLimit resources selecting a proxy, or nil if there is none.
+// The limits of the proxy namespace take precedence over the limits of the root namespace.
+func (ps *PushContext) LocalRateLimit(proxy *Proxy) *ratelimit.LocalRateLimit {
+	// this should never happen
+	if proxy == nil {
+		return nil
+	}
+	var workloadLabels labels.Collection
+	// This should never happen except in tests.
+	if proxy.Metadata != nil && len(proxy.Metadata.Labels) > 0 {
+		workloadLabels = labels.Collection{proxy.Metadata.Labels}
+	}
+	namespaces := []string{proxy.ConfigNamespace}
+	if ps.Mesh.RootNamespace != "" && ps.Mesh.RootNamespace != proxy.ConfigNamespace {
+		namespaces = append(namespaces, ps.Mesh.RootNamespace)
+	}
+
+	var out *ratelimit.LocalRateLimit
+	for _, ns := range namespaces {
+		for _, cfg := range ps.localRateLimitsByNamespace[ns] {
+			rl := cfg.Spec.(*ratelimit.LocalRateLimit)
+			if rl.WorkloadSelector != nil && !workloadLabels.IsSupersetOf(rl.WorkloadSelector.Labels) {
+				continue
+			}
+			if out == nil {
+				out = &ratelimit.LocalRateLimit{}
+			}
+			out.Listeners = append(out.Listeners, rl.Listeners...)
+			out.Routes = append(out.Routes, rl.Routes...)
+		}
+	}
+	return out
+}
+
 // pre computes gateways per namespace
 func (ps *PushContext) initGateways(env *Environment) error {
 	gatewayConfigs, err := env.List(gvk.Gateway, NamespaceAll)
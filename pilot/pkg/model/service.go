@@ -77,15 +77,17 @@ type Service struct {
 	// AutoAllocatedAddress specifies the automatically allocated
 	// IPv4 address out of the reserved Class E subnet
 	// (240.240.0.0/16) for service entries with non-wildcard
-	// hostnames. The IPs assigned to services are not
-	// synchronized across istiod replicas as the DNS resolution
-	// for these service entries happens completely inside a pod
-	// whose proxy is managed by one istiod. That said, the algorithm
-	// to allocate IPs is pretty deterministic that at stable state, two
-	// istiods will allocate the exact same set of IPs for a given set of
-	// service entries.
+	// hostnames. The IPs are derived from a hash of the hostname
+	// and namespace of the service, so that two istiods allocate the
+	// exact same set of IPs for a given set of service entries. They may
+	// optionally be persisted in the status of the service entries.
 	AutoAllocatedAddress string `json:"autoAllocatedAddress,omitempty"`
 
+	// AutoAllocatedIPv6Address specifies the automatically allocated
+	// IPv6 address out of 2001:2::/48, used instead of AutoAllocatedAddress
+	// by IPv6 only proxies.
+	AutoAllocatedIPv6Address string `json:"autoAllocatedIPv6Address,omitempty"`
+
 	// Protect concurrent ClusterVIPs read/write
 	Mutex sync.RWMutex
 
@@ -627,8 +629,13 @@ func (s *Service) GetServiceAddressForProxy(node *Proxy) string {
 		return clusterIP
 	}
 	if node.Metadata != nil && node.Metadata.DNSCapture && node.Metadata.DNSAutoAllocate &&
-		s.Address == constants.UnspecifiedIP && s.AutoAllocatedAddress != "" {
-		return s.AutoAllocatedAddress
+		s.Address == constants.UnspecifiedIP {
+		if s.AutoAllocatedIPv6Address != "" && node.SupportsIPv6() && !node.SupportsIPv4() {
+			return s.AutoAllocatedIPv6Address
+		}
+		if s.AutoAllocatedAddress != "" {
+			return s.AutoAllocatedAddress
+		}
 	}
 	return s.Address
 }
@@ -24,6 +24,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
 
@@ -44,9 +45,10 @@ var (
 	// clusterScopedConfigTypes includes configs when they are in root namespace,
 	// they will be applied to all namespaces within the cluster.
 	clusterScopedConfigTypes = map[config.GroupVersionKind]struct{}{
-		gvk.EnvoyFilter:           {},
-		gvk.AuthorizationPolicy:   {},
-		gvk.RequestAuthentication: {},
+		gvk.EnvoyFilter:            {},
+		gvk.AuthorizationPolicy:    {},
+		gvk.RequestAuthentication:  {},
+		ratelimit.GroupVersionKind: {},
 	}
 )
 
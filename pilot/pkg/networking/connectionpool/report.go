@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectionpool
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+)
+
+// Report is the connection pool usage of a cluster reported by a proxy.
+type Report struct {
+	Cluster           string
+	ActiveRequests    uint64
+	PendingRequests   uint64
+	ActiveConnections uint64
+	// RequestOverflows and ConnectionOverflows are the requests and connections rejected by the circuit
+	// breakers since the previous report.
+	RequestOverflows    uint64
+	ConnectionOverflows uint64
+}
+
+const (
+	activeRequestsKey      = "rq_active"
+	pendingRequestsKey     = "rq_pending"
+	activeConnectionsKey   = "cx_active"
+	requestOverflowsKey    = "rq_overflow"
+	connectionOverflowsKey = "cx_overflow"
+)
+
+// EncodeReports encodes the reports as the resource names of the request sent by the agent, one
+// `<cluster>?rq_active=..&rq_pending=..&cx_active=..&rq_overflow=..&cx_overflow=..` resource name per cluster.
+func EncodeReports(reports []Report) []string {
+	names := make([]string, 0, len(reports))
+	for _, r := range reports {
+		v := url.Values{}
+		v.Set(activeRequestsKey, strconv.FormatUint(r.ActiveRequests, 10))
+		v.Set(pendingRequestsKey, strconv.FormatUint(r.PendingRequests, 10))
+		v.Set(activeConnectionsKey, strconv.FormatUint(r.ActiveConnections, 10))
+		v.Set(requestOverflowsKey, strconv.FormatUint(r.RequestOverflows, 10))
+		v.Set(connectionOverflowsKey, strconv.FormatUint(r.ConnectionOverflows, 10))
+		names = append(names, r.Cluster+"?"+v.Encode())
+	}
+	return names
+}
+
+// DecodeReports decodes the resource names encoded by EncodeReports.
+func DecodeReports(names []string) ([]Report, error) {
+	reports := make([]Report, 0, len(names))
+	for _, name := range names {
+		sep := strings.LastIndex(name, "?")
+		if sep <= 0 {
+			return nil, fmt.Errorf("invalid connection pool usage %q", name)
+		}
+		v, err := url.ParseQuery(name[sep+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection pool usage %q: %v", name, err)
+		}
+		r := Report{Cluster: name[:sep]}
+		for key, value := range map[string]*uint64{
+			activeRequestsKey:      &r.ActiveRequests,
+			pendingRequestsKey:     &r.PendingRequests,
+			activeConnectionsKey:   &r.ActiveConnections,
+			requestOverflowsKey:    &r.RequestOverflows,
+			connectionOverflowsKey: &r.ConnectionOverflows,
+		} {
+			if v.Get(key) == "" {
+				continue
+			}
+			if *value, err = strconv.ParseUint(v.Get(key), 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s of cluster %s: %v", key, r.Cluster, err)
+			}
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// ScopeReports returns the reports of the outbound clusters of the services a proxy is configured with, as
+// returned by serviceFor, so that a proxy cannot influence the limits of other clusters.
+func ScopeReports(reports []Report, serviceFor func(hostname host.Name) *model.Service) []Report {
+	scoped := make([]Report, 0, len(reports))
+	for _, r := range reports {
+		direction, _, hostname, port := model.ParseSubsetKey(r.Cluster)
+		if direction != model.TrafficDirectionOutbound {
+			continue
+		}
+		svc := serviceFor(hostname)
+		if svc == nil {
+			continue
+		}
+		if _, ok := svc.Ports.GetByPort(port); !ok {
+			continue
+		}
+		scoped = append(scoped, r)
+	}
+	return scoped
+}
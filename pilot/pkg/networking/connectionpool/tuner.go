@@ -0,0 +1,675 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package connectionpool tunes the connection pool limits of the outbound clusters from the usage reported by
+// the proxies.
+package connectionpool
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+var scope = log.RegisterScope("connectionpool", "Adaptive connection pool tuning", 0)
+
+// Mode is the mode of the adaptive connection pool.
+type Mode string
+
+const (
+	// Off disables the tuning.
+	Off Mode = "off"
+	// Recommend computes the tuned limits and records them in the history, without applying them.
+	Recommend Mode = "recommend"
+	// Apply overrides the limits of the clusters with the tuned limits.
+	Apply Mode = "apply"
+)
+
+// ParseMode parses the mode of the adaptive connection pool.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "", Off:
+		return Off, nil
+	case Recommend, Apply:
+		return m, nil
+	default:
+		return Off, fmt.Errorf("invalid adaptive connection pool mode %q, expected one of off, recommend, apply", s)
+	}
+}
+
+// Options configure the Tuner.
+type Options struct {
+	Mode Mode `json:"mode"`
+	// Min and Max bound the tuned limits.
+	Min uint32 `json:"min"`
+	Max uint32 `json:"max"`
+	// Headroom is the ratio between the tuned limits and the peak usage.
+	Headroom float64 `json:"headroom"`
+	// Hysteresis is the relative change of a limit below which the current limit is kept, unless there are
+	// overflows.
+	Hysteresis float64 `json:"hysteresis"`
+	// Cooldown is the minimum time between a change of the limits of a cluster and their decrease.
+	Cooldown time.Duration `json:"cooldown"`
+	// Window is the duration for which a report is taken into account. The state of a cluster without report
+	// within the window is dropped.
+	Window time.Duration `json:"window"`
+	// HistorySize is the number of changes kept in the history.
+	HistorySize int `json:"historySize"`
+}
+
+// Change is a change of the limits of a cluster.
+type Change struct {
+	Time     time.Time                  `json:"time"`
+	Cluster  string                     `json:"cluster"`
+	Previous model.ConnectionPoolLimits `json:"previous"`
+	Limits   model.ConnectionPoolLimits `json:"limits"`
+	Reason   string                     `json:"reason"`
+	// Applied is true if the limits were pushed to the proxies.
+	Applied bool `json:"applied"`
+}
+
+// ClusterState is the state of the tuning of a cluster.
+type ClusterState struct {
+	Limits          model.ConnectionPoolLimits `json:"limits"`
+	LastChange      time.Time                  `json:"lastChange,omitempty"`
+	PeakRequests    uint64                     `json:"peakRequests"`
+	PeakConnections uint64                     `json:"peakConnections"`
+	Proxies         int                        `json:"proxies"`
+}
+
+// DebugState is the state of the Tuner reported on the debug endpoint.
+type DebugState struct {
+	Options Options `json:"options"`
+	// Leader is true if this istiod tunes the limits, false if it follows the limits tuned by the leader.
+	Leader   bool                    `json:"leader"`
+	Clusters map[string]ClusterState `json:"clusters"`
+	History  []Change                `json:"history"`
+}
+
+// sharedLimits are the limits of a cluster shared by the istiod replicas.
+type sharedLimits struct {
+	Limits     model.ConnectionPoolLimits `json:"limits"`
+	LastChange time.Time                  `json:"lastChange"`
+}
+
+// replicaUsage is the usage of the clusters reported to a replica, shared with the leader.
+type replicaUsage struct {
+	Time     time.Time               `json:"time"`
+	Clusters map[string]clusterUsage `json:"clusters"`
+}
+
+type clusterUsage struct {
+	PeakRequests        uint64 `json:"peakRequests,omitempty"`
+	PeakConnections     uint64 `json:"peakConnections,omitempty"`
+	RequestOverflows    uint64 `json:"requestOverflows,omitempty"`
+	ConnectionOverflows uint64 `json:"connectionOverflows,omitempty"`
+}
+
+type sample struct {
+	Report
+	time time.Time
+}
+
+// replicaSamplePrefix prefixes the samples of the usage shared by the other replicas.
+const replicaSamplePrefix = "replica/"
+
+type clusterState struct {
+	// samples holds the latest report of each proxy, and the latest usage shared by each replica.
+	samples map[string]sample
+	// firstReport is the time of the first report of the cluster.
+	firstReport time.Time
+	// lastReport is the time of the latest report of the cluster, or the time its state was loaded or taken
+	// over by a new leader.
+	lastReport time.Time
+	limits     model.ConnectionPoolLimits
+	lastChange time.Time
+	// unsharedRequestOverflows and unsharedConnectionOverflows are the overflows reported since the usage of
+	// this replica was last shared with the leader.
+	unsharedRequestOverflows    uint64
+	unsharedConnectionOverflows uint64
+}
+
+// Tuner computes the connection pool limits of the clusters from the usage reported by the proxies.
+//
+// The istiod replicas share the limits once Share is called: only the leader tunes them, from the usage reported
+// by its proxies and the usage the other replicas share, and publishes them with their time of change. All the
+// replicas follow the published limits, which are also the state the leader resumes from after a restart.
+//
+// The state of a cluster is dropped once no usage was reported for it within the window.
+type Tuner struct {
+	opts     Options
+	onChange func(clusters []string)
+	now      func() time.Time
+
+	// publishMu orders the publications of the limits.
+	publishMu sync.Mutex
+
+	mu           sync.Mutex
+	publish      func(limits []byte) error
+	publishUsage func(usage []byte) error
+	clusters     map[string]*clusterState
+	history      []Change
+	leader       bool
+	// loaded is true once the published limits are loaded.
+	loaded bool
+	// replicaUsage is the time of the latest usage recorded from each replica.
+	replicaUsage map[string]time.Time
+	// sharedEmpty is true if the latest usage shared by this replica had no clusters.
+	sharedEmpty bool
+	lastSweep   time.Time
+}
+
+const (
+	defaultHistorySize = 100
+
+	// MaxReports is the maximum number of clusters a proxy reports the usage of in a request.
+	MaxReports = 1000
+
+	// sweepInterval is how often the clusters without usage reported within the window are dropped.
+	sweepInterval = time.Minute
+
+	// usageInterval is how often the replicas share the usage reported by their proxies with the leader.
+	usageInterval = 30 * time.Second
+)
+
+// NewTuner returns a Tuner. onChange is called with the names of the clusters whose limits to apply change.
+func NewTuner(opts Options, onChange func(clusters []string)) *Tuner {
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = defaultHistorySize
+	}
+	if opts.Max < opts.Min {
+		opts.Max = opts.Min
+	}
+	if opts.Headroom < 1 {
+		opts.Headroom = 1
+	}
+	return &Tuner{
+		opts:         opts,
+		onChange:     onChange,
+		now:          time.Now,
+		clusters:     map[string]*clusterState{},
+		replicaUsage: map[string]time.Time{},
+	}
+}
+
+// Share makes the istiod replicas share the limits. The leader, running Lead, publishes them with publish, and
+// all the replicas follow the published limits passed to SetLimits. The other replicas, running Run, share the
+// usage reported by their proxies with publishUsage, which the leader records from RecordUsage. It must be
+// called before the first report.
+func (t *Tuner) Share(publish func(limits []byte) error, publishUsage func(usage []byte) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.publish = publish
+	t.publishUsage = publishUsage
+}
+
+// Run shares the usage reported to this replica with the leader until stop is closed.
+func (t *Tuner) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(usageInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			t.shareUsage()
+		}
+	}
+}
+
+// Lead tunes the limits until stop is closed. It must only run on the leader istiod.
+func (t *Tuner) Lead(stop <-chan struct{}) {
+	t.setLeader(true)
+	<-stop
+	t.setLeader(false)
+}
+
+func (t *Tuner) setLeader(leader bool) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.leader = leader
+	if leader {
+		// The clusters are only reported to the new leader once the other replicas share their usage.
+		for _, c := range t.clusters {
+			c.lastReport = now
+		}
+	}
+}
+
+// tuning returns true if this istiod tunes the limits: it is the leader and has loaded the published limits, or
+// the limits are not shared. It must be called with the lock.
+func (t *Tuner) tuning() bool {
+	return t.publish == nil || (t.leader && t.loaded)
+}
+
+// SetLimits follows the limits published by the leader, nil if none were published. The leader only loads them
+// once, to resume tuning from them.
+func (t *Tuner) SetLimits(limits []byte) error {
+	changed, err := t.setLimits(limits)
+	t.notify(changed)
+	return err
+}
+
+// setLimits sets the published limits and returns the clusters whose limits changed.
+func (t *Tuner) setLimits(limits []byte) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.leader && t.loaded {
+		return nil, nil
+	}
+	shared := map[string]sharedLimits{}
+	if len(limits) > 0 {
+		if err := json.Unmarshal(limits, &shared); err != nil {
+			return nil, fmt.Errorf("invalid connection pool limits: %v", err)
+		}
+	}
+	t.loaded = true
+	now := t.now()
+	var changed []string
+	for name, l := range shared {
+		c := t.clusters[name]
+		if c == nil {
+			c = &clusterState{samples: map[string]sample{}, lastReport: now}
+			t.clusters[name] = c
+		}
+		if c.limits != l.Limits {
+			changed = append(changed, name)
+		}
+		c.limits = l.Limits
+		c.lastChange = l.LastChange
+	}
+	for name, c := range t.clusters {
+		if _, f := shared[name]; !f && c.limits != (model.ConnectionPoolLimits{}) {
+			changed = append(changed, name)
+			c.limits = model.ConnectionPoolLimits{}
+			c.lastChange = time.Time{}
+		}
+	}
+	return changed, nil
+}
+
+// Record records the reports of a proxy and, if this istiod tunes the limits, updates the limits of the
+// reported clusters.
+func (t *Tuner) Record(proxyID string, reports []Report) {
+	now := t.now()
+	t.mu.Lock()
+	changed := t.sweep(now)
+	tuning := t.tuning()
+	for _, r := range reports {
+		c := t.cluster(r.Cluster, now)
+		c.samples[proxyID] = sample{Report: r, time: now}
+		if !tuning {
+			c.unsharedRequestOverflows += r.RequestOverflows
+			c.unsharedConnectionOverflows += r.ConnectionOverflows
+			continue
+		}
+		if t.update(r.Cluster, c, now) {
+			changed = append(changed, r.Cluster)
+		}
+	}
+	t.mu.Unlock()
+	t.changed(changed)
+}
+
+// RecordUsage records the usage shared by the other replicas, keyed by replica, and, if this istiod tunes the
+// limits, updates the limits of the clusters they report. Usage already recorded or older than the window is
+// ignored.
+func (t *Tuner) RecordUsage(usage map[string]string) {
+	now := t.now()
+	t.mu.Lock()
+	if !t.tuning() {
+		t.mu.Unlock()
+		return
+	}
+	changed := t.sweep(now)
+	for replica, u := range usage {
+		shared := replicaUsage{}
+		if err := json.Unmarshal([]byte(u), &shared); err != nil {
+			scope.Warnf("invalid connection pool usage shared by %s: %v", replica, err)
+			continue
+		}
+		if !shared.Time.After(t.replicaUsage[replica]) || now.Sub(shared.Time) > t.opts.Window {
+			continue
+		}
+		t.replicaUsage[replica] = shared.Time
+		for name, cu := range shared.Clusters {
+			c := t.cluster(name, now)
+			c.samples[replicaSamplePrefix+replica] = sample{
+				Report: Report{
+					Cluster:             name,
+					ActiveRequests:      cu.PeakRequests,
+					ActiveConnections:   cu.PeakConnections,
+					RequestOverflows:    cu.RequestOverflows,
+					ConnectionOverflows: cu.ConnectionOverflows,
+				},
+				time: shared.Time,
+			}
+			if t.update(name, c, now) {
+				changed = append(changed, name)
+			}
+		}
+	}
+	t.mu.Unlock()
+	t.changed(changed)
+}
+
+// UsageExpired returns true if the usage shared by a replica is invalid or older than the window, e.g. because
+// the replica is gone.
+func (t *Tuner) UsageExpired(usage []byte) bool {
+	shared := replicaUsage{}
+	if err := json.Unmarshal(usage, &shared); err != nil {
+		return true
+	}
+	return t.now().Sub(shared.Time) > t.opts.Window
+}
+
+// shareUsage publishes the usage reported to this replica within the window, unless this replica tunes the
+// limits. The overflows are only shared once.
+func (t *Tuner) shareUsage() {
+	now := t.now()
+	t.mu.Lock()
+	publish := t.publishUsage
+	if publish == nil || t.tuning() {
+		t.mu.Unlock()
+		return
+	}
+	t.sweep(now)
+	usage := replicaUsage{Time: now, Clusters: map[string]clusterUsage{}}
+	for name, c := range t.clusters {
+		cu := clusterUsage{
+			RequestOverflows:    c.unsharedRequestOverflows,
+			ConnectionOverflows: c.unsharedConnectionOverflows,
+		}
+		for id, s := range c.samples {
+			// Usage shared by other replicas while this one was the leader is theirs to share.
+			if strings.HasPrefix(id, replicaSamplePrefix) {
+				continue
+			}
+			cu.PeakRequests = max64(cu.PeakRequests, s.ActiveRequests+s.PendingRequests)
+			cu.PeakConnections = max64(cu.PeakConnections, s.ActiveConnections)
+		}
+		if cu != (clusterUsage{}) {
+			usage.Clusters[name] = cu
+		}
+		c.unsharedRequestOverflows, c.unsharedConnectionOverflows = 0, 0
+	}
+	// The leader ignores usage older than the window, so an empty usage is only shared once.
+	if len(usage.Clusters) == 0 && t.sharedEmpty {
+		t.mu.Unlock()
+		return
+	}
+	t.sharedEmpty = len(usage.Clusters) == 0
+	t.mu.Unlock()
+
+	b, err := json.Marshal(usage)
+	if err == nil {
+		err = publish(b)
+	}
+	if err != nil {
+		scope.Errorf("failed to share the connection pool usage: %v", err)
+		// Share the overflows with the next usage.
+		t.mu.Lock()
+		for name, cu := range usage.Clusters {
+			if c := t.clusters[name]; c != nil {
+				c.unsharedRequestOverflows += cu.RequestOverflows
+				c.unsharedConnectionOverflows += cu.ConnectionOverflows
+			}
+		}
+		t.sharedEmpty = false
+		t.mu.Unlock()
+	}
+}
+
+// cluster returns the state of a cluster, created on its first report. It must be called with the lock.
+func (t *Tuner) cluster(name string, now time.Time) *clusterState {
+	c := t.clusters[name]
+	if c == nil {
+		c = &clusterState{samples: map[string]sample{}}
+		t.clusters[name] = c
+	}
+	if c.firstReport.IsZero() {
+		c.firstReport = now
+	}
+	c.lastReport = now
+	return c
+}
+
+// sweep drops the samples older than the window, and the clusters left without samples. If this istiod tunes the
+// limits, the limits of the dropped clusters are removed, and the clusters are returned. It must be called with
+// the lock, and only sweeps once per sweep interval.
+func (t *Tuner) sweep(now time.Time) []string {
+	if now.Sub(t.lastSweep) < sweepInterval {
+		return nil
+	}
+	t.lastSweep = now
+	tuning := t.tuning()
+	var changed []string
+	for name, c := range t.clusters {
+		for id, s := range c.samples {
+			if now.Sub(s.time) > t.opts.Window {
+				delete(c.samples, id)
+			}
+		}
+		if len(c.samples) > 0 || now.Sub(c.lastReport) <= t.opts.Window ||
+			c.unsharedRequestOverflows > 0 || c.unsharedConnectionOverflows > 0 {
+			continue
+		}
+		if c.limits != (model.ConnectionPoolLimits{}) {
+			// The limits of a follower are removed by the leader.
+			if !tuning {
+				continue
+			}
+			t.recordChange(Change{
+				Time:     now,
+				Cluster:  name,
+				Previous: c.limits,
+				Reason:   "no usage reported within the window",
+				Applied:  t.opts.Mode == Apply,
+			})
+			changed = append(changed, name)
+		}
+		delete(t.clusters, name)
+	}
+	for replica, last := range t.replicaUsage {
+		if now.Sub(last) > t.opts.Window {
+			delete(t.replicaUsage, replica)
+		}
+	}
+	return changed
+}
+
+// changed publishes the limits and notifies the change of the limits of clusters, if any.
+func (t *Tuner) changed(clusters []string) {
+	if len(clusters) == 0 {
+		return
+	}
+	t.publishLimits()
+	t.notify(clusters)
+}
+
+// notify calls onChange with the clusters whose limits changed, if they are applied.
+func (t *Tuner) notify(changed []string) {
+	if len(changed) > 0 && t.opts.Mode == Apply && t.onChange != nil {
+		t.onChange(changed)
+	}
+}
+
+// publishLimits publishes the limits of all the clusters, if they are shared.
+func (t *Tuner) publishLimits() {
+	t.publishMu.Lock()
+	defer t.publishMu.Unlock()
+	t.mu.Lock()
+	publish := t.publish
+	if publish == nil {
+		t.mu.Unlock()
+		return
+	}
+	shared := make(map[string]sharedLimits, len(t.clusters))
+	for name, c := range t.clusters {
+		if c.limits != (model.ConnectionPoolLimits{}) {
+			shared[name] = sharedLimits{Limits: c.limits, LastChange: c.lastChange}
+		}
+	}
+	t.mu.Unlock()
+	limits, err := json.Marshal(shared)
+	if err == nil {
+		err = publish(limits)
+	}
+	if err != nil {
+		scope.Errorf("failed to publish the connection pool limits: %v", err)
+	}
+}
+
+// update updates the limits of a cluster and returns true if they changed. It must be called with the lock.
+func (t *Tuner) update(cluster string, c *clusterState, now time.Time) bool {
+	var peakRequests, peakConnections, requestOverflows, connectionOverflows uint64
+	for proxy, s := range c.samples {
+		if now.Sub(s.time) > t.opts.Window {
+			delete(c.samples, proxy)
+			continue
+		}
+		peakRequests = max64(peakRequests, s.ActiveRequests+s.PendingRequests)
+		peakConnections = max64(peakConnections, s.ActiveConnections)
+		requestOverflows += s.RequestOverflows
+		connectionOverflows += s.ConnectionOverflows
+		// Overflows are only counted once.
+		s.RequestOverflows, s.ConnectionOverflows = 0, 0
+		c.samples[proxy] = s
+	}
+
+	// The first limits are only set once the cluster was reported for a cooldown.
+	since := c.lastChange
+	if since.IsZero() {
+		since = c.firstReport
+	}
+	cooledDown := now.Sub(since) >= t.opts.Cooldown
+	limits := model.ConnectionPoolLimits{
+		MaxRequests:    t.tune(c.limits.MaxRequests, peakRequests, requestOverflows > 0, cooledDown),
+		MaxConnections: t.tune(c.limits.MaxConnections, peakConnections, connectionOverflows > 0, cooledDown),
+	}
+	if limits == c.limits {
+		return false
+	}
+	change := Change{
+		Time:     now,
+		Cluster:  cluster,
+		Previous: c.limits,
+		Limits:   limits,
+		Reason: fmt.Sprintf("peak requests %d, peak connections %d, request overflows %d, connection overflows %d",
+			peakRequests, peakConnections, requestOverflows, connectionOverflows),
+		Applied: t.opts.Mode == Apply,
+	}
+	c.limits = limits
+	c.lastChange = now
+	t.recordChange(change)
+	return true
+}
+
+// recordChange logs a change and adds it to the history. It must be called with the lock.
+func (t *Tuner) recordChange(change Change) {
+	scope.Infof("connection pool limits of cluster %s changed from %+v to %+v: %s",
+		change.Cluster, change.Previous, change.Limits, change.Reason)
+	t.history = append(t.history, change)
+	if len(t.history) > t.opts.HistorySize {
+		t.history = t.history[len(t.history)-t.opts.HistorySize:]
+	}
+}
+
+// tune returns the limit for the peak usage.
+func (t *Tuner) tune(current uint32, peak uint64, overflow, cooledDown bool) uint32 {
+	target := float64(peak) * t.opts.Headroom
+	if overflow {
+		target = math.Max(target, float64(current)*2)
+	}
+	target = math.Max(target, float64(t.opts.Min))
+	target = math.Min(target, float64(t.opts.Max))
+	limit := uint32(math.Ceil(target))
+	if current == 0 {
+		if overflow || cooledDown {
+			return limit
+		}
+		return 0
+	}
+	if overflow && limit > current {
+		return limit
+	}
+	if math.Abs(float64(limit)-float64(current)) < float64(current)*t.opts.Hysteresis {
+		return current
+	}
+	if limit < current && !cooledDown {
+		return current
+	}
+	return limit
+}
+
+// Limits returns the limits to apply, keyed by cluster name. It returns nil unless the mode is Apply.
+func (t *Tuner) Limits() map[string]model.ConnectionPoolLimits {
+	if t.opts.Mode != Apply {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limits := make(map[string]model.ConnectionPoolLimits, len(t.clusters))
+	for name, c := range t.clusters {
+		if c.limits != (model.ConnectionPoolLimits{}) {
+			limits[name] = c.limits
+		}
+	}
+	return limits
+}
+
+// Debug returns the state of the Tuner.
+func (t *Tuner) Debug() DebugState {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := DebugState{
+		Options:  t.opts,
+		Leader:   t.tuning(),
+		Clusters: make(map[string]ClusterState, len(t.clusters)),
+		History:  append([]Change{}, t.history...),
+	}
+	for name, c := range t.clusters {
+		cs := ClusterState{Limits: c.limits, LastChange: c.lastChange}
+		for _, s := range c.samples {
+			if now.Sub(s.time) > t.opts.Window {
+				continue
+			}
+			cs.Proxies++
+			cs.PeakRequests = max64(cs.PeakRequests, s.ActiveRequests+s.PendingRequests)
+			cs.PeakConnections = max64(cs.PeakConnections, s.ActiveConnections)
+		}
+		state.Clusters[name] = cs
+	}
+	// Most recent changes first.
+	sort.SliceStable(state.History, func(i, j int) bool {
+		return state.History[i].Time.After(state.History[j].Time)
+	})
+	return state
+}
+
+func max64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,327 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectionpool
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+)
+
+func TestReports(t *testing.T) {
+	reports := []Report{
+		{
+			Cluster:             "outbound|9080||reviews.default.svc.cluster.local",
+			ActiveRequests:      12,
+			PendingRequests:     3,
+			ActiveConnections:   4,
+			RequestOverflows:    7,
+			ConnectionOverflows: 1,
+		},
+		{Cluster: "outbound|80||httpbin.org"},
+	}
+	got, err := DecodeReports(EncodeReports(reports))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, reports) {
+		t.Errorf("DecodeReports() => %+v, want %+v", got, reports)
+	}
+	for _, invalid := range []string{"outbound|80||httpbin.org", "?rq_active=1", "outbound|80||httpbin.org?rq_active=many"} {
+		if _, err := DecodeReports([]string{invalid}); err == nil {
+			t.Errorf("DecodeReports(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestTuner(t *testing.T) {
+	pushes := 0
+	tuner := NewTuner(Options{
+		Mode:       Apply,
+		Min:        10,
+		Max:        1000,
+		Headroom:   2,
+		Hysteresis: 0.2,
+		Cooldown:   5 * time.Minute,
+		Window:     10 * time.Minute,
+	}, func(clusters []string) {
+		if len(clusters) != 1 || clusters[0] != "c" {
+			t.Errorf("changed clusters = %v, want [c]", clusters)
+		}
+		pushes++
+	})
+	start := time.Now()
+	record := func(after time.Duration, proxy string, r Report) {
+		t.Helper()
+		tuner.now = func() time.Time { return start.Add(after) }
+		r.Cluster = "c"
+		tuner.Record(proxy, []Report{r})
+	}
+	expect := func(want model.ConnectionPoolLimits, wantPushes int) {
+		t.Helper()
+		if got := tuner.Limits()["c"]; got != want {
+			t.Errorf("limits = %+v, want %+v", got, want)
+		}
+		if pushes != wantPushes {
+			t.Errorf("pushes = %d, want %d", pushes, wantPushes)
+		}
+	}
+
+	// No limits are set until the cluster was reported for a cooldown.
+	record(0, "a", Report{ActiveRequests: 20, PendingRequests: 5, ActiveConnections: 3})
+	expect(model.ConnectionPoolLimits{}, 0)
+	record(time.Minute, "b", Report{ActiveRequests: 22})
+	expect(model.ConnectionPoolLimits{}, 0)
+
+	// The limits are the peak usage with headroom, bounded by the minimum.
+	record(5*time.Minute, "a", Report{ActiveRequests: 20, PendingRequests: 5, ActiveConnections: 3})
+	expect(model.ConnectionPoolLimits{MaxRequests: 50, MaxConnections: 10}, 1)
+
+	// Overflows at least double the limit.
+	record(6*time.Minute, "a", Report{ActiveRequests: 10, RequestOverflows: 4})
+	expect(model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 10}, 2)
+
+	// The limits are only decreased after the cooldown.
+	record(7*time.Minute, "a", Report{ActiveRequests: 10})
+	expect(model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 10}, 2)
+
+	// The report of proxy b is out of the window.
+	record(17*time.Minute, "a", Report{ActiveRequests: 10})
+	expect(model.ConnectionPoolLimits{MaxRequests: 20, MaxConnections: 10}, 3)
+
+	// Small changes are ignored.
+	record(23*time.Minute, "a", Report{ActiveRequests: 11})
+	expect(model.ConnectionPoolLimits{MaxRequests: 20, MaxConnections: 10}, 3)
+
+	state := tuner.Debug()
+	if len(state.History) != 3 || state.History[0].Limits.MaxRequests != 20 || !state.History[0].Applied {
+		t.Errorf("history = %+v, want 3 changes, most recent first", state.History)
+	}
+	if c := state.Clusters["c"]; c.Proxies != 1 || c.PeakRequests != 11 {
+		t.Errorf("cluster state = %+v", c)
+	}
+}
+
+func TestTunerRecommend(t *testing.T) {
+	pushes := 0
+	tuner := NewTuner(Options{Mode: Recommend, Min: 10, Max: 100, Headroom: 2, Window: time.Minute}, func([]string) { pushes++ })
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 200, ActiveConnections: 20}})
+	if tuner.Limits() != nil || pushes != 0 {
+		t.Errorf("recommended limits were applied")
+	}
+	history := tuner.Debug().History
+	want := model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 40}
+	if len(history) != 1 || history[0].Limits != want || history[0].Applied {
+		t.Errorf("history = %+v, want a recommendation of %+v", history, want)
+	}
+}
+
+func TestTunerOverflowBeforeCooldown(t *testing.T) {
+	tuner := NewTuner(Options{Mode: Apply, Min: 10, Max: 1000, Headroom: 2, Cooldown: time.Hour, Window: time.Hour}, nil)
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 40}})
+	if got := tuner.Limits()["c"]; got != (model.ConnectionPoolLimits{}) {
+		t.Errorf("limits = %+v before the cooldown, want none", got)
+	}
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 40, RequestOverflows: 1}})
+	if got := tuner.Limits()["c"]; got.MaxRequests != 80 {
+		t.Errorf("limits = %+v after an overflow, want max requests 80", got)
+	}
+}
+
+func TestTunerShared(t *testing.T) {
+	opts := Options{Mode: Apply, Min: 10, Max: 1000, Headroom: 2, Hysteresis: 0.2, Window: time.Hour}
+	var published []byte
+	publish := func(limits []byte) error {
+		published = limits
+		return nil
+	}
+	want := model.ConnectionPoolLimits{MaxRequests: 40, MaxConnections: 10}
+
+	leader := NewTuner(opts, nil)
+	leader.Share(publish, nil)
+	leader.setLeader(true)
+	// The leader only tunes once the published limits are loaded.
+	leader.Record("a", []Report{{Cluster: "c", ActiveRequests: 20}})
+	if published != nil || len(leader.Limits()) != 0 {
+		t.Fatalf("the leader tuned the limits before loading the published ones")
+	}
+	if err := leader.SetLimits(nil); err != nil {
+		t.Fatal(err)
+	}
+	leader.Record("a", []Report{{Cluster: "c", ActiveRequests: 20}})
+	if got := leader.Limits()["c"]; got != want {
+		t.Fatalf("leader limits = %+v, want %+v", got, want)
+	}
+	if published == nil {
+		t.Fatalf("the leader did not publish the limits")
+	}
+
+	// The other replicas follow the published limits, without tuning them.
+	var changed []string
+	follower := NewTuner(opts, func(clusters []string) { changed = clusters })
+	follower.Share(publish, nil)
+	if err := follower.SetLimits(published); err != nil {
+		t.Fatal(err)
+	}
+	if got := follower.Limits()["c"]; got != want || len(changed) != 1 || changed[0] != "c" {
+		t.Errorf("follower limits = %+v, changed %v, want %+v for c", got, changed, want)
+	}
+	follower.Record("b", []Report{{Cluster: "c", ActiveRequests: 200}})
+	if got := follower.Limits()["c"]; got != want {
+		t.Errorf("follower limits = %+v after a report, want %+v", got, want)
+	}
+
+	// A leader restarting resumes from the published limits: a small change is ignored.
+	restarted := NewTuner(opts, nil)
+	restarted.Share(publish, nil)
+	restarted.setLeader(true)
+	if err := restarted.SetLimits(published); err != nil {
+		t.Fatal(err)
+	}
+	restarted.Record("a", []Report{{Cluster: "c", ActiveRequests: 21}})
+	if got := restarted.Limits()["c"]; got != want {
+		t.Errorf("restarted leader limits = %+v, want %+v", got, want)
+	}
+	if err := restarted.SetLimits([]byte("invalid")); err != nil {
+		t.Errorf("the leader should ignore the published limits once loaded: %v", err)
+	}
+	if err := follower.SetLimits([]byte("invalid")); err == nil {
+		t.Errorf("SetLimits() succeeded with invalid limits")
+	}
+}
+
+func TestScopeReports(t *testing.T) {
+	reviews := &model.Service{
+		Hostname: "reviews.default.svc.cluster.local",
+		Ports:    model.PortList{{Name: "http", Port: 9080}},
+	}
+	serviceFor := func(hostname host.Name) *model.Service {
+		if hostname == reviews.Hostname {
+			return reviews
+		}
+		return nil
+	}
+	reports := []Report{
+		{Cluster: "outbound|9080||reviews.default.svc.cluster.local"},
+		{Cluster: "outbound|9080|v1|reviews.default.svc.cluster.local"},
+		{Cluster: "outbound|8080||reviews.default.svc.cluster.local"},
+		{Cluster: "inbound|9080||reviews.default.svc.cluster.local"},
+		{Cluster: "outbound|9080||ratings.default.svc.cluster.local"},
+		{Cluster: "BlackHoleCluster"},
+	}
+	got := ScopeReports(reports, serviceFor)
+	if !reflect.DeepEqual(got, reports[:2]) {
+		t.Errorf("ScopeReports() = %+v, want the outbound clusters of reviews", got)
+	}
+}
+
+func TestTunerEviction(t *testing.T) {
+	var changed []string
+	tuner := NewTuner(Options{Mode: Apply, Min: 10, Max: 1000, Headroom: 2, Window: 10 * time.Minute},
+		func(clusters []string) { changed = append(changed, clusters...) })
+	start := time.Now()
+	at := func(after time.Duration) {
+		tuner.now = func() time.Time { return start.Add(after) }
+	}
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 20}})
+	tuner.Record("a", []Report{{Cluster: "unused", ActiveRequests: 20}})
+	if got := tuner.Limits()["c"]; got.MaxRequests != 40 {
+		t.Fatalf("limits = %+v, want max requests 40", got)
+	}
+
+	// The state of a cluster is dropped, with its limits, once it is not reported within the window.
+	at(8 * time.Minute)
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 20}})
+	at(15 * time.Minute)
+	tuner.Record("a", []Report{{Cluster: "c", ActiveRequests: 20}})
+	if _, f := tuner.clusters["unused"]; f {
+		t.Errorf("the state of a cluster not reported within the window was kept")
+	}
+	if _, f := tuner.Limits()["unused"]; f {
+		t.Errorf("the limits of a cluster not reported within the window were kept")
+	}
+	if _, f := tuner.Limits()["c"]; !f {
+		t.Errorf("the limits of a reported cluster were dropped")
+	}
+	if len(changed) != 3 || changed[2] != "unused" {
+		t.Errorf("changed clusters = %v, want the dropped cluster", changed)
+	}
+	if h := tuner.Debug().History[0]; h.Cluster != "unused" || h.Limits != (model.ConnectionPoolLimits{}) {
+		t.Errorf("latest change = %+v, want the removal of the limits of the dropped cluster", h)
+	}
+}
+
+func TestTunerSharedUsage(t *testing.T) {
+	opts := Options{Mode: Apply, Min: 10, Max: 1000, Headroom: 2, Window: 10 * time.Minute}
+	start := time.Now()
+	usage := map[string]string{}
+	follower := NewTuner(opts, nil)
+	follower.Share(func([]byte) error { return nil }, func(u []byte) error {
+		usage["istiod-b"] = string(u)
+		return nil
+	})
+	if err := follower.SetLimits(nil); err != nil {
+		t.Fatal(err)
+	}
+	leader := NewTuner(opts, nil)
+	leader.Share(func([]byte) error { return nil }, nil)
+	leader.setLeader(true)
+	if err := leader.SetLimits(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The leader tunes the limits from the peak usage of its proxies and of the proxies of the other replicas.
+	leader.Record("a", []Report{{Cluster: "c", ActiveRequests: 10}})
+	follower.Record("b", []Report{{Cluster: "c", ActiveRequests: 50, ActiveConnections: 5}})
+	follower.shareUsage()
+	leader.RecordUsage(usage)
+	if got, want := leader.Limits()["c"], (model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 10}); got != want {
+		t.Errorf("leader limits = %+v, want %+v", got, want)
+	}
+
+	// The overflows of the other replicas are counted once.
+	follower.now = func() time.Time { return start.Add(time.Minute) }
+	leader.now = follower.now
+	follower.Record("b", []Report{{Cluster: "c", ActiveRequests: 50, ActiveConnections: 5, RequestOverflows: 3}})
+	follower.shareUsage()
+	leader.RecordUsage(usage)
+	leader.RecordUsage(usage)
+	if got := leader.Limits()["c"]; got.MaxRequests != 200 {
+		t.Errorf("leader limits = %+v after an overflow, want max requests 200", got)
+	}
+	if c := leader.clusters["c"].samples[replicaSamplePrefix+"istiod-b"]; c.RequestOverflows != 0 {
+		t.Errorf("the overflows of the shared usage were not consumed: %+v", c)
+	}
+
+	// An empty usage is only shared once, and expired usage is ignored.
+	follower.now = func() time.Time { return start.Add(20 * time.Minute) }
+	follower.shareUsage()
+	shared := usage["istiod-b"]
+	delete(usage, "istiod-b")
+	follower.shareUsage()
+	if _, f := usage["istiod-b"]; f {
+		t.Errorf("an empty usage was shared twice")
+	}
+	if follower.UsageExpired([]byte(shared)) {
+		t.Errorf("the latest usage of the follower is expired")
+	}
+	leader.now = func() time.Time { return start.Add(40 * time.Minute) }
+	if !leader.UsageExpired([]byte(shared)) || !leader.UsageExpired([]byte("invalid")) {
+		t.Errorf("old or invalid usage should be expired")
+	}
+}
@@ -583,6 +583,24 @@ func (cb *ClusterBuilder) setH2Options(mc *MutableCluster) {
 	}
 }
 
+// tunedConnectionPoolLimits returns the connection pool limits of the cluster tuned by the adaptive connection
+// pool, if any.
+func (cb *ClusterBuilder) tunedConnectionPoolLimits(mc *MutableCluster) model.ConnectionPoolLimits {
+	if cb.push == nil || mc == nil || mc.cluster == nil {
+		return model.ConnectionPoolLimits{}
+	}
+	return cb.push.ConnectionPoolLimits[mc.cluster.Name]
+}
+
+// raiseLimit returns the limit set by the DestinationRule raised to the tuned limit. Limits the
+// DestinationRule doesn't set are not tuned.
+func raiseLimit(limit *wrappers.UInt32Value, set bool, tuned uint32) *wrappers.UInt32Value {
+	if !set || tuned <= limit.GetValue() {
+		return limit
+	}
+	return &wrappers.UInt32Value{Value: tuned}
+}
+
 func (cb *ClusterBuilder) applyTrafficPolicy(opts buildClusterOpts) {
 	connectionPool, outlierDetection, loadBalancer, tls := selectTrafficPolicyComponents(opts.policy)
 	// Connection pool settings are applicable for both inbound and outbound clusters.
@@ -648,6 +666,11 @@ func (cb *ClusterBuilder) applyConnectionPool(mesh *meshconfig.MeshConfig, mc *M
 		applyTCPKeepalive(mesh, mc.cluster, settings)
 	}
 
+	// Limits tuned from the traffic reported by the proxies raise the limits set by the DestinationRule.
+	tuned := cb.tunedConnectionPoolLimits(mc)
+	threshold.MaxRequests = raiseLimit(threshold.MaxRequests, settings.Http.GetHttp2MaxRequests() > 0, tuned.MaxRequests)
+	threshold.MaxConnections = raiseLimit(threshold.MaxConnections, settings.Tcp.GetMaxConnections() > 0, tuned.MaxConnections)
+
 	mc.cluster.CircuitBreakers = &cluster.CircuitBreakers{
 		Thresholds: []*cluster.CircuitBreakers_Thresholds{threshold},
 	}
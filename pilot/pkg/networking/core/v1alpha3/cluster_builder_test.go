@@ -2424,3 +2424,84 @@ func TestIsHttp2Cluster(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyConnectionPoolTunedLimits(t *testing.T) {
+	defaults := getDefaultCircuitBreakerThresholds()
+	cases := []struct {
+		name     string
+		settings *networking.ConnectionPoolSettings
+		tuned    *model.ConnectionPoolLimits
+		want     *cluster.CircuitBreakers_Thresholds
+	}{
+		{
+			name: "no settings",
+		},
+		{
+			name:  "tuned limits without settings",
+			tuned: &model.ConnectionPoolLimits{MaxRequests: 100},
+		},
+		{
+			name: "tuned limits without the limits in settings",
+			settings: &networking.ConnectionPoolSettings{
+				Http: &networking.ConnectionPoolSettings_HTTPSettings{Http1MaxPendingRequests: 7},
+			},
+			tuned: &model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 50},
+			want: &cluster.CircuitBreakers_Thresholds{
+				MaxRetries:         defaults.MaxRetries,
+				MaxRequests:        defaults.MaxRequests,
+				MaxConnections:     defaults.MaxConnections,
+				MaxPendingRequests: &wrappers.UInt32Value{Value: 7},
+				TrackRemaining:     true,
+			},
+		},
+		{
+			name: "tuned limits below settings",
+			settings: &networking.ConnectionPoolSettings{
+				Http: &networking.ConnectionPoolSettings_HTTPSettings{Http2MaxRequests: 1000},
+				Tcp:  &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 500},
+			},
+			tuned: &model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 50},
+			want: &cluster.CircuitBreakers_Thresholds{
+				MaxRetries:         defaults.MaxRetries,
+				MaxRequests:        &wrappers.UInt32Value{Value: 1000},
+				MaxConnections:     &wrappers.UInt32Value{Value: 500},
+				MaxPendingRequests: defaults.MaxPendingRequests,
+				TrackRemaining:     true,
+			},
+		},
+		{
+			name: "tuned limits raise settings",
+			settings: &networking.ConnectionPoolSettings{
+				Http: &networking.ConnectionPoolSettings_HTTPSettings{Http2MaxRequests: 10, Http1MaxPendingRequests: 7},
+				Tcp:  &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 5},
+			},
+			tuned: &model.ConnectionPoolLimits{MaxRequests: 100, MaxConnections: 50},
+			want: &cluster.CircuitBreakers_Thresholds{
+				MaxRetries:         defaults.MaxRetries,
+				MaxRequests:        &wrappers.UInt32Value{Value: 100},
+				MaxConnections:     &wrappers.UInt32Value{Value: 50},
+				MaxPendingRequests: &wrappers.UInt32Value{Value: 7},
+				TrackRemaining:     true,
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			const name = "outbound|9080||reviews.default.svc.cluster.local"
+			push := model.NewPushContext()
+			if tt.tuned != nil {
+				push.ConnectionPoolLimits = map[string]model.ConnectionPoolLimits{name: *tt.tuned}
+			}
+			mc := NewMutableCluster(&cluster.Cluster{Name: name})
+			NewClusterBuilder(nil, push).applyConnectionPool(&meshconfig.MeshConfig{}, mc, tt.settings)
+
+			var got *cluster.CircuitBreakers_Thresholds
+			if mc.cluster.CircuitBreakers != nil {
+				got = mc.cluster.CircuitBreakers.Thresholds[0]
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected thresholds: %v", diff)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package envoyfilter
 
 import (
 	"fmt"
+	"sort"
 
 	xdslistener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
@@ -27,6 +28,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/util/runtime"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/pkg/log"
 )
@@ -408,6 +410,7 @@ func doHTTPFilterListOperation(patchContext networking.EnvoyFilter_PatchContext,
 		}
 		doHTTPFilterOperation(patchContext, patches, listener, fc, filter, httpFilter, &httpFiltersRemoved)
 	}
+	var phasePatches []*model.EnvoyFilterConfigPatchWrapper
 	for _, cp := range patches[networking.EnvoyFilter_HTTP_FILTER] {
 		if !commonConditionMatch(patchContext, cp) ||
 			!listenerMatch(listener, cp) ||
@@ -416,7 +419,10 @@ func doHTTPFilterListOperation(patchContext networking.EnvoyFilter_PatchContext,
 			continue
 		}
 
-		if cp.Operation == networking.EnvoyFilter_Patch_ADD {
+		if isPhaseInsert(cp) {
+			// inserted by phase once the other patches are applied
+			phasePatches = append(phasePatches, cp)
+		} else if cp.Operation == networking.EnvoyFilter_Patch_ADD {
 			hcm.HttpFilters = append(hcm.HttpFilters, proto.Clone(cp.Value).(*http_conn.HttpFilter))
 		} else if cp.Operation == networking.EnvoyFilter_Patch_INSERT_FIRST {
 			hcm.HttpFilters = append([]*http_conn.HttpFilter{proto.Clone(cp.Value).(*http_conn.HttpFilter)}, hcm.HttpFilters...)
@@ -502,12 +508,96 @@ func doHTTPFilterListOperation(patchContext networking.EnvoyFilter_PatchContext,
 		}
 		hcm.HttpFilters = tempArray
 	}
+	if len(phasePatches) > 0 {
+		hcm.HttpFilters = insertHTTPFiltersByPhase(hcm.HttpFilters, phasePatches)
+	}
 	if filter.GetTypedConfig() != nil {
 		// convert to any type
 		filter.ConfigType = &xdslistener.Filter_TypedConfig{TypedConfig: util.MessageToAny(hcm)}
 	}
 }
 
+// isPhaseInsert returns true if the patch inserts an HTTP filter in the phase of its filter class.
+func isPhaseInsert(cp *model.EnvoyFilterConfigPatchWrapper) bool {
+	if cp.FilterClass == networking.EnvoyFilter_Patch_UNSPECIFIED {
+		return false
+	}
+	switch cp.Operation {
+	case networking.EnvoyFilter_Patch_ADD, networking.EnvoyFilter_Patch_INSERT_FIRST,
+		networking.EnvoyFilter_Patch_INSERT_BEFORE, networking.EnvoyFilter_Patch_INSERT_AFTER:
+		return true
+	}
+	return false
+}
+
+// insertHTTPFiltersByPhase inserts the filters of the patches in the phase of their filter class, so
+// that the result does not depend on the order of the EnvoyFilters nor on the names of the other filters.
+// Patches are ordered by phase, priority, then namespace and name of their EnvoyFilter. A STATS filter is
+// inserted before the first stats filter Istio generates, a filter of another class after the last filter
+// of its phase. Without such filters, it is inserted before the first filter of a later phase, or else at
+// the end.
+func insertHTTPFiltersByPhase(filters []*http_conn.HttpFilter, patches []*model.EnvoyFilterConfigPatchWrapper) []*http_conn.HttpFilter {
+	sort.SliceStable(patches, func(i, j int) bool {
+		pi, pj := xdsfilters.PhaseOfClass(patches[i].FilterClass), xdsfilters.PhaseOfClass(patches[j].FilterClass)
+		if pi != pj {
+			return pi < pj
+		}
+		if patches[i].Priority != patches[j].Priority {
+			return patches[i].Priority < patches[j].Priority
+		}
+		if patches[i].Namespace != patches[j].Namespace {
+			return patches[i].Namespace < patches[j].Namespace
+		}
+		return patches[i].Name < patches[j].Name
+	})
+	phases := make([]xdsfilters.Phase, 0, len(filters)+len(patches))
+	// generated is false for the filters inserted by the patches.
+	generated := make([]bool, 0, len(filters)+len(patches))
+	for _, f := range filters {
+		phases = append(phases, xdsfilters.PhaseOf(f.Name))
+		generated = append(generated, true)
+	}
+	for _, cp := range patches {
+		phase := xdsfilters.PhaseOfClass(cp.FilterClass)
+		insertPosition := -1
+		if phase == xdsfilters.PhaseStats {
+			for i, p := range phases {
+				if p == phase && generated[i] {
+					insertPosition = i
+					break
+				}
+			}
+		}
+		if insertPosition == -1 {
+			for i := len(phases) - 1; i >= 0; i-- {
+				if phases[i] == phase {
+					insertPosition = i + 1
+					break
+				}
+			}
+		}
+		if insertPosition == -1 {
+			insertPosition = len(phases)
+			for i, p := range phases {
+				if p > phase {
+					insertPosition = i
+					break
+				}
+			}
+		}
+		filters = append(filters, nil)
+		copy(filters[insertPosition+1:], filters[insertPosition:])
+		filters[insertPosition] = proto.Clone(cp.Value).(*http_conn.HttpFilter)
+		phases = append(phases, xdsfilters.PhaseUnspecified)
+		copy(phases[insertPosition+1:], phases[insertPosition:])
+		phases[insertPosition] = phase
+		generated = append(generated, false)
+		copy(generated[insertPosition+1:], generated[insertPosition:])
+		generated[insertPosition] = false
+	}
+	return filters
+}
+
 func doHTTPFilterOperation(patchContext networking.EnvoyFilter_PatchContext,
 	patches map[networking.EnvoyFilter_ApplyTo][]*model.EnvoyFilterConfigPatchWrapper,
 	listener *xdslistener.Listener, fc *xdslistener.FilterChain, filter *xdslistener.Filter,
@@ -1498,6 +1498,102 @@ func TestApplyListenerPatches(t *testing.T) {
 	}
 }
 
+func TestInsertHTTPFiltersByPhase(t *testing.T) {
+	istioFilters := []string{
+		wellknown.HTTPExternalAuthorization, "envoy.filters.http.jwt_authn", "istio_authn",
+		wellknown.HTTPRoleBasedAccessControl, wellknown.CORS, wellknown.Router,
+	}
+	patch := func(name string, class networking.EnvoyFilter_Patch_FilterClass, priority int32) *model.EnvoyFilterConfigPatchWrapper {
+		return &model.EnvoyFilterConfigPatchWrapper{
+			Value:       &http_conn.HttpFilter{Name: name},
+			Operation:   networking.EnvoyFilter_Patch_INSERT_FIRST,
+			Name:        name,
+			Namespace:   "default",
+			FilterClass: class,
+			Priority:    priority,
+		}
+	}
+	tests := []struct {
+		name    string
+		filters []string
+		patches []*model.EnvoyFilterConfigPatchWrapper
+		want    []string
+	}{
+		{
+			name:    "after the last filter of the phase",
+			filters: istioFilters,
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("authz", networking.EnvoyFilter_Patch_AUTHZ, 0),
+				patch("authn", networking.EnvoyFilter_Patch_AUTHN, 0),
+			},
+			want: []string{
+				wellknown.HTTPExternalAuthorization, "envoy.filters.http.jwt_authn", "istio_authn", "authn",
+				wellknown.HTTPRoleBasedAccessControl, "authz", wellknown.CORS, wellknown.Router,
+			},
+		},
+		{
+			name:    "before the first filter of a later phase",
+			filters: istioFilters,
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("stats", networking.EnvoyFilter_Patch_STATS, 0),
+			},
+			want: append(append([]string{}, istioFilters[:5]...), "stats", wellknown.Router),
+		},
+		{
+			name:    "stats before the first stats filter",
+			filters: []string{wellknown.CORS, "istio.metadata_exchange", "istio.stats", wellknown.Router},
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("stats-2", networking.EnvoyFilter_Patch_STATS, 2),
+				patch("stats-1", networking.EnvoyFilter_Patch_STATS, 1),
+			},
+			want: []string{wellknown.CORS, "stats-1", "stats-2", "istio.metadata_exchange", "istio.stats", wellknown.Router},
+		},
+		{
+			name:    "at the end without filters of a later phase",
+			filters: []string{wellknown.CORS},
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("authn", networking.EnvoyFilter_Patch_AUTHN, 0),
+			},
+			want: []string{wellknown.CORS, "authn"},
+		},
+		{
+			name:    "ordered by priority regardless of the patch order",
+			filters: []string{wellknown.Router},
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("stats-10", networking.EnvoyFilter_Patch_STATS, 10),
+				patch("stats-default-b", networking.EnvoyFilter_Patch_STATS, 0),
+				patch("stats-minus-1", networking.EnvoyFilter_Patch_STATS, -1),
+				patch("stats-default-a", networking.EnvoyFilter_Patch_STATS, 0),
+			},
+			// The same priority is ordered by name.
+			want: []string{"stats-minus-1", "stats-default-a", "stats-default-b", "stats-10", wellknown.Router},
+		},
+		{
+			name:    "deprecated names",
+			filters: []string{"envoy.router"},
+			patches: []*model.EnvoyFilterConfigPatchWrapper{
+				patch("stats", networking.EnvoyFilter_Patch_STATS, 0),
+			},
+			want: []string{"stats", "envoy.router"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := make([]*http_conn.HttpFilter, 0, len(tt.filters))
+			for _, name := range tt.filters {
+				filters = append(filters, &http_conn.HttpFilter{Name: name})
+			}
+			var got []string
+			for _, f := range insertHTTPFiltersByPhase(filters, tt.patches) {
+				got = append(got, f.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("insertHTTPFiltersByPhase(): mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 // This benchmark measures the performance of Telemetry V2 EnvoyFilter patches. The intent here is to
 // measure overhead of using EnvoyFilters rather than native code.
 func BenchmarkTelemetryV2Filters(b *testing.B) {
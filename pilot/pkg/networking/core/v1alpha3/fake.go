@@ -224,6 +224,7 @@ func (f *ConfigGenTest) SetupProxy(p *model.Proxy) *model.Proxy {
 	pc := f.PushContext()
 	p.SetSidecarScope(pc)
 	p.SetGatewaysForProxy(pc)
+	p.SetLocalRateLimit(pc)
 	p.SetServiceInstances(f.env.ServiceDiscovery)
 	p.DiscoverIPVersions()
 	return p
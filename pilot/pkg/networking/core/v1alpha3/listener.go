@@ -1434,6 +1434,10 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 		filters = append(filters, xdsfilters.Alpn)
 	}
 
+	if rateLimit := buildHTTPLocalRateLimitFilter(listenerOpts); rateLimit != nil {
+		filters = append(filters, rateLimit)
+	}
+
 	filters = append(filters, xdsfilters.Cors, xdsfilters.Fault, xdsfilters.Router)
 
 	if httpOpts.connectionManager == nil {
@@ -1671,6 +1675,11 @@ func (ml *MutableListener) build(opts buildListenerOpts) error {
 			// For network filters such as mysql, mongo, etc., we need the filter codec upfront. Data from this
 			// codec is used by RBAC later.
 
+			// Rate limit new connections before any other processing.
+			if rateLimit := buildNetworkLocalRateLimitFilter(opts); rateLimit != nil {
+				ml.Listener.FilterChains[i].Filters = append(ml.Listener.FilterChains[i].Filters, rateLimit)
+			}
+
 			if len(opt.networkFilters) > 0 {
 				// this is the terminating filter
 				lastNetworkFilter := opt.networkFilters[len(opt.networkFilters)-1]
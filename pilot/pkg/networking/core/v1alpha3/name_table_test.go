@@ -83,6 +83,40 @@ func TestNameTable(t *testing.T) {
 	push.AddServiceInstances(headlessService,
 		makeServiceInstances(pod2, headlessService, "pod2", "headless-svc"))
 
+	clusterSetHeadlessService := &model.Service{
+		Hostname:    host.Name("headless-svc.testns.svc.clusterset.local"),
+		Address:     constants.UnspecifiedIP,
+		ClusterVIPs: make(map[string]string),
+		Ports:       headlessService.Ports,
+		Resolution:  model.Passthrough,
+		Attributes:  headlessService.Attributes,
+	}
+	clusterSetService := &model.Service{
+		Hostname:    host.Name("svc.testns.svc.clusterset.local"),
+		Address:     constants.UnspecifiedIP,
+		ClusterVIPs: map[string]string{"cluster-1": "240.0.0.1"},
+		Ports:       headlessService.Ports,
+		Resolution:  model.ClientSideLB,
+		Attributes: model.ServiceAttributes{
+			Name:            "svc",
+			Namespace:       "testns",
+			ServiceRegistry: string(serviceregistry.Kubernetes),
+		},
+	}
+	clusterSetPush := model.NewPushContext()
+	clusterSetPush.AddPublicServices([]*model.Service{clusterSetHeadlessService, clusterSetService})
+	clusterSetPush.AddServiceInstances(clusterSetHeadlessService,
+		makeServiceInstances(pod1, clusterSetHeadlessService, "pod1", "headless-svc"))
+	clusterSetPush.AddServiceInstances(clusterSetHeadlessService,
+		makeServiceInstances(pod2, clusterSetHeadlessService, "pod2", "headless-svc"))
+
+	cluster1Proxy := &model.Proxy{
+		IPAddresses: []string{"9.9.9.9"},
+		Metadata:    &model.NodeMetadata{ClusterID: "cluster-1"},
+		Type:        model.SidecarProxy,
+		DNSDomain:   "testns.svc.cluster.local",
+	}
+
 	cases := []struct {
 		name              string
 		proxy             *model.Proxy
@@ -116,6 +150,37 @@ func TestNameTable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "clusterset services in importing cluster",
+			proxy: cluster1Proxy,
+			push:  clusterSetPush,
+			expectedNameTable: &nds.NameTable{
+				Table: map[string]*nds.NameTable_NameInfo{
+					// the headless service has no endpoints in cluster-1
+					"svc.testns.svc.clusterset.local": {
+						Ips:       []string{"240.0.0.1"},
+						Registry:  "Kubernetes",
+						Shortname: "svc",
+						Namespace: "testns",
+					},
+				},
+			},
+		},
+		{
+			name:  "clusterset services in cluster without ServiceImport, without pod names",
+			proxy: proxy,
+			push:  clusterSetPush,
+			expectedNameTable: &nds.NameTable{
+				Table: map[string]*nds.NameTable_NameInfo{
+					"headless-svc.testns.svc.clusterset.local": {
+						Ips:       []string{"1.2.3.4", "9.6.7.8"},
+						Registry:  "Kubernetes",
+						Shortname: "headless-svc",
+						Namespace: "testns",
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+// listenerRateLimit returns the token bucket configured for the listener, or nil. Listener rate limits
+// only apply to traffic received by the workload: inbound sidecar listeners and gateway listeners.
+func listenerRateLimit(opts buildListenerOpts) *ratelimit.TokenBucket {
+	if opts.port == nil || (opts.class != ListenerClassSidecarInbound && opts.class != ListenerClassGateway) {
+		return nil
+	}
+	return opts.proxy.LocalRateLimit.ListenerLimit(uint32(opts.port.Port))
+}
+
+// buildHTTPLocalRateLimitFilter returns the HTTP local rate limit filter of the listener, or nil if the
+// proxy has no listener or route rate limits applying to it.
+func buildHTTPLocalRateLimitFilter(opts buildListenerOpts) *hcm.HttpFilter {
+	if bucket := listenerRateLimit(opts); bucket != nil {
+		return xdsfilters.BuildHTTPLocalRateLimit(bucket)
+	}
+	if rl := opts.proxy.LocalRateLimit; rl != nil && len(rl.Routes) > 0 {
+		// Route rate limits are configured per route, but only apply if the filter is in the chain.
+		return xdsfilters.EmptyHTTPLocalRateLimit
+	}
+	return nil
+}
+
+// buildNetworkLocalRateLimitFilter returns the network local rate limit filter of the listener, or nil.
+func buildNetworkLocalRateLimitFilter(opts buildListenerOpts) *listener.Filter {
+	if bucket := listenerRateLimit(opts); bucket != nil {
+		return xdsfilters.BuildNetworkLocalRateLimit(bucket)
+	}
+	return nil
+}
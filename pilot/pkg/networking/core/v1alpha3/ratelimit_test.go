@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3_test
+
+import (
+	"testing"
+
+	httplrl "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
+
+	"istio.io/istio/pilot/pkg/config/kube/ratelimit"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	ratelimitconfig "istio.io/istio/pkg/config/ratelimit"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+func TestGatewayLocalRateLimit(t *testing.T) {
+	configs := createGateway("gateway", "", `port:
+  number: 80
+  name: http
+  protocol: HTTP
+hosts:
+- "*"
+`) + `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+  namespace: default
+spec:
+  hosts:
+  - "example.com"
+  gateways:
+  - gateway
+  http:
+  - name: limited
+    match:
+    - uri:
+        prefix: /limited
+    route:
+    - destination:
+        host: a
+  - name: unlimited
+    route:
+    - destination:
+        host: a
+`
+	bucket := func(maxTokens uint32) ratelimitconfig.TokenBucket {
+		return ratelimitconfig.TokenBucket{MaxTokens: maxTokens, FillInterval: "1s"}
+	}
+	cases := []struct {
+		name           string
+		namespace      string
+		spec           *ratelimitconfig.LocalRateLimit
+		listenerFilter bool
+		listenerTokens uint32
+		limitedTokens  uint32
+	}{
+		{
+			name: "no rate limit",
+		},
+		{
+			name: "listener",
+			spec: &ratelimitconfig.LocalRateLimit{
+				Listeners: []ratelimitconfig.ListenerRateLimit{{Port: 80, TokenBucket: bucket(100)}},
+			},
+			listenerFilter: true,
+			listenerTokens: 100,
+		},
+		{
+			name: "route",
+			spec: &ratelimitconfig.LocalRateLimit{
+				Routes: []ratelimitconfig.RouteRateLimit{{Name: "limited", VirtualService: "vs.default", TokenBucket: bucket(5)}},
+			},
+			listenerFilter: true,
+			limitedTokens:  5,
+		},
+		{
+			name: "route of another virtual service",
+			spec: &ratelimitconfig.LocalRateLimit{
+				Routes: []ratelimitconfig.RouteRateLimit{{Name: "limited", VirtualService: "other.default", TokenBucket: bucket(5)}},
+			},
+			listenerFilter: true,
+		},
+		{
+			name: "selected workload",
+			spec: &ratelimitconfig.LocalRateLimit{
+				WorkloadSelector: &ratelimitconfig.WorkloadSelector{Labels: map[string]string{"istio": "ingressgateway"}},
+				Listeners:        []ratelimitconfig.ListenerRateLimit{{Port: 80, TokenBucket: bucket(100)}},
+			},
+			listenerFilter: true,
+			listenerTokens: 100,
+		},
+		{
+			name: "other workload",
+			spec: &ratelimitconfig.LocalRateLimit{
+				WorkloadSelector: &ratelimitconfig.WorkloadSelector{Labels: map[string]string{"istio": "egressgateway"}},
+				Listeners:        []ratelimitconfig.ListenerRateLimit{{Port: 80, TokenBucket: bucket(100)}},
+			},
+		},
+		{
+			name:      "root namespace",
+			namespace: "istio-system",
+			spec: &ratelimitconfig.LocalRateLimit{
+				Listeners: []ratelimitconfig.ListenerRateLimit{{Port: 80, TokenBucket: bucket(100)}},
+			},
+			listenerFilter: true,
+			listenerTokens: 100,
+		},
+		{
+			name:      "other namespace",
+			namespace: "other",
+			spec: &ratelimitconfig.LocalRateLimit{
+				Listeners: []ratelimitconfig.ListenerRateLimit{{Port: 80, TokenBucket: bucket(100)}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewController(memory.Make(collection.SchemasFor(ratelimit.Schema)))
+			if tt.spec != nil {
+				namespace := tt.namespace
+				if namespace == "" {
+					namespace = "default"
+				}
+				if _, err := store.Create(config.Config{
+					Meta: config.Meta{
+						GroupVersionKind: ratelimitconfig.GroupVersionKind,
+						Name:             "ratelimit",
+						Namespace:        namespace,
+					},
+					Spec: tt.spec,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{
+				ConfigString:      configs,
+				ConfigStoreCaches: []model.ConfigStoreCache{store},
+			})
+			proxy := s.SetupProxy(&model.Proxy{
+				Metadata: &model.NodeMetadata{
+					Labels: map[string]string{"istio": "ingressgateway"},
+				},
+				Type: model.Router,
+			})
+
+			l := xdstest.ExtractListener("0.0.0.0_80", s.Listeners(proxy))
+			if l == nil {
+				t.Fatalf("listener 0.0.0.0_80 not found")
+			}
+			h := xdstest.ExtractHTTPConnectionManager(t, l.FilterChains[0])
+			var filter *httplrl.LocalRateLimit
+			for _, f := range h.HttpFilters {
+				if f.Name == xdsfilters.HTTPLocalRateLimitFilterName {
+					filter = &httplrl.LocalRateLimit{}
+					if err := f.GetTypedConfig().UnmarshalTo(filter); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if (filter != nil) != tt.listenerFilter {
+				t.Fatalf("got local rate limit filter %v, want %v", filter != nil, tt.listenerFilter)
+			}
+			if got := filter.GetTokenBucket().GetMaxTokens(); got != tt.listenerTokens {
+				t.Errorf("got listener max tokens %d, want %d", got, tt.listenerTokens)
+			}
+
+			rc := xdstest.ExtractRouteConfigurations(s.Routes(proxy))["http.80"]
+			if rc == nil {
+				t.Fatalf("route http.80 not found")
+			}
+			for _, vh := range rc.VirtualHosts {
+				for _, r := range vh.Routes {
+					var want uint32
+					if r.Name == "limited" {
+						want = tt.limitedTokens
+					}
+					cfg, f := r.TypedPerFilterConfig[xdsfilters.HTTPLocalRateLimitFilterName]
+					if f != (want != 0) {
+						t.Fatalf("route %q: got per route rate limit %v, want %v", r.Name, f, want != 0)
+					}
+					if !f {
+						continue
+					}
+					rl := &httplrl.LocalRateLimit{}
+					if err := cfg.UnmarshalTo(rl); err != nil {
+						t.Fatal(err)
+					}
+					if got := rl.GetTokenBucket().GetMaxTokens(); got != want {
+						t.Errorf("route %q: got max tokens %d, want %d", r.Name, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route/retry"
 	"istio.io/istio/pilot/pkg/networking/util"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
@@ -492,6 +493,9 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 	if fault := in.Fault; fault != nil {
 		out.TypedPerFilterConfig[wellknown.Fault] = util.MessageToAny(translateFault(in.Fault))
 	}
+	if bucket := node.LocalRateLimit.RouteLimit(in.Name, virtualService.Name+"."+virtualService.Namespace); bucket != nil {
+		out.TypedPerFilterConfig[xdsfilters.HTTPLocalRateLimitFilterName] = xdsfilters.BuildHTTPLocalRateLimitConfig(bucket)
+	}
 
 	return out
 }
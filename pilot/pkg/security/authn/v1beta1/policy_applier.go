@@ -269,7 +269,7 @@ func convertToEnvoyJwtConfig(jwtRules []*v1beta1.JWTRule, push *model.PushContex
 		}
 		provider.FromParams = jwtRule.FromParams
 
-		if features.EnableRemoteJwks && jwtRule.JwksUri != "" {
+		if (features.EnableRemoteJwks || push.JwtKeyResolver.FetchByEnvoy(jwtRule.Issuer)) && jwtRule.JwksUri != "" {
 			// Use remote jwks if jwksUri is non empty. Parse the jwksUri to get the cluster name,
 			// generate the jwt filter config using remoteJwks.
 			// If failed to parse the cluster name, fallback to let istiod to fetch the jwksUri.
@@ -0,0 +1,229 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"net"
+	"strings"
+)
+
+// Covers returns true if every request matched by o is also matched by m. The check is conservative, false is
+// returned when it cannot be determined, e.g. for wildcard values excluded by not values.
+func (m *Model) Covers(o *Model) bool {
+	return listsCover(m.permissions, o.permissions) && listsCover(m.principals, o.principals)
+}
+
+// MatchesAll returns true if m matches every request, e.g. for a rule without conditions.
+func (m *Model) MatchesAll() bool {
+	return anyMatchesAll(m.permissions) && anyMatchesAll(m.principals)
+}
+
+// listsCover returns true if each of the rule lists of b is covered by one of the rule lists of a.
+func listsCover(a, b []ruleList) bool {
+	for _, bl := range b {
+		covered := false
+		for _, al := range a {
+			if al.covers(bl) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func anyMatchesAll(lists []ruleList) bool {
+	for _, l := range lists {
+		if l.matchesAll() {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if every request matching all the rules of o also matches all the rules of p.
+func (p ruleList) covers(o ruleList) bool {
+	for _, r := range p.rules {
+		if r.matchesAll() {
+			continue
+		}
+		covered := false
+		for _, or := range o.rules {
+			if or.key == r.key && r.covers(or) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func (p ruleList) matchesAll() bool {
+	for _, r := range p.rules {
+		if !r.matchesAll() {
+			return false
+		}
+	}
+	return true
+}
+
+// covers returns true if every value of the attribute matched by o, a rule for the same attribute, is matched by r.
+func (r *rule) covers(o *rule) bool {
+	if len(r.values) > 0 {
+		if len(o.values) == 0 {
+			return false
+		}
+		for _, ov := range o.values {
+			if !r.anyValueCovers(ov) {
+				return false
+			}
+		}
+	}
+	for _, nv := range r.notValues {
+		if !o.excludes(nv) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *rule) anyValueCovers(v string) bool {
+	for _, rv := range r.values {
+		if valueCovers(r.key, rv, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes returns true if none of the values matching v are matched by r.
+func (r *rule) excludes(v string) bool {
+	for _, nv := range r.notValues {
+		if valueCovers(r.key, nv, v) {
+			return true
+		}
+	}
+	if len(r.values) == 0 {
+		return false
+	}
+	for _, rv := range r.values {
+		if !valuesDisjoint(r.key, rv, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAll returns true if the rule matches every request. This is only known for the attributes that are
+// always present, when one of the values matches any value.
+func (r *rule) matchesAll() bool {
+	if len(r.notValues) > 0 {
+		return false
+	}
+	switch {
+	case isIPAttribute(r.key):
+		var v4, v6 bool
+		for _, v := range r.values {
+			v4 = v4 || v == "0.0.0.0/0"
+			v6 = v6 || v == "::/0"
+		}
+		return v4 && v6
+	case r.key == methodHeader || r.key == pathMatcher || r.key == hostHeader:
+		for _, v := range r.values {
+			if v == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueCovers returns true if every value matched by b is matched by a. String values support the exact, prefix
+// ("abc*"), suffix ("*abc") and presence ("*") matches, IP values are addresses or CIDR ranges.
+func valueCovers(key, a, b string) bool {
+	if isIPAttribute(key) {
+		return cidrCovers(a, b)
+	}
+	if a == b || a == "*" {
+		return true
+	}
+	if b == "*" {
+		return false
+	}
+	switch {
+	case strings.HasSuffix(a, "*"):
+		if strings.HasPrefix(b, "*") {
+			return false
+		}
+		return strings.HasPrefix(strings.TrimSuffix(b, "*"), strings.TrimSuffix(a, "*"))
+	case strings.HasPrefix(a, "*"):
+		if strings.HasSuffix(b, "*") {
+			return false
+		}
+		return strings.HasSuffix(strings.TrimPrefix(b, "*"), strings.TrimPrefix(a, "*"))
+	}
+	return false
+}
+
+// valuesDisjoint returns true if no value is matched by both a and b. It is only known when one of them is an
+// exact value.
+func valuesDisjoint(key, a, b string) bool {
+	if isIPAttribute(key) {
+		return !cidrCovers(a, b) && !cidrCovers(b, a)
+	}
+	if !strings.Contains(b, "*") {
+		return !valueCovers(key, a, b)
+	}
+	if !strings.Contains(a, "*") {
+		return !valueCovers(key, b, a)
+	}
+	return false
+}
+
+func cidrCovers(a, b string) bool {
+	an, err := parseCIDR(a)
+	if err != nil {
+		return false
+	}
+	bn, err := parseCIDR(b)
+	if err != nil {
+		return false
+	}
+	aOnes, aBits := an.Mask.Size()
+	bOnes, bBits := bn.Mask.Size()
+	return aBits == bBits && aOnes <= bOnes && an.Contains(bn.IP)
+}
+
+func parseCIDR(v string) (*net.IPNet, error) {
+	if !strings.Contains(v, "/") {
+		if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+			v += "/32"
+		} else {
+			v += "/128"
+		}
+	}
+	_, n, err := net.ParseCIDR(v)
+	return n, err
+}
+
+func isIPAttribute(key string) bool {
+	return key == attrSrcIP || key == attrRemoteIP || key == attrDestIP
+}
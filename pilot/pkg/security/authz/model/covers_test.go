@@ -0,0 +1,353 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestModel_Covers(t *testing.T) {
+	cases := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "empty-covers-all",
+			a:    `{}`,
+			b: `
+from:
+- source:
+    namespaces: ["foo"]
+to:
+- operation:
+    paths: ["/api"]
+`,
+			want: true,
+		},
+		{
+			name: "all-not-covered",
+			a: `
+from:
+- source:
+    namespaces: ["foo"]
+`,
+			b:    `{}`,
+			want: false,
+		},
+		{
+			name: "identical",
+			a: `
+to:
+- operation:
+    methods: ["GET"]
+    paths: ["/api"]
+`,
+			b: `
+to:
+- operation:
+    methods: ["GET"]
+    paths: ["/api"]
+`,
+			want: true,
+		},
+		{
+			name: "prefix-covers-exact-and-prefix",
+			a: `
+to:
+- operation:
+    paths: ["/api/*"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/api/v1", "/api/v2/*"]
+    methods: ["GET"]
+`,
+			want: true,
+		},
+		{
+			name: "prefix-does-not-cover-suffix",
+			a: `
+to:
+- operation:
+    paths: ["/api/*"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["*/v1"]
+`,
+			want: false,
+		},
+		{
+			name: "suffix-covers-exact",
+			a: `
+to:
+- operation:
+    hosts: ["*.example.com"]
+`,
+			b: `
+to:
+- operation:
+    hosts: ["www.example.com"]
+`,
+			want: true,
+		},
+		{
+			name: "more-conditions-not-covered",
+			a: `
+to:
+- operation:
+    methods: ["GET"]
+    paths: ["/api"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/api"]
+`,
+			want: false,
+		},
+		{
+			name: "every-operation-covered",
+			a: `
+to:
+- operation:
+    paths: ["/a"]
+- operation:
+    paths: ["/b"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/b"]
+`,
+			want: true,
+		},
+		{
+			name: "one-operation-not-covered",
+			a: `
+to:
+- operation:
+    paths: ["/a"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/a"]
+- operation:
+    paths: ["/b"]
+`,
+			want: false,
+		},
+		{
+			name: "cidr",
+			a: `
+from:
+- source:
+    ipBlocks: ["10.0.0.0/8"]
+`,
+			b: `
+from:
+- source:
+    ipBlocks: ["10.1.0.0/16", "10.2.3.4"]
+`,
+			want: true,
+		},
+		{
+			name: "cidr-not-covered",
+			a: `
+from:
+- source:
+    ipBlocks: ["10.1.0.0/16"]
+`,
+			b: `
+from:
+- source:
+    ipBlocks: ["10.0.0.0/8"]
+`,
+			want: false,
+		},
+		{
+			name: "not-values-excluded",
+			a: `
+to:
+- operation:
+    notPaths: ["/admin*"]
+`,
+			b: `
+to:
+- operation:
+    notPaths: ["/admin*", "/debug"]
+`,
+			want: true,
+		},
+		{
+			name: "not-values-disjoint",
+			a: `
+to:
+- operation:
+    notPaths: ["/admin"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/api"]
+`,
+			want: true,
+		},
+		{
+			name: "not-values-not-excluded",
+			a: `
+to:
+- operation:
+    notPaths: ["/admin"]
+`,
+			b: `
+to:
+- operation:
+    paths: ["/*"]
+`,
+			want: false,
+		},
+		{
+			name: "when-condition",
+			a: `
+when:
+- key: request.headers[x-token]
+  values: ["*"]
+`,
+			b: `
+from:
+- source:
+    namespaces: ["foo"]
+when:
+- key: request.headers[x-token]
+  values: ["abc"]
+`,
+			want: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(yamlRule(t, tc.a))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := New(yamlRule(t, tc.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Covers(b); got != tc.want {
+				t.Errorf("got %v but want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestModel_MatchesAll(t *testing.T) {
+	cases := []struct {
+		name string
+		rule string
+		want bool
+	}{
+		{
+			name: "empty",
+			rule: `{}`,
+			want: true,
+		},
+		{
+			name: "empty-source-and-operation",
+			rule: `
+from:
+- source: {}
+to:
+- operation: {}
+`,
+			want: true,
+		},
+		{
+			name: "wildcard-operation",
+			rule: `
+to:
+- operation:
+    paths: ["*"]
+    methods: ["*"]
+`,
+			want: true,
+		},
+		{
+			name: "all-ips",
+			rule: `
+from:
+- source:
+    ipBlocks: ["0.0.0.0/0", "::/0"]
+`,
+			want: true,
+		},
+		{
+			name: "ipv4-only",
+			rule: `
+from:
+- source:
+    ipBlocks: ["0.0.0.0/0"]
+`,
+			want: false,
+		},
+		{
+			name: "any-principal",
+			rule: `
+from:
+- source:
+    principals: ["*"]
+`,
+			want: false,
+		},
+		{
+			name: "one-open-source",
+			rule: `
+from:
+- source:
+    namespaces: ["foo"]
+- source: {}
+`,
+			want: true,
+		},
+		{
+			name: "not-values",
+			rule: `
+to:
+- operation:
+    notPaths: ["/admin"]
+`,
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(yamlRule(t, tc.rule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.MatchesAll(); got != tc.want {
+				t.Errorf("got %v but want %v", got, tc.want)
+			}
+		})
+	}
+}
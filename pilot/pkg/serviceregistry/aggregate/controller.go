@@ -172,11 +172,18 @@ func (c *Controller) GetService(hostname host.Name) (*model.Service, error) {
 }
 
 func mergeService(dst, src *model.Service, srcCluster string) {
+	// Services without default address, such as MCS clusterset.local services, may still have a VIP in their cluster.
+	vip := src.Address
+	src.Mutex.RLock()
+	if clusterVIP := src.ClusterVIPs[srcCluster]; clusterVIP != "" {
+		vip = clusterVIP
+	}
+	src.Mutex.RUnlock()
 	dst.Mutex.Lock()
 	if dst.ClusterVIPs == nil {
 		dst.ClusterVIPs = make(map[string]string)
 	}
-	dst.ClusterVIPs[srcCluster] = src.Address
+	dst.ClusterVIPs[srcCluster] = vip
 	dst.Mutex.Unlock()
 }
 
@@ -170,6 +170,17 @@ func TestServicesForMultiCluster(t *testing.T) {
 	t.Logf("Return service ClusterVIPs match ground truth")
 }
 
+func TestMergeServiceClusterVIPs(t *testing.T) {
+	dst := &model.Service{Hostname: "svc.ns.svc.clusterset.local", Address: "0.0.0.0", ClusterVIPs: map[string]string{"cluster-1": "240.0.0.1"}}
+	mergeService(dst, &model.Service{Address: "0.0.0.0", ClusterVIPs: map[string]string{"cluster-2": "240.0.0.2"}}, "cluster-2")
+	mergeService(dst, &model.Service{Address: "0.0.0.0", ClusterVIPs: map[string]string{}}, "cluster-3")
+	mergeService(dst, &model.Service{Address: "10.0.0.4"}, "cluster-4")
+	want := map[string]string{"cluster-1": "240.0.0.1", "cluster-2": "240.0.0.2", "cluster-3": "0.0.0.0", "cluster-4": "10.0.0.4"}
+	if !reflect.DeepEqual(dst.ClusterVIPs, want) {
+		t.Fatalf("got ClusterVIPs %v, want %v", dst.ClusterVIPs, want)
+	}
+}
+
 func TestServices(t *testing.T) {
 	aggregateCtl := buildMockController()
 	// List Services from aggregate controller
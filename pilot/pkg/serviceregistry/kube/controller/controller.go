@@ -218,6 +218,9 @@ type Controller struct {
 
 	pods *PodCache
 
+	// mcs synthesizes clusterset.local services from MCS ServiceExports and ServiceImports, if enabled.
+	mcs *mcsController
+
 	metrics         model.Metrics
 	networksWatcher mesh.NetworksWatcher
 	xdsUpdater      model.XDSUpdater
@@ -355,6 +358,10 @@ func NewController(kubeClient kubelib.Client, options Options) *Controller {
 	})
 	registerHandlers(c.pods.informer, c.queue, "Pods", c.pods.onEvent, nil)
 
+	if features.EnableMCSClusterSetLocal {
+		c.mcs = newMCSController(c, kubeClient)
+	}
+
 	return c
 }
 
@@ -466,6 +473,10 @@ func (c *Controller) onServiceEvent(curr interface{}, event model.Event) error {
 		f(svcConv, event)
 	}
 
+	if c.mcs != nil {
+		c.mcs.updateService(svc.Name, svc.Namespace)
+	}
+
 	return nil
 }
 
@@ -598,7 +609,8 @@ func (c *Controller) HasSynced() bool {
 		!c.serviceInformer.HasSynced() ||
 		!c.endpoints.HasSynced() ||
 		!c.pods.informer.HasSynced() ||
-		!c.nodeInformer.HasSynced() {
+		!c.nodeInformer.HasSynced() ||
+		(c.mcs != nil && !c.mcs.HasSynced()) {
 		return false
 	}
 
@@ -744,6 +756,11 @@ func (c *Controller) getPodLocality(pod *v1.Pod) string {
 
 // InstancesByPort implements a service catalog operation
 func (c *Controller) InstancesByPort(svc *model.Service, reqSvcPort int, labelsList labels.Collection) []*model.ServiceInstance {
+	// Only clusters exporting a service are endpoints of its clusterset.local service.
+	if kube.IsClusterSetHostname(svc.Hostname) &&
+		(c.mcs == nil || !c.mcs.isExported(svc.Attributes.Name, svc.Attributes.Namespace)) {
+		return nil
+	}
 	// First get k8s standard service instances and the workload entry instances
 	outInstances := c.endpoints.InstancesByPort(c, svc, reqSvcPort, labelsList)
 	outInstances = append(outInstances, c.serviceInstancesFromWorkloadInstances(svc, reqSvcPort)...)
@@ -110,6 +110,9 @@ func updateEDS(c *Controller, epc kubeEndpointsController, ep interface{}, event
 	}
 
 	c.xdsUpdater.EDSUpdate(c.clusterID, string(host), ns, endpoints)
+	if c.mcs != nil {
+		c.mcs.onEndpoints(svcName, ns, endpoints)
+	}
 }
 
 // getPod fetches a pod by name or IP address.
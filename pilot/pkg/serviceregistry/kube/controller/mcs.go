@@ -0,0 +1,167 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller/filter"
+	"istio.io/istio/pkg/config/constants"
+	kubelib "istio.io/istio/pkg/kube"
+)
+
+// mcsController synthesizes the <svc>.<ns>.svc.clusterset.local services of the Kubernetes Multi-Cluster
+// Services API from the ServiceExport and ServiceImport objects of a cluster. A ServiceImport makes the
+// service resolvable from the cluster, through its ClusterSetIP. A ServiceExport makes the endpoints of
+// the service in the cluster reachable from the clusterset.
+// The clusterset.local services of all clusters are merged by the aggregate registry, like the cluster.local
+// services, so that each cluster gets its own ClusterSetIP and only exporting clusters contribute endpoints.
+type mcsController struct {
+	c *Controller
+
+	exportInformer filter.FilteredSharedIndexInformer
+	importInformer filter.FilteredSharedIndexInformer
+}
+
+func newMCSController(c *Controller, kubeClient kubelib.Client) *mcsController {
+	m := &mcsController{c: c}
+	informers := kubeClient.MCSApisInformer().Multicluster().V1alpha1()
+	m.exportInformer = filter.NewFilteredSharedIndexInformer(c.discoveryNamespacesFilter.Filter, informers.ServiceExports().Informer())
+	m.importInformer = filter.NewFilteredSharedIndexInformer(c.discoveryNamespacesFilter.Filter, informers.ServiceImports().Informer())
+	registerHandlers(m.exportInformer, c.queue, "ServiceExports", m.onEvent, nil)
+	registerHandlers(m.importInformer, c.queue, "ServiceImports", m.onEvent, nil)
+	return m
+}
+
+func (m *mcsController) HasSynced() bool {
+	return m.exportInformer.HasSynced() && m.importInformer.HasSynced()
+}
+
+func (m *mcsController) onEvent(obj interface{}, _ model.Event) error {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return err
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	m.updateService(name, namespace)
+	return nil
+}
+
+// isExported returns true if the service is exported by this cluster.
+func (m *mcsController) isExported(name, namespace string) bool {
+	_, exists, err := m.exportInformer.GetIndexer().GetByKey(kube.KeyFunc(name, namespace))
+	return exists && err == nil
+}
+
+func (m *mcsController) getImport(name, namespace string) *v1alpha1.ServiceImport {
+	item, exists, err := m.importInformer.GetIndexer().GetByKey(kube.KeyFunc(name, namespace))
+	if !exists || err != nil {
+		return nil
+	}
+	return item.(*v1alpha1.ServiceImport)
+}
+
+// updateService recomputes the clusterset.local service of a k8s service, after a change to the service
+// or to its ServiceExport or ServiceImport.
+func (m *mcsController) updateService(name, namespace string) {
+	c := m.c
+	hostname := kube.ClusterSetHostname(name, namespace)
+	svc, _ := c.serviceLister.Services(namespace).Get(name)
+	exported := m.isExported(name, namespace)
+	imp := m.getImport(name, namespace)
+
+	c.RLock()
+	_, existed := c.servicesMap[hostname]
+	c.RUnlock()
+
+	// ExternalName services have no endpoints to export.
+	if svc == nil || svc.Spec.Type == v1.ServiceTypeExternalName || (!exported && imp == nil) {
+		if !existed {
+			return
+		}
+		c.Lock()
+		svcConv := c.servicesMap[hostname]
+		delete(c.servicesMap, hostname)
+		c.Unlock()
+		c.xdsUpdater.EDSUpdate(c.clusterID, string(hostname), namespace, nil)
+		c.xdsUpdater.SvcUpdate(c.clusterID, string(hostname), namespace, model.EventDelete)
+		for _, f := range c.serviceHandlers {
+			f(svcConv, model.EventDelete)
+		}
+		return
+	}
+
+	svcConv := m.convertService(svc, imp)
+	c.Lock()
+	c.servicesMap[hostname] = svcConv
+	c.Unlock()
+
+	// Clusters not exporting the service clear their endpoints, which may have been exported before.
+	var endpoints []*model.IstioEndpoint
+	if exported {
+		// The endpoints are the endpoints of the cluster.local service, which are cached under its hostname.
+		endpoints = c.endpoints.buildIstioEndpointsWithService(name, namespace, kube.ServiceHostname(name, namespace, c.domainSuffix))
+		if features.EnableK8SServiceSelectWorkloadEntries {
+			endpoints = append(endpoints, c.collectWorkloadInstanceEndpoints(svcConv)...)
+		}
+	}
+	c.xdsUpdater.EDSUpdate(c.clusterID, string(hostname), namespace, endpoints)
+
+	event := model.EventAdd
+	if existed {
+		event = model.EventUpdate
+	}
+	c.xdsUpdater.SvcUpdate(c.clusterID, string(hostname), namespace, event)
+	for _, f := range c.serviceHandlers {
+		f(svcConv, event)
+	}
+}
+
+// convertService builds the clusterset.local service of a k8s service. The service has no default
+// address: it is only resolvable from clusters importing it, through the ClusterSetIP of the cluster.
+func (m *mcsController) convertService(svc *v1.Service, imp *v1alpha1.ServiceImport) *model.Service {
+	c := m.c
+	out := kube.ConvertService(*svc, c.domainSuffix, c.clusterID)
+	out.Hostname = kube.ClusterSetHostname(svc.Name, svc.Namespace)
+	out.Address = constants.UnspecifiedIP
+	out.ClusterVIPs = map[string]string{}
+	// Gateways are exposed through the cluster.local service.
+	out.Attributes.ClusterExternalAddresses = nil
+	out.Attributes.ClusterExternalPorts = nil
+	if imp != nil {
+		if imp.Spec.Type == v1alpha1.Headless {
+			out.Resolution = model.Passthrough
+		} else if len(imp.Spec.IPs) > 0 {
+			out.ClusterVIPs[c.clusterID] = imp.Spec.IPs[0]
+		}
+	}
+	return out
+}
+
+// onEndpoints updates the endpoints of the clusterset.local service of an exported k8s service.
+func (m *mcsController) onEndpoints(name, namespace string, endpoints []*model.IstioEndpoint) {
+	if !m.isExported(name, namespace) {
+		return
+	}
+	m.c.xdsUpdater.EDSUpdate(m.c.clusterID, string(kube.ClusterSetHostname(name, namespace)), namespace, endpoints)
+}
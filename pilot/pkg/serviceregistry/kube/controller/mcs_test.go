@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pkg/config/constants"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestClusterSetLocalServices(t *testing.T) {
+	features.EnableMCSClusterSetLocal = true
+	defer func() {
+		features.EnableMCSClusterSetLocal = false
+	}()
+	client := kubelib.NewFakeClient()
+	controller, fx := NewFakeControllerWithOptions(FakeControllerOptions{Client: client, ClusterID: "cluster-1", Mode: EndpointsOnly})
+	defer controller.Stop()
+	mcs := client.MCSApis().MulticlusterV1alpha1()
+	hostname := kube.ClusterSetHostname("svc1", "nsA")
+
+	createService(controller, "svc1", "nsA", nil, []int32{8080}, map[string]string{"app": "a"}, t)
+	createEndpoints(controller, "svc1", "nsA", []string{"tcp-port"}, []string{"10.1.1.1"}, nil, t)
+	waitForEDS(t, fx, string(kube.ServiceHostname("svc1", "nsA", defaultFakeDomainSuffix)), 1)
+	if svc, _ := controller.GetService(hostname); svc != nil {
+		t.Fatalf("expected no clusterset.local service before export or import, got %v", svc)
+	}
+
+	// The ServiceImport makes the service resolvable through its ClusterSetIP, without exporting endpoints.
+	if _, err := mcs.ServiceImports("nsA").Create(context.TODO(), &v1alpha1.ServiceImport{
+		ObjectMeta: metaV1.ObjectMeta{Name: "svc1", Namespace: "nsA"},
+		Spec: v1alpha1.ServiceImportSpec{
+			Type:  v1alpha1.ClusterSetIP,
+			IPs:   []string{"240.0.0.1"},
+			Ports: []v1alpha1.ServicePort{{Name: "tcp-port", Port: 8080}},
+		},
+	}, metaV1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		svc, _ := controller.GetService(hostname)
+		if svc == nil {
+			return fmt.Errorf("clusterset.local service not found")
+		}
+		if svc.Address != constants.UnspecifiedIP || svc.ClusterVIPs["cluster-1"] != "240.0.0.1" {
+			return fmt.Errorf("unexpected addresses %s %v", svc.Address, svc.ClusterVIPs)
+		}
+		return nil
+	})
+	svc, _ := controller.GetService(hostname)
+	if instances := controller.InstancesByPort(svc, 8080, nil); len(instances) != 0 {
+		t.Fatalf("expected no instances for a service not exported, got %v", instances)
+	}
+
+	// The ServiceExport makes the endpoints of the cluster reachable through the clusterset.local service.
+	if _, err := mcs.ServiceExports("nsA").Create(context.TODO(), &v1alpha1.ServiceExport{
+		ObjectMeta: metaV1.ObjectMeta{Name: "svc1", Namespace: "nsA"},
+	}, metaV1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForEDS(t, fx, string(hostname), 1)
+	if instances := controller.InstancesByPort(svc, 8080, nil); len(instances) != 1 || instances[0].Endpoint.Address != "10.1.1.1" {
+		t.Fatalf("expected the endpoint of the exported service, got %v", instances)
+	}
+	createEndpoints(controller, "svc1", "nsA", []string{"tcp-port"}, []string{"10.1.1.1", "10.1.1.2"}, nil, t)
+	waitForEDS(t, fx, string(hostname), 2)
+
+	// The service stays part of the clusterset as long as it is imported or exported.
+	if err := mcs.ServiceImports("nsA").Delete(context.TODO(), "svc1", metaV1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		svc, _ := controller.GetService(hostname)
+		if svc == nil || len(svc.ClusterVIPs) != 0 {
+			return fmt.Errorf("expected clusterset.local service without ClusterSetIP, got %v", svc)
+		}
+		return nil
+	})
+	if err := mcs.ServiceExports("nsA").Delete(context.TODO(), "svc1", metaV1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if svc, _ := controller.GetService(hostname); svc != nil {
+			return fmt.Errorf("expected clusterset.local service to be removed, got %v", svc)
+		}
+		return nil
+	})
+}
+
+func waitForEDS(t *testing.T, fx *FakeXdsUpdater, hostname string, endpoints int) {
+	t.Helper()
+	for {
+		ev := fx.Wait("eds")
+		if ev == nil {
+			t.Fatalf("timed out waiting for EDS update of %s", hostname)
+		}
+		if ev.ID == hostname && len(ev.Endpoints) == endpoints {
+			return
+		}
+	}
+}
@@ -59,6 +59,17 @@ type kubeController struct {
 	workloadEntryStore *serviceentry.ServiceEntryStore
 }
 
+// ClusterHandler is notified of the clusters added and removed, including the main cluster. A cluster whose
+// remote secret is updated is added again without being deleted: the stop channel of its previous client is
+// closed and ClusterAdded is called with the new one.
+type ClusterHandler interface {
+	// ClusterAdded is called before the informers of the client are started. stop is closed when the cluster
+	// is removed or its remote secret is updated.
+	ClusterAdded(clusterID string, client kubelib.Client, stop <-chan struct{})
+	// ClusterDeleted is only called when the remote secret of the cluster is removed.
+	ClusterDeleted(clusterID string)
+}
+
 // Multicluster structure holds the remote kube Controllers and multicluster specific attributes.
 type Multicluster struct {
 	// serverID of this pilot instance used for leader election
@@ -78,6 +89,7 @@ type Multicluster struct {
 
 	m                     sync.Mutex // protects remoteKubeControllers
 	remoteKubeControllers map[string]*kubeController
+	clusterHandlers       []ClusterHandler
 	networksWatcher       mesh.NetworksWatcher
 	clusterLocal          model.ClusterLocalProvider
 
@@ -135,6 +147,14 @@ func NewMulticluster(
 	return mc
 }
 
+// AddClusterHandler registers a handler notified of the clusters added and removed. It must be called before
+// the member clusters are added.
+func (m *Multicluster) AddClusterHandler(h ClusterHandler) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.clusterHandlers = append(m.clusterHandlers, h)
+}
+
 func (m *Multicluster) Run(stopCh <-chan struct{}) error {
 	// Wait for server shutdown.
 	<-stopCh
@@ -188,9 +208,14 @@ func (m *Multicluster) AddMemberCluster(client kubelib.Client, clusterID string)
 	}
 	// localCluster may also be the "config" cluster, in an external-istiod setup.
 	localCluster := m.opts.ClusterID == clusterID
+	clusterHandlers := m.clusterHandlers
 
 	m.m.Unlock()
 
+	for _, h := range clusterHandlers {
+		h.ClusterAdded(clusterID, client, clusterStopCh)
+	}
+
 	// Only need to add service handler for kubernetes registry as `initRegistryEventHandlers`,
 	// because when endpoints update `XDSUpdater.EDSUpdate` has already been called.
 	kubeRegistry.AppendServiceHandler(func(svc *model.Service, ev model.Event) { m.updateHandler(svc) })
@@ -308,7 +333,7 @@ func (m *Multicluster) AddMemberCluster(client kubelib.Client, clusterID string)
 }
 
 func (m *Multicluster) UpdateMemberCluster(clients kubelib.Client, clusterID string) error {
-	if err := m.DeleteMemberCluster(clusterID); err != nil {
+	if err := m.deleteMemberCluster(clusterID, false); err != nil {
 		return err
 	}
 	return m.AddMemberCluster(clients, clusterID)
@@ -318,6 +343,12 @@ func (m *Multicluster) UpdateMemberCluster(clients kubelib.Client, clusterID str
 // when a remote cluster is deleted.  Also must clear the cache so remote resources
 // are removed.
 func (m *Multicluster) DeleteMemberCluster(clusterID string) error {
+	return m.deleteMemberCluster(clusterID, true)
+}
+
+// deleteMemberCluster stops the controllers of the cluster. The cluster handlers are only notified when the
+// cluster is removed, not when it is deleted to be added again with an updated remote secret.
+func (m *Multicluster) deleteMemberCluster(clusterID string, removed bool) error {
 	m.m.Lock()
 	defer m.m.Unlock()
 	m.serviceController.DeleteRegistry(clusterID, serviceregistry.Kubernetes)
@@ -332,6 +363,11 @@ func (m *Multicluster) DeleteMemberCluster(clusterID string) error {
 	if kc.workloadEntryStore != nil {
 		m.serviceController.DeleteRegistry(clusterID, serviceregistry.External)
 	}
+	if removed {
+		for _, h := range m.clusterHandlers {
+			h.ClusterDeleted(clusterID)
+		}
+	}
 	close(m.remoteKubeControllers[clusterID].stopCh)
 	delete(m.remoteKubeControllers, clusterID)
 	if m.XDSUpdater != nil {
@@ -382,3 +418,11 @@ func (m *Multicluster) InitSecretController(stop <-chan struct{}) {
 func (m *Multicluster) HasSynced() bool {
 	return m.secretController.HasSynced()
 }
+
+// ClusterStatuses returns the status of the remote clusters configured through remote secrets.
+func (m *Multicluster) ClusterStatuses() []secretcontroller.ClusterStatus {
+	if m.secretController == nil {
+		return nil
+	}
+	return m.secretController.ClusterStatuses()
+}
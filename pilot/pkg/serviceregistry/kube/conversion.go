@@ -186,6 +186,17 @@ func ServiceHostname(name, namespace, domainSuffix string) host.Name {
 	return host.Name(name + "." + namespace + "." + "svc" + "." + domainSuffix) // Format: "%s.%s.svc.%s"
 }
 
+// ClusterSetHostname produces the clusterset.local FQDN of a k8s service, under which it is reachable
+// through the Multi-Cluster Services API.
+func ClusterSetHostname(name, namespace string) host.Name {
+	return ServiceHostname(name, namespace, constants.DefaultClusterSetLocalDomain)
+}
+
+// IsClusterSetHostname returns true if the hostname is the clusterset.local FQDN of a k8s service.
+func IsClusterSetHostname(h host.Name) bool {
+	return strings.HasSuffix(string(h), ".svc."+constants.DefaultClusterSetLocalDomain)
+}
+
 // kubeToIstioServiceAccount converts a K8s service account to an Istio service account
 func kubeToIstioServiceAccount(saname string, ns string) string {
 	return spiffe.MustGenSpiffeURI(ns, saname)
@@ -0,0 +1,269 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/log"
+)
+
+const (
+	// IPv4 addresses are allocated from the reserved Class E subnet 240.240.0.0/16. Addresses ending
+	// in .0 and .255 are skipped, which leaves 254 addresses in each of the 256 /24 subnets.
+	autoAllocatedIPv4Slots = 256 * 254
+
+	// IPv6 addresses are allocated from 2001:2::/48, reserved for benchmarking (RFC 5180), with the
+	// 64 bits of the interface identifier taken from the hash of the service.
+	autoAllocatedIPv6Prefix = "2001:2::/48"
+)
+
+var (
+	autoAllocatedIPv4Net       = &net.IPNet{IP: net.IPv4(240, 240, 0, 0).To4(), Mask: net.CIDRMask(16, 32)}
+	_, autoAllocatedIPv6Net, _ = net.ParseCIDR(autoAllocatedIPv6Prefix)
+)
+
+// autoAllocatedAddresses are the addresses allocated to a hostname in a namespace.
+type autoAllocatedAddresses struct {
+	ipv4 string
+	ipv6 string
+}
+
+// autoAllocateIPs automatically allocates IPs for service entry services WITHOUT an
+// address field if the hostname is not a wildcard, or when resolution
+// is not NONE. The IPs are allocated from the reserved Class E subnet
+// (240.240.0.0/16) that is not reachable outside the pod, and from 2001:2::/48
+// for IPv6 only proxies. When DNS capture is enabled, Envoy will resolve the DNS
+// to these IPs. The listeners for TCP services will also be set up on these IPs.
+// The allocation is deterministic: given the same set of services and pinned
+// addresses, all istiods allocate the exact same IPs.
+//
+// NOTE: If DNS capture is not enabled by the proxy, the automatically
+// allocated IP addresses do not take effect.
+//
+// Addresses are derived from the hash of the namespace and hostname of the service, so adding or
+// deleting a service entry does not change the addresses of the others, except for the services
+// whose hash collided with it. Collisions are resolved deterministically by linear probing, in the
+// order of the namespace and hostname of the services. Services of the same hostname in a namespace
+// share the same addresses.
+//
+// Pinned addresses, typically persisted in the status of the service entries, are kept as long as
+// they are valid and not already pinned by another service. They take precedence over hashed
+// addresses, which keeps addresses stable even when a colliding service entry is later created.
+func autoAllocateIPs(services []*model.Service, pinned map[instancesKey]autoAllocatedAddresses) []*model.Service {
+	byKey := map[instancesKey][]*model.Service{}
+	keys := make([]instancesKey, 0, len(services))
+	for _, svc := range services {
+		// we can allocate IPs only if
+		// 1. the service has resolution set to static/dns. We cannot allocate
+		//   for NONE because we will not know the original DST IP that the application requested.
+		// 2. the address is not set (0.0.0.0)
+		// 3. the hostname is not a wildcard
+		if svc.Address != constants.UnspecifiedIP || svc.Hostname.IsWildCarded() || svc.Resolution == model.Passthrough {
+			continue
+		}
+		k := instancesKey{hostname: svc.Hostname, namespace: svc.Attributes.Namespace}
+		if _, f := byKey[k]; !f {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], svc)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		return keys[i].hostname < keys[j].hostname
+	})
+
+	allocated := make(map[instancesKey]autoAllocatedAddresses, len(keys))
+	usedIPv4 := map[uint32]struct{}{}
+	usedIPv6 := map[uint64]struct{}{}
+	// Pinned addresses are reserved first, so that hashed addresses are probed around them.
+	for _, k := range keys {
+		p, f := pinned[k]
+		if !f {
+			continue
+		}
+		var addrs autoAllocatedAddresses
+		if slot, ok := ipv4Slot(p.ipv4); ok {
+			if _, used := usedIPv4[slot]; !used {
+				usedIPv4[slot] = struct{}{}
+				addrs.ipv4 = p.ipv4
+			}
+		}
+		if iid, ok := ipv6InterfaceID(p.ipv6); ok {
+			if _, used := usedIPv6[iid]; !used {
+				usedIPv6[iid] = struct{}{}
+				addrs.ipv6 = p.ipv6
+			}
+		}
+		allocated[k] = addrs
+	}
+
+	for _, k := range keys {
+		addrs := allocated[k]
+		h := autoAllocateHash(k)
+		if addrs.ipv4 == "" {
+			if len(usedIPv4) >= autoAllocatedIPv4Slots {
+				log.Errorf("out of IPs to allocate for service entries")
+			} else {
+				slot := uint32(h % autoAllocatedIPv4Slots)
+				for {
+					if _, used := usedIPv4[slot]; !used {
+						break
+					}
+					slot = (slot + 1) % autoAllocatedIPv4Slots
+				}
+				usedIPv4[slot] = struct{}{}
+				addrs.ipv4 = ipv4FromSlot(slot)
+			}
+		}
+		if addrs.ipv6 == "" {
+			// The IPv6 space is large enough for collisions to be extremely rare; the subnet router
+			// anycast address (all zeros) is never allocated.
+			iid := h
+			for {
+				if _, used := usedIPv6[iid]; !used && iid != 0 {
+					break
+				}
+				iid++
+			}
+			usedIPv6[iid] = struct{}{}
+			addrs.ipv6 = ipv6FromInterfaceID(iid)
+		}
+		for _, svc := range byKey[k] {
+			svc.AutoAllocatedAddress = addrs.ipv4
+			svc.AutoAllocatedIPv6Address = addrs.ipv6
+		}
+	}
+	return services
+}
+
+func autoAllocateHash(k instancesKey) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(k.namespace))
+	_, _ = h.Write([]byte{'/'})
+	_, _ = h.Write([]byte(k.hostname))
+	return h.Sum64()
+}
+
+func ipv4FromSlot(slot uint32) string {
+	return fmt.Sprintf("240.240.%d.%d", slot/254, slot%254+1)
+}
+
+// ipv4Slot returns the slot of an address allocated out of 240.240.0.0/16.
+func ipv4Slot(addr string) (uint32, bool) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil || !autoAllocatedIPv4Net.Contains(ip) || ip[3] == 0 || ip[3] == 255 {
+		return 0, false
+	}
+	return uint32(ip[2])*254 + uint32(ip[3]) - 1, true
+}
+
+func ipv6FromInterfaceID(iid uint64) string {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, autoAllocatedIPv6Net.IP)
+	binary.BigEndian.PutUint64(ip[8:], iid)
+	return ip.String()
+}
+
+// ipv6InterfaceID returns the interface identifier of an address allocated out of 2001:2::/48.
+// Only addresses of the first /64 subnet are ever allocated.
+func ipv6InterfaceID(addr string) (uint64, bool) {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil || !autoAllocatedIPv6Net.Contains(ip) || ip[6] != 0 || ip[7] != 0 {
+		return 0, false
+	}
+	iid := binary.BigEndian.Uint64(ip[8:])
+	return iid, iid != 0
+}
+
+// autoAllocatedAddressesFromStatus returns the addresses persisted in the status of a ServiceEntry.
+func autoAllocatedAddressesFromStatus(cfg config.Config) map[host.Name]autoAllocatedAddresses {
+	cond := status.GetConditionFromSpec(cfg, status.ConditionAutoAllocatedAddresses)
+	if cond == nil {
+		return nil
+	}
+	return parseAutoAllocatedAddresses(cond.Message)
+}
+
+// parseAutoAllocatedAddresses parses addresses in the "<host>=<ipv4>,<ipv6>;<host>=..." form.
+// Malformed entries are ignored, they are reallocated and then persisted again.
+func parseAutoAllocatedAddresses(s string) map[host.Name]autoAllocatedAddresses {
+	out := map[host.Name]autoAllocatedAddresses{}
+	for _, entry := range strings.Split(s, ";") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		addrs := strings.Split(kv[1], ",")
+		a := autoAllocatedAddresses{ipv4: addrs[0]}
+		if len(addrs) > 1 {
+			a.ipv6 = addrs[1]
+		}
+		out[host.Name(kv[0])] = a
+	}
+	return out
+}
+
+func formatAutoAllocatedAddresses(services []*model.Service) string {
+	entries := make([]string, 0, len(services))
+	seen := map[host.Name]struct{}{}
+	for _, svc := range services {
+		if svc.AutoAllocatedAddress == "" && svc.AutoAllocatedIPv6Address == "" {
+			continue
+		}
+		if _, f := seen[svc.Hostname]; f {
+			continue
+		}
+		seen[svc.Hostname] = struct{}{}
+		entries = append(entries, fmt.Sprintf("%s=%s,%s", svc.Hostname, svc.AutoAllocatedAddress, svc.AutoAllocatedIPv6Address))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// autoAllocatedAddressesStatus returns the ServiceEntry with its allocated addresses persisted in its
+// status, or false if the status is already up to date.
+func autoAllocatedAddressesStatus(cfg config.Config, services []*model.Service) (config.Config, bool) {
+	msg := formatAutoAllocatedAddresses(services)
+	cond := status.GetConditionFromSpec(cfg, status.ConditionAutoAllocatedAddresses)
+	if (cond == nil && msg == "") || (cond != nil && cond.Message == msg) {
+		return config.Config{}, false
+	}
+	condStatus := status.StatusTrue
+	if msg == "" {
+		condStatus = status.StatusFalse
+	}
+	return status.UpdateConfigCondition(cfg, &v1alpha1.IstioCondition{
+		Type:               status.ConditionAutoAllocatedAddresses,
+		Status:             condStatus,
+		LastTransitionTime: types.TimestampNow(),
+		Message:            msg,
+	}), true
+}
@@ -0,0 +1,429 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"go.uber.org/atomic"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func Test_autoAllocateIP_conditions(t *testing.T) {
+	tests := []struct {
+		name         string
+		inServices   []*model.Service
+		wantServices []*model.Service
+	}{
+		{
+			name: "no allocation for passthrough",
+			inServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.Passthrough,
+					Address:    "0.0.0.0",
+				},
+			},
+			wantServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.Passthrough,
+					Address:    "0.0.0.0",
+				},
+			},
+		},
+		{
+			name: "no allocation if address exists",
+			inServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.ClientSideLB,
+					Address:    "1.1.1.1",
+				},
+			},
+			wantServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.ClientSideLB,
+					Address:    "1.1.1.1",
+				},
+			},
+		},
+		{
+			name: "no allocation if hostname is wildcard",
+			inServices: []*model.Service{
+				{
+					Hostname:   "*.foo.com",
+					Resolution: model.ClientSideLB,
+					Address:    "1.1.1.1",
+				},
+			},
+			wantServices: []*model.Service{
+				{
+					Hostname:   "*.foo.com",
+					Resolution: model.ClientSideLB,
+					Address:    "1.1.1.1",
+				},
+			},
+		},
+		{
+			name: "allocate IP for clientside lb",
+			inServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.ClientSideLB,
+					Address:    "0.0.0.0",
+				},
+			},
+			wantServices: []*model.Service{
+				{
+					Hostname:                 "foo.com",
+					Resolution:               model.ClientSideLB,
+					Address:                  "0.0.0.0",
+					AutoAllocatedAddress:     "240.240.2.178",
+					AutoAllocatedIPv6Address: "2001:2::4472:45ab:4bf:36ad",
+				},
+			},
+		},
+		{
+			name: "allocate IP for dns lb",
+			inServices: []*model.Service{
+				{
+					Hostname:   "foo.com",
+					Resolution: model.DNSLB,
+					Address:    "0.0.0.0",
+				},
+			},
+			wantServices: []*model.Service{
+				{
+					Hostname:                 "foo.com",
+					Resolution:               model.DNSLB,
+					Address:                  "0.0.0.0",
+					AutoAllocatedAddress:     "240.240.2.178",
+					AutoAllocatedIPv6Address: "2001:2::4472:45ab:4bf:36ad",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autoAllocateIPs(tt.inServices, nil); !reflect.DeepEqual(got, tt.wantServices) {
+				t.Errorf("autoAllocateIPs() = %v, want %v", got, tt.wantServices)
+			}
+		})
+	}
+}
+
+func Test_autoAllocateIP_values(t *testing.T) {
+	inServices := make([]*model.Service, 1024)
+	for i := range inServices {
+		inServices[i] = &model.Service{
+			Hostname:   host.Name(fmt.Sprintf("svc-%d.example.com", i)),
+			Resolution: model.ClientSideLB,
+			Address:    constants.UnspecifiedIP,
+		}
+	}
+	gotServices := autoAllocateIPs(inServices, nil)
+
+	_, ipv4Net, _ := net.ParseCIDR("240.240.0.0/16")
+	_, ipv6Net, _ := net.ParseCIDR("2001:2::/48")
+	gotIPMap := make(map[string]bool)
+	for _, svc := range gotServices {
+		ip := net.ParseIP(svc.AutoAllocatedAddress).To4()
+		if ip == nil || !ipv4Net.Contains(ip) || ip[3] == 0 || ip[3] == 255 {
+			t.Errorf("unexpected value for auto allocated IP address %s", svc.AutoAllocatedAddress)
+		}
+		if ip := net.ParseIP(svc.AutoAllocatedIPv6Address); ip == nil || !ipv6Net.Contains(ip) {
+			t.Errorf("unexpected value for auto allocated IPv6 address %s", svc.AutoAllocatedIPv6Address)
+		}
+		for _, addr := range []string{svc.AutoAllocatedAddress, svc.AutoAllocatedIPv6Address} {
+			if gotIPMap[addr] {
+				t.Errorf("multiple allocations of same IP address to different services: %s", addr)
+			}
+			gotIPMap[addr] = true
+		}
+	}
+}
+
+func Test_autoAllocateIP_stable(t *testing.T) {
+	// svc-1068.example.com and svc-1495.example.com both hash to 240.240.93.13 in the default namespace.
+	type step struct {
+		hosts  []string
+		pinned map[string]string
+		want   map[string]string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "adding services keeps addresses",
+			steps: []step{
+				{
+					hosts: []string{"foo.com", "bar.com"},
+					want:  map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12"},
+				},
+				{
+					hosts: []string{"foo.com", "bar.com", "baz.com"},
+					want:  map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12", "baz.com": "240.240.243.118"},
+				},
+			},
+		},
+		{
+			name: "deleting services keeps addresses",
+			steps: []step{
+				{
+					hosts: []string{"foo.com", "bar.com", "baz.com"},
+					want:  map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12", "baz.com": "240.240.243.118"},
+				},
+				{
+					hosts: []string{"bar.com"},
+					want:  map[string]string{"bar.com": "240.240.34.12"},
+				},
+			},
+		},
+		{
+			name: "order of services does not matter",
+			steps: []step{
+				{
+					hosts: []string{"baz.com", "foo.com", "bar.com"},
+					want:  map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12", "baz.com": "240.240.243.118"},
+				},
+				{
+					hosts: []string{"bar.com", "baz.com", "foo.com"},
+					want:  map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12", "baz.com": "240.240.243.118"},
+				},
+			},
+		},
+		{
+			name: "collisions are resolved in hostname order",
+			steps: []step{
+				{
+					hosts: []string{"svc-1495.example.com", "svc-1068.example.com"},
+					want:  map[string]string{"svc-1068.example.com": "240.240.93.13", "svc-1495.example.com": "240.240.93.14"},
+				},
+				{
+					hosts: []string{"svc-1068.example.com", "foo.com", "svc-1495.example.com"},
+					want: map[string]string{
+						"svc-1068.example.com": "240.240.93.13",
+						"svc-1495.example.com": "240.240.93.14",
+						"foo.com":              "240.240.151.73",
+					},
+				},
+			},
+		},
+		{
+			name: "deleting a colliding service frees its address",
+			steps: []step{
+				{
+					hosts: []string{"svc-1068.example.com", "svc-1495.example.com"},
+					want:  map[string]string{"svc-1068.example.com": "240.240.93.13", "svc-1495.example.com": "240.240.93.14"},
+				},
+				{
+					hosts: []string{"svc-1495.example.com"},
+					want:  map[string]string{"svc-1495.example.com": "240.240.93.13"},
+				},
+			},
+		},
+		{
+			name: "pinned addresses are kept after deletes",
+			steps: []step{
+				{
+					hosts: []string{"svc-1068.example.com", "svc-1495.example.com"},
+					want:  map[string]string{"svc-1068.example.com": "240.240.93.13", "svc-1495.example.com": "240.240.93.14"},
+				},
+				{
+					hosts:  []string{"svc-1495.example.com"},
+					pinned: map[string]string{"svc-1495.example.com": "240.240.93.14"},
+					want:   map[string]string{"svc-1495.example.com": "240.240.93.14"},
+				},
+			},
+		},
+		{
+			name: "pinned addresses take precedence over hashed addresses",
+			steps: []step{
+				{
+					hosts: []string{"svc-1495.example.com"},
+					want:  map[string]string{"svc-1495.example.com": "240.240.93.13"},
+				},
+				{
+					hosts:  []string{"svc-1068.example.com", "svc-1495.example.com"},
+					pinned: map[string]string{"svc-1495.example.com": "240.240.93.13"},
+					want:   map[string]string{"svc-1068.example.com": "240.240.93.14", "svc-1495.example.com": "240.240.93.13"},
+				},
+			},
+		},
+		{
+			name: "invalid pinned addresses are reallocated",
+			steps: []step{
+				{
+					hosts:  []string{"foo.com", "bar.com"},
+					pinned: map[string]string{"foo.com": "10.0.0.1", "bar.com": "240.240.7.255"},
+					want:   map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12"},
+				},
+			},
+		},
+		{
+			name: "conflicting pinned addresses are reallocated",
+			steps: []step{
+				{
+					hosts:  []string{"foo.com", "bar.com"},
+					pinned: map[string]string{"foo.com": "240.240.34.12", "bar.com": "240.240.34.12"},
+					want:   map[string]string{"foo.com": "240.240.151.73", "bar.com": "240.240.34.12"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipv6 := map[string]string{}
+			for i, s := range tt.steps {
+				services := make([]*model.Service, 0, len(s.hosts))
+				for _, h := range s.hosts {
+					services = append(services, &model.Service{
+						Hostname:   host.Name(h),
+						Resolution: model.ClientSideLB,
+						Address:    constants.UnspecifiedIP,
+						Attributes: model.ServiceAttributes{Namespace: "default"},
+					})
+				}
+				pinned := map[instancesKey]autoAllocatedAddresses{}
+				for h, addr := range s.pinned {
+					pinned[instancesKey{hostname: host.Name(h), namespace: "default"}] = autoAllocatedAddresses{ipv4: addr}
+				}
+				got := map[string]string{}
+				for _, svc := range autoAllocateIPs(services, pinned) {
+					got[string(svc.Hostname)] = svc.AutoAllocatedAddress
+					// IPv6 addresses never collide in these tests, so they must not change either.
+					if prev, f := ipv6[string(svc.Hostname)]; f && prev != svc.AutoAllocatedIPv6Address {
+						t.Errorf("step %d: IPv6 address of %s changed from %s to %s", i, svc.Hostname, prev, svc.AutoAllocatedIPv6Address)
+					}
+					ipv6[string(svc.Hostname)] = svc.AutoAllocatedIPv6Address
+				}
+				if !reflect.DeepEqual(got, s.want) {
+					t.Errorf("step %d: got addresses %v, want %v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoAllocatedAddressesRoundTrip(t *testing.T) {
+	services := []*model.Service{
+		{Hostname: "foo.com", AutoAllocatedAddress: "240.240.151.73", AutoAllocatedIPv6Address: "2001:2::517f:c44b:572b:c21a"},
+		{Hostname: "bar.com", AutoAllocatedAddress: "240.240.34.12"},
+		{Hostname: "foo.com", AutoAllocatedAddress: "240.240.151.73", AutoAllocatedIPv6Address: "2001:2::517f:c44b:572b:c21a"},
+		{Hostname: "baz.com", Address: "1.1.1.1"},
+	}
+	msg := formatAutoAllocatedAddresses(services)
+	if want := "bar.com=240.240.34.12,;foo.com=240.240.151.73,2001:2::517f:c44b:572b:c21a"; msg != want {
+		t.Fatalf("got %q, want %q", msg, want)
+	}
+	want := map[host.Name]autoAllocatedAddresses{
+		"foo.com": {ipv4: "240.240.151.73", ipv6: "2001:2::517f:c44b:572b:c21a"},
+		"bar.com": {ipv4: "240.240.34.12"},
+	}
+	if got := parseAutoAllocatedAddresses(msg + ";malformed"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPersistAutoAllocatedAddresses(t *testing.T) {
+	features.PersistAutoAllocatedIPs = true
+	defer func() {
+		features.PersistAutoAllocatedIPs = false
+	}()
+	store, registry, _, cleanup := initServiceDiscovery()
+	defer cleanup()
+	updates := &statusCountingStore{IstioConfigStore: store}
+	registry.store = updates
+	createConfigs([]*config.Config{tcpDNS}, store, t)
+
+	svcs, err := registry.Services()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 1 || svcs[0].AutoAllocatedAddress == "" {
+		t.Fatalf("expected one service with an auto allocated address, got %v", svcs)
+	}
+	want := fmt.Sprintf("tcpdns.com=%s,%s", svcs[0].AutoAllocatedAddress, svcs[0].AutoAllocatedIPv6Address)
+	cfg := store.Get(gvk.ServiceEntry, tcpDNS.Name, tcpDNS.Namespace)
+	if cond := status.GetConditionFromSpec(*cfg, status.ConditionAutoAllocatedAddresses); cond != nil {
+		t.Fatalf("expected no condition written before becoming the leader, got %v", cond)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go registry.PersistAutoAllocatedAddresses(stop)
+	retry.UntilSuccessOrFail(t, func() error {
+		cfg = store.Get(gvk.ServiceEntry, tcpDNS.Name, tcpDNS.Namespace)
+		if cond := status.GetConditionFromSpec(*cfg, status.ConditionAutoAllocatedAddresses); cond == nil || cond.Message != want {
+			return fmt.Errorf("got condition %v, want message %q", cond, want)
+		}
+		return nil
+	})
+	// The status is unchanged: refreshing the indexes again does not write it.
+	registry.refreshIndexes.Store(true)
+	if _, err := registry.Services(); err != nil {
+		t.Fatal(err)
+	}
+	if got := updates.count.Load(); got != 1 {
+		t.Fatalf("expected the status to be written once, got %d writes", got)
+	}
+
+	// Another istiod, or istiod after a restart, keeps the persisted addresses rather than the hashed ones.
+	pinned := status.UpdateConfigCondition(*cfg, &v1alpha1.IstioCondition{
+		Type:    status.ConditionAutoAllocatedAddresses,
+		Status:  status.StatusTrue,
+		Message: "tcpdns.com=240.240.1.1,2001:2::1",
+	})
+	if _, err := store.UpdateStatus(pinned); err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewServiceDiscovery(nil, store, &FakeXdsUpdater{Events: make(chan Event, 100)})
+	retry.UntilSuccessOrFail(t, func() error {
+		svcs, err := restarted.Services()
+		if err != nil {
+			return err
+		}
+		if len(svcs) != 1 || svcs[0].AutoAllocatedAddress != "240.240.1.1" || svcs[0].AutoAllocatedIPv6Address != "2001:2::1" {
+			return fmt.Errorf("expected persisted addresses, got %v", svcs)
+		}
+		return nil
+	})
+}
+
+// statusCountingStore counts the status updates of the ServiceEntries.
+type statusCountingStore struct {
+	model.IstioConfigStore
+	count atomic.Int32
+}
+
+func (s *statusCountingStore) UpdateStatus(cfg config.Config) (string, error) {
+	s.count.Inc()
+	return s.IstioConfigStore.UpdateStatus(cfg)
+}
@@ -15,7 +15,6 @@
 package serviceentry
 
 import (
-	"fmt"
 	"reflect"
 	"strconv"
 	"sync"
@@ -83,6 +82,8 @@ type ServiceEntryStore struct { // nolint:golint
 	services         []*model.Service
 	refreshIndexes   *atomic.Bool
 	workloadHandlers []func(*model.WorkloadInstance, model.Event)
+	// writeStatus is true while this istiod is the leader persisting the auto allocated addresses.
+	writeStatus *atomic.Bool
 
 	processServiceEntry bool
 }
@@ -110,6 +111,7 @@ func NewServiceDiscovery(
 		workloadInstancesByIP:      map[string]*model.WorkloadInstance{},
 		workloadInstancesIPsByName: map[string]string{},
 		refreshIndexes:             atomic.NewBool(true),
+		writeStatus:                atomic.NewBool(false),
 		processServiceEntry:        true,
 	}
 	for _, o := range options {
@@ -489,7 +491,7 @@ func (s *ServiceEntryStore) Services() ([]*model.Service, error) {
 	s.maybeRefreshIndexes()
 	s.storeMutex.RLock()
 	defer s.storeMutex.RUnlock()
-	return autoAllocateIPs(s.services), nil
+	return s.services, nil
 }
 
 // GetService retrieves a service by host name if it exists.
@@ -632,6 +634,11 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 	// otherwise, what may happen is both the refresh thread and workload entry/pod handler both generate their own
 	// view of s.instances and then write them, leading to inconsistent state. This lock ensures that both threads do
 	// a full R+W before the other can start, rather than R,R,W,W.
+	var statusUpdates []config.Config
+	// Status updates are written once the lock is released, as they trigger ServiceEntry events.
+	defer func() {
+		s.persistAutoAllocatedAddresses(statusUpdates)
+	}()
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
 
@@ -649,6 +656,8 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 	// First refresh service entry
 	seWithSelectorByNamespace := map[string][]servicesWithEntry{}
 	allServices := []*model.Service{}
+	pinnedAddresses := map[instancesKey]autoAllocatedAddresses{}
+	var servicesByEntry []configWithServices
 	if s.processServiceEntry {
 		for _, cfg := range s.store.ServiceEntries() {
 			key := configKey{
@@ -665,6 +674,24 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 				seWithSelectorByNamespace[cfg.Namespace] = append(seWithSelectorByNamespace[cfg.Namespace], servicesWithEntry{se, services})
 			}
 			allServices = append(allServices, services...)
+			if features.PersistAutoAllocatedIPs {
+				// All the replicas keep the persisted addresses, only the leader writes them.
+				for hostname, addrs := range autoAllocatedAddressesFromStatus(cfg) {
+					k := instancesKey{hostname: hostname, namespace: cfg.Namespace}
+					if _, f := pinnedAddresses[k]; !f {
+						pinnedAddresses[k] = addrs
+					}
+				}
+				servicesByEntry = append(servicesByEntry, configWithServices{cfg, services})
+			}
+		}
+		autoAllocateIPs(allServices, pinnedAddresses)
+		if s.writeStatus.Load() {
+			for _, se := range servicesByEntry {
+				if cfg, changed := autoAllocatedAddressesStatus(se.cfg, se.services); changed {
+					statusUpdates = append(statusUpdates, cfg)
+				}
+			}
 		}
 	}
 
@@ -722,6 +749,33 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 	s.ip2instance = ip2instances
 }
 
+type configWithServices struct {
+	cfg      config.Config
+	services []*model.Service
+}
+
+// PersistAutoAllocatedAddresses records the automatically allocated addresses in the status of the
+// ServiceEntries until stop is closed. It must only run on the leader istiod, so that the replicas do not
+// all write the status on every refresh.
+func (s *ServiceEntryStore) PersistAutoAllocatedAddresses(stop <-chan struct{}) {
+	s.writeStatus.Store(true)
+	// Record the addresses allocated before this istiod became the leader.
+	s.refreshIndexes.Store(true)
+	s.maybeRefreshIndexes()
+	<-stop
+	s.writeStatus.Store(false)
+}
+
+// persistAutoAllocatedAddresses records the automatically allocated addresses in the status of the
+// ServiceEntries, so that they are kept across changes to other ServiceEntries and istiod restarts.
+func (s *ServiceEntryStore) persistAutoAllocatedAddresses(updates []config.Config) {
+	for _, cfg := range updates {
+		if _, err := s.store.UpdateStatus(cfg); err != nil {
+			log.Warnf("failed to persist auto allocated addresses of ServiceEntry %s/%s: %v", cfg.Namespace, cfg.Name, err)
+		}
+	}
+}
+
 func (s *ServiceEntryStore) deleteExistingInstances(ckey configKey, instances []*model.ServiceInstance) {
 	s.storeMutex.Lock()
 	defer s.storeMutex.Unlock()
@@ -858,66 +912,6 @@ func selectorChanged(old, curr config.Config) bool {
 	return !reflect.DeepEqual(o.WorkloadSelector, n.WorkloadSelector)
 }
 
-// Automatically allocates IPs for service entry services WITHOUT an
-// address field if the hostname is not a wildcard, or when resolution
-// is not NONE. The IPs are allocated from the reserved Class E subnet
-// (240.240.0.0/16) that is not reachable outside the pod. When DNS
-// capture is enabled, Envoy will resolve the DNS to these IPs. The
-// listeners for TCP services will also be set up on these IPs. The
-// IPs allocated to a service entry may differ from istiod to istiod
-// but it does not matter because these IPs only affect the listener
-// IPs on a given proxy managed by a given istiod.
-//
-// NOTE: If DNS capture is not enabled by the proxy, the automatically
-// allocated IP addresses do not take effect.
-//
-// The current algorithm to allocate IPs is deterministic across all istiods.
-// At stable state, given two istiods with exact same set of services, there should
-// be no change in XDS as the algorithm is just a dumb iterative one that allocates sequentially.
-//
-// TODO: Rather than sequentially allocate IPs, switch to a hash based allocation mechanism so that
-// deletion of the oldest service entry does not cause change of IPs for all other service entries.
-// Currently, the sequential allocation will result in unnecessary XDS reloads (lds/rds) when a
-// service entry with auto allocated IP is deleted. We are trading off a perf problem (xds reload)
-// for a usability problem (e.g., multiple cloud SQL or AWS RDS tcp services with no VIPs end up having
-// the same port, causing traffic to go to the wrong place). Once we move to a deterministic hash-based
-// allocation with deterministic collision resolution, the perf problem will go away. If the collision guarantee
-// cannot be made within the IP address space we have (which is about 64K services), then we may need to
-// have the sequential allocation algorithm as a fallback when too many collisions take place.
-func autoAllocateIPs(services []*model.Service) []*model.Service {
-	// i is everything from 240.240.0.(j) to 240.240.255.(j)
-	// j is everything from 240.240.(i).1 to 240.240.(i).254
-	// we can capture this in one integer variable.
-	// given X, we can compute i by X/255, and j is X%255
-	// To avoid allocating 240.240.(i).255, if X % 255 is 0, increment X.
-	// For example, when X=510, the resulting IP would be 240.240.2.0 (invalid)
-	// So we bump X to 511, so that the resulting IP is 240.240.2.1
-	maxIPs := 255 * 255 // are we going to exceeed this limit by processing 64K services?
-	x := 0
-	for _, svc := range services {
-		// we can allocate IPs only if
-		// 1. the service has resolution set to static/dns. We cannot allocate
-		//   for NONE because we will not know the original DST IP that the application requested.
-		// 2. the address is not set (0.0.0.0)
-		// 3. the hostname is not a wildcard
-		if svc.Address == constants.UnspecifiedIP && !svc.Hostname.IsWildCarded() &&
-			svc.Resolution != model.Passthrough {
-			x++
-			if x%255 == 0 {
-				x++
-			}
-			if x >= maxIPs {
-				log.Errorf("out of IPs to allocate for service entries")
-				return services
-			}
-			thirdOctet := x / 255
-			fourthOctet := x % 255
-			svc.AutoAllocatedAddress = fmt.Sprintf("240.240.%d.%d", thirdOctet, fourthOctet)
-		}
-	}
-	return services
-}
-
 func makeConfigKey(svc *model.Service) model.ConfigKey {
 	return model.ConfigKey{
 		Kind:      gvk.ServiceEntry,
@@ -31,8 +31,10 @@ import (
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/connectionpool"
 	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/env"
 	istiolog "istio.io/pkg/log"
@@ -633,6 +635,7 @@ func (s *DiscoveryServer) setProxyState(proxy *model.Proxy, push *model.PushCont
 	// applicable to this proxy
 	proxy.SetSidecarScope(push)
 	proxy.SetGatewaysForProxy(push)
+	proxy.SetLocalRateLimit(push)
 }
 
 // pre-process request. returns whether or not to continue.
@@ -650,6 +653,30 @@ func (s *DiscoveryServer) preProcessRequest(proxy *model.Proxy, req *discovery.D
 		}
 		return false
 	}
+	if req.TypeUrl == v3.ConnectionPoolUsageType {
+		if s.ConnectionPoolTuner != nil {
+			names := req.ResourceNames
+			if len(names) > connectionpool.MaxReports {
+				log.Warnf("ADS: ignoring the connection pool usage of %d clusters over %d from %s",
+					len(names)-connectionpool.MaxReports, connectionpool.MaxReports, proxy.ID)
+				names = names[:connectionpool.MaxReports]
+			}
+			reports, err := connectionpool.DecodeReports(names)
+			if err != nil {
+				log.Warnf("ADS: invalid connection pool usage from %s: %v", proxy.ID, err)
+				return false
+			}
+			// The reports are scoped to the services of the proxy, unknown until its state is computed.
+			if proxy.SidecarScope == nil {
+				return false
+			}
+			push := s.globalPushContext()
+			s.ConnectionPoolTuner.Record(proxy.ID, connectionpool.ScopeReports(reports, func(hostname host.Name) *model.Service {
+				return push.ServiceForHostname(proxy, hostname)
+			}))
+		}
+		return false
+	}
 	return true
 }
 
@@ -26,11 +26,15 @@ import (
 
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdslistener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/protobuf/types/known/anypb"
 
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -38,9 +42,13 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/kube/secretcontroller"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/ledger"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -179,11 +187,18 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 	s.addDebugHandler(mux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, "/debug/configz", "Debug support for config", s.configz)
 	s.addDebugHandler(mux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
+	s.addDebugHandler(mux, "/debug/filterchainz", "HTTP filters of each listener of a proxy, with their phase and origin", s.filterchainz)
 	s.addDebugHandler(mux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 
 	s.addDebugHandler(mux, "/debug/authorizationz", "Internal authorization policies", s.Authorizationz)
 	s.addDebugHandler(mux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
+	s.addDebugHandler(mux, "/debug/jwksz", "Cached JWKS, their expiry and last fetch errors per issuer", s.jwksz)
+	s.addDebugHandler(mux, "/debug/clusterz", "Health of the remote clusters configured through remote secrets", s.clusterz)
+	s.addDebugHandler(mux, "/debug/connection_pool_tuning", "Connection pool limits tuned from the traffic reported by proxies, "+
+		"and their history", s.connectionPoolTuning)
+	s.addDebugHandler(mux, "/debug/carotationz", "State of the rotation of the plugged-in CA certificates", s.carotationz)
+	s.addDebugHandler(mux, "/debug/issuedcertz", "Unexpired certificates issued by the CA, filtered by san or serial", s.issuedcertz)
 	s.addDebugHandler(mux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
 	s.addDebugHandler(mux, "/debug/push_status", "Last PushContext Details", s.PushStatusHandler)
 	s.addDebugHandler(mux, "/debug/pushcontext", "Debug support for current push context", s.PushContextHandler)
@@ -441,6 +456,115 @@ func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(by)
 }
 
+// FilterChainDebug lists the HTTP filters of a filter chain of a listener, in the order they run.
+type FilterChainDebug struct {
+	Listener string `json:"listener"`
+	// FilterChain is the name of the filter chain, or its index if it has none. It is empty for the default
+	// filter chain.
+	FilterChain string            `json:"filter_chain,omitempty"`
+	HTTPFilters []HTTPFilterDebug `json:"http_filters"`
+}
+
+// HTTPFilterDebug explains the position of an HTTP filter in a filter chain.
+type HTTPFilterDebug struct {
+	Name string `json:"name"`
+	// Phase is the phase of the filter, empty if it has none and keeps the position it was inserted at.
+	Phase string `json:"phase,omitempty"`
+	// Priority is the priority of the EnvoyFilter that inserted the filter in its phase.
+	Priority int32 `json:"priority,omitempty"`
+	// Source is istio for generated filters, or the EnvoyFilter that added the filter.
+	Source string `json:"source"`
+}
+
+// filterchainz explains the HTTP filter chains generated for a proxy.
+func (s *DiscoveryServer) filterchainz(w http.ResponseWriter, req *http.Request) {
+	proxyID := req.URL.Query().Get("proxyID")
+	if proxyID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a proxyID in the query string"))
+		return
+	}
+	con := s.getProxyConnection(proxyID)
+	if con == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Proxy not connected to this Pilot instance"))
+		return
+	}
+
+	push := s.globalPushContext()
+	efw := push.EnvoyFilters(con.proxy)
+	out := []FilterChainDebug{}
+	for _, l := range s.ConfigGenerator.BuildListeners(con.proxy, push) {
+		for i, fc := range l.FilterChains {
+			name := fc.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			if filters := httpFiltersDebug(fc, efw); filters != nil {
+				out = append(out, FilterChainDebug{Listener: l.Name, FilterChain: name, HTTPFilters: filters})
+			}
+		}
+		if l.DefaultFilterChain != nil {
+			if filters := httpFiltersDebug(l.DefaultFilterChain, efw); filters != nil {
+				out = append(out, FilterChainDebug{Listener: l.Name, HTTPFilters: filters})
+			}
+		}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal filter chains: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+// httpFiltersDebug returns the HTTP filters of the http connection manager of a filter chain, or nil if it
+// has none.
+func httpFiltersDebug(fc *xdslistener.FilterChain, efw *model.EnvoyFilterWrapper) []HTTPFilterDebug {
+	for _, f := range fc.Filters {
+		if f.Name != wellknown.HTTPConnectionManager || f.GetTypedConfig() == nil {
+			continue
+		}
+		h := &hcm.HttpConnectionManager{}
+		if err := f.GetTypedConfig().UnmarshalTo(h); err != nil {
+			return nil
+		}
+		out := make([]HTTPFilterDebug, 0, len(h.HttpFilters))
+		for _, hf := range h.HttpFilters {
+			out = append(out, httpFilterDebug(hf.Name, efw))
+		}
+		return out
+	}
+	return nil
+}
+
+// httpFilterDebug attributes a filter to the first EnvoyFilter adding a filter of the same name, as the
+// generated config does not record where filters come from.
+func httpFilterDebug(name string, efw *model.EnvoyFilterWrapper) HTTPFilterDebug {
+	d := HTTPFilterDebug{Name: name, Phase: xdsfilters.PhaseOf(name).String(), Source: "istio"}
+	if efw == nil {
+		return d
+	}
+	for _, cp := range efw.Patches[networking.EnvoyFilter_HTTP_FILTER] {
+		if cp.Operation == networking.EnvoyFilter_Patch_REMOVE || cp.Operation == networking.EnvoyFilter_Patch_MERGE {
+			continue
+		}
+		if v, ok := cp.Value.(*hcm.HttpFilter); !ok || v.Name != name {
+			continue
+		}
+		d.Source = fmt.Sprintf("EnvoyFilter %s/%s", cp.Namespace, cp.Name)
+		if cp.FilterClass != networking.EnvoyFilter_Patch_UNSPECIFIED {
+			d.Phase = xdsfilters.PhaseOfClass(cp.FilterClass).String()
+			d.Priority = cp.Priority
+		}
+		break
+	}
+	return d
+}
+
 // Resource debugging.
 func (s *DiscoveryServer) resourcez(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
@@ -471,6 +595,121 @@ func (s *DiscoveryServer) Authorizationz(w http.ResponseWriter, req *http.Reques
 	}
 }
 
+// jwksz dumps the JWKS cached by the JWT key resolver.
+func (s *DiscoveryServer) jwksz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	entries := []model.JwksDebugEntry{}
+	if s.JwtKeyResolver != nil {
+		entries = s.JwtKeyResolver.Debug()
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal JWKS: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func (s *DiscoveryServer) clusterz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	statuses := []secretcontroller.ClusterStatus{}
+	if s.ClusterStatuses != nil {
+		statuses = append(statuses, s.ClusterStatuses()...)
+	}
+	b, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal cluster status: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func (s *DiscoveryServer) connectionPoolTuning(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if s.ConnectionPoolTuner == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("The adaptive connection pool is disabled, set PILOT_ADAPTIVE_CONNECTION_POOL to enable it"))
+		return
+	}
+	b, err := json.MarshalIndent(s.ConnectionPoolTuner.Debug(), "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal connection pool tuning: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func (s *DiscoveryServer) carotationz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	var status *ca.RotationStatus
+	if s.CARotationStatus != nil {
+		status = s.CARotationStatus()
+	}
+	if status == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("The CA does not rotate plugged-in certificates"))
+		return
+	}
+	b, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal CA rotation status: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+// IssuedCertsDebug lists the unexpired certificates issued by the Istio CA, and the revocations.
+type IssuedCertsDebug struct {
+	Revocations  ledger.Revocations `json:"revocations"`
+	Certificates []IssuedCertDebug  `json:"certificates"`
+}
+
+// IssuedCertDebug is a certificate issued by the Istio CA.
+type IssuedCertDebug struct {
+	*ledger.Entry
+	Revoked bool `json:"revoked"`
+}
+
+func (s *DiscoveryServer) issuedcertz(w http.ResponseWriter, req *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	if s.CALedger == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("The CA does not record the issued certificates"))
+		return
+	}
+	entries, err := s.CALedger.List(ledger.Filter{
+		SAN:     req.URL.Query().Get("san"),
+		Serial:  req.URL.Query().Get("serial"),
+		ValidAt: time.Now(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to list the issued certificates: %v", err)
+		return
+	}
+	out := IssuedCertsDebug{Certificates: make([]IssuedCertDebug, 0, len(entries))}
+	if s.CARevoker != nil {
+		out.Revocations = s.CARevoker.Revocations()
+	}
+	for _, e := range entries {
+		out.Certificates = append(out.Certificates, IssuedCertDebug{
+			Entry:   e,
+			Revoked: s.CARevoker != nil && s.CARevoker.IsRevoked(e),
+		})
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal the issued certificates: %v", err)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
 func (s *DiscoveryServer) telemetryz(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 	t := s.globalPushContext().Telemetry
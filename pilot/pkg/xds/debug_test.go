@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
@@ -27,6 +28,8 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/ledger"
 	"istio.io/istio/tests/util/leak"
 )
 
@@ -217,3 +220,139 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestFilterchainz(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: stats
+  namespace: default
+  annotations:
+    networking.istio.io/filterPriority: "5"
+spec:
+  configPatches:
+  - applyTo: HTTP_FILTER
+    match:
+      context: SIDECAR_OUTBOUND
+    patch:
+      operation: INSERT_FIRST
+      filterClass: STATS
+      value:
+        name: custom.stats
+`})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(&discovery.DiscoveryRequest{TypeUrl: v3.ListenerType})
+
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, false, nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/filterchainz?proxyID=test.default", nil))
+	if rr.Code != 200 {
+		t.Fatalf("wanted response code 200, got %v: %s", rr.Code, rr.Body.String())
+	}
+	var chains []xds.FilterChainDebug
+	if err := json.Unmarshal(rr.Body.Bytes(), &chains); err != nil {
+		t.Fatal(err)
+	}
+	for _, fc := range chains {
+		if fc.Listener != "0.0.0.0_80" {
+			continue
+		}
+		filters := fc.HTTPFilters
+		if len(filters) < 2 {
+			t.Fatalf("got filters %+v, want the custom stats filter and the router", filters)
+		}
+		want := xds.HTTPFilterDebug{Name: "custom.stats", Phase: "STATS", Priority: 5, Source: "EnvoyFilter default/stats"}
+		if got := filters[len(filters)-2]; got != want {
+			t.Errorf("got filter %+v before the router, want %+v", got, want)
+		}
+		if got := filters[len(filters)-1]; got.Phase != "ROUTER" || got.Source != "istio" {
+			t.Errorf("got last filter %+v, want the router", got)
+		}
+		return
+	}
+	t.Fatalf("no filter chain for listener 0.0.0.0_80 in %+v", chains)
+}
+
+func TestCARotationz(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, false, nil)
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/carotationz", nil))
+		return rr
+	}
+
+	if rr := get(); rr.Code != http.StatusNotFound {
+		t.Errorf("wanted response code 404 without rotation, got %v", rr.Code)
+	}
+
+	s.Discovery.CARotationStatus = func() *ca.RotationStatus {
+		return &ca.RotationStatus{Phase: ca.RotationDistributingTrust.String()}
+	}
+	rr := get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wanted response code 200, got %v", rr.Code)
+	}
+	got := &ca.RotationStatus{}
+	if err := json.Unmarshal(rr.Body.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Phase != "DistributingTrust" {
+		t.Errorf("got phase %q, want DistributingTrust", got.Phase)
+	}
+}
+
+func TestIssuedcertz(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, false, nil)
+	get := func(url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		return rr
+	}
+
+	if rr := get("/debug/issuedcertz"); rr.Code != http.StatusNotFound {
+		t.Errorf("wanted response code 404 without ledger, got %v", rr.Code)
+	}
+
+	store := ledger.NewMemoryStore()
+	now := time.Now()
+	for _, e := range []*ledger.Entry{
+		{Serial: "a1", SANs: []string{"spiffe://cluster.local/ns/a/sa/a"}, IssuedAt: now, NotAfter: now.Add(time.Hour)},
+		{Serial: "b2", SANs: []string{"spiffe://cluster.local/ns/b/sa/b"}, IssuedAt: now, NotAfter: now.Add(time.Hour)},
+		{Serial: "a3", SANs: []string{"spiffe://cluster.local/ns/a/sa/a"}, IssuedAt: now, NotAfter: now.Add(-time.Hour)},
+	} {
+		_ = store.Add(e)
+	}
+	s.Discovery.CALedger = store
+
+	rr := get("/debug/issuedcertz?san=spiffe://cluster.local/ns/a/sa/a")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wanted response code 200, got %v", rr.Code)
+	}
+	got := xds.IssuedCertsDebug{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Certificates) != 1 || got.Certificates[0].Serial != "a1" || got.Certificates[0].Revoked {
+		t.Errorf("got certificates %+v, want the unexpired certificate of the SAN", got.Certificates)
+	}
+}
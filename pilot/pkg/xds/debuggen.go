@@ -33,9 +33,10 @@ const (
 )
 
 var activeNamespaceDebuggers = map[string]struct{}{
-	"config_dump": {},
-	"ndsz":        {},
-	"edsz":        {},
+	"config_dump":  {},
+	"ndsz":         {},
+	"edsz":         {},
+	"filterchainz": {},
 }
 
 // DebugGen is a Generator for istio debug info
@@ -28,6 +28,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/apigen"
+	"istio.io/istio/pilot/pkg/networking/connectionpool"
 	"istio.io/istio/pilot/pkg/networking/core"
 	"istio.io/istio/pilot/pkg/networking/grpcgen"
 	"istio.io/istio/pilot/pkg/serviceregistry"
@@ -35,7 +36,11 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/util/sets"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube/secretcontroller"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/ledger"
 )
 
 var (
@@ -143,6 +148,23 @@ type DiscoveryServer struct {
 
 	// JwtKeyResolver holds a reference to the JWT key resolver instance.
 	JwtKeyResolver *model.JwksResolver
+
+	// ClusterStatuses returns the health of the remote clusters, if multicluster is enabled.
+	ClusterStatuses func() []secretcontroller.ClusterStatus
+
+	// CARotationStatus returns the state of the rotation of the plugged-in CA certificates, or nil if
+	// they are not rotated.
+	CARotationStatus func() *ca.RotationStatus
+
+	// CALedger records the certificates issued by the Istio CA, if it runs in istiod.
+	CALedger ledger.Store
+
+	// CARevoker publishes the certificates revoked by the Istio CA to proxies, if it runs in istiod.
+	CARevoker *ledger.Revoker
+
+	// ConnectionPoolTuner tunes the connection pool limits from the usage reported by the proxies, if the
+	// adaptive connection pool is enabled.
+	ConnectionPoolTuner *connectionpool.Tuner
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -189,6 +211,7 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 	}
 
 	out.initJwksResolver()
+	out.initConnectionPoolTuner()
 
 	out.initGenerators(env, systemNameSpace)
 
@@ -209,6 +232,11 @@ func (s *DiscoveryServer) initJwksResolver() {
 	s.JwtKeyResolver = model.NewJwksResolver(
 		model.JwtPubKeyEvictionDuration, model.JwtPubKeyRefreshInterval,
 		model.JwtPubKeyRefreshIntervalOnFailure, model.JwtPubKeyRetryInterval)
+	if features.JwksFetchPolicyFile != "" {
+		if err := s.loadJwksFetchPolicies(); err != nil {
+			log.Errorf("failed to apply JWKS fetch policies, falling back to default fetch: %v", err)
+		}
+	}
 
 	// Flush cached discovery responses when detecting jwt public key change.
 	s.JwtKeyResolver.PushFunc = func() {
@@ -216,6 +244,72 @@ func (s *DiscoveryServer) initJwksResolver() {
 	}
 }
 
+// loadJwksFetchPolicies applies the per-issuer JWKS fetch policies of PILOT_JWKS_FETCH_POLICY_FILE.
+func (s *DiscoveryServer) loadJwksFetchPolicies() error {
+	policies, err := model.LoadJwksFetchPolicies(features.JwksFetchPolicyFile)
+	if err != nil {
+		return err
+	}
+	return s.JwtKeyResolver.SetFetchPolicies(policies)
+}
+
+// ReloadJwksFetchPolicies reloads the per-issuer JWKS fetch policies after PILOT_JWKS_FETCH_POLICY_FILE changed,
+// and pushes the configuration of the issuers fetched by Envoy. The previous policies are kept if the file is
+// invalid.
+func (s *DiscoveryServer) ReloadJwksFetchPolicies() {
+	if features.JwksFetchPolicyFile == "" || s.JwtKeyResolver == nil {
+		return
+	}
+	if err := s.loadJwksFetchPolicies(); err != nil {
+		log.Errorf("failed to reload JWKS fetch policies, keeping the previous policies: %v", err)
+		return
+	}
+	log.Infof("reloaded JWKS fetch policies from %s", features.JwksFetchPolicyFile)
+	s.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.GlobalUpdate}})
+}
+
+// initConnectionPoolTuner initializes the connection pool tuner, if the adaptive connection pool is enabled.
+func (s *DiscoveryServer) initConnectionPoolTuner() {
+	mode, err := connectionpool.ParseMode(features.AdaptiveConnectionPool)
+	if err != nil {
+		log.Errorf("adaptive connection pool disabled: %v", err)
+		return
+	}
+	if mode == connectionpool.Off {
+		return
+	}
+	s.ConnectionPoolTuner = connectionpool.NewTuner(connectionpool.Options{
+		Mode:       mode,
+		Min:        uint32(features.AdaptiveConnectionPoolMin),
+		Max:        uint32(features.AdaptiveConnectionPoolMax),
+		Headroom:   features.AdaptiveConnectionPoolHeadroom,
+		Hysteresis: features.AdaptiveConnectionPoolHysteresis,
+		Cooldown:   features.AdaptiveConnectionPoolCooldown,
+		Window:     features.AdaptiveConnectionPoolWindow,
+	}, s.connectionPoolLimitsChanged)
+}
+
+// connectionPoolLimitsChanged pushes the clusters whose tuned connection pool limits changed to the proxies
+// depending on their services.
+func (s *DiscoveryServer) connectionPoolLimitsChanged(clusters []string) {
+	push := s.globalPushContext()
+	configsUpdated := map[model.ConfigKey]struct{}{}
+	for _, name := range clusters {
+		_, _, hostname, _ := model.ParseSubsetKey(name)
+		for namespace := range push.ServiceIndex.HostnameAndNamespace[hostname] {
+			configsUpdated[model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(hostname), Namespace: namespace}] = struct{}{}
+		}
+	}
+	if len(configsUpdated) == 0 {
+		return
+	}
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: configsUpdated,
+		Reason:         []model.TriggerReason{model.ConnectionPoolUpdate},
+	})
+}
+
 // closeJwksResolver shuts down the JWT key resolver used.
 func (s *DiscoveryServer) closeJwksResolver() {
 	if s.JwtKeyResolver != nil {
@@ -494,6 +588,9 @@ func (s *DiscoveryServer) initPushContext(req *model.PushRequest, oldPushContext
 	push := model.NewPushContext()
 	push.PushVersion = version
 	push.JwtKeyResolver = s.JwtKeyResolver
+	if s.ConnectionPoolTuner != nil {
+		push.ConnectionPoolLimits = s.ConnectionPoolTuner.Limits()
+	}
 	if err := push.InitContext(s.Env, oldPushContext, req); err != nil {
 		log.Errorf("XDS: Failed to update services: %v", err)
 		// We can't push if we can't read the data - stick with previous version.
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	networking "istio.io/api/networking/v1alpha3"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/config/xds"
+)
+
+// Phase is the stage of request processing an HTTP filter belongs to. Phases are ordered: the filters
+// of a phase run before the filters of the following phases.
+type Phase int
+
+const (
+	// PhaseUnspecified is the phase of filters with no known stage, they keep their position.
+	PhaseUnspecified Phase = iota
+	// PhaseAuthn filters authenticate the request.
+	PhaseAuthn
+	// PhaseAuthz filters authorize the request.
+	PhaseAuthz
+	// PhaseStats filters record telemetry.
+	PhaseStats
+	// PhaseRouter is the terminal router filter.
+	PhaseRouter
+)
+
+// StatsFilterName is the name of the stats filter installed by the telemetry EnvoyFilters.
+const StatsFilterName = "istio.stats"
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseAuthn:
+		return "AUTHN"
+	case PhaseAuthz:
+		return "AUTHZ"
+	case PhaseStats:
+		return "STATS"
+	case PhaseRouter:
+		return "ROUTER"
+	}
+	return ""
+}
+
+// filterPhases is the phase of the well known HTTP filters.
+var filterPhases = map[string]Phase{
+	authn_model.EnvoyJwtFilterName:       PhaseAuthn,
+	authn_model.AuthnFilterName:          PhaseAuthn,
+	wellknown.HTTPExternalAuthorization:  PhaseAuthz,
+	wellknown.HTTPRoleBasedAccessControl: PhaseAuthz,
+	MxFilterName:                         PhaseStats,
+	StatsFilterName:                      PhaseStats,
+	wellknown.HTTPGRPCStats:              PhaseStats,
+	wellknown.Router:                     PhaseRouter,
+}
+
+// PhaseOf returns the phase of an HTTP filter from its name, which may be a deprecated name.
+func PhaseOf(name string) Phase {
+	if nn, f := xds.ReverseDeprecatedFilterNames[name]; f {
+		name = nn
+	}
+	return filterPhases[name]
+}
+
+// PhaseOfClass returns the phase of the filters inserted by EnvoyFilter patches of a filter class.
+func PhaseOfClass(class networking.EnvoyFilter_Patch_FilterClass) Phase {
+	switch class {
+	case networking.EnvoyFilter_Patch_AUTHN:
+		return PhaseAuthn
+	case networking.EnvoyFilter_Patch_AUTHZ:
+		return PhaseAuthz
+	case networking.EnvoyFilter_Patch_STATS:
+		return PhaseStats
+	}
+	return PhaseUnspecified
+}
@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	httplrl "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	networklrl "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/local_ratelimit/v3"
+	envoytype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+const (
+	HTTPLocalRateLimitFilterName    = "envoy.filters.http.local_ratelimit"
+	NetworkLocalRateLimitFilterName = "envoy.filters.network.local_ratelimit"
+
+	localRateLimitStatPrefix = "local_rate_limit"
+)
+
+var (
+	// EmptyHTTPLocalRateLimit is a disabled HTTP local rate limit filter. It enforces nothing by itself,
+	// but must be present in the connection manager for per route rate limits to apply.
+	EmptyHTTPLocalRateLimit = &hcm.HttpFilter{
+		Name: HTTPLocalRateLimitFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&httplrl.LocalRateLimit{
+				StatPrefix: localRateLimitStatPrefix,
+			}),
+		},
+	}
+
+	localRateLimitFullyEnabled = &envoytype.FractionalPercent{
+		Numerator:   100,
+		Denominator: envoytype.FractionalPercent_HUNDRED,
+	}
+)
+
+// BuildHTTPLocalRateLimit builds an HTTP local rate limit filter enforcing the token bucket on all
+// requests of the connection manager.
+func BuildHTTPLocalRateLimit(bucket *ratelimit.TokenBucket) *hcm.HttpFilter {
+	return &hcm.HttpFilter{
+		Name: HTTPLocalRateLimitFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: BuildHTTPLocalRateLimitConfig(bucket),
+		},
+	}
+}
+
+// BuildHTTPLocalRateLimitConfig builds an enabled and enforced HTTP local rate limit configuration,
+// usable as filter configuration or as per route configuration.
+func BuildHTTPLocalRateLimitConfig(bucket *ratelimit.TokenBucket) *any.Any {
+	return util.MessageToAny(&httplrl.LocalRateLimit{
+		StatPrefix:  localRateLimitStatPrefix,
+		TokenBucket: buildTokenBucket(bucket),
+		FilterEnabled: &core.RuntimeFractionalPercent{
+			DefaultValue: localRateLimitFullyEnabled,
+			RuntimeKey:   "local_rate_limit_enabled",
+		},
+		FilterEnforced: &core.RuntimeFractionalPercent{
+			DefaultValue: localRateLimitFullyEnabled,
+			RuntimeKey:   "local_rate_limit_enforced",
+		},
+	})
+}
+
+// BuildNetworkLocalRateLimit builds a network local rate limit filter enforcing the token bucket on
+// new connections.
+func BuildNetworkLocalRateLimit(bucket *ratelimit.TokenBucket) *listener.Filter {
+	return &listener.Filter{
+		Name: NetworkLocalRateLimitFilterName,
+		ConfigType: &listener.Filter_TypedConfig{
+			TypedConfig: util.MessageToAny(&networklrl.LocalRateLimit{
+				StatPrefix:  localRateLimitStatPrefix,
+				TokenBucket: buildTokenBucket(bucket),
+			}),
+		},
+	}
+}
+
+func buildTokenBucket(bucket *ratelimit.TokenBucket) *envoytype.TokenBucket {
+	out := &envoytype.TokenBucket{
+		MaxTokens:    bucket.MaxTokens,
+		FillInterval: durationpb.New(bucket.Interval()),
+	}
+	if bucket.TokensPerFill > 0 {
+		out.TokensPerFill = wrapperspb.UInt32(bucket.TokensPerFill)
+	}
+	return out
+}
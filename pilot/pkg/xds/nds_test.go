@@ -43,7 +43,7 @@ func TestNDS(t *testing.T) {
 			expected: &nds.NameTable{
 				Table: map[string]*nds.NameTable_NameInfo{
 					"random-1.host.example": {
-						Ips:      []string{"240.240.0.1"},
+						Ips:      []string{"240.240.0.33"},
 						Registry: "External",
 					},
 					"random-2.host.example": {
@@ -51,7 +51,7 @@ func TestNDS(t *testing.T) {
 						Registry: "External",
 					},
 					"random-3.host.example": {
-						Ips:      []string{"240.240.0.2"},
+						Ips:      []string{"240.240.46.111"},
 						Registry: "External",
 					},
 				},
@@ -30,8 +30,8 @@ type PcdsGenerator struct {
 
 var _ model.XdsResourceGenerator = &PcdsGenerator{}
 
-func pcdsNeedsPush(req *model.PushRequest) bool {
-	if !features.MultiRootMesh.Get() {
+func pcdsNeedsPush(req *model.PushRequest, revocation bool) bool {
+	if !features.MultiRootMesh.Get() && !revocation {
 		return false
 	}
 
@@ -53,15 +53,22 @@ func pcdsNeedsPush(req *model.PushRequest) bool {
 
 // Generate returns ProxyConfig protobuf containing TrustBundle for given proxy
 func (e *PcdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource, req *model.PushRequest) (model.Resources, error) {
-	if !pcdsNeedsPush(req) {
-		return nil, nil
-	}
-	if e.TrustBundle == nil {
+	if !pcdsNeedsPush(req, e.Server.CARevoker != nil) {
 		return nil, nil
 	}
 	// TODO: For now, only TrustBundle updates are pushed. Eventually, this should push entire Proxy Configuration
-	pc := &mesh.ProxyConfig{
-		CaCertificatesPem: e.TrustBundle.GetTrustBundle(),
+	pc := &mesh.ProxyConfig{}
+	if features.MultiRootMesh.Get() && e.TrustBundle != nil {
+		pc.CaCertificatesPem = e.TrustBundle.GetTrustBundle()
+	}
+	// The CRLs of the CA are published with the trust bundle. The agent moves them to the
+	// validation context, so revoked certificates are rejected mesh-wide.
+	if e.Server.CARevoker != nil {
+		if crls := e.Server.CARevoker.RevocationLists(); len(crls) > 0 {
+			pc.CaCertificatesPem = append(pc.CaCertificatesPem, string(crls))
+		}
+	} else if e.TrustBundle == nil {
+		return nil, nil
 	}
 	return model.Resources{gogo.MessageToAny(pc)}, nil
 }
@@ -31,9 +31,10 @@ const (
 	SecretType                 = resource.SecretType
 	ExtensionConfigurationType = resource.ExtensionConfigType
 
-	NameTableType   = apiTypePrefix + "istio.networking.nds.v1.NameTable"
-	HealthInfoType  = apiTypePrefix + "istio.v1.HealthInformation"
-	ProxyConfigType = apiTypePrefix + "istio.mesh.v1alpha1.ProxyConfig"
+	NameTableType           = apiTypePrefix + "istio.networking.nds.v1.NameTable"
+	HealthInfoType          = apiTypePrefix + "istio.v1.HealthInformation"
+	ProxyConfigType         = apiTypePrefix + "istio.mesh.v1alpha1.ProxyConfig"
+	ConnectionPoolUsageType = apiTypePrefix + "istio.v1.ConnectionPoolUsage"
 
 	// nolint
 	HttpProtocolOptionsType = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
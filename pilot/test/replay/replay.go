@@ -0,0 +1,248 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// Options configures a replay.
+type Options struct {
+	// Iterations is the number of times the initial full push to all proxies is repeated. Defaults to 1.
+	Iterations int
+}
+
+// Run loads the snapshot into a fake discovery server, pushes to all proxies of the snapshot, then
+// applies the changes of the snapshot one at a time, pushing to the proxies that need it after each.
+//
+// Pushes are generated serially on the calling goroutine, as if istiod had a single push worker, so that
+// the cost of each generator can be attributed.
+func Run(t test.Failer, snap *Snapshot, opts Options) (*Report, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = 1
+	}
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		Configs:  snap.Configs,
+		Services: snap.Services,
+	})
+	r := &runner{
+		s:      s,
+		report: newReport(),
+	}
+
+	for i := 0; i < opts.Iterations; i++ {
+		// Rebuild the push context from scratch, as on startup.
+		d, err := r.update(func() error {
+			s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := r.push(fmt.Sprintf("initial#%d", i), d, &model.PushRequest{Full: true}, snap.Proxies); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range snap.Changes {
+		c := c
+		d, err := r.update(func() error {
+			return r.apply(c)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", c, err)
+		}
+		req := &model.PushRequest{
+			Full: true,
+			ConfigsUpdated: map[model.ConfigKey]struct{}{{
+				Kind:      c.Config.GroupVersionKind,
+				Name:      c.Config.Name,
+				Namespace: c.Config.Namespace,
+			}: {}},
+			Reason: []model.TriggerReason{model.ConfigUpdate},
+		}
+		if err := r.push(c.String(), d, req, snap.Proxies); err != nil {
+			return nil, err
+		}
+	}
+	return r.report, nil
+}
+
+type runner struct {
+	s      *xds.FakeDiscoveryServer
+	report *Report
+}
+
+// update runs a function triggering config updates, and returns the time until the discovery server
+// has rebuilt the push context for them.
+func (r *runner) update(f func() error) (time.Duration, error) {
+	t0 := time.Now()
+	if err := f(); err != nil {
+		return 0, err
+	}
+	inbound := r.s.Discovery.InboundUpdates.Load()
+	err := retry.Until(func() bool {
+		return r.s.Discovery.CommittedUpdates.Load() >= inbound
+	}, retry.Delay(time.Millisecond), retry.Timeout(time.Minute))
+	return time.Since(t0), err
+}
+
+// apply writes the change to the config store.
+func (r *runner) apply(c Change) error {
+	store := r.s.Store()
+	switch c.Type {
+	case EventAdd:
+		_, err := store.Create(c.Config)
+		return err
+	case EventUpdate:
+		_, err := store.Update(c.Config)
+		return err
+	case EventDelete:
+		return store.Delete(c.Config.GroupVersionKind, c.Config.Name, c.Config.Namespace, nil)
+	}
+	return fmt.Errorf("unknown event type %q", c.Type)
+}
+
+// push generates the configuration of each proxy that needs a push for the request, in the order
+// Envoy requests it.
+func (r *runner) push(name string, pushContextTime time.Duration, req *model.PushRequest, proxies []*model.Proxy) error {
+	push := r.s.PushContext()
+	req.Push = push
+	req.Start = time.Now()
+	stats := PushStats{
+		Name:            name,
+		PushContextTime: pushContextTime,
+	}
+	for _, proxy := range proxies {
+		proxy = r.s.SetupProxy(proxy)
+		if !r.s.Discovery.ProxyNeedsPush(proxy, req) {
+			continue
+		}
+		stats.Proxies++
+
+		clusters, err := r.generate(proxy, push, v3.ClusterType, nil, req)
+		if err != nil {
+			return err
+		}
+		if _, err := r.generate(proxy, push, v3.EndpointType, xdstest.ExtractEdsClusterNames(unmarshalClusters(clusters)), req); err != nil {
+			return err
+		}
+		listeners, err := r.generate(proxy, push, v3.ListenerType, nil, req)
+		if err != nil {
+			return err
+		}
+		if _, err := r.generate(proxy, push, v3.RouteType, xdstest.ExtractRoutesFromListeners(unmarshalListeners(listeners)), req); err != nil {
+			return err
+		}
+		if proxy.Type == model.SidecarProxy {
+			if _, err := r.generate(proxy, push, v3.NameTableType, nil, req); err != nil {
+				return err
+			}
+		}
+	}
+	stats.GenerateTime = time.Since(req.Start)
+	stats.Latency = stats.PushContextTime + stats.GenerateTime
+	r.report.Pushes = append(r.report.Pushes, stats)
+	return nil
+}
+
+// generate runs the generator the discovery server would pick for the proxy and type, and records its cost.
+func (r *runner) generate(proxy *model.Proxy, push *model.PushContext, typeURL string, names []string,
+	req *model.PushRequest) (model.Resources, error) {
+	gen := r.generator(proxy, typeURL)
+	if gen == nil {
+		return nil, nil
+	}
+	w := &model.WatchedResource{TypeUrl: typeURL, ResourceNames: names}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cpu := cpuTime()
+	t0 := time.Now()
+	res, err := gen.Generate(proxy, push, w, req)
+	wall := time.Since(t0)
+	cpu = cpuTime() - cpu
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		return nil, fmt.Errorf("%s generation for %s failed: %v", v3.GetShortType(typeURL), proxy.ID, err)
+	}
+
+	stats := r.report.generator(v3.GetShortType(typeURL))
+	stats.Calls++
+	stats.CPUTime += cpu
+	stats.WallTime += wall
+	stats.Allocs += after.Mallocs - before.Mallocs
+	stats.AllocBytes += after.TotalAlloc - before.TotalAlloc
+	stats.Resources += len(res)
+	stats.ResponseBytes += proto.Size(&discovery.DiscoveryResponse{TypeUrl: typeURL, Resources: res})
+	return res, nil
+}
+
+// generator mirrors the lookup of the discovery server: a generator registered for the generator of the
+// proxy and the type takes precedence over the one registered for the type.
+func (r *runner) generator(proxy *model.Proxy, typeURL string) model.XdsResourceGenerator {
+	gens := r.s.Discovery.Generators
+	if g, f := gens[proxy.Metadata.Generator+"/"+typeURL]; f {
+		return g
+	}
+	return gens[typeURL]
+}
+
+func unmarshalClusters(res model.Resources) []*cluster.Cluster {
+	out := make([]*cluster.Cluster, 0, len(res))
+	for _, r := range res {
+		c := &cluster.Cluster{}
+		if err := r.UnmarshalTo(c); err == nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func unmarshalListeners(res model.Resources) []*listener.Listener {
+	out := make([]*listener.Listener, 0, len(res))
+	for _, r := range res {
+		l := &listener.Listener{}
+		if err := r.UnmarshalTo(l); err == nil {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// cpuTime returns the user and system CPU time of the process. It includes the garbage collector and
+// other goroutines, which are mostly idle during a replay.
+func cpuTime() time.Duration {
+	ru := syscall.Rusage{}
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestLoad(t *testing.T) {
+	snap, err := Load("testdata/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Configs) != 3 {
+		t.Errorf("got %d configs, want 3", len(snap.Configs))
+	}
+	// The ServiceEntry service and the terminating empty object of registryz are skipped.
+	if len(snap.Services) != 1 || snap.Services[0].Hostname != "reviews.default.svc.cluster.local" {
+		t.Errorf("got services %v, want reviews", snap.Services)
+	}
+	if len(snap.Proxies) != 2 {
+		t.Fatalf("got %d proxies, want 2", len(snap.Proxies))
+	}
+	if p := snap.Proxies[1]; p.Type != model.Router || p.ConfigNamespace != "istio-system" || p.IPAddresses[0] != "10.0.0.2" {
+		t.Errorf("unexpected gateway proxy %+v", p)
+	}
+	want := []string{
+		"add DestinationRule default/reviews",
+		"update VirtualService default/reviews",
+		"delete Gateway istio-system/ingress",
+	}
+	if len(snap.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(snap.Changes), len(want))
+	}
+	for i, c := range snap.Changes {
+		if c.String() != want[i] {
+			t.Errorf("change %d: got %q, want %q", i, c.String(), want[i])
+		}
+	}
+	if snap.Changes[0].Config.GroupVersionKind != gvk.DestinationRule {
+		t.Errorf("got kind %v, want DestinationRule", snap.Changes[0].Config.GroupVersionKind)
+	}
+}
+
+func TestRun(t *testing.T) {
+	snap, err := Load("testdata/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Run(t, snap, Options{Iterations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Pushes) != 2+len(snap.Changes) {
+		t.Fatalf("got %d pushes, want %d", len(report.Pushes), 2+len(snap.Changes))
+	}
+	for _, p := range report.Pushes[:2] {
+		if p.Proxies != len(snap.Proxies) {
+			t.Errorf("%s: pushed to %d proxies, want all %d", p.Name, p.Proxies, len(snap.Proxies))
+		}
+		if p.Latency < p.GenerateTime {
+			t.Errorf("%s: latency %v is less than the generation time %v", p.Name, p.Latency, p.GenerateTime)
+		}
+	}
+	for _, name := range []string{"CDS", "EDS", "LDS", "RDS", "NDS"} {
+		g, f := report.Generators[name]
+		if !f || g.Calls == 0 {
+			t.Errorf("%s was not called", name)
+		}
+	}
+	for _, name := range []string{"CDS", "LDS", "RDS"} {
+		g := report.Generators[name]
+		if g.Resources == 0 || g.ResponseBytes == 0 {
+			t.Errorf("%s generated no resources", name)
+		}
+		if g.Allocs == 0 || g.AllocBytes == 0 {
+			t.Errorf("%s has no allocations", name)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{
+		Generators: map[string]*GeneratorStats{
+			"CDS": {Calls: 10, CPUTime: 10 * time.Millisecond, Allocs: 1000, AllocBytes: 1 << 20, ResponseBytes: 5000},
+			"LDS": {Calls: 10, CPUTime: 10 * time.Millisecond, Allocs: 1000},
+		},
+		Pushes: []PushStats{{Latency: time.Second}},
+	}
+	current := &Report{
+		Generators: map[string]*GeneratorStats{
+			// Twice as many calls for the same cost per call, except the allocations.
+			"CDS": {Calls: 20, CPUTime: 20 * time.Millisecond, Allocs: 3000, AllocBytes: 2 << 20, ResponseBytes: 10000},
+			"LDS": {Calls: 10, CPUTime: 10 * time.Millisecond, Allocs: 1050},
+		},
+		Pushes: []PushStats{{Latency: time.Second}, {Latency: time.Second}},
+	}
+	got := Compare(baseline, current, 0.1)
+	want := []string{"CDS allocs per call: 100 -> 150 (+50.0%)"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("got regressions %v, want %v", got, want)
+	}
+	if got := Compare(baseline, current, 0.6); len(got) != 0 {
+		t.Errorf("got regressions %v with a higher tolerance", got)
+	}
+}
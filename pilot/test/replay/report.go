@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// GeneratorStats is the cost of all calls to a generator during a replay.
+type GeneratorStats struct {
+	Calls         int           `json:"calls"`
+	CPUTime       time.Duration `json:"cpuTime"`
+	WallTime      time.Duration `json:"wallTime"`
+	Allocs        uint64        `json:"allocs"`
+	AllocBytes    uint64        `json:"allocBytes"`
+	Resources     int           `json:"resources"`
+	ResponseBytes int           `json:"responseBytes"`
+}
+
+// PushStats is the cost of a push to all proxies, after the initial load or a change of the snapshot.
+type PushStats struct {
+	Name string `json:"name"`
+	// Proxies is the number of proxies that needed a push.
+	Proxies int `json:"proxies"`
+	// PushContextTime is the time from the change until the discovery server rebuilt the push context.
+	PushContextTime time.Duration `json:"pushContextTime"`
+	// GenerateTime is the time to generate the configuration of all proxies, one at a time.
+	GenerateTime time.Duration `json:"generateTime"`
+	// Latency is the time from the change until the last proxy got its configuration.
+	Latency time.Duration `json:"latency"`
+}
+
+// Report is the result of a replay.
+type Report struct {
+	// Generators is keyed by the short name of the type, for example CDS.
+	Generators map[string]*GeneratorStats `json:"generators"`
+	Pushes     []PushStats                `json:"pushes"`
+}
+
+func newReport() *Report {
+	return &Report{Generators: map[string]*GeneratorStats{}}
+}
+
+func (r *Report) generator(name string) *GeneratorStats {
+	if _, f := r.Generators[name]; !f {
+		r.Generators[name] = &GeneratorStats{}
+	}
+	return r.Generators[name]
+}
+
+// Latency returns the total latency of all pushes.
+func (r *Report) Latency() time.Duration {
+	var total time.Duration
+	for _, p := range r.Pushes {
+		total += p.Latency
+	}
+	return total
+}
+
+func (r *Report) generatorNames() []string {
+	names := make([]string, 0, len(r.Generators))
+	for name := range r.Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write prints the report as tables.
+func (r *Report) Write(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "GENERATOR\tCALLS\tCPU\tWALL\tALLOCS\tALLOC BYTES\tRESOURCES\tRESPONSE BYTES")
+	for _, name := range r.generatorNames() {
+		g := r.Generators[name]
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%d\t%d\t%d\t%d\n",
+			name, g.Calls, g.CPUTime, g.WallTime, g.Allocs, g.AllocBytes, g.Resources, g.ResponseBytes)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "PUSH\tPROXIES\tPUSH CONTEXT\tGENERATE\tLATENCY")
+	for _, p := range r.Pushes {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\n", p.Name, p.Proxies, p.PushContextTime, p.GenerateTime, p.Latency)
+	}
+	return w.Flush()
+}
+
+// Compare returns the regressions of the report from a baseline, where a cost grew by more than the
+// tolerance, as a fraction of the baseline. Costs are compared per call, so that the reports of replays
+// with a different number of iterations can be compared.
+func Compare(baseline, current *Report, tolerance float64) []string {
+	var regressions []string
+	check := func(what string, base, cur float64) {
+		if base > 0 && cur > base*(1+tolerance) {
+			regressions = append(regressions, fmt.Sprintf("%s: %.0f -> %.0f (+%.1f%%)", what, base, cur, (cur/base-1)*100))
+		}
+	}
+	for _, name := range baseline.generatorNames() {
+		base := baseline.Generators[name]
+		cur, f := current.Generators[name]
+		if !f || base.Calls == 0 || cur.Calls == 0 {
+			continue
+		}
+		perCall := func(v float64, g *GeneratorStats) float64 {
+			return v / float64(g.Calls)
+		}
+		check(name+" CPU time per call (ns)", perCall(float64(base.CPUTime), base), perCall(float64(cur.CPUTime), cur))
+		check(name+" allocs per call", perCall(float64(base.Allocs), base), perCall(float64(cur.Allocs), cur))
+		check(name+" alloc bytes per call", perCall(float64(base.AllocBytes), base), perCall(float64(cur.AllocBytes), cur))
+		check(name+" response bytes per call", perCall(float64(base.ResponseBytes), base), perCall(float64(cur.ResponseBytes), cur))
+	}
+	if len(baseline.Pushes) > 0 && len(current.Pushes) > 0 {
+		check("push latency per push (ns)",
+			float64(baseline.Latency())/float64(len(baseline.Pushes)), float64(current.Latency())/float64(len(current.Pushes)))
+	}
+	return regressions
+}
@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replay loads a snapshot recorded from a running istiod into a fake discovery server, and
+// replays the recorded config changes to measure the cost of generating xDS for the recorded proxies.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/resource"
+)
+
+const (
+	// ConfigsFile holds the output of /debug/configz.
+	ConfigsFile = "configz.json"
+	// ServicesFile holds the output of /debug/registryz.
+	ServicesFile = "registryz.json"
+	// ProxiesFile holds the list of recorded proxies, see ProxyMetadata.
+	ProxiesFile = "proxies.json"
+	// EventsFile optionally holds the recorded sequence of config changes, see Event.
+	EventsFile = "events.json"
+)
+
+// EventType is the type of a recorded config change.
+type EventType string
+
+const (
+	EventAdd    EventType = "add"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+)
+
+// ProxyMetadata is a recorded proxy, as sent in the node of its xDS requests.
+type ProxyMetadata struct {
+	// ID is the node ID, for example sidecar~10.0.0.1~app-1234.default~default.svc.cluster.local.
+	ID string `json:"id"`
+	// Metadata is the node metadata.
+	Metadata *model.NodeMetadata `json:"metadata"`
+}
+
+// Event is a recorded config change. Object is in the format of /debug/configz; only the kind, name
+// and namespace are needed for a delete.
+type Event struct {
+	Type   EventType     `json:"type"`
+	Object crd.IstioKind `json:"object"`
+}
+
+// Change is a config change of the snapshot, ready to be applied to the config store.
+type Change struct {
+	Type   EventType
+	Config config.Config
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s/%s", c.Type, c.Config.GroupVersionKind.Kind, c.Config.Namespace, c.Config.Name)
+}
+
+// Snapshot is the state of an istiod, and a sequence of changes to replay on top of it.
+type Snapshot struct {
+	Configs  []config.Config
+	Services []*model.Service
+	Proxies  []*model.Proxy
+	Changes  []Change
+}
+
+// Load reads a snapshot from a directory holding the files ConfigsFile, ServicesFile, ProxiesFile and
+// optionally EventsFile.
+func Load(dir string) (*Snapshot, error) {
+	s := &Snapshot{}
+
+	var objects []crd.IstioKind
+	if err := readJSON(filepath.Join(dir, ConfigsFile), &objects); err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		cfg, err := convertObject(&objects[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ConfigsFile, err)
+		}
+		if cfg != nil {
+			s.Configs = append(s.Configs, *cfg)
+		}
+	}
+
+	var services []*model.Service
+	if err := readJSON(filepath.Join(dir, ServicesFile), &services); err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		// registryz terminates the list with an empty object. Services of the external registry are
+		// built from the ServiceEntries of the snapshot instead.
+		if svc.Hostname == "" || svc.Attributes.ServiceRegistry == serviceregistry.External {
+			continue
+		}
+		s.Services = append(s.Services, svc)
+	}
+
+	var proxies []ProxyMetadata
+	if err := readJSON(filepath.Join(dir, ProxiesFile), &proxies); err != nil {
+		return nil, err
+	}
+	for _, p := range proxies {
+		if p.Metadata == nil {
+			p.Metadata = &model.NodeMetadata{}
+		}
+		proxy, err := model.ParseServiceNodeWithMetadata(p.ID, p.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ProxiesFile, err)
+		}
+		proxy.ConfigNamespace = model.GetProxyConfigNamespace(proxy)
+		s.Proxies = append(s.Proxies, proxy)
+	}
+
+	var events []Event
+	if err := readJSON(filepath.Join(dir, EventsFile), &events); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for i, e := range events {
+		switch e.Type {
+		case EventAdd, EventUpdate, EventDelete:
+		default:
+			return nil, fmt.Errorf("%s: event %d has unknown type %q", EventsFile, i, e.Type)
+		}
+		cfg, err := convertObject(&events[i].Object)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", EventsFile, err)
+		}
+		if cfg == nil {
+			return nil, fmt.Errorf("%s: event %d has unsupported kind %v", EventsFile, i, e.Object.GroupVersionKind())
+		}
+		s.Changes = append(s.Changes, Change{Type: e.Type, Config: *cfg})
+	}
+
+	return s, nil
+}
+
+// convertObject converts a config from the format of /debug/configz. Kinds the fake discovery server
+// does not store, such as the service-apis kinds, are skipped and returned as nil.
+func convertObject(obj *crd.IstioKind) (*config.Config, error) {
+	gvk := obj.GroupVersionKind()
+	schema, exists := collections.Pilot.FindByGroupVersionKind(resource.FromKubernetesGVK(&gvk))
+	if !exists {
+		return nil, nil
+	}
+	// Unlike crd.ParseInputs, configs are not validated: they were accepted by the recorded istiod,
+	// whose validation may differ.
+	cfg, err := crd.ConvertObject(schema, obj, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s %s/%s: %v", obj.Kind, obj.Namespace, obj.Name, err)
+	}
+	return cfg, nil
+}
+
+func readJSON(path string, out interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed parsing %s: %v", path, err)
+	}
+	return nil
+}
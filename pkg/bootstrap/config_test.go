@@ -15,12 +15,17 @@
 package bootstrap
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	"k8s.io/kubectl/pkg/util/fieldpath"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/bootstrap/platform"
 )
 
 func TestParseDownwardApi(t *testing.T) {
@@ -96,3 +101,31 @@ func TestGetNodeMetaData(t *testing.T) {
 	g.Expect(node.RawMetadata["OWNER"]).To(Equal(expectOwner))
 	g.Expect(node.RawMetadata["WORKLOAD_NAME"]).To(Equal(expectWorkloadName))
 }
+
+func TestBareMetalInstanceLabels(t *testing.T) {
+	g := NewWithT(t)
+	path := filepath.Join(t.TempDir(), "platform.json")
+	err := ioutil.WriteFile(path, []byte(`{"labels": {"topology.istio.io/network": "onprem"}}`), 0o644)
+	g.Expect(err).Should(BeNil())
+	os.Setenv("PLATFORM_METADATA_FILE", path)
+	defer os.Unsetenv("PLATFORM_METADATA_FILE")
+	host, onKubernetes := os.LookupEnv(platform.KubernetesServiceHost)
+	os.Unsetenv(platform.KubernetesServiceHost)
+	defer func() {
+		if onKubernetes {
+			os.Setenv(platform.KubernetesServiceHost, host)
+		} else {
+			os.Unsetenv(platform.KubernetesServiceHost)
+		}
+	}()
+
+	meta := &model.BootstrapNodeMetadata{}
+	extractInstanceLabels(platform.NewBareMetal(), meta)
+	g.Expect(meta.Labels).To(Equal(map[string]string{"topology.istio.io/network": "onprem"}))
+
+	// In a pod, the labels of the pod are used.
+	os.Setenv(platform.KubernetesServiceHost, "10.0.0.1")
+	meta = &model.BootstrapNodeMetadata{}
+	extractInstanceLabels(platform.NewBareMetal(), meta)
+	g.Expect(meta.Labels).To(BeNil())
+}
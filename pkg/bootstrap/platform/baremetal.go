@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	"istio.io/pkg/env"
+	"istio.io/pkg/log"
+)
+
+const (
+	BareMetalPrefix      = "baremetal_"
+	BareMetalSysVendor   = BareMetalPrefix + "sys_vendor"
+	BareMetalProductName = BareMetalPrefix + "product_name"
+	BareMetalProductUUID = BareMetalPrefix + "product_uuid"
+)
+
+var bareMetalMetadataFileVar = env.RegisterStringVar("PLATFORM_METADATA_FILE", "",
+	"Path to a JSON file describing the locality, labels and metadata of the host. "+
+		"Intended for bare-metal and on-prem hosts without a metadata service.")
+
+// dmiDir is the sysfs directory holding the DMI identification of the host.
+var dmiDir = "/sys/class/dmi/id"
+
+// BareMetalMetadata is the schema of the file referenced by PLATFORM_METADATA_FILE.
+// All fields are optional. Metadata keys are prefixed with "baremetal_" when exposed.
+type BareMetalMetadata struct {
+	Region   string            `json:"region,omitempty"`
+	Zone     string            `json:"zone,omitempty"`
+	SubZone  string            `json:"subZone,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type bareMetalEnv struct {
+	file BareMetalMetadata
+	dmi  map[string]string
+}
+
+// IsBareMetal returns whether or not a platform metadata file has been configured.
+// An explicitly configured file takes precedence over any detected cloud platform.
+func IsBareMetal() bool {
+	path := bareMetalMetadataFileVar.Get()
+	if path == "" {
+		return false
+	}
+	if _, err := os.Stat(path); err != nil {
+		log.Warnf("platform metadata file %s is not readable: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// NewBareMetal returns a platform environment built from the file referenced by
+// PLATFORM_METADATA_FILE, augmented with the host DMI identification.
+func NewBareMetal() Environment {
+	return newBareMetal(bareMetalMetadataFileVar.Get(), dmiDir)
+}
+
+func newBareMetal(path, dmi string) *bareMetalEnv {
+	e := &bareMetalEnv{
+		dmi: readDMI(dmi),
+	}
+	if path == "" {
+		return e
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Warnf("failed to read platform metadata file %s: %v", path, err)
+		return e
+	}
+	if err := json.Unmarshal(b, &e.file); err != nil {
+		log.Warnf("failed to parse platform metadata file %s: %v", path, err)
+	}
+	return e
+}
+
+// readDMI reads the DMI identification of the host. Missing or unreadable entries
+// (product_uuid is typically root only) are skipped.
+func readDMI(dir string) map[string]string {
+	md := map[string]string{}
+	for key, file := range map[string]string{
+		BareMetalSysVendor:   "sys_vendor",
+		BareMetalProductName: "product_name",
+		BareMetalProductUUID: "product_uuid",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		if v := strings.TrimSpace(string(b)); v != "" {
+			md[key] = v
+		}
+	}
+	return md
+}
+
+// Metadata returns the host DMI identification and the metadata entries of the file.
+func (e *bareMetalEnv) Metadata() map[string]string {
+	md := map[string]string{}
+	for k, v := range e.dmi {
+		md[k] = v
+	}
+	for k, v := range e.file.Metadata {
+		md[BareMetalPrefix+k] = v
+	}
+	return md
+}
+
+// Locality returns the region, zone and sub zone declared in the file.
+func (e *bareMetalEnv) Locality() *core.Locality {
+	return &core.Locality{
+		Region:  e.file.Region,
+		Zone:    e.file.Zone,
+		SubZone: e.file.SubZone,
+	}
+}
+
+// Labels returns the labels declared in the file.
+func (e *bareMetalEnv) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range e.file.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// IsKubernetes returns true if the proxy runs in a Kubernetes pod on the host.
+func (e *bareMetalEnv) IsKubernetes() bool {
+	_, onKubernetes := os.LookupEnv(KubernetesServiceHost)
+	return onKubernetes
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+)
+
+const mockBareMetalFile = `{"region": "dc1", "zone": "rack-a", "subZone": "host-7", ` +
+	`"labels": {"topology.istio.io/network": "onprem"}, "metadata": {"site": "fra"}}`
+
+func TestBareMetal(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		dmi      map[string]string
+		metadata map[string]string
+		locality *core.Locality
+		labels   map[string]string
+	}{
+		{
+			name:     "no file",
+			metadata: map[string]string{},
+			locality: &core.Locality{},
+			labels:   map[string]string{},
+		},
+		{
+			name:     "invalid file",
+			file:     "{",
+			metadata: map[string]string{},
+			locality: &core.Locality{},
+			labels:   map[string]string{},
+		},
+		{
+			name:     "dmi only",
+			dmi:      map[string]string{"sys_vendor": "Dell Inc.\n", "product_name": "PowerEdge R640\n"},
+			metadata: map[string]string{BareMetalSysVendor: "Dell Inc.", BareMetalProductName: "PowerEdge R640"},
+			locality: &core.Locality{},
+			labels:   map[string]string{},
+		},
+		{
+			name: "file and dmi",
+			file: mockBareMetalFile,
+			dmi:  map[string]string{"product_uuid": "4c4c4544-0042-3510-8051-b4c04f4e4d32"},
+			metadata: map[string]string{
+				BareMetalProductUUID:     "4c4c4544-0042-3510-8051-b4c04f4e4d32",
+				BareMetalPrefix + "site": "fra",
+			},
+			locality: &core.Locality{Region: "dc1", Zone: "rack-a", SubZone: "host-7"},
+			labels:   map[string]string{"topology.istio.io/network": "onprem"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := ""
+			if tt.file != "" {
+				path = filepath.Join(dir, "platform.json")
+				if err := ioutil.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dmi := filepath.Join(dir, "dmi")
+			for name, content := range tt.dmi {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				dmi = dir
+			}
+
+			e := newBareMetal(path, dmi)
+			if got := e.Metadata(); !reflect.DeepEqual(got, tt.metadata) {
+				t.Errorf("bareMetalEnv.Metadata() => '%v'; want '%v'", got, tt.metadata)
+			}
+			if got := e.Locality(); !reflect.DeepEqual(got, tt.locality) {
+				t.Errorf("bareMetalEnv.Locality() => '%v'; want '%v'", got, tt.locality)
+			}
+			if got := e.Labels(); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("bareMetalEnv.Labels() => '%v'; want '%v'", got, tt.labels)
+			}
+		})
+	}
+}
+
+func TestBareMetalIsKubernetes(t *testing.T) {
+	host, set := os.LookupEnv(KubernetesServiceHost)
+	defer func() {
+		if set {
+			os.Setenv(KubernetesServiceHost, host)
+		} else {
+			os.Unsetenv(KubernetesServiceHost)
+		}
+	}()
+
+	e := newBareMetal("", t.TempDir())
+	os.Unsetenv(KubernetesServiceHost)
+	if e.IsKubernetes() {
+		t.Errorf("bareMetalEnv.IsKubernetes() => true outside Kubernetes")
+	}
+	os.Setenv(KubernetesServiceHost, "10.0.0.1")
+	if !e.IsKubernetes() {
+		t.Errorf("bareMetalEnv.IsKubernetes() => false in a Kubernetes pod")
+	}
+}
@@ -21,7 +21,7 @@ import (
 
 const (
 	defaultTimeout = 5 * time.Second
-	numPlatforms   = 3
+	numPlatforms   = 4
 )
 
 // Discover attempts to discover the host platform, defaulting to
@@ -33,11 +33,16 @@ func Discover() Environment {
 // DiscoverWithTimeout attempts to discover the host platform, defaulting to
 // `Unknown` after the provided timeout.
 func DiscoverWithTimeout(timeout time.Duration) Environment {
+	// An explicitly configured platform metadata file always wins over detection.
+	if IsBareMetal() {
+		return NewBareMetal()
+	}
+
 	plat := make(chan Environment, numPlatforms) // sized to match number of platform goroutines
 	done := make(chan bool)
 
 	var wg sync.WaitGroup
-	wg.Add(numPlatforms) // check GCP, AWS, and Azure
+	wg.Add(numPlatforms) // check GCP, AWS, Azure, and OpenStack
 
 	go func() {
 		if IsGCP() {
@@ -60,6 +65,13 @@ func DiscoverWithTimeout(timeout time.Duration) Environment {
 		wg.Done()
 	}()
 
+	go func() {
+		if IsOpenStack() {
+			plat <- NewOpenStack()
+		}
+		wg.Done()
+	}()
+
 	go func() {
 		wg.Wait()
 		close(done)
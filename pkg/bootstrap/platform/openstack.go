@@ -0,0 +1,161 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	"istio.io/pkg/log"
+)
+
+const (
+	OpenStackInstanceID       = "openstack_instance_id"
+	OpenStackInstanceName     = "openstack_instance_name"
+	OpenStackAvailabilityZone = "openstack_availability_zone"
+	OpenStackProjectID        = "openstack_project_id"
+	OpenStackRegion           = "openstack_region"
+
+	// OpenStackRegionMetaKey is the instance metadata key used to carry the region, as the
+	// OpenStack metadata service does not expose it natively.
+	OpenStackRegionMetaKey = "region"
+
+	openStackMetadataPath = "/openstack/latest/meta_data.json"
+	productNamePath       = "/sys/class/dmi/id/product_name"
+	openStackIdentifier   = "OpenStack"
+)
+
+var (
+	openStackMetadataEndpoint = "http://169.254.169.254"
+	openStackDMIPaths         = []string{SysVendorPath, productNamePath}
+)
+
+// openStackMetadata is the subset of the Nova meta_data.json document that we care about.
+// See https://docs.openstack.org/nova/latest/user/metadata.html#openstack-format-metadata
+type openStackMetadata struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	AvailabilityZone string            `json:"availability_zone"`
+	ProjectID        string            `json:"project_id"`
+	Meta             map[string]string `json:"meta"`
+}
+
+type openStackEnv struct {
+	metadata openStackMetadata
+}
+
+// IsOpenStack returns whether or not the platform for bootstrapping is OpenStack.
+// Nova sets the DMI system vendor or product name to identify OpenStack instances,
+// which avoids a slow metadata request on other platforms.
+func IsOpenStack() bool {
+	for _, p := range openStackDMIPaths {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Debugf("Error reading %s in OpenStack platform detection: %v", p, err)
+			}
+			continue
+		}
+		if strings.Contains(string(b), openStackIdentifier) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewOpenStack returns a platform environment customized for OpenStack.
+// Metadata returned by the OpenStack Environment is taken from the Nova metadata
+// service.
+func NewOpenStack() Environment {
+	e := &openStackEnv{}
+	if md, ok := fetchOpenStackMetadata(openStackMetadataEndpoint); ok {
+		e.metadata = md
+	}
+	return e
+}
+
+func fetchOpenStackMetadata(endpoint string) (openStackMetadata, bool) {
+	md := openStackMetadata{}
+	client := http.Client{Timeout: defaultTimeout}
+	resp, err := client.Get(endpoint + openStackMetadataPath)
+	if err != nil {
+		log.Warnf("OpenStack metadata request failed: %v", err)
+		return md, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Warnf("OpenStack metadata request unsuccessful with status: %v", resp.Status)
+		return md, false
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&md); err != nil {
+		log.Warnf("Could not decode OpenStack metadata: %v", err)
+		return md, false
+	}
+	return md, true
+}
+
+// Metadata returns OpenStack instance metadata, including the instance and project IDs.
+func (e *openStackEnv) Metadata() map[string]string {
+	md := map[string]string{}
+	if len(e.metadata.UUID) > 0 {
+		md[OpenStackInstanceID] = e.metadata.UUID
+	}
+	if len(e.metadata.Name) > 0 {
+		md[OpenStackInstanceName] = e.metadata.Name
+	}
+	if len(e.metadata.AvailabilityZone) > 0 {
+		md[OpenStackAvailabilityZone] = e.metadata.AvailabilityZone
+	}
+	if len(e.metadata.ProjectID) > 0 {
+		md[OpenStackProjectID] = e.metadata.ProjectID
+	}
+	if r := e.region(); len(r) > 0 {
+		md[OpenStackRegion] = r
+	}
+	return md
+}
+
+// Locality returns the availability zone, and the region if one was set through
+// instance metadata.
+func (e *openStackEnv) Locality() *core.Locality {
+	return &core.Locality{
+		Region: e.region(),
+		Zone:   e.metadata.AvailabilityZone,
+	}
+}
+
+// Labels returns the user-provided instance metadata ("meta") key/value pairs.
+func (e *openStackEnv) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range e.metadata.Meta {
+		labels[k] = v
+	}
+	return labels
+}
+
+// IsKubernetes returns true if the proxy runs in a Kubernetes pod on the host.
+func (e *openStackEnv) IsKubernetes() bool {
+	_, onKubernetes := os.LookupEnv(KubernetesServiceHost)
+	return onKubernetes
+}
+
+func (e *openStackEnv) region() string {
+	return e.metadata.Meta[OpenStackRegionMetaKey]
+}
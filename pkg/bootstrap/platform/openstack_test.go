@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+)
+
+// Based on the sample in https://docs.openstack.org/nova/latest/user/metadata.html
+const mockOpenStackMetadata = `{"uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38", "name": "test", ` +
+	`"availability_zone": "nova", "project_id": "6ccc2b5d8e6b4d3e8f7d3e5a4f0a1b2c", "hostname": "test.novalocal", ` +
+	`"meta": {"region": "RegionOne", "role": "webservers"}, "launch_index": 0}`
+
+func TestOpenStackMetadata(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		response string
+		metadata map[string]string
+		locality *core.Locality
+		labels   map[string]string
+	}{
+		{
+			name:     "unavailable",
+			status:   http.StatusNotFound,
+			metadata: map[string]string{},
+			locality: &core.Locality{},
+			labels:   map[string]string{},
+		},
+		{
+			name:     "invalid response",
+			status:   http.StatusOK,
+			response: "not json",
+			metadata: map[string]string{},
+			locality: &core.Locality{},
+			labels:   map[string]string{},
+		},
+		{
+			name:     "parse fields",
+			status:   http.StatusOK,
+			response: mockOpenStackMetadata,
+			metadata: map[string]string{
+				OpenStackInstanceID:       "d8e02d56-2648-49a3-bf97-6be8f1204f38",
+				OpenStackInstanceName:     "test",
+				OpenStackAvailabilityZone: "nova",
+				OpenStackProjectID:        "6ccc2b5d8e6b4d3e8f7d3e5a4f0a1b2c",
+				OpenStackRegion:           "RegionOne",
+			},
+			locality: &core.Locality{Region: "RegionOne", Zone: "nova"},
+			labels:   map[string]string{"region": "RegionOne", "role": "webservers"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != openStackMetadataPath {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			oldEndpoint := openStackMetadataEndpoint
+			openStackMetadataEndpoint = srv.URL
+			defer func() { openStackMetadataEndpoint = oldEndpoint }()
+
+			e := NewOpenStack()
+			if got := e.Metadata(); !reflect.DeepEqual(got, tt.metadata) {
+				t.Errorf("openStackEnv.Metadata() => '%v'; want '%v'", got, tt.metadata)
+			}
+			if got := e.Locality(); !reflect.DeepEqual(got, tt.locality) {
+				t.Errorf("openStackEnv.Locality() => '%v'; want '%v'", got, tt.locality)
+			}
+			if got := e.Labels(); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("openStackEnv.Labels() => '%v'; want '%v'", got, tt.labels)
+			}
+		})
+	}
+}
+
+func TestIsOpenStack(t *testing.T) {
+	cases := []struct {
+		name    string
+		vendor  string
+		product string
+		want    bool
+	}{
+		{"no dmi", "", "", false},
+		{"other vendor", "QEMU", "Standard PC", false},
+		{"openstack vendor", "OpenStack Foundation\n", "", true},
+		{"openstack product", "RDO", "OpenStack Compute\n", true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			vendor, product := filepath.Join(dir, "sys_vendor"), filepath.Join(dir, "product_name")
+			if tt.vendor != "" {
+				if err := ioutil.WriteFile(vendor, []byte(tt.vendor), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.product != "" {
+				if err := ioutil.WriteFile(product, []byte(tt.product), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			oldPaths := openStackDMIPaths
+			openStackDMIPaths = []string{vendor, product}
+			defer func() { openStackDMIPaths = oldPaths }()
+
+			if got := IsOpenStack(); got != tt.want {
+				t.Errorf("IsOpenStack() => %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
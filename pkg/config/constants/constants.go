@@ -65,6 +65,9 @@ const (
 	// DefaultKubernetesDomain the default service domain suffix for Kubernetes, if not overridden in config.
 	DefaultKubernetesDomain = "cluster.local"
 
+	// DefaultClusterSetLocalDomain is the domain suffix of services imported through the Kubernetes Multi-Cluster Services API.
+	DefaultClusterSetLocalDomain = "clusterset.local"
+
 	// IstioLabel indicates that a workload is part of a named Istio system component.
 	IstioLabel = "istio"
 
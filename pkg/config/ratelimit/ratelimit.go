@@ -0,0 +1,157 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit defines the local rate limiting configuration of workloads.
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config"
+)
+
+// GroupVersionKind of the LocalRateLimit resource.
+var GroupVersionKind = config.GroupVersionKind{
+	Group:   "extensions.istio.io",
+	Version: "v1alpha1",
+	Kind:    "LocalRateLimit",
+}
+
+// Plural is the resource name of the LocalRateLimit resource.
+const Plural = "localratelimits"
+
+// LocalRateLimit is the spec of the LocalRateLimit resource, which configures Envoy local rate limiting for
+// the workloads it selects. Token buckets are local to each proxy instance; they are not shared across replicas.
+//
+// A LocalRateLimit applies to the workloads of its namespace, or to all the workloads of the mesh when it
+// is in the root namespace. Limits in the workload namespace take precedence over the root namespace ones.
+//
+// Example:
+//
+//   workloadSelector:
+//     labels:
+//       app: reviews
+//   listeners:
+//   - port: 8080
+//     tokenBucket:
+//       maxTokens: 100
+//       tokensPerFill: 100
+//       fillInterval: 1s
+//   routes:
+//   - name: reviews-v2
+//     virtualService: reviews.default
+//     tokenBucket:
+//       maxTokens: 10
+//       fillInterval: 1s
+type LocalRateLimit struct {
+	// WorkloadSelector selects the workloads the limits apply to. All the workloads of the namespace are
+	// selected if it is not set.
+	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+
+	// Listeners limits the requests (HTTP) or connections (TCP) accepted by inbound sidecar listeners
+	// and gateway listeners on a port.
+	Listeners []ListenerRateLimit `json:"listeners,omitempty"`
+
+	// Routes limits the requests matched by named VirtualService HTTP routes.
+	Routes []RouteRateLimit `json:"routes,omitempty"`
+}
+
+// WorkloadSelector selects workloads by their labels.
+type WorkloadSelector struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ListenerRateLimit is a token bucket scoped to all traffic on a listener port.
+type ListenerRateLimit struct {
+	// Port is the workload port for sidecars, or the server port for gateways.
+	Port uint32 `json:"port"`
+
+	TokenBucket TokenBucket `json:"tokenBucket"`
+}
+
+// RouteRateLimit is a token bucket scoped to the requests matched by an HTTP route.
+type RouteRateLimit struct {
+	// Name of the HTTP route, as set in the VirtualService http[].name field.
+	Name string `json:"name"`
+
+	// VirtualService optionally restricts the match to routes of a VirtualService, in <name>.<namespace> form.
+	VirtualService string `json:"virtualService,omitempty"`
+
+	TokenBucket TokenBucket `json:"tokenBucket"`
+}
+
+// TokenBucket mirrors the Envoy token bucket: it holds at most MaxTokens tokens, and is refilled with
+// TokensPerFill tokens every FillInterval.
+type TokenBucket struct {
+	MaxTokens uint32 `json:"maxTokens"`
+
+	// TokensPerFill defaults to a single token.
+	TokensPerFill uint32 `json:"tokensPerFill,omitempty"`
+
+	// FillInterval is a duration, such as "1s" or "100ms".
+	FillInterval string `json:"fillInterval"`
+}
+
+// Interval returns the parsed fill interval. FillInterval must have been validated.
+func (b TokenBucket) Interval() time.Duration {
+	d, _ := time.ParseDuration(b.FillInterval)
+	return d
+}
+
+// Parse parses a LocalRateLimit spec, in YAML or JSON. The spec is not validated.
+func Parse(value string) (*LocalRateLimit, error) {
+	out := &LocalRateLimit{}
+	if err := yaml.UnmarshalStrict([]byte(value), out); err != nil {
+		return nil, fmt.Errorf("failed to parse local rate limit: %v", err)
+	}
+	return out, nil
+}
+
+// ListenerLimit returns the token bucket for the given listener port, or nil.
+func (l *LocalRateLimit) ListenerLimit(port uint32) *TokenBucket {
+	if l == nil {
+		return nil
+	}
+	for i := range l.Listeners {
+		if l.Listeners[i].Port == port {
+			return &l.Listeners[i].TokenBucket
+		}
+	}
+	return nil
+}
+
+// RouteLimit returns the token bucket for the given route of a VirtualService, in <name>.<namespace>
+// form, or nil. Limits scoped to the VirtualService take precedence over limits matching the route name only.
+func (l *LocalRateLimit) RouteLimit(route, virtualService string) *TokenBucket {
+	if l == nil || route == "" {
+		return nil
+	}
+	var out *TokenBucket
+	for i := range l.Routes {
+		r := &l.Routes[i]
+		if r.Name != route {
+			continue
+		}
+		if r.VirtualService == virtualService {
+			return &r.TokenBucket
+		}
+		if r.VirtualService == "" && out == nil {
+			out = &r.TokenBucket
+		}
+	}
+	return out
+}
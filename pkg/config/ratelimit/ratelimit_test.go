@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    *LocalRateLimit
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			value: `
+listeners:
+- port: 8080
+  tokenBucket:
+    maxTokens: 100
+    tokensPerFill: 10
+    fillInterval: 1s
+routes:
+- name: reviews
+  tokenBucket:
+    maxTokens: 5
+    fillInterval: 100ms
+`,
+			want: &LocalRateLimit{
+				Listeners: []ListenerRateLimit{{Port: 8080, TokenBucket: TokenBucket{MaxTokens: 100, TokensPerFill: 10, FillInterval: "1s"}}},
+				Routes:    []RouteRateLimit{{Name: "reviews", TokenBucket: TokenBucket{MaxTokens: 5, FillInterval: "100ms"}}},
+			},
+		},
+		{
+			name:  "json",
+			value: `{"listeners":[{"port":9090,"tokenBucket":{"maxTokens":1,"fillInterval":"1m"}}]}`,
+			want: &LocalRateLimit{
+				Listeners: []ListenerRateLimit{{Port: 9090, TokenBucket: TokenBucket{MaxTokens: 1, FillInterval: "1m"}}},
+			},
+		},
+		{
+			name:    "unknown field",
+			value:   `{"listener":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			value:   `{`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() => %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	rl := &LocalRateLimit{
+		Listeners: []ListenerRateLimit{{Port: 8080, TokenBucket: TokenBucket{MaxTokens: 1}}},
+		Routes: []RouteRateLimit{
+			{Name: "a", TokenBucket: TokenBucket{MaxTokens: 2}},
+			{Name: "a", VirtualService: "vs.ns", TokenBucket: TokenBucket{MaxTokens: 3}},
+			{Name: "b", VirtualService: "vs.ns", TokenBucket: TokenBucket{MaxTokens: 4}},
+		},
+	}
+	maxTokens := func(b *TokenBucket) uint32 {
+		if b == nil {
+			return 0
+		}
+		return b.MaxTokens
+	}
+	cases := []struct {
+		name string
+		got  *TokenBucket
+		want uint32
+	}{
+		{"listener", rl.ListenerLimit(8080), 1},
+		{"unknown listener", rl.ListenerLimit(9090), 0},
+		{"route name only", rl.RouteLimit("a", "other.ns"), 2},
+		{"route scoped to virtual service", rl.RouteLimit("a", "vs.ns"), 3},
+		{"route of other virtual service", rl.RouteLimit("b", "other.ns"), 0},
+		{"unnamed route", rl.RouteLimit("", "vs.ns"), 0},
+		{"nil config", (*LocalRateLimit)(nil).RouteLimit("a", "vs.ns"), 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxTokens(tt.got); got != tt.want {
+				t.Errorf("got maxTokens %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := (TokenBucket{FillInterval: "250ms"}).Interval(); got != 250*time.Millisecond {
+		t.Errorf("Interval() => %v, want 250ms", got)
+	}
+}
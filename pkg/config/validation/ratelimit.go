@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	type_beta "istio.io/api/type/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+// Envoy rejects local rate limit token buckets refilled more often than every 50ms.
+const minTokenBucketFillInterval = 50 * time.Millisecond
+
+// ValidateLocalRateLimitConfig checks that a LocalRateLimit resource is well-formed.
+func ValidateLocalRateLimitConfig(cfg config.Config) (Warning, error) {
+	in, ok := cfg.Spec.(*ratelimit.LocalRateLimit)
+	if !ok {
+		return nil, fmt.Errorf("cannot cast to LocalRateLimit")
+	}
+	var errs error
+	if in.WorkloadSelector != nil {
+		errs = appendErrors(errs, validateWorkloadSelector(&type_beta.WorkloadSelector{MatchLabels: in.WorkloadSelector.Labels}))
+	}
+	return nil, appendErrors(errs, ValidateLocalRateLimit(in))
+}
+
+// ValidateLocalRateLimit checks the listener and route limits of a LocalRateLimit.
+func ValidateLocalRateLimit(rl *ratelimit.LocalRateLimit) (errs error) {
+	if rl == nil {
+		return nil
+	}
+	ports := map[uint32]struct{}{}
+	for i, l := range rl.Listeners {
+		if err := ValidatePort(int(l.Port)); err != nil {
+			errs = appendErrors(errs, fmt.Errorf("listeners[%d]: %v", i, err))
+		}
+		if _, f := ports[l.Port]; f {
+			errs = appendErrors(errs, fmt.Errorf("listeners[%d]: duplicate rate limit for port %d", i, l.Port))
+		}
+		ports[l.Port] = struct{}{}
+		if err := validateTokenBucket(l.TokenBucket); err != nil {
+			errs = appendErrors(errs, multierror.Prefix(err, fmt.Sprintf("listeners[%d]:", i)))
+		}
+	}
+	type routeKey struct{ name, virtualService string }
+	routes := map[routeKey]struct{}{}
+	for i, r := range rl.Routes {
+		if r.Name == "" {
+			errs = appendErrors(errs, fmt.Errorf("routes[%d]: route name must not be empty", i))
+		}
+		k := routeKey{r.Name, r.VirtualService}
+		if _, f := routes[k]; f {
+			errs = appendErrors(errs, fmt.Errorf("routes[%d]: duplicate rate limit for route %q", i, r.Name))
+		}
+		routes[k] = struct{}{}
+		if err := validateTokenBucket(r.TokenBucket); err != nil {
+			errs = appendErrors(errs, multierror.Prefix(err, fmt.Sprintf("routes[%d]:", i)))
+		}
+	}
+	return
+}
+
+func validateTokenBucket(b ratelimit.TokenBucket) (errs error) {
+	if b.MaxTokens == 0 {
+		errs = appendErrors(errs, fmt.Errorf("maxTokens must be greater than 0"))
+	}
+	interval, err := time.ParseDuration(b.FillInterval)
+	if err != nil {
+		return appendErrors(errs, fmt.Errorf("invalid fillInterval %q: %v", b.FillInterval, err))
+	}
+	if interval < minTokenBucketFillInterval {
+		errs = appendErrors(errs, fmt.Errorf("fillInterval must be at least %v", minTokenBucketFillInterval))
+	}
+	if interval%time.Millisecond != 0 {
+		errs = appendErrors(errs, fmt.Errorf("only fillInterval to ms precision is supported"))
+	}
+	return
+}
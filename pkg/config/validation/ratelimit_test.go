@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+func TestValidateLocalRateLimit(t *testing.T) {
+	valid := ratelimit.TokenBucket{MaxTokens: 10, TokensPerFill: 5, FillInterval: "1s"}
+	cases := []struct {
+		name  string
+		in    *ratelimit.LocalRateLimit
+		valid bool
+	}{
+		{name: "empty", in: &ratelimit.LocalRateLimit{}, valid: true},
+		{
+			name: "valid",
+			in: &ratelimit.LocalRateLimit{
+				WorkloadSelector: &ratelimit.WorkloadSelector{Labels: map[string]string{"app": "reviews"}},
+				Listeners:        []ratelimit.ListenerRateLimit{{Port: 8080, TokenBucket: valid}},
+				Routes: []ratelimit.RouteRateLimit{
+					{Name: "a", TokenBucket: valid},
+					{Name: "a", VirtualService: "vs.ns", TokenBucket: valid},
+				},
+			},
+			valid: true,
+		},
+		{
+			name: "wildcard selector",
+			in: &ratelimit.LocalRateLimit{
+				WorkloadSelector: &ratelimit.WorkloadSelector{Labels: map[string]string{"app": "*"}},
+			},
+			valid: false,
+		},
+		{
+			name:  "invalid port",
+			in:    &ratelimit.LocalRateLimit{Listeners: []ratelimit.ListenerRateLimit{{Port: 0, TokenBucket: valid}}},
+			valid: false,
+		},
+		{
+			name: "duplicate port",
+			in: &ratelimit.LocalRateLimit{Listeners: []ratelimit.ListenerRateLimit{
+				{Port: 8080, TokenBucket: valid},
+				{Port: 8080, TokenBucket: valid},
+			}},
+			valid: false,
+		},
+		{
+			name:  "missing route name",
+			in:    &ratelimit.LocalRateLimit{Routes: []ratelimit.RouteRateLimit{{TokenBucket: valid}}},
+			valid: false,
+		},
+		{
+			name: "duplicate route",
+			in: &ratelimit.LocalRateLimit{Routes: []ratelimit.RouteRateLimit{
+				{Name: "a", VirtualService: "vs.ns", TokenBucket: valid},
+				{Name: "a", VirtualService: "vs.ns", TokenBucket: valid},
+			}},
+			valid: false,
+		},
+		{
+			name: "zero max tokens",
+			in: &ratelimit.LocalRateLimit{Routes: []ratelimit.RouteRateLimit{
+				{Name: "a", TokenBucket: ratelimit.TokenBucket{FillInterval: "1s"}},
+			}},
+			valid: false,
+		},
+		{
+			name: "missing fill interval",
+			in: &ratelimit.LocalRateLimit{Routes: []ratelimit.RouteRateLimit{
+				{Name: "a", TokenBucket: ratelimit.TokenBucket{MaxTokens: 1}},
+			}},
+			valid: false,
+		},
+		{
+			name: "fill interval too short",
+			in: &ratelimit.LocalRateLimit{Listeners: []ratelimit.ListenerRateLimit{
+				{Port: 80, TokenBucket: ratelimit.TokenBucket{MaxTokens: 1, FillInterval: "10ms"}},
+			}},
+			valid: false,
+		},
+		{
+			name: "fill interval below ms precision",
+			in: &ratelimit.LocalRateLimit{Listeners: []ratelimit.ListenerRateLimit{
+				{Port: 80, TokenBucket: ratelimit.TokenBucket{MaxTokens: 1, FillInterval: "100500us"}},
+			}},
+			valid: false,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateLocalRateLimitConfig(config.Config{Spec: tt.in})
+			if gotValid := err == nil; gotValid != tt.valid {
+				t.Errorf("ValidateLocalRateLimitConfig() valid = %v, want %v: %v", gotValid, tt.valid, err)
+			}
+		})
+	}
+}
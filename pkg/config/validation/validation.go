@@ -680,6 +680,9 @@ var ValidateEnvoyFilter = registerValidateFunc("ValidateEnvoyFilter",
 		if err := validateAlphaWorkloadSelector(rule.WorkloadSelector); err != nil {
 			return nil, err
 		}
+		if _, err := xds.EnvoyFilterPriority(cfg.Annotations); err != nil {
+			errs = appendValidation(errs, err)
+		}
 
 		for _, cp := range rule.ConfigPatches {
 			if cp == nil {
@@ -703,6 +706,20 @@ var ValidateEnvoyFilter = registerValidateFunc("ValidateEnvoyFilter",
 				continue
 			}
 
+			if cp.Patch.FilterClass != networking.EnvoyFilter_Patch_UNSPECIFIED {
+				if cp.ApplyTo != networking.EnvoyFilter_HTTP_FILTER {
+					errs = appendValidation(errs, fmt.Errorf("Envoy filter: filterClass can be used with applyTo HTTP_FILTER only")) // nolint: golint,stylecheck
+					continue
+				}
+				if cp.Patch.Operation != networking.EnvoyFilter_Patch_ADD &&
+					cp.Patch.Operation != networking.EnvoyFilter_Patch_INSERT_FIRST &&
+					cp.Patch.Operation != networking.EnvoyFilter_Patch_INSERT_BEFORE &&
+					cp.Patch.Operation != networking.EnvoyFilter_Patch_INSERT_AFTER {
+					errs = appendValidation(errs, fmt.Errorf("Envoy filter: filterClass can be used with add and insert operations only")) // nolint: golint,stylecheck
+					continue
+				}
+			}
+
 			// ensure that the supplied regex for proxy version compiles
 			if cp.Match != nil && cp.Match.Proxy != nil && cp.Match.Proxy.ProxyVersion != "" {
 				if _, err := regexp.Compile(cp.Match.Proxy.ProxyVersion); err != nil {
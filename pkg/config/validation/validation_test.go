@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/xds"
 )
 
 const (
@@ -3334,10 +3335,11 @@ func TestValidateOutlierDetection(t *testing.T) {
 
 func TestValidateEnvoyFilter(t *testing.T) {
 	tests := []struct {
-		name    string
-		in      proto.Message
-		error   string
-		warning string
+		name        string
+		in          proto.Message
+		annotations map[string]string
+		error       string
+		warning     string
 	}{
 		{name: "empty filters", in: &networking.EnvoyFilter{}, error: ""},
 
@@ -3695,13 +3697,52 @@ func TestValidateEnvoyFilter(t *testing.T) {
 				},
 			},
 		}, error: "", warning: "using deprecated filter name"},
+		{name: "filter class", in: &networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+					Patch: &networking.EnvoyFilter_Patch{
+						Operation:   networking.EnvoyFilter_Patch_INSERT_FIRST,
+						Value:       &types.Struct{},
+						FilterClass: networking.EnvoyFilter_Patch_AUTHZ,
+					},
+				},
+			},
+		}, annotations: map[string]string{xds.EnvoyFilterPriorityAnnotation: "-10"}, error: ""},
+		{name: "filter class with network filter", in: &networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: networking.EnvoyFilter_NETWORK_FILTER,
+					Patch: &networking.EnvoyFilter_Patch{
+						Operation:   networking.EnvoyFilter_Patch_INSERT_FIRST,
+						Value:       &types.Struct{},
+						FilterClass: networking.EnvoyFilter_Patch_AUTHZ,
+					},
+				},
+			},
+		}, error: "filterClass can be used with applyTo HTTP_FILTER only"},
+		{name: "filter class with merge", in: &networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+					Patch: &networking.EnvoyFilter_Patch{
+						Operation:   networking.EnvoyFilter_Patch_MERGE,
+						Value:       &types.Struct{},
+						FilterClass: networking.EnvoyFilter_Patch_STATS,
+					},
+				},
+			},
+		}, error: "filterClass can be used with add and insert operations only"},
+		{name: "invalid priority", in: &networking.EnvoyFilter{},
+			annotations: map[string]string{xds.EnvoyFilterPriorityAnnotation: "high"}, error: "invalid networking.istio.io/filterPriority annotation"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			warn, err := ValidateEnvoyFilter(config.Config{
 				Meta: config.Meta{
-					Name:      someName,
-					Namespace: someNamespace,
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: tt.annotations,
 				},
 				Spec: tt.in,
 			})
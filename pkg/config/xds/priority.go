@@ -0,0 +1,37 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// EnvoyFilterPriorityAnnotation orders the HTTP filters that EnvoyFilters insert with a filter class.
+// Within a phase, the filters of EnvoyFilters with a lower priority run first. The default priority is 0.
+const EnvoyFilterPriorityAnnotation = "networking.istio.io/filterPriority"
+
+// EnvoyFilterPriority returns the priority of an EnvoyFilter from its annotations.
+func EnvoyFilterPriority(annotations map[string]string) (int32, error) {
+	v, f := annotations[EnvoyFilterPriorityAnnotation]
+	if !f {
+		return 0, nil
+	}
+	p, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation %q: must be a 32 bit integer", EnvoyFilterPriorityAnnotation, v)
+	}
+	return int32(p), nil
+}
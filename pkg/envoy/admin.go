@@ -15,10 +15,13 @@
 package envoy
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
@@ -77,6 +80,207 @@ func GetConfigDump(adminPort uint32) (*envoyAdmin.ConfigDump, error) {
 	return msg, nil
 }
 
+// ClusterStatsPath is the admin path returning the total upstream requests and connections of each cluster.
+var ClusterStatsPath = "stats?filter=" + url.QueryEscape(`^cluster\..*\.upstream_(rq|cx)_total$`)
+
+// ClusterTraffic is the traffic sent by Envoy to an upstream cluster since it started.
+type ClusterTraffic struct {
+	RequestsTotal    uint64
+	ConnectionsTotal uint64
+}
+
+// GetClusterStats polls Envoy admin port for the upstream traffic of each cluster, keyed by cluster name.
+func GetClusterStats(adminPort uint32) (map[string]ClusterTraffic, error) {
+	buffer, err := doEnvoyGet(ClusterStatsPath, adminPort)
+	if err != nil {
+		return nil, err
+	}
+	return ParseClusterStats(buffer.String())
+}
+
+// ParseClusterStats parses the upstream traffic of each cluster from Envoy stats in text format, keyed
+// by cluster name. Stats other than the upstream request and connection totals are ignored.
+func ParseClusterStats(stats string) (map[string]ClusterTraffic, error) {
+	out := map[string]ClusterTraffic{}
+	scanner := bufio.NewScanner(strings.NewReader(stats))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "cluster.") {
+			continue
+		}
+		sep := strings.LastIndex(line, ": ")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid stat %q", line)
+		}
+		name, value := line[:sep], line[sep+2:]
+		var counter func(t *ClusterTraffic) *uint64
+		switch {
+		case strings.HasSuffix(name, ".upstream_rq_total"):
+			name = strings.TrimSuffix(name, ".upstream_rq_total")
+			counter = func(t *ClusterTraffic) *uint64 { return &t.RequestsTotal }
+		case strings.HasSuffix(name, ".upstream_cx_total"):
+			name = strings.TrimSuffix(name, ".upstream_cx_total")
+			counter = func(t *ClusterTraffic) *uint64 { return &t.ConnectionsTotal }
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of stat %q: %v", line, err)
+		}
+		cluster := strings.TrimPrefix(name, "cluster.")
+		t := out[cluster]
+		*counter(&t) = v
+		out[cluster] = t
+	}
+	return out, scanner.Err()
+}
+
+// ConnectionPoolStatsPath is the admin path returning the usage of the connection pools of the outbound clusters.
+var ConnectionPoolStatsPath = "stats?filter=" + url.QueryEscape(
+	`^cluster\.outbound\|.*\.upstream_(rq_active|rq_pending_active|rq_pending_overflow|cx_active|cx_overflow)$`)
+
+// ConnectionPoolUsage is the usage of the connection pool of an upstream cluster. The overflows are the
+// requests and connections rejected by the circuit breakers since Envoy started.
+type ConnectionPoolUsage struct {
+	ActiveRequests      uint64
+	PendingRequests     uint64
+	ActiveConnections   uint64
+	RequestOverflows    uint64
+	ConnectionOverflows uint64
+}
+
+// GetConnectionPoolUsage polls Envoy admin port for the connection pool usage of each outbound cluster, keyed by
+// cluster name.
+func GetConnectionPoolUsage(adminPort uint32) (map[string]ConnectionPoolUsage, error) {
+	buffer, err := doEnvoyGet(ConnectionPoolStatsPath, adminPort)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConnectionPoolUsage(buffer.String())
+}
+
+// ParseConnectionPoolUsage parses the connection pool usage of each cluster from Envoy stats in text format,
+// keyed by cluster name. Other stats are ignored.
+func ParseConnectionPoolUsage(stats string) (map[string]ConnectionPoolUsage, error) {
+	out := map[string]ConnectionPoolUsage{}
+	scanner := bufio.NewScanner(strings.NewReader(stats))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "cluster.") {
+			continue
+		}
+		sep := strings.LastIndex(line, ": ")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid stat %q", line)
+		}
+		name, value := line[:sep], line[sep+2:]
+		dot := strings.LastIndex(name, ".")
+		var counter func(u *ConnectionPoolUsage) *uint64
+		switch name[dot+1:] {
+		case "upstream_rq_active":
+			counter = func(u *ConnectionPoolUsage) *uint64 { return &u.ActiveRequests }
+		case "upstream_rq_pending_active":
+			counter = func(u *ConnectionPoolUsage) *uint64 { return &u.PendingRequests }
+		case "upstream_cx_active":
+			counter = func(u *ConnectionPoolUsage) *uint64 { return &u.ActiveConnections }
+		case "upstream_rq_pending_overflow":
+			counter = func(u *ConnectionPoolUsage) *uint64 { return &u.RequestOverflows }
+		case "upstream_cx_overflow":
+			counter = func(u *ConnectionPoolUsage) *uint64 { return &u.ConnectionOverflows }
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of stat %q: %v", line, err)
+		}
+		cluster := strings.TrimPrefix(name[:dot], "cluster.")
+		u := out[cluster]
+		*counter(&u) = v
+		out[cluster] = u
+	}
+	return out, scanner.Err()
+}
+
+// DownstreamStatsPath is the admin path returning the active connections of each listener and the
+// active requests of each HTTP connection manager.
+var DownstreamStatsPath = "stats?filter=" +
+	url.QueryEscape(`^(listener\..*\.downstream_cx_active|http\..*\.downstream_rq_active)$`)
+
+// drainIgnoredStats are the listeners and HTTP connection managers of the admin interface and
+// of the Prometheus and health check endpoints. Their traffic does not hold up the drain, in
+// particular the admin request polling the stats is always active.
+var drainIgnoredStats = []string{"listener.admin.", "http.admin.", "http.stats.", "http.agent."}
+
+// drainIgnoredListenerPorts are the ports of the Prometheus and health check listeners, whose
+// listener stats are named after their address.
+var drainIgnoredListenerPorts = []string{"_15090.", "_15021."}
+
+// DownstreamTraffic is the traffic Envoy is serving to downstream clients.
+type DownstreamTraffic struct {
+	ActiveConnections uint64
+	ActiveRequests    uint64
+}
+
+// Idle returns true if there are no active downstream connections or requests.
+func (t DownstreamTraffic) Idle() bool {
+	return t.ActiveConnections == 0 && t.ActiveRequests == 0
+}
+
+// GetDownstreamTraffic polls Envoy admin port for the downstream connections and requests still active.
+func GetDownstreamTraffic(adminPort uint32) (DownstreamTraffic, error) {
+	buffer, err := doEnvoyGet(DownstreamStatsPath, adminPort)
+	if err != nil {
+		return DownstreamTraffic{}, err
+	}
+	return ParseDownstreamTraffic(buffer.String())
+}
+
+// ParseDownstreamTraffic sums the active downstream connections and requests from Envoy stats in
+// text format, ignoring the traffic of the admin, Prometheus and health check endpoints.
+func ParseDownstreamTraffic(stats string) (DownstreamTraffic, error) {
+	out := DownstreamTraffic{}
+	scanner := bufio.NewScanner(strings.NewReader(stats))
+stats:
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sep := strings.LastIndex(line, ": ")
+		if sep < 0 {
+			return DownstreamTraffic{}, fmt.Errorf("invalid stat %q", line)
+		}
+		name, value := line[:sep], line[sep+2:]
+		for _, ignored := range drainIgnoredStats {
+			if strings.HasPrefix(name, ignored) {
+				continue stats
+			}
+		}
+		var counter *uint64
+		switch {
+		case strings.HasPrefix(name, "listener.") && strings.HasSuffix(name, ".downstream_cx_active"):
+			for _, port := range drainIgnoredListenerPorts {
+				if strings.HasSuffix(strings.TrimSuffix(name, "downstream_cx_active"), port) {
+					continue stats
+				}
+			}
+			counter = &out.ActiveConnections
+		case strings.HasPrefix(name, "http.") && strings.HasSuffix(name, ".downstream_rq_active"):
+			counter = &out.ActiveRequests
+		default:
+			continue
+		}
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return DownstreamTraffic{}, fmt.Errorf("invalid value of stat %q: %v", line, err)
+		}
+		*counter += v
+	}
+	return out, scanner.Err()
+}
+
 func doEnvoyGet(path string, adminPort uint32) (*bytes.Buffer, error) {
 	requestURL := fmt.Sprintf("http://127.0.0.1:%d/%s", adminPort, path)
 	buffer, err := doHTTPGet(requestURL)
@@ -0,0 +1,143 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClusterStats(t *testing.T) {
+	cases := []struct {
+		name    string
+		stats   string
+		want    map[string]ClusterTraffic
+		wantErr bool
+	}{
+		{
+			name: "clusters",
+			stats: `cluster.BlackHoleCluster.upstream_cx_total: 0
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_cx_total: 2
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_rq_total: 10
+cluster.outbound|80|v1|httpbin.org.upstream_rq_total: 1
+cluster.outbound|80|v1|httpbin.org.upstream_rq_timeout: 1
+server.uptime: 100
+`,
+			want: map[string]ClusterTraffic{
+				"BlackHoleCluster": {},
+				"outbound|9080||reviews.default.svc.cluster.local": {RequestsTotal: 10, ConnectionsTotal: 2},
+				"outbound|80|v1|httpbin.org":                       {RequestsTotal: 1},
+			},
+		},
+		{
+			name:  "empty",
+			stats: "",
+			want:  map[string]ClusterTraffic{},
+		},
+		{
+			name:    "invalid value",
+			stats:   "cluster.a.upstream_rq_total: many\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClusterStats(tt.stats)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClusterStats() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClusterStats() => %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDownstreamTraffic(t *testing.T) {
+	cases := []struct {
+		name    string
+		stats   string
+		want    DownstreamTraffic
+		wantErr bool
+	}{
+		{
+			name: "listeners and connection managers",
+			stats: `http.admin.downstream_rq_active: 1
+http.agent.downstream_rq_active: 1
+http.inbound_0.0.0.0_8080.downstream_rq_active: 3
+http.outbound_0.0.0.0_80.downstream_rq_active: 1
+listener.0.0.0.0_15090.downstream_cx_active: 4
+listener.[__]_15021.downstream_cx_active: 1
+listener.10.0.0.1_8080.downstream_cx_active: 2
+listener.admin.downstream_cx_active: 1
+listener.0.0.0.0_15006.downstream_cx_active: 5
+`,
+			want: DownstreamTraffic{ActiveConnections: 7, ActiveRequests: 4},
+		},
+		{
+			name:  "only admin traffic",
+			stats: "http.admin.downstream_rq_active: 1\nlistener.admin.downstream_cx_active: 1\n",
+			want:  DownstreamTraffic{},
+		},
+		{
+			name:    "invalid value",
+			stats:   "listener.0.0.0.0_15001.downstream_cx_active: many\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDownstreamTraffic(tt.stats)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDownstreamTraffic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseDownstreamTraffic() => %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConnectionPoolUsage(t *testing.T) {
+	stats := `cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_rq_active: 12
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_rq_pending_active: 3
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_rq_pending_overflow: 7
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_cx_active: 4
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_cx_overflow: 1
+cluster.outbound|9080||reviews.default.svc.cluster.local.upstream_rq_total: 100
+cluster.outbound|80||httpbin.org.upstream_cx_active: 2
+server.uptime: 100
+`
+	want := map[string]ConnectionPoolUsage{
+		"outbound|9080||reviews.default.svc.cluster.local": {
+			ActiveRequests:      12,
+			PendingRequests:     3,
+			ActiveConnections:   4,
+			RequestOverflows:    7,
+			ConnectionOverflows: 1,
+		},
+		"outbound|80||httpbin.org": {ActiveConnections: 2},
+	}
+	got, err := ParseConnectionPoolUsage(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConnectionPoolUsage() => %v, want %v", got, want)
+	}
+	if _, err := ParseConnectionPoolUsage("cluster.a.upstream_cx_active: many\n"); err == nil {
+		t.Errorf("ParseConnectionPoolUsage() succeeded with an invalid value")
+	}
+}
@@ -17,6 +17,7 @@ package envoy
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"istio.io/pkg/log"
@@ -26,16 +27,31 @@ var errAbort = errors.New("epoch aborted")
 
 const errOutOfMemory = "signal: killed"
 
+// defaultDrainPollInterval is how often the proxy traffic is polled while draining.
+const defaultDrainPollInterval = time.Second
+
 // NewAgent creates a new proxy agent for the proxy start-up and clean-up functions.
-func NewAgent(proxy Proxy, terminationDrainDuration time.Duration) *Agent {
+// On termination the proxy is drained for at least minDrainDuration and at most
+// terminationDrainDuration, ending as soon as it has no downstream traffic left if the proxy is a
+// TrafficReporter.
+func NewAgent(proxy Proxy, terminationDrainDuration, minDrainDuration time.Duration) *Agent {
 	return &Agent{
 		proxy:                    proxy,
 		statusCh:                 make(chan exitStatus),
 		abortCh:                  make(chan error, 1),
 		terminationDrainDuration: terminationDrainDuration,
+		minDrainDuration:         minDrainDuration,
+		drainPollInterval:        defaultDrainPollInterval,
+		supervisor:               &supervisor{},
 	}
 }
 
+// SetRestartPolicy enables restarting the proxy when it exits with an error. It must be called
+// before Run.
+func (a *Agent) SetRestartPolicy(policy RestartPolicy) {
+	a.supervisor = &supervisor{policy: policy}
+}
+
 // Proxy defines command interface for a proxy
 type Proxy interface {
 
@@ -49,6 +65,12 @@ type Proxy interface {
 	Cleanup(int)
 }
 
+// TrafficReporter is implemented by proxies reporting the traffic they serve, which lets the
+// agent end the drain as soon as it has completed.
+type TrafficReporter interface {
+	DownstreamTraffic() (DownstreamTraffic, error)
+}
+
 type Agent struct {
 	// proxy commands
 	proxy Proxy
@@ -60,6 +82,35 @@ type Agent struct {
 
 	// time to allow for the proxy to drain before terminating all remaining proxy processes
 	terminationDrainDuration time.Duration
+
+	// time the proxy drains for at least, before the agent checks whether traffic is still flowing
+	minDrainDuration time.Duration
+
+	drainPollInterval time.Duration
+
+	drainMutex  sync.RWMutex
+	drainStatus DrainStatus
+
+	// supervisor restarts the proxy when it exits with an error, and keeps crash reports
+	supervisor *supervisor
+}
+
+// DrainStatus is the progress of the proxy drain on termination.
+type DrainStatus struct {
+	// Draining is true once termination has started.
+	Draining bool `json:"draining"`
+	// Complete is true once the drain has ended and remaining proxies are terminated.
+	Complete bool `json:"complete"`
+	// StartTime is when the drain started.
+	StartTime time.Time `json:"startTime,omitempty"`
+	// MinDuration and MaxDuration bound how long the drain lasts.
+	MinDuration string `json:"minDuration,omitempty"`
+	MaxDuration string `json:"maxDuration,omitempty"`
+	// ActiveConnections and ActiveRequests are the downstream traffic at the last poll.
+	ActiveConnections uint64 `json:"activeConnections"`
+	ActiveRequests    uint64 `json:"activeRequests"`
+	// Error is the error of the last poll of the proxy traffic, if it failed.
+	Error string `json:"error,omitempty"`
 }
 
 type exitStatus struct {
@@ -67,44 +118,149 @@ type exitStatus struct {
 	err   error
 }
 
-// Run starts the envoy and waits until it terminates.
+// Run starts the envoy and waits until it terminates. If a restart policy is set, the proxy is
+// restarted with a new epoch when it exits with an error, until the restart budget is exhausted.
 func (a *Agent) Run(ctx context.Context) error {
 	log.Info("Starting proxy agent")
-	go a.runWait(0, a.abortCh)
+	epoch := 0
+	start := time.Now()
+	go a.runWait(epoch, a.abortCh)
 
-	select {
-	case status := <-a.statusCh:
-		if status.err != nil {
-			if status.err.Error() == errOutOfMemory {
-				log.Warnf("Envoy may have been out of memory killed. Check memory usage and limits.")
+	for {
+		select {
+		case status := <-a.statusCh:
+			if status.err != nil {
+				if status.err.Error() == errOutOfMemory {
+					log.Warnf("Envoy may have been out of memory killed. Check memory usage and limits.")
+				}
+				log.Errorf("Epoch %d exited with error: %v", status.epoch, status.err)
+			} else {
+				log.Infof("Epoch %d exited normally", status.epoch)
 			}
-			log.Errorf("Epoch %d exited with error: %v", status.epoch, status.err)
-		} else {
-			log.Infof("Epoch %d exited normally", status.epoch)
-		}
 
-		log.Infof("No more active epochs, terminating")
-		return nil
-	case <-ctx.Done():
-		a.terminate()
-		log.Info("Agent has successfully terminated")
-		return nil
+			delay, restart := a.supervisor.exited(status, start)
+			if !restart {
+				if a.supervisor.status().GaveUp {
+					log.Errorf("Proxy exited with an error after %d consecutive restarts, giving up", a.supervisor.policy.MaxRestarts)
+				}
+				log.Infof("No more active epochs, terminating")
+				return nil
+			}
+			log.Warnf("Restarting proxy in %v", delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				// No proxy is running, there is nothing to drain.
+				log.Info("Agent has successfully terminated")
+				return nil
+			}
+			epoch++
+			start = time.Now()
+			a.supervisor.restarted()
+			go a.runWait(epoch, a.abortCh)
+		case <-ctx.Done():
+			a.terminate()
+			log.Info("Agent has successfully terminated")
+			return nil
+		}
 	}
 }
 
+// SupervisorStatus returns the restarts and crash reports of the proxy.
+func (a *Agent) SupervisorStatus() SupervisorStatus {
+	return a.supervisor.status()
+}
+
+// DrainStatus returns the progress of the proxy drain.
+func (a *Agent) DrainStatus() DrainStatus {
+	a.drainMutex.RLock()
+	defer a.drainMutex.RUnlock()
+	return a.drainStatus
+}
+
+func (a *Agent) updateDrainStatus(update func(s *DrainStatus)) {
+	a.drainMutex.Lock()
+	defer a.drainMutex.Unlock()
+	update(&a.drainStatus)
+}
+
 func (a *Agent) terminate() {
 	log.Infof("Agent draining Proxy")
 	e := a.proxy.Drain()
 	if e != nil {
 		log.Warnf("Error in invoking drain listeners endpoint %v", e)
 	}
-	log.Infof("Graceful termination period is %v, starting...", a.terminationDrainDuration)
-	time.Sleep(a.terminationDrainDuration)
+	a.waitForDrain()
+	a.updateDrainStatus(func(s *DrainStatus) { s.Complete = true })
 	log.Infof("Graceful termination period complete, terminating remaining proxies.")
 	a.abortCh <- errAbort
 	log.Warnf("Aborted all epochs")
 }
 
+// waitForDrain waits for the minimum drain duration, then until the proxy has no downstream
+// traffic left or the termination drain duration has elapsed. Proxies that do not report their
+// traffic are drained for the termination drain duration.
+func (a *Agent) waitForDrain() {
+	start := time.Now()
+	maxDuration := a.terminationDrainDuration
+	minDuration := a.minDrainDuration
+	if minDuration > maxDuration {
+		minDuration = maxDuration
+	}
+	reporter, adaptive := a.proxy.(TrafficReporter)
+	if !adaptive {
+		minDuration = maxDuration
+	}
+	a.updateDrainStatus(func(s *DrainStatus) {
+		s.Draining = true
+		s.StartTime = start
+		s.MinDuration = minDuration.String()
+		s.MaxDuration = maxDuration.String()
+	})
+	if !adaptive {
+		log.Infof("Graceful termination period is %v, starting...", maxDuration)
+		time.Sleep(maxDuration)
+		return
+	}
+
+	log.Infof("Graceful termination period is %v to %v depending on active traffic, starting...", minDuration, maxDuration)
+	time.Sleep(minDuration)
+	var last DownstreamTraffic
+	for {
+		traffic, err := reporter.DownstreamTraffic()
+		a.updateDrainStatus(func(s *DrainStatus) {
+			s.ActiveConnections, s.ActiveRequests = traffic.ActiveConnections, traffic.ActiveRequests
+			s.Error = ""
+			if err != nil {
+				s.Error = err.Error()
+			}
+		})
+		elapsed := time.Since(start)
+		switch {
+		case err != nil:
+			// Without stats, assume traffic is still flowing until the deadline.
+			log.Warnf("Failed to get proxy traffic while draining: %v", err)
+		case traffic.Idle():
+			log.Infof("Proxy has no active downstream connections or requests after %v", elapsed.Round(time.Millisecond))
+			return
+		case traffic != last:
+			log.Infof("Draining proxy: %d active downstream connections, %d active requests after %v",
+				traffic.ActiveConnections, traffic.ActiveRequests, elapsed.Round(time.Millisecond))
+			last = traffic
+		}
+		if elapsed >= maxDuration {
+			log.Warnf("Graceful termination period of %v elapsed with %d active downstream connections and %d active requests",
+				maxDuration, traffic.ActiveConnections, traffic.ActiveRequests)
+			return
+		}
+		wait := a.drainPollInterval
+		if remaining := maxDuration - elapsed; remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+	}
+}
+
 // runWait runs the start-up command as a go routine and waits for it to finish
 func (a *Agent) runWait(epoch int, abortCh <-chan error) {
 	log.Infof("Epoch %d starting", epoch)
@@ -16,6 +16,7 @@ package envoy
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 )
@@ -49,7 +50,7 @@ func (tp TestProxy) Cleanup(epoch int) {
 func TestStartExit(t *testing.T) {
 	ctx := context.Background()
 	done := make(chan struct{})
-	a := NewAgent(TestProxy{}, 0)
+	a := NewAgent(TestProxy{}, 0, 0)
 	go func() {
 		_ = a.Run(ctx)
 		done <- struct{}{}
@@ -80,7 +81,7 @@ func TestStartDrain(t *testing.T) {
 		}
 		return nil
 	}
-	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, -10*time.Second)
+	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, -10*time.Second, 0)
 	go func() { _ = a.Run(ctx) }()
 	<-blockChan
 	cancel()
@@ -103,7 +104,7 @@ func TestStartStop(t *testing.T) {
 			cancel()
 		}
 	}
-	a := NewAgent(TestProxy{run: start, cleanup: cleanup}, 0)
+	a := NewAgent(TestProxy{run: start, cleanup: cleanup}, 0, 0)
 	go func() { _ = a.Run(ctx) }()
 	<-ctx.Done()
 }
@@ -123,10 +124,126 @@ func TestRecovery(t *testing.T) {
 		<-ctx.Done()
 		return nil
 	}
-	a := NewAgent(TestProxy{run: start}, 0)
+	a := NewAgent(TestProxy{run: start}, 0, 0)
 	go func() { _ = a.Run(ctx) }()
 
 	// make sure we don't try to reconcile twice
 	<-time.After(100 * time.Millisecond)
 	cancel()
 }
+
+// trafficProxy reports a scripted sequence of downstream traffic, repeating the last entry.
+type trafficProxy struct {
+	TestProxy
+	mu      sync.Mutex
+	traffic []DownstreamTraffic
+	polls   int
+}
+
+func (tp *trafficProxy) Drain() error {
+	return nil
+}
+
+func (tp *trafficProxy) DownstreamTraffic() (DownstreamTraffic, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	i := tp.polls
+	if i >= len(tp.traffic) {
+		i = len(tp.traffic) - 1
+	}
+	tp.polls++
+	return tp.traffic[i], nil
+}
+
+func TestWaitForDrain(t *testing.T) {
+	busy := DownstreamTraffic{ActiveConnections: 2, ActiveRequests: 1}
+	cases := []struct {
+		name        string
+		traffic     []DownstreamTraffic
+		min, max    time.Duration
+		wantMin     time.Duration
+		wantMax     time.Duration
+		wantPolls   int
+		wantTraffic DownstreamTraffic
+	}{
+		{
+			name:      "idle exits after minimum",
+			traffic:   []DownstreamTraffic{{}},
+			min:       50 * time.Millisecond,
+			max:       10 * time.Second,
+			wantMin:   50 * time.Millisecond,
+			wantMax:   2 * time.Second,
+			wantPolls: 1,
+		},
+		{
+			name:      "exits once traffic stops",
+			traffic:   []DownstreamTraffic{busy, busy, {}},
+			min:       0,
+			max:       10 * time.Second,
+			wantMin:   20 * time.Millisecond,
+			wantMax:   2 * time.Second,
+			wantPolls: 3,
+		},
+		{
+			name:        "deadline with active traffic",
+			traffic:     []DownstreamTraffic{busy},
+			min:         0,
+			max:         100 * time.Millisecond,
+			wantMin:     100 * time.Millisecond,
+			wantMax:     2 * time.Second,
+			wantTraffic: busy,
+		},
+		{
+			name:      "minimum capped by maximum",
+			traffic:   []DownstreamTraffic{{}},
+			min:       10 * time.Second,
+			max:       50 * time.Millisecond,
+			wantMin:   50 * time.Millisecond,
+			wantMax:   2 * time.Second,
+			wantPolls: 1,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &trafficProxy{traffic: tt.traffic}
+			a := NewAgent(proxy, tt.max, tt.min)
+			a.drainPollInterval = 10 * time.Millisecond
+			start := time.Now()
+			a.waitForDrain()
+			elapsed := time.Since(start)
+			if elapsed < tt.wantMin || elapsed > tt.wantMax {
+				t.Errorf("drain took %v, want between %v and %v", elapsed, tt.wantMin, tt.wantMax)
+			}
+			if tt.wantPolls > 0 && proxy.polls != tt.wantPolls {
+				t.Errorf("traffic polled %d times, want %d", proxy.polls, tt.wantPolls)
+			}
+			status := a.DrainStatus()
+			if !status.Draining || status.ActiveConnections != tt.wantTraffic.ActiveConnections ||
+				status.ActiveRequests != tt.wantTraffic.ActiveRequests {
+				t.Errorf("drain status = %+v, want draining with %+v", status, tt.wantTraffic)
+			}
+		})
+	}
+}
+
+func TestDrainStatusComplete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	proxy := &trafficProxy{
+		TestProxy: TestProxy{run: func(_ int, abort <-chan error) error { return <-abort }},
+		traffic:   []DownstreamTraffic{{}},
+	}
+	a := NewAgent(proxy, time.Second, 0)
+	if a.DrainStatus().Draining {
+		t.Fatalf("agent is draining before termination")
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = a.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+	if status := a.DrainStatus(); !status.Draining || !status.Complete {
+		t.Errorf("drain status = %+v, want complete", status)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import "istio.io/pkg/monitoring"
+
+var (
+	reasonTag = monitoring.MustCreateLabel("reason")
+
+	proxyCrashes = monitoring.NewSum(
+		"proxy_crashes_total",
+		"The total number of times the proxy exited with an error, by reason: exit, signal or oom.",
+		monitoring.WithLabels(reasonTag),
+	)
+
+	proxyRestarts = monitoring.NewSum(
+		"proxy_restarts_total",
+		"The total number of times the proxy was restarted by the agent.",
+	)
+
+	proxyConsecutiveRestarts = monitoring.NewGauge(
+		"proxy_consecutive_restarts",
+		"The number of consecutive restarts of the proxy, counted against the restart budget.",
+	)
+
+	proxyRestartBudgetExhausted = monitoring.NewGauge(
+		"proxy_restart_budget_exhausted",
+		"Set to 1 once the proxy crash looped beyond the restart budget and the agent gave up.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		proxyCrashes,
+		proxyRestarts,
+		proxyConsecutiveRestarts,
+		proxyRestartBudgetExhausted,
+	)
+}
@@ -16,6 +16,7 @@ package envoy
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
@@ -77,6 +78,11 @@ func (e *envoy) Drain() error {
 	return err
 }
 
+// DownstreamTraffic returns the downstream connections and requests Envoy is still serving.
+func (e *envoy) DownstreamTraffic() (DownstreamTraffic, error) {
+	return GetDownstreamTraffic(uint32(e.Metadata.ProxyConfig.ProxyAdminPort))
+}
+
 func (e *envoy) args(fname string, epoch int, bootstrapConfig string) []string {
 	proxyLocalAddressType := "v4"
 	if isIPv6Proxy(e.NodeIPs) {
@@ -152,7 +158,9 @@ func (e *envoy) Run(epoch int, abort <-chan error) error {
 	/* #nosec */
 	cmd := exec.Command(config.BinaryPath, args...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Keep the last lines of stderr, where Envoy logs why it exits, for crash reports.
+	stderr := newLineRing(crashLogLines)
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
 	if err := cmd.Start(); err != nil {
 		return err
 	}
@@ -169,7 +177,10 @@ func (e *envoy) Run(epoch int, abort <-chan error) error {
 		}
 		return err
 	case err := <-done:
-		return err
+		if err != nil {
+			return &ExitError{Err: err, Stderr: stderr.Lines()}
+		}
+		return nil
 	}
 }
 
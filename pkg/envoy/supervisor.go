@@ -0,0 +1,254 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxCrashReports is the number of crash reports kept, most recent first.
+	maxCrashReports = 10
+
+	// crashLogLines is the number of lines of proxy stderr kept for crash reports.
+	crashLogLines = 50
+)
+
+// RestartPolicy configures the supervisor restarting the proxy when it exits with an error.
+type RestartPolicy struct {
+	// MaxRestarts is the restart budget: after this many consecutive restarts the agent gives
+	// up and exits. Zero disables restarts.
+	MaxRestarts int
+
+	// InitialBackoff is the delay before the first restart, doubled for each consecutive one.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between restarts.
+	MaxBackoff time.Duration
+
+	// ResetAfter is how long an epoch must run for the next exit not to count as consecutive,
+	// resetting the backoff and the restart budget.
+	ResetAfter time.Duration
+}
+
+// CrashReport describes how an epoch of the proxy exited.
+type CrashReport struct {
+	Epoch int       `json:"epoch"`
+	Time  time.Time `json:"time"`
+	// Uptime is how long the epoch ran for.
+	Uptime string `json:"uptime"`
+	Error  string `json:"error"`
+	// ExitCode is the exit code of the proxy, or -1 if it was killed by a signal.
+	ExitCode int    `json:"exitCode"`
+	Signal   string `json:"signal,omitempty"`
+	// OOMSuspected is set when the proxy was killed by SIGKILL, which is how the kernel
+	// terminates processes exceeding their memory limit.
+	OOMSuspected bool `json:"oomSuspected"`
+	// Stderr is the last lines the proxy wrote to stderr.
+	Stderr []string `json:"stderr,omitempty"`
+}
+
+// SupervisorStatus is the state of the proxy supervisor, served by the status server.
+type SupervisorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Restarts is the number of consecutive restarts, counted against MaxRestarts.
+	Restarts    int `json:"restarts"`
+	MaxRestarts int `json:"maxRestarts"`
+	// TotalRestarts is the number of restarts since the agent started.
+	TotalRestarts int `json:"totalRestarts"`
+	// NextRestart is when the proxy is restarted, while it is backing off.
+	NextRestart *time.Time `json:"nextRestart,omitempty"`
+	// GaveUp is set once the restart budget is exhausted.
+	GaveUp bool `json:"gaveUp"`
+	// Crashes are the most recent crash reports, most recent first.
+	Crashes []CrashReport `json:"crashes"`
+}
+
+// ExitError is returned by Proxy.Run when the proxy process exits with an error. It keeps the
+// tail of the proxy stderr for crash reports.
+type ExitError struct {
+	Err    error
+	Stderr []string
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// supervisor decides whether and when to restart the proxy, and keeps crash reports.
+type supervisor struct {
+	policy RestartPolicy
+
+	mu           sync.RWMutex
+	restarts     int
+	total        int
+	gaveUp       bool
+	nextRestart  *time.Time
+	crashReports []CrashReport
+}
+
+// exited records the exit of an epoch started at start, and returns the delay before the
+// proxy is restarted, or false if it must not be.
+func (s *supervisor) exited(status exitStatus, start time.Time) (time.Duration, bool) {
+	if status.err == nil {
+		// The proxy was asked to exit, e.g. through its admin /quitquitquit endpoint.
+		return 0, false
+	}
+	now := time.Now()
+	report := newCrashReport(status, now.Sub(start))
+	report.Time = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crashReports = append([]CrashReport{report}, s.crashReports...)
+	if len(s.crashReports) > maxCrashReports {
+		s.crashReports = s.crashReports[:maxCrashReports]
+	}
+	proxyCrashes.With(reasonTag.Value(report.reason())).Increment()
+	if s.policy.MaxRestarts <= 0 {
+		return 0, false
+	}
+	if s.policy.ResetAfter > 0 && now.Sub(start) >= s.policy.ResetAfter {
+		s.restarts = 0
+	}
+	if s.restarts >= s.policy.MaxRestarts {
+		s.gaveUp = true
+		proxyRestartBudgetExhausted.Record(1)
+		return 0, false
+	}
+	delay := s.policy.InitialBackoff
+	for i := 0; i < s.restarts && delay < s.policy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if s.policy.MaxBackoff > 0 && delay > s.policy.MaxBackoff {
+		delay = s.policy.MaxBackoff
+	}
+	s.restarts++
+	s.total++
+	next := now.Add(delay)
+	s.nextRestart = &next
+	proxyRestarts.Increment()
+	proxyConsecutiveRestarts.Record(float64(s.restarts))
+	return delay, true
+}
+
+// restarted records that the backoff has elapsed and the proxy is running again.
+func (s *supervisor) restarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRestart = nil
+}
+
+func (s *supervisor) status() SupervisorStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return SupervisorStatus{
+		Enabled:       s.policy.MaxRestarts > 0,
+		Restarts:      s.restarts,
+		MaxRestarts:   s.policy.MaxRestarts,
+		TotalRestarts: s.total,
+		NextRestart:   s.nextRestart,
+		GaveUp:        s.gaveUp,
+		Crashes:       append([]CrashReport{}, s.crashReports...),
+	}
+}
+
+func newCrashReport(status exitStatus, uptime time.Duration) CrashReport {
+	report := CrashReport{
+		Epoch:    status.epoch,
+		Uptime:   uptime.Round(time.Millisecond).String(),
+		Error:    status.err.Error(),
+		ExitCode: -1,
+	}
+	var exitErr *ExitError
+	if errors.As(status.err, &exitErr) {
+		report.Stderr = exitErr.Stderr
+	}
+	var procErr *exec.ExitError
+	if errors.As(status.err, &procErr) {
+		report.ExitCode = procErr.ExitCode()
+		if ws, ok := procErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			report.Signal = ws.Signal().String()
+			report.OOMSuspected = ws.Signal() == syscall.SIGKILL
+		}
+	}
+	return report
+}
+
+func (r CrashReport) reason() string {
+	switch {
+	case r.OOMSuspected:
+		return "oom"
+	case r.Signal != "":
+		return "signal"
+	default:
+		return "exit"
+	}
+}
+
+// lineRing keeps the last lines written to it.
+type lineRing struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newLineRing(max int) *lineRing {
+	return &lineRing{max: max}
+}
+
+func (r *lineRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		r.lines = append(r.lines, strings.TrimRight(string(data[:i]), "\r"))
+		data = data[i+1:]
+	}
+	if len(r.lines) > r.max {
+		r.lines = append([]string{}, r.lines[len(r.lines)-r.max:]...)
+	}
+	r.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+// Lines returns the kept lines, including a trailing line without newline.
+func (r *lineRing) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := append([]string{}, r.lines...)
+	if len(r.partial) > 0 {
+		out = append(out, string(r.partial))
+		if len(out) > r.max {
+			out = out[1:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestarts(t *testing.T) {
+	var epochs []int
+	start := func(epoch int, _ <-chan error) error {
+		epochs = append(epochs, epoch)
+		if epoch < 2 {
+			return &ExitError{Err: fmt.Errorf("exit status 1"), Stderr: []string{fmt.Sprintf("crash %d", epoch)}}
+		}
+		return nil
+	}
+	a := NewAgent(TestProxy{run: start}, 0, 0)
+	a.SetRestartPolicy(RestartPolicy{MaxRestarts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(epochs, []int{0, 1, 2}) {
+		t.Errorf("epochs = %v, want [0 1 2]", epochs)
+	}
+	status := a.SupervisorStatus()
+	if !status.Enabled || status.Restarts != 2 || status.TotalRestarts != 2 || status.GaveUp || status.NextRestart != nil {
+		t.Errorf("status = %+v, want 2 restarts", status)
+	}
+	if len(status.Crashes) != 2 || status.Crashes[0].Epoch != 1 || !reflect.DeepEqual(status.Crashes[0].Stderr, []string{"crash 1"}) {
+		t.Errorf("crashes = %+v, want epoch 1 then 0", status.Crashes)
+	}
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	runs := 0
+	start := func(epoch int, _ <-chan error) error {
+		runs++
+		return errors.New("exit status 1")
+	}
+	a := NewAgent(TestProxy{run: start}, 0, 0)
+	a.SetRestartPolicy(RestartPolicy{MaxRestarts: 2, InitialBackoff: time.Millisecond})
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 3 {
+		t.Errorf("proxy ran %d times, want 3", runs)
+	}
+	if status := a.SupervisorStatus(); !status.GaveUp || status.Restarts != 2 || len(status.Crashes) != 3 {
+		t.Errorf("status = %+v, want to give up after 2 restarts", status)
+	}
+}
+
+func TestSupervisorDisabled(t *testing.T) {
+	runs := 0
+	start := func(epoch int, _ <-chan error) error {
+		runs++
+		return errors.New("exit status 1")
+	}
+	a := NewAgent(TestProxy{run: start}, 0, 0)
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("proxy ran %d times, want 1", runs)
+	}
+	if status := a.SupervisorStatus(); status.Enabled || len(status.Crashes) != 1 {
+		t.Errorf("status = %+v, want disabled with a crash report", status)
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s := &supervisor{policy: RestartPolicy{
+		MaxRestarts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		ResetAfter:     time.Minute,
+	}}
+	crash := exitStatus{err: errors.New("exit status 1")}
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delay, ok := s.exited(crash, time.Now())
+		if !ok {
+			t.Fatalf("restart %d refused", i)
+		}
+		delays = append(delays, delay)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+	// An epoch running longer than ResetAfter resets the backoff.
+	if delay, _ := s.exited(crash, time.Now().Add(-2*time.Minute)); delay != time.Second {
+		t.Errorf("delay after stable epoch = %v, want 1s", delay)
+	}
+	if _, ok := s.exited(exitStatus{}, time.Now()); ok {
+		t.Errorf("clean exit restarted")
+	}
+}
+
+func TestCrashReport(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo starting >&2; printf 'fatal' >&2; kill -9 $$")
+	stderr := newLineRing(crashLogLines)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	exitErr := &ExitError{Err: err, Stderr: stderr.Lines()}
+	if exitErr.Error() != errOutOfMemory {
+		t.Errorf("Error() = %q, want %q", exitErr.Error(), errOutOfMemory)
+	}
+	report := newCrashReport(exitStatus{epoch: 3, err: exitErr}, time.Second)
+	want := CrashReport{
+		Epoch:        3,
+		Uptime:       "1s",
+		Error:        errOutOfMemory,
+		ExitCode:     -1,
+		Signal:       "killed",
+		OOMSuspected: true,
+		Stderr:       []string{"starting", "fatal"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestLineRing(t *testing.T) {
+	r := newLineRing(2)
+	_, _ = r.Write([]byte("one\ntw"))
+	_, _ = r.Write([]byte("o\r\nthree\nfo"))
+	if got, want := r.Lines(), []string{"three", "fo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+}
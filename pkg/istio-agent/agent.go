@@ -21,6 +21,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	mesh "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/dns"
@@ -131,6 +132,10 @@ type AgentOptions struct {
 
 	// Ability to retrieve ProxyConfig dynamically through XDS
 	EnableDynamicProxyConfig bool
+
+	// ConnectionPoolUsageReportInterval is the interval at which the connection pool usage of the outbound
+	// clusters is reported to istiod. Zero disables the reports.
+	ConnectionPoolUsageReportInterval time.Duration
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -198,6 +203,22 @@ func (a *Agent) Check() (err error) {
 	return nil
 }
 
+// CheckWorkloadCertificate returns an error if the agent has not issued a workload certificate
+// over SDS yet, or if it has expired. File mounted certificates are not checked.
+func (a *Agent) CheckWorkloadCertificate() error {
+	if a.secretCache == nil || a.secOpts.FileMountedCerts {
+		return nil
+	}
+	cert := a.secretCache.WorkloadCertificate()
+	if cert == nil {
+		return errors.New("no workload certificate was issued yet")
+	}
+	if time.Now().After(cert.ExpireTime) {
+		return fmt.Errorf("workload certificate expired at %v", cert.ExpireTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
 func (a *Agent) Close() {
 	if a.xdsProxy != nil {
 		a.xdsProxy.close()
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"sort"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/networking/connectionpool"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/envoy"
+)
+
+// reportConnectionPoolUsage periodically reports the connection pool usage of the outbound clusters of Envoy to
+// istiod, until the proxy is closed. The usage is only reported over SotW connections.
+func (p *XdsProxy) reportConnectionPoolUsage(adminPort uint32, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var previous map[string]envoy.ConnectionPoolUsage
+	for {
+		select {
+		case <-ticker.C:
+			usage, err := envoy.GetConnectionPoolUsage(adminPort)
+			if err != nil {
+				proxyLog.Debugf("failed to read the connection pool usage: %v", err)
+				continue
+			}
+			reports := connectionPoolReports(previous, usage)
+			previous = usage
+			if len(reports) == 0 {
+				continue
+			}
+			p.sendRequest(&discovery.DiscoveryRequest{
+				TypeUrl:       v3.ConnectionPoolUsageType,
+				ResourceNames: connectionpool.EncodeReports(reports),
+			})
+		case <-p.stopChan:
+			return
+		}
+	}
+}
+
+// connectionPoolReports returns the reports of the clusters, with the overflows since the previous usage.
+func connectionPoolReports(previous, current map[string]envoy.ConnectionPoolUsage) []connectionpool.Report {
+	reports := make([]connectionpool.Report, 0, len(current))
+	for cluster, u := range current {
+		prev := previous[cluster]
+		reports = append(reports, connectionpool.Report{
+			Cluster:             cluster,
+			ActiveRequests:      u.ActiveRequests,
+			PendingRequests:     u.PendingRequests,
+			ActiveConnections:   u.ActiveConnections,
+			RequestOverflows:    counterDelta(prev.RequestOverflows, u.RequestOverflows),
+			ConnectionOverflows: counterDelta(prev.ConnectionOverflows, u.ConnectionOverflows),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Cluster < reports[j].Cluster
+	})
+	return reports
+}
+
+// counterDelta returns the increase of a counter, which is reset when Envoy restarts.
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// sendRequest sends a request to istiod if a SotW stream is connected. Unlike PersistRequest, the request is not
+// resent on reconnection, and is dropped if the stream is busy.
+func (p *XdsProxy) sendRequest(req *discovery.DiscoveryRequest) {
+	p.connectedMutex.RLock()
+	defer p.connectedMutex.RUnlock()
+	if p.connected == nil || p.connected.requestsChan == nil {
+		return
+	}
+	select {
+	case p.connected.requestsChan <- req:
+	default:
+		proxyLog.Debugf("dropped request for type url %s: stream busy", req.TypeUrl)
+	}
+}
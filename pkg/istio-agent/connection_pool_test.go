@@ -0,0 +1,44 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/networking/connectionpool"
+	"istio.io/istio/pkg/envoy"
+)
+
+func TestConnectionPoolReports(t *testing.T) {
+	previous := map[string]envoy.ConnectionPoolUsage{
+		"outbound|80||a": {RequestOverflows: 5, ConnectionOverflows: 1},
+		"outbound|80||b": {RequestOverflows: 10},
+	}
+	current := map[string]envoy.ConnectionPoolUsage{
+		"outbound|80||a": {ActiveRequests: 3, PendingRequests: 1, ActiveConnections: 2, RequestOverflows: 8, ConnectionOverflows: 1},
+		// Envoy restarted and reset its counters.
+		"outbound|80||b": {RequestOverflows: 2},
+		"outbound|80||c": {ConnectionOverflows: 4},
+	}
+	want := []connectionpool.Report{
+		{Cluster: "outbound|80||a", ActiveRequests: 3, PendingRequests: 1, ActiveConnections: 2, RequestOverflows: 3},
+		{Cluster: "outbound|80||b", RequestOverflows: 2},
+		{Cluster: "outbound|80||c", ConnectionOverflows: 4},
+	}
+	if got := connectionPoolReports(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("connectionPoolReports() => %+v, want %+v", got, want)
+	}
+}
@@ -212,6 +212,10 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		proxy.PersistDeltaRequest(deltaReq)
 	}, proxy.stopChan)
 
+	if ia.cfg.ConnectionPoolUsageReportInterval > 0 {
+		go proxy.reportConnectionPoolUsage(uint32(ia.proxyConfig.ProxyAdminPort), ia.cfg.ConnectionPoolUsageReportInterval)
+	}
+
 	return proxy, nil
 }
 
@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/atomic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/util"
+)
+
+// kubeConfigInfo holds the parts of a remote cluster kubeconfig relevant to credential rotation and health.
+type kubeConfigInfo struct {
+	// token is the bearer token of the current context, if any.
+	token string
+	// identitySha is the checksum of the kubeconfig without its bearer token. Kubeconfigs with the
+	// same identity only differ by their token, which can be swapped without recreating the clients.
+	identitySha [sha256.Size]byte
+	// expiry is the earliest expiry of the bearer token and client certificate, or zero if unknown.
+	expiry time.Time
+}
+
+func parseKubeConfig(kubeConfig []byte) (*kubeConfigInfo, error) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig cannot be loaded: %v", err)
+	}
+	out := &kubeConfigInfo{}
+	if context := rawConfig.Contexts[rawConfig.CurrentContext]; context != nil {
+		if authInfo := rawConfig.AuthInfos[context.AuthInfo]; authInfo != nil {
+			out.token = authInfo.Token
+			if out.token != "" {
+				if exp, err := util.GetExp(out.token); err == nil {
+					out.expiry = exp
+				}
+			}
+			if len(authInfo.ClientCertificateData) > 0 {
+				if cert, err := pkiutil.ParsePemEncodedCertificate(authInfo.ClientCertificateData); err == nil &&
+					(out.expiry.IsZero() || cert.NotAfter.Before(out.expiry)) {
+					out.expiry = cert.NotAfter
+				}
+			}
+			authInfo.Token = ""
+		}
+	}
+	identity, err := clientcmd.Write(*rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig cannot be serialized: %v", err)
+	}
+	out.identitySha = sha256.Sum256(identity)
+	return out, nil
+}
+
+// BearerToken is the bearer token used to authenticate to a remote cluster. It is injected in every
+// request, so that it can be rotated in place without restarting the informers of the cluster.
+type BearerToken struct {
+	token atomic.String
+}
+
+// Set replaces the bearer token sent on subsequent requests.
+func (b *BearerToken) Set(token string) {
+	b.token.Store(token)
+}
+
+// Get returns the current bearer token.
+func (b *BearerToken) Get() string {
+	return b.token.Load()
+}
+
+// WrapTransport sets the current bearer token on requests, unless they are already authenticated.
+func (b *BearerToken) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		token := b.Get()
+		if token == "" || req.Header.Get("Authorization") != "" {
+			return rt.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+		return rt.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func makeToken(exp time.Time) string {
+	payload, _ := json.Marshal(map[string]interface{}{"exp": exp.Unix()})
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func makeKubeConfig(server, token string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: %s
+`, server, token))
+}
+
+func TestParseKubeConfig(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	a, err := parseKubeConfig(makeKubeConfig("https://remote", makeToken(exp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.expiry.Equal(exp) {
+		t.Errorf("got expiry %v, want %v", a.expiry, exp)
+	}
+	if a.token != makeToken(exp) {
+		t.Errorf("got token %q, want %q", a.token, makeToken(exp))
+	}
+
+	rotated, err := parseKubeConfig(makeKubeConfig("https://remote", "opaque-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.identitySha != a.identitySha {
+		t.Errorf("kubeconfigs only differing by their token should have the same identity")
+	}
+	if !rotated.expiry.IsZero() {
+		t.Errorf("got expiry %v for opaque token, want none", rotated.expiry)
+	}
+
+	moved, err := parseKubeConfig(makeKubeConfig("https://other", "opaque-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.identitySha == a.identitySha {
+		t.Errorf("kubeconfigs with different servers should have different identities")
+	}
+
+	if _, err := parseKubeConfig([]byte("not a kubeconfig")); err == nil {
+		t.Errorf("expected error for invalid kubeconfig")
+	}
+}
+
+func TestBearerTokenWrapTransport(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	token := &BearerToken{}
+	client := &http.Client{Transport: token.WrapTransport(http.DefaultTransport)}
+	do := func(authorization string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	do("")
+	if got != "" {
+		t.Errorf("got Authorization %q without token, want none", got)
+	}
+	token.Set("a")
+	do("")
+	if got != "Bearer a" {
+		t.Errorf("got Authorization %q, want %q", got, "Bearer a")
+	}
+	token.Set("b")
+	do("")
+	if got != "Bearer b" {
+		t.Errorf("got Authorization %q after rotation, want %q", got, "Bearer b")
+	}
+	do("Basic other")
+	if got != "Basic other" {
+		t.Errorf("got Authorization %q, want the request authorization to be kept", got)
+	}
+}
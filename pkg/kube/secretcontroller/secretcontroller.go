@@ -21,6 +21,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
@@ -43,6 +45,9 @@ const (
 	initialSyncSignal       = "INIT"
 	MultiClusterSecretLabel = "istio/multiCluster"
 	maxRetries              = 5
+
+	// healthCheckInterval is the interval at which the API servers of the remote clusters are probed.
+	healthCheckInterval = time.Minute
 )
 
 // addSecretCallback prototype for the add secret callback function.
@@ -75,10 +80,42 @@ type RemoteCluster struct {
 	secretName    string
 	clients       kube.Client
 	kubeConfigSha [sha256.Size]byte
+	// token is the bearer token used by the clients. info is nil if the kubeconfig could not be
+	// parsed, in which case credentials cannot be rotated in place.
+	token *BearerToken
+	info  *kubeConfigInfo
+	// status is protected by the ClusterStore mutex.
+	status ClusterStatus
+}
+
+// ClusterStatus reports the health of a remote cluster.
+type ClusterStatus struct {
+	ID         string `json:"id"`
+	SecretName string `json:"secret_name"`
+
+	AddedTime time.Time `json:"added_time"`
+	// Synced is true once the informers of the cluster have synced.
+	Synced     bool      `json:"synced"`
+	SyncedTime time.Time `json:"synced_time"`
+
+	// CredentialsExpiry is the earliest expiry of the bearer token and client certificate of the
+	// kubeconfig, or zero if unknown.
+	CredentialsExpiry time.Time `json:"credentials_expiry"`
+	// CredentialsRotatedTime is the last time the bearer token was swapped in place.
+	CredentialsRotatedTime time.Time `json:"credentials_rotated_time"`
+
+	// Healthy is the result of the last probe of the API server.
+	Healthy             bool      `json:"healthy"`
+	LastHealthCheckTime time.Time `json:"last_health_check_time"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorTime       time.Time `json:"last_error_time"`
 }
 
 // ClusterStore is a collection of clusters
 type ClusterStore struct {
+	// mu protects remoteClusters and their status. The map is only written by the controller worker,
+	// which may read it without locking.
+	mu             sync.RWMutex
 	remoteClusters map[string]*RemoteCluster
 }
 
@@ -174,6 +211,7 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	c.queue.Add(initialSyncSignal)
 
 	go wait.Until(c.runWorker, 5*time.Second, stopCh)
+	go wait.Until(c.checkHealth, healthCheckInterval, stopCh)
 	<-stopCh
 }
 
@@ -247,8 +285,10 @@ func (c *Controller) processItem(secretName string) error {
 	return nil
 }
 
-// BuildClientsFromConfig creates kube.Clients from the provided kubeconfig. This is overiden for testing only
-var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
+// BuildClientsFromConfig creates kube.Clients from the provided kubeconfig. If the kubeconfig has a static
+// bearer token, it is moved to the given BearerToken, which then authenticates all requests and can be
+// rotated in place. This is overiden for testing only
+var BuildClientsFromConfig = func(kubeConfig []byte, token *BearerToken) (kube.Client, error) {
 	if len(kubeConfig) == 0 {
 		return nil, errors.New("kubeconfig is empty")
 	}
@@ -263,8 +303,17 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 	}
 
 	clientConfig := clientcmd.NewDefaultClientConfig(*rawConfig, &clientcmd.ConfigOverrides{})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig is not valid: %v", err)
+	}
+	if token != nil && restConfig.BearerToken != "" && restConfig.BearerTokenFile == "" {
+		token.Set(restConfig.BearerToken)
+		restConfig.BearerToken = ""
+		restConfig.Wrap(token.WrapTransport)
+	}
 
-	clients, err := kube.NewClient(clientConfig)
+	clients, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kube clients: %v", err)
 	}
@@ -272,15 +321,26 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 }
 
 func createRemoteCluster(kubeConfig []byte, secretName string) (*RemoteCluster, error) {
-	clients, err := BuildClientsFromConfig(kubeConfig)
+	token := &BearerToken{}
+	clients, err := BuildClientsFromConfig(kubeConfig, token)
 	if err != nil {
 		return nil, err
 	}
-	return &RemoteCluster{
+	remoteCluster := &RemoteCluster{
 		secretName:    secretName,
 		clients:       clients,
 		kubeConfigSha: sha256.Sum256(kubeConfig),
-	}, nil
+		token:         token,
+		status: ClusterStatus{
+			SecretName: secretName,
+			AddedTime:  time.Now(),
+		},
+	}
+	if info, err := parseKubeConfig(kubeConfig); err == nil {
+		remoteCluster.info = info
+		remoteCluster.status.CredentialsExpiry = info.expiry
+	}
+	return remoteCluster, nil
 }
 
 func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
@@ -296,11 +356,13 @@ func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
 				continue
 			}
 
-			c.cs.remoteClusters[clusterID] = remoteCluster
-			if err := c.addCallback(remoteCluster.clients, clusterID); err != nil {
+			c.cs.store(clusterID, remoteCluster)
+			err = c.addCallback(remoteCluster.clients, clusterID)
+			if err != nil {
 				log.Errorf("Error creating cluster_id=%s from secret %v: %v",
 					clusterID, secretName, err)
 			}
+			c.cs.synced(remoteCluster, err)
 		} else {
 			if prev.secretName != secretName {
 				log.Errorf("ClusterID reused in two different secrets: %v and %v. ClusterID "+
@@ -311,6 +373,8 @@ func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
 			kubeConfigSha := sha256.Sum256(kubeConfig)
 			if bytes.Equal(kubeConfigSha[:], prev.kubeConfigSha[:]) {
 				log.Infof("Updating cluster_id=%v from secret=%v: (kubeconfig are identical)", clusterID, secretName)
+			} else if c.cs.rotateCredentials(prev, kubeConfig) {
+				log.Infof("Rotated credentials of cluster_id=%v from secret=%v", clusterID, secretName)
 			} else {
 				log.Infof("Updating cluster %v from secret %v", clusterID, secretName)
 
@@ -320,11 +384,13 @@ func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
 						clusterID, secretName, err)
 					continue
 				}
-				c.cs.remoteClusters[clusterID] = remoteCluster
-				if err := c.updateCallback(remoteCluster.clients, clusterID); err != nil {
+				c.cs.store(clusterID, remoteCluster)
+				err = c.updateCallback(remoteCluster.clients, clusterID)
+				if err != nil {
 					log.Errorf("Error updating cluster_id from secret=%v: %s %v",
 						clusterID, secretName, err)
 				}
+				c.cs.synced(remoteCluster, err)
 			}
 		}
 	}
@@ -341,8 +407,105 @@ func (c *Controller) deleteMemberCluster(secretName string) {
 				log.Errorf("Error removing cluster_id=%v configured by secret=%v: %v",
 					clusterID, secretName, err)
 			}
+			c.cs.mu.Lock()
 			delete(c.cs.remoteClusters, clusterID)
+			c.cs.mu.Unlock()
 		}
 	}
 	log.Infof("Number of remote clusters: %d", len(c.cs.remoteClusters))
 }
+
+// ClusterStatuses returns the status of the remote clusters, sorted by cluster ID.
+func (c *Controller) ClusterStatuses() []ClusterStatus {
+	c.cs.mu.RLock()
+	defer c.cs.mu.RUnlock()
+	out := make([]ClusterStatus, 0, len(c.cs.remoteClusters))
+	for _, cluster := range c.cs.remoteClusters {
+		out = append(out, cluster.status)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// checkHealth probes the API server of each remote cluster, recording failures such as expired credentials.
+func (c *Controller) checkHealth() {
+	c.cs.mu.RLock()
+	clusters := make(map[string]*RemoteCluster, len(c.cs.remoteClusters))
+	for clusterID, cluster := range c.cs.remoteClusters {
+		clusters[clusterID] = cluster
+	}
+	c.cs.mu.RUnlock()
+
+	wg := sync.WaitGroup{}
+	for clusterID, cluster := range clusters {
+		clusterID, cluster := clusterID, cluster
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cluster.clients.Kube().Discovery().ServerVersion()
+			now := time.Now()
+			c.cs.mu.Lock()
+			cluster.status.Healthy = err == nil
+			cluster.status.LastHealthCheckTime = now
+			if err != nil {
+				cluster.status.LastError = err.Error()
+				cluster.status.LastErrorTime = now
+			}
+			expiry := cluster.status.CredentialsExpiry
+			c.cs.mu.Unlock()
+			if err != nil {
+				log.Warnf("Health check of cluster_id=%v failed: %v", clusterID, err)
+			}
+			if !expiry.IsZero() && expiry.Before(now) {
+				log.Warnf("Credentials of cluster_id=%v configured by secret=%v expired at %v",
+					clusterID, cluster.secretName, expiry)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *ClusterStore) store(clusterID string, cluster *RemoteCluster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster.status.ID = clusterID
+	c.remoteClusters[clusterID] = cluster
+}
+
+// synced records the result of the add or update callback of a cluster, which returns once the
+// informers of the cluster have synced.
+func (c *ClusterStore) synced(cluster *RemoteCluster, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if err != nil {
+		cluster.status.LastError = err.Error()
+		cluster.status.LastErrorTime = now
+		return
+	}
+	cluster.status.Synced = true
+	cluster.status.SyncedTime = now
+}
+
+// rotateCredentials swaps the bearer token of a cluster in place if the new kubeconfig only differs
+// from the current one by its bearer token. It returns false if the clients must be recreated instead.
+func (c *ClusterStore) rotateCredentials(cluster *RemoteCluster, kubeConfig []byte) bool {
+	if cluster.info == nil || cluster.info.token == "" {
+		return false
+	}
+	info, err := parseKubeConfig(kubeConfig)
+	if err != nil || info.token == "" || info.identitySha != cluster.info.identitySha {
+		return false
+	}
+	cluster.token.Set(info.token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster.info = info
+	cluster.kubeConfigSha = sha256.Sum256(kubeConfig)
+	cluster.status.CredentialsExpiry = info.expiry
+	cluster.status.CredentialsRotatedTime = time.Now()
+	return true
+}
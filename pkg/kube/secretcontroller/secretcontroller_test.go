@@ -80,7 +80,7 @@ func resetCallbackData() {
 }
 
 func Test_SecretController(t *testing.T) {
-	BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
+	BuildClientsFromConfig = func(kubeConfig []byte, _ *BearerToken) (kube.Client, error) {
 		return kube.NewFakeClient(), nil
 	}
 	clientset := kube.NewFakeClient()
@@ -168,3 +168,63 @@ func Test_SecretController(t *testing.T) {
 		})
 	}
 }
+
+func Test_SecretControllerRotatesCredentials(t *testing.T) {
+	BuildClientsFromConfig = func(kubeConfig []byte, _ *BearerToken) (kube.Client, error) {
+		return kube.NewFakeClient(), nil
+	}
+	clientset := kube.NewFakeClient()
+	g := NewWithT(t)
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	secret := makeSecret("s0", "c0", makeKubeConfig("https://remote", makeToken(exp)))
+	rotated := makeSecret("s0", "c0", makeKubeConfig("https://remote", makeToken(exp.Add(time.Hour))))
+	moved := makeSecret("s0", "c0", makeKubeConfig("https://other", makeToken(exp.Add(time.Hour))))
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+	})
+	resetCallbackData()
+	c := StartSecretController(clientset, addCallback, updateCallback, deleteCallback, secretNamespace, time.Microsecond, stopCh)
+	kube.WaitForCacheSyncInterval(stopCh, time.Microsecond, c.informer.HasSynced)
+	clientset.RunAndWait(stopCh)
+
+	status := func() ClusterStatus {
+		statuses := c.ClusterStatuses()
+		if len(statuses) != 1 {
+			return ClusterStatus{}
+		}
+		return statuses[0]
+	}
+
+	_, err := clientset.CoreV1().Secrets(secretNamespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	g.Expect(err).Should(BeNil())
+	g.Eventually(func() bool { return status().Synced }, 10*time.Second).Should(BeTrue())
+	g.Expect(status().ID).To(Equal("c0"))
+	g.Expect(status().SecretName).To(Equal("s0"))
+	g.Expect(status().CredentialsExpiry.Equal(exp)).To(BeTrue())
+
+	// Only the token changed: it is swapped in place, without recreating the cluster.
+	_, err = clientset.CoreV1().Secrets(secretNamespace).Update(context.TODO(), rotated, metav1.UpdateOptions{})
+	g.Expect(err).Should(BeNil())
+	g.Eventually(func() bool { return !status().CredentialsRotatedTime.IsZero() }, 10*time.Second).Should(BeTrue())
+	g.Expect(status().CredentialsExpiry.Equal(exp.Add(time.Hour))).To(BeTrue())
+	mu.Lock()
+	g.Expect(updated).To(Equal(""))
+	mu.Unlock()
+
+	// The server changed: the cluster is recreated.
+	_, err = clientset.CoreV1().Secrets(secretNamespace).Update(context.TODO(), moved, metav1.UpdateOptions{})
+	g.Expect(err).Should(BeNil())
+	g.Eventually(func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return updated
+	}, 10*time.Second).Should(Equal("c0"))
+	g.Eventually(func() bool { return status().CredentialsRotatedTime.IsZero() }, 10*time.Second).Should(BeTrue())
+
+	c.checkHealth()
+	g.Expect(status().Healthy).To(BeTrue())
+	g.Expect(status().LastHealthCheckTime.IsZero()).To(BeFalse())
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AttestedCredentialPrefix marks a bearer token carrying an AttestedCredential rather than a JWT.
+const AttestedCredentialPrefix = "attested."
+
+// AttestedCredential is an instance identity document signed by the cloud platform, as
+// presented by a VM to the CA in place of a JWT. The credential fetcher plugins produce it
+// and the platform authenticators in istiod verify it.
+type AttestedCredential struct {
+	// Type is the credential fetcher type that produced the credential, e.g. AWS or Azure.
+	Type string `json:"type"`
+
+	// Document is the instance identity document as returned by the platform.
+	// For Azure the document is embedded in the signature and this is empty.
+	Document []byte `json:"document,omitempty"`
+
+	// Signature is the platform signature over the document. This is a PKCS#1 v1.5
+	// signature for AWS, and a PKCS#7 SignedData structure for Azure.
+	Signature []byte `json:"signature"`
+
+	// Nonce is a random value the VM generates once and presents with every AWS document.
+	// AWS documents have no expiry or challenge, so the CA binds each instance to the first
+	// nonce it authenticates with, and rejects the document with any other nonce.
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// Encode serializes the credential into a bearer token.
+func (c *AttestedCredential) Encode() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return AttestedCredentialPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsAttestedCredential returns true if the bearer token carries an AttestedCredential.
+func IsAttestedCredential(token string) bool {
+	return strings.HasPrefix(token, AttestedCredentialPrefix)
+}
+
+// ParseAttestedCredential decodes a bearer token produced by AttestedCredential.Encode.
+func ParseAttestedCredential(token string) (*AttestedCredential, error) {
+	if !IsAttestedCredential(token) {
+		return nil, fmt.Errorf("token is not an attested credential")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, AttestedCredentialPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attested credential: %v", err)
+	}
+	c := &AttestedCredential{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attested credential: %v", err)
+	}
+	if len(c.Signature) == 0 {
+		return nil, fmt.Errorf("attested credential has no signature")
+	}
+	return c, nil
+}
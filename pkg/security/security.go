@@ -54,8 +54,10 @@ const (
 	WorkloadKeyCertResourceName = "default"
 
 	// Credential fetcher type
-	GCE  = "GoogleComputeEngine"
-	Mock = "Mock" // testing only
+	GCE   = "GoogleComputeEngine"
+	AWS   = "AmazonWebServices"
+	Azure = "MicrosoftAzure"
+	Mock  = "Mock" // testing only
 )
 
 // TODO: For 1.8, make sure MeshConfig is updated with those settings,
@@ -253,7 +255,8 @@ type CredFetcher interface {
 	// GetPlatformCredential fetches workload credential provided by the platform.
 	GetPlatformCredential() (string, error)
 
-	// GetType returns credential fetcher type. Currently the supported type is "GoogleComputeEngine".
+	// GetType returns credential fetcher type. Currently the supported types are "GoogleComputeEngine",
+	// "AmazonWebServices" and "MicrosoftAzure".
 	GetType() string
 
 	// The name of the IdentityProvider that can authenticate the workload credential.
@@ -271,6 +274,17 @@ const (
 	AuthSourceIDToken
 )
 
+func (s AuthSource) String() string {
+	switch s {
+	case AuthSourceClientCertificate:
+		return "ClientCertificate"
+	case AuthSourceIDToken:
+		return "IDToken"
+	default:
+		return fmt.Sprintf("AuthSource(%d)", int(s))
+	}
+}
+
 const (
 	// IdentityTemplate is the SPIFFE format template of the identity.
 	IdentityTemplate = "spiffe://%s/ns/%s/sa/%s"
@@ -40,6 +40,8 @@ var (
 	ClusterFieldRegex        = regexp.MustCompile(string(response.ClusterField) + "=(.*)")
 	IstioVersionFieldRegex   = regexp.MustCompile(string(response.IstioVersionField) + "=(.*)")
 	IPFieldRegex             = regexp.MustCompile(string(response.IPField) + "=(.*)")
+	SNIFieldRegex            = regexp.MustCompile(string(response.SNIField) + "=(.*)")
+	ALPNFieldRegex           = regexp.MustCompile(string(response.ALPNField) + "=(.*)")
 )
 
 // ParsedResponse represents a response to a single echo request.
@@ -69,6 +71,10 @@ type ParsedResponse struct {
 	IstioVersion string
 	// IP is the requester's ip address
 	IP string
+	// SNI is the server name presented by the requester in TLS connections
+	SNI string
+	// ALPN is the application protocol negotiated in TLS connections
+	ALPN string
 	// RawResponse gives a map of all values returned in the response (headers, etc)
 	RawResponse map[string]string
 }
@@ -96,6 +102,8 @@ func (r *ParsedResponse) String() string {
 	out += fmt.Sprintf("Cluster:      %s\n", r.Cluster)
 	out += fmt.Sprintf("IstioVersion: %s\n", r.IstioVersion)
 	out += fmt.Sprintf("IP:           %s\n", r.IP)
+	out += fmt.Sprintf("SNI:          %s\n", r.SNI)
+	out += fmt.Sprintf("ALPN:         %s\n", r.ALPN)
 
 	return out
 }
@@ -312,6 +320,23 @@ func (r ParsedResponses) CheckIPOrFail(t test.Failer, expected string) ParsedRes
 	return r
 }
 
+func (r ParsedResponses) CheckSNI(expected string) error {
+	return r.Check(func(i int, response *ParsedResponse) error {
+		if response.SNI != expected {
+			return fmt.Errorf("response[%d] SNI: expected %s, received %s", i, expected, response.SNI)
+		}
+		return nil
+	})
+}
+
+func (r ParsedResponses) CheckSNIOrFail(t test.Failer, expected string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckSNI(expected); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
 // Count occurrences of the given text within the bodies of all responses.
 func (r ParsedResponses) Count(text string) int {
 	count := 0
@@ -404,6 +429,16 @@ func parseResponse(output string) *ParsedResponse {
 		out.IP = match[1]
 	}
 
+	match = SNIFieldRegex.FindStringSubmatch(output)
+	if match != nil {
+		out.SNI = match[1]
+	}
+
+	match = ALPNFieldRegex.FindStringSubmatch(output)
+	if match != nil {
+		out.ALPN = match[1]
+	}
+
 	out.RawResponse = map[string]string{}
 
 	matches := responseHeaderFieldRegex.FindAllStringSubmatch(output, -1)
@@ -47,6 +47,7 @@ var (
 	serverName      string
 	serverFirst     bool
 	followRedirects bool
+	proxyProtocol   bool
 	clientCert      string
 	clientKey       string
 
@@ -134,6 +135,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&clientKey, "client-key", "", "client certificate key file to use for request")
 	rootCmd.PersistentFlags().StringSliceVarP(&alpn, "alpn", "", nil, "alpn to set")
 	rootCmd.PersistentFlags().StringVarP(&serverName, "server-name", "", serverName, "server name to set")
+	rootCmd.PersistentFlags().BoolVar(&proxyProtocol, "proxy-protocol", false,
+		"Send a PROXY protocol header at the start of TCP, TLS and HTTP/1.1 connections")
 
 	loggingOptions.AttachCobraFlags(rootCmd)
 
@@ -165,6 +168,7 @@ func getRequest(url string) (*proto.ForwardEchoRequest, error) {
 		FollowRedirects: followRedirects,
 		Method:          method,
 		ServerName:      serverName,
+		ProxyProtocol:   proxyProtocol,
 	}
 
 	if alpn != nil {
@@ -30,20 +30,23 @@ import (
 )
 
 var (
-	httpPorts        []int
-	grpcPorts        []int
-	tcpPorts         []int
-	tlsPorts         []int
-	instanceIPPorts  []int
-	localhostIPPorts []int
-	serverFirstPorts []int
-	metricsPort      int
-	uds              string
-	version          string
-	cluster          string
-	crt              string
-	key              string
-	istioVersion     string
+	httpPorts           []int
+	grpcPorts           []int
+	tcpPorts            []int
+	tlsPassthroughPorts []int
+	udpPorts            []int
+	tlsPorts            []int
+	proxyProtocolPorts  []int
+	instanceIPPorts     []int
+	localhostIPPorts    []int
+	serverFirstPorts    []int
+	metricsPort         int
+	uds                 string
+	version             string
+	cluster             string
+	crt                 string
+	key                 string
+	istioVersion        string
 
 	loggingOptions = log.DefaultOptions()
 
@@ -54,7 +57,7 @@ var (
 		Long:              `Echo application for testing Istio E2E`,
 		PersistentPreRunE: configureLogging,
 		Run: func(cmd *cobra.Command, args []string) {
-			ports := make(common.PortList, len(httpPorts)+len(grpcPorts)+len(tcpPorts))
+			ports := make(common.PortList, len(httpPorts)+len(grpcPorts)+len(tcpPorts)+len(tlsPassthroughPorts)+len(udpPorts))
 			tlsByPort := map[int]bool{}
 			for _, p := range tlsPorts {
 				tlsByPort[p] = true
@@ -63,14 +66,19 @@ var (
 			for _, p := range serverFirstPorts {
 				serverFirstByPort[p] = true
 			}
+			proxyProtocolByPort := map[int]bool{}
+			for _, p := range proxyProtocolPorts {
+				proxyProtocolByPort[p] = true
+			}
 			portIndex := 0
 			for i, p := range httpPorts {
 				ports[portIndex] = &common.Port{
-					Name:        "http-" + strconv.Itoa(i),
-					Protocol:    protocol.HTTP,
-					Port:        p,
-					TLS:         tlsByPort[p],
-					ServerFirst: serverFirstByPort[p],
+					Name:          "http-" + strconv.Itoa(i),
+					Protocol:      protocol.HTTP,
+					Port:          p,
+					TLS:           tlsByPort[p],
+					ServerFirst:   serverFirstByPort[p],
+					ProxyProtocol: proxyProtocolByPort[p],
 				}
 				portIndex++
 			}
@@ -86,11 +94,30 @@ var (
 			}
 			for i, p := range tcpPorts {
 				ports[portIndex] = &common.Port{
-					Name:        "tcp-" + strconv.Itoa(i),
-					Protocol:    protocol.TCP,
-					Port:        p,
-					TLS:         tlsByPort[p],
-					ServerFirst: serverFirstByPort[p],
+					Name:          "tcp-" + strconv.Itoa(i),
+					Protocol:      protocol.TCP,
+					Port:          p,
+					TLS:           tlsByPort[p],
+					ServerFirst:   serverFirstByPort[p],
+					ProxyProtocol: proxyProtocolByPort[p],
+				}
+				portIndex++
+			}
+			for i, p := range tlsPassthroughPorts {
+				ports[portIndex] = &common.Port{
+					Name:          "tls-" + strconv.Itoa(i),
+					Protocol:      protocol.TLS,
+					Port:          p,
+					ServerFirst:   serverFirstByPort[p],
+					ProxyProtocol: proxyProtocolByPort[p],
+				}
+				portIndex++
+			}
+			for i, p := range udpPorts {
+				ports[portIndex] = &common.Port{
+					Name:     "udp-" + strconv.Itoa(i),
+					Protocol: protocol.UDP,
+					Port:     p,
 				}
 				portIndex++
 			}
@@ -143,7 +170,12 @@ func init() {
 	rootCmd.PersistentFlags().IntSliceVar(&httpPorts, "port", []int{8080}, "HTTP/1.1 ports")
 	rootCmd.PersistentFlags().IntSliceVar(&grpcPorts, "grpc", []int{7070}, "GRPC ports")
 	rootCmd.PersistentFlags().IntSliceVar(&tcpPorts, "tcp", []int{9090}, "TCP ports")
+	rootCmd.PersistentFlags().IntSliceVar(&tlsPassthroughPorts, "tls-passthrough", []int{},
+		"TLS ports. The server terminates TLS and reports the SNI and ALPN presented by the client.")
+	rootCmd.PersistentFlags().IntSliceVar(&udpPorts, "udp", []int{}, "UDP ports")
 	rootCmd.PersistentFlags().IntSliceVar(&tlsPorts, "tls", []int{}, "Ports that are using TLS. These must be defined as http/grpc/tcp.")
+	rootCmd.PersistentFlags().IntSliceVar(&proxyProtocolPorts, "proxy-protocol", []int{},
+		"Ports expecting a PROXY protocol header at the start of connections. These must be defined as http/tcp/tls-passthrough.")
 	rootCmd.PersistentFlags().IntSliceVar(&instanceIPPorts, "bind-ip", []int{}, "Ports that are bound to INSTANCE_IP rather than wildcard IP.")
 	rootCmd.PersistentFlags().IntSliceVar(&localhostIPPorts, "bind-localhost", []int{}, "Ports that are bound to localhost rather than wildcard IP.")
 	rootCmd.PersistentFlags().IntSliceVar(&serverFirstPorts, "server-first", []int{}, "Ports that are server first. These must be defined as tcp.")
@@ -57,6 +57,10 @@ type Port struct {
 
 	// LocalhostIP determines if echo will listen on the localhost IP; otherwise, it will listen on wildcard
 	LocalhostIP bool
+
+	// ProxyProtocol determines if connections to the port start with a PROXY protocol header.
+	// Only supported for HTTP, TCP and TLS ports.
+	ProxyProtocol bool
 }
 
 // PortList is a set of ports
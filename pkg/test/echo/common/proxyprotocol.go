@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The PROXY protocol headers, see https://www.haproxy.org/download/2.3/doc/proxy-protocol.txt.
+const (
+	proxyProtocolV1Prefix = "PROXY "
+	// proxyProtocolV1MaxLength is the maximum length of a v1 header, including the CRLF.
+	proxyProtocolV1MaxLength = 107
+)
+
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// WriteProxyProtocolHeader writes a PROXY protocol v1 header for a TCP connection from src to dst.
+// If the addresses are not TCP addresses of the same family, the UNKNOWN header is written.
+func WriteProxyProtocolHeader(w io.Writer, src, dst net.Addr) error {
+	header := proxyProtocolV1Prefix + "UNKNOWN\r\n"
+	s, sok := src.(*net.TCPAddr)
+	d, dok := dst.(*net.TCPAddr)
+	if sok && dok {
+		switch {
+		case s.IP.To4() != nil && d.IP.To4() != nil:
+			header = fmt.Sprintf("%sTCP4 %s %s %d %d\r\n", proxyProtocolV1Prefix, s.IP.To4(), d.IP.To4(), s.Port, d.Port)
+		case s.IP.To4() == nil && d.IP.To4() == nil:
+			header = fmt.Sprintf("%sTCP6 %s %s %d %d\r\n", proxyProtocolV1Prefix, s.IP, d.IP, s.Port, d.Port)
+		}
+	}
+	_, err := io.WriteString(w, header)
+	return err
+}
+
+// ReadProxyProtocolHeader reads a PROXY protocol v1 or v2 header from r and returns the source address it
+// carries. The returned address is nil if the header does not carry one, e.g. for health checks sent by proxies.
+func ReadProxyProtocolHeader(r *bufio.Reader) (net.Addr, error) {
+	prefix, err := r.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading PROXY protocol header: %v", err)
+	}
+	if bytes.Equal(prefix, proxyProtocolV2Signature) {
+		return readProxyProtocolV2(r)
+	}
+	if strings.HasPrefix(string(prefix), proxyProtocolV1Prefix) {
+		return readProxyProtocolV1(r)
+	}
+	return nil, fmt.Errorf("missing PROXY protocol header, got %q", prefix)
+}
+
+func readProxyProtocolV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyProtocolV1MaxLength {
+			return nil, fmt.Errorf("PROXY protocol v1 header exceeds %d bytes", proxyProtocolV1MaxLength)
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed reading PROXY protocol v1 header: %v", err)
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r\n"))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil || net.ParseIP(fields[3]) == nil {
+		return nil, fmt.Errorf("invalid address in PROXY protocol v1 header %q", line)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in PROXY protocol v1 header %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readProxyProtocolV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyProtocolV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed reading PROXY protocol v2 header: %v", err)
+	}
+	verCmd, family := header[12], header[13]
+	addresses := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, addresses); err != nil {
+		return nil, fmt.Errorf("failed reading PROXY protocol v2 addresses: %v", err)
+	}
+	if verCmd>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", verCmd>>4)
+	}
+	// The LOCAL command is used by proxies for health checks, the addresses are ignored.
+	if verCmd&0x0f == 0 {
+		return nil, nil
+	}
+
+	var ipLen int
+	switch family >> 4 {
+	case 1:
+		ipLen = net.IPv4len
+	case 2:
+		ipLen = net.IPv6len
+	default:
+		// Unix sockets and unspecified families don't carry an IP address.
+		return nil, nil
+	}
+	if len(addresses) < 2*ipLen+4 {
+		return nil, fmt.Errorf("PROXY protocol v2 addresses too short: %d bytes", len(addresses))
+	}
+	ip := net.IP(addresses[:ipLen])
+	port := int(binary.BigEndian.Uint16(addresses[2*ipLen:]))
+	if family&0x0f == 2 {
+		return &net.UDPAddr{IP: ip, Port: port}, nil
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
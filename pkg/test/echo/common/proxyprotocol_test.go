@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestProxyProtocolHeader(t *testing.T) {
+	v2 := func(verCmd, family byte, addresses ...byte) string {
+		b := append(append([]byte{}, proxyProtocolV2Signature...), verCmd, family, 0, byte(len(addresses)))
+		return string(append(b, addresses...))
+	}
+	cases := []struct {
+		name   string
+		header string
+		want   string
+		err    bool
+	}{
+		{
+			name:   "v1 tcp4",
+			header: "PROXY TCP4 10.0.0.1 10.0.0.2 5000 80\r\n",
+			want:   "10.0.0.1:5000",
+		},
+		{
+			name:   "v1 tcp6",
+			header: "PROXY TCP6 fd00::1 fd00::2 5000 80\r\n",
+			want:   "[fd00::1]:5000",
+		},
+		{
+			name:   "v1 unknown",
+			header: "PROXY UNKNOWN\r\n",
+		},
+		{
+			name:   "v1 invalid",
+			header: "PROXY TCP4 10.0.0.1\r\n",
+			err:    true,
+		},
+		{
+			name: "v2 tcp4",
+			header: v2(0x21, 0x11,
+				10, 0, 0, 1,
+				10, 0, 0, 2,
+				0x13, 0x88,
+				0, 80),
+			want: "10.0.0.1:5000",
+		},
+		{
+			name:   "v2 local",
+			header: v2(0x20, 0x00),
+		},
+		{
+			name:   "v2 truncated addresses",
+			header: v2(0x21, 0x11, 10, 0, 0, 1),
+			err:    true,
+		},
+		{
+			name:   "missing header",
+			header: "GET / HTTP/1.1\r\n",
+			err:    true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tc.header + "payload"))
+			addr, err := ReadProxyProtocolHeader(r)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got address %v", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tc.want {
+				t.Errorf("got address %q, want %q", got, tc.want)
+			}
+			if rest, _ := ioutil.ReadAll(r); string(rest) != "payload" {
+				t.Errorf("got remaining data %q, want %q", rest, "payload")
+			}
+		})
+	}
+}
+
+func TestWriteProxyProtocolHeader(t *testing.T) {
+	cases := []struct {
+		name     string
+		src, dst net.Addr
+		want     string
+	}{
+		{
+			name: "tcp4",
+			src:  &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000},
+			dst:  &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 80},
+			want: "PROXY TCP4 10.0.0.1 10.0.0.2 5000 80\r\n",
+		},
+		{
+			name: "tcp6",
+			src:  &net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 5000},
+			dst:  &net.TCPAddr{IP: net.ParseIP("fd00::2"), Port: 80},
+			want: "PROXY TCP6 fd00::1 fd00::2 5000 80\r\n",
+		},
+		{
+			name: "mixed families",
+			src:  &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000},
+			dst:  &net.TCPAddr{IP: net.ParseIP("fd00::2"), Port: 80},
+			want: "PROXY UNKNOWN\r\n",
+		},
+		{
+			name: "unix",
+			src:  &net.UnixAddr{Name: "/tmp/a", Net: "unix"},
+			dst:  &net.UnixAddr{Name: "/tmp/b", Net: "unix"},
+			want: "PROXY UNKNOWN\r\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteProxyProtocolHeader(&b, tc.src, tc.dst); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tc.want {
+				t.Errorf("got %q, want %q", b.String(), tc.want)
+			}
+		})
+	}
+}
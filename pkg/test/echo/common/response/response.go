@@ -43,5 +43,7 @@ const (
 	ResponseHeader      Field = "ResponseHeader"
 	ClusterField        Field = "Cluster"
 	IstioVersionField   Field = "IstioVersion"
-	IPField             Field = "IP" // The Requester’s IP Address.
+	IPField             Field = "IP"   // The Requester’s IP Address.
+	SNIField            Field = "SNI"  // The server name presented by the client in TLS connections.
+	ALPNField           Field = "Alpn" // The application protocol negotiated in TLS connections.
 )
@@ -23,5 +23,9 @@ const (
 	GRPC      Instance = "grpc"
 	WebSocket Instance = "ws"
 	TCP       Instance = "tcp"
+	TLS       Instance = "tls"
+	UDP       Instance = "udp"
 	DNS       Instance = "dns"
+	// XDS is used for proxyless gRPC, the target is resolved through the xDS server in the gRPC bootstrap.
+	XDS Instance = "xds"
 )
@@ -183,7 +183,9 @@ type ForwardEchoRequest struct {
 	// List of ALPNs to present. If not set, this will be automatically be set based on the protocol
 	Alpn *Alpn `protobuf:"bytes,13,opt,name=alpn,proto3" json:"alpn,omitempty"`
 	// Server name (SNI) to present in TLS connections. If not set, Host will be used for http requests.
-	ServerName           string   `protobuf:"bytes,20,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	ServerName string `protobuf:"bytes,20,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	// If true, a PROXY protocol (v1) header will be sent at the start of TCP, TLS and HTTP/1.1 connections.
+	ProxyProtocol        bool     `protobuf:"varint,21,opt,name=proxyProtocol,proto3" json:"proxyProtocol,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -354,6 +356,13 @@ func (m *ForwardEchoRequest) GetServerName() string {
 	return ""
 }
 
+func (m *ForwardEchoRequest) GetProxyProtocol() bool {
+	if m != nil {
+		return m.ProxyProtocol
+	}
+	return false
+}
+
 type Alpn struct {
 	Value                []string `protobuf:"bytes,1,rep,name=value,proto3" json:"value,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -446,38 +455,39 @@ func init() {
 }
 
 var fileDescriptor_08134aea513e0001 = []byte{
-	// 495 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0xdb, 0x8e, 0xd3, 0x3c,
-	0x10, 0x56, 0xfe, 0x26, 0x69, 0x77, 0xb2, 0xdd, 0xee, 0x3f, 0xad, 0x2a, 0x53, 0x21, 0x88, 0x2a,
-	0xa1, 0xcd, 0x0d, 0x05, 0xb5, 0x4f, 0x80, 0x80, 0x8a, 0x1b, 0xb8, 0xc8, 0x22, 0x6e, 0x51, 0x48,
-	0x67, 0x69, 0xd4, 0x34, 0xce, 0xda, 0x4e, 0x57, 0x7d, 0x03, 0x5e, 0x8e, 0x77, 0x42, 0x3e, 0x94,
-	0xa4, 0xb0, 0xe2, 0xca, 0xfe, 0x0e, 0x1e, 0x7f, 0x1e, 0x0f, 0x00, 0xe5, 0x5b, 0xbe, 0xa8, 0x05,
-	0x57, 0x1c, 0x03, 0xb3, 0xcc, 0x6f, 0x20, 0x7a, 0x9f, 0x6f, 0x79, 0x4a, 0xf7, 0x0d, 0x49, 0x85,
-	0x0c, 0xfa, 0x7b, 0x92, 0x32, 0xfb, 0x4e, 0xcc, 0x8b, 0xbd, 0xe4, 0x22, 0x3d, 0xc1, 0x79, 0x02,
-	0x97, 0xd6, 0x28, 0x6b, 0x5e, 0x49, 0xfa, 0x87, 0xf3, 0x35, 0x84, 0x1f, 0x28, 0xdb, 0x90, 0xc0,
-	0x6b, 0xe8, 0xed, 0xe8, 0xe8, 0x74, 0xbd, 0xc5, 0x09, 0x04, 0x87, 0xac, 0x6c, 0x88, 0xfd, 0x67,
-	0x38, 0x0b, 0xe6, 0x3f, 0x7d, 0xc0, 0x35, 0x17, 0x0f, 0x99, 0xd8, 0x74, 0xc3, 0x4c, 0x20, 0xc8,
-	0x79, 0x53, 0x29, 0x53, 0x20, 0x48, 0x2d, 0xd0, 0x45, 0xef, 0x6b, 0x69, 0x0a, 0x04, 0xa9, 0xde,
-	0xe2, 0x0b, 0xb8, 0x52, 0xc5, 0x9e, 0x78, 0xa3, 0xbe, 0xee, 0x8b, 0x5c, 0x70, 0xc9, 0x7a, 0xb1,
-	0x97, 0xf4, 0xd2, 0xa1, 0x63, 0x3f, 0x1a, 0x52, 0x1f, 0x6c, 0x44, 0xc9, 0x7c, 0x9b, 0xa6, 0x11,
-	0x25, 0xde, 0x40, 0x7f, 0x6b, 0x92, 0x4a, 0x16, 0xc4, 0xbd, 0x24, 0x5a, 0x0e, 0x6d, 0x73, 0x16,
-	0x36, 0x7f, 0x7a, 0x52, 0xbb, 0x8f, 0x0d, 0xcf, 0x1e, 0x8b, 0x53, 0x08, 0xf7, 0xa4, 0xb6, 0x7c,
-	0xc3, 0x2e, 0x8c, 0xe0, 0x90, 0xce, 0xbe, 0x55, 0xaa, 0x5e, 0xb2, 0x7e, 0xec, 0x25, 0x83, 0xd4,
-	0x82, 0x13, 0xbb, 0x62, 0xa3, 0x96, 0x5d, 0x61, 0x0c, 0x91, 0x24, 0x71, 0x20, 0xb1, 0x2e, 0x84,
-	0x54, 0x6c, 0x60, 0xb4, 0x2e, 0x85, 0x09, 0x8c, 0xee, 0x78, 0x59, 0xf2, 0x87, 0x94, 0x36, 0x85,
-	0xa0, 0x5c, 0x49, 0x76, 0x65, 0x5c, 0x7f, 0xd2, 0x88, 0xe0, 0xe7, 0x24, 0x14, 0x03, 0x93, 0xc6,
-	0xec, 0x4f, 0xdf, 0x10, 0xb5, 0xdf, 0x30, 0x85, 0x30, 0xcf, 0xde, 0x6a, 0xdf, 0xa5, 0x4d, 0x6d,
-	0x11, 0xce, 0x60, 0xa0, 0x4f, 0xac, 0x8b, 0x92, 0xd8, 0xb5, 0x51, 0x7e, 0x63, 0xdd, 0x83, 0x1d,
-	0x1d, 0x8d, 0xf4, 0xbf, 0xed, 0x81, 0x83, 0xf8, 0x0c, 0xc0, 0x9e, 0x37, 0x22, 0x1a, 0xb1, 0xc3,
-	0xe0, 0x02, 0xb0, 0xa8, 0x24, 0xe5, 0x8d, 0xa0, 0xdb, 0x5d, 0x51, 0x7f, 0x21, 0x51, 0xdc, 0x1d,
-	0xd9, 0xd8, 0x3c, 0xe0, 0x11, 0x05, 0x9f, 0x83, 0x9f, 0x95, 0x75, 0xc5, 0x86, 0xb1, 0x97, 0x44,
-	0xcb, 0xc8, 0xfd, 0xc9, 0x9b, 0xb2, 0xae, 0x52, 0x23, 0xe8, 0x0b, 0x6d, 0x77, 0x3e, 0x65, 0x7b,
-	0x62, 0x13, 0x7b, 0x61, 0xcb, 0xcc, 0x9f, 0x82, 0xaf, 0xdd, 0xed, 0xb4, 0x79, 0x71, 0xaf, 0x9d,
-	0xb6, 0x97, 0x30, 0x3e, 0x1b, 0x36, 0x37, 0xd0, 0x53, 0x08, 0x79, 0xa3, 0xea, 0x46, 0x39, 0xb7,
-	0x43, 0xcb, 0x1f, 0x1e, 0x8c, 0xb4, 0xf1, 0x33, 0x49, 0x75, 0x4b, 0xe2, 0x50, 0xe4, 0x84, 0xaf,
-	0xc0, 0xd7, 0x14, 0xa2, 0xcb, 0xd6, 0x99, 0xda, 0xd9, 0xf8, 0x8c, 0x73, 0xc5, 0xdf, 0x41, 0xd4,
-	0xb9, 0x13, 0x9f, 0x38, 0xcf, 0xdf, 0x43, 0x3f, 0x9b, 0x3d, 0x26, 0xd9, 0x2a, 0xdf, 0x42, 0x23,
-	0xad, 0x7e, 0x05, 0x00, 0x00, 0xff, 0xff, 0x06, 0x06, 0xb1, 0xb3, 0xc8, 0x03, 0x00, 0x00,
+	// 512 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x53, 0xdd, 0x6e, 0xd3, 0x4c,
+	0x10, 0x95, 0xbf, 0xd8, 0x4e, 0x3a, 0x6e, 0x9a, 0x7e, 0x93, 0x10, 0x2d, 0x11, 0x02, 0x2b, 0x02,
+	0xd5, 0x37, 0x04, 0x94, 0x3c, 0x01, 0x02, 0x22, 0x6e, 0x40, 0xc8, 0x45, 0xdc, 0x22, 0xe3, 0x4c,
+	0x89, 0x15, 0x27, 0xeb, 0xee, 0xae, 0x53, 0xf2, 0x06, 0xbc, 0x2f, 0x2f, 0x80, 0xf6, 0x27, 0xd8,
+	0x81, 0x8a, 0x2b, 0xef, 0x39, 0x67, 0x76, 0xe6, 0xcc, 0xec, 0x18, 0x80, 0xf2, 0x35, 0x9f, 0x55,
+	0x82, 0x2b, 0x8e, 0x81, 0xf9, 0x4c, 0xaf, 0x20, 0x7a, 0x9b, 0xaf, 0x79, 0x4a, 0xb7, 0x35, 0x49,
+	0x85, 0x0c, 0xba, 0x5b, 0x92, 0x32, 0xfb, 0x46, 0xcc, 0x8b, 0xbd, 0xe4, 0x2c, 0x3d, 0xc2, 0x69,
+	0x02, 0xe7, 0x36, 0x50, 0x56, 0x7c, 0x27, 0xe9, 0x1f, 0x91, 0x2f, 0x21, 0x7c, 0x47, 0xd9, 0x8a,
+	0x04, 0x5e, 0x42, 0x67, 0x43, 0x07, 0xa7, 0xeb, 0x23, 0x8e, 0x20, 0xd8, 0x67, 0x65, 0x4d, 0xec,
+	0x3f, 0xc3, 0x59, 0x30, 0xfd, 0xe9, 0x03, 0x2e, 0xb9, 0xb8, 0xcb, 0xc4, 0xaa, 0x6d, 0x66, 0x04,
+	0x41, 0xce, 0xeb, 0x9d, 0x32, 0x09, 0x82, 0xd4, 0x02, 0x9d, 0xf4, 0xb6, 0x92, 0x26, 0x41, 0x90,
+	0xea, 0x23, 0x3e, 0x83, 0x0b, 0x55, 0x6c, 0x89, 0xd7, 0xea, 0xcb, 0xb6, 0xc8, 0x05, 0x97, 0xac,
+	0x13, 0x7b, 0x49, 0x27, 0xed, 0x3b, 0xf6, 0xbd, 0x21, 0xf5, 0xc5, 0x5a, 0x94, 0xcc, 0xb7, 0x6e,
+	0x6a, 0x51, 0xe2, 0x15, 0x74, 0xd7, 0xc6, 0xa9, 0x64, 0x41, 0xdc, 0x49, 0xa2, 0x79, 0xdf, 0x0e,
+	0x67, 0x66, 0xfd, 0xa7, 0x47, 0xb5, 0xdd, 0x6c, 0x78, 0xd2, 0x2c, 0x8e, 0x21, 0xdc, 0x92, 0x5a,
+	0xf3, 0x15, 0x3b, 0x33, 0x82, 0x43, 0xda, 0xfb, 0x5a, 0xa9, 0x6a, 0xce, 0xba, 0xb1, 0x97, 0xf4,
+	0x52, 0x0b, 0x8e, 0xec, 0x82, 0x0d, 0x1a, 0x76, 0x81, 0x31, 0x44, 0x92, 0xc4, 0x9e, 0xc4, 0xb2,
+	0x10, 0x52, 0xb1, 0x9e, 0xd1, 0xda, 0x14, 0x26, 0x30, 0xb8, 0xe1, 0x65, 0xc9, 0xef, 0x52, 0x5a,
+	0x15, 0x82, 0x72, 0x25, 0xd9, 0x85, 0x89, 0xfa, 0x93, 0x46, 0x04, 0x3f, 0x27, 0xa1, 0x18, 0x18,
+	0x37, 0xe6, 0x7c, 0x7c, 0x86, 0xa8, 0x79, 0x86, 0x31, 0x84, 0x79, 0xf6, 0x5a, 0xc7, 0x9d, 0x5b,
+	0xd7, 0x16, 0xe1, 0x04, 0x7a, 0xfa, 0xc6, 0xb2, 0x28, 0x89, 0x5d, 0x1a, 0xe5, 0x37, 0xd6, 0x33,
+	0xd8, 0xd0, 0xc1, 0x48, 0xff, 0xdb, 0x19, 0x38, 0x88, 0x8f, 0x01, 0xec, 0x7d, 0x23, 0xa2, 0x11,
+	0x5b, 0x0c, 0xce, 0x00, 0x8b, 0x9d, 0xa4, 0xbc, 0x16, 0x74, 0xbd, 0x29, 0xaa, 0xcf, 0x24, 0x8a,
+	0x9b, 0x03, 0x1b, 0x9a, 0x06, 0xee, 0x51, 0xf0, 0x09, 0xf8, 0x59, 0x59, 0xed, 0x58, 0x3f, 0xf6,
+	0x92, 0x68, 0x1e, 0xb9, 0x37, 0x79, 0x55, 0x56, 0xbb, 0xd4, 0x08, 0xba, 0xa0, 0x9d, 0xce, 0x87,
+	0x6c, 0x4b, 0x6c, 0x64, 0x0b, 0x36, 0x0c, 0x3e, 0x85, 0x7e, 0x25, 0xf8, 0xf7, 0xc3, 0x47, 0x7d,
+	0x31, 0xe7, 0x25, 0x7b, 0x60, 0x6a, 0x9d, 0x92, 0xd3, 0x47, 0xe0, 0xeb, 0x9c, 0xcd, 0x4e, 0x7a,
+	0x71, 0xa7, 0xd9, 0xc9, 0xe7, 0x30, 0x3c, 0x59, 0x49, 0xb7, 0xf6, 0x63, 0x08, 0x79, 0xad, 0xaa,
+	0x5a, 0xb9, 0x68, 0x87, 0xe6, 0x3f, 0x3c, 0x18, 0xe8, 0xc0, 0x4f, 0x24, 0xd5, 0x35, 0x89, 0x7d,
+	0x91, 0x13, 0xbe, 0x00, 0x5f, 0x53, 0x88, 0xae, 0x83, 0xd6, 0x6e, 0x4f, 0x86, 0x27, 0x9c, 0x4b,
+	0xfe, 0x06, 0xa2, 0x56, 0x4d, 0x7c, 0xe8, 0x62, 0xfe, 0xfe, 0x35, 0x26, 0x93, 0xfb, 0x24, 0x9b,
+	0xe5, 0x6b, 0x68, 0xa4, 0xc5, 0xaf, 0x01, 0x00, 0xee, 0xb9, 0xef, 0xa7, 0xee, 0x03, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -56,7 +56,7 @@ func (s *grpcInstance) GetConfig() Config {
 
 func (s *grpcInstance) Start(onReady OnReadyFunc) error {
 	// Listen on the given port and update the port if it changed from what was passed in.
-	listener, p, err := listenOnAddress(s.ListenerIP, s.Port.Port)
+	listener, p, err := listenOnAddress(s.ListenerIP, s.Port.Port, false)
 	if err != nil {
 		return err
 	}
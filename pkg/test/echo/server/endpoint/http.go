@@ -97,12 +97,12 @@ func (s *httpInstance) Start(onReady OnReadyFunc) error {
 			},
 		}
 		// Listen on the given port and update the port if it changed from what was passed in.
-		listener, port, err = listenOnAddressTLS(s.ListenerIP, s.Port.Port, config)
+		listener, port, err = listenOnAddressTLS(s.ListenerIP, s.Port.Port, s.Port.ProxyProtocol, config)
 		// Store the actual listening port back to the argument.
 		s.Port.Port = port
 	} else {
 		// Listen on the given port and update the port if it changed from what was passed in.
-		listener, port, err = listenOnAddress(s.ListenerIP, s.Port.Port)
+		listener, port, err = listenOnAddress(s.ListenerIP, s.Port.Port, s.Port.ProxyProtocol)
 		// Store the actual listening port back to the argument.
 		s.Port.Port = port
 	}
@@ -154,6 +154,24 @@ func (s *httpInstance) awaitReady(onReady OnReadyFunc, port int) {
 	} else {
 		url = fmt.Sprintf("http://127.0.0.1:%d", port)
 	}
+	if s.Port != nil && s.Port.ProxyProtocol {
+		transport, _ := client.Transport.(*http.Transport)
+		if transport == nil {
+			transport = &http.Transport{}
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			if err := common.WriteProxyProtocolHeader(conn, conn.LocalAddr(), conn.RemoteAddr()); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		}
+		client.Transport = transport
+	}
 
 	err := retry.UntilSuccess(func() error {
 		resp, err := client.Get(url)
@@ -313,11 +331,15 @@ func (h *httpHandler) addResponsePayload(r *http.Request, body *bytes.Buffer) {
 
 	// Note: since this is the NegotiatedProtocol, it will be set to empty if the client sends an ALPN
 	// not supported by the server (ie one of h2,http/1.1,http/1.0)
-	var alpn string
+	var alpn, sni string
 	if r.TLS != nil {
 		alpn = r.TLS.NegotiatedProtocol
+		sni = r.TLS.ServerName
+	}
+	writeField(body, response.ALPNField, alpn)
+	if sni != "" {
+		writeField(body, response.SNIField, sni)
 	}
-	writeField(body, "Alpn", alpn)
 
 	keys := []string{}
 	for k := range r.Header {
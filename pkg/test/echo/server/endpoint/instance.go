@@ -57,8 +57,10 @@ func New(cfg Config) (Instance, error) {
 			return newHTTP(cfg), nil
 		case protocol.HTTP2, protocol.GRPC:
 			return newGRPC(cfg), nil
-		case protocol.TCP:
+		case protocol.TCP, protocol.TLS:
 			return newTCP(cfg), nil
+		case protocol.UDP:
+			return newUDP(cfg), nil
 		default:
 			return nil, fmt.Errorf("unsupported protocol: %s", cfg.Port.Protocol)
 		}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+// proxyProtocolListener accepts connections starting with a PROXY protocol header. The remote address of the
+// accepted connections is the source address carried by the header.
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{Conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// proxyProtocolConn reads the PROXY protocol header on first use rather than on accept, so that a slow or
+// misbehaving client does not block the accept loop.
+type proxyProtocolConn struct {
+	net.Conn
+	r *bufio.Reader
+
+	once   sync.Once
+	source net.Addr
+	err    error
+}
+
+func (c *proxyProtocolConn) readHeader() {
+	c.once.Do(func() {
+		c.source, c.err = common.ReadProxyProtocolHeader(c.r)
+		if c.err != nil {
+			epLog.Warnf("PROXY protocol header from %s rejected: %v", c.Conn.RemoteAddr(), c.err)
+		}
+	})
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.source != nil {
+		return c.source
+	}
+	return c.Conn.RemoteAddr()
+}
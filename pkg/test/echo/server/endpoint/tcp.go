@@ -21,6 +21,7 @@ import (
 	"net"
 	"strconv"
 
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/istio/pkg/test/util/retry"
@@ -47,19 +48,27 @@ func (s *tcpInstance) Start(onReady OnReadyFunc) error {
 	var listener net.Listener
 	var port int
 	var err error
-	if s.Port.TLS {
+	if s.isTLS() {
 		cert, cerr := tls.LoadX509KeyPair(s.TLSCert, s.TLSKey)
 		if cerr != nil {
 			return fmt.Errorf("could not load TLS keys: %v", cerr)
 		}
 		config := &tls.Config{Certificates: []tls.Certificate{cert}}
+		if s.Port.Protocol == protocol.TLS {
+			// Accept any ALPN presented by the client, so that the protocol it prefers is reported back.
+			config.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+				c := config.Clone()
+				c.NextProtos = info.SupportedProtos
+				return c, nil
+			}
+		}
 		// Listen on the given port and update the port if it changed from what was passed in.
-		listener, port, err = listenOnAddressTLS(s.ListenerIP, s.Port.Port, config)
+		listener, port, err = listenOnAddressTLS(s.ListenerIP, s.Port.Port, s.Port.ProxyProtocol, config)
 		// Store the actual listening port back to the argument.
 		s.Port.Port = port
 	} else {
 		// Listen on the given port and update the port if it changed from what was passed in.
-		listener, port, err = listenOnAddress(s.ListenerIP, s.Port.Port)
+		listener, port, err = listenOnAddress(s.ListenerIP, s.Port.Port, s.Port.ProxyProtocol)
 		// Store the actual listening port back to the argument.
 		s.Port.Port = port
 	}
@@ -68,7 +77,9 @@ func (s *tcpInstance) Start(onReady OnReadyFunc) error {
 	}
 
 	s.l = listener
-	if s.Port.TLS {
+	if s.Port.Protocol == protocol.TLS {
+		fmt.Printf("Listening TLS on %v\n", port)
+	} else if s.Port.TLS {
 		fmt.Printf("Listening TCP (over TLS) on %v\n", port)
 	} else {
 		fmt.Printf("Listening TCP on %v\n", port)
@@ -93,6 +104,11 @@ func (s *tcpInstance) Start(onReady OnReadyFunc) error {
 	return nil
 }
 
+// isTLS returns true if connections are terminated with TLS: the port is a TLS port, or a TCP port over TLS.
+func (s *tcpInstance) isTLS() bool {
+	return s.Port.TLS || s.Port.Protocol == protocol.TLS
+}
+
 // Handles incoming connection.
 func (s *tcpInstance) echo(conn net.Conn) {
 	defer common.Metrics.TCPRequests.With(common.PortLabel.Value(strconv.Itoa(s.Port.Port))).Increment()
@@ -149,6 +165,11 @@ func (s *tcpInstance) writeResponse(conn net.Conn) {
 		response.ServicePortField:    strconv.Itoa(s.Port.Port),
 		response.IPField:             ip,
 	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		respFields[response.SNIField] = state.ServerName
+		respFields[response.ALPNField] = state.NegotiatedProtocol
+	}
 	for field, val := range respFields {
 		val := fmt.Sprintf("%s=%s\n", string(field), val)
 		_, err := conn.Write([]byte(val))
@@ -177,6 +198,10 @@ func (s *tcpInstance) awaitReady(onReady OnReadyFunc, port int) {
 			return err
 		}
 		defer conn.Close()
+		if s.Port.ProxyProtocol {
+			// Don't have the server reject the connection for a missing header.
+			_ = common.WriteProxyProtocolHeader(conn, conn.LocalAddr(), conn.RemoteAddr())
+		}
 
 		// Server is up now, we're ready.
 		return nil
@@ -0,0 +1,145 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/common/response"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// maxDatagramSize is the maximum size of a UDP payload.
+const maxDatagramSize = 65507
+
+var _ Instance = &udpInstance{}
+
+type udpInstance struct {
+	Config
+	conn net.PacketConn
+}
+
+func newUDP(config Config) Instance {
+	return &udpInstance{
+		Config: config,
+	}
+}
+
+func (s *udpInstance) GetConfig() Config {
+	return s.Config
+}
+
+func (s *udpInstance) Start(onReady OnReadyFunc) error {
+	// Listen on the given port and update the port if it changed from what was passed in.
+	conn, port, err := listenOnUDPAddress(s.ListenerIP, s.Port.Port)
+	if err != nil {
+		return err
+	}
+	// Store the actual listening port back to the argument.
+	s.Port.Port = port
+	s.conn = conn
+	fmt.Printf("Listening UDP on %v\n", port)
+
+	// Start serving UDP traffic.
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !strings.Contains(err.Error(), "use of closed network connection") {
+					epLog.Warn("UDP read failed: " + err.Error())
+				}
+				return
+			}
+			s.echo(addr, buf[:n])
+		}
+	}()
+
+	// Notify the WaitGroup once the port has transitioned to ready.
+	go s.awaitReady(onReady, port)
+
+	return nil
+}
+
+// echo replies to a datagram with a single datagram holding the response fields, followed by the received payload.
+func (s *udpInstance) echo(addr net.Addr, payload []byte) {
+	defer common.Metrics.UDPRequests.With(common.PortLabel.Value(strconv.Itoa(s.Port.Port))).Increment()
+
+	ip, _, _ := net.SplitHostPort(addr.String())
+	var out bytes.Buffer
+	writeField(&out, response.StatusCodeField, response.StatusCodeOK)
+	writeField(&out, response.ClusterField, s.Cluster)
+	writeField(&out, response.IstioVersionField, s.IstioVersion)
+	writeField(&out, response.ServiceVersionField, s.Version)
+	writeField(&out, response.ServicePortField, strconv.Itoa(s.Port.Port))
+	writeField(&out, response.IPField, ip)
+	out.Write(payload)
+
+	reply := out.Bytes()
+	if len(reply) > maxDatagramSize {
+		reply = reply[:maxDatagramSize]
+	}
+	if _, err := s.conn.WriteTo(reply, addr); err != nil {
+		epLog.Warnf("UDP write to %s failed: %v", addr, err)
+	}
+}
+
+func (s *udpInstance) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+func (s *udpInstance) awaitReady(onReady OnReadyFunc, port int) {
+	defer onReady()
+
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+
+	err := retry.UntilSuccess(func() error {
+		conn, err := net.Dial("udp", address)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		// UDP is connectionless, the server is only known to be up once it replies.
+		if err := conn.SetDeadline(time.Now().Add(readyInterval)); err != nil {
+			return err
+		}
+		if _, err := conn.Write([]byte("ready")); err != nil {
+			return err
+		}
+		buf := make([]byte, maxDatagramSize)
+		if _, err := conn.Read(buf); err != nil {
+			return err
+		}
+
+		// Server is up now, we're ready.
+		return nil
+	}, retry.Timeout(readyTimeout), retry.Delay(readyInterval))
+
+	if err != nil {
+		epLog.Errorf("readiness failed for endpoint %s: %v", address, err)
+	} else {
+		epLog.Infof("ready for UDP endpoint %s", address)
+	}
+}
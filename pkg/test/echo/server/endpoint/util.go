@@ -27,24 +27,37 @@ import (
 
 var epLog = log.RegisterScope("endpoint", "echo serverside", 0)
 
-func listenOnAddress(ip string, port int) (net.Listener, int, error) {
+func listenOnAddress(ip string, port int, proxyProtocol bool) (net.Listener, int, error) {
 	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", ip, port))
 	if err != nil {
 		return nil, 0, err
 	}
 
 	port = ln.Addr().(*net.TCPAddr).Port
+	if proxyProtocol {
+		ln = &proxyProtocolListener{Listener: ln}
+	}
 	return ln, port, nil
 }
 
-func listenOnAddressTLS(ip string, port int, cfg *tls.Config) (net.Listener, int, error) {
-	ln, err := tls.Listen("tcp", fmt.Sprintf("%s:%d", ip, port), cfg)
+func listenOnAddressTLS(ip string, port int, proxyProtocol bool, cfg *tls.Config) (net.Listener, int, error) {
+	// The PROXY protocol header precedes the TLS handshake, so TLS is layered over the PROXY protocol listener.
+	ln, port, err := listenOnAddress(ip, port, proxyProtocol)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	port = ln.Addr().(*net.TCPAddr).Port
-	return ln, port, nil
+	return tls.NewListener(ln, cfg), port, nil
+}
+
+func listenOnUDPAddress(ip string, port int) (net.PacketConn, int, error) {
+	conn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", ip, port))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	port = conn.LocalAddr().(*net.UDPAddr).Port
+	return conn, port, nil
 }
 
 func listenOnUDS(uds string) (net.Listener, error) {
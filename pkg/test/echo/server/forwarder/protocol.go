@@ -35,6 +35,8 @@ import (
 	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	// To install the xds resolvers and balancers, used by proxyless gRPC requests.
+	_ "google.golang.org/grpc/xds"
 
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/scheme"
@@ -68,6 +70,23 @@ func newProtocol(cfg Config) (protocol, error) {
 			return net.Dial("unix", cfg.UDS)
 		}
 	}
+	if cfg.Request.ProxyProtocol {
+		dial := httpDialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		httpDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			if err := common.WriteProxyProtocolHeader(conn, conn.LocalAddr(), conn.RemoteAddr()); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		}
+	}
 
 	rawURL := cfg.Request.Url
 	u, err := url.Parse(rawURL)
@@ -231,6 +250,18 @@ func newProtocol(cfg Config) (protocol, error) {
 			conn:   grpcConn,
 			client: proto.NewEchoTestServiceClient(grpcConn),
 		}, nil
+	case scheme.XDS:
+		// The target and its endpoints are resolved through the xDS server, see GRPC_XDS_BOOTSTRAP.
+		ctx, cancel := context.WithTimeout(context.Background(), common.ConnectionTimeout)
+		defer cancel()
+		grpcConn, err := cfg.Dialer.GRPC(ctx, rawURL, grpc.WithInsecure())
+		if err != nil {
+			return nil, err
+		}
+		return &grpcProtocol{
+			conn:   grpcConn,
+			client: proto.NewEchoTestServiceClient(grpcConn),
+		}, nil
 	case scheme.WebSocket:
 		dialer := &websocket.Dialer{
 			TLSClientConfig:  tlsConfig,
@@ -242,7 +273,7 @@ func newProtocol(cfg Config) (protocol, error) {
 		}, nil
 	case scheme.DNS:
 		return &dnsProtocol{}, nil
-	case scheme.TCP:
+	case scheme.TCP, scheme.TLS:
 		return &tcpProtocol{
 			conn: func() (net.Conn, error) {
 				dialer := net.Dialer{
@@ -253,10 +284,40 @@ func newProtocol(cfg Config) (protocol, error) {
 				ctx, cancel := context.WithTimeout(context.Background(), common.ConnectionTimeout)
 				defer cancel()
 
-				if getClientCertificate == nil {
-					return cfg.Dialer.TCP(dialer, ctx, address)
+				conn, err := cfg.Dialer.TCP(dialer, ctx, address)
+				if err != nil {
+					return nil, err
 				}
-				return tls.Dial("tcp", address, tlsConfig)
+				if cfg.Request.ProxyProtocol {
+					if err := common.WriteProxyProtocolHeader(conn, conn.LocalAddr(), conn.RemoteAddr()); err != nil {
+						_ = conn.Close()
+						return nil, err
+					}
+				}
+				if getClientCertificate == nil && scheme.Instance(u.Scheme) != scheme.TLS {
+					return conn, nil
+				}
+				// Like tls.Dial, present the host of the URL as SNI if no server name is set.
+				config := tlsConfig
+				if config.ServerName == "" {
+					config = tlsConfig.Clone()
+					config.ServerName = u.Hostname()
+				}
+				return tls.Client(conn, config), nil
+			},
+		}, nil
+	case scheme.UDP:
+		return &udpProtocol{
+			conn: func() (net.Conn, error) {
+				dialer := net.Dialer{
+					Timeout: timeout,
+				}
+				address := rawURL[len(u.Scheme+"://"):]
+
+				ctx, cancel := context.WithTimeout(context.Background(), common.ConnectionTimeout)
+				defer cancel()
+
+				return dialer.DialContext(ctx, "udp", address)
 			},
 		}, nil
 	}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/common/response"
+)
+
+var _ protocol = &udpProtocol{}
+
+// maxDatagramSize is the maximum size of a UDP payload.
+const maxDatagramSize = 65507
+
+type udpProtocol struct {
+	// conn returns a new connection. As for TCP, a new socket is used for each request, so that the
+	// replies of different requests can't be mixed up.
+	conn func() (net.Conn, error)
+}
+
+func (c *udpProtocol) makeRequest(ctx context.Context, req *request) (string, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	msgBuilder := strings.Builder{}
+	msgBuilder.WriteString(fmt.Sprintf("[%d] Url=%s\n", req.RequestID, req.URL))
+
+	if req.Message != "" {
+		msgBuilder.WriteString(fmt.Sprintf("[%d] Echo=%s\n", req.RequestID, req.Message))
+	}
+
+	// Apply per-request timeout to calculate deadline for reads/writes.
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	// Apply the deadline to the connection.
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return msgBuilder.String(), err
+	}
+
+	message := "HelloWorld"
+	if req.Message != "" {
+		message = req.Message
+	}
+
+	if _, err := conn.Write([]byte(message + "\n")); err != nil {
+		fwLog.Warnf("UDP write failed: %v", err)
+		return msgBuilder.String(), err
+	}
+
+	// The server replies with a single datagram.
+	buf := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		fwLog.Warnf("UDP read failed: %v", err)
+		return msgBuilder.String(), err
+	}
+
+	// format the output for forwarder response
+	for _, line := range strings.Split(string(buf[:n]), "\n") {
+		if line != "" {
+			msgBuilder.WriteString(fmt.Sprintf("[%d body] %s\n", req.RequestID, line))
+		}
+	}
+
+	msg := msgBuilder.String()
+	expected := fmt.Sprintf("%s=%s", string(response.StatusCodeField), response.StatusCodeOK)
+	if !strings.Contains(msg, expected) {
+		return msg, fmt.Errorf("expect to recv message with %s, got %s", expected, msg)
+	}
+	return msg, nil
+}
+
+func (c *udpProtocol) Close() error {
+	return nil
+}
@@ -183,6 +183,8 @@ func (s *Instance) validate() error {
 	for _, port := range s.Ports {
 		switch port.Protocol {
 		case protocol.TCP:
+		case protocol.TLS:
+		case protocol.UDP:
 		case protocol.HTTP:
 		case protocol.HTTPS:
 		case protocol.HTTP2:
@@ -190,6 +192,13 @@ func (s *Instance) validate() error {
 		default:
 			return fmt.Errorf("protocol %v not currently supported", port.Protocol)
 		}
+		if port.ProxyProtocol {
+			switch port.Protocol {
+			case protocol.HTTP, protocol.HTTPS, protocol.TCP, protocol.TLS:
+			default:
+				return fmt.Errorf("PROXY protocol not supported for protocol %v", port.Protocol)
+			}
+		}
 	}
 	return nil
 }
@@ -24,6 +24,9 @@ import (
 	// imported to trigger registration
 	_ "istio.io/istio/pkg/test/framework/components/cluster/kube"
 
+	// imported to trigger registration
+	_ "istio.io/istio/pkg/test/framework/components/cluster/local"
+
 	// imported to trigger registration
 	_ "istio.io/istio/pkg/test/framework/components/cluster/staticvm"
 	"istio.io/istio/pkg/test/scopes"
@@ -107,7 +110,7 @@ func (a factory) Build() (cluster.Clusters, error) {
 }
 
 func validPrimaryOrConfig(c cluster.Cluster) bool {
-	return c.Kind() == cluster.Kubernetes || c.Kind() == cluster.Fake
+	return c.Kind() == cluster.Kubernetes || c.Kind() == cluster.Fake || c.Kind() == cluster.Local
 }
 
 func buildCluster(cfg cluster.Config, allClusters cluster.Map) (cluster.Cluster, error) {
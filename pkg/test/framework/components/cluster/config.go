@@ -27,6 +27,7 @@ const (
 	Fake       Kind = "Fake"
 	Aggregate  Kind = "Aggregate"
 	StaticVM   Kind = "StaticVM"
+	Local      Kind = "Local"
 	Unknown    Kind = "Unknown"
 )
 
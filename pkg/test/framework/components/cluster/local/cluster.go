@@ -0,0 +1,258 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	extscheme "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	gatewayscheme "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/scheme"
+
+	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/yml"
+)
+
+var (
+	_ echo.Cluster = &Cluster{}
+
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+
+	// serviceCIDR is the range cluster IPs are allocated from. The addresses are not routable, traffic to
+	// services is captured by the listeners the proxies bind to the workload addresses.
+	serviceCIDR = net.IPNet{IP: net.IPv4(240, 240, 0, 0), Mask: net.CIDRMask(16, 32)}
+
+	// clusterScoped lists the kinds that are not namespaced, which may be applied by the tests.
+	clusterScoped = map[string]bool{
+		"Namespace":                      true,
+		"CustomResourceDefinition":       true,
+		"ClusterRole":                    true,
+		"ClusterRoleBinding":             true,
+		"MutatingWebhookConfiguration":   true,
+		"ValidatingWebhookConfiguration": true,
+	}
+)
+
+func init() {
+	utilruntime.Must(kubescheme.AddToScheme(scheme))
+	utilruntime.Must(extscheme.AddToScheme(scheme))
+	utilruntime.Must(istioscheme.AddToScheme(scheme))
+	utilruntime.Must(gatewayscheme.AddToScheme(scheme))
+}
+
+// Cluster is an in-memory cluster backed by the fake kube client, for an Istio control plane running in
+// the test process. There is no API server: applied objects are written to the fake clientsets as is, and
+// nothing reconciles them, e.g. no pods are created for a Deployment.
+type Cluster struct {
+	// ExtendedClient is embedded to interact with the in-memory cluster.
+	kube.ExtendedClient
+
+	// Topology is embedded to include common functionality.
+	cluster.Topology
+
+	version *version.Info
+
+	mu sync.Mutex
+	// allocatedIPs is the number of cluster IPs allocated to services so far.
+	allocatedIPs uint32
+}
+
+// CanDeploy for a local cluster returns true if the config is a non-vm. Workloads are always
+// local processes, there is no difference between pods and VMs.
+func (c *Cluster) CanDeploy(config echo.Config) (echo.Config, bool) {
+	if config.DeployAsVM {
+		return echo.Config{}, false
+	}
+	return config, true
+}
+
+func (c *Cluster) GetKubernetesVersion() (*version.Info, error) {
+	return c.version, nil
+}
+
+func (c *Cluster) String() string {
+	buf := &bytes.Buffer{}
+
+	_, _ = fmt.Fprint(buf, c.Topology.String())
+	_, _ = fmt.Fprintf(buf, "Version:            %s\n", c.version.GitVersion)
+
+	return buf.String()
+}
+
+// ApplyYAMLFiles writes the objects in the given files to the fake clientsets, replacing existing objects.
+func (c *Cluster) ApplyYAMLFiles(namespace string, yamlFiles ...string) error {
+	objs, err := readObjects(namespace, yamlFiles)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := c.apply(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteYAMLFiles removes the objects in the given files from the fake clientsets.
+func (c *Cluster) DeleteYAMLFiles(namespace string, yamlFiles ...string) error {
+	objs, err := readObjects(namespace, yamlFiles)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		tracker, gvr, err := c.trackerFor(obj)
+		if err != nil {
+			return err
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		if err := tracker.Delete(gvr, m.GetNamespace(), m.GetName()); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) apply(obj runtime.Object) error {
+	tracker, gvr, err := c.trackerFor(obj)
+	if err != nil {
+		return err
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if svc, ok := obj.(*corev1.Service); ok {
+		if err := c.allocateClusterIP(svc); err != nil {
+			return err
+		}
+	}
+	err = tracker.Create(gvr, obj, m.GetNamespace())
+	if kerrors.IsAlreadyExists(err) {
+		err = tracker.Update(gvr, obj, m.GetNamespace())
+	}
+	return err
+}
+
+// allocateClusterIP assigns a cluster IP to a non-headless service, as the service controller would.
+func (c *Cluster) allocateClusterIP(svc *corev1.Service) error {
+	if svc.Spec.ClusterIP != "" || svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return nil
+	}
+	// Keep the address of a service that is re-applied.
+	if existing, err := c.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{}); err == nil {
+		svc.Spec.ClusterIP = existing.Spec.ClusterIP
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ones, bits := serviceCIDR.Mask.Size()
+	if c.allocatedIPs >= 1<<(bits-ones)-2 {
+		return fmt.Errorf("no cluster IPs left in %s for service %s/%s", serviceCIDR.String(), svc.Namespace, svc.Name)
+	}
+	c.allocatedIPs++
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, serviceCIDR.IP.To4())
+	ip[2] = byte(c.allocatedIPs >> 8)
+	ip[3] = byte(c.allocatedIPs)
+	svc.Spec.ClusterIP = ip.String()
+	return nil
+}
+
+// trackerFor returns the object tracker of the fake clientset serving the object's API group.
+func (c *Cluster) trackerFor(obj runtime.Object) (clienttesting.ObjectTracker, schema.GroupVersionResource, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+
+	var clientset interface{}
+	switch {
+	case strings.HasSuffix(gvk.Group, "istio.io"):
+		clientset = c.Istio()
+	case gvk.Group == "networking.x-k8s.io":
+		clientset = c.GatewayAPI()
+	case gvk.Group == "apiextensions.k8s.io":
+		clientset = c.Ext()
+	default:
+		clientset = c.Kube()
+	}
+	f, ok := clientset.(interface {
+		Tracker() clienttesting.ObjectTracker
+	})
+	if !ok {
+		return nil, gvr, fmt.Errorf("clientset for %v is not a fake", gvk)
+	}
+	return f.Tracker(), gvr, nil
+}
+
+func readObjects(namespace string, yamlFiles []string) ([]runtime.Object, error) {
+	var out []runtime.Object
+	for _, f := range yamlFiles {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		objs, err := decodeObjects(namespace, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding %s: %v", f, err)
+		}
+		out = append(out, objs...)
+	}
+	return out, nil
+}
+
+func decodeObjects(namespace string, content string) ([]runtime.Object, error) {
+	var out []runtime.Object
+	for _, part := range yml.SplitString(content) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		obj, gvk, err := codecs.UniversalDeserializer().Decode([]byte(part), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if m.GetNamespace() == "" && !clusterScoped[gvk.Kind] {
+			m.SetNamespace(namespace)
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/test/framework/components/cluster"
+)
+
+const testYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: a
+spec:
+  ports:
+  - name: http
+    port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: b
+spec:
+  ports:
+  - name: http
+    port: 80
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: a
+spec:
+  hosts:
+  - a
+  http:
+  - route:
+    - destination:
+        host: b
+`
+
+func TestApplyDeleteYAML(t *testing.T) {
+	c, err := buildLocal(cluster.Config{Kind: cluster.Local, Name: "local"}, cluster.Topology{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(f, []byte(testYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Apply twice, the second apply updates the objects.
+	for i := 0; i < 2; i++ {
+		if err := c.ApplyYAMLFiles("ns", f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := c.CoreV1().Services("ns").Get(context.TODO(), "a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.CoreV1().Services("ns").Get(context.TODO(), "b", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Spec.ClusterIP != "240.240.0.1" || b.Spec.ClusterIP != "240.240.0.2" {
+		t.Errorf("got cluster IPs %q and %q, want 240.240.0.1 and 240.240.0.2", a.Spec.ClusterIP, b.Spec.ClusterIP)
+	}
+	vs, err := c.Istio().NetworkingV1alpha3().VirtualServices("ns").Get(context.TODO(), "a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vs.Spec.Hosts; len(got) != 1 || got[0] != "a" {
+		t.Errorf("got VirtualService hosts %v, want [a]", got)
+	}
+
+	if err := c.DeleteYAMLFiles("ns", f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CoreV1().Services("ns").Get(context.TODO(), "a", metav1.GetOptions{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected service to be deleted, got %v", err)
+	}
+	if _, err := c.Istio().NetworkingV1alpha3().VirtualServices("ns").Get(context.TODO(), "a", metav1.GetOptions{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected VirtualService to be deleted, got %v", err)
+	}
+}
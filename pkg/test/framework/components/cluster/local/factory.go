@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"fmt"
+
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+)
+
+const (
+	majorVersionMetaKey = "majorVersion"
+	minorVersionMetaKey = "minorVersion"
+)
+
+func init() {
+	cluster.RegisterFactory(cluster.Local, buildLocal)
+}
+
+func buildLocal(cfg cluster.Config, topology cluster.Topology) (cluster.Cluster, error) {
+	client := kube.NewFakeClient()
+
+	// The Istio config controllers only watch the types whose CRDs are present.
+	for _, s := range collections.PilotServiceApi.All() {
+		crd := &apiextensions.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s.%s", s.Resource().Plural(), s.Resource().Group()),
+			},
+		}
+		if _, err := client.Ext().ApiextensionsV1().CustomResourceDefinitions().Create(context.TODO(), crd, metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("failed creating CRD %s: %v", crd.Name, err)
+		}
+	}
+
+	major, minor := cfg.Meta.String(majorVersionMetaKey), cfg.Meta.String(minorVersionMetaKey)
+	if major == "" {
+		major = "1"
+	}
+	if minor == "" {
+		minor = "20"
+	}
+
+	return &Cluster{
+		ExtendedClient: client,
+		Topology:       topology,
+		version: &version.Info{
+			Major:      major,
+			Minor:      minor,
+			GitVersion: fmt.Sprintf("v%s.%s.0", major, minor),
+		},
+	}, nil
+}
@@ -45,6 +45,7 @@ var (
 	SidecarIncludeInboundPorts     = workloadAnnotation(annotation.SidecarTrafficIncludeInboundPorts.Name, "")
 	SidecarIncludeOutboundIPRanges = workloadAnnotation(annotation.SidecarTrafficIncludeOutboundIPRanges.Name, "")
 	SidecarProxyConfig             = workloadAnnotation(annotation.ProxyConfig.Name, "")
+	SidecarInjectTemplates         = workloadAnnotation("inject.istio.io/templates", "")
 )
 
 type AnnotationValue struct {
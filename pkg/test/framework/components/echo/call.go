@@ -81,6 +81,17 @@ type CallOptions struct {
 	// is returned directly.
 	FollowRedirects bool
 
+	// ServerName (SNI) to present in TLS connections. If not set, the host of the request URL is used.
+	ServerName string
+
+	// Alpn is the list of application protocols to present in TLS connections. If not set, a default
+	// appropriate for the scheme is used.
+	Alpn []string
+
+	// ProxyProtocol will instruct the call to send a PROXY protocol header at the start of TCP, TLS and HTTP/1.1
+	// connections, as load balancers in front of gateways do.
+	ProxyProtocol bool
+
 	// Validator for server responses. If no validator is provided, only the number of responses received
 	// will be verified.
 	Validator Validator
@@ -51,8 +51,10 @@ func callInternal(srcName string, opts *echo.CallOptions, send sendFunc,
 	switch opts.Scheme {
 	case scheme.DNS:
 		targetURL = fmt.Sprintf("%s://%s", string(opts.Scheme), opts.Address)
-	case scheme.TCP:
+	case scheme.TCP, scheme.TLS, scheme.UDP:
 		targetURL = fmt.Sprintf("%s://%s", string(opts.Scheme), addressAndPort)
+	case scheme.XDS:
+		targetURL = fmt.Sprintf("%s:///%s", string(opts.Scheme), addressAndPort)
 	default:
 		targetURL = fmt.Sprintf("%s://%s%s", string(opts.Scheme), addressAndPort, opts.Path)
 	}
@@ -80,6 +82,11 @@ func callInternal(srcName string, opts *echo.CallOptions, send sendFunc,
 		CaCertFile:         opts.CaCertFile,
 		InsecureSkipVerify: opts.InsecureSkipVerify,
 		FollowRedirects:    opts.FollowRedirects,
+		ServerName:         opts.ServerName,
+		ProxyProtocol:      opts.ProxyProtocol,
+	}
+	if len(opts.Alpn) > 0 {
+		req.Alpn = &proto.Alpn{Value: opts.Alpn}
 	}
 
 	var responses client.ParsedResponses
@@ -258,6 +265,10 @@ func schemeForPort(port *echo.Port) (scheme.Instance, error) {
 		return scheme.HTTPS, nil
 	case protocol.TCP:
 		return scheme.TCP, nil
+	case protocol.TLS:
+		return scheme.TLS, nil
+	case protocol.UDP:
+		return scheme.UDP, nil
 	default:
 		return "", fmt.Errorf("failed creating call for port %s: unsupported protocol %s",
 			port.Name, port.Protocol)
@@ -133,6 +133,10 @@ type Port struct {
 
 	// LocalhostIP determines if echo will listen on the localhost IP; otherwise, it will listen on wildcard
 	LocalhostIP bool
+
+	// ProxyProtocol determines whether connections to the port start with a PROXY protocol header.
+	// Only supported for HTTP, TCP and TLS ports.
+	ProxyProtocol bool
 }
 
 // Workload provides an interface for a single deployed echo server.
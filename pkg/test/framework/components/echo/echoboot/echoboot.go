@@ -28,6 +28,8 @@ import (
 	"istio.io/istio/pkg/test/framework/components/echo/common"
 	"istio.io/istio/pkg/test/framework/components/echo/kube"
 
+	// force registraton of factory func
+	_ "istio.io/istio/pkg/test/framework/components/echo/local"
 	// force registraton of factory func
 	_ "istio.io/istio/pkg/test/framework/components/echo/staticvm"
 	"istio.io/istio/pkg/test/framework/resource"
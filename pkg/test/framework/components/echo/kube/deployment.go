@@ -83,6 +83,9 @@ spec:
   - name: {{ $p.Name }}
     port: {{ $p.ServicePort }}
     targetPort: {{ $p.InstancePort }}
+{{- if eq $p.Protocol "UDP" }}
+    protocol: UDP
+{{- end }}
 {{- end }}
   selector:
     app: {{ .Service }}
@@ -166,6 +169,10 @@ spec:
           - --grpc
 {{- else if eq .Protocol "TCP" }}
           - --tcp
+{{- else if eq .Protocol "TLS" }}
+          - --tls-passthrough
+{{- else if eq .Protocol "UDP" }}
+          - --udp
 {{- else }}
           - --port
 {{- end }}
@@ -176,6 +183,9 @@ spec:
 {{- if $p.ServerFirst }}
           - --server-first={{ $p.Port }}
 {{- end }}
+{{- if $p.ProxyProtocol }}
+          - --proxy-protocol={{ $p.Port }}
+{{- end }}
 {{- if $p.InstanceIP }}
           - --bind-ip={{ $p.Port }}
 {{- end }}
@@ -214,6 +224,9 @@ spec:
 {{- if eq .Port 3333 }}
           name: tcp-health-port
 {{- end }}
+{{- if eq .Protocol "UDP" }}
+          protocol: UDP
+{{- end }}
 {{- end }}
         env:
         - name: INSTANCE_IP
@@ -361,6 +374,10 @@ spec:
              --grpc \
 {{- else if eq .Protocol "TCP" }}
              --tcp \
+{{- else if eq .Protocol "TLS" }}
+             --tls-passthrough \
+{{- else if eq .Protocol "UDP" }}
+             --udp \
 {{- else }}
              --port \
 {{- end }}
@@ -371,6 +388,9 @@ spec:
 {{- if $p.TLS }}
              --tls={{ $p.Port }} \
 {{- end }}
+{{- if $p.ProxyProtocol }}
+             --proxy-protocol={{ $p.Port }} \
+{{- end }}
 {{- if $p.InstanceIP }}
              --bind-ip={{ $p.Port }} \
 {{- end }}
@@ -870,18 +890,19 @@ func getContainerPorts(ports []echo.Port) echoCommon.PortList {
 	for _, p := range ports {
 		// Add the port to the set of application ports.
 		cport := &echoCommon.Port{
-			Name:        p.Name,
-			Protocol:    p.Protocol,
-			Port:        p.InstancePort,
-			TLS:         p.TLS,
-			ServerFirst: p.ServerFirst,
-			InstanceIP:  p.InstanceIP,
-			LocalhostIP: p.LocalhostIP,
+			Name:          p.Name,
+			Protocol:      p.Protocol,
+			Port:          p.InstancePort,
+			TLS:           p.TLS,
+			ServerFirst:   p.ServerFirst,
+			InstanceIP:    p.InstanceIP,
+			LocalhostIP:   p.LocalhostIP,
+			ProxyProtocol: p.ProxyProtocol,
 		}
 		containerPorts = append(containerPorts, cport)
 
 		switch p.Protocol {
-		case protocol.GRPC:
+		case protocol.GRPC, protocol.UDP:
 			continue
 		case protocol.HTTP:
 			if p.InstancePort == httpReadinessPort {
@@ -56,6 +56,36 @@ func TestDeploymentYAML(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "udp-tls-proxy-protocol",
+			wantFilePath: "testdata/udp-tls-proxy-protocol.yaml",
+			config: echo.Config{
+				Service: "foo",
+				Version: "bar",
+				Ports: []echo.Port{
+					{
+						Name:         "udp",
+						Protocol:     protocol.UDP,
+						InstancePort: 5353,
+						ServicePort:  5353,
+					},
+					{
+						Name:          "tls",
+						Protocol:      protocol.TLS,
+						InstancePort:  8443,
+						ServicePort:   443,
+						ProxyProtocol: true,
+					},
+					{
+						Name:          "tcp",
+						Protocol:      protocol.TCP,
+						InstancePort:  9000,
+						ServicePort:   9000,
+						ProxyProtocol: true,
+					},
+				},
+			},
+		},
 		{
 			name:         "two-workloads-one-nosidecar",
 			wantFilePath: "testdata/two-workloads-one-nosidecar.yaml",
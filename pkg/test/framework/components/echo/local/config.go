@@ -0,0 +1,286 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/tmpl"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	grpcBootstrapFile     = "grpc-bootstrap.json"
+	bootstrapTemplateFile = "envoy_bootstrap_tmpl.json"
+
+	// caKeyID is the key of the private key in the secret of the self-signed CA of istiod.
+	caKeyID = "ca-key.pem"
+)
+
+// sidecarYAML binds the inbound listeners of Envoy to the workload address, and the outbound listeners
+// to a second address of the workload, as instance and service ports often match. The proxy runs without
+// traffic capture, so inbound traffic is forwarded to the echo server on localhost and the application
+// sends outbound traffic to the outbound address.
+const sidecarYAML = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: {{ .Name }}
+spec:
+  workloadSelector:
+    labels:
+      app: {{ .Service }}
+      version: {{ .Version }}
+{{- if .Ingress }}
+  ingress:
+{{- range .Ingress }}
+  - port:
+      number: {{ .Port }}
+      protocol: {{ .Protocol }}
+      name: {{ .Name }}
+    bind: {{ $.IP }}
+    defaultEndpoint: 127.0.0.1:{{ .AppPort }}
+{{- end }}
+{{- end }}
+  egress:
+  - bind: {{ .OutboundIP }}
+    hosts:
+    - "*/*"
+`
+
+// envoyFilterYAML removes the virtual listeners of the proxy. They are only used with traffic capture,
+// and would conflict between the proxies as they bind to the wildcard address.
+const envoyFilterYAML = `
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: {{ .Name }}
+spec:
+  workloadSelector:
+    labels:
+      app: {{ .Service }}
+      version: {{ .Version }}
+  configPatches:
+  - applyTo: LISTENER
+    match:
+      context: SIDECAR_OUTBOUND
+      listener:
+        name: virtualOutbound
+    patch:
+      operation: REMOVE
+  - applyTo: LISTENER
+    match:
+      context: SIDECAR_INBOUND
+      listener:
+        name: virtualInbound
+    patch:
+      operation: REMOVE
+`
+
+type sidecarIngress struct {
+	Name     string
+	Protocol protocol.Instance
+	Port     int
+	AppPort  int
+}
+
+// proxyConfigs returns the Sidecar and EnvoyFilter resources for the proxy of the workload.
+func (w *workload) proxyConfigs() []string {
+	return []string{w.sidecarConfig(), tmpl.MustEvaluate(envoyFilterYAML, w.templateParams())}
+}
+
+func (w *workload) templateParams() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":       w.name,
+		"Service":    w.cfg.echo.Service,
+		"Version":    w.cfg.subset.Version,
+		"IP":         w.ip,
+		"OutboundIP": w.outboundIP,
+	}
+}
+
+func (w *workload) sidecarConfig() string {
+	var ingress []sidecarIngress
+	seen := map[int]bool{}
+	for _, p := range w.cfg.echo.Ports {
+		// Envoy does not proxy UDP.
+		if p.Protocol == protocol.UDP || seen[p.InstancePort] {
+			continue
+		}
+		seen[p.InstancePort] = true
+		ingress = append(ingress, sidecarIngress{
+			Name:     p.Name,
+			Protocol: p.Protocol,
+			Port:     p.InstancePort,
+			AppPort:  w.appPorts[p.InstancePort],
+		})
+	}
+	sort.Slice(ingress, func(i, j int) bool {
+		return ingress[i].Port < ingress[j].Port
+	})
+	params := w.templateParams()
+	params["Ingress"] = ingress
+	return tmpl.MustEvaluate(sidecarYAML, params)
+}
+
+func (w *workload) certDir() string {
+	return filepath.Join(w.dir, "etc", "certs")
+}
+
+// writeCerts issues the workload certificate with the self-signed CA of istiod and writes it where the
+// pilot-agent looks for mounted certificates, as the workloads cannot authenticate to the CA.
+func (w *workload) writeCerts() error {
+	secret, err := w.cfg.echo.Cluster.CoreV1().Secrets(w.cfg.systemNS).Get(context.TODO(), ca.CASecret, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed reading the CA of istiod: %v", err)
+	}
+	caCert := secret.Data[ca.CaCertID]
+	signerCert, err := util.ParsePemEncodedCertificate(caCert)
+	if err != nil {
+		return err
+	}
+	signerKey, err := util.ParsePemEncodedKey(secret.Data[caKeyID])
+	if err != nil {
+		return err
+	}
+	certPEM, keyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:       spiffe.MustGenSpiffeURI(w.namespace, w.serviceAccount()),
+		TTL:        24 * time.Hour,
+		SignerCert: signerCert,
+		SignerPriv: signerKey,
+		RSAKeySize: 2048,
+		IsServer:   true,
+		IsClient:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(w.certDir(), 0o755); err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		constants.CertChainFilename: append(certPEM, caCert...),
+		constants.KeyFilename:       keyPEM,
+		constants.RootCertFilename:  caCert,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(w.certDir(), name), content, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBootstrapTemplate writes a copy of the Envoy bootstrap template with the listeners Envoy binds
+// on the wildcard address moved to the workload address, and the agent cluster pointing at the status
+// port of this workload, so that several proxies can run on one host.
+func (w *workload) writeBootstrapTemplate() error {
+	in, err := ioutil.ReadFile(filepath.Join(env.IstioSrc, "tools/packaging/common/envoy_bootstrap.json"))
+	if err != nil {
+		return err
+	}
+	out := rewriteBootstrapTemplate(string(in), w.ip, w.statusPort)
+	return ioutil.WriteFile(filepath.Join(w.dir, bootstrapTemplateFile), []byte(out), 0o644)
+}
+
+func rewriteBootstrapTemplate(in string, ip string, statusPort int) string {
+	out := strings.ReplaceAll(in, `"{{ .wildcard }}"`, strconv.Quote(ip))
+	return strings.ReplaceAll(out, `"port_value": 15020`, `"port_value": `+strconv.Itoa(statusPort))
+}
+
+func (w *workload) proxyArgs() []string {
+	return []string{
+		"proxy", "sidecar",
+		"--domain", fmt.Sprintf("%s.svc.%s", w.namespace, constants.DefaultKubernetesDomain),
+		"--templateFile", filepath.Join(w.dir, bootstrapTemplateFile),
+		"--concurrency", "1",
+	}
+}
+
+func (w *workload) proxyEnv() ([]string, error) {
+	proxyConfig, err := json.Marshal(map[string]interface{}{
+		"discoveryAddress":       w.cfg.discoveryAddr,
+		"controlPlaneAuthPolicy": "NONE",
+		"proxyAdminPort":         w.adminPort,
+		"statusPort":             w.statusPort,
+		"binaryPath":             filepath.Join(env.LocalOut, "envoy"),
+		"configPath":             w.dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	certs := w.certDir()
+	return []string{
+		"INSTANCE_IP=" + w.ip,
+		"POD_NAME=" + w.name,
+		"POD_NAMESPACE=" + w.namespace,
+		"SERVICE_ACCOUNT=" + w.serviceAccount(),
+		"ISTIO_META_CLUSTER_ID=" + w.cfg.echo.Cluster.Name(),
+		"ISTIO_META_INTERCEPTION_MODE=NONE",
+		"PROXY_CONFIG=" + string(proxyConfig),
+		// Not a JWT policy: the agent uses the mounted certificates rather than a token.
+		"JWT_POLICY=none",
+		"FILE_MOUNTED_CERTS=true",
+		"ISTIO_META_TLS_CLIENT_CERT_CHAIN=" + filepath.Join(certs, constants.CertChainFilename),
+		"ISTIO_META_TLS_CLIENT_KEY=" + filepath.Join(certs, constants.KeyFilename),
+		"ISTIO_META_TLS_CLIENT_ROOT_CERT=" + filepath.Join(certs, constants.RootCertFilename),
+		"ISTIO_META_TLS_SERVER_CERT_CHAIN=" + filepath.Join(certs, constants.CertChainFilename),
+		"ISTIO_META_TLS_SERVER_KEY=" + filepath.Join(certs, constants.KeyFilename),
+		"ISTIO_META_TLS_SERVER_ROOT_CERT=" + filepath.Join(certs, constants.RootCertFilename),
+	}, nil
+}
+
+// writeGRPCBootstrap writes the xDS bootstrap of proxyless gRPC, connecting to the plaintext port of istiod.
+func (w *workload) writeGRPCBootstrap() error {
+	out, err := json.MarshalIndent(map[string]interface{}{
+		"xds_servers": []interface{}{
+			map[string]interface{}{
+				"server_uri":      w.cfg.discoveryAddr,
+				"channel_creds":   []interface{}{map[string]string{"type": "insecure"}},
+				"server_features": []string{"xds_v3"},
+			},
+		},
+		"node": map[string]interface{}{
+			"id": fmt.Sprintf("sidecar~%s~%s.%s~%s.svc.%s", w.ip, w.name, w.namespace, w.namespace, constants.DefaultKubernetesDomain),
+			"metadata": map[string]string{
+				"GENERATOR":    "grpc",
+				"NAMESPACE":    w.namespace,
+				"CLUSTER_ID":   w.cfg.echo.Cluster.Name(),
+				"INSTANCE_IPS": w.ip,
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dir, grpcBootstrapFile), out, 0o644)
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+func TestRewriteBootstrapTemplate(t *testing.T) {
+	in, err := ioutil.ReadFile(filepath.Join(env.IstioSrc, "tools/packaging/common/envoy_bootstrap.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rewriteBootstrapTemplate(string(in), "127.1.0.1", 12345)
+	for _, unexpected := range []string{"{{ .wildcard }}", "15020"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("rewritten template still contains %q", unexpected)
+		}
+	}
+	if got := strings.Count(out, `"address": "127.1.0.1"`); got != 2 {
+		t.Errorf("got %d listeners bound to the workload address, want 2 (prometheus and readiness)", got)
+	}
+	if !strings.Contains(out, `"port_value": 12345`) {
+		t.Errorf("agent cluster does not use the status port")
+	}
+}
+
+func TestSidecarConfig(t *testing.T) {
+	w := &workload{
+		name:       "a-v1",
+		ip:         "127.1.0.1",
+		outboundIP: "127.1.0.2",
+		cfg: workloadConfig{
+			echo: echo.Config{
+				Service: "a",
+				Ports: []echo.Port{
+					{Name: "http", Protocol: protocol.HTTP, ServicePort: 80, InstancePort: 18080},
+					{Name: "grpc", Protocol: protocol.GRPC, ServicePort: 7070, InstancePort: 17070},
+					{Name: "udp", Protocol: protocol.UDP, ServicePort: 53, InstancePort: 1053},
+				},
+			},
+			subset: echo.SubsetConfig{Version: "v1"},
+		},
+		appPorts: map[int]int{18080: 30001, 17070: 30002, 1053: 1053},
+	}
+	want := `
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: a-v1
+spec:
+  workloadSelector:
+    labels:
+      app: a
+      version: v1
+  ingress:
+  - port:
+      number: 17070
+      protocol: GRPC
+      name: grpc
+    bind: 127.1.0.1
+    defaultEndpoint: 127.0.0.1:30002
+  - port:
+      number: 18080
+      protocol: HTTP
+      name: http
+    bind: 127.1.0.1
+    defaultEndpoint: 127.0.0.1:30001
+  egress:
+  - bind: 127.1.0.2
+    hosts:
+    - "*/*"
+`
+	if got := w.sidecarConfig(); got != want {
+		t.Errorf("got Sidecar:\n%s\nwant:\n%s", got, want)
+	}
+}
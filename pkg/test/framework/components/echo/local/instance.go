@@ -0,0 +1,358 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package local deploys echo instances as processes on the local host, for the local environment.
+// Each workload gets its own loopback address and either runs behind a pilot-agent and Envoy, or
+// in proxyless gRPC mode.
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/go-multierror"
+	kubeCore "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var (
+	_ echo.Instance = &instance{}
+	_ io.Closer     = &instance{}
+)
+
+func init() {
+	echo.RegisterFactory(cluster.Local, newInstances)
+}
+
+type instance struct {
+	id        resource.ID
+	cfg       echo.Config
+	clusterIP string
+	workloads []*workload
+}
+
+func newInstances(ctx resource.Context, config []echo.Config) (echo.Instances, error) {
+	errG := multierror.Group{}
+	out := make(echo.Instances, len(config))
+	for idx, c := range config {
+		idx, c := idx, c
+		errG.Go(func() error {
+			i, err := newInstance(ctx, c)
+			if err != nil {
+				return err
+			}
+			// Keep the order of the configs, the builder assigns references by index.
+			out[idx] = i
+			return nil
+		})
+	}
+	if err := errG.Wait().ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newInstance(ctx resource.Context, cfg echo.Config) (*instance, error) {
+	cfg = cfg.DeepCopy()
+	if common.GetPortForProtocol(&cfg, protocol.GRPC) == nil {
+		return nil, fmt.Errorf("unable to find GRPC command port for %s", cfg.Service)
+	}
+
+	ist, err := istio.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	istiodAddr, err := ist.RemoteDiscoveryAddressFor(cfg.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := cfg.Cluster.CoreV1().Services(cfg.Namespace.Name()).Get(context.TODO(), cfg.Service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	i := &instance{
+		cfg:       cfg,
+		clusterIP: svc.Spec.ClusterIP,
+	}
+	if i.clusterIP == kubeCore.ClusterIPNone {
+		i.clusterIP = ""
+	}
+
+	for _, subset := range cfg.Subsets {
+		w, err := newWorkload(ctx, workloadConfig{
+			echo:          cfg,
+			subset:        subset,
+			systemNS:      ist.Settings().SystemNamespace,
+			discoveryAddr: istiodAddr.String(),
+		})
+		if err != nil {
+			_ = i.Close()
+			return nil, err
+		}
+		i.workloads = append(i.workloads, w)
+	}
+	if err := i.updateEndpoints(); err != nil {
+		_ = i.Close()
+		return nil, err
+	}
+	i.id = ctx.TrackResource(i)
+
+	for _, w := range i.workloads {
+		if err := w.waitForReady(cfg.ReadinessTimeout); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// updateEndpoints adds the addresses of the workloads to the Endpoints of the service, as the endpoints
+// controller would. Addresses of workloads from other echo instances for the same service are kept.
+func (i *instance) updateEndpoints() error {
+	return i.editEndpoints(func(subset *kubeCore.EndpointSubset) {
+		for _, w := range i.workloads {
+			subset.Addresses = append(subset.Addresses, kubeCore.EndpointAddress{
+				IP: w.ip,
+				TargetRef: &kubeCore.ObjectReference{
+					Kind:      "Pod",
+					Name:      w.name,
+					Namespace: w.namespace,
+				},
+			})
+		}
+		subset.Ports = nil
+		for _, p := range i.cfg.Ports {
+			proto := kubeCore.ProtocolTCP
+			if p.Protocol == protocol.UDP {
+				proto = kubeCore.ProtocolUDP
+			}
+			subset.Ports = append(subset.Ports, kubeCore.EndpointPort{
+				Name:     p.Name,
+				Port:     int32(p.InstancePort),
+				Protocol: proto,
+			})
+		}
+	})
+}
+
+// editEndpoints removes the addresses of the workloads of this instance from the Endpoints of the service,
+// then applies the edit function before writing the Endpoints back.
+func (i *instance) editEndpoints(edit func(subset *kubeCore.EndpointSubset)) error {
+	endpoints := i.cfg.Cluster.CoreV1().Endpoints(i.cfg.Namespace.Name())
+	ep, err := endpoints.Get(context.TODO(), i.cfg.Service, metav1.GetOptions{})
+	create := kerrors.IsNotFound(err)
+	if create {
+		ep = &kubeCore.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      i.cfg.Service,
+				Namespace: i.cfg.Namespace.Name(),
+			},
+		}
+	} else if err != nil {
+		return err
+	}
+
+	owned := map[string]bool{}
+	for _, w := range i.workloads {
+		owned[w.name] = true
+	}
+	subset := kubeCore.EndpointSubset{}
+	for _, s := range ep.Subsets {
+		subset.Ports = s.Ports
+		for _, a := range s.Addresses {
+			if a.TargetRef == nil || !owned[a.TargetRef.Name] {
+				subset.Addresses = append(subset.Addresses, a)
+			}
+		}
+	}
+	edit(&subset)
+	ep.Subsets = nil
+	if len(subset.Addresses) > 0 {
+		ep.Subsets = []kubeCore.EndpointSubset{subset}
+	}
+
+	if create {
+		_, err = endpoints.Create(context.TODO(), ep, metav1.CreateOptions{})
+	} else {
+		_, err = endpoints.Update(context.TODO(), ep, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+func (i *instance) ID() resource.ID {
+	return i.id
+}
+
+func (i *instance) Config() echo.Config {
+	return i.cfg
+}
+
+// Address returns the cluster IP of the service. It is not routable on the local host, calls between
+// instances are sent to the proxy of the caller or directly to the workloads of the target.
+func (i *instance) Address() string {
+	return i.clusterIP
+}
+
+func (i *instance) Workloads() ([]echo.Workload, error) {
+	out := make([]echo.Workload, 0, len(i.workloads))
+	for _, w := range i.workloads {
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+func (i *instance) WorkloadsOrFail(t test.Failer) []echo.Workload {
+	t.Helper()
+	out, err := i.Workloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func (i *instance) Call(opts echo.CallOptions) (client.ParsedResponses, error) {
+	return i.aggregateResponses(opts, false)
+}
+
+func (i *instance) CallOrFail(t test.Failer, opts echo.CallOptions) client.ParsedResponses {
+	t.Helper()
+	r, err := i.Call(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func (i *instance) CallWithRetry(opts echo.CallOptions, retryOptions ...retry.Option) (client.ParsedResponses, error) {
+	return i.aggregateResponses(opts, true, retryOptions...)
+}
+
+func (i *instance) CallWithRetryOrFail(t test.Failer, opts echo.CallOptions, retryOptions ...retry.Option) client.ParsedResponses {
+	t.Helper()
+	r, err := i.CallWithRetry(opts, retryOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Restart stops and starts the processes of all workloads. The workloads keep their addresses.
+func (i *instance) Restart() error {
+	for _, w := range i.workloads {
+		if err := w.restart(); err != nil {
+			return err
+		}
+	}
+	for _, w := range i.workloads {
+		if err := w.waitForReady(i.cfg.ReadinessTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *instance) Close() error {
+	var errs error
+	for _, w := range i.workloads {
+		if err := w.Close(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if err := i.editEndpoints(func(*kubeCore.EndpointSubset) {}); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	return errs
+}
+
+// aggregateResponses forwards an echo request from all workloads belonging to this echo instance and aggregates the results.
+func (i *instance) aggregateResponses(opts echo.CallOptions, retry bool, retryOptions ...retry.Option) (client.ParsedResponses, error) {
+	resps := make(client.ParsedResponses, 0)
+	var aggErr error
+	for _, w := range i.workloads {
+		wopts, err := w.callOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		out, err := common.ForwardEcho(i.cfg.Service, w.Client, &wopts, retry, retryOptions...)
+		if err != nil {
+			aggErr = multierror.Append(err, aggErr)
+			continue
+		}
+		resps = append(resps, out...)
+	}
+	if aggErr != nil {
+		return nil, aggErr
+	}
+	return resps, nil
+}
+
+// callOptions fills in the address of a call to another local instance, as there is no DNS and
+// the cluster IPs are not routable. Calls from a workload with a sidecar are sent to the outbound
+// listeners its Envoy binds to the outbound address of the workload, and routed by the Host header. Proxyless gRPC
+// calls resolve the target through istiod. All other calls go directly to a workload of the target.
+func (w *workload) callOptions(opts echo.CallOptions) (echo.CallOptions, error) {
+	target, ok := opts.Target.(*instance)
+	if !ok || opts.Address != "" || len(target.workloads) == 0 {
+		return opts, nil
+	}
+	port := opts.Port
+	if opts.PortName != "" {
+		if port = target.cfg.PortByName(opts.PortName); port == nil {
+			return opts, fmt.Errorf("callOptions: no port named %s available in Target Instance", opts.PortName)
+		}
+	}
+	if port == nil {
+		// Let the common validation report the missing port.
+		return opts, nil
+	}
+
+	switch {
+	case w.mode == modeSidecar:
+		opts.Address = w.outboundIP
+	case w.mode == modeProxyless && port.Protocol == protocol.GRPC && (opts.Scheme == "" || opts.Scheme == scheme.GRPC):
+		opts.Scheme = scheme.XDS
+		opts.Address = target.cfg.FQDN()
+	default:
+		// The target workload listens on the instance port, with or without a sidecar.
+		direct := *port
+		direct.ServicePort = port.InstancePort
+		headers := http.Header{}
+		if opts.Headers != nil {
+			headers = opts.Headers.Clone()
+		}
+		if headers.Get("Host") == "" {
+			headers.Set("Host", target.cfg.HostHeader())
+		}
+		opts.Headers = headers
+		opts.Target = nil
+		opts.PortName = ""
+		opts.Port = &direct
+		opts.Address = target.workloads[0].ip
+	}
+	return opts, nil
+}
@@ -0,0 +1,229 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	// Import all XDS config types
+	_ "istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var _ echo.Sidecar = &sidecar{}
+
+// sidecar queries the admin interface of the Envoy of a local workload directly, as it listens on localhost.
+type sidecar struct {
+	nodeID     string
+	adminPort  int
+	statusPort int
+	logFile    string
+}
+
+func (w *workload) newSidecar() (*sidecar, error) {
+	s := &sidecar{
+		adminPort:  w.adminPort,
+		statusPort: w.statusPort,
+		logFile:    filepath.Join(w.dir, proxyLogFile),
+	}
+
+	// The agent reports ready once Envoy has received its initial configuration.
+	body, err := s.get(fmt.Sprintf("http://127.0.0.1:%d/healthz/ready", s.statusPort))
+	if err != nil {
+		return nil, fmt.Errorf("sidecar not ready: %v %s", err, body)
+	}
+
+	// Extract the node ID from Envoy.
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cfg.Configs {
+		if c.TypeUrl == "type.googleapis.com/envoy.admin.v3.BootstrapConfigDump" {
+			cd := envoyAdmin.BootstrapConfigDump{}
+			if err := c.UnmarshalTo(&cd); err != nil {
+				return nil, err
+			}
+			s.nodeID = cd.Bootstrap.Node.Id
+			return s, nil
+		}
+	}
+	return nil, errors.New("envoy Bootstrap not found in config dump")
+}
+
+func (s *sidecar) NodeID() string {
+	return s.nodeID
+}
+
+func (s *sidecar) Info() (*envoyAdmin.ServerInfo, error) {
+	msg := &envoyAdmin.ServerInfo{}
+	if err := s.adminRequest("server_info", msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *sidecar) InfoOrFail(t test.Failer) *envoyAdmin.ServerInfo {
+	t.Helper()
+	info, err := s.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func (s *sidecar) Config() (*envoyAdmin.ConfigDump, error) {
+	msg := &envoyAdmin.ConfigDump{}
+	if err := s.adminRequest("config_dump", msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *sidecar) ConfigOrFail(t test.Failer) *envoyAdmin.ConfigDump {
+	t.Helper()
+	cfg, err := s.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func (s *sidecar) WaitForConfig(accept func(*envoyAdmin.ConfigDump) (bool, error), options ...retry.Option) error {
+	return common.WaitForConfig(s.Config, accept, options...)
+}
+
+func (s *sidecar) WaitForConfigOrFail(t test.Failer, accept func(*envoyAdmin.ConfigDump) (bool, error), options ...retry.Option) {
+	t.Helper()
+	if err := s.WaitForConfig(accept, options...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (s *sidecar) Clusters() (*envoyAdmin.Clusters, error) {
+	msg := &envoyAdmin.Clusters{}
+	if err := s.adminRequest("clusters?format=json", msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *sidecar) ClustersOrFail(t test.Failer) *envoyAdmin.Clusters {
+	t.Helper()
+	clusters, err := s.Clusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clusters
+}
+
+func (s *sidecar) Listeners() (*envoyAdmin.Listeners, error) {
+	msg := &envoyAdmin.Listeners{}
+	if err := s.adminRequest("listeners?format=json", msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *sidecar) ListenersOrFail(t test.Failer) *envoyAdmin.Listeners {
+	t.Helper()
+	listeners, err := s.Listeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listeners
+}
+
+func (s *sidecar) Stats() (map[string]*dto.MetricFamily, error) {
+	body, err := s.get(fmt.Sprintf("http://127.0.0.1:%d/stats/prometheus", s.adminPort))
+	if err != nil {
+		return nil, err
+	}
+	parser := expfmt.TextParser{}
+	mfMap, err := parser.TextToMetricFamilies(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing prometheus stats: %v", err)
+	}
+	return mfMap, nil
+}
+
+func (s *sidecar) StatsOrFail(t test.Failer) map[string]*dto.MetricFamily {
+	t.Helper()
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) adminRequest(path string, out proto.Message) error {
+	body, err := s.get(fmt.Sprintf("http://127.0.0.1:%d/%s", s.adminPort, path))
+	if err != nil {
+		return err
+	}
+	jspb := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := jspb.Unmarshal(strings.NewReader(body), out); err != nil {
+		return fmt.Errorf("failed parsing Envoy admin response from '/%s': %v\nResponse JSON: %s", path, err, body)
+	}
+	return nil
+}
+
+func (s *sidecar) get(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return string(body), fmt.Errorf("request to %s failed: %s", url, resp.Status)
+	}
+	return string(body), nil
+}
+
+func (s *sidecar) Logs() (string, error) {
+	out, err := ioutil.ReadFile(s.logFile)
+	return string(out), err
+}
+
+func (s *sidecar) LogsOrFail(t test.Failer) string {
+	t.Helper()
+	logs, err := s.Logs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
@@ -0,0 +1,568 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	kubeCore "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	echoCommon "istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// mode is how a workload is connected to the mesh.
+type mode int
+
+const (
+	// modeNone runs the echo server alone, listening on the workload address.
+	modeNone mode = iota
+	// modeSidecar runs the echo server on localhost behind a pilot-agent and Envoy, which listen on the
+	// workload address.
+	modeSidecar
+	// modeProxyless runs the echo server alone, with a gRPC xDS bootstrap for proxyless gRPC calls.
+	modeProxyless
+)
+
+const (
+	// proxylessTemplate is the injection template selecting proxyless gRPC, see echo.SidecarInjectTemplates.
+	proxylessTemplate = "grpc-agent"
+
+	appLogFile   = "app.log"
+	proxyLogFile = "istio-proxy.log"
+)
+
+var (
+	_ echo.Workload = &workload{}
+
+	// workloadIPs is the range the workload addresses are allocated from. All of 127.0.0.0/8 is
+	// routed to the loopback interface on Linux, so no setup is needed.
+	workloadIPs = net.IPNet{IP: net.IPv4(127, 1, 0, 0), Mask: net.CIDRMask(16, 32)}
+
+	ipMu sync.Mutex
+	// allocatedIPs is the number of workload addresses allocated so far.
+	allocatedIPs uint32
+)
+
+type workloadConfig struct {
+	echo          echo.Config
+	subset        echo.SubsetConfig
+	systemNS      string
+	discoveryAddr string
+}
+
+type workload struct {
+	cfg       workloadConfig
+	mode      mode
+	name      string
+	namespace string
+	ip        string
+	// outboundIP is the address the outbound listeners of the sidecar bind to.
+	outboundIP string
+	dir        string
+
+	// appPorts maps the instance ports of the echo server to the ports it listens on, which differ
+	// from the instance ports when the server runs behind a sidecar.
+	appPorts    map[int]int
+	metricsPort int
+	adminPort   int
+	statusPort  int
+
+	mu      sync.Mutex
+	procs   []*exec.Cmd
+	client  *client.Instance
+	sidecar *sidecar
+}
+
+func newWorkload(ctx resource.Context, cfg workloadConfig) (w *workload, err error) {
+	w = &workload{
+		cfg:       cfg,
+		mode:      workloadMode(cfg.subset),
+		name:      fmt.Sprintf("%s-%s", cfg.echo.Service, cfg.subset.Version),
+		namespace: cfg.echo.Namespace.Name(),
+		appPorts:  map[int]int{},
+	}
+	if w.ip, err = allocateIP(); err != nil {
+		return nil, err
+	}
+	if w.mode == modeSidecar {
+		if w.outboundIP, err = allocateIP(); err != nil {
+			return nil, err
+		}
+	}
+	if w.dir, err = ctx.CreateTmpDirectory(w.name); err != nil {
+		return nil, err
+	}
+	if err := w.allocatePorts(); err != nil {
+		return nil, err
+	}
+	if err := w.createPod(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = w.Close()
+		}
+	}()
+
+	switch w.mode {
+	case modeSidecar:
+		if err := w.writeCerts(); err != nil {
+			return nil, err
+		}
+		if err := w.writeBootstrapTemplate(); err != nil {
+			return nil, err
+		}
+		if err := ctx.Config(cfg.echo.Cluster).ApplyYAML(w.namespace, w.proxyConfigs()...); err != nil {
+			return nil, err
+		}
+	case modeProxyless:
+		if err := w.writeGRPCBootstrap(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.start(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func workloadMode(subset echo.SubsetConfig) mode {
+	if !subset.Annotations.GetBool(echo.SidecarInject) {
+		return modeNone
+	}
+	if subset.Annotations.Get(echo.SidecarInjectTemplates) == proxylessTemplate {
+		return modeProxyless
+	}
+	return modeSidecar
+}
+
+// allocateIP returns the next unused workload address.
+func allocateIP() (string, error) {
+	ipMu.Lock()
+	defer ipMu.Unlock()
+	ones, bits := workloadIPs.Mask.Size()
+	if allocatedIPs >= 1<<(bits-ones)-2 {
+		return "", fmt.Errorf("no workload addresses left in %s", workloadIPs.String())
+	}
+	allocatedIPs++
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, workloadIPs.IP.To4())
+	ip[2] = byte(allocatedIPs >> 8)
+	ip[3] = byte(allocatedIPs)
+	return ip.String(), nil
+}
+
+// freePort returns a port that is currently unused on localhost.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (w *workload) allocatePorts() error {
+	var ports []int
+	for _, p := range w.cfg.echo.Ports {
+		ports = append(ports, p.InstancePort)
+	}
+	for _, p := range w.cfg.echo.WorkloadOnlyPorts {
+		ports = append(ports, p.Port)
+	}
+	for _, p := range ports {
+		if w.mode != modeSidecar {
+			// Each workload has its own address, the instance ports can be used as is.
+			w.appPorts[p] = p
+			continue
+		}
+		local, err := freePort()
+		if err != nil {
+			return err
+		}
+		w.appPorts[p] = local
+	}
+
+	var err error
+	if w.metricsPort, err = freePort(); err != nil {
+		return err
+	}
+	if w.mode == modeSidecar {
+		if w.adminPort, err = freePort(); err != nil {
+			return err
+		}
+		if w.statusPort, err = freePort(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createPod writes the pod of the workload to the cluster, so istiod can find the labels and
+// service account of the proxy by its address.
+func (w *workload) createPod() error {
+	labels := map[string]string{
+		"app":     w.cfg.echo.Service,
+		"version": w.cfg.subset.Version,
+	}
+	if w.mode == modeSidecar {
+		labels[label.SecurityTlsMode.Name] = model.IstioMutualTLSModeLabel
+	}
+	annotations := map[string]string{}
+	for k, v := range w.cfg.subset.Annotations {
+		annotations[k.Name] = v.Value
+	}
+	pod := &kubeCore.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        w.name,
+			Namespace:   w.namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: kubeCore.PodSpec{
+			ServiceAccountName: w.serviceAccount(),
+		},
+		Status: kubeCore.PodStatus{
+			Phase:  kubeCore.PodRunning,
+			PodIP:  w.ip,
+			PodIPs: []kubeCore.PodIP{{IP: w.ip}},
+			Conditions: []kubeCore.PodCondition{{
+				Type:   kubeCore.PodReady,
+				Status: kubeCore.ConditionTrue,
+			}},
+		},
+	}
+	_, err := w.cfg.echo.Cluster.CoreV1().Pods(w.namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	return err
+}
+
+func (w *workload) serviceAccount() string {
+	if w.cfg.echo.ServiceAccount {
+		return w.cfg.echo.Service
+	}
+	return "default"
+}
+
+// appArgs returns the arguments of the echo server. All ports are given explicitly, as the server
+// otherwise listens on default ports, which would conflict between workloads.
+func (w *workload) appArgs() ([]string, error) {
+	args := []string{
+		"--metrics", strconv.Itoa(w.metricsPort),
+		"--cluster", w.cfg.echo.Cluster.Name(),
+		"--version", w.cfg.subset.Version,
+	}
+
+	ports := make(echoCommon.PortList, 0, len(w.cfg.echo.Ports)+len(w.cfg.echo.WorkloadOnlyPorts))
+	for _, p := range w.cfg.echo.Ports {
+		ports = append(ports, &echoCommon.Port{
+			Protocol:      p.Protocol,
+			Port:          w.appPorts[p.InstancePort],
+			TLS:           p.TLS,
+			ServerFirst:   p.ServerFirst,
+			ProxyProtocol: p.ProxyProtocol,
+		})
+	}
+	for _, p := range w.cfg.echo.WorkloadOnlyPorts {
+		ports = append(ports, &echoCommon.Port{
+			Protocol:    p.Protocol,
+			Port:        w.appPorts[p.Port],
+			TLS:         p.TLS,
+			ServerFirst: p.ServerFirst,
+		})
+	}
+
+	used := map[string]bool{}
+	for _, p := range ports {
+		flag := portFlag(p.Protocol)
+		used[flag] = true
+		args = append(args, flag, strconv.Itoa(p.Port))
+		if p.TLS {
+			args = append(args, "--tls="+strconv.Itoa(p.Port))
+		}
+		if p.ServerFirst {
+			args = append(args, "--server-first="+strconv.Itoa(p.Port))
+		}
+		if p.ProxyProtocol {
+			args = append(args, "--proxy-protocol="+strconv.Itoa(p.Port))
+		}
+		args = append(args, w.bindArg(p.Port))
+	}
+	// Move the default ports of the protocols without ports out of the way.
+	for _, flag := range []string{"--port", "--grpc", "--tcp"} {
+		if used[flag] {
+			continue
+		}
+		p, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flag, strconv.Itoa(p), "--bind-localhost="+strconv.Itoa(p))
+	}
+
+	if w.cfg.echo.TLSSettings != nil {
+		crt := filepath.Join(w.dir, "app-cert.pem")
+		key := filepath.Join(w.dir, "app-key.pem")
+		if err := ioutil.WriteFile(crt, []byte(w.cfg.echo.TLSSettings.ClientCert), 0o644); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(key, []byte(w.cfg.echo.TLSSettings.Key), 0o600); err != nil {
+			return nil, err
+		}
+		args = append(args, "--crt="+crt, "--key="+key)
+	}
+	return args, nil
+}
+
+func portFlag(p protocol.Instance) string {
+	switch p {
+	case protocol.GRPC:
+		return "--grpc"
+	case protocol.TCP:
+		return "--tcp"
+	case protocol.TLS:
+		return "--tls-passthrough"
+	case protocol.UDP:
+		return "--udp"
+	default:
+		return "--port"
+	}
+}
+
+// bindArg returns the flag binding a port of the echo server to localhost behind a sidecar, where
+// Envoy forwards inbound traffic to, or to the workload address otherwise.
+func (w *workload) bindArg(port int) string {
+	if w.mode == modeSidecar {
+		return "--bind-localhost=" + strconv.Itoa(port)
+	}
+	return "--bind-ip=" + strconv.Itoa(port)
+}
+
+func (w *workload) appEnv() []string {
+	out := []string{"INSTANCE_IP=" + w.ip}
+	if w.mode == modeProxyless {
+		out = append(out,
+			"GRPC_XDS_BOOTSTRAP="+filepath.Join(w.dir, grpcBootstrapFile),
+			"GRPC_XDS_EXPERIMENTAL_V3_SUPPORT=true")
+	}
+	return out
+}
+
+func (w *workload) start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	args, err := w.appArgs()
+	if err != nil {
+		return err
+	}
+	app, err := w.startProcess(filepath.Join(env.LocalOut, "server"), args, w.appEnv(), appLogFile)
+	if err != nil {
+		return err
+	}
+	w.procs = append(w.procs, app)
+
+	if w.mode == modeSidecar {
+		proxyEnv, err := w.proxyEnv()
+		if err != nil {
+			return err
+		}
+		agent, err := w.startProcess(filepath.Join(env.LocalOut, "pilot-agent"), w.proxyArgs(), proxyEnv, proxyLogFile)
+		if err != nil {
+			return err
+		}
+		w.procs = append(w.procs, agent)
+	}
+	return nil
+}
+
+func (w *workload) startProcess(binary string, args []string, environ []string, logFile string) (*exec.Cmd, error) {
+	out, err := os.OpenFile(filepath.Join(w.dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = w.dir
+	cmd.Env = append(os.Environ(), environ...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Start a process group, so the Envoy started by pilot-agent is stopped with it.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	scopes.Framework.Debugf("starting %s for workload %s/%s: %v", filepath.Base(binary), w.namespace, w.name, args)
+	if err := cmd.Start(); err != nil {
+		_ = out.Close()
+		return nil, fmt.Errorf("failed starting %s: %v", binary, err)
+	}
+	go func() {
+		_ = cmd.Wait()
+		_ = out.Close()
+	}()
+	return cmd, nil
+}
+
+// stop kills the processes of the workload.
+func (w *workload) stop() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs error
+	for _, p := range w.procs {
+		if err := syscall.Kill(-p.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	w.procs = nil
+	if w.client != nil {
+		if err := w.client.Close(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		w.client = nil
+	}
+	w.sidecar = nil
+	return errs
+}
+
+func (w *workload) restart() error {
+	if err := w.stop(); err != nil {
+		return err
+	}
+	return w.start()
+}
+
+// waitForReady waits until the echo server accepts requests and, with a sidecar, Envoy is ready and has
+// received its configuration.
+func (w *workload) waitForReady(timeout time.Duration) error {
+	err := retry.UntilSuccess(func() error {
+		if _, err := w.Client(); err != nil {
+			return err
+		}
+		if w.mode == modeSidecar {
+			s, err := w.newSidecar()
+			if err != nil {
+				return err
+			}
+			w.mu.Lock()
+			w.sidecar = s
+			w.mu.Unlock()
+		}
+		return nil
+	}, retry.Timeout(timeout), retry.Delay(500*time.Millisecond))
+	if err != nil {
+		logs, _ := w.Logs()
+		return fmt.Errorf("workload %s/%s did not become ready: %v\napp logs:\n%s", w.namespace, w.name, err, logs)
+	}
+	return nil
+}
+
+// Client returns the client for the command port of the echo server.
+func (w *workload) Client() (*client.Instance, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client != nil {
+		return w.client, nil
+	}
+	grpcPort := common.GetPortForProtocol(&w.cfg.echo, protocol.GRPC)
+	if grpcPort == nil {
+		return nil, errors.New("unable to find GRPC command port")
+	}
+	host := w.ip
+	if w.mode == modeSidecar {
+		host = "127.0.0.1"
+	}
+	c, err := client.New(net.JoinHostPort(host, strconv.Itoa(w.appPorts[grpcPort.InstancePort])), w.cfg.echo.TLSSettings)
+	if err != nil {
+		return nil, err
+	}
+	w.client = c
+	return c, nil
+}
+
+func (w *workload) PodName() string {
+	return w.name
+}
+
+func (w *workload) Address() string {
+	return w.ip
+}
+
+func (w *workload) Sidecar() echo.Sidecar {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sidecar == nil {
+		// Keep the interface nil for workloads without a sidecar.
+		return nil
+	}
+	return w.sidecar
+}
+
+func (w *workload) ForwardEcho(ctx context.Context, request *proto.ForwardEchoRequest) (client.ParsedResponses, error) {
+	c, err := w.Client()
+	if err != nil {
+		return nil, err
+	}
+	return c.ForwardEcho(ctx, request)
+}
+
+func (w *workload) Logs() (string, error) {
+	out, err := ioutil.ReadFile(filepath.Join(w.dir, appLogFile))
+	return string(out), err
+}
+
+func (w *workload) LogsOrFail(t test.Failer) string {
+	t.Helper()
+	logs, err := w.Logs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
+
+// Close stops the processes and removes the pod of the workload.
+func (w *workload) Close() error {
+	var errs error
+	if err := w.stop(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	err := w.cfg.echo.Cluster.CoreV1().Pods(w.namespace).Delete(context.TODO(), w.name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		errs = multierror.Append(errs, err)
+	}
+	return errs
+}
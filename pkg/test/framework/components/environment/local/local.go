@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/cluster/clusterboot"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+)
+
+// Name of the local environment, as given to --istio.test.env.
+const Name = "local"
+
+// clusterName is the name of the single in-memory cluster, also used as the cluster ID of istiod.
+const clusterName = "local"
+
+// Environment is a hermetic environment running on the local host. Istiod runs in the test process against
+// an in-memory cluster backed by the fake kube client, and echo instances run as local processes.
+type Environment struct {
+	id       resource.ID
+	ctx      resource.Context
+	clusters []cluster.Cluster
+}
+
+var _ resource.Environment = &Environment{}
+
+// New returns a new local environment.
+func New(ctx resource.Context) (resource.Environment, error) {
+	scopes.Framework.Infof("Test Framework local environment")
+	e := &Environment{
+		ctx: ctx,
+	}
+	e.id = ctx.TrackResource(e)
+
+	clusters, err := clusterboot.NewFactory().With(cluster.Config{
+		Kind: cluster.Local,
+		Name: clusterName,
+	}).Build()
+	if err != nil {
+		return nil, err
+	}
+	e.clusters = clusters
+
+	return e, nil
+}
+
+func (e *Environment) EnvironmentName() string {
+	return "Local"
+}
+
+// IsMultinetwork returns false, all workloads share the network of the local host.
+func (e *Environment) IsMultinetwork() bool {
+	return false
+}
+
+func (e *Environment) Clusters() cluster.Clusters {
+	out := make([]cluster.Cluster, 0, len(e.clusters))
+	out = append(out, e.clusters...)
+	return out
+}
+
+// ID implements resource.Instance
+func (e *Environment) ID() resource.ID {
+	return e.id
+}
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/environment/local"
 	"istio.io/istio/pkg/test/framework/components/istio/ingress"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/scopes"
@@ -69,7 +70,8 @@ func GetOrFail(f test.Failer, ctx resource.Context) Instance {
 	return i
 }
 
-// Setup is a setup function that will deploy Istio on Kubernetes environment
+// Setup is a setup function that will deploy Istio on Kubernetes environment, or run istiod in process
+// in the local environment.
 func Setup(i *Instance, cfn SetupConfigFn, ctxFns ...SetupContextFn) resource.SetupFn {
 	return func(ctx resource.Context) error {
 		cfg, err := DefaultConfig(ctx)
@@ -122,7 +124,9 @@ func Deploy(ctx resource.Context, cfg *Config) (i Instance, err error) {
 		}
 	}()
 
-	if cfg.DeployHelm {
+	if env, ok := ctx.Environment().(*local.Environment); ok {
+		i, err = deployLocal(ctx, env, *cfg)
+	} else if cfg.DeployHelm {
 		i, err = deployWithHelm(ctx, ctx.Environment().(*kube.Environment), *cfg)
 	} else {
 		i, err = deploy(ctx, ctx.Environment().(*kube.Environment), *cfg)
@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/bootstrap"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/environment/local"
+	"istio.io/istio/pkg/test/framework/components/istio/ingress"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// localComponent is an istiod running in the test process, against the in-memory cluster of the local environment.
+type localComponent struct {
+	id       resource.ID
+	settings Config
+	cluster  cluster.Cluster
+	server   *bootstrap.Server
+	stop     chan struct{}
+}
+
+var (
+	_ io.Closer = &localComponent{}
+	_ Instance  = &localComponent{}
+)
+
+func deployLocal(ctx resource.Context, env *local.Environment, cfg Config) (Instance, error) {
+	i := &localComponent{
+		settings: cfg,
+		cluster:  env.Clusters()[0],
+		stop:     make(chan struct{}),
+	}
+	i.id = ctx.TrackResource(i)
+
+	scopes.Framework.Infof("=== Istio Component Config ===")
+	scopes.Framework.Infof("\n%s", cfg.String())
+	scopes.Framework.Infof("================================")
+
+	workDir, err := ctx.CreateTmpDirectory("istiod-local")
+	if err != nil {
+		return nil, err
+	}
+	meshConfigFile, err := writeLocalMeshConfig(workDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	args := bootstrap.NewPilotArgs(func(p *bootstrap.PilotArgs) {
+		p.Namespace = cfg.SystemNamespace
+		p.PodName = "istiod-local"
+		p.Revision = ctx.Settings().Revision
+		p.MeshConfigFile = meshConfigFile
+		p.ServerOptions = bootstrap.DiscoveryServerOptions{
+			// Dynamically assign all ports. Workloads connect to the plaintext gRPC port.
+			HTTPAddr:       "127.0.0.1:0",
+			MonitoringAddr: "127.0.0.1:0",
+			GRPCAddr:       "127.0.0.1:0",
+		}
+		p.RegistryOptions = bootstrap.RegistryOptions{
+			Registries: []string{string(serviceregistry.Kubernetes)},
+			KubeOptions: kubecontroller.Options{
+				ClusterID:    i.cluster.Name(),
+				DomainSuffix: constants.DefaultKubernetesDomain,
+			},
+		}
+		p.Plugins = bootstrap.DefaultPlugins
+		p.ShutdownDuration = time.Millisecond
+	})
+	s, err := bootstrap.NewServer(args, bootstrap.WithKubeClient(i.cluster))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating istiod: %v", err)
+	}
+	if err := s.Start(i.stop); err != nil {
+		close(i.stop)
+		return nil, fmt.Errorf("failed starting istiod: %v", err)
+	}
+	i.server = s
+
+	readyURL := fmt.Sprintf("http://%s/ready", s.HTTPListener.Addr())
+	if err := retry.UntilSuccess(func() error {
+		resp, err := http.Get(readyURL)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("istiod not ready: %s", resp.Status)
+		}
+		return nil
+	}, retry.Timeout(cfg.DeployTimeout), retry.Delay(100*time.Millisecond)); err != nil {
+		return nil, err
+	}
+	scopes.Framework.Infof("istiod is serving XDS at %s", s.GRPCListener.Addr())
+
+	return i, nil
+}
+
+// writeLocalMeshConfig writes the meshConfig of the control plane values to a file for istiod, as the
+// istio ConfigMap would be mounted for an installed istiod.
+func writeLocalMeshConfig(workDir string, cfg Config) (string, error) {
+	iop := struct {
+		Spec struct {
+			MeshConfig map[string]interface{} `json:"meshConfig,omitempty"`
+		} `json:"spec"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cfg.IstioOperatorConfigYAML(cfg.ControlPlaneValues)), &iop); err != nil {
+		return "", fmt.Errorf("failed parsing control plane values: %v", err)
+	}
+	mesh, err := yaml.Marshal(iop.Spec.MeshConfig)
+	if err != nil {
+		return "", err
+	}
+	meshConfigFile := filepath.Join(workDir, "mesh")
+	if iop.Spec.MeshConfig == nil {
+		// istiod falls back to the default mesh config if the file doesn't exist.
+		return meshConfigFile, nil
+	}
+	if err := ioutil.WriteFile(meshConfigFile, mesh, 0o644); err != nil {
+		return "", err
+	}
+	return meshConfigFile, nil
+}
+
+// ID implements resource.Instance
+func (i *localComponent) ID() resource.ID {
+	return i.id
+}
+
+func (i *localComponent) Settings() Config {
+	return i.settings
+}
+
+// IngressFor returns nil, no gateways are deployed in the local environment.
+func (i *localComponent) IngressFor(cluster.Cluster) ingress.Instance {
+	scopes.Framework.Errorf("ingress gateways are not supported in the local environment")
+	return nil
+}
+
+// CustomIngressFor returns nil, no gateways are deployed in the local environment.
+func (i *localComponent) CustomIngressFor(cluster.Cluster, string, string) ingress.Instance {
+	scopes.Framework.Errorf("ingress gateways are not supported in the local environment")
+	return nil
+}
+
+// RemoteDiscoveryAddressFor returns the plaintext XDS address of the in-process istiod, which serves all
+// workloads of the local environment.
+func (i *localComponent) RemoteDiscoveryAddressFor(cluster.Cluster) (net.TCPAddr, error) {
+	addr, ok := i.server.GRPCListener.Addr().(*net.TCPAddr)
+	if !ok {
+		return net.TCPAddr{}, fmt.Errorf("unexpected istiod address %v", i.server.GRPCListener.Addr())
+	}
+	return *addr, nil
+}
+
+func (i *localComponent) Close() error {
+	if i.server == nil {
+		return nil
+	}
+	close(i.stop)
+	i.server.WaitUntilCompletion()
+	return nil
+}
@@ -65,8 +65,8 @@ func init() {
 	flag.StringVar(&settingsFromCommandLine.BaseDir, "istio.test.work_dir", os.TempDir(),
 		"Local working directory for creating logs/temp files. If left empty, os.TempDir() is used.")
 
-	var env string
-	flag.StringVar(&env, "istio.test.env", "", "Deprecated. This flag does nothing")
+	flag.StringVar(&settingsFromCommandLine.Environment, "istio.test.env", settingsFromCommandLine.Environment,
+		"The environment to run the tests in: 'kube' (default), or 'local' to run the control plane and workloads on the local host.")
 
 	flag.BoolVar(&settingsFromCommandLine.NoCleanup, "istio.test.nocleanup", settingsFromCommandLine.NoCleanup,
 		"Do not cleanup resources after test completion")
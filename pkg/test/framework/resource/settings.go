@@ -70,6 +70,10 @@ type Settings struct {
 	// The label selector, in parsed form.
 	Selector label.Selector
 
+	// Environment is the name of the environment the tests run in, "kube" if empty. The "local" environment
+	// runs the control plane and the workloads on the local host, without a cluster.
+	Environment string
+
 	// EnvironmentFactory allows caller to override the environment creation. If nil, a default is used based
 	// on the known environment names.
 	EnvironmentFactory EnvironmentFactory
@@ -122,6 +126,7 @@ func (s *Settings) String() string {
 
 	result += fmt.Sprintf("TestID:            %s\n", s.TestID)
 	result += fmt.Sprintf("RunID:             %s\n", s.RunID.String())
+	result += fmt.Sprintf("Environment:       %s\n", s.Environment)
 	result += fmt.Sprintf("NoCleanup:         %v\n", s.NoCleanup)
 	result += fmt.Sprintf("BaseDir:           %s\n", s.BaseDir)
 	result += fmt.Sprintf("Selector:          %v\n", s.Selector)
@@ -33,6 +33,7 @@ import (
 
 	"istio.io/istio/pkg/test/framework/components/cluster"
 	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/environment/local"
 	ferrors "istio.io/istio/pkg/test/framework/errors"
 	"istio.io/istio/pkg/test/framework/label"
 	"istio.io/istio/pkg/test/framework/resource"
@@ -478,11 +479,18 @@ func initRuntime(s *suiteImpl) error {
 }
 
 func newEnvironment(ctx resource.Context) (resource.Environment, error) {
-	s, err := kube.NewSettingsFromCommandLine()
-	if err != nil {
-		return nil, err
+	switch ctx.Settings().Environment {
+	case "", "kube":
+		s, err := kube.NewSettingsFromCommandLine()
+		if err != nil {
+			return nil, err
+		}
+		return kube.New(ctx, s)
+	case local.Name:
+		return local.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown environment %q", ctx.Settings().Environment)
 	}
-	return kube.New(ctx, s)
 }
 
 func getSettings(testID string) (*resource.Settings, error) {
@@ -26,6 +26,10 @@ func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (se
 	switch credtype {
 	case security.GCE:
 		return plugin.CreateGCEPlugin(trustdomain, jwtPath, identityProvider), nil
+	case security.AWS:
+		return plugin.CreateAWSPlugin(plugin.DefaultMetadataEndpoint, identityProvider, plugin.DefaultAWSNonceFile), nil
+	case security.Azure:
+		return plugin.CreateAzurePlugin(plugin.DefaultMetadataEndpoint, identityProvider), nil
 	case security.Mock: // for test only
 		return plugin.CreateMockPlugin("test_token"), nil
 	default:
@@ -40,6 +40,16 @@ func TestNewCredFetcher(t *testing.T) {
 			expectedToken:    "",
 			expectedIdp:      "GoogleComputeEngine",
 		},
+		"aws test": {
+			fetcherType:      security.AWS,
+			identityProvider: security.AWS,
+			expectedIdp:      "AmazonWebServices",
+		},
+		"azure test": {
+			fetcherType:      security.Azure,
+			identityProvider: security.Azure,
+			expectedIdp:      "MicrosoftAzure",
+		},
 		"mock test": {
 			fetcherType:      security.Mock,
 			trustdomain:      "",
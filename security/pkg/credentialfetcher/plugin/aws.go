@@ -0,0 +1,199 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is AWS plugin of credentialfetcher.
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+var awscredLog = log.RegisterScope("awscred", "AWS credential fetcher for istio agent", 0)
+
+const (
+	// DefaultMetadataEndpoint is the link-local address of the AWS and Azure instance metadata services.
+	DefaultMetadataEndpoint = "http://169.254.169.254"
+
+	// DefaultAWSNonceFile is where the agent keeps the nonce presented with the instance
+	// identity document. It must survive agent restarts, since istiod rejects the document of
+	// the instance with any other nonce.
+	DefaultAWSNonceFile = "./etc/istio/proxy/aws-instance-nonce"
+
+	awsTokenPath        = "/latest/api/token"
+	awsDocumentPath     = "/latest/dynamic/instance-identity/document"
+	awsSignaturePath    = "/latest/dynamic/instance-identity/signature"
+	awsTokenHeader      = "X-aws-ec2-metadata-token"
+	awsTokenTTLHeader   = "X-aws-ec2-metadata-token-ttl-seconds"
+	awsTokenTTLSeconds  = "60"
+	metadataHTTPTimeout = 5 * time.Second
+	awsNonceBytes       = 32
+)
+
+// The plugin object.
+type AWSPlugin struct {
+	// endpoint is the address of the EC2 instance metadata service.
+	endpoint string
+
+	// identity provider
+	identityProvider string
+
+	client *http.Client
+
+	// nonceFile persists the nonce presented with the document. When empty, the nonce is only
+	// kept in memory.
+	nonceFile string
+
+	// The signed instance identity document does not change for the lifetime of the
+	// instance, so it is only fetched once.
+	credential string
+	mutex      sync.Mutex
+}
+
+// CreateAWSPlugin creates an AWS credential fetcher plugin, which presents the signed EC2
+// instance identity document as the workload credential, along with the nonce stored in
+// nonceFile. Return the pointer to the created plugin.
+func CreateAWSPlugin(endpoint, identityProvider, nonceFile string) *AWSPlugin {
+	if endpoint == "" {
+		endpoint = DefaultMetadataEndpoint
+	}
+	return &AWSPlugin{
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		identityProvider: identityProvider,
+		client:           &http.Client{Timeout: metadataHTTPTimeout},
+		nonceFile:        nonceFile,
+	}
+}
+
+// GetPlatformCredential fetches the instance identity document and its signature from the
+// EC2 instance metadata service using IMDSv2, and encodes them as an attested credential.
+// Note: this function only works in an EC2 instance, or against a stand-in metadata server.
+func (p *AWSPlugin) GetPlatformCredential() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.credential != "" {
+		return p.credential, nil
+	}
+	req, _ := http.NewRequest(http.MethodPut, p.endpoint+awsTokenPath, nil)
+	req.Header.Set(awsTokenTTLHeader, awsTokenTTLSeconds)
+	token, err := fetchMetadata(p.client, req)
+	if err != nil {
+		awscredLog.Errorf("Failed to get IMDSv2 session token: %v", err)
+		return "", err
+	}
+	get := func(path string) ([]byte, error) {
+		req, _ := http.NewRequest(http.MethodGet, p.endpoint+path, nil)
+		req.Header.Set(awsTokenHeader, string(token))
+		return fetchMetadata(p.client, req)
+	}
+	document, err := get(awsDocumentPath)
+	if err != nil {
+		awscredLog.Errorf("Failed to get instance identity document: %v", err)
+		return "", err
+	}
+	encodedSig, err := get(awsSignaturePath)
+	if err != nil {
+		awscredLog.Errorf("Failed to get instance identity signature: %v", err)
+		return "", err
+	}
+	// The signature is returned base64 encoded, wrapped over multiple lines.
+	sig, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(encodedSig)), ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode instance identity signature: %v", err)
+	}
+	nonce, err := p.loadNonce()
+	if err != nil {
+		awscredLog.Errorf("Failed to load instance nonce: %v", err)
+		return "", err
+	}
+	cred := &security.AttestedCredential{Type: security.AWS, Document: document, Signature: sig, Nonce: nonce}
+	if p.credential, err = cred.Encode(); err != nil {
+		return "", err
+	}
+	awscredLog.Debugf("Got AWS instance identity document: %d", len(document))
+	return p.credential, nil
+}
+
+// loadNonce returns the nonce of the nonce file, generating it on first use.
+func (p *AWSPlugin) loadNonce() (string, error) {
+	if p.nonceFile != "" {
+		b, err := ioutil.ReadFile(p.nonceFile)
+		if err == nil {
+			if nonce := strings.TrimSpace(string(b)); nonce != "" {
+				return nonce, nil
+			}
+			return "", fmt.Errorf("nonce file %s is empty", p.nonceFile)
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	b := make([]byte, awsNonceBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	nonce := hex.EncodeToString(b)
+	if p.nonceFile != "" {
+		if err := os.MkdirAll(filepath.Dir(p.nonceFile), 0700); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(p.nonceFile, []byte(nonce), 0600); err != nil {
+			return "", fmt.Errorf("failed to store nonce: %v", err)
+		}
+		awscredLog.Infof("Generated instance nonce in %s", p.nonceFile)
+	}
+	return nonce, nil
+}
+
+// GetType returns credential fetcher type.
+func (p *AWSPlugin) GetType() string {
+	return security.AWS
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *AWSPlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *AWSPlugin) Stop() {}
+
+// fetchMetadata sends a request to an instance metadata service and returns the response body.
+func fetchMetadata(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: unexpected status %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is Azure plugin of credentialfetcher.
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+var azurecredLog = log.RegisterScope("azurecred", "Azure credential fetcher for istio agent", 0)
+
+const (
+	azureAttestedPath       = "/metadata/attested/document"
+	azureAttestedAPIVersion = "2020-09-01"
+)
+
+// The plugin object.
+type AzurePlugin struct {
+	// endpoint is the address of the Azure instance metadata service.
+	endpoint string
+
+	// identity provider
+	identityProvider string
+
+	client *http.Client
+
+	// now returns the time used as nonce of the attested document.
+	now func() time.Time
+}
+
+// CreateAzurePlugin creates an Azure credential fetcher plugin, which presents the attested
+// data document of the VM as the workload credential. Return the pointer to the created plugin.
+func CreateAzurePlugin(endpoint, identityProvider string) *AzurePlugin {
+	if endpoint == "" {
+		endpoint = DefaultMetadataEndpoint
+	}
+	return &AzurePlugin{
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		identityProvider: identityProvider,
+		client:           &http.Client{Timeout: metadataHTTPTimeout},
+		now:              time.Now,
+	}
+}
+
+type azureAttestedResponse struct {
+	Encoding  string `json:"encoding"`
+	Signature string `json:"signature"`
+}
+
+// GetPlatformCredential fetches a fresh attested data document from the Azure instance metadata
+// service, and encodes it as an attested credential. The current unix time is used as nonce so
+// that istiod can bound how long a document is accepted for.
+// Note: this function only works in an Azure VM, or against a stand-in metadata server.
+func (p *AzurePlugin) GetPlatformCredential() (string, error) {
+	nonce := strconv.FormatInt(p.now().Unix(), 10)
+	url := fmt.Sprintf("%s%s?api-version=%s&nonce=%s", p.endpoint, azureAttestedPath, azureAttestedAPIVersion, nonce)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Metadata", "true")
+	body, err := fetchMetadata(p.client, req)
+	if err != nil {
+		azurecredLog.Errorf("Failed to get attested document from metadata server: %v", err)
+		return "", err
+	}
+	resp := azureAttestedResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal attested document: %v", err)
+	}
+	if resp.Encoding != "pkcs7" {
+		return "", fmt.Errorf("unsupported attested document encoding %q", resp.Encoding)
+	}
+	sig, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode attested document signature: %v", err)
+	}
+	azurecredLog.Debugf("Got Azure attested document: %d", len(sig))
+	return (&security.AttestedCredential{Type: security.Azure, Signature: sig}).Encode()
+}
+
+// GetType returns credential fetcher type.
+func (p *AzurePlugin) GetType() string {
+	return security.Azure
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *AzurePlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *AzurePlugin) Stop() {}
@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Test only: local stand-ins of the AWS and Azure instance metadata services.
+package plugin
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+// azureTimeStampLayout is the layout of the timestamps in Azure attested documents.
+const azureTimeStampLayout = "01/02/06 15:04:05 -0700"
+
+// AWSMetadataServer mocks the EC2 instance metadata service. It serves an instance identity
+// document signed with a test key, and requires IMDSv2 session tokens.
+type AWSMetadataServer struct {
+	server *httptest.Server
+
+	// CertPem is the PEM encoded certificate verifying the document signatures, the
+	// stand-in of the AWS public certificate of a region.
+	CertPem []byte
+
+	key      *rsa.PrivateKey
+	document map[string]interface{}
+	mutex    sync.RWMutex
+}
+
+// StartAWSMetadataServer starts a mock EC2 instance metadata service serving document.
+func StartAWSMetadataServer(document map[string]interface{}) (*AWSMetadataServer, error) {
+	certPem, keyPem, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "ec2.amazonaws.test",
+		TTL:          time.Hour,
+		Org:          "Test",
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		return nil, err
+	}
+	key, err := util.ParsePemEncodedKey(keyPem)
+	if err != nil {
+		return nil, err
+	}
+	ms := &AWSMetadataServer{CertPem: certPem, key: key.(*rsa.PrivateKey), document: document}
+	ms.server = httptest.NewServer(http.HandlerFunc(ms.serve))
+	return ms, nil
+}
+
+// URL returns the endpoint to pass to CreateAWSPlugin.
+func (ms *AWSMetadataServer) URL() string {
+	return ms.server.URL
+}
+
+// SetDocument replaces the served instance identity document.
+func (ms *AWSMetadataServer) SetDocument(document map[string]interface{}) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.document = document
+}
+
+func (ms *AWSMetadataServer) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == awsTokenPath {
+		if req.Method != http.MethodPut || req.Header.Get(awsTokenTTLHeader) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "test-session-token")
+		return
+	}
+	if req.Header.Get(awsTokenHeader) != "test-session-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	ms.mutex.RLock()
+	document, _ := json.MarshalIndent(ms.document, "", "  ")
+	ms.mutex.RUnlock()
+	switch req.URL.Path {
+	case awsDocumentPath:
+		_, _ = w.Write(document)
+	case awsSignaturePath:
+		digest := sha256.Sum256(document)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, ms.key, crypto.SHA256, digest[:])
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, base64.StdEncoding.EncodeToString(sig))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (ms *AWSMetadataServer) Stop() {
+	ms.server.Close()
+}
+
+// AzureMetadataServer mocks the Azure instance metadata service. It serves attested documents
+// signed by a test certificate issued by a test root.
+type AzureMetadataServer struct {
+	server *httptest.Server
+
+	// RootCertPem is the PEM encoded root certificate of the signer, the stand-in of the
+	// Azure attested data certificate chain.
+	RootCertPem []byte
+
+	signer   *x509.Certificate
+	key      crypto.Signer
+	document map[string]interface{}
+	validity time.Duration
+	mutex    sync.RWMutex
+}
+
+// StartAzureMetadataServer starts a mock Azure instance metadata service serving document,
+// signed by a certificate for signerName.
+func StartAzureMetadataServer(document map[string]interface{}, signerName string) (*AzureMetadataServer, error) {
+	rootPem, rootKeyPem, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "azure-test-root",
+		TTL:          time.Hour,
+		Org:          "Test",
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		return nil, err
+	}
+	root, err := util.ParsePemEncodedCertificate(rootPem)
+	if err != nil {
+		return nil, err
+	}
+	rootKey, err := util.ParsePemEncodedKey(rootKeyPem)
+	if err != nil {
+		return nil, err
+	}
+	signerPem, signerKeyPem, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:       signerName,
+		TTL:        time.Hour,
+		SignerCert: root,
+		SignerPriv: rootKey,
+		RSAKeySize: 2048,
+		IsServer:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signer, err := util.ParsePemEncodedCertificate(signerPem)
+	if err != nil {
+		return nil, err
+	}
+	key, err := util.ParsePemEncodedKey(signerKeyPem)
+	if err != nil {
+		return nil, err
+	}
+	ms := &AzureMetadataServer{
+		RootCertPem: rootPem,
+		signer:      signer,
+		key:         key.(crypto.Signer),
+		document:    document,
+		validity:    6 * time.Hour,
+	}
+	ms.server = httptest.NewServer(http.HandlerFunc(ms.serve))
+	return ms, nil
+}
+
+// URL returns the endpoint to pass to CreateAzurePlugin.
+func (ms *AzureMetadataServer) URL() string {
+	return ms.server.URL
+}
+
+// SetValidity sets how long served documents are valid for. A negative validity serves expired documents.
+func (ms *AzureMetadataServer) SetValidity(d time.Duration) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.validity = d
+}
+
+func (ms *AzureMetadataServer) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != azureAttestedPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if req.Header.Get("Metadata") != "true" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ms.mutex.RLock()
+	document := map[string]interface{}{}
+	for k, v := range ms.document {
+		document[k] = v
+	}
+	validity := ms.validity
+	ms.mutex.RUnlock()
+
+	now := time.Now().UTC()
+	document["nonce"] = req.URL.Query().Get("nonce")
+	document["timeStamp"] = map[string]string{
+		"createdOn": now.Format(azureTimeStampLayout),
+		"expiresOn": now.Add(validity).Format(azureTimeStampLayout),
+	}
+	content, _ := json.Marshal(document)
+	sig, err := util.SignPKCS7(content, ms.signer, ms.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(azureAttestedResponse{
+		Encoding:  "pkcs7",
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+}
+
+func (ms *AzureMetadataServer) Stop() {
+	ms.server.Close()
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestAWSPlugin(t *testing.T) {
+	ms, err := StartAWSMetadataServer(map[string]interface{}{
+		"accountId":  "123456789012",
+		"instanceId": "i-0123456789abcdef0",
+		"region":     "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("StartAWSMetadataServer() failed: %v", err)
+	}
+	defer ms.Stop()
+
+	p := CreateAWSPlugin(ms.URL(), "", "")
+	token, err := p.GetPlatformCredential()
+	if err != nil {
+		t.Fatalf("GetPlatformCredential() failed: %v", err)
+	}
+	cred, err := security.ParseAttestedCredential(token)
+	if err != nil {
+		t.Fatalf("ParseAttestedCredential() failed: %v", err)
+	}
+	if cred.Type != security.AWS {
+		t.Errorf("credential type = %q, want %q", cred.Type, security.AWS)
+	}
+	doc := map[string]string{}
+	if err := json.Unmarshal(cred.Document, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["instanceId"] != "i-0123456789abcdef0" {
+		t.Errorf("document = %v, want instanceId i-0123456789abcdef0", doc)
+	}
+	cert, _ := util.ParsePemEncodedCertificate(ms.CertPem)
+	digest := sha256.Sum256(cred.Document)
+	if err := rsa.VerifyPKCS1v15(cert.PublicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], cred.Signature); err != nil {
+		t.Errorf("document signature does not verify: %v", err)
+	}
+
+	// The document is static for the lifetime of the instance, so it is cached.
+	ms.SetDocument(map[string]interface{}{"instanceId": "i-other"})
+	if again, _ := p.GetPlatformCredential(); again != token {
+		t.Errorf("GetPlatformCredential() fetched the document again")
+	}
+}
+
+func TestAWSPluginNonce(t *testing.T) {
+	ms, err := StartAWSMetadataServer(map[string]interface{}{"instanceId": "i-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Stop()
+	nonceFile := path.Join(t.TempDir(), "proxy", "aws-instance-nonce")
+	nonce := func() string {
+		token, err := CreateAWSPlugin(ms.URL(), "", nonceFile).GetPlatformCredential()
+		if err != nil {
+			t.Fatalf("GetPlatformCredential() failed: %v", err)
+		}
+		cred, err := security.ParseAttestedCredential(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cred.Nonce
+	}
+	first := nonce()
+	if len(first) != 2*awsNonceBytes {
+		t.Fatalf("nonce = %q, want %d random bytes", first, awsNonceBytes)
+	}
+	// A restarted agent must present the same nonce.
+	if again := nonce(); again != first {
+		t.Errorf("nonce after restart = %q, want %q", again, first)
+	}
+	if err := ioutil.WriteFile(nonceFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateAWSPlugin(ms.URL(), "", nonceFile).GetPlatformCredential(); err == nil {
+		t.Errorf("GetPlatformCredential() succeeded with an empty nonce file")
+	}
+}
+
+func TestAWSPluginRequiresSessionToken(t *testing.T) {
+	ms, err := StartAWSMetadataServer(map[string]interface{}{"instanceId": "i-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Stop()
+	if _, err := CreateAWSPlugin(ms.URL()+"/unknown", "", "").GetPlatformCredential(); err == nil {
+		t.Errorf("GetPlatformCredential() succeeded against an invalid endpoint")
+	}
+}
+
+func TestAzurePlugin(t *testing.T) {
+	ms, err := StartAzureMetadataServer(map[string]interface{}{
+		"vmId":           "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		"subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d",
+	}, "metadata.azure.test")
+	if err != nil {
+		t.Fatalf("StartAzureMetadataServer() failed: %v", err)
+	}
+	defer ms.Stop()
+
+	now := time.Unix(1600000000, 0)
+	p := CreateAzurePlugin(ms.URL(), "azure-idp")
+	p.now = func() time.Time { return now }
+	token, err := p.GetPlatformCredential()
+	if err != nil {
+		t.Fatalf("GetPlatformCredential() failed: %v", err)
+	}
+	if p.GetIdentityProvider() != "azure-idp" {
+		t.Errorf("GetIdentityProvider() = %q, want azure-idp", p.GetIdentityProvider())
+	}
+	cred, err := security.ParseAttestedCredential(token)
+	if err != nil {
+		t.Fatalf("ParseAttestedCredential() failed: %v", err)
+	}
+	if cred.Type != security.Azure {
+		t.Errorf("credential type = %q, want %q", cred.Type, security.Azure)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ms.RootCertPem)
+	content, _, err := util.VerifyPKCS7(cred.Signature, x509.VerifyOptions{Roots: roots, DNSName: "metadata.azure.test"})
+	if err != nil {
+		t.Fatalf("VerifyPKCS7() failed: %v", err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["nonce"] != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("document nonce = %v, want %d", doc["nonce"], now.Unix())
+	}
+	if doc["vmId"] != "02aab8a4-74ef-476e-8182-f6d2ba4166a6" {
+		t.Errorf("document = %v, want vmId 02aab8a4-74ef-476e-8182-f6d2ba4166a6", doc)
+	}
+}
@@ -209,6 +209,11 @@ func (sc *SecretManagerClient) CallUpdateCallback(resourceName string) {
 	}
 }
 
+// WorkloadCertificate returns the cached workload certificate, or nil if none was generated yet.
+func (sc *SecretManagerClient) WorkloadCertificate() *security.SecretItem {
+	return sc.cache.GetWorkload()
+}
+
 // getCachedSecret: retrieve cached Secret Item (workload-certificate/workload-root) from secretManager client
 func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *security.SecretItem) {
 	var rootCertBundle []byte
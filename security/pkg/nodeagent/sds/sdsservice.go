@@ -36,6 +36,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/security"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
 )
 
@@ -208,15 +209,25 @@ func toEnvoySecret(s *security.SecretItem) *tls.Secret {
 
 	cfg, ok := model.SdsCertificateConfigFromResourceName(s.ResourceName)
 	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
-		secret.Type = &tls.Secret_ValidationContext{
-			ValidationContext: &tls.CertificateValidationContext{
-				TrustedCa: &core.DataSource{
-					Specifier: &core.DataSource_InlineBytes{
-						InlineBytes: s.RootCert,
-					},
+		validationContext := &tls.CertificateValidationContext{
+			TrustedCa: &core.DataSource{
+				Specifier: &core.DataSource_InlineBytes{
+					InlineBytes: s.RootCert,
 				},
 			},
 		}
+		// The trust bundle may carry the CRLs of the CA, revoking certificates mesh-wide.
+		if certs, crls := pkiutil.SplitRevocationLists(s.RootCert); len(crls) > 0 {
+			validationContext.TrustedCa.Specifier = &core.DataSource_InlineBytes{InlineBytes: certs}
+			validationContext.Crl = &core.DataSource{
+				Specifier: &core.DataSource_InlineBytes{
+					InlineBytes: crls,
+				},
+			}
+		}
+		secret.Type = &tls.Secret_ValidationContext{
+			ValidationContext: validationContext,
+		}
 	} else {
 		secret.Type = &tls.Secret_TlsCertificate{
 			TlsCertificate: &tls.TlsCertificate{
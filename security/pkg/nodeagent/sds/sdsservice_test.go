@@ -14,6 +14,7 @@
 package sds
 
 import (
+	"encoding/pem"
 	"fmt"
 	"net"
 	"strings"
@@ -310,3 +311,23 @@ func setupConnection(socket string) (*grpc.ClientConn, error) {
 
 	return conn, nil
 }
+
+func TestToEnvoySecretRevocationLists(t *testing.T) {
+	root := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("root")})
+	crl := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: []byte("crl")})
+
+	secret := toEnvoySecret(&ca2.SecretItem{ResourceName: rootResourceName, RootCert: root})
+	vc := secret.GetValidationContext()
+	if string(vc.GetTrustedCa().GetInlineBytes()) != string(root) || vc.GetCrl() != nil {
+		t.Errorf("unexpected validation context without CRLs: %v", vc)
+	}
+
+	secret = toEnvoySecret(&ca2.SecretItem{ResourceName: rootResourceName, RootCert: append(append([]byte{}, root...), crl...)})
+	vc = secret.GetValidationContext()
+	if string(vc.GetTrustedCa().GetInlineBytes()) != string(root) {
+		t.Errorf("got trusted CA %q, want the root only", vc.GetTrustedCa().GetInlineBytes())
+	}
+	if string(vc.GetCrl().GetInlineBytes()) != string(crl) {
+		t.Errorf("got CRL %q, want %q", vc.GetCrl().GetInlineBytes(), crl)
+	}
+}
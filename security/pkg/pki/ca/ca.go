@@ -51,6 +51,8 @@ const (
 	PrivateKeyID = "key.pem"
 	// RootCertID is the ID/name for the CA root certificate file.
 	RootCertID = "root-cert.pem"
+	// CRLChainID is the file of the CRLs issued by the CAs above a plugged-in intermediate CA.
+	CRLChainID = "crl-chain.pem"
 	// ServiceAccountNameAnnotationKey is the key to specify corresponding service account in the annotation of K8s secrets.
 	ServiceAccountNameAnnotationKey = "istio.io/service-account.name"
 
@@ -86,6 +88,9 @@ type IstioCAOptions struct {
 
 	// Config for creating self-signed root cert rotator.
 	RotatorConfig *SelfSignedCARootCertRotatorConfig
+
+	// Config for creating plugged-in cert rotator. The certificates are not rotated if nil.
+	PluggedCertRotatorConfig *PluggedCertRotatorConfig
 }
 
 // NewSelfSignedIstioCAOptions returns a new IstioCAOptions instance using self-signed certificate.
@@ -255,6 +260,10 @@ type IstioCA struct {
 	// rootCertRotator periodically rotates self-signed root cert for CA. It is nil
 	// if CA is not self-signed CA.
 	rootCertRotator *SelfSignedCARootCertRotator
+
+	// pluggedCertRotator rotates the plugged-in certs of the CA when they change. It is nil
+	// if CA is not a plugged-in CA or the rotation is disabled.
+	pluggedCertRotator *PluggedCertRotator
 }
 
 // NewIstioCA returns a new IstioCA instance.
@@ -269,6 +278,10 @@ func NewIstioCA(opts *IstioCAOptions) (*IstioCA, error) {
 	if opts.CAType == selfSignedCA && opts.RotatorConfig.CheckInterval > time.Duration(0) {
 		ca.rootCertRotator = NewSelfSignedCARootCertRotator(opts.RotatorConfig, ca)
 	}
+	if opts.CAType == pluggedCertCA && opts.PluggedCertRotatorConfig != nil &&
+		opts.PluggedCertRotatorConfig.CheckInterval > time.Duration(0) {
+		ca.pluggedCertRotator = NewPluggedCertRotator(opts.PluggedCertRotatorConfig, ca)
+	}
 
 	// if CA cert becomes invalid before workload cert it's going to cause workload cert to be invalid too,
 	// however citatel won't rotate if that happens, this function will prevent that using cert chain TTL as
@@ -287,6 +300,19 @@ func (ca *IstioCA) Run(stopChan chan struct{}) {
 		// Start root cert rotator in a separate goroutine.
 		go ca.rootCertRotator.Run(stopChan)
 	}
+	if ca.pluggedCertRotator != nil {
+		// Start plugged-in cert rotator in a separate goroutine.
+		go ca.pluggedCertRotator.Run(stopChan)
+	}
+}
+
+// PluggedCertRotationStatus returns the state of the rotation of the plugged-in certs, or nil
+// if they are not rotated.
+func (ca *IstioCA) PluggedCertRotationStatus() *RotationStatus {
+	if ca.pluggedCertRotator == nil {
+		return nil
+	}
+	return ca.pluggedCertRotator.Status()
 }
 
 // Sign takes a PEM-encoded CSR, subject IDs and lifetime, and returns a signed certificate. If forCA is true,
@@ -0,0 +1,48 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	phaseTag = monitoring.MustCreateLabel("phase")
+
+	pluggedCARotationPhase = monitoring.NewGauge(
+		"citadel_plugged_ca_rotation_phase",
+		"The phase of the rotation of the plugged-in CA certificates: 0 when idle, 1 while distributing "+
+			"the new roots, 2 while distributing the old roots after switching to the new signing key.",
+	)
+
+	pluggedCARotationTransitions = monitoring.NewSum(
+		"citadel_plugged_ca_rotation_transitions_total",
+		"The number of transitions of the rotation of the plugged-in CA certificates, by entered phase.",
+		monitoring.WithLabels(phaseTag),
+	)
+
+	pluggedCARotationErrors = monitoring.NewSum(
+		"citadel_plugged_ca_rotation_err_count",
+		"The number of failed checks of the plugged-in CA certificates, for example invalid new certificates.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		pluggedCARotationPhase,
+		pluggedCARotationTransitions,
+		pluggedCARotationErrors,
+	)
+}
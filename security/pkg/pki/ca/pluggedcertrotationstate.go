@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// PluggedCertRotationStateSecret stores the state of the rotation of the plugged-in CA certificates.
+	// It is a Secret as it holds the key the CA signs with during the rotation.
+	PluggedCertRotationStateSecret = "istio-ca-rotation-state"
+
+	rotationStateSecretType = "istio.io/ca-rotation-state"
+
+	phaseKey      = "phase"
+	phaseStartKey = "phase-start"
+	// The target files are stored with this prefix, the current ones without.
+	targetPrefix = "target-"
+)
+
+// RotationState is the state of a rotation of the plugged-in CA certificates, from which an istiod
+// restarting in the middle of the rotation resumes it.
+type RotationState struct {
+	Phase      RotationPhase
+	PhaseStart time.Time
+	// Current holds the certificate and key the CA signs with and the roots it distributes.
+	Current caFiles
+	// Target holds the plugged-in certificates the rotation converges to.
+	Target caFiles
+}
+
+// RotationStateStore persists the state of the rotation of the plugged-in CA certificates.
+type RotationStateStore interface {
+	// Load returns the persisted state, or nil if there is none.
+	Load() (*RotationState, error)
+	// Save persists the state.
+	Save(state *RotationState) error
+}
+
+// SecretRotationStateStore persists the rotation state in the PluggedCertRotationStateSecret Secret.
+// All the istiod replicas load it, only the one running Persist writes it.
+type SecretRotationStateStore struct {
+	client    corev1.SecretsGetter
+	namespace string
+
+	mutex  sync.Mutex
+	leader bool
+	// pending is the last state saved, written once this istiod becomes the leader.
+	pending *RotationState
+}
+
+var _ RotationStateStore = &SecretRotationStateStore{}
+
+// NewSecretRotationStateStore returns a store of the rotation state in a Secret of the namespace.
+func NewSecretRotationStateStore(client corev1.SecretsGetter, namespace string) *SecretRotationStateStore {
+	return &SecretRotationStateStore{client: client, namespace: namespace}
+}
+
+// Persist writes the saved states until stop is closed. It must only run on the leader istiod.
+func (s *SecretRotationStateStore) Persist(stop <-chan struct{}) {
+	s.mutex.Lock()
+	s.leader = true
+	if s.pending != nil {
+		if err := s.write(s.pending); err != nil {
+			pluggedCARotationErrors.Increment()
+			pluggedCertRotatorLog.Errorf("Failed to persist the plugged-in CA rotation state: %v", err)
+		}
+	}
+	s.mutex.Unlock()
+	<-stop
+	s.mutex.Lock()
+	s.leader = false
+	s.mutex.Unlock()
+}
+
+func (s *SecretRotationStateStore) Load() (*RotationState, error) {
+	secret, err := s.client.Secrets(s.namespace).Get(context.TODO(), PluggedCertRotationStateSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	phase, err := strconv.Atoi(string(secret.Data[phaseKey]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s of Secret %s: %v", phaseKey, PluggedCertRotationStateSecret, err)
+	}
+	phaseStart, err := time.Parse(time.RFC3339Nano, string(secret.Data[phaseStartKey]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s of Secret %s: %v", phaseStartKey, PluggedCertRotationStateSecret, err)
+	}
+	return &RotationState{
+		Phase:      RotationPhase(phase),
+		PhaseStart: phaseStart,
+		Current:    filesFromData(secret.Data, ""),
+		Target:     filesFromData(secret.Data, targetPrefix),
+	}, nil
+}
+
+func (s *SecretRotationStateStore) Save(state *RotationState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = state
+	if !s.leader {
+		return nil
+	}
+	return s.write(state)
+}
+
+func (s *SecretRotationStateStore) write(state *RotationState) error {
+	data := map[string][]byte{
+		phaseKey:      []byte(strconv.Itoa(int(state.Phase))),
+		phaseStartKey: []byte(state.PhaseStart.UTC().Format(time.RFC3339Nano)),
+	}
+	filesToData(data, "", state.Current)
+	filesToData(data, targetPrefix, state.Target)
+	secrets := s.client.Secrets(s.namespace)
+	secret, err := secrets.Get(context.TODO(), PluggedCertRotationStateSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = secrets.Create(context.TODO(), &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: PluggedCertRotationStateSecret, Namespace: s.namespace},
+			Data:       data,
+			Type:       rotationStateSecretType,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	secret.Data = data
+	_, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+	return err
+}
+
+func filesToData(data map[string][]byte, prefix string, f caFiles) {
+	data[prefix+CaCertID] = f.cert
+	data[prefix+caPrivateKeyID] = f.key
+	data[prefix+CertChainID] = f.chain
+	data[prefix+RootCertID] = f.root
+}
+
+func filesFromData(data map[string][]byte, prefix string) caFiles {
+	return caFiles{
+		cert:  data[prefix+CaCertID],
+		key:   data[prefix+caPrivateKeyID],
+		chain: data[prefix+CertChainID],
+		root:  data[prefix+RootCertID],
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/pkg/log"
+)
+
+var pluggedCertRotatorLog = log.RegisterScope("pluggedcertrotator", "Plugged-in CA cert rotator log", 0)
+
+// RotationPhase is the phase of a rotation of the plugged-in CA certificates.
+type RotationPhase int
+
+const (
+	// RotationIdle is the phase outside of a rotation: the CA signs with the plugged-in key and
+	// distributes the plugged-in roots.
+	RotationIdle RotationPhase = iota
+	// RotationDistributingTrust is the first phase of a rotation: the CA still signs with the old key,
+	// and distributes the old and the new roots, so that all workloads trust the new roots before any
+	// certificate is signed with the new key.
+	RotationDistributingTrust
+	// RotationRetainingOldRoots is the last phase of a rotation: the CA signs with the new key, and still
+	// distributes the old roots until the certificates signed with the old key expire.
+	RotationRetainingOldRoots
+)
+
+func (p RotationPhase) String() string {
+	switch p {
+	case RotationIdle:
+		return "Idle"
+	case RotationDistributingTrust:
+		return "DistributingTrust"
+	case RotationRetainingOldRoots:
+		return "RetainingOldRoots"
+	}
+	return fmt.Sprintf("RotationPhase(%d)", int(p))
+}
+
+// PluggedCertRotatorConfig configures the rotation of the plugged-in CA certificates.
+type PluggedCertRotatorConfig struct {
+	// CertDir holds the plugged-in ca-cert.pem, ca-key.pem, cert-chain.pem and root-cert.pem.
+	CertDir string
+	// CheckInterval is the interval of the checks of CertDir for new certificates.
+	CheckInterval time.Duration
+	// TrustDistributionPeriod is how long the old and new roots are distributed before the CA signs with
+	// the new key. It should cover the time for all workloads to get the new roots.
+	TrustDistributionPeriod time.Duration
+	// OldRootRetentionPeriod is how long the old roots are distributed after the CA signs with the new
+	// key. It should cover the lifetime of the certificates signed with the old key.
+	OldRootRetentionPeriod time.Duration
+	// Sync, if set, refreshes the files of CertDir before each check, for example from a remote Secret.
+	Sync func() error
+	// OnRootCertsChanged, if set, is called after the roots distributed by the CA changed.
+	OnRootCertsChanged func()
+	// State, if set, persists the state of the rotation so that it resumes after a restart, rather than
+	// signing with the plugged-in key and distributing the plugged-in roots only.
+	State RotationStateStore
+}
+
+// RotationStatus is the state of the rotation of the plugged-in CA certificates.
+type RotationStatus struct {
+	Phase string `json:"phase"`
+	// PhaseStart is when the current phase started.
+	PhaseStart time.Time `json:"phaseStart"`
+	// NextPhase is when the rotation moves to the next phase, unset when idle.
+	NextPhase *time.Time `json:"nextPhase,omitempty"`
+	// SigningCert describes the certificate the CA signs with.
+	SigningCert string `json:"signingCert"`
+	// PendingSigningCert describes the certificate the CA will sign with once the new roots are distributed.
+	PendingSigningCert string `json:"pendingSigningCert,omitempty"`
+	// RootCerts describes the distributed roots.
+	RootCerts []string  `json:"rootCerts"`
+	LastCheck time.Time `json:"lastCheck"`
+	// LastError is the error of the last check, for example invalid new certificates.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// caFiles is the content of the plugged-in CA files.
+type caFiles struct {
+	cert, key, chain, root []byte
+}
+
+func (f caFiles) equal(o caFiles) bool {
+	return bytes.Equal(f.cert, o.cert) && bytes.Equal(f.key, o.key) &&
+		bytes.Equal(f.chain, o.chain) && bytes.Equal(f.root, o.root)
+}
+
+// PluggedCertRotator watches the plugged-in CA certificates, and rotates them in stages when they change:
+// first distributing the old and new roots together, then signing with the new key, then dropping the
+// old roots. Each stage lasts long enough for the previous one to reach all workloads, so that trust
+// is never broken during the rotation.
+type PluggedCertRotator struct {
+	config *PluggedCertRotatorConfig
+	ca     *IstioCA
+	now    func() time.Time
+
+	mutex      sync.RWMutex
+	phase      RotationPhase
+	phaseStart time.Time
+	// target is the content of the files the rotation converges to.
+	target    caFiles
+	lastCheck time.Time
+	lastErr   error
+}
+
+// NewPluggedCertRotator returns a rotator of the plugged-in certificates the CA was created with. If
+// a rotation state was persisted and the CA still signs with a plugged-in key in it, the CA is reset to
+// it: the rotation resumes where it was, for example still distributing the old roots.
+func NewPluggedCertRotator(config *PluggedCertRotatorConfig, ca *IstioCA) *PluggedCertRotator {
+	cert, key, chain, root := ca.keyCertBundle.GetAllPem()
+	r := &PluggedCertRotator{
+		config: config,
+		ca:     ca,
+		now:    time.Now,
+		target: caFiles{cert: cert, key: key, chain: chain, root: root},
+	}
+	r.phaseStart = r.now()
+	if err := r.resume(); err != nil {
+		pluggedCARotationErrors.Increment()
+		pluggedCertRotatorLog.Errorf("Failed to resume the plugged-in CA rotation, using the plugged-in certificates: %v", err)
+	}
+	pluggedCARotationPhase.Record(float64(r.phase))
+	return r
+}
+
+// resume resets the CA and the rotation to the persisted state, if any. A state signing with a key that
+// is no longer plugged-in is discarded: the operator may have removed a compromised key, which must never
+// be signed with again, so the CA starts from the plugged-in certificates.
+func (r *PluggedCertRotator) resume() error {
+	if r.config.State == nil {
+		return nil
+	}
+	state, err := r.config.State.Load()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		r.saveState()
+		return nil
+	}
+	plugged := r.target
+	c := state.Current
+	if !bytes.Equal(c.key, plugged.key) {
+		pluggedCertRotatorLog.Warnf("The persisted plugged-in CA rotation signs with a key that is no longer " +
+			"plugged-in, discarding it and using the plugged-in certificates")
+		r.saveState()
+		return nil
+	}
+	if err := r.ca.keyCertBundle.VerifyAndSetAll(c.cert, c.key, c.chain, c.root); err != nil {
+		// Replace the invalid state, the CA keeps the plugged-in certificates.
+		r.saveState()
+		return fmt.Errorf("invalid persisted certificates: %v", err)
+	}
+	// The rotation goes on to the plugged-in certificates, never to certificates the operator removed.
+	r.phase, r.phaseStart = state.Phase, state.PhaseStart
+	pluggedCertRotatorLog.Infof("Resumed the plugged-in CA rotation in phase %v started at %v", r.phase, r.phaseStart)
+	return nil
+}
+
+// saveState persists the state of the rotation, if a store is configured.
+func (r *PluggedCertRotator) saveState() {
+	if r.config.State == nil {
+		return
+	}
+	cert, key, chain, root := r.ca.keyCertBundle.GetAllPem()
+	if err := r.config.State.Save(&RotationState{
+		Phase:      r.phase,
+		PhaseStart: r.phaseStart,
+		Current:    caFiles{cert: cert, key: key, chain: chain, root: root},
+		Target:     r.target,
+	}); err != nil {
+		pluggedCARotationErrors.Increment()
+		pluggedCertRotatorLog.Errorf("Failed to persist the plugged-in CA rotation state: %v", err)
+	}
+}
+
+// Run checks the plugged-in certificates every CheckInterval until stopCh is closed.
+func (r *PluggedCertRotator) Run(stopCh chan struct{}) {
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.checkAndRotate()
+		case <-stopCh:
+			pluggedCertRotatorLog.Info("Received stop signal, so stop the plugged-in cert rotator.")
+			return
+		}
+	}
+}
+
+// Status returns the state of the rotation.
+func (r *PluggedCertRotator) Status() *RotationStatus {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	cert, _, _, root := r.ca.keyCertBundle.GetAllPem()
+	s := &RotationStatus{
+		Phase:      r.phase.String(),
+		PhaseStart: r.phaseStart,
+		LastCheck:  r.lastCheck,
+	}
+	if next, f := r.nextPhase(); f {
+		s.NextPhase = &next
+	}
+	if certs := describeCerts(cert); len(certs) > 0 {
+		s.SigningCert = certs[0]
+	}
+	if !bytes.Equal(cert, r.target.cert) {
+		if certs := describeCerts(r.target.cert); len(certs) > 0 {
+			s.PendingSigningCert = certs[0]
+		}
+	}
+	s.RootCerts = describeCerts(root)
+	if r.lastErr != nil {
+		s.LastError = r.lastErr.Error()
+	}
+	return s
+}
+
+// checkAndRotate starts a rotation if the plugged-in certificates changed, and moves the current rotation
+// to its next phase when it is due.
+func (r *PluggedCertRotator) checkAndRotate() {
+	rootsChanged, err := r.check()
+	if err != nil {
+		pluggedCARotationErrors.Increment()
+		pluggedCertRotatorLog.Errorf("Failed to check the plugged-in CA certificates: %v", err)
+	}
+	if rootsChanged && r.config.OnRootCertsChanged != nil {
+		r.config.OnRootCertsChanged()
+	}
+}
+
+// check returns whether the distributed roots changed.
+func (r *PluggedCertRotator) check() (rootsChanged bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lastCheck = r.now()
+	defer func() {
+		r.lastErr = err
+	}()
+
+	if r.config.Sync != nil {
+		if err := r.config.Sync(); err != nil {
+			return false, fmt.Errorf("failed to refresh %s: %v", r.config.CertDir, err)
+		}
+	}
+	files, err := r.readFiles()
+	if err != nil {
+		return false, err
+	}
+	if files.equal(r.target) {
+		return r.advance()
+	}
+	if verr := verifyCAFiles(files); verr != nil {
+		// Keep rotating to the last valid certificates.
+		if rootsChanged, err = r.advance(); err != nil {
+			return rootsChanged, err
+		}
+		return rootsChanged, fmt.Errorf("the new plugged-in CA certificates are invalid, ignoring them: %v", verr)
+	}
+
+	// Start a new rotation from the current state, which may be in the middle of a previous rotation.
+	pluggedCertRotatorLog.Infof("New plugged-in CA certificates found in %s, starting a rotation", r.config.CertDir)
+	r.target = files
+	cert, key, chain, root := r.ca.keyCertBundle.GetAllPem()
+	roots := mergeRootCerts(root, files.root)
+	if bytes.Equal(roots, root) {
+		// The new roots are already distributed.
+		return false, r.switchSigningCert(roots)
+	}
+	if err := r.ca.keyCertBundle.VerifyAndSetAll(cert, key, chain, roots); err != nil {
+		return false, err
+	}
+	r.setPhase(RotationDistributingTrust)
+	return true, nil
+}
+
+// advance moves the current rotation to its next phase if it is due.
+func (r *PluggedCertRotator) advance() (rootsChanged bool, err error) {
+	next, f := r.nextPhase()
+	if !f || r.now().Before(next) {
+		return false, nil
+	}
+	switch r.phase {
+	case RotationDistributingTrust:
+		return false, r.switchSigningCert(r.ca.keyCertBundle.GetRootCertPem())
+	case RotationRetainingOldRoots:
+		t := r.target
+		if err := r.ca.keyCertBundle.VerifyAndSetAll(t.cert, t.key, t.chain, t.root); err != nil {
+			return false, err
+		}
+		r.setPhase(RotationIdle)
+		return true, nil
+	}
+	return false, nil
+}
+
+// switchSigningCert signs with the target certificate, keeping the given roots.
+func (r *PluggedCertRotator) switchSigningCert(roots []byte) error {
+	t := r.target
+	if err := r.ca.keyCertBundle.VerifyAndSetAll(t.cert, t.key, t.chain, roots); err != nil {
+		return err
+	}
+	if bytes.Equal(roots, t.root) {
+		r.setPhase(RotationIdle)
+	} else {
+		r.setPhase(RotationRetainingOldRoots)
+	}
+	return nil
+}
+
+func (r *PluggedCertRotator) nextPhase() (time.Time, bool) {
+	switch r.phase {
+	case RotationDistributingTrust:
+		return r.phaseStart.Add(r.config.TrustDistributionPeriod), true
+	case RotationRetainingOldRoots:
+		return r.phaseStart.Add(r.config.OldRootRetentionPeriod), true
+	}
+	return time.Time{}, false
+}
+
+func (r *PluggedCertRotator) setPhase(phase RotationPhase) {
+	pluggedCertRotatorLog.Infof("Plugged-in CA rotation phase %v -> %v", r.phase, phase)
+	r.phase = phase
+	r.phaseStart = r.now()
+	r.saveState()
+	pluggedCARotationPhase.Record(float64(phase))
+	pluggedCARotationTransitions.With(phaseTag.Value(phase.String())).Increment()
+}
+
+func (r *PluggedCertRotator) readFiles() (caFiles, error) {
+	var f caFiles
+	for name, out := range map[string]*[]byte{
+		CaCertID:       &f.cert,
+		caPrivateKeyID: &f.key,
+		CertChainID:    &f.chain,
+		RootCertID:     &f.root,
+	} {
+		b, err := ioutil.ReadFile(path.Join(r.config.CertDir, name))
+		if err != nil {
+			return f, err
+		}
+		*out = b
+	}
+	return f, nil
+}
+
+// verifyCAFiles checks that the files hold a CA certificate and its key, verified by the roots.
+func verifyCAFiles(f caFiles) error {
+	if err := util.Verify(f.cert, f.key, f.chain, f.root); err != nil {
+		return err
+	}
+	cert, err := util.ParsePemEncodedCertificate(f.cert)
+	if err != nil {
+		return err
+	}
+	if !cert.IsCA {
+		return fmt.Errorf("certificate is not authorized to sign other certificates")
+	}
+	return nil
+}
+
+// mergeRootCerts returns the roots followed by the additional roots they do not hold.
+func mergeRootCerts(roots, additional []byte) []byte {
+	seen := map[string]struct{}{}
+	for rest := roots; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		seen[string(block.Bytes)] = struct{}{}
+	}
+	out := roots
+	for rest := additional; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if _, f := seen[string(block.Bytes)]; f {
+			continue
+		}
+		seen[string(block.Bytes)] = struct{}{}
+		out = util.AppendCertByte(out, pem.EncodeToMemory(block))
+	}
+	return out
+}
+
+// describeCerts returns the subject, serial number and expiry of each certificate of a PEM bundle.
+func describeCerts(pemCerts []byte) []string {
+	var out []string
+	for rest := pemCerts; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		cert, err := util.ParsePemEncodedCertificate(pem.EncodeToMemory(block))
+		if err != nil {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s (serial %x, expires %s)",
+			cert.Subject.String(), cert.SerialNumber, cert.NotAfter.UTC().Format(time.RFC3339)))
+	}
+	return out
+}
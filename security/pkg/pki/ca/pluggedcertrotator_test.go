@@ -0,0 +1,269 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+type testCA struct {
+	root, cert, key []byte
+	rootKey         interface{}
+}
+
+// newTestCA generates a root and an intermediate CA signed by it.
+func newTestCA(t *testing.T, org string) testCA {
+	t.Helper()
+	root, rootKey, err := util.GenCertKeyFromOptions(util.CertOptions{
+		TTL: time.Hour, Org: org, IsCA: true, IsSelfSigned: true, ECSigAlg: util.EcdsaSigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := util.ParsePemEncodedCertificate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := util.ParsePemEncodedKey(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := util.GenCertKeyFromOptions(util.CertOptions{
+		TTL: time.Hour, Org: org, IsCA: true, SignerCert: rootCert, SignerPriv: signer, ECSigAlg: util.EcdsaSigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{root: root, cert: cert, key: key, rootKey: signer}
+}
+
+func (c testCA) write(t *testing.T, dir string) {
+	t.Helper()
+	for name, b := range map[string][]byte{
+		CaCertID:       c.cert,
+		caPrivateKeyID: c.key,
+		CertChainID:    c.cert,
+		RootCertID:     c.root,
+	} {
+		if err := ioutil.WriteFile(path.Join(dir, name), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestPluggedCertRotator(t *testing.T, dir string, state RotationStateStore) (*PluggedCertRotator, *time.Time, *int) {
+	t.Helper()
+	opts, err := NewPluggedCertIstioCAOptions(path.Join(dir, CertChainID), path.Join(dir, CaCertID),
+		path.Join(dir, caPrivateKeyID), path.Join(dir, RootCertID), time.Hour, time.Hour, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootChanges := 0
+	opts.PluggedCertRotatorConfig = &PluggedCertRotatorConfig{
+		CertDir:                 dir,
+		CheckInterval:           time.Minute,
+		TrustDistributionPeriod: 10 * time.Minute,
+		OldRootRetentionPeriod:  time.Hour,
+		OnRootCertsChanged:      func() { rootChanges++ },
+		State:                   state,
+	}
+	ca, err := NewIstioCA(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ca.pluggedCertRotator.now = func() time.Time { return now }
+	return ca.pluggedCertRotator, &now, &rootChanges
+}
+
+func TestPluggedCertRotatorNewRoot(t *testing.T) {
+	dir := t.TempDir()
+	old, next := newTestCA(t, "old"), newTestCA(t, "new")
+	old.write(t, dir)
+	r, now, rootChanges := newTestPluggedCertRotator(t, dir, nil)
+	bundle := r.ca.GetCAKeyCertBundle()
+
+	r.checkAndRotate()
+	if r.phase != RotationIdle || *rootChanges != 0 {
+		t.Fatalf("got phase %v and %d root changes without new certs", r.phase, *rootChanges)
+	}
+
+	next.write(t, dir)
+	r.checkAndRotate()
+	cert, _, _, roots := bundle.GetAllPem()
+	if r.phase != RotationDistributingTrust || *rootChanges != 1 {
+		t.Fatalf("got phase %v and %d root changes, want DistributingTrust and 1", r.phase, *rootChanges)
+	}
+	if !bytes.Equal(cert, old.cert) {
+		t.Errorf("the CA signs with the new cert before the new root is distributed")
+	}
+	if want := util.AppendCertByte(old.root, next.root); !bytes.Equal(roots, want) {
+		t.Errorf("got roots\n%s\nwant the old and new roots", roots)
+	}
+	if s := r.Status(); s.PendingSigningCert == "" || len(s.RootCerts) != 2 || s.NextPhase == nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	// Nothing happens until the trust distribution period is over.
+	*now = now.Add(9 * time.Minute)
+	r.checkAndRotate()
+	if r.phase != RotationDistributingTrust {
+		t.Fatalf("got phase %v before the end of the trust distribution period", r.phase)
+	}
+
+	*now = now.Add(time.Minute)
+	r.checkAndRotate()
+	cert, _, _, roots = bundle.GetAllPem()
+	if r.phase != RotationRetainingOldRoots || *rootChanges != 1 {
+		t.Fatalf("got phase %v and %d root changes, want RetainingOldRoots and 1", r.phase, *rootChanges)
+	}
+	if !bytes.Equal(cert, next.cert) {
+		t.Errorf("the CA does not sign with the new cert")
+	}
+	if !bytes.Equal(roots, util.AppendCertByte(old.root, next.root)) {
+		t.Errorf("the old root was dropped while certs signed with the old key are valid")
+	}
+
+	*now = now.Add(time.Hour)
+	r.checkAndRotate()
+	if r.phase != RotationIdle || *rootChanges != 2 {
+		t.Fatalf("got phase %v and %d root changes, want Idle and 2", r.phase, *rootChanges)
+	}
+	if roots := bundle.GetRootCertPem(); !bytes.Equal(roots, next.root) {
+		t.Errorf("got roots\n%s\nwant the new root only", roots)
+	}
+	if s := r.Status(); s.PendingSigningCert != "" || len(s.RootCerts) != 1 || s.NextPhase != nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestPluggedCertRotatorSameRoot(t *testing.T) {
+	dir := t.TempDir()
+	old := newTestCA(t, "old")
+	old.write(t, dir)
+	r, _, rootChanges := newTestPluggedCertRotator(t, dir, nil)
+
+	// A new intermediate signed by the same root is used right away.
+	rootCert, err := util.ParsePemEncodedCertificate(old.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := testCA{root: old.root}
+	if next.cert, next.key, err = util.GenCertKeyFromOptions(util.CertOptions{
+		TTL: time.Hour, Org: "next", IsCA: true, SignerCert: rootCert, SignerPriv: old.rootKey, ECSigAlg: util.EcdsaSigAlg,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	next.write(t, dir)
+	r.checkAndRotate()
+	if r.phase != RotationIdle || *rootChanges != 0 {
+		t.Fatalf("got phase %v and %d root changes, want Idle and 0", r.phase, *rootChanges)
+	}
+	if cert := r.ca.GetCAKeyCertBundle().GetRootCertPem(); !bytes.Equal(cert, old.root) {
+		t.Errorf("the roots changed")
+	}
+	if cert, _, _, _ := r.ca.GetCAKeyCertBundle().GetAllPem(); !bytes.Equal(cert, next.cert) {
+		t.Errorf("the CA does not sign with the new cert")
+	}
+}
+
+func TestPluggedCertRotatorInvalidCerts(t *testing.T) {
+	dir := t.TempDir()
+	old, next := newTestCA(t, "old"), newTestCA(t, "new")
+	old.write(t, dir)
+	r, _, rootChanges := newTestPluggedCertRotator(t, dir, nil)
+
+	// The new cert is not signed by the new root.
+	next.root = newTestCA(t, "other").root
+	next.write(t, dir)
+	r.checkAndRotate()
+	if r.phase != RotationIdle || *rootChanges != 0 {
+		t.Fatalf("got phase %v and %d root changes with invalid certs", r.phase, *rootChanges)
+	}
+	if s := r.Status(); s.LastError == "" {
+		t.Errorf("the error is not reported")
+	}
+	if cert, _, _, roots := r.ca.GetCAKeyCertBundle().GetAllPem(); !bytes.Equal(cert, old.cert) || !bytes.Equal(roots, old.root) {
+		t.Errorf("the CA does not use the old certs")
+	}
+}
+
+func TestPluggedCertRotatorRestart(t *testing.T) {
+	dir := t.TempDir()
+	old, next := newTestCA(t, "old"), newTestCA(t, "new")
+	old.write(t, dir)
+	state := NewSecretRotationStateStore(fake.NewSimpleClientset().CoreV1(), "istio-system")
+	state.leader = true
+	r, now, _ := newTestPluggedCertRotator(t, dir, state)
+
+	next.write(t, dir)
+	r.checkAndRotate()
+	if r.phase != RotationDistributingTrust {
+		t.Fatalf("got phase %v, want DistributingTrust", r.phase)
+	}
+
+	// istiod restarts in the middle of the rotation: the old key was removed, for example because it was
+	// compromised, so the CA signs with the plugged-in key right away.
+	restarted, restartedNow, _ := newTestPluggedCertRotator(t, dir, state)
+	cert, _, _, roots := restarted.ca.GetCAKeyCertBundle().GetAllPem()
+	if restarted.phase != RotationIdle {
+		t.Fatalf("got phase %v, want Idle", restarted.phase)
+	}
+	if !bytes.Equal(cert, next.cert) || !bytes.Equal(roots, next.root) {
+		t.Errorf("the CA does not use the plugged-in certs after a restart")
+	}
+	*restartedNow = now.Add(time.Hour)
+	restarted.checkAndRotate()
+	if cert, _, _, _ := restarted.ca.GetCAKeyCertBundle().GetAllPem(); !bytes.Equal(cert, next.cert) {
+		t.Errorf("the CA moved back to the removed key")
+	}
+
+	// istiod restarts while retaining the old roots: it keeps distributing them until the rotation ends.
+	last := newTestCA(t, "last")
+	last.write(t, dir)
+	restarted.checkAndRotate()
+	*restartedNow = restartedNow.Add(10 * time.Minute)
+	restarted.checkAndRotate()
+	if restarted.phase != RotationRetainingOldRoots {
+		t.Fatalf("got phase %v, want RetainingOldRoots", restarted.phase)
+	}
+	phaseStart := restarted.phaseStart
+	resumed, resumedNow, _ := newTestPluggedCertRotator(t, dir, state)
+	cert, _, _, roots = resumed.ca.GetCAKeyCertBundle().GetAllPem()
+	if resumed.phase != RotationRetainingOldRoots || !resumed.phaseStart.Equal(phaseStart) {
+		t.Fatalf("got phase %v started at %v, want RetainingOldRoots started at %v", resumed.phase, resumed.phaseStart, phaseStart)
+	}
+	if !bytes.Equal(cert, last.cert) || !bytes.Equal(roots, util.AppendCertByte(next.root, last.root)) {
+		t.Errorf("got roots\n%s\nwant the old and new roots", roots)
+	}
+
+	// The rotation goes on from the persisted phase start.
+	*resumedNow = phaseStart.Add(time.Hour)
+	resumed.checkAndRotate()
+	if resumed.phase != RotationIdle {
+		t.Fatalf("got phase %v, want Idle", resumed.phase)
+	}
+	if _, _, _, roots := resumed.ca.GetCAKeyCertBundle().GetAllPem(); !bytes.Equal(roots, last.root) {
+		t.Errorf("the old roots are still distributed")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store which appends the entries to a file, one JSON object per line, so the
+// ledger survives istiod restarts. The entries are also kept in memory to serve List.
+type FileStore struct {
+	mutex   sync.RWMutex
+	path    string
+	file    *os.File
+	entries []*Entry
+}
+
+var _ Store = &FileStore{}
+
+// NewFileStore opens the ledger file at the given path, creating it if needed, and loads its entries.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read the certificate ledger %s: %v", path, err)
+	}
+	if err := s.load(data); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the certificate ledger %s: %v", path, err)
+	}
+	// Terminate a partial last line, so the next entry is not appended to it.
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write the certificate ledger %s: %v", path, err)
+		}
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileStore) load(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			// A partial last line is left by a crash while appending. Skip it rather than
+			// refusing to start the CA.
+			ledgerLog.Warnf("skipping invalid entry at line %d of the certificate ledger %s: %v", line, s.path, err)
+			continue
+		}
+		s.entries = append(s.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the certificate ledger %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Add implements Store.
+func (s *FileStore) Add(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write the certificate ledger %s: %v", s.path, err)
+	}
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// List implements Store.
+func (s *FileStore) List(f Filter) ([]*Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return filterEntries(s.entries, f), nil
+}
+
+// Prune implements Store. The file is rewritten without the expired entries.
+func (s *FileStore) Prune(before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	kept, pruned := pruneEntries(s.entries, before)
+	s.entries = kept
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune the certificate ledger %s: %v", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, e := range kept {
+		b, err := json.Marshal(e)
+		if err != nil {
+			tmp.Close()
+			return 0, err
+		}
+		_, _ = w.Write(append(b, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to prune the certificate ledger %s: %v", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to prune the certificate ledger %s: %v", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return 0, fmt.Errorf("failed to prune the certificate ledger %s: %v", s.path, err)
+	}
+
+	// Appends must go to the new file.
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return pruned, fmt.Errorf("failed to reopen the certificate ledger %s: %v", s.path, err)
+	}
+	s.file.Close()
+	s.file = f
+	return pruned, nil
+}
+
+// Close closes the ledger file.
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
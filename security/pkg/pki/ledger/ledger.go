@@ -0,0 +1,191 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ledger records the certificates issued by the Istio CA, and revokes them.
+package ledger
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/pkg/log"
+)
+
+var ledgerLog = log.RegisterScope("ledger", "Istio CA certificate ledger log", 0)
+
+// Entry is a certificate issued by the CA.
+type Entry struct {
+	// Serial is the serial number of the certificate, in lower case hex.
+	Serial string `json:"serial"`
+	// SANs are the URI and DNS subject alternative names of the certificate.
+	SANs []string `json:"sans"`
+	// Caller is the authenticated identity which requested the certificate.
+	Caller string `json:"caller,omitempty"`
+	// AuthSource is how the caller was authenticated.
+	AuthSource string `json:"authSource,omitempty"`
+	// IssuedAt is when the certificate was issued.
+	IssuedAt time.Time `json:"issuedAt"`
+	// NotAfter is when the certificate expires.
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// NewEntry returns the entry of an issued PEM encoded certificate.
+func NewEntry(certPEM []byte, caller, authSource string) (*Entry, error) {
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return entryFromCert(cert, caller, authSource)
+}
+
+func entryFromCert(cert *x509.Certificate, caller, authSource string) (*Entry, error) {
+	ids, err := util.ExtractIDs(cert.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract the SANs of certificate %x: %v", cert.SerialNumber, err)
+	}
+	return &Entry{
+		Serial:     fmt.Sprintf("%x", cert.SerialNumber),
+		SANs:       ids,
+		Caller:     caller,
+		AuthSource: authSource,
+		IssuedAt:   cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+	}, nil
+}
+
+// HasSAN returns whether the certificate has the given SAN.
+func (e *Entry) HasSAN(san string) bool {
+	for _, s := range e.SANs {
+		if s == san {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects ledger entries. Empty fields match all entries.
+type Filter struct {
+	// SAN matches the certificates with this SAN, e.g. a SPIFFE ID.
+	SAN string
+	// Serial matches the certificate with this serial number, in hex.
+	Serial string
+	// ValidAt matches the certificates which have not expired at this time.
+	ValidAt time.Time
+}
+
+// Matches returns whether the entry is selected by the filter.
+func (f Filter) Matches(e *Entry) bool {
+	if f.Serial != "" && !strings.EqualFold(NormalizeSerial(f.Serial), e.Serial) {
+		return false
+	}
+	if f.SAN != "" && !e.HasSAN(f.SAN) {
+		return false
+	}
+	if !f.ValidAt.IsZero() && !e.NotAfter.After(f.ValidAt) {
+		return false
+	}
+	return true
+}
+
+// NormalizeSerial returns a hex serial number in the format used by the ledger, so serials
+// copied from openssl output (e.g. "0A:1B") match the recorded ones.
+func NormalizeSerial(serial string) string {
+	s := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(serial, "0x"), ":", ""))
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// Store persists the ledger entries.
+type Store interface {
+	// Add records an issued certificate.
+	Add(e *Entry) error
+	// List returns the entries selected by the filter, ordered by issuance time.
+	List(f Filter) ([]*Entry, error)
+	// Prune removes the entries of the certificates which expired before the given time,
+	// and returns how many were removed.
+	Prune(before time.Time) (int, error)
+}
+
+// MemoryStore is a Store which keeps the entries in memory. They are lost when istiod restarts.
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	entries []*Entry
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(e *Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(f Filter) ([]*Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return filterEntries(s.entries, f), nil
+}
+
+// Prune implements Store.
+func (s *MemoryStore) Prune(before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var pruned int
+	s.entries, pruned = pruneEntries(s.entries, before)
+	return pruned, nil
+}
+
+func filterEntries(entries []*Entry, f Filter) []*Entry {
+	out := make([]*Entry, 0)
+	for _, e := range entries {
+		if f.Matches(e) {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].IssuedAt.Before(out[j].IssuedAt)
+	})
+	return out
+}
+
+func pruneEntries(entries []*Entry, before time.Time) ([]*Entry, int) {
+	kept := entries[:0]
+	for _, e := range entries {
+		if !e.NotAfter.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	pruned := len(entries) - len(kept)
+	// Clear the tail so the pruned entries can be garbage collected.
+	for i := len(kept); i < len(entries); i++ {
+		entries[i] = nil
+	}
+	return kept, pruned
+}
@@ -0,0 +1,154 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func testEntries() []*Entry {
+	return []*Entry{
+		{Serial: "a1", SANs: []string{"spiffe://cluster.local/ns/a/sa/a"}, IssuedAt: now.Add(-2 * time.Hour), NotAfter: now.Add(-time.Hour)},
+		{Serial: "b2", SANs: []string{"spiffe://cluster.local/ns/b/sa/b"}, IssuedAt: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+		{Serial: "a3", SANs: []string{"spiffe://cluster.local/ns/a/sa/a"}, IssuedAt: now, NotAfter: now.Add(2 * time.Hour)},
+	}
+}
+
+func serials(entries []*Entry) []string {
+	out := []string{}
+	for _, e := range entries {
+		out = append(out, e.Serial)
+	}
+	return out
+}
+
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	for _, e := range testEntries() {
+		if err := s.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"a1", "b2", "a3"}},
+		{"san", Filter{SAN: "spiffe://cluster.local/ns/a/sa/a"}, []string{"a1", "a3"}},
+		{"valid", Filter{SAN: "spiffe://cluster.local/ns/a/sa/a", ValidAt: now}, []string{"a3"}},
+		{"serial", Filter{Serial: "B2"}, []string{"b2"}},
+		{"openssl serial", Filter{Serial: "00:a3"}, []string{"a3"}},
+		{"no match", Filter{SAN: "spiffe://cluster.local/ns/c/sa/c"}, []string{}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(serials(got), tt.want) {
+				t.Errorf("got %v, want %v", serials(got), tt.want)
+			}
+		})
+	}
+
+	pruned, err := s.Prune(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.List(Filter{})
+	if pruned != 1 || !reflect.DeepEqual(serials(got), []string{"b2", "a3"}) {
+		t.Errorf("pruned %d entries and kept %v, want 1 and [b2 a3]", pruned, serials(got))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err := s.Add(&Entry{Serial: "c4", IssuedAt: now, NotAfter: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A partial line left by a crash is skipped.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"serial":"d5","sa`)
+	f.Close()
+
+	// The entries written after the prune are kept.
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.List(Filter{})
+	if want := []string{"b2", "a3", "c4"}; !reflect.DeepEqual(serials(got), want) {
+		t.Errorf("reloaded %v, want %v", serials(got), want)
+	}
+	if err := s.Add(&Entry{Serial: "e6", IssuedAt: now, NotAfter: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// The entry added after the partial line is not lost.
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	got, _ = s.List(Filter{})
+	if want := []string{"b2", "a3", "c4", "e6"}; !reflect.DeepEqual(serials(got), want) {
+		t.Errorf("reloaded %v, want %v", serials(got), want)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("got %d files, want the ledger only", len(files))
+	}
+}
+
+func TestNormalizeSerial(t *testing.T) {
+	for in, want := range map[string]string{
+		"0x0A1B":   "a1b",
+		"0A:1B":    "a1b",
+		"a1b":      "a1b",
+		"00":       "0",
+		"FF:00:01": "ff0001",
+	} {
+		if got := NormalizeSerial(in); got != want {
+			t.Errorf("NormalizeSerial(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	revokedCerts = monitoring.NewGauge(
+		"citadel_server_revoked_cert_count",
+		"The number of unexpired certificates in the CRL published by Citadel.",
+	)
+
+	revokedIdentities = monitoring.NewGauge(
+		"citadel_server_revoked_identity_count",
+		"The number of identities revoked in Citadel.",
+	)
+
+	revocationErrorCounts = monitoring.NewSum(
+		"citadel_server_revocation_err_count",
+		"The number of errors occurred when signing the CRL.",
+	)
+
+	chainCRLExpiryTimestamp = monitoring.NewGauge(
+		"citadel_server_chain_crl_expiry_timestamp",
+		"The unix timestamp, in seconds, of the next update of the first chain CRL to expire. "+
+			"Proxies reject all the peer certificates once it is passed.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		revokedCerts,
+		revokedIdentities,
+		revocationErrorCounts,
+		chainCRLExpiryTimestamp,
+	)
+}
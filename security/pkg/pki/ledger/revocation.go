@@ -0,0 +1,518 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// crlPEMType is the PEM block type of certificate revocation lists.
+	crlPEMType = "X509 CRL"
+
+	// DefaultCRLValidity is how long a published CRL is valid by default.
+	DefaultCRLValidity = 24 * time.Hour
+	// DefaultChainCheckInterval is how often the chain CRLs are read again by default.
+	DefaultChainCheckInterval = time.Minute
+)
+
+var oidExtensionCRLNumber = asn1.ObjectIdentifier{2, 5, 29, 20}
+
+// Revocations are the certificates revoked by the mesh operator.
+type Revocations struct {
+	// Serials are the hex serial numbers of revoked certificates.
+	Serials []string `json:"serials,omitempty"`
+	// Identities are revoked SANs, typically SPIFFE IDs. All the unexpired certificates issued
+	// for them are revoked, and the CA refuses to issue new ones.
+	Identities []string `json:"identities,omitempty"`
+}
+
+// IsEmpty returns whether nothing is revoked.
+func (r Revocations) IsEmpty() bool {
+	return len(r.Serials) == 0 && len(r.Identities) == 0
+}
+
+// RevokerConfig configures a Revoker.
+type RevokerConfig struct {
+	// CRLValidity is how long a published CRL is valid. It is signed again halfway through. If
+	// it is not signed again in time, for example because no istiod is the leader, proxies reject
+	// all the peer certificates once it expires.
+	CRLValidity time.Duration
+	// ChainRevocationLists are PEM encoded CRLs issued by the CAs above the signing CA, when
+	// it is an intermediate CA. Envoy rejects the peer certificates when the CRL of any CA in
+	// their chain is missing or expired, so revocations require them with an intermediate CA.
+	ChainRevocationLists []byte
+	// ChainRevocationListsFile, if set, holds the ChainRevocationLists. It is read again every
+	// ChainCheckInterval, so that the CRLs renewed by the CAs above are served before the
+	// previous ones expire.
+	ChainRevocationListsFile string
+	ChainCheckInterval       time.Duration
+	// OnChange is called when the published revocation lists change.
+	OnChange func()
+	// Publish, if set, publishes the CRL signed by the leader, nil when nothing is revoked. The
+	// CRL is then only served once read back with SetRevocationList, so that all the istiod
+	// replicas serve the same CRL. If unset, the CRL signed by this istiod is served right away.
+	Publish func(crl []byte) error
+}
+
+// Revoker turns the revoked serials and identities into a CRL signed by the CA, so proxies
+// reject the revoked certificates. Identities are resolved to serials using the ledger.
+type Revoker struct {
+	config RevokerConfig
+	store  Store
+	bundle *util.KeyCertBundle
+	now    func() time.Time
+
+	mutex sync.RWMutex
+	// leader is true while this istiod signs the CRL published with Publish.
+	leader      bool
+	revocations Revocations
+	revokedIDs  map[string]struct{}
+	// revokedAt records when a serial was first revoked, which is published in the CRL.
+	revokedAt map[string]time.Time
+	// revoked are the serials in the published CRL.
+	revoked   []string
+	crl       []byte
+	crlNumber int64
+	// chainCRLs are the ChainRevocationLists, updated from ChainRevocationListsFile.
+	chainCRLs []byte
+}
+
+// NewRevoker returns a revoker for the certificates issued with the key of the given bundle
+// and recorded in the given store.
+func NewRevoker(store Store, bundle *util.KeyCertBundle, config RevokerConfig) (*Revoker, error) {
+	if config.CRLValidity <= 0 {
+		config.CRLValidity = DefaultCRLValidity
+	}
+	if config.ChainCheckInterval <= 0 {
+		config.ChainCheckInterval = DefaultChainCheckInterval
+	}
+	if err := verifyRevocationLists(config.ChainRevocationLists); err != nil {
+		return nil, fmt.Errorf("invalid chain CRLs: %v", err)
+	}
+	r := &Revoker{
+		config:     config,
+		store:      store,
+		bundle:     bundle,
+		now:        time.Now,
+		revokedIDs: map[string]struct{}{},
+		revokedAt:  map[string]time.Time{},
+		chainCRLs:  config.ChainRevocationLists,
+	}
+	r.checkChainExpiry()
+	return r, nil
+}
+
+// Update replaces the revoked serials and identities, and publishes a new CRL. Identities are
+// revoked even if the CRL can't be signed, so the CA stops issuing certificates for them.
+func (r *Revoker) Update(revocations Revocations) error {
+	revs := Revocations{}
+	for _, s := range revocations.Serials {
+		serial := NormalizeSerial(s)
+		if _, ok := new(big.Int).SetString(serial, 16); !ok {
+			return fmt.Errorf("invalid serial number %q", s)
+		}
+		revs.Serials = append(revs.Serials, serial)
+	}
+	revs.Identities = append(revs.Identities, revocations.Identities...)
+	sort.Strings(revs.Serials)
+	sort.Strings(revs.Identities)
+
+	r.mutex.Lock()
+	r.revocations = revs
+	r.revokedIDs = make(map[string]struct{}, len(revs.Identities))
+	for _, id := range revs.Identities {
+		r.revokedIDs[id] = struct{}{}
+	}
+	r.mutex.Unlock()
+	revokedIdentities.Record(float64(len(revs.Identities)))
+	ledgerLog.Infof("revoked %d serials and %d identities", len(revs.Serials), len(revs.Identities))
+	return r.refresh()
+}
+
+// Run signs the CRL again before it expires, drops the expired certificates from it and
+// prunes them from the ledger, until the stop channel is closed.
+func (r *Revoker) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(r.config.CRLValidity / 2)
+	defer ticker.Stop()
+	chainTicker := time.NewTicker(r.config.ChainCheckInterval)
+	defer chainTicker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-chainTicker.C:
+			if err := r.reloadChainRevocationLists(); err != nil {
+				revocationErrorCounts.Increment()
+				ledgerLog.Errorf("failed to reload the chain CRLs: %v", err)
+			}
+			r.checkChainExpiry()
+		case <-ticker.C:
+			if err := r.refresh(); err != nil {
+				ledgerLog.Errorf("failed to refresh the CRL: %v", err)
+			}
+			if n, err := r.store.Prune(r.now()); err != nil {
+				ledgerLog.Errorf("failed to prune the certificate ledger: %v", err)
+			} else if n > 0 {
+				ledgerLog.Debugf("pruned %d expired certificates from the ledger", n)
+			}
+		}
+	}
+}
+
+// Lead signs and publishes the CRL until the stop channel is closed. It must only run on the
+// leader istiod, when RevokerConfig.Publish is set.
+func (r *Revoker) Lead(stopCh <-chan struct{}) {
+	r.mutex.Lock()
+	r.leader = true
+	r.mutex.Unlock()
+	if err := r.refresh(); err != nil {
+		ledgerLog.Errorf("failed to publish the CRL: %v", err)
+	}
+	<-stopCh
+	r.mutex.Lock()
+	r.leader = false
+	r.mutex.Unlock()
+}
+
+// SetRevocationList serves the PEM encoded CRL published by the leader, nil when nothing is
+// revoked. The revocation times and the CRL number are taken over, so that they are kept when
+// another istiod becomes the leader.
+func (r *Revoker) SetRevocationList(crl []byte) error {
+	if len(bytes.TrimSpace(crl)) == 0 {
+		crl = nil
+	}
+	var list *pkix.CertificateList
+	if crl != nil {
+		block, _ := pem.Decode(crl)
+		if block == nil || block.Type != crlPEMType {
+			return fmt.Errorf("failed to decode the published CRL")
+		}
+		var err error
+		if list, err = x509.ParseCRL(block.Bytes); err != nil {
+			return fmt.Errorf("failed to parse the published CRL: %v", err)
+		}
+	}
+
+	r.mutex.Lock()
+	changed := !bytes.Equal(crl, r.crl)
+	r.crl = crl
+	if list != nil {
+		for _, c := range list.TBSCertList.RevokedCertificates {
+			s := fmt.Sprintf("%x", c.SerialNumber)
+			if _, f := r.revokedAt[s]; !f {
+				r.revokedAt[s] = c.RevocationTime
+			}
+		}
+		if n := crlNumber(list); n > r.crlNumber {
+			r.crlNumber = n
+		}
+	}
+	r.mutex.Unlock()
+
+	if changed && r.config.OnChange != nil {
+		r.config.OnChange()
+	}
+	return nil
+}
+
+// reloadChainRevocationLists reads the chain CRLs from ChainRevocationListsFile again, if set.
+func (r *Revoker) reloadChainRevocationLists() error {
+	if r.config.ChainRevocationListsFile == "" {
+		return nil
+	}
+	crls, err := ioutil.ReadFile(r.config.ChainRevocationListsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return r.SetChainRevocationLists(crls)
+}
+
+// SetChainRevocationLists replaces the PEM encoded CRLs of the CAs above the signing CA.
+func (r *Revoker) SetChainRevocationLists(crls []byte) error {
+	if err := verifyRevocationLists(crls); err != nil {
+		return fmt.Errorf("invalid chain CRLs: %v", err)
+	}
+	r.mutex.Lock()
+	changed := !bytes.Equal(crls, r.chainCRLs)
+	r.chainCRLs = crls
+	served := r.crl != nil
+	r.mutex.Unlock()
+	if !changed {
+		return nil
+	}
+	ledgerLog.Infof("loaded new chain CRLs")
+	r.checkChainExpiry()
+	if served && r.config.OnChange != nil {
+		r.config.OnChange()
+	}
+	return nil
+}
+
+// checkChainExpiry records when the first chain CRL expires, and warns when it is about to.
+func (r *Revoker) checkChainExpiry() {
+	r.mutex.RLock()
+	crls := r.chainCRLs
+	r.mutex.RUnlock()
+	var first *pkix.CertificateList
+	for rest := crls; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		list, err := x509.ParseCRL(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if first == nil || list.TBSCertList.NextUpdate.Before(first.TBSCertList.NextUpdate) {
+			first = list
+		}
+	}
+	if first == nil {
+		return
+	}
+	next := first.TBSCertList.NextUpdate
+	chainCRLExpiryTimestamp.Record(float64(next.Unix()))
+	now := r.now()
+	// Warn during the last quarter of the validity of the CRL.
+	validity := next.Sub(first.TBSCertList.ThisUpdate)
+	switch {
+	case !now.Before(next):
+		ledgerLog.Errorf("the chain CRL of %v expired at %v: proxies reject all the peer certificates "+
+			"until it is renewed", first.TBSCertList.Issuer, next)
+	case next.Sub(now) < validity/4:
+		ledgerLog.Warnf("the chain CRL of %v expires at %v: proxies will reject all the peer certificates "+
+			"unless it is renewed before", first.TBSCertList.Issuer, next)
+	}
+}
+
+// IsRevokedIdentity returns the first of the given identities which is revoked, if any.
+func (r *Revoker) IsRevokedIdentity(identities []string) (string, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, id := range identities {
+		if _, f := r.revokedIDs[id]; f {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// IsRevoked returns whether the certificate of the entry is revoked.
+func (r *Revoker) IsRevoked(e *Entry) bool {
+	if _, revoked := r.IsRevokedIdentity(e.SANs); revoked {
+		return true
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	i := sort.SearchStrings(r.revoked, e.Serial)
+	return i < len(r.revoked) && r.revoked[i] == e.Serial
+}
+
+// Revocations returns the revoked serials and identities.
+func (r *Revoker) Revocations() Revocations {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.revocations
+}
+
+// RevocationLists returns the PEM encoded CRLs to publish to proxies: the CRL of the signing
+// CA, followed by the chain CRLs. It is nil when nothing is revoked, so proxies don't check
+// revocation at all.
+func (r *Revoker) RevocationLists() []byte {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.crl == nil {
+		return nil
+	}
+	return util.AppendCertByte(r.crl, r.chainCRLs)
+}
+
+// refresh resolves the revoked serials and publishes a newly signed CRL.
+func (r *Revoker) refresh() error {
+	now := r.now()
+	revs := r.Revocations()
+
+	serials := map[string]struct{}{}
+	for _, s := range revs.Serials {
+		entries, err := r.store.List(Filter{Serial: s})
+		if err != nil {
+			return err
+		}
+		// Serials missing from the ledger are revoked anyway, they may have been issued
+		// before the ledger was enabled or by another istiod.
+		if len(entries) == 0 || entries[0].NotAfter.After(now) {
+			serials[s] = struct{}{}
+		}
+	}
+	for _, id := range revs.Identities {
+		entries, err := r.store.List(Filter{SAN: id, ValidAt: now})
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			serials[e.Serial] = struct{}{}
+		}
+	}
+	revoked := make([]string, 0, len(serials))
+	for s := range serials {
+		revoked = append(revoked, s)
+	}
+	sort.Strings(revoked)
+
+	r.mutex.Lock()
+	r.revoked = revoked
+	for s := range r.revokedAt {
+		if _, f := serials[s]; !f {
+			delete(r.revokedAt, s)
+		}
+	}
+	sign := r.config.Publish == nil || r.leader
+	r.mutex.Unlock()
+	revokedCerts.Record(float64(len(revoked)))
+	if !sign {
+		// The CRL is signed by the leader, and served once it is published.
+		return nil
+	}
+
+	var crl []byte
+	if len(revoked) > 0 {
+		var err error
+		if crl, err = r.signCRL(revoked, now); err != nil {
+			revocationErrorCounts.Increment()
+			return err
+		}
+	}
+	if r.config.Publish != nil {
+		if err := r.config.Publish(crl); err != nil {
+			revocationErrorCounts.Increment()
+			return fmt.Errorf("failed to publish the CRL: %v", err)
+		}
+		return nil
+	}
+
+	r.mutex.Lock()
+	changed := !bytes.Equal(crl, r.crl)
+	r.crl = crl
+	r.mutex.Unlock()
+
+	if changed && r.config.OnChange != nil {
+		r.config.OnChange()
+	}
+	return nil
+}
+
+func (r *Revoker) signCRL(serials []string, now time.Time) ([]byte, error) {
+	certPEM, keyPEM, _, _ := r.bundle.GetAllPem()
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, fmt.Errorf("the CA certificate does not have the cRLSign key usage, " +
+			"revoking certificates requires a CA certificate allowed to sign CRLs")
+	}
+	r.mutex.RLock()
+	chainCRLs := r.chainCRLs
+	r.mutex.RUnlock()
+	if cert.CheckSignatureFrom(cert) != nil && len(chainCRLs) == 0 {
+		return nil, fmt.Errorf("the CA certificate is an intermediate CA, " +
+			"revoking certificates requires the CRLs of the CAs above it")
+	}
+	key, err := util.ParsePemEncodedKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("the CA key of type %T can't sign CRLs", key)
+	}
+
+	r.mutex.Lock()
+	revokedCerts := make([]pkix.RevokedCertificate, 0, len(serials))
+	for _, s := range serials {
+		at, f := r.revokedAt[s]
+		if !f {
+			at = now
+			r.revokedAt[s] = at
+		}
+		n, _ := new(big.Int).SetString(s, 16)
+		revokedCerts = append(revokedCerts, pkix.RevokedCertificate{SerialNumber: n, RevocationTime: at})
+	}
+	// The CRL number must increase, including across restarts.
+	r.crlNumber++
+	if n := now.Unix(); n > r.crlNumber {
+		r.crlNumber = n
+	}
+	number := big.NewInt(r.crlNumber)
+	r.mutex.Unlock()
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: revokedCerts,
+		Number:              number,
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(r.config.CRLValidity),
+	}, cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the CRL: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: crlPEMType, Bytes: der}), nil
+}
+
+// crlNumber returns the CRL number extension of the CRL, or 0 if it has none.
+func crlNumber(list *pkix.CertificateList) int64 {
+	for _, ext := range list.TBSCertList.Extensions {
+		if !ext.Id.Equal(oidExtensionCRLNumber) {
+			continue
+		}
+		n := new(big.Int)
+		if _, err := asn1.Unmarshal(ext.Value, &n); err == nil && n.IsInt64() {
+			return n.Int64()
+		}
+	}
+	return 0
+}
+
+func verifyRevocationLists(crls []byte) error {
+	for rest := crls; len(bytes.TrimSpace(rest)) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return fmt.Errorf("failed to decode the PEM data")
+		}
+		if block.Type != crlPEMType {
+			return fmt.Errorf("unexpected PEM block of type %q", block.Type)
+		}
+		if _, err := x509.ParseCRL(block.Bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func newTestBundle(t *testing.T, signer *util.KeyCertBundle) *util.KeyCertBundle {
+	t.Helper()
+	opts := util.CertOptions{TTL: time.Hour, Org: "test", IsCA: true, ECSigAlg: util.EcdsaSigAlg}
+	if signer == nil {
+		opts.IsSelfSigned = true
+	} else {
+		cert, key, _, _ := signer.GetAll()
+		opts.SignerCert, opts.SignerPriv = cert, *key
+	}
+	certPEM, keyPEM, err := util.GenCertKeyFromOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := certPEM
+	if signer != nil {
+		root = signer.GetRootCertPem()
+	}
+	bundle, err := util.NewVerifiedKeyCertBundleFromPem(certPEM, keyPEM, nil, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+// issue records a certificate for the identity, which expires after the given TTL.
+func issue(t *testing.T, store Store, ca *util.KeyCertBundle, id string, ttl time.Duration) *Entry {
+	t.Helper()
+	caCert, caKey, _, _ := ca.GetAll()
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host: id, TTL: ttl, SignerCert: caCert, SignerPriv: *caKey, ECSigAlg: util.EcdsaSigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEntry(certPEM, "spiffe://cluster.local/ns/istio-system/sa/istiod", "ClientCertificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func parseCRL(t *testing.T, r *Revoker, ca *util.KeyCertBundle) []string {
+	t.Helper()
+	block, _ := pem.Decode(r.RevocationLists())
+	if block == nil {
+		return nil
+	}
+	crl, err := x509.ParseCRL(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _, _, _ := ca.GetAll()
+	if err := caCert.CheckCRLSignature(crl); err != nil {
+		t.Errorf("the CRL is not signed by the CA: %v", err)
+	}
+	out := []string{}
+	for _, c := range crl.TBSCertList.RevokedCertificates {
+		out = append(out, fmt.Sprintf("%x", c.SerialNumber))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sorted(s ...string) []string {
+	sort.Strings(s)
+	return s
+}
+
+func TestRevoker(t *testing.T) {
+	ca := newTestBundle(t, nil)
+	store := NewMemoryStore()
+	a1 := issue(t, store, ca, "spiffe://cluster.local/ns/a/sa/a", time.Hour)
+	a2 := issue(t, store, ca, "spiffe://cluster.local/ns/a/sa/a", time.Hour)
+	b := issue(t, store, ca, "spiffe://cluster.local/ns/b/sa/b", time.Hour)
+	expired := issue(t, store, ca, "spiffe://cluster.local/ns/b/sa/b", time.Hour)
+	expired.NotAfter = time.Now().Add(-time.Minute)
+
+	changes := 0
+	r, err := NewRevoker(store, ca, RevokerConfig{OnChange: func() { changes++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Identities are resolved to the serials of their certificates.
+	if err := r.Update(Revocations{Identities: []string{"spiffe://cluster.local/ns/a/sa/a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parseCRL(t, r, ca), sorted(a1.Serial, a2.Serial); !reflect.DeepEqual(got, want) {
+		t.Errorf("got revoked serials %v, want %v", got, want)
+	}
+	if id, revoked := r.IsRevokedIdentity([]string{"spiffe://cluster.local/ns/a/sa/a"}); !revoked || id != "spiffe://cluster.local/ns/a/sa/a" {
+		t.Errorf("the identity is not revoked")
+	}
+	if !r.IsRevoked(a1) || r.IsRevoked(b) {
+		t.Errorf("got revoked %v and %v, want true and false", r.IsRevoked(a1), r.IsRevoked(b))
+	}
+
+	// Expired serials are dropped, unknown ones are kept. The identity is no longer revoked.
+	if err := r.Update(Revocations{Serials: []string{strings.ToUpper(b.Serial), expired.Serial, "0x0F:FF"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parseCRL(t, r, ca), sorted(b.Serial, "fff"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got revoked serials %v, want %v", got, want)
+	}
+	if _, revoked := r.IsRevokedIdentity([]string{"spiffe://cluster.local/ns/a/sa/a"}); revoked {
+		t.Errorf("the identity is still revoked")
+	}
+
+	// Nothing revoked, nothing published.
+	if err := r.Update(Revocations{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.RevocationLists() != nil {
+		t.Errorf("got a CRL without revocations")
+	}
+	if changes != 3 {
+		t.Errorf("got %d changes, want 3", changes)
+	}
+
+	if err := r.Update(Revocations{Serials: []string{"not-hex"}}); err == nil {
+		t.Errorf("revoked an invalid serial")
+	}
+}
+
+func TestRevokerPublish(t *testing.T) {
+	ca := newTestBundle(t, nil)
+	store := NewMemoryStore()
+	e := issue(t, store, ca, "spiffe://cluster.local/ns/a/sa/a", time.Hour)
+
+	var published []byte
+	publishes := 0
+	config := RevokerConfig{Publish: func(crl []byte) error {
+		published = crl
+		publishes++
+		return nil
+	}}
+	leader, err := NewRevoker(store, ca, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower, err := NewRevoker(store, ca, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader.leader = true
+
+	// Only the leader signs and publishes the CRL, which is served once read back.
+	revocations := Revocations{Serials: []string{e.Serial}}
+	if err := follower.Update(revocations); err != nil {
+		t.Fatal(err)
+	}
+	if publishes != 0 || follower.RevocationLists() != nil {
+		t.Fatalf("the follower published or served a CRL")
+	}
+	if err := leader.Update(revocations); err != nil {
+		t.Fatal(err)
+	}
+	if publishes != 1 || leader.RevocationLists() != nil {
+		t.Fatalf("got %d publishes, the CRL is served before it is read back", publishes)
+	}
+	for _, r := range []*Revoker{leader, follower} {
+		if err := r.SetRevocationList(published); err != nil {
+			t.Fatal(err)
+		}
+		if got := parseCRL(t, r, ca); !reflect.DeepEqual(got, []string{e.Serial}) {
+			t.Errorf("got revoked serials %v, want %v", got, []string{e.Serial})
+		}
+	}
+
+	// The new leader keeps the revocation time, and increases the CRL number.
+	block, _ := pem.Decode(published)
+	first, err := x509.ParseCRL(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader.leader, follower.leader = false, true
+	// The clock of the new leader is behind.
+	follower.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	if err := follower.Update(revocations); err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode(published)
+	second, err := x509.ParseCRL(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crlNumber(second) <= crlNumber(first) {
+		t.Errorf("got CRL number %d after %d", crlNumber(second), crlNumber(first))
+	}
+	if got, want := second.TBSCertList.RevokedCertificates[0].RevocationTime, first.TBSCertList.RevokedCertificates[0].RevocationTime; !got.Equal(want) {
+		t.Errorf("got revocation time %v, want %v", got, want)
+	}
+}
+
+func TestRevokerIntermediateCA(t *testing.T) {
+	root := newTestBundle(t, nil)
+	ca := newTestBundle(t, root)
+	store := NewMemoryStore()
+	e := issue(t, store, ca, "spiffe://cluster.local/ns/a/sa/a", time.Hour)
+
+	r, err := NewRevoker(store, ca, RevokerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(Revocations{Serials: []string{e.Serial}}); err == nil {
+		t.Fatalf("revoked without the CRL of the root")
+	}
+	if _, err := NewRevoker(store, ca, RevokerConfig{ChainRevocationLists: []byte("not a CRL")}); err == nil {
+		t.Fatalf("created a revoker with invalid chain CRLs")
+	}
+
+	// The root revokes nothing, but its CRL must be published for Envoy to check the chain.
+	rootRevoker, err := NewRevoker(NewMemoryStore(), root, RevokerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, _, _, _ := root.GetAll()
+	rootCRL, err := rootRevoker.signCRL([]string{"1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err = NewRevoker(store, ca, RevokerConfig{ChainRevocationLists: rootCRL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(Revocations{Serials: []string{e.Serial}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := parseCRL(t, r, ca); !reflect.DeepEqual(got, []string{e.Serial}) {
+		t.Errorf("got revoked serials %v, want %v", got, []string{e.Serial})
+	}
+	certs, crls := util.SplitRevocationLists(util.AppendCertByte(root.GetRootCertPem(), r.RevocationLists()))
+	if n := strings.Count(string(crls), "BEGIN X509 CRL"); n != 2 {
+		t.Errorf("got %d CRLs, want 2", n)
+	}
+	if c, err := util.ParsePemEncodedCertificate(certs); err != nil || !c.Equal(rootCert) {
+		t.Errorf("the root cert is not split from the CRLs: %v", err)
+	}
+}
+
+func TestRevokerChainReload(t *testing.T) {
+	root := newTestBundle(t, nil)
+	ca := newTestBundle(t, root)
+	store := NewMemoryStore()
+	e := issue(t, store, ca, "spiffe://cluster.local/ns/a/sa/a", time.Hour)
+	rootRevoker, err := NewRevoker(NewMemoryStore(), root, RevokerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCRL, err := rootRevoker.signCRL([]string{"1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := path.Join(t.TempDir(), "crl-chain.pem")
+	if err := ioutil.WriteFile(file, rootCRL, 0600); err != nil {
+		t.Fatal(err)
+	}
+	changes := 0
+	r, err := NewRevoker(store, ca, RevokerConfig{
+		ChainRevocationLists:     rootCRL,
+		ChainRevocationListsFile: file,
+		OnChange:                 func() { changes++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(Revocations{Serials: []string{e.Serial}}); err != nil {
+		t.Fatal(err)
+	}
+	changes = 0
+
+	// The CRL renewed by the root is served without restarting istiod.
+	renewed, err := rootRevoker.signCRL([]string{"1"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, renewed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reloadChainRevocationLists(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(r.RevocationLists()), string(renewed)) || changes != 1 {
+		t.Errorf("the renewed chain CRL is not served, %d changes", changes)
+	}
+
+	// Invalid chain CRLs are ignored.
+	if err := ioutil.WriteFile(file, []byte("not a CRL"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reloadChainRevocationLists(); err == nil {
+		t.Errorf("reloaded invalid chain CRLs")
+	}
+	if !strings.HasSuffix(string(r.RevocationLists()), string(renewed)) || changes != 1 {
+		t.Errorf("the chain CRL changed with invalid chain CRLs")
+	}
+}
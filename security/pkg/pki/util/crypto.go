@@ -28,6 +28,7 @@ const (
 	blockTypeECPrivateKey    = "EC PRIVATE KEY"
 	blockTypeRSAPrivateKey   = "RSA PRIVATE KEY" // PKCS#1 private key
 	blockTypePKCS8PrivateKey = "PRIVATE KEY"     // PKCS#8 plain private key
+	blockTypeCRL             = "X509 CRL"
 )
 
 // ParsePemEncodedCertificate constructs a `x509.Certificate` object using the
@@ -46,6 +47,23 @@ func ParsePemEncodedCertificate(certBytes []byte) (*x509.Certificate, error) {
 	return cert, nil
 }
 
+// SplitRevocationLists separates the certificate revocation lists from the certificates of a
+// PEM bundle, e.g. a trust bundle published with the CRLs of the CA.
+func SplitRevocationLists(pemBundle []byte) (certs []byte, crls []byte) {
+	for {
+		var block *pem.Block
+		block, pemBundle = pem.Decode(pemBundle)
+		if block == nil {
+			return certs, crls
+		}
+		if block.Type == blockTypeCRL {
+			crls = append(crls, pem.EncodeToMemory(block)...)
+		} else {
+			certs = append(certs, pem.EncodeToMemory(block)...)
+		}
+	}
+}
+
 // ParsePemEncodedCSR constructs a `x509.CertificateRequest` object using the
 // given PEM-encoded certificate signing request.
 func ParsePemEncodedCSR(csrBytes []byte) (*x509.CertificateRequest, error) {
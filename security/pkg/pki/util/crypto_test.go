@@ -15,6 +15,7 @@
 package util
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
@@ -22,6 +23,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/pem"
 	"reflect"
 	"testing"
 )
@@ -342,3 +344,21 @@ func TestIsSupportedECPrivateKey(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitRevocationLists(t *testing.T) {
+	cert1 := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert1")})
+	cert2 := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert2")})
+	crl := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: []byte("crl")})
+
+	certs, crls := SplitRevocationLists(bytes.Join([][]byte{cert1, crl, cert2}, nil))
+	if want := append(append([]byte{}, cert1...), cert2...); !bytes.Equal(certs, want) {
+		t.Errorf("got certs %q, want %q", certs, want)
+	}
+	if !bytes.Equal(crls, crl) {
+		t.Errorf("got CRLs %q, want %q", crls, crl)
+	}
+
+	if certs, crls := SplitRevocationLists(cert1); !bytes.Equal(certs, cert1) || crls != nil {
+		t.Errorf("got certs %q and CRLs %q, want the cert only", certs, crls)
+	}
+}
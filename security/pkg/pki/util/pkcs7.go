@@ -0,0 +1,326 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+)
+
+// This file implements the subset of PKCS#7 (RFC 2315) SignedData needed to verify platform
+// attested documents: a single signer, DER encoding and content embedded in the structure.
+
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+
+	oidDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7Attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// VerifyPKCS7 verifies a DER encoded PKCS#7 SignedData structure with embedded content, and
+// returns the content. The signer certificate must be included in the structure and chain up
+// to roots; opts.Intermediates is extended with the other certificates of the structure.
+// Signatures over SHA-1 digests are rejected.
+func VerifyPKCS7(der []byte, opts x509.VerifyOptions) ([]byte, *x509.Certificate, error) {
+	var info pkcs7ContentInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse PKCS#7 content info: %v", err)
+	} else if len(rest) > 0 {
+		return nil, nil, fmt.Errorf("trailing data after PKCS#7 content info")
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, nil, fmt.Errorf("unsupported PKCS#7 content type %v", info.ContentType)
+	}
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse PKCS#7 signed data: %v", err)
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &content); err != nil {
+		return nil, nil, fmt.Errorf("PKCS#7 signed data has no embedded content: %v", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, nil, fmt.Errorf("expected exactly one PKCS#7 signer, got %d", len(sd.SignerInfos))
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse PKCS#7 certificates: %v", err)
+	}
+
+	si := sd.SignerInfos[0]
+	var signer *x509.Certificate
+	for _, c := range certs {
+		if c.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 &&
+			bytes.Equal(c.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) {
+			signer = c
+			break
+		}
+	}
+	if signer == nil {
+		return nil, nil, fmt.Errorf("PKCS#7 signer certificate is not included")
+	}
+
+	hash, err := pkcs7Hash(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	signed := content
+	if len(si.AuthenticatedAttributes.Bytes) > 0 {
+		digest, err := pkcs7MessageDigest(si.AuthenticatedAttributes.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		h := hash.New()
+		h.Write(content)
+		if !bytes.Equal(h.Sum(nil), digest) {
+			return nil, nil, fmt.Errorf("PKCS#7 message digest does not match the content")
+		}
+		// The signature covers the DER encoding of the attributes as a SET, not the
+		// IMPLICIT [0] tagged form they are carried in.
+		signed = append([]byte{0x31}, si.AuthenticatedAttributes.FullBytes[1:]...)
+	}
+	algo, err := pkcs7SignatureAlgorithm(hash, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := signer.CheckSignature(algo, signed, si.EncryptedDigest); err != nil {
+		return nil, nil, fmt.Errorf("invalid PKCS#7 signature: %v", err)
+	}
+
+	if opts.Intermediates == nil {
+		opts.Intermediates = x509.NewCertPool()
+	}
+	for _, c := range certs {
+		if c != signer {
+			opts.Intermediates.AddCert(c)
+		}
+	}
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	if _, err := signer.Verify(opts); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify PKCS#7 signer certificate: %v", err)
+	}
+	return content, signer, nil
+}
+
+// SignPKCS7 produces a DER encoded PKCS#7 SignedData structure embedding content, signed with
+// SHA-256 by key. The signer certificate and chain are included in the structure. It is the
+// counterpart of VerifyPKCS7, used by local stand-ins of platform metadata servers.
+func SignPKCS7(content []byte, signer *x509.Certificate, key crypto.Signer, chain ...*x509.Certificate) ([]byte, error) {
+	digest := sha256Sum(content)
+	attrs, err := marshalPKCS7Attributes([]pkcs7Attribute{
+		{Type: oidContentType, Value: mustMarshalSet(oidData)},
+		{Type: oidMessageDigest, Value: mustMarshalSet(digest)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	signed := append([]byte{0x31}, attrs.FullBytes[1:]...)
+	var sigAlgo asn1.ObjectIdentifier
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		sigAlgo = oidRSAEncryption
+	case *ecdsa.PrivateKey:
+		sigAlgo = oidECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#7 signing key type %T", key)
+	}
+	sig, err := key.Sign(rand.Reader, sha256Sum(signed), crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign PKCS#7 attributes: %v", err)
+	}
+
+	eContent, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var rawCerts []byte
+	for _, c := range append([]*x509.Certificate{signer}, chain...) {
+		rawCerts = append(rawCerts, c.Raw...)
+	}
+	sd := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA256}},
+		ContentInfo: pkcs7ContentInfo{
+			ContentType: oidData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: eContent},
+		},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: rawCerts},
+		SignerInfos: []pkcs7SignerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: pkcs7IssuerAndSerial{
+				Issuer:       asn1.RawValue{FullBytes: signer.RawIssuer},
+				SerialNumber: signer.SerialNumber,
+			},
+			DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256},
+			AuthenticatedAttributes:   attrs,
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: sigAlgo},
+			EncryptedDigest:           sig,
+		}},
+	}
+	sdBytes, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PKCS#7 signed data: %v", err)
+	}
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdBytes},
+	})
+}
+
+func pkcs7Hash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidDigestSHA1):
+		// SHA-1 is not collision resistant, a signature over a SHA-1 digest may cover a
+		// forged document.
+		return 0, fmt.Errorf("PKCS#7 digest algorithm SHA-1 is not accepted")
+	case oid.Equal(oidDigestSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidDigestSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidDigestSHA512):
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported PKCS#7 digest algorithm %v", oid)
+	}
+}
+
+func pkcs7SignatureAlgorithm(hash crypto.Hash, signer *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch signer.PublicKeyAlgorithm {
+	case x509.RSA:
+		switch hash {
+		case crypto.SHA256:
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			return x509.SHA512WithRSA, nil
+		}
+	case x509.ECDSA:
+		switch hash {
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported PKCS#7 signer key %v with digest %v",
+		signer.PublicKeyAlgorithm, hash)
+}
+
+// pkcs7MessageDigest extracts the messageDigest attribute from the contents of the
+// authenticated attributes.
+func pkcs7MessageDigest(attrs []byte) ([]byte, error) {
+	for len(attrs) > 0 {
+		var attr pkcs7Attribute
+		var err error
+		if attrs, err = asn1.Unmarshal(attrs, &attr); err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#7 authenticated attributes: %v", err)
+		}
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#7 message digest: %v", err)
+		}
+		return digest, nil
+	}
+	return nil, fmt.Errorf("PKCS#7 authenticated attributes have no message digest")
+}
+
+func marshalPKCS7Attributes(attrs []pkcs7Attribute) (asn1.RawValue, error) {
+	var b []byte
+	for _, a := range attrs {
+		ab, err := asn1.Marshal(a)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		b = append(b, ab...)
+	}
+	raw := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b}
+	full, err := asn1.Marshal(raw)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	raw.FullBytes = full
+	return raw, nil
+}
+
+func mustMarshalSet(v interface{}) asn1.RawValue {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: b}
+}
+
+func sha256Sum(b []byte) []byte {
+	h := crypto.SHA256.New()
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"strings"
+	"testing"
+	"time"
+)
+
+func genPKCS7TestSigner(t *testing.T, host string, ec bool) (*x509.Certificate, crypto.Signer, *x509.Certificate) {
+	t.Helper()
+	rootPem, rootKeyPem, err := GenCertKeyFromOptions(CertOptions{
+		Host:         "test-root",
+		TTL:          time.Hour,
+		Org:          "Test",
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := ParsePemEncodedCertificate(rootPem)
+	rootKey, _ := ParsePemEncodedKey(rootKeyPem)
+	opts := CertOptions{
+		Host:       host,
+		TTL:        time.Hour,
+		SignerCert: root,
+		SignerPriv: rootKey,
+		RSAKeySize: 2048,
+		IsServer:   true,
+	}
+	if ec {
+		opts.ECSigAlg = EcdsaSigAlg
+	}
+	leafPem, leafKeyPem, err := GenCertKeyFromOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := ParsePemEncodedCertificate(leafPem)
+	leafKey, _ := ParsePemEncodedKey(leafKeyPem)
+	return leaf, leafKey.(crypto.Signer), root
+}
+
+func TestPKCS7SignVerify(t *testing.T) {
+	content := []byte(`{"vmId":"d3f2a1"}`)
+	for _, ec := range []bool{false, true} {
+		leaf, key, root := genPKCS7TestSigner(t, "metadata.test", ec)
+		der, err := SignPKCS7(content, leaf, key)
+		if err != nil {
+			t.Fatalf("SignPKCS7() failed: %v", err)
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+
+		got, signer, err := VerifyPKCS7(der, x509.VerifyOptions{Roots: roots, DNSName: "metadata.test"})
+		if err != nil {
+			t.Fatalf("VerifyPKCS7() failed: %v", err)
+		}
+		if string(got) != string(content) {
+			t.Errorf("VerifyPKCS7() content = %q, want %q", got, content)
+		}
+		if signer.Subject.String() != leaf.Subject.String() {
+			t.Errorf("VerifyPKCS7() signer = %v, want %v", signer.Subject, leaf.Subject)
+		}
+
+		if _, _, err := VerifyPKCS7(der, x509.VerifyOptions{Roots: roots, DNSName: "other.test"}); err == nil {
+			t.Errorf("VerifyPKCS7() succeeded for the wrong signer name")
+		}
+		_, _, otherRoot := genPKCS7TestSigner(t, "metadata.test", ec)
+		otherRoots := x509.NewCertPool()
+		otherRoots.AddCert(otherRoot)
+		if _, _, err := VerifyPKCS7(der, x509.VerifyOptions{Roots: otherRoots}); err == nil {
+			t.Errorf("VerifyPKCS7() succeeded with untrusted roots")
+		}
+	}
+}
+
+func TestPKCS7VerifyTampered(t *testing.T) {
+	content := []byte(`{"vmId":"d3f2a1","subscriptionId":"sub-1"}`)
+	leaf, key, root := genPKCS7TestSigner(t, "metadata.test", false)
+	der, err := SignPKCS7(content, leaf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	tampered := []byte(strings.Replace(string(der), "sub-1", "sub-2", 1))
+	_, _, err = VerifyPKCS7(tampered, x509.VerifyOptions{Roots: roots})
+	if err == nil || !strings.Contains(err.Error(), "message digest does not match") {
+		t.Errorf("VerifyPKCS7() error = %v, want message digest mismatch", err)
+	}
+	if _, _, err := VerifyPKCS7([]byte("not pkcs7"), x509.VerifyOptions{Roots: roots}); err == nil {
+		t.Errorf("VerifyPKCS7() succeeded for garbage input")
+	}
+}
+
+func TestPKCS7VerifyRejectsSHA1(t *testing.T) {
+	content := []byte(`{"vmId":"d3f2a1"}`)
+	leaf, key, root := genPKCS7TestSigner(t, "metadata.test", false)
+	der, err := SignPKCS7(content, leaf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		t.Fatal(err)
+	}
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+	sd.DigestAlgorithms = []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA1}}
+	sd.SignerInfos[0].DigestAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA1}
+	sdBytes, err := asn1.Marshal(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha1DER, err := asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdBytes},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	_, _, err = VerifyPKCS7(sha1DER, x509.VerifyOptions{Roots: roots})
+	if err == nil || !strings.Contains(err.Error(), "SHA-1 is not accepted") {
+		t.Errorf("VerifyPKCS7() error = %v, want SHA-1 rejection", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc/peer"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	AWSAuthenticatorType = "AWSInstanceIdentityAuthenticator"
+
+	// minNonceLength is the shortest nonce accepted with a document, so that the nonce of an
+	// instance cannot be guessed.
+	minNonceLength = 32
+)
+
+// AWSAuthenticator authenticates EC2 instances by their signed instance identity document.
+// The document never expires, so each instance is bound in ledger to the nonce it first
+// presents the document with, and a document replayed with another nonce is rejected.
+type AWSAuthenticator struct {
+	keys              []*rsa.PublicKey
+	verifyPeerAddress bool
+	mapper            *identityMapper
+	ledger            InstanceLedger
+}
+
+var _ security.Authenticator = &AWSAuthenticator{}
+
+// NewAWSAuthenticator creates an authenticator for EC2 instance identity documents, mapping
+// instances to identities in trustDomain with the AWS rules. A nil ledger binds instances to
+// their nonce in memory only.
+func NewAWSAuthenticator(cfg *AWSConfig, rules []Rule, trustDomain string,
+	resolver WorkloadGroupResolver, ledger InstanceLedger) (*AWSAuthenticator, error) {
+	var keys []*rsa.PublicKey
+	rest := []byte(cfg.Certificates)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		cert, err := util.ParsePemEncodedCertificate(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, err
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("AWS certificate %s does not have an RSA key", cert.Subject)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no AWS certificates configured")
+	}
+	if ledger == nil {
+		ledger = NewMemoryLedger()
+	}
+	return &AWSAuthenticator{
+		keys:              keys,
+		verifyPeerAddress: cfg.VerifyPeerAddress,
+		mapper:            &identityMapper{trustDomain: trustDomain, rules: rules, resolver: resolver},
+		ledger:            ledger,
+	}, nil
+}
+
+func (a *AWSAuthenticator) AuthenticatorType() string {
+	return AWSAuthenticatorType
+}
+
+// Authenticate verifies the instance identity document presented as bearer token, and
+// returns the identity of the WorkloadGroup the instance maps to.
+func (a *AWSAuthenticator) Authenticate(ctx context.Context) (*security.Caller, error) {
+	cred, err := extractCredential(ctx, security.AWS)
+	if err != nil {
+		return nil, err
+	}
+	if !a.verify(cred.Document, cred.Signature) {
+		return nil, fmt.Errorf("invalid instance identity document signature")
+	}
+	if len(cred.Nonce) < minNonceLength {
+		return nil, fmt.Errorf("instance identity document presented without a nonce of at least %d characters",
+			minNonceLength)
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(cred.Document, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instance identity document: %v", err)
+	}
+	if a.verifyPeerAddress {
+		if err := checkPeerAddress(ctx, document["privateIp"]); err != nil {
+			return nil, err
+		}
+	}
+	caller, err := a.mapper.caller(security.AWS, document)
+	if err != nil {
+		return nil, err
+	}
+	// Only instances granted an identity are bound, so that documents of other accounts
+	// cannot fill the ledger.
+	instanceID, _ := document["instanceId"].(string)
+	if instanceID == "" {
+		return nil, fmt.Errorf("instance identity document has no instanceId")
+	}
+	if err := a.ledger.Bind(instanceID, cred.Nonce); err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+// verify checks the signature against the certificates of every configured region.
+func (a *AWSAuthenticator) verify(document, signature []byte) bool {
+	digest := sha256.Sum256(document)
+	for _, key := range a.keys {
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPeerAddress(ctx context.Context, privateIP interface{}) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return fmt.Errorf("no peer address to verify against the instance address")
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if ip, _ := privateIP.(string); ip == "" || host != ip {
+		return fmt.Errorf("peer address %s is not the instance address %v", host, privateIP)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformauth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	AzureAuthenticatorType = "AzureAttestedDataAuthenticator"
+
+	// azureTimeStampLayout is the layout of the timestamps in Azure attested documents.
+	azureTimeStampLayout = "01/02/06 15:04:05 -0700"
+)
+
+// AzureAuthenticator authenticates Azure VMs by their attested data document.
+type AzureAuthenticator struct {
+	roots      *x509.CertPool
+	signerName string
+	maxAge     time.Duration
+	mapper     *identityMapper
+
+	now func() time.Time
+}
+
+var _ security.Authenticator = &AzureAuthenticator{}
+
+type azureTimeStamp struct {
+	CreatedOn string `json:"createdOn"`
+	ExpiresOn string `json:"expiresOn"`
+}
+
+// NewAzureAuthenticator creates an authenticator for Azure attested data documents, mapping
+// VMs to identities in trustDomain with the Azure rules.
+func NewAzureAuthenticator(cfg *AzureConfig, rules []Rule, trustDomain string,
+	resolver WorkloadGroupResolver) (*AzureAuthenticator, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(cfg.RootCertificates)) {
+		return nil, fmt.Errorf("no Azure root certificates configured")
+	}
+	a := &AzureAuthenticator{
+		roots:      roots,
+		signerName: cfg.SignerName,
+		maxAge:     defaultAzureMaxAge,
+		mapper:     &identityMapper{trustDomain: trustDomain, rules: rules, resolver: resolver},
+		now:        time.Now,
+	}
+	if a.signerName == "" {
+		a.signerName = defaultAzureSignerName
+	}
+	if cfg.MaxAge != "" {
+		d, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid azure maxAge: %v", err)
+		}
+		a.maxAge = d
+	}
+	return a, nil
+}
+
+func (a *AzureAuthenticator) AuthenticatorType() string {
+	return AzureAuthenticatorType
+}
+
+// Authenticate verifies the attested data document presented as bearer token, and returns
+// the identity of the WorkloadGroup the VM maps to. The nonce of the document is the unix
+// time it was requested at, which bounds how long it can be replayed.
+func (a *AzureAuthenticator) Authenticate(ctx context.Context) (*security.Caller, error) {
+	cred, err := extractCredential(ctx, security.Azure)
+	if err != nil {
+		return nil, err
+	}
+	now := a.now()
+	content, _, err := util.VerifyPKCS7(cred.Signature, x509.VerifyOptions{
+		Roots:       a.roots,
+		DNSName:     a.signerName,
+		CurrentTime: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid attested document: %v", err)
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attested document: %v", err)
+	}
+	if err := a.checkFreshness(document, now); err != nil {
+		return nil, err
+	}
+	return a.mapper.caller(security.Azure, document)
+}
+
+func (a *AzureAuthenticator) checkFreshness(document map[string]interface{}, now time.Time) error {
+	nonce, _ := document["nonce"].(string)
+	requested, err := strconv.ParseInt(nonce, 10, 64)
+	if err != nil {
+		return fmt.Errorf("attested document nonce %q is not a timestamp", nonce)
+	}
+	if age := now.Sub(time.Unix(requested, 0)); age > a.maxAge || age < -a.maxAge {
+		return fmt.Errorf("attested document was requested %v ago, more than %v", age, a.maxAge)
+	}
+	ts := azureTimeStamp{}
+	if raw, err := json.Marshal(document["timeStamp"]); err == nil {
+		_ = json.Unmarshal(raw, &ts)
+	}
+	expiresOn, err := time.Parse(azureTimeStampLayout, ts.ExpiresOn)
+	if err != nil {
+		return fmt.Errorf("invalid attested document expiry %q", ts.ExpiresOn)
+	}
+	if now.After(expiresOn) {
+		return fmt.Errorf("attested document expired at %v", expiresOn)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package platformauth authenticates VMs presenting an instance identity document signed by
+// their cloud platform, and maps them to the identity of a WorkloadGroup.
+package platformauth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/security"
+)
+
+const (
+	defaultAzureSignerName = "metadata.azure.com"
+	defaultAzureMaxAge     = 5 * time.Minute
+)
+
+// Config configures the platform authenticators. It is loaded from the file set by
+// PLATFORM_ATTESTATION_CONFIG in istiod, for example:
+//
+//	aws:
+//	  certificates: |
+//	    -----BEGIN CERTIFICATE-----
+//	    ...
+//	rules:
+//	- platform: AmazonWebServices
+//	  match:
+//	    accountId: "123456789012"
+//	    region: us-east-1
+//	  workloadGroup:
+//	    namespace: vm
+//	    name: db
+type Config struct {
+	// AWS enables authentication of EC2 instance identity documents.
+	AWS *AWSConfig `json:"aws,omitempty"`
+
+	// Azure enables authentication of Azure attested data documents.
+	Azure *AzureConfig `json:"azure,omitempty"`
+
+	// Rules map instances to WorkloadGroups. The first matching rule is used, instances
+	// matching no rule are rejected.
+	Rules []Rule `json:"rules"`
+}
+
+// AWSConfig configures the verification of EC2 instance identity documents.
+type AWSConfig struct {
+	// Certificates is the PEM encoded AWS public certificates for the regions instances run
+	// in, which verify the RSA signature of the documents.
+	Certificates string `json:"certificates"`
+
+	// VerifyPeerAddress requires the privateIp of the document to be the address the
+	// request comes from. Instance identity documents do not expire, and each instance is
+	// bound to the nonce it first presents its document with; this additionally limits where
+	// a leaked document can be presented from.
+	VerifyPeerAddress bool `json:"verifyPeerAddress,omitempty"`
+}
+
+// AzureConfig configures the verification of Azure attested data documents.
+type AzureConfig struct {
+	// RootCertificates is the PEM encoded certificates the document signer must chain up to.
+	RootCertificates string `json:"rootCertificates"`
+
+	// SignerName is the DNS name of the signer certificate. Defaults to metadata.azure.com.
+	SignerName string `json:"signerName,omitempty"`
+
+	// MaxAge is how long after it was requested a document is accepted, as a duration
+	// string. Defaults to 5m.
+	MaxAge string `json:"maxAge,omitempty"`
+}
+
+// Rule maps the instances whose document matches to the identity of a WorkloadGroup.
+type Rule struct {
+	// Platform is the credential type the rule applies to, AmazonWebServices or MicrosoftAzure.
+	Platform string `json:"platform"`
+
+	// Match lists document fields and the value they must have. A value ending with "*"
+	// matches by prefix. Rules must match the accountId of AWS documents or the
+	// subscriptionId of Azure documents, so that instances of other tenants never match.
+	Match map[string]string `json:"match"`
+
+	// WorkloadGroup whose service account is granted to matching instances.
+	WorkloadGroup WorkloadGroupRef `json:"workloadGroup"`
+}
+
+// WorkloadGroupRef references a WorkloadGroup.
+type WorkloadGroupRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// tenantKeys are the document fields identifying the account an instance belongs to.
+var tenantKeys = map[string]string{
+	security.AWS:   "accountId",
+	security.Azure: "subscriptionId",
+}
+
+// LoadConfig reads and validates the configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read platform attestation config: %v", err)
+	}
+	return ParseConfig(b)
+}
+
+// ParseConfig parses and validates a YAML or JSON configuration.
+func ParseConfig(b []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse platform attestation config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that every rule targets a configured platform and is scoped to a tenant.
+func (c *Config) Validate() error {
+	if c.Azure != nil && c.Azure.MaxAge != "" {
+		if _, err := time.ParseDuration(c.Azure.MaxAge); err != nil {
+			return fmt.Errorf("invalid azure maxAge: %v", err)
+		}
+	}
+	for i, r := range c.Rules {
+		tenantKey, ok := tenantKeys[r.Platform]
+		if !ok {
+			return fmt.Errorf("rule %d: unsupported platform %q", i, r.Platform)
+		}
+		if (r.Platform == security.AWS && c.AWS == nil) || (r.Platform == security.Azure && c.Azure == nil) {
+			return fmt.Errorf("rule %d: platform %s is not configured", i, r.Platform)
+		}
+		if v := r.Match[tenantKey]; v == "" || strings.HasSuffix(v, "*") {
+			return fmt.Errorf("rule %d: must match an exact %s", i, tenantKey)
+		}
+		if r.WorkloadGroup.Namespace == "" || r.WorkloadGroup.Name == "" {
+			return fmt.Errorf("rule %d: workloadGroup namespace and name are required", i)
+		}
+	}
+	return nil
+}
+
+// NewAuthenticators creates the authenticators of the platforms configured in cfg, granting
+// identities in trustDomain. AWS instances are bound to their nonce in ledger.
+func NewAuthenticators(cfg *Config, trustDomain string, resolver WorkloadGroupResolver,
+	ledger InstanceLedger) ([]security.Authenticator, error) {
+	var authenticators []security.Authenticator
+	if cfg.AWS != nil {
+		a, err := NewAWSAuthenticator(cfg.AWS, cfg.Rules, trustDomain, resolver, ledger)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if cfg.Azure != nil {
+		a, err := NewAzureAuthenticator(cfg.Azure, cfg.Rules, trustDomain, resolver)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	return authenticators, nil
+}
@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// InstanceNoncesSecret is the Secret in the istiod namespace binding AWS instances to the nonce
+// they first authenticated with.
+const InstanceNoncesSecret = "istio-aws-instance-nonces"
+
+// InstanceLedger binds instances to the first nonce they authenticate with.
+type InstanceLedger interface {
+	// Bind records nonce for instanceID if the instance has none yet, and fails if the
+	// instance is bound to another nonce.
+	Bind(instanceID, nonce string) error
+}
+
+// NewMemoryLedger returns an InstanceLedger local to the process.
+func NewMemoryLedger() InstanceLedger {
+	return &memoryLedger{nonces: map[string]string{}}
+}
+
+type memoryLedger struct {
+	mu     sync.Mutex
+	nonces map[string]string
+}
+
+func (l *memoryLedger) Bind(instanceID, nonce string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	digest := nonceDigest(nonce)
+	if bound, ok := l.nonces[instanceID]; ok {
+		return checkNonce(instanceID, bound, digest)
+	}
+	l.nonces[instanceID] = digest
+	return nil
+}
+
+// NewSecretLedger returns an InstanceLedger stored in the InstanceNoncesSecret of namespace,
+// shared by the istiod replicas and kept across restarts.
+func NewSecretLedger(client kubernetes.Interface, namespace string) InstanceLedger {
+	return &secretLedger{client: client, namespace: namespace}
+}
+
+type secretLedger struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+func (l *secretLedger) Bind(instanceID, nonce string) error {
+	if !validSecretKey(instanceID) {
+		return fmt.Errorf("invalid instance ID %q", instanceID)
+	}
+	digest := nonceDigest(nonce)
+	secrets := l.client.CoreV1().Secrets(l.namespace)
+	// Replicas binding concurrently conflict on the resource version, and retry against the
+	// binding of the winner.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(context.TODO(), InstanceNoncesSecret, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = secrets.Create(context.TODO(), &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: InstanceNoncesSecret, Namespace: l.namespace},
+				Data:       map[string][]byte{instanceID: []byte(digest)},
+			}, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				return errors.NewConflict(v1.Resource("secrets"), InstanceNoncesSecret, err)
+			}
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read instance nonces: %v", err)
+		}
+		if bound, ok := secret.Data[instanceID]; ok {
+			return checkNonce(instanceID, string(bound), digest)
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[instanceID] = []byte(digest)
+		_, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// nonceDigest is what the ledgers store, so that reading the ledger does not reveal nonces.
+func nonceDigest(nonce string) string {
+	d := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(d[:])
+}
+
+func checkNonce(instanceID, bound, digest string) error {
+	if subtle.ConstantTimeCompare([]byte(bound), []byte(digest)) != 1 {
+		return fmt.Errorf("instance %s is bound to another nonce, the document may have been replayed", instanceID)
+	}
+	return nil
+}
+
+func validSecretKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
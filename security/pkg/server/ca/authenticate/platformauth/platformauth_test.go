@@ -0,0 +1,400 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformauth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/credentialfetcher/plugin"
+)
+
+const trustDomain = "cluster.local"
+
+func bearerContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{"Bearer " + token}})
+}
+
+func resolver(namespace, name string) (string, error) {
+	if name == "missing" {
+		return "", fmt.Errorf("not found")
+	}
+	if name == "no-sa" {
+		return "", nil
+	}
+	return name + "-sa", nil
+}
+
+func TestParseConfig(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		err    string
+	}{
+		"valid": {
+			config: `
+aws:
+  certificates: cert
+azure:
+  rootCertificates: root
+  maxAge: 10m
+rules:
+- platform: AmazonWebServices
+  match: {accountId: "123", region: "us-*"}
+  workloadGroup: {namespace: vm, name: db}
+- platform: MicrosoftAzure
+  match: {subscriptionId: "sub"}
+  workloadGroup: {namespace: vm, name: web}
+`,
+		},
+		"unknown field": {
+			config: `{"rules": [], "gcp": {}}`,
+			err:    "failed to parse",
+		},
+		"unsupported platform": {
+			config: `{"rules": [{"platform": "GoogleComputeEngine"}]}`,
+			err:    `unsupported platform "GoogleComputeEngine"`,
+		},
+		"platform not configured": {
+			config: `{"rules": [{"platform": "MicrosoftAzure", "match": {"subscriptionId": "sub"}}]}`,
+			err:    "platform MicrosoftAzure is not configured",
+		},
+		"no tenant": {
+			config: `{"aws": {}, "rules": [{"platform": "AmazonWebServices", "match": {"region": "us-east-1"}}]}`,
+			err:    "must match an exact accountId",
+		},
+		"tenant prefix": {
+			config: `{"aws": {}, "rules": [{"platform": "AmazonWebServices", "match": {"accountId": "12*"}}]}`,
+			err:    "must match an exact accountId",
+		},
+		"no workload group": {
+			config: `{"aws": {}, "rules": [{"platform": "AmazonWebServices", "match": {"accountId": "12"}}]}`,
+			err:    "workloadGroup namespace and name are required",
+		},
+		"invalid max age": {
+			config: `{"azure": {"maxAge": "soon"}}`,
+			err:    "invalid azure maxAge",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.config))
+			if tc.err == "" && err != nil {
+				t.Fatalf("ParseConfig() failed: %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("ParseConfig() error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestAWSAuthenticator(t *testing.T) {
+	document := map[string]interface{}{
+		"accountId":  "123456789012",
+		"instanceId": "i-0123456789abcdef0",
+		"region":     "us-east-1",
+		"privateIp":  "10.0.0.12",
+	}
+	ms, err := plugin.StartAWSMetadataServer(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Stop()
+	token, err := plugin.CreateAWSPlugin(ms.URL(), "", "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := security.ParseAttestedCredential(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Nonce = ""
+	noNonce, err := cred.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := plugin.StartAWSMetadataServer(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Stop()
+
+	rule := func(match map[string]string, name string) Rule {
+		return Rule{Platform: security.AWS, Match: match, WorkloadGroup: WorkloadGroupRef{Namespace: "vm", Name: name}}
+	}
+	cases := map[string]struct {
+		certs      []byte
+		rules      []Rule
+		verifyPeer bool
+		ctx        context.Context
+		identities []string
+		err        string
+	}{
+		"first matching rule": {
+			rules: []Rule{
+				rule(map[string]string{"accountId": "123456789012", "region": "eu-*"}, "eu"),
+				rule(map[string]string{"accountId": "123456789012", "region": "us-*"}, "us"),
+				rule(map[string]string{"accountId": "123456789012"}, "any"),
+			},
+			identities: []string{"spiffe://cluster.local/ns/vm/sa/us-sa"},
+		},
+		"default service account": {
+			rules:      []Rule{rule(map[string]string{"accountId": "123456789012"}, "no-sa")},
+			identities: []string{"spiffe://cluster.local/ns/vm/sa/default"},
+		},
+		"no matching rule": {
+			rules: []Rule{rule(map[string]string{"accountId": "210987654321"}, "db")},
+			err:   "no rule matches AmazonWebServices instance i-0123456789abcdef0",
+		},
+		"workload group not found": {
+			rules: []Rule{rule(map[string]string{"accountId": "123456789012"}, "missing")},
+			err:   "failed to resolve WorkloadGroup vm/missing",
+		},
+		"untrusted signer": {
+			certs: other.CertPem,
+			rules: []Rule{rule(map[string]string{"accountId": "123456789012"}, "db")},
+			err:   "invalid instance identity document signature",
+		},
+		"peer address matches": {
+			rules:      []Rule{rule(map[string]string{"accountId": "123456789012"}, "db")},
+			verifyPeer: true,
+			ctx: peer.NewContext(bearerContext(token),
+				&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.12"), Port: 4321}}),
+			identities: []string{"spiffe://cluster.local/ns/vm/sa/db-sa"},
+		},
+		"peer address mismatch": {
+			rules:      []Rule{rule(map[string]string{"accountId": "123456789012"}, "db")},
+			verifyPeer: true,
+			ctx: peer.NewContext(bearerContext(token),
+				&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.99"), Port: 4321}}),
+			err: "peer address 10.0.0.99 is not the instance address 10.0.0.12",
+		},
+		"no nonce": {
+			rules: []Rule{rule(map[string]string{"accountId": "123456789012"}, "db")},
+			ctx:   bearerContext(noNonce),
+			err:   "presented without a nonce",
+		},
+		"not an attested credential": {
+			rules: []Rule{rule(map[string]string{"accountId": "123456789012"}, "db")},
+			ctx:   bearerContext("header.payload.signature"),
+			err:   "token is not an attested credential",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			certs := tc.certs
+			if certs == nil {
+				certs = ms.CertPem
+			}
+			a, err := NewAWSAuthenticator(&AWSConfig{Certificates: string(certs), VerifyPeerAddress: tc.verifyPeer},
+				tc.rules, trustDomain, resolver, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = bearerContext(token)
+			}
+			caller, err := a.Authenticate(ctx)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Authenticate() error = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() failed: %v", err)
+			}
+			if !reflect.DeepEqual(caller.Identities, tc.identities) {
+				t.Errorf("Authenticate() identities = %v, want %v", caller.Identities, tc.identities)
+			}
+		})
+	}
+}
+
+func TestAWSAuthenticatorReplay(t *testing.T) {
+	ms, err := plugin.StartAWSMetadataServer(map[string]interface{}{
+		"accountId":  "123456789012",
+		"instanceId": "i-0123456789abcdef0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Stop()
+	// Plugins without a nonce file stand for the instance and an attacker who read its
+	// document, each presenting its own nonce.
+	instance, err := plugin.CreateAWSPlugin(ms.URL(), "", "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := plugin.CreateAWSPlugin(ms.URL(), "", "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []Rule{{
+		Platform:      security.AWS,
+		Match:         map[string]string{"accountId": "123456789012"},
+		WorkloadGroup: WorkloadGroupRef{Namespace: "vm", Name: "db"},
+	}}
+
+	client := fake.NewSimpleClientset()
+	ledgers := map[string]func() InstanceLedger{
+		"memory": NewMemoryLedger,
+		"secret": func() InstanceLedger { return NewSecretLedger(client, "istio-system") },
+	}
+	for name, ledger := range ledgers {
+		t.Run(name, func(t *testing.T) {
+			a, err := NewAWSAuthenticator(&AWSConfig{Certificates: string(ms.CertPem)}, rules, trustDomain, resolver, ledger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := a.Authenticate(bearerContext(instance)); err != nil {
+					t.Fatalf("Authenticate() failed: %v", err)
+				}
+			}
+			_, err = a.Authenticate(bearerContext(replayed))
+			if err == nil || !strings.Contains(err.Error(), "bound to another nonce") {
+				t.Fatalf("Authenticate() error = %v, want replay rejection", err)
+			}
+		})
+	}
+
+	// Another replica, or istiod after a restart, shares the bindings of the Secret.
+	a, err := NewAWSAuthenticator(&AWSConfig{Certificates: string(ms.CertPem)}, rules, trustDomain, resolver,
+		NewSecretLedger(client, "istio-system"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(bearerContext(replayed)); err == nil {
+		t.Errorf("Authenticate() accepted a replayed document on another replica")
+	}
+	if _, err := a.Authenticate(bearerContext(instance)); err != nil {
+		t.Errorf("Authenticate() failed on another replica: %v", err)
+	}
+}
+
+func TestAzureAuthenticator(t *testing.T) {
+	document := map[string]interface{}{
+		"vmId":           "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		"subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d",
+	}
+	ms, err := plugin.StartAzureMetadataServer(document, "metadata.azure.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Stop()
+	token, err := plugin.CreateAzurePlugin(ms.URL(), "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.SetValidity(-time.Minute)
+	expired, err := plugin.CreateAzurePlugin(ms.URL(), "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsServer, err := plugin.StartAWSMetadataServer(map[string]interface{}{"accountId": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer awsServer.Stop()
+	awsToken, err := plugin.CreateAWSPlugin(awsServer.URL(), "", "").GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []Rule{
+		{
+			Platform:      security.AWS,
+			Match:         map[string]string{"accountId": "8d10da13-8125-4ba9-a717-bf7490507b3d"},
+			WorkloadGroup: WorkloadGroupRef{Namespace: "vm", Name: "aws"},
+		},
+		{
+			Platform:      security.Azure,
+			Match:         map[string]string{"subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d"},
+			WorkloadGroup: WorkloadGroupRef{Namespace: "vm", Name: "web"},
+		},
+	}
+	cases := map[string]struct {
+		signerName string
+		token      string
+		now        time.Time
+		identities []string
+		err        string
+	}{
+		"valid": {
+			signerName: "metadata.azure.test",
+			identities: []string{"spiffe://cluster.local/ns/vm/sa/web-sa"},
+		},
+		"wrong signer name": {
+			signerName: "metadata.azure.com",
+			err:        "failed to verify PKCS#7 signer certificate",
+		},
+		"stale nonce": {
+			signerName: "metadata.azure.test",
+			now:        time.Now().Add(10 * time.Minute),
+			err:        "attested document was requested",
+		},
+		"expired document": {
+			signerName: "metadata.azure.test",
+			token:      expired,
+			err:        "attested document expired",
+		},
+		"aws credential": {
+			signerName: "metadata.azure.test",
+			token:      awsToken,
+			err:        "attested credential is of type AmazonWebServices, not MicrosoftAzure",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, err := NewAzureAuthenticator(&AzureConfig{RootCertificates: string(ms.RootCertPem), SignerName: tc.signerName},
+				rules, trustDomain, resolver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.now.IsZero() {
+				a.now = func() time.Time { return tc.now }
+			}
+			tok := tc.token
+			if tok == "" {
+				tok = token
+			}
+			caller, err := a.Authenticate(bearerContext(tok))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Authenticate() error = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() failed: %v", err)
+			}
+			if !reflect.DeepEqual(caller.Identities, tc.identities) {
+				t.Errorf("Authenticate() identities = %v, want %v", caller.Identities, tc.identities)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platformauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/security"
+)
+
+// WorkloadGroupResolver returns the service account of the template of a WorkloadGroup.
+// An empty service account stands for the default service account of the namespace.
+type WorkloadGroupResolver func(namespace, name string) (serviceAccount string, err error)
+
+// identityMapper maps verified instance identity documents to SPIFFE identities.
+type identityMapper struct {
+	trustDomain string
+	rules       []Rule
+	resolver    WorkloadGroupResolver
+}
+
+// caller returns the caller for the document of an instance of platform, using the first
+// matching rule.
+func (m *identityMapper) caller(platform string, document map[string]interface{}) (*security.Caller, error) {
+	for _, r := range m.rules {
+		if r.Platform != platform || !matches(r.Match, document) {
+			continue
+		}
+		sa, err := m.resolver(r.WorkloadGroup.Namespace, r.WorkloadGroup.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve WorkloadGroup %s/%s: %v",
+				r.WorkloadGroup.Namespace, r.WorkloadGroup.Name, err)
+		}
+		if sa == "" {
+			sa = "default"
+		}
+		return &security.Caller{
+			AuthSource: security.AuthSourceIDToken,
+			Identities: []string{fmt.Sprintf(security.IdentityTemplate, m.trustDomain, r.WorkloadGroup.Namespace, sa)},
+		}, nil
+	}
+	return nil, fmt.Errorf("no rule matches %s instance %v", platform, document[instanceKeys[platform]])
+}
+
+// instanceKeys are the document fields identifying an instance, used in error messages.
+var instanceKeys = map[string]string{
+	security.AWS:   "instanceId",
+	security.Azure: "vmId",
+}
+
+func matches(match map[string]string, document map[string]interface{}) bool {
+	for k, want := range match {
+		got, ok := document[k].(string)
+		if !ok {
+			return false
+		}
+		if strings.HasSuffix(want, "*") {
+			if !strings.HasPrefix(got, strings.TrimSuffix(want, "*")) {
+				return false
+			}
+		} else if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// extractCredential returns the attested credential of type credType carried by the bearer token of ctx.
+func extractCredential(ctx context.Context, credType string) (*security.AttestedCredential, error) {
+	token, err := security.ExtractBearerToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("attested credential extraction error: %v", err)
+	}
+	cred, err := security.ParseAttestedCredential(token)
+	if err != nil {
+		return nil, err
+	}
+	if cred.Type != credType {
+		return nil, fmt.Errorf("attested credential is of type %s, not %s", cred.Type, credType)
+	}
+	return cred, nil
+}
@@ -51,6 +51,16 @@ var (
 		monitoring.WithLabels(errorTag),
 	)
 
+	revokedIdentityErrorCounts = monitoring.NewSum(
+		"citadel_server_revoked_identity_err_count",
+		"The number of CSRs refused because the identity of the caller is revoked.",
+	)
+
+	ledgerErrorCounts = monitoring.NewSum(
+		"citadel_server_ledger_err_count",
+		"The number of issued certificates which could not be recorded in the ledger.",
+	)
+
 	successCounts = monitoring.NewSum(
 		"citadel_server_success_cert_issuance_count",
 		"The number of certificates issuances that have succeeded.",
@@ -75,6 +85,8 @@ func init() {
 		csrParsingErrorCounts,
 		idExtractionErrorCounts,
 		certSignErrorCounts,
+		revokedIdentityErrorCounts,
+		ledgerErrorCounts,
 		successCounts,
 		rootCertExpiryTimestamp,
 		certChainExpiryTimestamp,
@@ -83,23 +95,27 @@ func init() {
 
 // monitoringMetrics are counters for certificate signing related operations.
 type monitoringMetrics struct {
-	CSR               monitoring.Metric
-	AuthnError        monitoring.Metric
-	Success           monitoring.Metric
-	CSRError          monitoring.Metric
-	IDExtractionError monitoring.Metric
-	certSignErrors    monitoring.Metric
+	CSR                  monitoring.Metric
+	AuthnError           monitoring.Metric
+	Success              monitoring.Metric
+	CSRError             monitoring.Metric
+	IDExtractionError    monitoring.Metric
+	RevokedIdentityError monitoring.Metric
+	LedgerError          monitoring.Metric
+	certSignErrors       monitoring.Metric
 }
 
 // newMonitoringMetrics creates a new monitoringMetrics.
 func newMonitoringMetrics() monitoringMetrics {
 	return monitoringMetrics{
-		CSR:               csrCounts,
-		AuthnError:        authnErrorCounts,
-		Success:           successCounts,
-		CSRError:          csrParsingErrorCounts,
-		IDExtractionError: idExtractionErrorCounts,
-		certSignErrors:    certSignErrorCounts,
+		CSR:                  csrCounts,
+		AuthnError:           authnErrorCounts,
+		Success:              successCounts,
+		CSRError:             csrParsingErrorCounts,
+		IDExtractionError:    idExtractionErrorCounts,
+		RevokedIdentityError: revokedIdentityErrorCounts,
+		LedgerError:          ledgerErrorCounts,
+		certSignErrors:       certSignErrorCounts,
 	}
 }
 
@@ -16,6 +16,7 @@ package ca
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -27,6 +28,7 @@ import (
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pkg/security"
 	caerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/ledger"
 	"istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
 )
@@ -51,6 +53,10 @@ type Server struct {
 	Authenticators []security.Authenticator
 	ca             CertificateAuthority
 	serverCertTTL  time.Duration
+	// Ledger records the issued certificates, if set.
+	Ledger ledger.Store
+	// Revoker refuses to issue certificates for the revoked identities, if set.
+	Revoker *ledger.Revoker
 }
 
 func getConnectionAddress(ctx context.Context) string {
@@ -79,6 +85,14 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 
 	// TODO: Call authorizer.
 
+	if s.Revoker != nil {
+		if id, revoked := s.Revoker.IsRevokedIdentity(caller.Identities); revoked {
+			s.monitoring.RevokedIdentityError.Increment()
+			serverCaLog.Warnf("refusing to sign a CSR for the revoked identity %s", id)
+			return nil, status.Errorf(codes.PermissionDenied, "identity %s is revoked", id)
+		}
+	}
+
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
 	cert, signErr := s.ca.Sign(
 		[]byte(request.Csr), caller.Identities, time.Duration(request.ValidityDuration)*time.Second, false)
@@ -87,6 +101,7 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		s.monitoring.GetCertSignError(signErr.(*caerror.Error).ErrorType()).Increment()
 		return nil, status.Errorf(signErr.(*caerror.Error).HTTPErrorCode(), "CSR signing error (%v)", signErr.(*caerror.Error))
 	}
+	s.recordIssuance(cert, caller)
 	respCertChain := []string{string(cert)}
 	if len(certChainBytes) != 0 {
 		respCertChain = append(respCertChain, string(certChainBytes))
@@ -100,6 +115,22 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	return response, nil
 }
 
+// recordIssuance adds the issued certificate to the ledger. The request does not fail if the
+// certificate can't be recorded, since it is issued already.
+func (s *Server) recordIssuance(cert []byte, caller *security.Caller) {
+	if s.Ledger == nil {
+		return
+	}
+	e, err := ledger.NewEntry(cert, strings.Join(caller.Identities, ","), caller.AuthSource.String())
+	if err == nil {
+		err = s.Ledger.Add(e)
+	}
+	if err != nil {
+		s.monitoring.LedgerError.Increment()
+		serverCaLog.Errorf("failed to record the issued certificate in the ledger: %v", err)
+	}
+}
+
 func recordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	rootCertExpiry, err := keyCertBundle.ExtractRootCertExpiryTimestamp()
 	if err != nil {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
@@ -32,6 +33,7 @@ import (
 	"istio.io/istio/pkg/security"
 	mockca "istio.io/istio/security/pkg/pki/ca/mock"
 	caerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/ledger"
 	"istio.io/istio/security/pkg/pki/util"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 )
@@ -257,3 +259,50 @@ func TestCreateCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateCertificateLedger(t *testing.T) {
+	id := "spiffe://cluster.local/ns/default/sa/app"
+	cert, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host: id, TTL: time.Hour, IsSelfSigned: true, ECSigAlg: util.EcdsaSigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := ledger.NewMemoryStore()
+	revoker, err := ledger.NewRevoker(store, util.NewKeyCertBundleFromPem(nil, nil, nil, nil), ledger.RevokerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		ca: &mockca.FakeCA{
+			SignedCert:    cert,
+			KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+		},
+		Authenticators: []security.Authenticator{&mockAuthenticator{
+			authSource: security.AuthSourceIDToken,
+			identities: []string{id},
+		}},
+		monitoring: newMonitoringMetrics(),
+		Ledger:     store,
+		Revoker:    revoker,
+	}
+	request := &pb.IstioCertificateRequest{Csr: "dumb CSR"}
+
+	if _, err := server.CreateCertificate(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := store.List(ledger.Filter{SAN: id})
+	if len(entries) != 1 || entries[0].Caller != id || entries[0].AuthSource != "IDToken" {
+		t.Fatalf("got ledger entries %v, want the issued certificate", entries)
+	}
+
+	// The CRL can't be signed by the fake CA, but the identity is revoked anyway.
+	_ = revoker.Update(ledger.Revocations{Identities: []string{id}})
+	_, err = server.CreateCertificate(context.Background(), request)
+	if s, _ := status.FromError(err); s.Code() != codes.PermissionDenied {
+		t.Errorf("got code %v for a revoked identity, want PermissionDenied", s.Code())
+	}
+	if entries, _ := store.List(ledger.Filter{}); len(entries) != 1 {
+		t.Errorf("got %d ledger entries, want 1", len(entries))
+	}
+}
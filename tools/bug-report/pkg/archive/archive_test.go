@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateExtract(t *testing.T) {
+	src, err := ioutil.TempDir("", "archive-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	root := filepath.Join(src, "bug-report")
+	files := map[string]string{
+		filepath.Join(ClusterInfoPath(root), "crs"):                        "kind: List\n",
+		filepath.Join(IstiodPath(root, "istio-system", "istiod-1"), "log"): "log line\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(src, "bug-report.tar.gz")
+	if err := Create(root, out); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := ioutil.TempDir("", "archive-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+	if err := Extract(out, dst); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range files {
+		rel, _ := filepath.Rel(root, path)
+		got, err := ioutil.ReadFile(filepath.Join(dst, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestExtractRejectsTraversal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-traversal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "evil.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	content := "x"
+	if err := tw.WriteHeader(&tar.Header{Name: "../../evil", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gzw.Close()
+	f.Close()
+
+	err = Extract(path, filepath.Join(dir, "out"))
+	if err == nil || !strings.Contains(err.Error(), "outside of the archive root") {
+		t.Errorf("Extract() error = %v, want outside of the archive root", err)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/istioctl/pkg/util/formatting"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/tools/bug-report/pkg/archive"
+)
+
+const (
+	analyzeArchiveTimeout = 5 * time.Minute
+
+	// archivedCRsFile and archivedResourcesFile are the files of the cluster info dir holding the
+	// Istio and Kubernetes resources, as written by GetCRs and GetK8sResources.
+	archivedCRsFile       = "crs"
+	archivedResourcesFile = "k8s-resources"
+	// archivedMeshConfigName is the name of the ConfigMap holding the mesh config.
+	archivedMeshConfigName = "istio"
+	// archivedSynczPath is the Istiod debug output holding the proxy sync status.
+	archivedSynczPath = "debug/syncz"
+)
+
+func analyzeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "analyze <archive>",
+		Short: "Analyze a bug report archive without cluster access.",
+		Long: `analyze runs istioctl analyze against the Istio and Kubernetes resources saved in a bug report archive, and
+prints the sync status of the proxies as reported by the archived Istiod instances, as istioctl proxy-status does.
+Proxies whose logs were archived but which are not connected to any archived Istiod are listed separately.
+
+Only the resources captured by bug-report are analyzed, e.g. namespaces and secrets are not archived, so analyzers
+depending on them may report fewer issues than when run against the cluster.`,
+		Example: "bug-report analyze bug-report.tar.gz",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return analyzeArchive(args[0], gConfig.IstioNamespace, cmd.OutOrStdout())
+		},
+	}
+}
+
+// analyzeArchive extracts the archive at archivePath and writes the analysis of its contents to w.
+func analyzeArchive(archivePath, istioNamespace string, w io.Writer) error {
+	dir, err := ioutil.TempDir("", "bug-report-analyze")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := archive.Extract(archivePath, dir); err != nil {
+		return fmt.Errorf("failed to extract %s: %v", archivePath, err)
+	}
+	root, err := findArchiveRoot(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Analyzing the archived Istio configuration:\n\n")
+	if err := analyzeArchivedConfig(root, istioNamespace, w); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nProxy status reported by the archived Istiod instances:\n\n")
+	return printArchivedProxyStatus(root, w)
+}
+
+// findArchiveRoot returns the output root dir of the extracted archive in dir.
+func findArchiveRoot(dir string) (string, error) {
+	candidates := []string{dir}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			candidates = append(candidates, filepath.Join(dir, e.Name()))
+		}
+	}
+	for _, c := range candidates {
+		if fi, err := os.Stat(archive.ClusterInfoPath(c)); err == nil && fi.IsDir() {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("the archive does not contain cluster information, is it a bug report archive?")
+}
+
+// analyzeArchivedConfig runs the istioctl analyzers on the resources archived in the cluster info dir
+// of root, using the mesh config archived in istioNamespace.
+func analyzeArchivedConfig(root, istioNamespace string, w io.Writer) error {
+	var readers []local.ReaderSource
+	meshConfig := ""
+	for _, name := range []string{archivedCRsFile, archivedResourcesFile} {
+		path := filepath.Join(archive.ClusterInfoPath(root), name)
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		objects, err := splitList(b)
+		if err != nil {
+			return fmt.Errorf("failed to parse archived %s: %v", name, err)
+		}
+		docs := make([]string, 0, len(objects))
+		for _, obj := range objects {
+			if mc := archivedMeshConfig(obj, istioNamespace); mc != "" {
+				meshConfig = mc
+			}
+			doc, err := yaml.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, string(doc))
+		}
+		readers = append(readers, local.ReaderSource{Name: name, Reader: strings.NewReader(strings.Join(docs, "\n---\n"))})
+	}
+	if len(readers) == 0 {
+		return fmt.Errorf("the archive does not contain any cluster resources")
+	}
+
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+		"", resource.Namespace(istioNamespace), nil, true, analyzeArchiveTimeout)
+	if meshConfig != "" {
+		meshFile := filepath.Join(root, "mesh-config.yaml")
+		if err := ioutil.WriteFile(meshFile, []byte(meshConfig), 0o644); err != nil {
+			return err
+		}
+		if err := sa.AddFileKubeMeshConfig(meshFile); err != nil {
+			fmt.Fprintf(w, "Using the default mesh config, the archived one is invalid: %v\n", err)
+		}
+	} else {
+		fmt.Fprintf(w, "Using the default mesh config, none was found in namespace %s.\n", istioNamespace)
+	}
+	if err := sa.AddReaderKubeSource(readers); err != nil {
+		// Analyze the resources that could be read.
+		fmt.Fprintf(w, "Some archived resources could not be read: %v\n", err)
+	}
+
+	result, err := sa.Analyze(make(chan struct{}))
+	if err != nil {
+		return err
+	}
+	messages := result.Messages.SetDocRef("istioctl-analyze").FilterOutLowerThan(diag.Info)
+	if len(messages) == 0 {
+		fmt.Fprintf(w, "No validation issues found.\n")
+		return nil
+	}
+	out, err := formatting.Print(messages, formatting.LogFormat, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, out)
+	return nil
+}
+
+// splitList returns the objects of a YAML document, which is either a single object or a List of objects
+// as output by kubectl get.
+func splitList(b []byte) ([]map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	if obj["kind"] != "List" {
+		return []map[string]interface{}{obj}, nil
+	}
+	items, _ := obj["items"].([]interface{})
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if o, ok := item.(map[string]interface{}); ok {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// archivedMeshConfig returns the mesh config if obj is the Istio ConfigMap of istioNamespace.
+func archivedMeshConfig(obj map[string]interface{}, istioNamespace string) string {
+	if obj["kind"] != "ConfigMap" {
+		return ""
+	}
+	meta, _ := obj["metadata"].(map[string]interface{})
+	if meta["name"] != archivedMeshConfigName || meta["namespace"] != istioNamespace {
+		return ""
+	}
+	data, _ := obj["data"].(map[string]interface{})
+	mc, _ := data["mesh"].(string)
+	return mc
+}
+
+// printArchivedProxyStatus prints the sync status of the proxies from the syncz debug output of the
+// archived Istiod instances, followed by the archived proxies none of them reports.
+func printArchivedProxyStatus(root string, w io.Writer) error {
+	files, err := filepath.Glob(filepath.Join(archive.IstiodPath(root, "*", "*"), filepath.FromSlash(archivedSynczPath)))
+	if err != nil {
+		return err
+	}
+	statuses := make(map[string][]byte)
+	connected := make(map[string]bool)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		var ss []xds.SyncStatus
+		if err := json.Unmarshal(b, &ss); err != nil {
+			fmt.Fprintf(w, "Ignoring invalid sync status %s: %v\n", f, err)
+			continue
+		}
+		for _, s := range ss {
+			connected[s.ProxyID] = true
+		}
+		// The Istiod pod name is the dir holding debug/syncz.
+		pod := filepath.Base(filepath.Dir(filepath.Dir(f)))
+		statuses[pod] = b
+	}
+	if len(statuses) == 0 {
+		fmt.Fprintf(w, "No Istiod sync status found in the archive.\n")
+	} else {
+		sw := &pilot.StatusWriter{Writer: w}
+		if err := sw.PrintAll(statuses); err != nil {
+			return err
+		}
+	}
+
+	proxies, err := filepath.Glob(archive.ProxyOutputPath(root, "*", "*"))
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, p := range proxies {
+		pod, namespace := filepath.Base(p), filepath.Base(filepath.Dir(p))
+		if id := pod + "." + namespace; !connected[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Fprintf(w, "\nArchived proxies not connected to any archived Istiod:\n  %s\n", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
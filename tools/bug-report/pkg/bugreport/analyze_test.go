@@ -0,0 +1,145 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/tools/bug-report/pkg/archive"
+)
+
+const (
+	archivedCRs = `apiVersion: v1
+kind: List
+items:
+- apiVersion: networking.istio.io/v1alpha3
+  kind: VirtualService
+  metadata:
+    name: reviews
+    namespace: default
+  spec:
+    hosts:
+    - reviews
+    gateways:
+    - missing-gateway
+    http:
+    - route:
+      - destination:
+          host: reviews
+`
+	archivedResources = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: istio
+    namespace: istio-system
+  data:
+    mesh: |-
+      rootNamespace: istio-system
+`
+	archivedSyncz = `[{"proxy":"productpage-1.default","proxy_version":"1.10.0","istio_version":"1.10.0",` +
+		`"cluster_sent":"a","cluster_acked":"a","listener_sent":"a","listener_acked":"a"}]`
+)
+
+func TestAnalyzeArchive(t *testing.T) {
+	src, err := ioutil.TempDir("", "bug-report-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	root := filepath.Join(src, "bug-report")
+	clusterDir := archive.ClusterInfoPath(root)
+	istiodDir := archive.IstiodPath(root, "istio-system", "istiod-1")
+	files := map[string]string{
+		filepath.Join(clusterDir, archivedCRsFile):                                      archivedCRs,
+		filepath.Join(clusterDir, archivedResourcesFile):                                archivedResources,
+		filepath.Join(istiodDir, filepath.FromSlash(archivedSynczPath)):                 archivedSyncz,
+		filepath.Join(archive.ProxyOutputPath(root, "default", "productpage-1"), "log"): "log line\n",
+		filepath.Join(archive.ProxyOutputPath(root, "default", "reviews-1"), "log"):     "log line\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := ioutil.TempDir("", "bug-report-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+	archivePath := filepath.Join(out, "bug-report.tar.gz")
+	// As bug-report does, archive the parent of the output root.
+	if err := archive.Create(archive.DirToArchive(root), archivePath); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &bytes.Buffer{}
+	if err := analyzeArchive(archivePath, "istio-system", w); err != nil {
+		t.Fatalf("analyzeArchive() failed: %v", err)
+	}
+	got := w.String()
+	for _, want := range []string{
+		"IST0101",
+		"VirtualService reviews.default",
+		"missing-gateway",
+		"productpage-1.default",
+		"istiod-1",
+		"Archived proxies not connected to any archived Istiod:\n  reviews-1.default\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("analyzeArchive() output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Using the default mesh config") {
+		t.Errorf("analyzeArchive() did not use the archived mesh config:\n%s", got)
+	}
+	if strings.Contains(got, "\n  productpage-1.default") {
+		t.Errorf("analyzeArchive() reported a connected proxy as not connected:\n%s", got)
+	}
+}
+
+func TestAnalyzeArchiveNotBugReport(t *testing.T) {
+	src, err := ioutil.TempDir("", "bug-report-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	if err := ioutil.WriteFile(filepath.Join(src, "README"), []byte("not a bug report"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.TempDir("", "bug-report-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+	archivePath := filepath.Join(out, "other.tar.gz")
+	if err := archive.Create(src, archivePath); err != nil {
+		t.Fatal(err)
+	}
+	err = analyzeArchive(archivePath, "istio-system", &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "does not contain cluster information") {
+		t.Errorf("analyzeArchive() error = %v, want missing cluster information", err)
+	}
+}
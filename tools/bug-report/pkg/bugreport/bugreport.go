@@ -46,6 +46,7 @@ import (
 	"istio.io/istio/tools/bug-report/pkg/kubeclient"
 	"istio.io/istio/tools/bug-report/pkg/kubectlcmd"
 	"istio.io/istio/tools/bug-report/pkg/processlog"
+	"istio.io/istio/tools/bug-report/pkg/redact"
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
 )
@@ -79,12 +80,18 @@ All names except label and annotation keys support '*' glob matching pattern.
 
 e.g.
 --include ns1,ns2 (only namespaces ns1 and ns2)
---include n*//p*/l=v* (pods with name beginning with 'p' in namespaces beginning with 'n' and having label 'l' with value beginning with 'v'.)`,
+--include n*//p*/l=v* (pods with name beginning with 'p' in namespaces beginning with 'n' and having label 'l' with value beginning with 'v'.)
+
+Private keys, bearer tokens and JWTs are redacted from all files before they are archived. Additional patterns can be
+redacted with --redact, and structured fields of JSON and YAML files with redactionRules in the --filename config.
+
+Archives can be analyzed without cluster access with 'bug-report analyze <archive>'.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runBugReportCommand(cmd, logOpts)
 		},
 	}
 	rootCmd.AddCommand(version.CobraCommand())
+	rootCmd.AddCommand(analyzeCmd())
 	addFlags(rootCmd, gConfig)
 
 	return rootCmd
@@ -99,6 +106,8 @@ var (
 	// Aggregated errors for all fetch operations.
 	gErrors util.Errors
 	lock    = sync.RWMutex{}
+	// redactor is applied to all text files before they are written.
+	redactor *redact.Redactor
 )
 
 func runBugReportCommand(_ *cobra.Command, logOpts *log.Options) error {
@@ -109,6 +118,13 @@ func runBugReportCommand(_ *cobra.Command, logOpts *log.Options) error {
 	if err != nil {
 		return err
 	}
+	redactor, err = redact.New(config.RedactionRules, config.SkipDefaultRedaction)
+	if err != nil {
+		return err
+	}
+	if redactor.Enabled() && !config.IncludeCoreDumps {
+		common.LogAndPrintf("Proxy core dumps cannot be redacted and are not archived, set --include-core-dumps to archive them.\n")
+	}
 	clusterCtxStr := ""
 	if config.Context == "" {
 		var err error
@@ -291,7 +307,9 @@ func gatherInfo(client kube.ExtendedClient, config *config.BugReportConfig, reso
 		proxyDir := archive.ProxyOutputPath(tempDir, namespace, pod)
 		switch {
 		case common.IsProxyContainer(params.ClusterVersion, container):
-			getFromCluster(content.GetCoredumps, cp, filepath.Join(proxyDir, "cores"), &mandatoryWg)
+			if !redactor.Enabled() || config.IncludeCoreDumps {
+				getFromCluster(content.GetCoredumps, cp, filepath.Join(proxyDir, "cores"), &mandatoryWg)
+			}
 			getFromCluster(content.GetNetstat, cp, proxyDir, &mandatoryWg)
 			getFromCluster(content.GetProxyInfo, cp, archive.ProxyOutputPath(tempDir, namespace, pod), &optionalWg)
 			getProxyLogs(client, config, resources, p, namespace, pod, container, &optionalWg)
@@ -434,6 +452,11 @@ func writeFile(path, text string) {
 		return
 	}
 	mkdirOrExit(path)
+	// Core dumps are binary, redacting them would corrupt them. They are only collected if redaction is
+	// disabled or the user opted in with --include-core-dumps.
+	if !strings.HasSuffix(path, ".core") {
+		text = redactor.Redact(text)
+	}
 	if err := ioutil.WriteFile(path, []byte(text), 0o644); err != nil {
 		log.Errorf(err.Error())
 	}
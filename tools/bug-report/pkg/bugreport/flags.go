@@ -30,7 +30,7 @@ import (
 
 var (
 	startTime, endTime, configFile, tempDir string
-	included, excluded                      []string
+	included, excluded, redacted            []string
 	commandTimeout, since                   time.Duration
 	gConfig                                 = &config2.BugReportConfig{}
 )
@@ -88,6 +88,16 @@ func addFlags(cmd *cobra.Command, args *config2.BugReportConfig) {
 		"List of comma separated glob patterns to match against log error strings. "+
 			"Any error matching these patterns is ignored when calculating the log importance heuristic.")
 
+	// redaction
+	cmd.PersistentFlags().StringSliceVar(&redacted, "redact", nil,
+		"List of comma separated regular expressions whose matches are replaced with [redacted] in the archived text files. "+
+			"Rules redacting structured fields of JSON and YAML files can be set in the file given with --filename.")
+	cmd.PersistentFlags().BoolVar(&args.SkipDefaultRedaction, "skip-default-redaction", false,
+		"If set, private keys, bearer tokens and JWTs are not redacted from the archived text files.")
+	cmd.PersistentFlags().BoolVar(&args.IncludeCoreDumps, "include-core-dumps", false,
+		"If set, proxy core dumps are archived even though redaction is enabled. Core dumps are binary and are never "+
+			"redacted, they may contain private keys and tokens. Without redaction rules, core dumps are always archived.")
+
 	// output/working dir
 	cmd.PersistentFlags().StringVar(&tempDir, "dir", "",
 		"Set a specific directory for temporary artifact storage.")
@@ -105,6 +115,7 @@ func parseConfig() (*config2.BugReportConfig, error) {
 		}
 	}
 
+	gConfig.Since = config2.Duration(since)
 	if err := parseTimes(gConfig, startTime, endTime); err != nil {
 		log.Fatal(err.Error())
 	}
@@ -130,7 +141,18 @@ func parseConfig() (*config2.BugReportConfig, error) {
 		}
 		gConfig.Exclude = append(gConfig.Exclude, ess)
 	}
-	return overlayConfig(fileConfig, gConfig)
+	out, err := overlayConfig(fileConfig, gConfig)
+	if err != nil {
+		return nil, err
+	}
+	// Patterns from --redact are added to the rules of the configuration file, not replacing them.
+	for i, p := range redacted {
+		out.RedactionRules = append(out.RedactionRules, &config2.RedactionRule{
+			Name:    fmt.Sprintf("redact-flag-%d", i),
+			Pattern: p,
+		})
+	}
+	return out, nil
 }
 
 func parseTimes(config *config2.BugReportConfig, startTime, endTime string) error {
@@ -144,7 +166,7 @@ func parseTimes(config *config2.BugReportConfig, startTime, endTime string) erro
 	}
 	if config.Since != 0 {
 		if startTime != "" {
-			return fmt.Errorf("only one --start-time or --since may be set")
+			return fmt.Errorf("only one --start-time or --duration may be set")
 		}
 		config.StartTime = config.EndTime.Add(-1 * time.Duration(config.Since))
 	} else {
@@ -156,6 +156,44 @@ type BugReportConfig struct {
 	// IgnoredErrors are glob error patterns which are ignored when
 	// calculating the error heuristic for a log.
 	IgnoredErrors []string `json:"ignoredErrors,omitempty"`
+
+	// RedactionRules are applied to all text files before they are written
+	// to the archive, in addition to the default rules.
+	RedactionRules []*RedactionRule `json:"redactionRules,omitempty"`
+	// SkipDefaultRedaction disables the default redaction rules for
+	// private keys, bearer tokens and JWTs.
+	SkipDefaultRedaction bool `json:"skipDefaultRedaction,omitempty"`
+	// IncludeCoreDumps includes the proxy core dumps in the archive when
+	// redaction is enabled. Core dumps are binary and cannot be redacted,
+	// so they are excluded by default unless redaction is disabled.
+	IncludeCoreDumps bool `json:"includeCoreDumps,omitempty"`
+}
+
+// RedactionRule replaces sensitive content in the archived files.
+//
+// Examples:
+//
+// 1. Redact API keys anywhere in the archive:
+//      pattern: 'x-api-key: \S+'
+//      replacement: 'x-api-key: [redacted]'
+//
+// 2. Redact the data of all ConfigMaps, and the client secrets in Envoy config dumps:
+//      paths:
+//      - items.*.data
+//      - '**.client_secret'
+//
+type RedactionRule struct {
+	// Name describes the rule in the bug report output.
+	Name string `json:"name,omitempty"`
+	// Pattern is a regular expression whose matches are replaced in all
+	// files. The replacement may reference submatches, e.g. ${1}.
+	Pattern string `json:"pattern,omitempty"`
+	// Paths are field paths whose values are replaced in JSON and YAML files.
+	// Paths are dot separated keys or list indices, where "*" matches any
+	// single key or index and "**" matches any number of them.
+	Paths []string `json:"paths,omitempty"`
+	// Replacement replaces the redacted content. Default is "[redacted]".
+	Replacement string `json:"replacement,omitempty"`
 }
 
 func (b *BugReportConfig) String() string {
@@ -171,6 +209,8 @@ func (b *BugReportConfig) String() string {
 	out += fmt.Sprintf("timeout (mins): %v\n", math.Round(float64(int(b.CommandTimeout))/float64(time.Minute)))
 	out += fmt.Sprintf("include: %s\n", b.Include)
 	out += fmt.Sprintf("exclude: %s\n", b.Exclude)
+	out += fmt.Sprintf("redaction rules: %d (default rules skipped: %v)\n", len(b.RedactionRules), b.SkipDefaultRedaction)
+	out += fmt.Sprintf("include-core-dumps: %v\n", b.IncludeCoreDumps)
 	if !b.StartTime.Equal(time.Time{}) {
 		out += fmt.Sprintf("start-time: %v\n", b.StartTime)
 	}
@@ -15,6 +15,7 @@
 package processlog
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -101,23 +102,45 @@ func getStats(config *config.BugReportConfig, logStr string) *Stats {
 	return out
 }
 
+// jsonLogLine is a log line written with JSON encoding by Istio components (--log_as_json) or by
+// Envoy (--log-format with the Istio JSON format).
+type jsonLogLine struct {
+	Level string `json:"level"`
+	Time  string `json:"time"`
+	Msg   string `json:"msg"`
+}
+
 func processLogLine(line string) (timeStamp *time.Time, level string, text string, valid bool) {
-	lv := strings.Split(line, "\t")
-	if len(lv) < 3 {
-		return nil, "", "", false
+	var ts, lvl string
+	if strings.HasPrefix(line, "{") {
+		jl := &jsonLogLine{}
+		if err := json.Unmarshal([]byte(line), jl); err != nil {
+			return nil, "", "", false
+		}
+		ts, lvl, text = jl.Time, jl.Level, jl.Msg
+	} else {
+		lv := strings.Split(line, "\t")
+		if len(lv) < 3 {
+			return nil, "", "", false
+		}
+		ts, lvl, text = lv[0], lv[1], strings.Join(lv[2:], "\t")
 	}
-	ts, err := time.Parse(time.RFC3339Nano, lv[0])
+	t, err := time.Parse(time.RFC3339Nano, ts)
 	if err != nil {
 		return nil, "", "", false
 	}
-	timeStamp = &ts
-	switch lv[1] {
+	timeStamp = &t
+	switch lvl {
 	case levelFatal, levelError, levelWarn, levelInfo, levelDebug, levelTrace:
-		level = lv[1]
+		level = lvl
+	// Envoy log levels.
+	case "critical":
+		level = levelFatal
+	case "warning":
+		level = levelWarn
 	default:
 		return nil, "", "", false
 	}
-	text = strings.Join(lv[2:], "\t")
 	valid = true
 	return
 }
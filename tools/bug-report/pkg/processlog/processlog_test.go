@@ -83,3 +83,62 @@ func TestTimeRangeFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessLogLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		level string
+		text  string
+		valid bool
+	}{
+		{
+			name:  "istio",
+			line:  "2020-06-29T23:37:27.285053Z\terror\tads\tpush failed",
+			level: levelError,
+			text:  "ads\tpush failed",
+			valid: true,
+		},
+		{
+			name:  "envoy",
+			line:  "2020-06-29T23:37:27.285053Z\twarning\tenvoy config\tgRPC config stream closed",
+			level: levelWarn,
+			text:  "envoy config\tgRPC config stream closed",
+			valid: true,
+		},
+		{
+			name:  "envoy critical",
+			line:  "2020-06-29T23:37:27.285053Z\tcritical\tenvoy backtrace\tCaught Segmentation fault",
+			level: levelFatal,
+			text:  "envoy backtrace\tCaught Segmentation fault",
+			valid: true,
+		},
+		{
+			name:  "json",
+			line:  `{"level":"info","time":"2020-06-29T23:37:27.285053Z","scope":"envoy main","msg":"starting"}`,
+			level: levelInfo,
+			text:  "starting",
+			valid: true,
+		},
+		{
+			name: "json without time",
+			line: `{"level":"info","msg":"starting"}`,
+		},
+		{
+			name: "continuation",
+			line: "\tat some stack frame",
+		},
+	}
+	want, _ := time.Parse(time.RFC3339Nano, "2020-06-29T23:37:27.285053Z")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, level, text, valid := processLogLine(tt.line)
+			if valid != tt.valid || level != tt.level || text != tt.text {
+				t.Fatalf("got (%q, %q, %v), want (%q, %q, %v)", level, text, valid, tt.level, tt.text, tt.valid)
+			}
+			if valid && !ts.Equal(want) {
+				t.Errorf("got time %v, want %v", ts, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact removes secrets and tokens from the files written to the bug report archive.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/tools/bug-report/pkg/config"
+)
+
+const defaultReplacement = "[redacted]"
+
+// DefaultRules are the rules applied unless SkipDefaultRedaction is set. They redact private keys,
+// bearer tokens and JWTs in any file, and the inline secrets of Envoy config dumps.
+var DefaultRules = []*config.RedactionRule{
+	{
+		Name:    "private-keys",
+		Pattern: `-----BEGIN ([A-Z ]*)PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	},
+	{
+		Name:        "bearer-tokens",
+		Pattern:     `(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`,
+		Replacement: "${1}" + defaultReplacement,
+	},
+	{
+		Name:    "jwts",
+		Pattern: `eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+`,
+	},
+	{
+		Name: "envoy-inline-secrets",
+		Paths: []string{
+			"**.private_key.inline_bytes",
+			"**.private_key.inline_string",
+			"**.password.inline_bytes",
+			"**.password.inline_string",
+			"**.session_ticket_keys.keys.*.inline_bytes",
+			"**.session_ticket_keys.keys.*.inline_string",
+			"**.hmac_secret.inline_bytes",
+			"**.hmac_secret.inline_string",
+		},
+	},
+}
+
+// Redactor applies redaction rules to text.
+type Redactor struct {
+	patterns []pattern
+	paths    []path
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+type path struct {
+	segments    []string
+	replacement string
+}
+
+// New returns a Redactor applying rules, and the default rules unless skipDefaults is set.
+func New(rules []*config.RedactionRule, skipDefaults bool) (*Redactor, error) {
+	if !skipDefaults {
+		rules = append(append([]*config.RedactionRule{}, DefaultRules...), rules...)
+	}
+	r := &Redactor{}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if rule.Pattern == "" && len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("redaction rule %s: a pattern or paths are required", name)
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = defaultReplacement
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule %s: %v", name, err)
+			}
+			r.patterns = append(r.patterns, pattern{re: re, replacement: replacement})
+		}
+		for _, p := range rule.Paths {
+			if p == "" {
+				return nil, fmt.Errorf("redaction rule %s: empty path", name)
+			}
+			r.paths = append(r.paths, path{segments: strings.Split(p, "."), replacement: replacement})
+		}
+	}
+	return r, nil
+}
+
+// Enabled returns true if the Redactor has any rule to apply.
+func (r *Redactor) Enabled() bool {
+	return r != nil && (len(r.patterns) > 0 || len(r.paths) > 0)
+}
+
+// Redact returns text with the paths of the rules redacted if it is a JSON or YAML document, and
+// the patterns of the rules redacted.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	if len(r.paths) > 0 {
+		text = r.redactPaths(text)
+	}
+	for _, p := range r.patterns {
+		text = p.re.ReplaceAllString(text, p.replacement)
+	}
+	return text
+}
+
+// redactPaths redacts the paths of the rules in text. Text is only re-encoded if something was
+// redacted, so that other files are archived as they were collected.
+func (r *Redactor) redactPaths(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	isJSON := strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+	var doc interface{}
+	if isJSON {
+		if err := json.Unmarshal([]byte(trimmed), &doc); err != nil {
+			return text
+		}
+	} else if err := yaml.Unmarshal([]byte(trimmed), &doc); err != nil {
+		return text
+	}
+	switch doc.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		// Plain text that happens to parse as a YAML scalar.
+		return text
+	}
+	if !r.walk(doc, nil) {
+		return text
+	}
+	var out []byte
+	var err error
+	if isJSON {
+		out, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		out, err = yaml.Marshal(doc)
+	}
+	if err != nil {
+		return text
+	}
+	return string(out)
+}
+
+// walk redacts the values of node whose path matches a rule, and reports whether any was.
+func (r *Redactor) walk(node interface{}, at []string) bool {
+	redacted := false
+	visit := func(key string, value interface{}, set func(interface{})) {
+		child := append(at[:len(at):len(at)], key)
+		for _, p := range r.paths {
+			if matchPath(p.segments, child) {
+				set(p.replacement)
+				redacted = true
+				return
+			}
+		}
+		if r.walk(value, child) {
+			redacted = true
+		}
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			k := k
+			visit(k, v, func(nv interface{}) { n[k] = nv })
+		}
+	case []interface{}:
+		for i, v := range n {
+			i := i
+			visit(strconv.Itoa(i), v, func(nv interface{}) { n[i] = nv })
+		}
+	}
+	return redacted
+}
+
+// matchPath reports whether the field path matches the pattern segments, where "*" matches one
+// segment and "**" any number of them.
+func matchPath(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+		return false
+	}
+	return matchPath(pattern[1:], path[1:])
+}
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/pilot/test/replay"
+	"istio.io/istio/pkg/test"
+	"istio.io/pkg/log"
+)
+
+func main() {
+	if err := cmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func cmd() *cobra.Command {
+	var (
+		iterations int
+		output     string
+		baseline   string
+		tolerance  float64
+		verbose    bool
+	)
+	c := &cobra.Command{
+		Use:          "xds-replay <snapshot directory>",
+		Short:        "Replays a snapshot of istiod and reports the cost of xDS generation.",
+		SilenceUsage: true,
+		Long: fmt.Sprintf(`xds-replay loads a snapshot recorded from a running istiod into an in-memory discovery server,
+pushes to all recorded proxies, then replays the recorded config changes, and reports the CPU time,
+allocations and response sizes of each xDS generator, and the latency of each push.
+
+The snapshot directory holds:
+  %-15s the output of /debug/configz
+  %-15s the output of /debug/registryz
+  %-15s a list of {"id": <node ID>, "metadata": <node metadata>} of the proxies to push to
+  %-15s optional, a list of {"type": "add|update|delete", "object": <config as in configz>}
+
+Endpoints are not part of the snapshot, so EDS responses are empty for services of the service registry.
+
+With --baseline, the command fails if a cost per call grew by more than the tolerance compared to the
+JSON report of a previous run.`, replay.ConfigsFile, replay.ServicesFile, replay.ProxiesFile, replay.EventsFile),
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unknown output format %q", output)
+			}
+			if !verbose {
+				for _, s := range log.Scopes() {
+					s.SetOutputLevel(log.NoneLevel)
+				}
+			}
+			snap, err := replay.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			var report *replay.Report
+			if err := test.Wrap(func(t test.Failer) {
+				var err error
+				if report, err = replay.Run(t, snap, replay.Options{Iterations: iterations}); err != nil {
+					t.Fatal(err)
+				}
+			}); err != nil {
+				return err
+			}
+
+			if output == "json" {
+				b, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(c.OutOrStdout(), string(b))
+			} else if err := report.Write(c.OutOrStdout()); err != nil {
+				return err
+			}
+
+			if baseline == "" {
+				return nil
+			}
+			b, err := ioutil.ReadFile(baseline)
+			if err != nil {
+				return err
+			}
+			base := &replay.Report{}
+			if err := json.Unmarshal(b, base); err != nil {
+				return fmt.Errorf("failed parsing baseline %s: %v", baseline, err)
+			}
+			if regressions := replay.Compare(base, report, tolerance); len(regressions) > 0 {
+				return fmt.Errorf("regressions from %s:\n  %s", baseline, strings.Join(regressions, "\n  "))
+			}
+			return nil
+		},
+	}
+	c.Flags().IntVar(&iterations, "iterations", 1, "Number of times to repeat the initial push to all proxies")
+	c.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+	c.Flags().StringVar(&baseline, "baseline", "", "JSON report of a previous run to compare with")
+	c.Flags().Float64Var(&tolerance, "tolerance", 0.1, "Allowed growth of a cost compared to the baseline, as a fraction")
+	c.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show the logs of the discovery server")
+	return c
+}